@@ -0,0 +1,136 @@
+// Package analysis studies how text tokenizes, to inform decisions about
+// vocabularies and prompts.
+//
+// Coverage measures how well a tokenizer's vocabulary fits a corpus, to
+// decide whether training a custom vocabulary for a domain is worth it:
+//
+//	f, err := os.Open("corpus.txt")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//	report, err := analysis.Coverage(tokenizer, f)
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Printf("%.1f%% of words are single tokens\n", 100*report.SingleTokenRate())
+//
+// A vocabulary that fits covers most pre-tokens (words, numbers and
+// punctuation runs) with a single token and rarely falls back to tokens that
+// hold only part of a character.
+//
+// TemplateBudgets estimates the worst-case token count of text/template
+// prompt files from their static text and estimates for their actions.
+//
+// Optimize suggests whitespace and punctuation rewrites, such as collapsing
+// double spaces, that make a prompt shorter in tokens without changing how
+// it reads.
+package analysis
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3"
+	"github.com/agentstation/tokenizer/llama3/pretoken"
+)
+
+// readSize is the size of the reads from the corpus.
+const readSize = 64 * 1024
+
+// CoverageReport is the vocabulary coverage of a corpus.
+type CoverageReport struct {
+	Bytes        int64 `json:"bytes"`         // size of the corpus
+	Pretokens    int64 `json:"pretokens"`     // pre-tokens of the corpus
+	SingleToken  int64 `json:"single_token"`  // pre-tokens that are one token
+	MultiToken   int64 `json:"multi_token"`   // pre-tokens split into several tokens
+	Tokens       int64 `json:"tokens"`        // tokens of the corpus
+	ByteFallback int64 `json:"byte_fallback"` // tokens that hold part of a UTF-8 character
+}
+
+// SingleTokenRate returns the fraction of pre-tokens that are one token.
+func (r CoverageReport) SingleTokenRate() float64 {
+	return ratio(r.SingleToken, r.Pretokens)
+}
+
+// ByteFallbackRate returns the fraction of tokens that hold part of a UTF-8
+// character, the sign of characters the vocabulary does not know.
+func (r CoverageReport) ByteFallbackRate() float64 {
+	return ratio(r.ByteFallback, r.Tokens)
+}
+
+// TokensPerPretoken returns the average number of tokens per pre-token.
+func (r CoverageReport) TokensPerPretoken() float64 {
+	return ratio(r.Tokens, r.Pretokens)
+}
+
+func ratio(a, b int64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// Coverage reads a corpus from r and reports how its pre-tokens tokenize
+// with tok. The corpus is processed as it is read, so it may be larger than
+// memory. Special token strings in the corpus count as text.
+func Coverage(tok *llama3.Tokenizer, r io.Reader) (CoverageReport, error) {
+	c := coverage{tok: tok, fallback: make(map[int]bool)}
+	stream := pretoken.NewStream()
+	buf := make([]byte, readSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			c.report.Bytes += int64(n)
+			c.add(stream.Feed(buf[:n]))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return c.report, fmt.Errorf("failed to read corpus: %w", err)
+		}
+	}
+	c.add(stream.Flush())
+	return c.report, nil
+}
+
+// coverage accumulates a CoverageReport.
+type coverage struct {
+	tok      *llama3.Tokenizer
+	report   CoverageReport
+	fallback map[int]bool // whether each token seen is a byte fallback
+}
+
+func (c *coverage) add(pretokens []pretoken.PreToken) {
+	for _, pt := range pretokens {
+		// PreTokenize returns the pre-token in the byte-level form BPE takes
+		n := 0
+		for _, part := range c.tok.PreTokenize(pt.Text) {
+			for _, id := range c.tok.EncodeBPE(part) {
+				if c.isFallback(id) {
+					c.report.ByteFallback++
+				}
+				n++
+			}
+		}
+
+		c.report.Pretokens++
+		c.report.Tokens += int64(n)
+		if n == 1 {
+			c.report.SingleToken++
+		} else {
+			c.report.MultiToken++
+		}
+	}
+}
+
+func (c *coverage) isFallback(id int) bool {
+	fallback, ok := c.fallback[id]
+	if !ok {
+		fallback = !utf8.Valid(c.tok.DecodeBytes([]int{id}))
+		c.fallback[id] = fallback
+	}
+	return fallback
+}
@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestCoverage(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		corpus       string
+		pretokens    int64
+		singleToken  int64
+		byteFallback bool
+	}{
+		{"empty", "", 0, 0, false},
+		{"common words", "hello world, hello", 4, 4, false},
+		{"rare word", "the pneumonoultramicroscopic", 2, 1, false},
+		{"rare script", "𓀀𓀁", 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Coverage(tok, strings.NewReader(tt.corpus))
+			if err != nil {
+				t.Fatalf("Coverage failed: %v", err)
+			}
+			if report.Bytes != int64(len(tt.corpus)) {
+				t.Errorf("Expected %d bytes, got %d", len(tt.corpus), report.Bytes)
+			}
+			if report.Pretokens != tt.pretokens || report.SingleToken != tt.singleToken {
+				t.Errorf("Expected %d pretokens, %d single, got %+v", tt.pretokens, tt.singleToken, report)
+			}
+			if report.SingleToken+report.MultiToken != report.Pretokens {
+				t.Errorf("Expected single and multi-token pretokens to add up, got %+v", report)
+			}
+			if want := int64(len(tok.Encode(tt.corpus, &llama3.EncodeOptions{}))); report.Tokens != want {
+				t.Errorf("Expected %d tokens, got %d", want, report.Tokens)
+			}
+			if (report.ByteFallback > 0) != tt.byteFallback {
+				t.Errorf("Expected byte fallback %v, got %d tokens", tt.byteFallback, report.ByteFallback)
+			}
+		})
+	}
+}
+
+func TestCoverageStreaming(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	corpus := strings.Repeat("Tokenizers split text: 12345 ünïcödé 日本語 😀\n\n", 50)
+	want, err := Coverage(tok, strings.NewReader(corpus))
+	if err != nil {
+		t.Fatalf("Coverage failed: %v", err)
+	}
+	got, err := Coverage(tok, iotest.OneByteReader(strings.NewReader(corpus)))
+	if err != nil {
+		t.Fatalf("Coverage failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if rate := want.SingleTokenRate(); rate <= 0 || rate >= 1 {
+		t.Errorf("Expected a single-token rate between 0 and 1, got %v", rate)
+	}
+
+	errRead := errors.New("read failed")
+	if _, err := Coverage(tok, iotest.ErrReader(errRead)); !errors.Is(err, errRead) {
+		t.Errorf("Expected the read error, got %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// Rewrite rules of Optimize. None changes how the text reads, though
+// RuleTrailingSpace drops Markdown hard line breaks.
+const (
+	RuleCRLF          = "crlf"           // "\r\n" line endings become "\n"
+	RuleTrailingSpace = "trailing-space" // spaces and tabs before a line break are removed
+	RuleDoubleSpace   = "double-space"   // runs of spaces inside a line become one space
+	RuleBlankLines    = "blank-lines"    // three or more line breaks become two
+	RuleSmartQuotes   = "smart-quotes"   // typographic quotes become ASCII quotes
+	RuleNBSP          = "nbsp"           // no-break spaces become spaces
+	RuleZeroWidth     = "zero-width"     // zero-width spaces and BOMs are removed
+	RuleEllipsis      = "ellipsis"       // "…" becomes "..."
+)
+
+// optimizeRules finds the candidate rewrites of each rule in a text.
+var optimizeRules = map[string]func(text string) []Rewrite{
+	RuleCRLF: func(text string) []Rewrite {
+		return replaceAll(text, RuleCRLF, map[string]string{"\r\n": "\n"})
+	},
+	RuleTrailingSpace: trailingSpace,
+	RuleDoubleSpace:   doubleSpace,
+	RuleBlankLines:    blankLines,
+	RuleSmartQuotes: func(text string) []Rewrite {
+		return replaceAll(text, RuleSmartQuotes, map[string]string{
+			"“": `"`, "”": `"`, "„": `"`, "‟": `"`,
+			"‘": "'", "’": "'", "‚": "'", "‛": "'",
+		})
+	},
+	RuleNBSP: func(text string) []Rewrite {
+		return replaceAll(text, RuleNBSP, map[string]string{"\u00a0": " ", "\u202f": " "})
+	},
+	RuleZeroWidth: func(text string) []Rewrite {
+		return replaceAll(text, RuleZeroWidth, map[string]string{"\u200b": "", "\u2060": "", "\ufeff": ""})
+	},
+	RuleEllipsis: func(text string) []Rewrite {
+		return replaceAll(text, RuleEllipsis, map[string]string{"…": "..."})
+	},
+}
+
+// OptimizeRules returns the names of the rewrite rules of Optimize, sorted.
+func OptimizeRules() []string {
+	names := make([]string, 0, len(optimizeRules))
+	for name := range optimizeRules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Rewrite is a change to a text that Optimize suggests.
+type Rewrite struct {
+	Rule  string `json:"rule"`
+	Start int    `json:"start"` // byte range of Old in the input
+	End   int    `json:"end"`
+	Line  int    `json:"line"` // 1-based line of Start
+	Old   string `json:"old"`
+	New   string `json:"new"`
+	Saved int    `json:"saved"` // tokens saved, with the rewrites before it applied
+}
+
+// OptimizeReport is the result of Optimize.
+type OptimizeReport struct {
+	Tokens          int       `json:"tokens"`           // tokens of the input
+	OptimizedTokens int       `json:"optimized_tokens"` // tokens of Optimized
+	Optimized       string    `json:"optimized"`        // the input with Rewrites applied
+	Rewrites        []Rewrite `json:"rewrites"`         // in input order
+}
+
+// Saved returns the number of tokens the rewrites save.
+func (r OptimizeReport) Saved() int {
+	return r.Tokens - r.OptimizedTokens
+}
+
+// Optimize looks for rewrites of whitespace and punctuation that make text
+// shorter in tokens without changing how it reads, such as collapsing
+// double spaces or straightening typographic quotes. rules selects the
+// rules to apply; none means all of OptimizeRules.
+//
+// The search is greedy: candidate rewrites are tried in text order, each
+// with the ones accepted before it applied, and kept if they save at least
+// one token. Rewrites that cost tokens, such as a straight quote that
+// merges worse with its neighbours, are left out. Counts are of tok's
+// tokens without BOS/EOS, with special token strings counted as text.
+//
+// Only the lines around a candidate are encoded to try it: text splits
+// into pre-tokens independently at each line start that is not whitespace,
+// so the counts are those of the whole text.
+func Optimize(tok *llama3.Tokenizer, text string, rules ...string) (OptimizeReport, error) {
+	if len(rules) == 0 {
+		rules = OptimizeRules()
+	}
+	var candidates []Rewrite
+	for _, rule := range rules {
+		find, ok := optimizeRules[rule]
+		if !ok {
+			return OptimizeReport{}, fmt.Errorf("unknown rule %q: use one of %s", rule, strings.Join(OptimizeRules(), ", "))
+		}
+		candidates = append(candidates, find(text)...)
+	}
+	candidates = dropOverlaps(candidates)
+
+	count := func(s string) int {
+		return countText(tok, s)
+	}
+	report := OptimizeReport{Tokens: count(text)}
+
+	// Try the candidates block by block, where blocks start at line starts
+	// no candidate touches
+	var optimized strings.Builder
+	optimized.Grow(len(text))
+	next := 0             // first candidate of the block
+	line, linePos := 1, 0 // line of text[linePos]
+	for start := 0; start < len(text); {
+		end := blockEnd(text, start, candidates[next:])
+		block, blockTokens := text[start:end], -1
+		shift := 0 // offset of the optimized block from the input
+		for ; next < len(candidates) && candidates[next].Start < end; next++ {
+			c := candidates[next]
+			if blockTokens < 0 {
+				blockTokens = count(block)
+			}
+			i, j := c.Start-start+shift, c.End-start+shift
+			rewritten := block[:i] + c.New + block[j:]
+			if n := count(rewritten); n < blockTokens {
+				line += strings.Count(text[linePos:c.Start], "\n")
+				c.Line, linePos = line, c.Start
+				c.Saved = blockTokens - n
+				report.Rewrites = append(report.Rewrites, c)
+				block, blockTokens = rewritten, n
+				shift += len(c.New) - len(c.Old)
+			}
+		}
+		optimized.WriteString(block)
+		start = end
+	}
+
+	report.Optimized = optimized.String()
+	report.OptimizedTokens = count(report.Optimized)
+	return report, nil
+}
+
+// countText returns the number of tokens of text without BOS/EOS, with
+// special token strings counted as text.
+func countText(tok *llama3.Tokenizer, text string) int {
+	n := 0
+	for _, pretoken := range tok.PreTokenize(text) {
+		if pretoken != "" {
+			n += len(tok.EncodeBPE(pretoken))
+		}
+	}
+	return n
+}
+
+// blockEnd returns the end of the block of text that starts at start: the
+// next line start before a non-space character that the candidates, sorted
+// and not overlapping, leave alone.
+func blockEnd(text string, start int, candidates []Rewrite) int {
+	for i := start + 1; i < len(text); i++ {
+		if text[i-1] != '\n' {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(text[i:]); unicode.IsSpace(r) {
+			continue
+		}
+		for len(candidates) > 0 && candidates[0].End < i {
+			candidates = candidates[1:]
+		}
+		if len(candidates) > 0 && candidates[0].Start <= i {
+			continue // touches the line start
+		}
+		return i
+	}
+	return len(text)
+}
+
+// dropOverlaps sorts candidates by position and drops those overlapping an
+// earlier one.
+func dropOverlaps(candidates []Rewrite) []Rewrite {
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Start < candidates[j].Start })
+	kept := candidates[:0]
+	for _, c := range candidates {
+		if len(kept) > 0 && c.Start < kept[len(kept)-1].End {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// replaceAll returns a rewrite of each occurrence of a key of replacements.
+func replaceAll(text, rule string, replacements map[string]string) []Rewrite {
+	var rewrites []Rewrite
+	for old, replacement := range replacements {
+		for i := 0; ; {
+			j := strings.Index(text[i:], old)
+			if j < 0 {
+				break
+			}
+			i += j
+			rewrites = append(rewrites, Rewrite{Rule: rule, Start: i, End: i + len(old), Old: old, New: replacement})
+			i += len(old)
+		}
+	}
+	return rewrites
+}
+
+// trailingSpace returns rewrites removing spaces and tabs before a line
+// break or the end of text.
+func trailingSpace(text string) []Rewrite {
+	var rewrites []Rewrite
+	for i := 0; i < len(text); {
+		if text[i] != ' ' && text[i] != '\t' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(text) && (text[j] == ' ' || text[j] == '\t') {
+			j++
+		}
+		if rest := text[j:]; rest == "" || rest[0] == '\n' || strings.HasPrefix(rest, "\r\n") {
+			rewrites = append(rewrites, Rewrite{Rule: RuleTrailingSpace, Start: i, End: j, Old: text[i:j]})
+		}
+		i = j
+	}
+	return rewrites
+}
+
+// doubleSpace returns rewrites collapsing runs of spaces between two other
+// characters on a line. Indentation and trailing space are left alone.
+func doubleSpace(text string) []Rewrite {
+	var rewrites []Rewrite
+	for i := 0; i < len(text); {
+		if text[i] != ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(text) && text[j] == ' ' {
+			j++
+		}
+		if j-i >= 2 && i > 0 && text[i-1] != '\n' && text[i-1] != '\t' &&
+			j < len(text) && text[j] != '\n' && text[j] != '\r' && text[j] != '\t' {
+			rewrites = append(rewrites, Rewrite{Rule: RuleDoubleSpace, Start: i, End: j, Old: text[i:j], New: " "})
+		}
+		i = j
+	}
+	return rewrites
+}
+
+// blankLines returns rewrites reducing runs of three or more line breaks to
+// two.
+func blankLines(text string) []Rewrite {
+	var rewrites []Rewrite
+	for i := 0; i < len(text); {
+		if text[i] != '\n' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(text) && text[j] == '\n' {
+			j++
+		}
+		if j-i >= 3 {
+			rewrites = append(rewrites, Rewrite{Rule: RuleBlankLines, Start: i, End: j, Old: text[i:j], New: "\n\n"})
+		}
+		i = j
+	}
+	return rewrites
+}
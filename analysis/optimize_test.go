@@ -0,0 +1,98 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestOptimize(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	noBOS := &llama3.EncodeOptions{}
+
+	text := "Hello  world,  it’s a “nice” day…   \r\n" +
+		"\n\n\n\n  Indented  line\u00a0with\u200bzero width.\r\n" +
+		strings.Repeat("Another  line with   gaps.\n", 20)
+
+	report, err := Optimize(tok, text)
+	if err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+	if n := len(tok.Encode(text, noBOS)); report.Tokens != n {
+		t.Errorf("Expected %d input tokens, got %d", n, report.Tokens)
+	}
+	if n := len(tok.Encode(report.Optimized, noBOS)); report.OptimizedTokens != n {
+		t.Errorf("Expected %d optimized tokens, got %d", n, report.OptimizedTokens)
+	}
+	if report.Saved() <= 0 || len(report.Rewrites) == 0 {
+		t.Fatalf("Expected savings, got %+v", report)
+	}
+
+	// The rewrites applied to the input give the optimized text, and their
+	// savings add up
+	var sb strings.Builder
+	pos, saved := 0, 0
+	for _, r := range report.Rewrites {
+		if r.Saved <= 0 || text[r.Start:r.End] != r.Old || r.Start < pos {
+			t.Errorf("Invalid rewrite %+v", r)
+			continue
+		}
+		if want := 1 + strings.Count(text[:r.Start], "\n"); r.Line != want {
+			t.Errorf("Expected rewrite %+v on line %d", r, want)
+		}
+		sb.WriteString(text[pos:r.Start] + r.New)
+		pos, saved = r.End, saved+r.Saved
+	}
+	sb.WriteString(text[pos:])
+	if sb.String() != report.Optimized {
+		t.Errorf("Expected the rewrites to give %q, got %q", report.Optimized, sb.String())
+	}
+	if saved != report.Saved() {
+		t.Errorf("Expected rewrites to save %d tokens in total, got %d", report.Saved(), saved)
+	}
+	if !strings.Contains(report.Optimized, "\n  Indented") {
+		t.Errorf("Expected indentation to be kept, got %q", report.Optimized)
+	}
+}
+
+func TestOptimizeRules(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		text  string
+		rules []string
+		want  string
+	}{
+		{"double_space", "Hello  world", []string{RuleDoubleSpace}, "Hello world"},
+		{"trailing_space", "Done.   \nNext", []string{RuleTrailingSpace}, "Done.\nNext"},
+		{"only_selected_rules", "Hello  world.   \nNext", []string{RuleTrailingSpace}, "Hello  world.\nNext"},
+		{"blank_lines", "x" + strings.Repeat("\n", 18) + "y", []string{RuleBlankLines}, "x\n\ny"},
+		{"zero_width", "Hello\u200b world", []string{RuleZeroWidth}, "Hello world"},
+		{"nbsp", "price:\u00a0$5", []string{RuleNBSP}, "price: $5"},
+		{"no_savings", "it’s", []string{RuleSmartQuotes}, "it’s"},
+		{"nothing_to_do", "Plain text.", nil, "Plain text."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Optimize(tok, tt.text, tt.rules...)
+			if err != nil {
+				t.Fatalf("Optimize failed: %v", err)
+			}
+			if report.Optimized != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, report.Optimized)
+			}
+		})
+	}
+
+	if _, err := Optimize(tok, "text", "unicode-normalize"); err == nil {
+		t.Error("Expected an error for an unknown rule")
+	}
+}
@@ -0,0 +1,211 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"text/template/parse"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// Defaults of TemplateOptions.
+const (
+	DefaultVarTokens       = 64
+	DefaultRangeIterations = 5
+)
+
+// TemplateOptions sets the estimates TemplateBudgets uses for the parts of
+// a template that depend on its data.
+type TemplateOptions struct {
+	// VarTokens is the estimated size of each action that prints a value,
+	// such as {{.Question}}, and of templates defined in other files.
+	// Zero means DefaultVarTokens.
+	VarTokens int
+	// Vars overrides VarTokens per action, keyed by the action's pipeline
+	// as written in the template without delimiters, e.g. ".Question" or
+	// `.Name | printf "%q"`.
+	Vars map[string]int
+	// RangeIterations is the number of times the body of a range is
+	// assumed to run. Zero means DefaultRangeIterations.
+	RangeIterations int
+}
+
+// TemplateReport is the token budget of one template.
+type TemplateReport struct {
+	Name string `json:"name"`
+	// StaticTokens and VarTokens split WorstCase into the tokens of the
+	// template's text and the estimates of its actions.
+	StaticTokens int `json:"static_tokens"`
+	VarTokens    int `json:"var_tokens"`
+	// Vars is the number of actions estimated in the worst case.
+	Vars int `json:"vars"`
+	// WorstCase is the largest output of the template: the longer branch
+	// of each if and with, and the body of each range repeated
+	// RangeIterations times.
+	WorstCase int `json:"worst_case"`
+}
+
+// TemplateBudgets parses a text/template file and estimates the worst-case
+// number of tokens of each template it defines, starting with the file's
+// own template, named name, unless it only holds definitions. Static text is
+// counted with tok, without BOS/EOS; actions that print values are
+// estimated as set by opts. Counting each piece separately can differ from
+// counting the output by a token at the seams.
+func TemplateBudgets(tok *llama3.Tokenizer, name, text string, opts *TemplateOptions) ([]TemplateReport, error) {
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+	trees := make(map[string]*parse.Tree)
+	if _, err := tree.Parse(text, "", "", trees); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	b := &templateBudget{tok: tok, trees: trees, varTokens: DefaultVarTokens, iterations: DefaultRangeIterations}
+	if opts != nil {
+		if opts.VarTokens > 0 {
+			b.varTokens = opts.VarTokens
+		}
+		if opts.RangeIterations > 0 {
+			b.iterations = opts.RangeIterations
+		}
+		b.vars = opts.Vars
+	}
+
+	names := make([]string, 0, len(trees))
+	for n := range trees {
+		if n != name {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	if main, ok := trees[name]; ok && (len(names) == 0 || !parse.IsEmptyTree(main.Root)) {
+		names = append([]string{name}, names...)
+	}
+
+	reports := make([]TemplateReport, 0, len(names))
+	for _, n := range names {
+		c := b.template(n)
+		reports = append(reports, TemplateReport{
+			Name:         n,
+			StaticTokens: c.static,
+			VarTokens:    c.vars,
+			Vars:         c.count,
+			WorstCase:    c.total(),
+		})
+	}
+	return reports, nil
+}
+
+// templateCost is the worst-case size of a part of a template.
+type templateCost struct {
+	static int // tokens of text
+	vars   int // estimated tokens of actions
+	count  int // actions estimated
+}
+
+func (c templateCost) total() int {
+	return c.static + c.vars
+}
+
+func (c templateCost) add(other templateCost) templateCost {
+	return templateCost{c.static + other.static, c.vars + other.vars, c.count + other.count}
+}
+
+func (c templateCost) times(n int) templateCost {
+	return templateCost{c.static * n, c.vars * n, c.count * n}
+}
+
+// larger returns the costlier of a and b.
+func larger(a, b templateCost) templateCost {
+	if b.total() > a.total() {
+		return b
+	}
+	return a
+}
+
+// templateBudget computes the worst-case costs of the templates of a file.
+type templateBudget struct {
+	tok        *llama3.Tokenizer
+	trees      map[string]*parse.Tree
+	vars       map[string]int
+	varTokens  int
+	iterations int
+	active     map[string]bool // templates being computed, to stop recursion
+}
+
+// template returns the cost of the named template.
+func (b *templateBudget) template(name string) templateCost {
+	tree, ok := b.trees[name]
+	if !ok || b.active[name] {
+		// Defined elsewhere, or recursive: estimate it like a value
+		return b.variable(name)
+	}
+	if b.active == nil {
+		b.active = make(map[string]bool)
+	}
+	b.active[name] = true
+	defer delete(b.active, name)
+	return b.list(tree.Root)
+}
+
+func (b *templateBudget) variable(key string) templateCost {
+	n, ok := b.vars[key]
+	if !ok {
+		n = b.varTokens
+	}
+	return templateCost{vars: n, count: 1}
+}
+
+func (b *templateBudget) list(list *parse.ListNode) templateCost {
+	var c templateCost
+	if list == nil {
+		return c
+	}
+	for _, node := range list.Nodes {
+		c = c.add(b.node(node))
+	}
+	return c
+}
+
+func (b *templateBudget) node(node parse.Node) templateCost {
+	switch n := node.(type) {
+	case *parse.TextNode:
+		return b.text(string(n.Text))
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return templateCost{} // assignments print nothing
+		}
+		if s, ok := stringLiteral(n.Pipe); ok {
+			return b.text(s)
+		}
+		return b.variable(n.Pipe.String())
+	case *parse.IfNode:
+		return larger(b.list(n.List), b.list(n.ElseList))
+	case *parse.WithNode:
+		return larger(b.list(n.List), b.list(n.ElseList))
+	case *parse.RangeNode:
+		return larger(b.list(n.List).times(b.iterations), b.list(n.ElseList))
+	case *parse.TemplateNode:
+		return b.template(n.Name)
+	case *parse.ListNode:
+		return b.list(n)
+	}
+	// Comments, break and continue print nothing
+	return templateCost{}
+}
+
+func (b *templateBudget) text(s string) templateCost {
+	return templateCost{static: len(b.tok.Encode(s, &llama3.EncodeOptions{}))}
+}
+
+// stringLiteral returns the string an action prints if it is a constant,
+// as in {{"\n"}}.
+func stringLiteral(pipe *parse.PipeNode) (string, bool) {
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	s, ok := pipe.Cmds[0].Args[0].(*parse.StringNode)
+	if !ok {
+		return "", false
+	}
+	return s.Text, true
+}
@@ -0,0 +1,75 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestTemplateBudgets(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	count := func(s string) int { return len(tok.Encode(s, &llama3.EncodeOptions{})) }
+
+	tests := []struct {
+		name   string
+		text   string
+		opts   *TemplateOptions
+		static int
+		vars   int
+		count  int
+	}{
+		{"static", "You are a helpful assistant.", nil, count("You are a helpful assistant."), 0, 0},
+		{"variable", "Answer: {{.Question}}", nil, count("Answer: "), DefaultVarTokens, 1},
+		{"var override", "Answer: {{.Question}}", &TemplateOptions{Vars: map[string]int{".Question": 500}}, count("Answer: "), 500, 1},
+		{"var tokens", "{{.A}}{{.B}}", &TemplateOptions{VarTokens: 10}, 0, 20, 2},
+		{"assignment and comment", "{{$x := .A}}{{/* note */}}", nil, 0, 0, 0},
+		{"string literal", `{{"hello world"}}`, nil, count("hello world"), 0, 0},
+		{"longer branch", "{{if .A}}short{{else}}a much longer branch of text{{end}}", nil, count("a much longer branch of text"), 0, 0},
+		{"variable branch", "{{with .A}}{{.}}{{else}}none{{end}}", nil, 0, DefaultVarTokens, 1},
+		{"range", "{{range .Items}}- {{.}}\n{{end}}", &TemplateOptions{RangeIterations: 3, VarTokens: 5}, 3 * (count("- ") + count("\n")), 15, 3},
+		{"defined template", `{{define "sig"}}Thanks{{end}}Hi {{template "sig"}}`, nil, count("Hi ") + count("Thanks"), 0, 0},
+		{"external template", `{{template "other" .}}`, &TemplateOptions{Vars: map[string]int{"other": 7}}, 0, 7, 1},
+		{"recursive template", `{{define "r"}}x{{template "r"}}{{end}}{{template "r"}}`, &TemplateOptions{VarTokens: 1}, count("x"), 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := TemplateBudgets(tok, "main", tt.text, tt.opts)
+			if err != nil {
+				t.Fatalf("TemplateBudgets failed: %v", err)
+			}
+			r := reports[0]
+			if r.Name != "main" {
+				t.Fatalf("Expected the main template first, got %q", r.Name)
+			}
+			if r.StaticTokens != tt.static || r.VarTokens != tt.vars || r.Vars != tt.count {
+				t.Errorf("Expected %d static, %d var tokens in %d actions, got %+v", tt.static, tt.vars, tt.count, r)
+			}
+			if r.WorstCase != r.StaticTokens+r.VarTokens {
+				t.Errorf("Expected the worst case to be static plus var tokens, got %+v", r)
+			}
+		})
+	}
+}
+
+func TestTemplateBudgetsDefinitions(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	reports, err := TemplateBudgets(tok, "partials", `{{define "b"}}B{{end}}{{define "a"}}A{{end}}`, nil)
+	if err != nil {
+		t.Fatalf("TemplateBudgets failed: %v", err)
+	}
+	if len(reports) != 2 || reports[0].Name != "a" || reports[1].Name != "b" {
+		t.Errorf("Expected reports for a and b, got %+v", reports)
+	}
+
+	if _, err := TemplateBudgets(tok, "bad", "{{if .A}}unterminated", nil); err == nil {
+		t.Error("Expected a parse error")
+	}
+}
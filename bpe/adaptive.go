@@ -0,0 +1,182 @@
+package bpe
+
+import (
+	"math"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+)
+
+// Tuning of AdaptiveLRU.
+const (
+	adaptiveWindow      = 4096  // lookups between capacity adjustments
+	adaptiveTargetRate  = 0.90  // hit rate below which a full cache grows
+	adaptiveMinGain     = 0.005 // hit rate a growth must gain to be kept
+	adaptiveHoldWindows = 8     // windows to wait after a shrink before growing
+	memoryPressureRatio = 0.80  // fraction of the memory limit counted as pressure
+)
+
+// AdaptiveLRU is an LRU cache that sizes itself between a minimum and a
+// maximum capacity from the hit rate it observes, so the capacity need not
+// be tuned per workload.
+//
+// Every 4096 lookups it reviews the last window:
+//   - under memory pressure it halves its capacity, down to the minimum;
+//   - once the entries added by the last growth are in use, if the hit rate
+//     rose by less than half a percentage point they are not paying for
+//     themselves, so it shrinks back and waits a few windows before growing
+//     again;
+//   - otherwise, if it is full and the hit rate is below 90%, it doubles its
+//     capacity, up to the maximum.
+//
+// Memory pressure means the Go runtime is above 80% of its soft memory limit
+// (GOMEMLIMIT or debug.SetMemoryLimit). Without a limit there is no pressure
+// and the capacity is driven by the hit rate alone.
+type AdaptiveLRU struct {
+	lru      *LRUCache
+	min, max int
+	window   int64       // lookups between adjustments
+	pressure func() bool // reports memory pressure
+
+	hits, misses atomic.Int64
+	lookups      atomic.Int64
+
+	mu       sync.Mutex // guards the adjustment state below
+	lastRate float64    // hit rate in the window before the last growth
+	growing  bool       // whether a growth is being evaluated
+	waited   int        // windows spent filling the last growth
+	hold     int        // windows left before growth is allowed again
+}
+
+// NewAdaptiveLRU creates an adaptive LRU cache that starts at minSize entries
+// and grows up to maxSize. Sizes below 1 are raised to 1, and maxSize is
+// raised to minSize if it is smaller.
+func NewAdaptiveLRU(minSize, maxSize int) *AdaptiveLRU {
+	minSize = max(minSize, 1)
+	maxSize = max(maxSize, minSize)
+	return &AdaptiveLRU{
+		lru:      NewLRU(minSize),
+		min:      minSize,
+		max:      maxSize,
+		window:   adaptiveWindow,
+		pressure: memoryPressure,
+	}
+}
+
+// Get retrieves a value from the cache, promoting it to most recently used.
+func (c *AdaptiveLRU) Get(key string) ([]int, bool) {
+	value, ok := c.lru.Get(key)
+	if ok {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+	if c.lookups.Add(1)%c.window == 0 {
+		c.adjust()
+	}
+	return value, ok
+}
+
+// Put adds or updates a value in the cache.
+func (c *AdaptiveLRU) Put(key string, value []int) {
+	c.lru.Put(key, value)
+}
+
+// Len returns the number of cached entries.
+func (c *AdaptiveLRU) Len() int {
+	return c.lru.Len()
+}
+
+// Capacity returns the current maximum number of entries.
+func (c *AdaptiveLRU) Capacity() int {
+	c.lru.mu.RLock()
+	defer c.lru.mu.RUnlock()
+	return c.lru.capacity
+}
+
+// Bounds returns the minimum and maximum capacity.
+func (c *AdaptiveLRU) Bounds() (minSize, maxSize int) {
+	return c.min, c.max
+}
+
+// adjust reviews the window that just ended and resizes the cache.
+func (c *AdaptiveLRU) adjust() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hits, misses := c.hits.Swap(0), c.misses.Swap(0)
+	if hits+misses == 0 {
+		return
+	}
+	rate := float64(hits) / float64(hits+misses)
+	capacity := c.Capacity()
+
+	switch {
+	case c.pressure():
+		c.lru.resize(max(capacity/2, c.min))
+		c.growing = false
+		c.hold = adaptiveHoldWindows
+	case c.growing:
+		// Judge a growth once the new entries are in use
+		if c.lru.Len() < capacity && c.waited < adaptiveHoldWindows {
+			c.waited++
+			return
+		}
+		c.growing = false
+		if rate-c.lastRate < adaptiveMinGain {
+			c.lru.resize(max(capacity/2, c.min))
+			c.hold = adaptiveHoldWindows
+		}
+	case c.hold > 0:
+		c.hold--
+	case rate < adaptiveTargetRate && capacity < c.max && c.lru.Len() >= capacity:
+		c.lru.resize(min(capacity*2, c.max))
+		c.lastRate = rate
+		c.growing = true
+		c.waited = 0
+	}
+}
+
+// resize changes the capacity, evicting the least recently used entries
+// that no longer fit.
+func (c *LRUCache) resize(capacity int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	for c.lru.Len() > capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// memoryMetrics are the runtime metrics read by memoryPressure.
+var memoryMetrics = []string{
+	"/gc/gomemlimit:bytes",
+	"/memory/classes/total:bytes",
+	"/memory/classes/heap/released:bytes",
+}
+
+// memoryPressure reports whether the memory mapped by the Go runtime, less
+// memory returned to the OS, exceeds memoryPressureRatio of the soft memory
+// limit.
+func memoryPressure() bool {
+	samples := make([]metrics.Sample, len(memoryMetrics))
+	for i, name := range memoryMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+	for _, s := range samples {
+		if s.Value.Kind() != metrics.KindUint64 {
+			return false
+		}
+	}
+
+	limit := samples[0].Value.Uint64()
+	if limit == math.MaxInt64 {
+		return false // no limit set
+	}
+	used := samples[1].Value.Uint64() - samples[2].Value.Uint64()
+	return float64(used) > memoryPressureRatio*float64(limit)
+}
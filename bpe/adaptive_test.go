@@ -0,0 +1,111 @@
+package bpe
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// lookup reads key from the cache and stores it on a miss, as the tokenizer
+// does.
+func lookup(c *AdaptiveLRU, key string) {
+	if _, ok := c.Get(key); !ok {
+		c.Put(key, []int{len(key)})
+	}
+}
+
+func newTestAdaptiveLRU(minSize, maxSize int) *AdaptiveLRU {
+	c := NewAdaptiveLRU(minSize, maxSize)
+	c.window = 1000
+	c.pressure = func() bool { return false }
+	return c
+}
+
+// zipfKeys returns n keys drawn from a Zipf distribution over vocab keys,
+// which resembles the skew of pre-tokens in natural text.
+func zipfKeys(n int, vocab uint64) []string {
+	z := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, vocab-1) // #nosec G404 - deterministic test data
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = strconv.FormatUint(z.Uint64(), 10)
+	}
+	return keys
+}
+
+func TestAdaptiveLRU(t *testing.T) {
+	t.Run("grows_under_skewed_load", func(t *testing.T) {
+		c := newTestAdaptiveLRU(16, 4096)
+		for _, key := range zipfKeys(200000, 100000) {
+			lookup(c, key)
+		}
+
+		if got := c.Capacity(); got < 256 || got > 4096 {
+			t.Errorf("Expected capacity to grow to between 256 and 4096, got %d", got)
+		}
+	})
+
+	t.Run("stops_at_max", func(t *testing.T) {
+		c := newTestAdaptiveLRU(16, 64)
+		for _, key := range zipfKeys(100000, 100000) {
+			lookup(c, key)
+		}
+
+		if got := c.Capacity(); got > 64 {
+			t.Errorf("Expected capacity at most 64, got %d", got)
+		}
+		if got := c.Len(); got > 64 {
+			t.Errorf("Expected at most 64 entries, got %d", got)
+		}
+	})
+
+	t.Run("shrinks_when_growth_does_not_help", func(t *testing.T) {
+		c := newTestAdaptiveLRU(16, 1024)
+		maxSeen := 0
+		for i := 0; i < 50000; i++ {
+			lookup(c, strconv.Itoa(i)) // never repeats
+			maxSeen = max(maxSeen, c.Capacity())
+		}
+
+		if maxSeen > 32 {
+			t.Errorf("Expected capacity to stay near the minimum, reached %d", maxSeen)
+		}
+	})
+
+	t.Run("shrinks_under_memory_pressure", func(t *testing.T) {
+		c := newTestAdaptiveLRU(16, 4096)
+		keys := zipfKeys(200000, 100000)
+		for _, key := range keys {
+			lookup(c, key)
+		}
+		grown := c.Capacity()
+
+		c.pressure = func() bool { return true }
+		for _, key := range keys[:20000] {
+			lookup(c, key)
+		}
+
+		if got := c.Capacity(); got != 16 {
+			t.Errorf("Expected capacity to fall from %d to 16, got %d", grown, got)
+		}
+		if got := c.Len(); got > 16 {
+			t.Errorf("Expected at most 16 entries, got %d", got)
+		}
+	})
+
+	t.Run("bounds", func(t *testing.T) {
+		c := NewAdaptiveLRU(0, -5)
+		if got := c.Capacity(); got != 1 {
+			t.Errorf("Expected capacity 1, got %d", got)
+		}
+		if c.max != 1 {
+			t.Errorf("Expected max 1, got %d", c.max)
+		}
+	})
+}
+
+func TestMemoryPressure(t *testing.T) {
+	// The test binary runs without a memory limit, so there is no pressure
+	if memoryPressure() {
+		t.Error("Expected no memory pressure without a memory limit")
+	}
+}
@@ -0,0 +1,48 @@
+package bpe_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/bpe"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func BenchmarkMerge(b *testing.B) {
+	tokenizer, err := llama3.New()
+	if err != nil {
+		b.Skipf("tokenizer not available: %v", err)
+	}
+	p := tokenizer.Processor()
+
+	groups := map[string][]string{
+		"short": {" the", " quick", " brown", " fox", ",", " jumps", " over", " lazy", " dog", "."},
+		"long":  {" internationalization", " antidisestablishment", " supercalifragilistic", " pneumonoultramicroscopic"},
+		"huge":  {" " + strings.Repeat("abcdefghij", 8), strings.Repeat("=", 64)},
+	}
+
+	for name, words := range groups {
+		var pretokens []string
+		for _, word := range words {
+			pretokens = append(pretokens, tokenizer.PreTokenize(word)...)
+		}
+
+		b.Run(name+"/linear", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, pretoken := range pretokens {
+					bpe.LinearMerge(p, bpe.PretokenToIDs(p, pretoken))
+				}
+			}
+		})
+
+		b.Run(name+"/heap", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, pretoken := range pretokens {
+					bpe.HeapMerge(p, bpe.PretokenToIDs(p, pretoken), len(pretoken))
+				}
+			}
+		})
+	}
+}
@@ -1,17 +1,33 @@
-// Package bpe implements Byte Pair Encoding algorithms for tokenization.
+// Package bpe implements the Byte Pair Encoding merge engine shared by the
+// tokenizer implementations in this module.
+//
+// The engine is independent of any particular vocabulary: a tokenizer family
+// supplies its token lookup and a merge table built with BuildMerges or
+// BuildMergesFromPairs, and applies its own pre-tokenization and byte-level
+// encoding before calling Processor.PerformBPE.
 package bpe
 
 import (
 	"container/heap"
-	"strings"
 )
 
+// linearMergeThreshold is the maximum number of initial symbols for which
+// merges are found with a linear scan instead of the priority queue.
+// Most pre-tokens in natural text are short, and for those a quadratic scan
+// is cheaper than building the linked list and heap. BenchmarkMerge shows the
+// linear scan winning up to roughly 20 symbols and losing clearly by 64.
+const linearMergeThreshold = 16
+
 // Processor handles the BPE algorithm implementation.
 type Processor struct {
-	Tokens      []string       // Token ID to text mapping
-	TokenLookup map[string]int // Text to token ID mapping
-	MergeRules  map[string]int // BPE merge rules with priorities
-	Cache       Cache          // Cache for BPE results
+	TokenLookup map[string]int   // Text to token ID mapping
+	Merges      map[uint64]Merge // Merges keyed by PairKey(left, right)
+	Cache       Cache            // Cache for BPE results
+
+	// SkipVocabLookup always applies the merges, even when the whole
+	// pre-token is a vocabulary token. With the trained ranks both give the
+	// same result; with modified ranks only the merges reflect the change.
+	SkipVocabLookup bool
 }
 
 // PerformBPE executes the Byte Pair Encoding algorithm on a pre-token.
@@ -22,9 +38,15 @@ func (p *Processor) PerformBPE(pretoken string) []int {
 			return cached
 		}
 	}
+	return p.PerformBPEMiss(pretoken)
+}
 
+// PerformBPEMiss is PerformBPE for a pre-token the caller has already looked
+// up in the cache without a hit. It skips the lookup, so that caches counting
+// their hit rate see one miss, and caches the result.
+func (p *Processor) PerformBPEMiss(pretoken string) []int {
 	// Check for direct vocabulary match
-	if tokenID, ok := p.TokenLookup[pretoken]; ok {
+	if tokenID, ok := p.TokenLookup[pretoken]; ok && !p.SkipVocabLookup {
 		result := []int{tokenID}
 		if p.Cache != nil {
 			p.Cache.Put(pretoken, result)
@@ -41,9 +63,50 @@ func (p *Processor) PerformBPE(pretoken string) []int {
 		return tokenIDs
 	}
 
+	// Short pre-tokens use a linear scan; longer ones use the priority queue
+	var result []int
+	if len(tokenIDs) <= linearMergeThreshold {
+		result = p.linearMerge(tokenIDs)
+	} else {
+		result = p.heapMerge(tokenIDs, len(pretoken))
+	}
+
+	if p.Cache != nil {
+		p.Cache.Put(pretoken, result)
+	}
+	return result
+}
+
+// linearMerge applies merges by repeatedly scanning all adjacent pairs for the
+// lowest-priority merge (leftmost on ties). It runs in O(n²) but allocates
+// nothing beyond the input slice, which it reuses for the result.
+func (p *Processor) linearMerge(tokenIDs []int) []int {
+	for len(tokenIDs) > 1 {
+		bestPos := -1
+		var best Merge
+		for i := 0; i < len(tokenIDs)-1; i++ {
+			merge, ok := p.Merges[PairKey(tokenIDs[i], tokenIDs[i+1])]
+			if ok && (bestPos < 0 || merge.Rank < best.Rank) {
+				bestPos, best = i, merge
+			}
+		}
+		if bestPos < 0 {
+			break
+		}
+
+		tokenIDs[bestPos] = best.ID
+		tokenIDs = append(tokenIDs[:bestPos+1], tokenIDs[bestPos+2:]...)
+	}
+
+	return tokenIDs
+}
+
+// heapMerge applies merges using a linked list and a priority queue, which
+// scales to long pre-tokens.
+func (p *Processor) heapMerge(tokenIDs []int, pretokenLen int) []int {
 	// Build linked list and priority queue
 	pq := NewPriorityQueue()
-	firstNode := p.buildMergeList(tokenIDs, pq, len(pretoken))
+	firstNode := p.buildMergeList(tokenIDs, pq, pretokenLen)
 
 	// Perform merges
 	for pq.Len() > 0 {
@@ -55,7 +118,7 @@ func (p *Processor) PerformBPE(pretoken string) []int {
 		}
 
 		// Perform the merge
-		firstNode = p.performMerge(leftOfMerge, firstNode, pq, len(pretoken))
+		firstNode = p.performMerge(leftOfMerge, firstNode, pq, pretokenLen)
 	}
 
 	// Collect final token IDs
@@ -64,9 +127,6 @@ func (p *Processor) PerformBPE(pretoken string) []int {
 		result = append(result, node.TokenID)
 	}
 
-	if p.Cache != nil {
-		p.Cache.Put(pretoken, result)
-	}
 	return result
 }
 
@@ -116,27 +176,18 @@ func (p *Processor) addToMergeQueue(leftNode *MergeNode, pq *PriorityQueue, pret
 		return
 	}
 
-	mergeIdentifier := p.getMergeIdentifier(leftNode.TokenID, leftNode.Next.TokenID)
-	mergePrio, ok := p.MergeRules[mergeIdentifier]
+	merge, ok := p.Merges[PairKey(leftNode.TokenID, leftNode.Next.TokenID)]
 	if !ok {
 		return // This merge is not possible
 	}
 
 	// Add position bias to ensure left-to-right processing of equal priority merges
-	leftNode.MergePrio = float64(mergePrio) + float64(leftNode.OrigPos)/float64(pretokenLen)
-	leftNode.MergeToString = strings.ReplaceAll(mergeIdentifier, " ", "")
+	leftNode.MergePrio = float64(merge.Rank) + float64(leftNode.OrigPos)/float64(pretokenLen)
+	leftNode.MergeID = merge.ID
 
 	heap.Push(pq, leftNode)
 }
 
-// getMergeIdentifier creates a merge identifier from two token IDs.
-func (p *Processor) getMergeIdentifier(firstTokenID, secondTokenID int) string {
-	if firstTokenID >= len(p.Tokens) || secondTokenID >= len(p.Tokens) {
-		return ""
-	}
-	return p.Tokens[firstTokenID] + " " + p.Tokens[secondTokenID]
-}
-
 // isValidMerge checks if a merge node is still valid for merging.
 func (p *Processor) isValidMerge(node *MergeNode) bool {
 	return node != nil && !node.Deleted && node.Next != nil && !node.Next.Deleted
@@ -154,15 +205,9 @@ func (p *Processor) performMerge(leftOfMerge *MergeNode, firstNode *MergeNode, p
 	}
 
 	// Create merged node
-	mergedTokenID, ok := p.TokenLookup[leftOfMerge.MergeToString]
-	if !ok {
-		// This shouldn't happen with valid merge data
-		return firstNode
-	}
-
 	resultOfMerge := &MergeNode{
 		OrigPos: leftOfMerge.OrigPos,
-		TokenID: mergedTokenID,
+		TokenID: leftOfMerge.MergeID,
 		Prev:    leftOfMerge.Prev,
 		Next:    leftOfMerge.Next.Next,
 	}
@@ -0,0 +1,87 @@
+package bpe
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/agentstation/tokenizer"
+)
+
+// newTestProcessor creates a processor over a small synthetic vocabulary.
+func newTestProcessor() *Processor {
+	tokens := []string{"a", "b", "c", "ab", "bc", "abc", "ca", "aa", "aab", "cab"}
+	lookup := make(map[string]int, len(tokens))
+	for id, token := range tokens {
+		lookup[token] = id
+	}
+
+	return &Processor{
+		TokenLookup: lookup,
+		Merges: BuildMerges(map[string]int{
+			"a b":  1,
+			"b c":  2,
+			"a a":  3,
+			"ab c": 4,
+			"c a":  5,
+			"a ab": 6,
+			"c ab": 7,
+		}, lookup),
+	}
+}
+
+func TestMergeStrategiesAgree(t *testing.T) {
+	p := newTestProcessor()
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "c"}
+
+	for i := 0; i < 2000; i++ {
+		length := 1 + rng.Intn(20)
+		pretoken := ""
+		for j := 0; j < length; j++ {
+			pretoken += alphabet[rng.Intn(len(alphabet))]
+		}
+
+		linear := p.linearMerge(p.pretokenToIDs(pretoken))
+		heapResult := p.heapMerge(p.pretokenToIDs(pretoken), len(pretoken))
+
+		if !tokenizer.EqualTokens(linear, heapResult) {
+			t.Fatalf("pretoken %q: linear=%v heap=%v", pretoken, linear, heapResult)
+		}
+	}
+}
+
+func TestPerformBPE(t *testing.T) {
+	p := newTestProcessor()
+
+	tests := []struct {
+		pretoken string
+		want     []int
+	}{
+		{"a", []int{0}},
+		{"abc", []int{5}},
+		{"aab", []int{8}},
+		{"cabc", []int{2, 5}},
+		{"abcabcabcabc", []int{5, 5, 5, 5}},
+	}
+
+	for _, tt := range tests {
+		if got := p.PerformBPE(tt.pretoken); !tokenizer.EqualTokens(got, tt.want) {
+			t.Errorf("PerformBPE(%q) = %v, want %v", tt.pretoken, got, tt.want)
+		}
+	}
+}
+
+func TestSkipVocabLookup(t *testing.T) {
+	p := newTestProcessor()
+	delete(p.Merges, PairKey(p.TokenLookup["a"], p.TokenLookup["b"]))
+
+	// "abc" is in the vocabulary but no longer reachable by merges
+	if got := p.PerformBPE("abc"); !tokenizer.EqualTokens(got, []int{5}) {
+		t.Errorf("PerformBPE(\"abc\") = %v, want [5]", got)
+	}
+
+	p.SkipVocabLookup = true
+	if got := p.PerformBPE("abc"); !tokenizer.EqualTokens(got, []int{0, 4}) {
+		t.Errorf("PerformBPE(\"abc\") with SkipVocabLookup = %v, want [0 4]", got)
+	}
+}
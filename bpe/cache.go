@@ -75,6 +75,20 @@ func (c *LRUCache) Put(key string, value []int) {
 	}
 }
 
+// Len returns the number of cached entries.
+func (c *LRUCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lru.Len()
+}
+
+// Capacity returns the maximum number of entries, 0 if unlimited.
+func (c *LRUCache) Capacity() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.capacity
+}
+
 // SimpleCache wraps a regular map for unlimited caching (backward compatibility).
 type SimpleCache struct {
 	cache map[string][]int
@@ -102,3 +116,10 @@ func (c *SimpleCache) Put(key string, value []int) {
 	defer c.mu.Unlock()
 	c.cache[key] = value
 }
+
+// Len returns the number of cached entries.
+func (c *SimpleCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.cache)
+}
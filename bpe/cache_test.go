@@ -34,6 +34,10 @@ func TestLRUCache(t *testing.T) {
 		if _, ok := cache.Get("key4"); !ok {
 			t.Error("Expected key4 to exist")
 		}
+
+		if cache.Len() != 3 {
+			t.Errorf("Expected length 3, got %d", cache.Len())
+		}
 	})
 
 	t.Run("lru_ordering", func(t *testing.T) {
@@ -114,4 +118,8 @@ func TestSimpleCache(t *testing.T) {
 	if ok {
 		t.Error("Expected missing key to not exist")
 	}
+
+	if cache.Len() != 1 {
+		t.Errorf("Expected length 1, got %d", cache.Len())
+	}
 }
@@ -0,0 +1,8 @@
+package bpe
+
+// Merge strategies exposed for benchmarks in the external test package.
+var (
+	PretokenToIDs = (*Processor).pretokenToIDs
+	LinearMerge   = (*Processor).linearMerge
+	HeapMerge     = (*Processor).heapMerge
+)
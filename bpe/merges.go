@@ -0,0 +1,69 @@
+package bpe
+
+import "strings"
+
+// Merge describes the result of merging an adjacent token pair.
+type Merge struct {
+	Rank int // Merge priority (lower is applied first)
+	ID   int // Token ID of the merged token
+}
+
+// PairKey packs two token IDs into a single map key.
+// Token IDs must fit in 32 bits.
+func PairKey(left, right int) uint64 {
+	return uint64(uint32(left))<<32 | uint64(uint32(right)) // #nosec G115 - token IDs fit in 32 bits
+}
+
+// BuildMerges converts string-keyed merge rules ("left right" -> priority)
+// into a table keyed by token ID pairs. Rules whose parts or merged result
+// are not in the vocabulary are skipped.
+//
+// Keying by ID pairs avoids building and hashing a merge identifier string
+// for every candidate pair on the encoding hot path.
+func BuildMerges(rules map[string]int, tokenLookup map[string]int) map[uint64]Merge {
+	merges := make(map[uint64]Merge, len(rules))
+	for identifier, rank := range rules {
+		left, right, ok := strings.Cut(identifier, " ")
+		if !ok {
+			continue
+		}
+
+		leftID, ok := tokenLookup[left]
+		if !ok {
+			continue
+		}
+		rightID, ok := tokenLookup[right]
+		if !ok {
+			continue
+		}
+		mergedID, ok := tokenLookup[left+right]
+		if !ok {
+			continue
+		}
+
+		merges[PairKey(leftID, rightID)] = Merge{Rank: rank, ID: mergedID}
+	}
+	return merges
+}
+
+// BuildMergesFromPairs builds a merge table from a flat list of token ID pairs
+// in priority order, as produced by vocabulary.DecompressMergePairs.
+// The merge at pair index i has rank i+1. Pairs referencing unknown token IDs
+// or producing a token that is not in the vocabulary are skipped.
+func BuildMergesFromPairs(pairs []int, tokens []string, tokenLookup map[string]int) map[uint64]Merge {
+	merges := make(map[uint64]Merge, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		left, right := pairs[i], pairs[i+1]
+		if left >= len(tokens) || right >= len(tokens) {
+			continue
+		}
+
+		mergedID, ok := tokenLookup[tokens[left]+tokens[right]]
+		if !ok {
+			continue
+		}
+
+		merges[PairKey(left, right)] = Merge{Rank: i/2 + 1, ID: mergedID}
+	}
+	return merges
+}
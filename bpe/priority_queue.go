@@ -4,14 +4,14 @@ import "container/heap"
 
 // MergeNode represents a position in the token sequence that can be merged.
 type MergeNode struct {
-	OrigPos       int     // Original position in the sequence
-	TokenID       int     // Token ID at this position
-	MergePrio     float64 // Merge priority (lower is better)
-	MergeToString string  // Result of merging with next token
-	Prev          *MergeNode
-	Next          *MergeNode
-	Deleted       bool // Whether this node has been deleted
-	HeapIndex     int  // Index in the heap (for container/heap)
+	OrigPos   int     // Original position in the sequence
+	TokenID   int     // Token ID at this position
+	MergePrio float64 // Merge priority (lower is better)
+	MergeID   int     // Token ID resulting from merging with next token
+	Prev      *MergeNode
+	Next      *MergeNode
+	Deleted   bool // Whether this node has been deleted
+	HeapIndex int  // Index in the heap (for container/heap)
 }
 
 // PriorityQueue implements a min-heap of merge nodes.
@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer"
+	"github.com/agentstation/tokenizer/tokenio"
+)
+
+// newChunkStoreCmd creates the chunk-store command.
+func newChunkStoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "chunk-store",
+		Short: "Store token streams as deduplicated content-defined chunks",
+		Long: `Store tokenized corpora in a content-addressed directory.
+
+put splits a token stream into content-defined chunks, writes each chunk not
+already in the store, and prints a manifest of chunk hashes. Passages shared
+between corpora, or between versions of one corpus, produce the same chunks
+and are stored once. get reassembles a stream from a manifest.
+
+Chunks are stored compressed under <dir>/<hash[:2]>/<hash>, where hash is
+the SHA-256 of the chunk's token IDs as little-endian uint32s.`,
+	}
+
+	cmd.AddCommand(newChunkStorePutCmd(), newChunkStoreGetCmd())
+	return cmd
+}
+
+func newChunkStorePutCmd() *cobra.Command {
+	var dir, from string
+	opts := tokenio.DefaultChunkOptions()
+
+	cmd := &cobra.Command{
+		Use:     "put [file]",
+		Short:   "Add a token stream to the store and print its manifest",
+		Example: `  tokenizer chunk-store put --dir store --from u32 corpus.u32 > corpus.manifest`,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := tokenio.ParseFormat(from)
+			if err != nil {
+				return err
+			}
+			in, closeIn, err := openInput(cmd, args)
+			if err != nil {
+				return err
+			}
+			defer closeIn()
+
+			r, err := tokenio.NewReader(in, format)
+			if err != nil {
+				return err
+			}
+			chunker, err := tokenio.NewChunker(r, opts)
+			if err != nil {
+				return err
+			}
+
+			manifest := bufio.NewWriter(cmd.OutOrStdout())
+			var chunks, stored, tokens int
+			for {
+				chunk, err := chunker.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					return err
+				}
+
+				hash := chunkHash(chunk)
+				added, err := storeChunk(dir, hash, chunk)
+				if err != nil {
+					return err
+				}
+				if added {
+					stored++
+				}
+				chunks++
+				tokens += len(chunk)
+				fmt.Fprintln(manifest, hash)
+			}
+			if err := manifest.Flush(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "%d tokens in %d chunks, %d new\n", tokens, chunks, stored)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Store directory (required)")
+	cmd.Flags().StringVar(&from, "from", "", "Input format: u32, uvarint, ndjson, compressed (required)")
+	cmd.Flags().IntVar(&opts.MinSize, "min", opts.MinSize, "Minimum chunk size in tokens")
+	cmd.Flags().IntVar(&opts.AvgSize, "avg", opts.AvgSize, "Average chunk size in tokens")
+	cmd.Flags().IntVar(&opts.MaxSize, "max", opts.MaxSize, "Maximum chunk size in tokens")
+	_ = cmd.MarkFlagRequired("dir")
+	_ = cmd.MarkFlagRequired("from")
+
+	return cmd
+}
+
+func newChunkStoreGetCmd() *cobra.Command {
+	var dir, to string
+
+	cmd := &cobra.Command{
+		Use:     "get [manifest]",
+		Short:   "Write the token stream described by a manifest",
+		Example: `  tokenizer chunk-store get --dir store --to u32 corpus.manifest > corpus.u32`,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := tokenio.ParseFormat(to)
+			if err != nil {
+				return err
+			}
+			in, closeIn, err := openInput(cmd, args)
+			if err != nil {
+				return err
+			}
+			defer closeIn()
+
+			w, err := tokenio.NewWriter(cmd.OutOrStdout(), format)
+			if err != nil {
+				return err
+			}
+			scanner := bufio.NewScanner(in)
+			for scanner.Scan() {
+				hash := strings.TrimSpace(scanner.Text())
+				if hash == "" {
+					continue
+				}
+				chunk, err := loadChunk(dir, hash)
+				if err != nil {
+					return err
+				}
+				if err := w.Write(chunk); err != nil {
+					return err
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return err
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&dir, "dir", "", "Store directory (required)")
+	cmd.Flags().StringVar(&to, "to", "", "Output format: u32, uvarint, ndjson, compressed (required)")
+	_ = cmd.MarkFlagRequired("dir")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+// openInput opens the file named by args, or returns stdin if there is none.
+func openInput(cmd *cobra.Command, args []string) (io.Reader, func(), error) {
+	if len(args) == 0 {
+		return cmd.InOrStdin(), func() {}, nil
+	}
+	f, err := os.Open(args[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open input: %w", err)
+	}
+	return f, func() { _ = f.Close() }, nil
+}
+
+// chunkHash returns the hex SHA-256 of tokens as little-endian uint32s.
+func chunkHash(tokens []int) string {
+	h := sha256.New()
+	var b [4]byte
+	for _, id := range tokens {
+		binary.LittleEndian.PutUint32(b[:], uint32(id)) // #nosec G115 - IDs are hashed, not stored
+		_, _ = h.Write(b[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func chunkPath(dir, hash string) string {
+	return filepath.Join(dir, hash[:2], hash)
+}
+
+// storeChunk writes a chunk unless the store has it and reports whether it
+// was written. The file is renamed into place so readers never see a partial
+// chunk.
+func storeChunk(dir, hash string, tokens []int) (bool, error) {
+	path := chunkPath(dir, hash)
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { // #nosec G301 - store directories are shared
+		return false, fmt.Errorf("failed to create chunk directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), hash+".tmp*")
+	if err != nil {
+		return false, fmt.Errorf("failed to write chunk: %w", err)
+	}
+	_, err = tmp.Write(tokenizer.CompressTokens(tokens))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return false, fmt.Errorf("failed to write chunk: %w", err)
+	}
+	return true, nil
+}
+
+// loadChunk reads a chunk and checks it against its hash.
+func loadChunk(dir, hash string) ([]int, error) {
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+		return nil, fmt.Errorf("invalid chunk hash %q in manifest", hash)
+	}
+	data, err := os.ReadFile(chunkPath(dir, hash)) // #nosec G304 - path is built from a validated hash
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk: %w", err)
+	}
+	tokens, err := tokenizer.DecompressTokens(data)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s: %w", hash, err)
+	}
+	if chunkHash(tokens) != hash {
+		return nil, fmt.Errorf("chunk %s does not match its hash", hash)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,162 @@
+// Package cli builds the tokenizer command-line tool and lets Go modules add
+// tokenizer backends to it.
+//
+// A backend is a top-level subcommand, such as "tokenizer llama3". Backends
+// register a constructor from an init function, the same way database/sql
+// drivers do:
+//
+//	package gpt2cmd
+//
+//	func init() {
+//		cli.Register("gpt2", Command)
+//	}
+//
+// A binary includes a backend by importing its package, usually for side
+// effects only. The core tokenizer binary imports only the built-in backends;
+// cmd/tokenizer-gen generates a main package that adds others, so a binary
+// with model-specific backends can be built without changing this module.
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]func() *cobra.Command)
+)
+
+// Register makes a backend available under the given subcommand name.
+// newCommand is called once per root command and must return a command whose
+// name is name. Register panics if name is empty, newCommand is nil, or the
+// name is already registered.
+func Register(name string, newCommand func() *cobra.Command) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("cli: Register with empty name")
+	}
+	if newCommand == nil {
+		panic("cli: Register " + name + " with nil command")
+	}
+	if _, dup := registry[name]; dup {
+		panic("cli: Register called twice for " + name)
+	}
+	registry[name] = newCommand
+}
+
+// Backends returns the names of the registered backends in sorted order.
+func Backends() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BuildInfo describes the binary for the version command. Main packages set
+// these from linker flags; empty fields are not printed.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	GoVersion string
+	BuiltBy   string
+}
+
+// NewRootCommand returns the tokenizer root command with the built-in version
+// and completion commands and one subcommand per registered backend.
+// It returns an error if a backend's command does not match its registered
+// name or collides with a built-in command.
+func NewRootCommand(info BuildInfo) (*cobra.Command, error) {
+	root := &cobra.Command{
+		Use:   "tokenizer",
+		Short: "A multi-model tokenizer CLI tool",
+		Long: `Tokenizer is a CLI tool for tokenizing text using various language models.
+
+This tool provides a unified interface for working with different tokenizer
+implementations. Each tokenizer is available as a subcommand with its own
+set of operations, listed under Available Commands below.
+
+Common operations available for tokenizers:
+  - encode: Convert text to token IDs
+  - decode: Convert token IDs back to text
+  - info:   Display tokenizer information
+
+Tokenizer config files can be checked with the config command. Stored token
+IDs can be converted between formats with the transcode command and kept in
+a deduplicated store with the chunk-store command.`,
+		Example: `  # Encode text with Llama 3
+  tokenizer llama3 encode "Hello, world!"
+  
+  # Decode tokens
+  tokenizer llama3 decode 1234 5678
+  
+  # Encode a large file
+  cat large_file.txt | tokenizer llama3
+  
+  # Get tokenizer info
+  tokenizer llama3 info
+
+  # Validate a tokenizer config file
+  tokenizer config validate tokenizer.json`,
+		SilenceUsage: true,
+	}
+	root.AddCommand(newVersionCmd(info), newCompletionCmd(), newTranscodeCmd(), newChunkStoreCmd(), newSampleCmd())
+
+	for _, name := range Backends() {
+		registryMu.Lock()
+		newCommand := registry[name]
+		registryMu.Unlock()
+
+		cmd := newCommand()
+		if cmd == nil || cmd.Name() != name {
+			return nil, fmt.Errorf("cli: backend %q returned a command with a different name", name)
+		}
+		for _, existing := range root.Commands() {
+			if existing.Name() == name {
+				return nil, fmt.Errorf("cli: backend %q conflicts with a built-in command", name)
+			}
+		}
+		root.AddCommand(cmd)
+	}
+
+	return root, nil
+}
+
+// newVersionCmd creates the version command.
+func newVersionCmd(info BuildInfo) *cobra.Command {
+	return &cobra.Command{
+		Use:   "version",
+		Short: "Print version information",
+		Run: func(cmd *cobra.Command, _ []string) {
+			out := cmd.OutOrStdout()
+			version := info.Version
+			if version == "" {
+				version = "dev"
+			}
+			fmt.Fprintf(out, "tokenizer version %s\n", version)
+			if info.Commit != "" {
+				fmt.Fprintf(out, "  commit:     %s\n", info.Commit)
+			}
+			if info.BuildDate != "" {
+				fmt.Fprintf(out, "  built:      %s\n", info.BuildDate)
+			}
+			if info.GoVersion != "" {
+				fmt.Fprintf(out, "  go version: %s\n", info.GoVersion)
+			}
+			if info.BuiltBy != "" {
+				fmt.Fprintf(out, "  built by:   %s\n", info.BuiltBy)
+			}
+		},
+	}
+}
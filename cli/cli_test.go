@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/tokenio"
+)
+
+// registerForTest registers a backend and removes it when the test ends.
+func registerForTest(t *testing.T, name string, newCommand func() *cobra.Command) {
+	t.Helper()
+	Register(name, newCommand)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		delete(registry, name)
+		registryMu.Unlock()
+	})
+}
+
+func TestRegister(t *testing.T) {
+	registerForTest(t, "testbackend", func() *cobra.Command {
+		return &cobra.Command{
+			Use: "testbackend",
+			Run: func(cmd *cobra.Command, args []string) {
+				cmd.Print(strings.Join(args, ","))
+			},
+		}
+	})
+
+	if names := Backends(); len(names) != 1 || names[0] != "testbackend" {
+		t.Fatalf("Expected [testbackend], got %v", names)
+	}
+
+	root, err := NewRootCommand(BuildInfo{Version: "1.2.3"})
+	if err != nil {
+		t.Fatalf("NewRootCommand failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"testbackend", "a", "b"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if out.String() != "a,b" {
+		t.Errorf("Expected %q, got %q", "a,b", out.String())
+	}
+
+	out.Reset()
+	root.SetArgs([]string{"version"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if out.String() != "tokenizer version 1.2.3\n" {
+		t.Errorf("Expected only the version line, got %q", out.String())
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	registerForTest(t, "dup", func() *cobra.Command { return &cobra.Command{Use: "dup"} })
+
+	tests := []struct {
+		name       string
+		newCommand func() *cobra.Command
+	}{
+		{"", func() *cobra.Command { return nil }},
+		{"nilcommand", nil},
+		{"dup", func() *cobra.Command { return nil }},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected Register(%q) to panic", tt.name)
+				}
+			}()
+			Register(tt.name, tt.newCommand)
+		}()
+	}
+}
+
+func TestNewRootCommandErrors(t *testing.T) {
+	t.Run("name mismatch", func(t *testing.T) {
+		registerForTest(t, "gpt2", func() *cobra.Command { return &cobra.Command{Use: "bert"} })
+		if _, err := NewRootCommand(BuildInfo{}); err == nil {
+			t.Error("Expected error for a command named differently from its backend")
+		}
+	})
+
+	t.Run("built-in conflict", func(t *testing.T) {
+		registerForTest(t, "version", func() *cobra.Command { return &cobra.Command{Use: "version"} })
+		if _, err := NewRootCommand(BuildInfo{}); err == nil {
+			t.Error("Expected error for a backend named like a built-in command")
+		}
+	})
+}
+
+func TestTranscodeCommand(t *testing.T) {
+	root, err := NewRootCommand(BuildInfo{})
+	if err != nil {
+		t.Fatalf("NewRootCommand failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetIn(strings.NewReader("[9906,11]\n[1917]\n"))
+	root.SetArgs([]string{"transcode", "--from", "ndjson", "--to", "u32"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if want := "\xb2\x26\x00\x00\x0b\x00\x00\x00\x7d\x07\x00\x00"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	root.SetArgs([]string{"transcode", "--from", "ndjson", "--to", "csv"})
+	if err := root.Execute(); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestChunkStoreCommand(t *testing.T) {
+	dir := t.TempDir()
+	var input strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&input, "[%d]\n", (i*7919)%128000)
+	}
+
+	put := func() (string, string) {
+		root, err := NewRootCommand(BuildInfo{})
+		if err != nil {
+			t.Fatalf("NewRootCommand failed: %v", err)
+		}
+		var out, errOut bytes.Buffer
+		root.SetOut(&out)
+		root.SetErr(&errOut)
+		root.SetIn(strings.NewReader(input.String()))
+		root.SetArgs([]string{"chunk-store", "put", "--dir", dir, "--from", "ndjson", "--min", "100", "--avg", "500", "--max", "2000"})
+		if err := root.Execute(); err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+		return out.String(), errOut.String()
+	}
+
+	manifest, summary := put()
+	if !strings.HasPrefix(summary, "5000 tokens in ") {
+		t.Errorf("Expected a summary of 5000 tokens, got %q", summary)
+	}
+	if _, summary := put(); !strings.HasSuffix(summary, " 0 new\n") {
+		t.Errorf("Expected no new chunks on the second put, got %q", summary)
+	}
+
+	root, err := NewRootCommand(BuildInfo{})
+	if err != nil {
+		t.Fatalf("NewRootCommand failed: %v", err)
+	}
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetIn(strings.NewReader(manifest))
+	root.SetArgs([]string{"chunk-store", "get", "--dir", dir, "--to", "uvarint"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+
+	var want bytes.Buffer
+	if _, err := tokenio.Transcode(&want, tokenio.Uvarint, strings.NewReader(input.String()), tokenio.NDJSON); err != nil {
+		t.Fatalf("Transcode failed: %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), want.Bytes()) {
+		t.Error("Expected get to restore the stream")
+	}
+}
+
+func TestSampleCommand(t *testing.T) {
+	dir := t.TempDir()
+	corpus := filepath.Join(dir, "corpus.tok")
+	index := filepath.Join(dir, "corpus.idx")
+
+	var input strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&input, "[%d,%d,%d]\n", 3*i, 3*i+1, 3*i+2)
+	}
+	root, err := NewRootCommand(BuildInfo{})
+	if err != nil {
+		t.Fatalf("NewRootCommand failed: %v", err)
+	}
+	root.SetIn(strings.NewReader(input.String()))
+	root.SetArgs([]string{"transcode", "--from", "ndjson", "--to", "compressed", "-O", corpus, "--index", index, "--index-interval", "50"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("transcode failed: %v", err)
+	}
+
+	root, err = NewRootCommand(BuildInfo{})
+	if err != nil {
+		t.Fatalf("NewRootCommand failed: %v", err)
+	}
+	var out bytes.Buffer
+	root.SetOut(&out)
+	root.SetArgs([]string{"sample", "--index", index, "--count", "5", "--length", "7", "--seed", "1", corpus})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("sample failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Expected 5 excerpts, got %d", len(lines))
+	}
+	for _, line := range lines {
+		var excerpt []int
+		if err := json.Unmarshal([]byte(line), &excerpt); err != nil {
+			t.Fatalf("Invalid excerpt %q: %v", line, err)
+		}
+		if len(excerpt) != 7 {
+			t.Fatalf("Expected 7 tokens, got %d", len(excerpt))
+		}
+		// The corpus counts up from 0, so excerpts are consecutive IDs
+		for i := 1; i < len(excerpt); i++ {
+			if excerpt[i] != excerpt[0]+i {
+				t.Errorf("Expected consecutive IDs, got %v", excerpt)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Package clitest runs the tokenizer CLI in-process for functional tests,
+// with injected standard streams instead of a spawned binary and a real
+// console. Packagers can test a build of the CLI, including its registered
+// backends, on any platform:
+//
+//	import (
+//		"github.com/agentstation/tokenizer/cli/clitest"
+//		_ "github.com/agentstation/tokenizer/llama3/cmd/llama3"
+//	)
+//
+//	func TestEncode(t *testing.T) {
+//		res := clitest.Run([]string{"llama3", "encode"}, clitest.WithStdin(strings.NewReader("Hello")))
+//		if res.Err != nil || res.Stdout != "128000 9906 128001\n" {
+//			t.Errorf("unexpected result: %+v", res)
+//		}
+//	}
+//
+// Commands see standard input as a terminal unless WithStdin pipes input to
+// them, as when the CLI is typed at a prompt without redirection. Output is
+// captured and is not a terminal unless WithTerminalOutput is given.
+//
+// Runs must not execute concurrently: commands may keep flag values in
+// package variables.
+package clitest
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/agentstation/tokenizer/cli"
+)
+
+// Terminal is a fake terminal. Commands that check whether their input or
+// output is a terminal, with cli.IsTerminal, treat it as one. Reads return
+// the input it was created with; writes are kept in the buffer.
+type Terminal struct {
+	bytes.Buffer
+}
+
+// NewTerminal returns a terminal from which commands read input, as if it
+// had been typed.
+func NewTerminal(input string) *Terminal {
+	t := &Terminal{}
+	t.WriteString(input)
+	return t
+}
+
+// IsTerminal reports true.
+func (t *Terminal) IsTerminal() bool {
+	return true
+}
+
+// Result is the outcome of a run.
+type Result struct {
+	Stdout string
+	Stderr string
+	Err    error // the error the command returned, as main prints it
+}
+
+// ExitCode returns the exit status the tokenizer binary would have.
+func (r Result) ExitCode() int {
+	if r.Err != nil {
+		return 1
+	}
+	return 0
+}
+
+// Option configures a run.
+type Option func(*run)
+
+type run struct {
+	stdin    io.Reader
+	terminal bool
+	info     cli.BuildInfo
+}
+
+// WithStdin pipes r to the command's standard input.
+func WithStdin(r io.Reader) Option {
+	return func(c *run) {
+		c.stdin = r
+	}
+}
+
+// WithTerminalInput makes standard input a terminal on which input has
+// been typed.
+func WithTerminalInput(input string) Option {
+	return func(c *run) {
+		c.stdin = NewTerminal(input)
+	}
+}
+
+// WithTerminalOutput makes standard output and error terminals, so commands
+// may color their output. NO_COLOR and TERM=dumb still disable colors.
+func WithTerminalOutput() Option {
+	return func(c *run) {
+		c.terminal = true
+	}
+}
+
+// WithBuildInfo sets the build information the version command reports.
+func WithBuildInfo(info cli.BuildInfo) Option {
+	return func(c *run) {
+		c.info = info
+	}
+}
+
+// Run runs the tokenizer CLI with args, which exclude the program name, and
+// returns its output. Commands are those of cli.NewRootCommand: the built-in
+// commands and the backends registered by the test binary's imports.
+func Run(args []string, opts ...Option) Result {
+	c := &run{stdin: NewTerminal("")}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	root, err := cli.NewRootCommand(c.info)
+	if err != nil {
+		return Result{Err: err}
+	}
+
+	var stdout, stderr io.ReadWriter = &bytes.Buffer{}, &bytes.Buffer{}
+	if c.terminal {
+		stdout, stderr = &Terminal{}, &Terminal{}
+	}
+	root.SetIn(c.stdin)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+	root.SetArgs(args)
+	err = root.Execute()
+
+	out, _ := io.ReadAll(stdout)
+	errOut, _ := io.ReadAll(stderr)
+	return Result{Stdout: string(out), Stderr: string(errOut), Err: err}
+}
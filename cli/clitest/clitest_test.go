@@ -0,0 +1,62 @@
+package clitest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/cli/clitest"
+	_ "github.com/agentstation/tokenizer/llama3/cmd/llama3"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		opts     []clitest.Option
+		stdout   string // substring of the expected output
+		stderr   string // substring of the expected error output
+		exitCode int
+	}{
+		{"piped stdin", []string{"llama3", "encode"}, []clitest.Option{clitest.WithStdin(strings.NewReader("Hello"))}, "128000 9906 128001\n", "", 0},
+		{"implicit encode of piped stdin", []string{"llama3"}, []clitest.Option{clitest.WithStdin(strings.NewReader("Hello"))}, "128000 9906 128001\n", "", 0},
+		{"terminal stdin shows help", []string{"llama3"}, nil, "Usage:", "", 0},
+		{"typed input", []string{"llama3", "decode"}, []clitest.Option{clitest.WithTerminalInput("9906 11 1917\n")}, "Hello, world", "", 0},
+		{"count", []string{"llama3", "count", "--bos=false", "--eos=false"}, []clitest.Option{clitest.WithStdin(strings.NewReader("Hello, world!"))}, "4", "", 0},
+		{"version", []string{"version"}, []clitest.Option{clitest.WithBuildInfo(cli.BuildInfo{Version: "1.2.3"})}, "1.2.3", "", 0},
+		{"error", []string{"llama3", "decode", "x"}, nil, "", "invalid token ID", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := clitest.Run(tt.args, tt.opts...)
+			if res.ExitCode() != tt.exitCode {
+				t.Fatalf("Expected exit code %d, got %d: %v", tt.exitCode, res.ExitCode(), res.Err)
+			}
+			if !strings.Contains(res.Stdout, tt.stdout) {
+				t.Errorf("Expected stdout containing %q, got %q", tt.stdout, res.Stdout)
+			}
+			if !strings.Contains(res.Stderr, tt.stderr) {
+				t.Errorf("Expected stderr containing %q, got %q", tt.stderr, res.Stderr)
+			}
+		})
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+
+	term := clitest.NewTerminal("input")
+	if !cli.IsTerminal(term) || !cli.ColorEnabled(term) {
+		t.Error("Expected a fake terminal to be a terminal with colors")
+	}
+	if cli.IsTerminal(strings.NewReader("")) {
+		t.Error("Expected a reader not to be a terminal")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if cli.ColorEnabled(term) {
+		t.Error("Expected NO_COLOR to disable colors")
+	}
+}
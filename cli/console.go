@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"io"
+	"os"
+)
+
+// ANSI escape sequences used when ColorEnabled reports true.
+const (
+	ColorReset = "\x1b[0m"
+	ColorRed   = "\x1b[31m"
+	ColorGreen = "\x1b[32m"
+)
+
+// ColorEnabled reports whether ANSI colors may be written to w. Colors are
+// used only when w is a terminal (see IsTerminal), NO_COLOR is unset or empty, and TERM is not
+// "dumb". On Windows the console must also accept escape sequences, which
+// older consoles do not; redirected output never gets colors, so counts and
+// token IDs written to files or pipes stay free of escape codes.
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	if t, ok := w.(terminal); ok {
+		return t.IsTerminal()
+	}
+	f, ok := w.(*os.File)
+	if !ok || !IsTerminal(f) {
+		return false
+	}
+	return enableVirtualTerminal(f)
+}
+
+// terminal is implemented by fake terminals, such as those of package
+// clitest, which stand in for a console in tests.
+type terminal interface {
+	IsTerminal() bool
+}
+
+// IsTerminal reports whether v, a command's input or output, is a terminal
+// rather than a file or pipe: an *os.File of a character device, or a fake
+// terminal whose IsTerminal method reports true.
+func IsTerminal(v any) bool {
+	switch v := v.(type) {
+	case terminal:
+		return v.IsTerminal()
+	case *os.File:
+		info, err := v.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+	return false
+}
+
+// Colorize wraps s in the given color if ColorEnabled(w) is true.
+func Colorize(w io.Writer, color, s string) string {
+	if !ColorEnabled(w) {
+		return s
+	}
+	return color + s + ColorReset
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package cli
+
+import (
+	"os"
+)
+
+// enableVirtualTerminal reports whether the terminal behind f accepts escape
+// sequences, which all supported terminals outside Windows do.
+func enableVirtualTerminal(_ *os.File) bool {
+	return true
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Line ending modes accepted by NormalizeInput and the --crlf flag.
+const (
+	CRLFAuto = "auto" // convert CRLF to LF on Windows, keep it elsewhere
+	CRLFKeep = "keep" // leave line endings unchanged
+	CRLFLF   = "lf"   // convert CRLF to LF
+)
+
+// NormalizeInput wraps r so that text piped on Windows tokenizes the same as
+// on other systems:
+//
+//   - Input starting with a UTF-16 byte order mark, as written by Windows
+//     PowerShell and by cmd's type command for UTF-16 files, is converted to
+//     UTF-8. Without the conversion every character would be split into bytes.
+//   - CRLF line endings are converted to LF according to crlf. Lone CR
+//     characters are kept.
+//
+// Interactive console input needs no conversion: Go already reads the Windows
+// console as UTF-8.
+func NormalizeInput(r io.Reader, crlf string) (io.Reader, error) {
+	convert, err := convertCRLF(crlf)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(r)
+	bom, _ := br.Peek(2)
+	switch {
+	case len(bom) == 2 && bom[0] == 0xFF && bom[1] == 0xFE:
+		_, _ = br.Discard(2)
+		r = &utf16Reader{r: br, littleEndian: true}
+	case len(bom) == 2 && bom[0] == 0xFE && bom[1] == 0xFF:
+		_, _ = br.Discard(2)
+		r = &utf16Reader{r: br}
+	default:
+		r = br
+	}
+
+	if convert {
+		if br, ok := r.(*bufio.Reader); ok {
+			r = &crlfReader{r: br}
+		} else {
+			r = &crlfReader{r: bufio.NewReader(r)}
+		}
+	}
+	return r, nil
+}
+
+// NormalizeText applies the line ending conversion of NormalizeInput to text
+// given on the command line.
+func NormalizeText(text, crlf string) (string, error) {
+	convert, err := convertCRLF(crlf)
+	if err != nil || !convert {
+		return text, err
+	}
+	return strings.ReplaceAll(text, "\r\n", "\n"), nil
+}
+
+// convertCRLF reports whether a --crlf mode converts line endings.
+func convertCRLF(mode string) (bool, error) {
+	switch mode {
+	case CRLFAuto, "":
+		return runtime.GOOS == "windows", nil
+	case CRLFKeep:
+		return false, nil
+	case CRLFLF:
+		return true, nil
+	}
+	return false, fmt.Errorf("unknown --crlf mode %q: use auto, keep or lf", mode)
+}
+
+// crlfReader converts CRLF to LF. A CR at the end of the buffered input is
+// left for the next read, which can see whether an LF follows it.
+type crlfReader struct {
+	r *bufio.Reader
+}
+
+func (c *crlfReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	head, err := c.r.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	if head[0] == '\r' {
+		if next, _ := c.r.Peek(2); len(next) == 2 && next[1] == '\n' {
+			_, _ = c.r.Discard(1)
+		}
+	}
+
+	buf, _ := c.r.Peek(c.r.Buffered())
+	n, i := 0, 0
+	for ; i < len(buf) && n < len(p); i++ {
+		if buf[i] == '\r' && i > 0 {
+			if i+1 == len(buf) {
+				break
+			}
+			if buf[i+1] == '\n' {
+				continue
+			}
+		}
+		p[n] = buf[i]
+		n++
+	}
+	_, _ = c.r.Discard(i)
+	return n, nil
+}
+
+// utf16Reader converts UTF-16 to UTF-8. Unpaired surrogates and a trailing odd
+// byte become U+FFFD.
+type utf16Reader struct {
+	r            *bufio.Reader
+	littleEndian bool
+	buf          []byte // converted bytes not yet returned
+	err          error
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	for len(u.buf) == 0 {
+		if u.err != nil {
+			return 0, u.err
+		}
+		u.fill()
+	}
+	n := copy(p, u.buf)
+	u.buf = u.buf[n:]
+	return n, nil
+}
+
+// fill converts up to a few kilobytes of input into buf.
+func (u *utf16Reader) fill() {
+	const units = 2048
+	out := u.buf[:0]
+	for i := 0; i < units; i++ {
+		r1, err := u.readUnit()
+		if err != nil {
+			u.err = err
+			break
+		}
+		r := rune(r1)
+		if utf16.IsSurrogate(r) {
+			r = utf8.RuneError
+			if r1 < 0xDC00 {
+				if next, err := u.peekUnit(); err == nil && next >= 0xDC00 && next <= 0xDFFF {
+					_, _ = u.r.Discard(2)
+					r = utf16.DecodeRune(rune(r1), rune(next))
+				}
+			}
+		}
+		out = utf8.AppendRune(out, r)
+		if u.r.Buffered() < 2 && i > 0 {
+			break // return what is available instead of blocking
+		}
+	}
+	u.buf = out
+}
+
+func (u *utf16Reader) readUnit() (uint16, error) {
+	var b [2]byte
+	n, err := io.ReadFull(u.r, b[:])
+	if n == 1 {
+		// Odd trailing byte
+		return utf8.RuneError, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return u.unit(b[0], b[1]), nil
+}
+
+func (u *utf16Reader) peekUnit() (uint16, error) {
+	b, err := u.r.Peek(2)
+	if err != nil {
+		return 0, err
+	}
+	return u.unit(b[0], b[1]), nil
+}
+
+func (u *utf16Reader) unit(b0, b1 byte) uint16 {
+	if u.littleEndian {
+		return uint16(b0) | uint16(b1)<<8
+	}
+	return uint16(b0)<<8 | uint16(b1)
+}
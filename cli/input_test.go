@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+func readNormalized(t *testing.T, r io.Reader, crlf string) string {
+	t.Helper()
+	input, err := NormalizeInput(r, crlf)
+	if err != nil {
+		t.Fatalf("NormalizeInput failed: %v", err)
+	}
+	got, err := io.ReadAll(input)
+	if err != nil {
+		t.Fatalf("ReadAll failed: %v", err)
+	}
+	return string(got)
+}
+
+func encodeUTF16(s string, littleEndian bool) []byte {
+	units := utf16.Encode([]rune(s))
+	out := make([]byte, 0, 2+2*len(units))
+	if littleEndian {
+		out = append(out, 0xFF, 0xFE)
+	} else {
+		out = append(out, 0xFE, 0xFF)
+	}
+	for _, u := range units {
+		if littleEndian {
+			out = append(out, byte(u), byte(u>>8))
+		} else {
+			out = append(out, byte(u>>8), byte(u))
+		}
+	}
+	return out
+}
+
+func TestNormalizeInputCRLF(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		crlf  string
+		want  string
+	}{
+		{"lf", "a\r\nb\r\n", CRLFLF, "a\nb\n"},
+		{"keep", "a\r\nb\r\n", CRLFKeep, "a\r\nb\r\n"},
+		{"lone_cr", "a\rb\r", CRLFLF, "a\rb\r"},
+		{"cr_cr_lf", "a\r\r\n", CRLFLF, "a\r\n"},
+		{"no_newlines", "hello", CRLFLF, "hello"},
+		{"empty", "", CRLFLF, ""},
+	}
+
+	readers := map[string]func(string) io.Reader{
+		"whole":    func(s string) io.Reader { return strings.NewReader(s) },
+		"one_byte": func(s string) io.Reader { return iotest.OneByteReader(strings.NewReader(s)) },
+		"half":     func(s string) io.Reader { return iotest.HalfReader(strings.NewReader(s)) },
+	}
+
+	for _, tt := range tests {
+		for rname, newReader := range readers {
+			t.Run(tt.name+"/"+rname, func(t *testing.T) {
+				if got := readNormalized(t, newReader(tt.input), tt.crlf); got != tt.want {
+					t.Errorf("Expected %q, got %q", tt.want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestNormalizeInputUTF16(t *testing.T) {
+	text := "héllo 🚀\r\nwörld"
+
+	for _, littleEndian := range []bool{true, false} {
+		data := encodeUTF16(text, littleEndian)
+		if got := readNormalized(t, bytes.NewReader(data), CRLFKeep); got != text {
+			t.Errorf("littleEndian=%v: expected %q, got %q", littleEndian, text, got)
+		}
+		if got := readNormalized(t, iotest.OneByteReader(bytes.NewReader(data)), CRLFLF); got != "héllo 🚀\nwörld" {
+			t.Errorf("littleEndian=%v with lf: got %q", littleEndian, got)
+		}
+	}
+
+	// Unpaired surrogate and odd trailing byte
+	data := []byte{0xFF, 0xFE, 'a', 0, 0x00, 0xD8, 'b', 0, 'c'}
+	if got := readNormalized(t, bytes.NewReader(data), CRLFKeep); got != "a�b�" {
+		t.Errorf("Expected replacement characters, got %q", got)
+	}
+
+	// A UTF-8 byte order mark is text, not an encoding marker
+	if got := readNormalized(t, strings.NewReader("\uFEFFhi"), CRLFKeep); got != "\uFEFFhi" {
+		t.Errorf("Expected UTF-8 input unchanged, got %q", got)
+	}
+}
+
+func TestNormalizeInvalidMode(t *testing.T) {
+	if _, err := NormalizeInput(strings.NewReader("x"), "crlf"); err == nil {
+		t.Error("Expected error for an unknown mode")
+	}
+	if _, err := NormalizeText("x", "crlf"); err == nil {
+		t.Error("Expected error for an unknown mode")
+	}
+	if got, err := NormalizeText("a\r\nb", CRLFLF); err != nil || got != "a\nb" {
+		t.Errorf("Expected %q, got %q (%v)", "a\nb", got, err)
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	var buf bytes.Buffer
+	if ColorEnabled(&buf) {
+		t.Error("Expected no color for a buffer")
+	}
+	if got := Colorize(&buf, ColorRed, "FAIL"); got != "FAIL" {
+		t.Errorf("Expected plain text, got %q", got)
+	}
+
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatalf("CreateTemp failed: %v", err)
+	}
+	defer f.Close()
+	if ColorEnabled(f) {
+		t.Error("Expected no color for a regular file")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if ColorEnabled(os.Stdout) {
+		t.Error("Expected no color with NO_COLOR set")
+	}
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/tokenio"
+)
+
+// newSampleCmd creates the sample command.
+func newSampleCmd() *cobra.Command {
+	var indexPath, to string
+	var count, length int
+	var seed int64
+
+	cmd := &cobra.Command{
+		Use:   "sample <file>",
+		Short: "Sample fixed-length token excerpts from an indexed corpus",
+		Long: `Sample excerpts of exactly --length tokens from a token stream, for building
+evaluation sets. Start positions are uniform over token positions, not
+bytes or documents, and excerpts may span documents.
+
+The stream needs an index written by tokenizer transcode --index, which
+also records the stream's format.`,
+		Example: `  tokenizer transcode --from u32 --to compressed corpus.u32 -O corpus.tok --index corpus.idx
+  tokenizer sample --index corpus.idx --count 1000 --length 512 --seed 1 corpus.tok > eval.ndjson`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := tokenio.ParseFormat(to)
+			if err != nil {
+				return err
+			}
+
+			data, err := os.ReadFile(indexPath) // #nosec G304 - path is provided by the caller
+			if err != nil {
+				return fmt.Errorf("failed to read index: %w", err)
+			}
+			var idx tokenio.Index
+			if err := idx.UnmarshalBinary(data); err != nil {
+				return err
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open input: %w", err)
+			}
+			defer func() { _ = f.Close() }()
+
+			if !cmd.Flags().Changed("seed") {
+				seed = time.Now().UnixNano()
+			}
+			rng := rand.New(rand.NewSource(seed)) // #nosec G404 - sampling does not need a secure source
+			excerpts, err := tokenio.SampleExcerpts(f, &idx, count, length, rng)
+			if err != nil {
+				return err
+			}
+
+			w, err := tokenio.NewWriter(cmd.OutOrStdout(), format)
+			if err != nil {
+				return err
+			}
+			for _, e := range excerpts {
+				if err := w.Write(e); err != nil {
+					return err
+				}
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().StringVar(&indexPath, "index", "", "Index written by transcode --index (required)")
+	cmd.Flags().StringVar(&to, "to", string(tokenio.NDJSON), "Output format: u32, uvarint, ndjson, compressed")
+	cmd.Flags().IntVarP(&count, "count", "n", 100, "Number of excerpts")
+	cmd.Flags().IntVar(&length, "length", 0, "Tokens per excerpt (required)")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "Random seed (default: time-based)")
+	_ = cmd.MarkFlagRequired("index")
+	_ = cmd.MarkFlagRequired("length")
+
+	return cmd
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/tokenio"
+)
+
+// newTranscodeCmd creates the transcode command.
+func newTranscodeCmd() *cobra.Command {
+	var from, to, output, indexPath string
+	var interval int64
+
+	cmd := &cobra.Command{
+		Use:   "transcode [file]",
+		Short: "Convert a token ID stream between storage formats",
+		Long: `Convert stored token IDs from one format to another without decoding them
+to text. The input is read from the file or stdin and streamed record by
+record, so corpora of any size can be converted.
+
+Formats:
+  u32         little-endian uint32 per token
+  uvarint     unsigned varint per token
+  ndjson      one JSON array of token IDs per line
+  compressed  delta and run-length encoded records
+
+ndjson and compressed streams keep record (document) boundaries. u32 and
+uvarint streams are flat: converting them to a record format produces
+records of up to 4096 tokens.
+
+--index writes a token-offset index of the output, which lets tokenizer
+sample and tokenio.SeekReader read from any token position without scanning
+the stream. Entries are at least --index-interval tokens apart; record
+formats can only be indexed at record starts.`,
+		Example: `  # Convert a binary corpus to compressed records
+  tokenizer transcode --from u32 --to compressed corpus.u32 -O corpus.tok
+
+  # Inspect compressed records as JSON
+  tokenizer transcode --from compressed --to ndjson < corpus.tok | head
+
+  # Index a corpus for sampling
+  tokenizer transcode --from u32 --to compressed corpus.u32 -O corpus.tok --index corpus.idx`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fromFormat, err := tokenio.ParseFormat(from)
+			if err != nil {
+				return err
+			}
+			toFormat, err := tokenio.ParseFormat(to)
+			if err != nil {
+				return err
+			}
+
+			in, closeIn, err := openInput(cmd, args)
+			if err != nil {
+				return err
+			}
+			defer closeIn()
+
+			out := cmd.OutOrStdout()
+			var outFile *os.File
+			if output != "" {
+				outFile, err = os.Create(output) // #nosec G304 - path is provided by the caller
+				if err != nil {
+					return fmt.Errorf("failed to create output: %w", err)
+				}
+				defer func() { _ = outFile.Close() }()
+				out = outFile
+			}
+
+			r, err := tokenio.NewReader(in, fromFormat)
+			if err != nil {
+				return err
+			}
+			idx := tokenio.Index{Interval: interval}
+			var w tokenio.Writer
+			if indexPath != "" {
+				w, err = tokenio.NewIndexedWriter(out, toFormat, &idx)
+			} else {
+				w, err = tokenio.NewWriter(out, toFormat)
+			}
+			if err != nil {
+				return err
+			}
+			n, err := tokenio.Copy(w, r)
+			if err != nil {
+				return fmt.Errorf("transcode failed after %d tokens: %w", n, err)
+			}
+			if outFile != nil {
+				if err := outFile.Close(); err != nil {
+					return err
+				}
+			}
+
+			if indexPath != "" {
+				data, err := idx.MarshalBinary()
+				if err != nil {
+					return err
+				}
+				if err := os.WriteFile(indexPath, data, 0o644); err != nil { // #nosec G306 - indexes are not secret
+					return fmt.Errorf("failed to write index: %w", err)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "Input format: u32, uvarint, ndjson, compressed (required)")
+	cmd.Flags().StringVar(&to, "to", "", "Output format: u32, uvarint, ndjson, compressed (required)")
+	cmd.Flags().StringVarP(&output, "output-file", "O", "", "Write to a file instead of stdout")
+	cmd.Flags().StringVar(&indexPath, "index", "", "Write a token-offset index of the output to this file")
+	cmd.Flags().Int64Var(&interval, "index-interval", tokenio.ChunkSize, "Tokens between index entries")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
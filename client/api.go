@@ -0,0 +1,99 @@
+package client
+
+// API paths served by the tokenization service.
+const (
+	EncodePath = "/v1/encode"
+	DecodePath = "/v1/decode"
+	CountPath  = "/v1/count"
+
+	EncodeBatchPath = "/v1/encode/batch"
+	CountBatchPath  = "/v1/count/batch"
+
+	// InfoPath serves the llama3.Info of the tokenizer named by the model
+	// query parameter on GET.
+	InfoPath = "/v1/info"
+)
+
+// EncodeRequest is the body of a POST to EncodePath.
+type EncodeRequest struct {
+	Model          string   `json:"model,omitempty"`
+	Text           string   `json:"text"`
+	BOS            bool     `json:"bos"`
+	EOS            bool     `json:"eos"`
+	SuffixSpecials []string `json:"suffix_specials,omitempty"`
+}
+
+// EncodeResponse is the response to an EncodeRequest.
+type EncodeResponse struct {
+	Tokens []int `json:"tokens"`
+}
+
+// DecodeRequest is the body of a POST to DecodePath.
+type DecodeRequest struct {
+	Model  string `json:"model,omitempty"`
+	Tokens []int  `json:"tokens"`
+}
+
+// DecodeResponse is the response to a DecodeRequest.
+type DecodeResponse struct {
+	Text string `json:"text"`
+}
+
+// CountRequest is the body of a POST to CountPath. The count excludes
+// BOS/EOS tokens.
+type CountRequest struct {
+	Model string `json:"model,omitempty"`
+	Text  string `json:"text"`
+}
+
+// CountResponse is the response to a CountRequest.
+type CountResponse struct {
+	Count int `json:"count"`
+}
+
+// ErrorResponse is the body of a non-2xx response.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// EncodeBatchRequest is the body of a POST to EncodeBatchPath. The options
+// apply to every text.
+type EncodeBatchRequest struct {
+	Model          string   `json:"model,omitempty"`
+	Texts          []string `json:"texts"`
+	BOS            bool     `json:"bos"`
+	EOS            bool     `json:"eos"`
+	SuffixSpecials []string `json:"suffix_specials,omitempty"`
+}
+
+// EncodeBatchResponse is the response to an EncodeBatchRequest, with one
+// result per text in request order.
+type EncodeBatchResponse struct {
+	Results []EncodeResult `json:"results"`
+}
+
+// EncodeResult is the outcome of encoding one text of a batch. A failed item
+// has a non-empty Error and does not fail the rest of the batch.
+type EncodeResult struct {
+	Tokens []int  `json:"tokens,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CountBatchRequest is the body of a POST to CountBatchPath.
+type CountBatchRequest struct {
+	Model string   `json:"model,omitempty"`
+	Texts []string `json:"texts"`
+}
+
+// CountBatchResponse is the response to a CountBatchRequest, with one result
+// per text in request order.
+type CountBatchResponse struct {
+	Results []CountResult `json:"results"`
+}
+
+// CountResult is the outcome of counting one text of a batch. A failed item
+// has a non-empty Error and does not fail the rest of the batch.
+type CountResult struct {
+	Count int    `json:"count"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.record(true)
+	if !b.allow() {
+		t.Fatal("Expected breaker to stay closed after one failure")
+	}
+	b.record(true)
+	if b.allow() {
+		t.Fatal("Expected breaker to open after two failures")
+	}
+
+	// After the cooldown a single trial request is allowed
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("Expected a trial request after cooldown")
+	}
+	if b.allow() {
+		t.Fatal("Expected only one trial request")
+	}
+
+	// A failed trial reopens the breaker for another cooldown
+	b.record(true)
+	if b.allow() {
+		t.Fatal("Expected breaker to reopen after a failed trial")
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("Expected a trial request after cooldown")
+	}
+	b.record(false)
+	if !b.allow() || b.isOpen() {
+		t.Fatal("Expected breaker to close after a successful trial")
+	}
+
+	// A canceled trial frees the slot without reopening the breaker
+	b.record(true)
+	b.record(true)
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("Expected a trial request after cooldown")
+	}
+	b.release()
+	if !b.allow() {
+		t.Fatal("Expected another trial request after a canceled one")
+	}
+}
+
+func TestClientCanceledTrial(t *testing.T) {
+	var hits atomic.Int32
+	status := atomic.Int32{}
+	status.Store(http.StatusServiceUnavailable)
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch code := int(status.Load()); code {
+		case 0:
+			select {
+			case <-r.Context().Done():
+			case <-hang:
+			}
+		case http.StatusOK:
+			writeJSON(w, code, CountResponse{Count: 42})
+		default:
+			writeJSON(w, code, ErrorResponse{Error: "unavailable"})
+		}
+	}))
+	defer srv.Close()
+	defer close(hang)
+
+	c, err := New(srv.URL, WithCircuitBreaker(1, time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	now := time.Unix(0, 0)
+	c.breaker.now = func() time.Time { return now }
+
+	if _, err := c.Count(context.Background(), "hi"); err == nil || !c.breaker.isOpen() {
+		t.Fatalf("Expected the breaker to open, got %v", err)
+	}
+
+	// The trial request times out
+	now = now.Add(time.Minute)
+	status.Store(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Count(ctx, "hi"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the trial to time out, got %v", err)
+	}
+
+	// The service is tried again and closes the breaker
+	status.Store(http.StatusOK)
+	for i := range 3 {
+		if n, err := c.Count(context.Background(), "hi"); err != nil || n != 42 {
+			t.Fatalf("Call %d: expected 42 from the service, got %d (%v)", i, n, err)
+		}
+	}
+	if hits.Load() != 5 || c.breaker.isOpen() {
+		t.Errorf("Expected every call to reach the service and the breaker to close, got %d calls", hits.Load())
+	}
+}
+
+func TestClientFallback(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	ctx := context.Background()
+	text := "Hello world"
+	want := len(tok.Encode(text, &llama3.EncodeOptions{}))
+
+	var hits atomic.Int32
+	status := atomic.Int32{}
+	status.Store(http.StatusServiceUnavailable)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		code := int(status.Load())
+		if code != http.StatusOK {
+			writeJSON(w, code, ErrorResponse{Error: "unavailable"})
+			return
+		}
+		writeJSON(w, http.StatusOK, CountResponse{Count: 42})
+	}))
+	defer srv.Close()
+
+	t.Run("without_fallback", func(t *testing.T) {
+		c, _ := New(srv.URL)
+		_, err := c.Count(ctx, text)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected 503 APIError, got %v", err)
+		}
+		if m := c.Metrics(); m.RemoteErrors != 1 || m.Fallbacks != 0 {
+			t.Errorf("Unexpected metrics: %+v", m)
+		}
+	})
+
+	t.Run("with_fallback_and_breaker", func(t *testing.T) {
+		c, err := New(srv.URL, WithFallback(tok), WithCircuitBreaker(2, time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		hits.Store(0)
+
+		for i := 0; i < 5; i++ {
+			got, err := c.Count(ctx, text)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != want {
+				t.Errorf("Expected local count %d, got %d", want, got)
+			}
+		}
+
+		// The breaker opens after two failures and stops calling the service
+		if hits.Load() != 2 {
+			t.Errorf("Expected 2 requests to reach the service, got %d", hits.Load())
+		}
+		m := c.Metrics()
+		if m.Requests != 5 || m.Fallbacks != 5 || !m.CircuitOpen || m.FallbackRate() != 1 {
+			t.Errorf("Unexpected metrics: %+v", m)
+		}
+	})
+
+	t.Run("client_errors_do_not_fall_back", func(t *testing.T) {
+		status.Store(http.StatusBadRequest)
+		defer status.Store(http.StatusServiceUnavailable)
+
+		c, _ := New(srv.URL, WithFallback(tok))
+		if _, err := c.Count(ctx, text); err == nil {
+			t.Error("Expected 400 error to be returned")
+		}
+		if m := c.Metrics(); m.Fallbacks != 0 {
+			t.Errorf("Unexpected fallback: %+v", m)
+		}
+	})
+
+	t.Run("connection_error", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+
+		c, _ := New(closed.URL, WithFallback(tok))
+		got, err := c.Encode(ctx, text, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(got) != want+2 {
+			t.Errorf("Expected %d tokens, got %v", want+2, got)
+		}
+
+		results, err := c.EncodeBatch(ctx, []string{text, text}, &llama3.EncodeOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(results) != 2 || len(results[1].Tokens) != want {
+			t.Errorf("Unexpected batch results: %+v", results)
+		}
+	})
+
+	t.Run("circuit_open_without_fallback", func(t *testing.T) {
+		c, _ := New(srv.URL, WithCircuitBreaker(1, time.Hour))
+		_, _ = c.Count(ctx, text)
+		if _, err := c.Count(ctx, text); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("Expected ErrCircuitOpen, got %v", err)
+		}
+	})
+}
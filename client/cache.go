@@ -0,0 +1,130 @@
+package client
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// cacheEntryOverhead approximates the bytes used by a cache entry besides its
+// tokens: the key, list element, map slot and timestamps.
+const cacheEntryOverhead = 128
+
+// cacheKey identifies a request by a hash of its path, model, options and text,
+// so cached texts are not retained in memory.
+type cacheKey [sha256.Size]byte
+
+// newCacheKey hashes the parts of a request that determine its result.
+func newCacheKey(path, model string, flags byte, specials []string, text string) cacheKey {
+	h := sha256.New()
+	writeField := func(s string) {
+		var n [8]byte
+		binary.LittleEndian.PutUint64(n[:], uint64(len(s)))
+		_, _ = h.Write(n[:])
+		_, _ = h.Write([]byte(s))
+	}
+	writeField(path)
+	writeField(model)
+	_, _ = h.Write([]byte{flags})
+	for _, s := range specials {
+		writeField(s)
+	}
+	writeField(text)
+
+	var key cacheKey
+	h.Sum(key[:0])
+	return key
+}
+
+// responseCache is an LRU cache of encode/count results with a TTL, bounded
+// by the approximate bytes it holds. A nil *responseCache caches nothing.
+type responseCache struct {
+	maxBytes int64
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu    sync.Mutex
+	bytes int64
+	items map[cacheKey]*list.Element
+	lru   *list.List // of *cachedResponse, most recently used at the front
+}
+
+// cachedResponse is a cached result: either tokens or a count.
+type cachedResponse struct {
+	key     cacheKey
+	tokens  []int
+	count   int
+	expires time.Time
+}
+
+func (e *cachedResponse) size() int64 {
+	return cacheEntryOverhead + int64(len(e.tokens))*8
+}
+
+func newResponseCache(maxBytes int64, ttl time.Duration) *responseCache {
+	return &responseCache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		now:      time.Now,
+		items:    make(map[cacheKey]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// get returns the cached response for key if present and not expired.
+func (c *responseCache) get(key cacheKey) (*cachedResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cachedResponse)
+	if c.ttl > 0 && !c.now().Before(entry.expires) {
+		c.removeLocked(elem)
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+// put stores a response, evicting least recently used entries to stay within
+// the byte bound. Responses larger than the bound are not cached.
+func (c *responseCache) put(key cacheKey, tokens []int, count int) {
+	if c == nil {
+		return
+	}
+	entry := &cachedResponse{key: key, tokens: tokens, count: count}
+	if entry.size() > c.maxBytes {
+		return
+	}
+	if c.ttl > 0 {
+		entry.expires = c.now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.removeLocked(elem)
+	}
+	c.items[key] = c.lru.PushFront(entry)
+	c.bytes += entry.size()
+
+	for c.bytes > c.maxBytes {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+// removeLocked removes an element from the cache.
+func (c *responseCache) removeLocked(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cachedResponse)
+	delete(c.items, entry.key)
+	c.bytes -= entry.size()
+}
@@ -0,0 +1,118 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestResponseCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := newResponseCache(3*cacheEntryOverhead, time.Minute)
+	c.now = func() time.Time { return now }
+
+	keys := make([]cacheKey, 4)
+	for i := range keys {
+		keys[i] = newCacheKey(CountPath, "", 0, nil, string(rune('a'+i)))
+	}
+
+	t.Run("lru_byte_bound", func(t *testing.T) {
+		c.put(keys[0], nil, 0)
+		c.put(keys[1], nil, 1)
+		c.put(keys[2], nil, 2)
+		c.get(keys[0]) // keys[1] is now least recently used
+		c.put(keys[3], nil, 3)
+
+		if _, ok := c.get(keys[1]); ok {
+			t.Error("Expected least recently used entry to be evicted")
+		}
+		for _, i := range []int{0, 2, 3} {
+			if e, ok := c.get(keys[i]); !ok || e.count != i {
+				t.Errorf("Expected entry %d to be cached, got %+v", i, e)
+			}
+		}
+		if c.bytes != 3*cacheEntryOverhead {
+			t.Errorf("Expected %d bytes, got %d", 3*cacheEntryOverhead, c.bytes)
+		}
+	})
+
+	t.Run("ttl", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		if _, ok := c.get(keys[0]); ok {
+			t.Error("Expected entry to expire after the TTL")
+		}
+	})
+
+	t.Run("oversized", func(t *testing.T) {
+		c.put(keys[0], make([]int, 1000), 0)
+		if _, ok := c.get(keys[0]); ok {
+			t.Error("Expected oversized response not to be cached")
+		}
+	})
+
+	t.Run("key_includes_options", func(t *testing.T) {
+		a := newCacheKey(EncodePath, "", 3, nil, "x")
+		b := newCacheKey(EncodePath, "", 1, nil, "x")
+		d := newCacheKey(EncodePath, "", 3, []string{"<|eot_id|>"}, "x")
+		if a == b || a == d || b == d {
+			t.Error("Expected different options to produce different keys")
+		}
+	})
+}
+
+func TestClientCache(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	srv := newTestServer(t, tok)
+
+	var hits atomic.Int32
+	counting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		srv.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer counting.Close()
+
+	c, err := New(counting.URL, WithCache(1<<20, time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	prompt := "You are a helpful assistant."
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Count(ctx, prompt); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		tokens, err := c.Encode(ctx, prompt, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		tokens[0] = -1 // must not corrupt the cached copy
+	}
+	if _, err := c.Encode(ctx, prompt, &llama3.EncodeOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if hits.Load() != 3 {
+		t.Errorf("Expected 3 requests to reach the service, got %d", hits.Load())
+	}
+	tokens, _ := c.Encode(ctx, prompt, nil)
+	if tokens[0] != 128000 {
+		t.Errorf("Expected cached tokens to start with BOS, got %v", tokens)
+	}
+
+	m := c.Metrics()
+	if m.CacheHits != 5 || m.CacheMisses != 3 || m.Requests != 8 {
+		t.Errorf("Unexpected metrics: %+v", m)
+	}
+	if rate := m.CacheHitRate(); rate != 5.0/8 {
+		t.Errorf("Expected hit rate 0.625, got %v", rate)
+	}
+}
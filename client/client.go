@@ -0,0 +1,459 @@
+// Package client is a Go client for the tokenization service's HTTP JSON API.
+//
+// Its methods mirror the in-process llama3.Tokenizer, taking the same
+// *llama3.EncodeOptions, so applications can move between local and remote
+// tokenization by changing the constructor:
+//
+//	c, err := client.New("http://localhost:8080")
+//	if err != nil {
+//	    return err
+//	}
+//	tokens, err := c.Encode(ctx, "Hello world", nil)
+//
+// Code written against the model-independent tokenizer.Tokenizer interface
+// can use the service through the Remote adapter returned by
+// Client.Tokenizer.
+//
+// To keep prompt budgeting working during outages, a client can fall back to
+// an embedded tokenizer when the service is unavailable, behind a circuit
+// breaker that stops sending requests to a failing service:
+//
+//	local, _ := llama3.New()
+//	c, err := client.New("http://tokenizer:8080",
+//	    client.WithFallback(local),
+//	    client.WithCircuitBreaker(5, 30*time.Second),
+//	)
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// defaultTimeout is the timeout of the default HTTP client.
+const defaultTimeout = 30 * time.Second
+
+// maxErrorBodyBytes bounds how much of an error response body is read.
+const maxErrorBodyBytes = 64 * 1024
+
+// APIError is returned when the service responds with a non-2xx status.
+type APIError struct {
+	StatusCode int    // HTTP status code
+	Message    string // error message from the service, if any
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("tokenizer service: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	}
+	return fmt.Sprintf("tokenizer service: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Client calls a remote tokenization service. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	model      string
+	apiKey     string
+	fallback   *llama3.Tokenizer
+	breaker    *breaker
+	cache      *responseCache
+
+	compress    bool // gzip requests and accept gzip responses
+	compressMin int  // minimum request body size to gzip
+
+	requests     atomic.Uint64
+	remoteErrors atomic.Uint64
+	fallbacks    atomic.Uint64
+	cacheHits    atomic.Uint64
+	cacheMisses  atomic.Uint64
+}
+
+// Metrics reports client request outcomes since creation.
+type Metrics struct {
+	Requests     uint64 // calls to Encode, Decode, Count and the batch methods
+	RemoteErrors uint64 // requests where the service was unavailable, including those rejected by an open circuit
+	Fallbacks    uint64 // requests served by the fallback tokenizer
+	CircuitOpen  bool   // whether the circuit breaker is currently open
+	CacheHits    uint64 // Encode and Count calls answered from the cache
+	CacheMisses  uint64 // Encode and Count calls not found in the cache
+}
+
+// FallbackRate returns the fraction of requests served by the fallback
+// tokenizer, or 0 if there have been no requests.
+func (m Metrics) FallbackRate() float64 {
+	if m.Requests == 0 {
+		return 0
+	}
+	return float64(m.Fallbacks) / float64(m.Requests)
+}
+
+// Option is a functional option for configuring a Client.
+type Option func(*Client) error
+
+// WithHTTPClient sets the HTTP client used for requests.
+// The default client has a 30 second timeout.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		if hc == nil {
+			return errors.New("client: nil HTTP client")
+		}
+		c.httpClient = hc
+		return nil
+	}
+}
+
+// WithAPIKey sends key as a bearer token with each request, for services that
+// require API key authentication.
+func WithAPIKey(key string) Option {
+	return func(c *Client) error {
+		c.apiKey = key
+		return nil
+	}
+}
+
+// WithModel sets the tokenizer name sent with each request, for services that
+// host several tokenizers. If empty, the service default is used.
+func WithModel(name string) Option {
+	return func(c *Client) error {
+		c.model = name
+		return nil
+	}
+}
+
+// CacheHitRate returns the fraction of cacheable requests answered from the
+// cache, or 0 if there have been none.
+func (m Metrics) CacheHitRate() float64 {
+	total := m.CacheHits + m.CacheMisses
+	if total == 0 {
+		return 0
+	}
+	return float64(m.CacheHits) / float64(total)
+}
+
+// WithFallback serves requests with the given local tokenizer when the
+// service is unavailable: on connection errors, 5xx and 429 responses, and
+// while the circuit breaker is open. Errors caused by the request itself, such
+// as 4xx responses or a canceled context, are still returned.
+//
+// The fallback tokenizer should match the model served remotely.
+func WithFallback(tok *llama3.Tokenizer) Option {
+	return func(c *Client) error {
+		if tok == nil {
+			return errors.New("client: nil fallback tokenizer")
+		}
+		c.fallback = tok
+		return nil
+	}
+}
+
+// WithCircuitBreaker stops sending requests to the service after failures
+// consecutive unavailability errors. While open, requests use the fallback
+// tokenizer or fail with ErrCircuitOpen. After cooldown, one trial request is
+// sent; if it succeeds the circuit closes. Zero values select the defaults of
+// 5 failures and 30 seconds.
+func WithCircuitBreaker(failures int, cooldown time.Duration) Option {
+	return func(c *Client) error {
+		if failures < 0 || cooldown < 0 {
+			return fmt.Errorf("client: invalid circuit breaker settings: failures=%d cooldown=%v", failures, cooldown)
+		}
+		c.breaker = newBreaker(failures, cooldown)
+		return nil
+	}
+}
+
+// WithCache caches Encode and Count responses from the service, keyed by a
+// hash of the text and options, to reduce load for repeated texts such as
+// system prompts. The cache is an LRU holding at most maxBytes of responses
+// (approximately); entries expire after ttl, or never if ttl is 0.
+// Responses served by the fallback tokenizer are not cached.
+func WithCache(maxBytes int64, ttl time.Duration) Option {
+	return func(c *Client) error {
+		if maxBytes <= 0 || ttl < 0 {
+			return fmt.Errorf("client: invalid cache settings: maxBytes=%d ttl=%v", maxBytes, ttl)
+		}
+		c.cache = newResponseCache(maxBytes, ttl)
+		return nil
+	}
+}
+
+// New creates a client for the service at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: parse base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL %q must use http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Encode converts text into token IDs. If opts is nil, BOS and EOS are added,
+// as with llama3.Tokenizer.Encode.
+func (c *Client) Encode(ctx context.Context, text string, opts *llama3.EncodeOptions) ([]int, error) {
+	req := EncodeRequest{Model: c.model, Text: text, BOS: true, EOS: true}
+	if opts != nil {
+		req.BOS, req.EOS, req.SuffixSpecials = opts.BOS, opts.EOS, opts.SuffixSpecials
+	}
+
+	var flags byte
+	if req.BOS {
+		flags |= 1
+	}
+	if req.EOS {
+		flags |= 2
+	}
+	key := newCacheKey(EncodePath, c.model, flags, req.SuffixSpecials, text)
+	if cached, ok := c.cacheGet(key); ok {
+		return append([]int(nil), cached.tokens...), nil
+	}
+
+	var resp EncodeResponse
+	remote := true
+	err := c.call(ctx, EncodePath, req, &resp, func(tok *llama3.Tokenizer) {
+		remote = false
+		resp.Tokens = tok.Encode(text, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if remote {
+		c.cache.put(key, append([]int(nil), resp.Tokens...), 0)
+	}
+	return resp.Tokens, nil
+}
+
+// Decode converts token IDs back into text.
+func (c *Client) Decode(ctx context.Context, tokens []int) (string, error) {
+	var resp DecodeResponse
+	err := c.call(ctx, DecodePath, DecodeRequest{Model: c.model, Tokens: tokens}, &resp, func(tok *llama3.Tokenizer) {
+		resp.Text = tok.Decode(tokens)
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// Count returns the number of tokens in text, excluding BOS/EOS.
+func (c *Client) Count(ctx context.Context, text string) (int, error) {
+	key := newCacheKey(CountPath, c.model, 0, nil, text)
+	if cached, ok := c.cacheGet(key); ok {
+		return cached.count, nil
+	}
+
+	var resp CountResponse
+	remote := true
+	err := c.call(ctx, CountPath, CountRequest{Model: c.model, Text: text}, &resp, func(tok *llama3.Tokenizer) {
+		remote = false
+		resp.Count = len(tok.Encode(text, &llama3.EncodeOptions{}))
+	})
+	if err != nil {
+		return 0, err
+	}
+	if remote {
+		c.cache.put(key, nil, resp.Count)
+	}
+	return resp.Count, nil
+}
+
+// EncodeBatch encodes several texts in one request. Per-item failures are
+// reported in the results; the returned error is for failures of the whole
+// request, such as a batch larger than the service allows (413).
+// If opts is nil, BOS and EOS are added.
+func (c *Client) EncodeBatch(ctx context.Context, texts []string, opts *llama3.EncodeOptions) ([]EncodeResult, error) {
+	req := EncodeBatchRequest{Model: c.model, Texts: texts, BOS: true, EOS: true}
+	if opts != nil {
+		req.BOS, req.EOS, req.SuffixSpecials = opts.BOS, opts.EOS, opts.SuffixSpecials
+	}
+
+	var resp EncodeBatchResponse
+	err := c.call(ctx, EncodeBatchPath, req, &resp, func(tok *llama3.Tokenizer) {
+		resp.Results = make([]EncodeResult, len(texts))
+		for i, text := range texts {
+			resp.Results[i].Tokens = tok.Encode(text, opts)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(texts) {
+		return nil, fmt.Errorf("client: %s: got %d results for %d texts", EncodeBatchPath, len(resp.Results), len(texts))
+	}
+	return resp.Results, nil
+}
+
+// CountBatch counts the tokens of several texts in one request, excluding
+// BOS/EOS. Per-item failures are reported in the results.
+func (c *Client) CountBatch(ctx context.Context, texts []string) ([]CountResult, error) {
+	var resp CountBatchResponse
+	err := c.call(ctx, CountBatchPath, CountBatchRequest{Model: c.model, Texts: texts}, &resp, func(tok *llama3.Tokenizer) {
+		resp.Results = make([]CountResult, len(texts))
+		for i, text := range texts {
+			resp.Results[i].Count = len(tok.Encode(text, &llama3.EncodeOptions{}))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(texts) {
+		return nil, fmt.Errorf("client: %s: got %d results for %d texts", CountBatchPath, len(resp.Results), len(texts))
+	}
+	return resp.Results, nil
+}
+
+// Metrics returns a snapshot of the client's request metrics.
+func (c *Client) Metrics() Metrics {
+	return Metrics{
+		Requests:     c.requests.Load(),
+		RemoteErrors: c.remoteErrors.Load(),
+		Fallbacks:    c.fallbacks.Load(),
+		CircuitOpen:  c.breaker.isOpen(),
+		CacheHits:    c.cacheHits.Load(),
+		CacheMisses:  c.cacheMisses.Load(),
+	}
+}
+
+// cacheGet looks up key in the response cache, counting the request and the
+// hit or miss. It reports false if caching is disabled.
+func (c *Client) cacheGet(key cacheKey) (*cachedResponse, bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	entry, ok := c.cache.get(key)
+	if !ok {
+		c.cacheMisses.Add(1)
+		return nil, false
+	}
+	c.requests.Add(1)
+	c.cacheHits.Add(1)
+	return entry, true
+}
+
+// call sends a request through the circuit breaker and, if the service is
+// unavailable and a fallback tokenizer is configured, runs local instead.
+func (c *Client) call(ctx context.Context, path string, body, out any, local func(*llama3.Tokenizer)) error {
+	c.requests.Add(1)
+
+	var err error
+	if c.breaker.allow() {
+		err = c.do(ctx, path, body, out)
+		if ctx.Err() == nil {
+			c.breaker.record(unavailable(err))
+		} else {
+			c.breaker.release() // the caller gave up; the service is untested
+		}
+	} else {
+		err = ErrCircuitOpen
+	}
+
+	if err == nil || ctx.Err() != nil || !unavailable(err) {
+		return err
+	}
+
+	c.remoteErrors.Add(1)
+	if c.fallback == nil {
+		return err
+	}
+	c.fallbacks.Add(1)
+	local(c.fallback)
+	return nil
+}
+
+// unavailable reports whether err means the service could not serve the
+// request, as opposed to rejecting it.
+func unavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// do POSTs body as JSON to path, or GETs path if body is nil, and decodes
+// the JSON response into out.
+func (c *Client) do(ctx context.Context, path string, body, out any) error {
+	method := http.MethodGet
+	var reqBody io.Reader
+	var contentEncoding string
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("client: encode request: %w", err)
+		}
+
+		method = http.MethodPost
+		reqBody, contentEncoding, err = c.newRequestBody(payload)
+		if err != nil {
+			return fmt.Errorf("client: compress request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("client: create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	if c.compress {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("client: %s: %w", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := responseBody(resp)
+	if err != nil {
+		return fmt.Errorf("client: %s: %w", path, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		var errResp ErrorResponse
+		data, _ := io.ReadAll(io.LimitReader(respBody, maxErrorBodyBytes))
+		if json.Unmarshal(data, &errResp) == nil {
+			apiErr.Message = errResp.Error
+		}
+		return apiErr
+	}
+
+	if err := json.NewDecoder(respBody).Decode(out); err != nil {
+		return fmt.Errorf("client: decode %s response: %w", path, err)
+	}
+	return nil
+}
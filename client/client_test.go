@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// newTestServer serves the client API from an in-process tokenizer.
+func newTestServer(t testing.TB, tok *llama3.Tokenizer) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(EncodePath, func(w http.ResponseWriter, r *http.Request) {
+		var req EncodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		tokens := tok.Encode(req.Text, &llama3.EncodeOptions{BOS: req.BOS, EOS: req.EOS, SuffixSpecials: req.SuffixSpecials})
+		writeJSON(w, http.StatusOK, EncodeResponse{Tokens: tokens})
+	})
+	mux.HandleFunc(DecodePath, func(w http.ResponseWriter, r *http.Request) {
+		var req DecodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, DecodeResponse{Text: tok.Decode(req.Tokens)})
+	})
+	mux.HandleFunc(CountPath, func(w http.ResponseWriter, r *http.Request) {
+		var req CountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if req.Model != "" && req.Model != "llama3" {
+			writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "unknown model " + req.Model})
+			return
+		}
+		writeJSON(w, http.StatusOK, CountResponse{Count: len(tok.Encode(req.Text, &llama3.EncodeOptions{}))})
+	})
+
+	mux.HandleFunc(CountBatchPath, func(w http.ResponseWriter, r *http.Request) {
+		var req CountBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if len(req.Texts) > 3 {
+			writeJSON(w, http.StatusRequestEntityTooLarge, ErrorResponse{Error: "batch too large"})
+			return
+		}
+		resp := CountBatchResponse{Results: make([]CountResult, len(req.Texts))}
+		for i, text := range req.Texts {
+			if text == "" {
+				resp.Results[i].Error = "empty text"
+				continue
+			}
+			resp.Results[i].Count = len(tok.Encode(text, &llama3.EncodeOptions{}))
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+
+	mux.HandleFunc("GET "+InfoPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, tok.Info())
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestClient(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	srv := newTestServer(t, tok)
+
+	c, err := New(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	text := "Hello world! 🦙"
+
+	t.Run("encode_matches_local", func(t *testing.T) {
+		for _, opts := range []*llama3.EncodeOptions{nil, {BOS: false, EOS: false}, {EOS: true, SuffixSpecials: []string{"<|eot_id|>"}}} {
+			got, err := c.Encode(ctx, text, opts)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			want := tok.Encode(text, opts)
+			if len(got) != len(want) {
+				t.Fatalf("Encode(%+v) = %v, want %v", opts, got, want)
+			}
+			for i := range got {
+				if got[i] != want[i] {
+					t.Fatalf("Encode(%+v) = %v, want %v", opts, got, want)
+				}
+			}
+		}
+	})
+
+	t.Run("decode", func(t *testing.T) {
+		got, err := c.Decode(ctx, tok.Encode(text, &llama3.EncodeOptions{}))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got != text {
+			t.Errorf("Expected %q, got %q", text, got)
+		}
+	})
+
+	t.Run("count", func(t *testing.T) {
+		got, err := c.Count(ctx, text)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := len(tok.Encode(text, &llama3.EncodeOptions{})); got != want {
+			t.Errorf("Expected %d, got %d", want, got)
+		}
+	})
+
+	t.Run("count_batch", func(t *testing.T) {
+		results, err := c.CountBatch(ctx, []string{text, "", "a b c"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 results, got %d", len(results))
+		}
+		if results[0].Count != len(tok.Encode(text, &llama3.EncodeOptions{})) || results[0].Error != "" {
+			t.Errorf("Unexpected first result: %+v", results[0])
+		}
+		if results[1].Error != "empty text" {
+			t.Errorf("Expected per-item error, got %+v", results[1])
+		}
+		if results[2].Count != 3 {
+			t.Errorf("Expected 3 tokens, got %+v", results[2])
+		}
+
+		_, err = c.CountBatch(ctx, []string{"a", "b", "c", "d"})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413 APIError, got %v", err)
+		}
+	})
+
+	t.Run("api_error", func(t *testing.T) {
+		c, err := New(srv.URL, WithModel("gpt-2"))
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		_, err = c.Count(ctx, text)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("Expected *APIError, got %v", err)
+		}
+		if apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "unknown model gpt-2" {
+			t.Errorf("Unexpected API error: %+v", apiErr)
+		}
+	})
+}
+
+func TestNewInvalidURL(t *testing.T) {
+	for _, u := range []string{"", "localhost:8080", "ftp://example.com", "http://[::1"} {
+		if _, err := New(u); err == nil {
+			t.Errorf("Expected error for base URL %q", u)
+		}
+	}
+}
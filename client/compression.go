@@ -0,0 +1,59 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WithCompression gzips request bodies of at least minBytes and asks the
+// service for gzip-compressed responses. Token arrays for long documents
+// compress well, so this mainly saves bandwidth on Encode responses and on
+// large texts sent to the service.
+//
+// Only gzip is supported; zstd would need a third-party dependency.
+func WithCompression(minBytes int) Option {
+	return func(c *Client) error {
+		if minBytes < 0 {
+			return fmt.Errorf("client: invalid compression threshold %d", minBytes)
+		}
+		c.compress = true
+		c.compressMin = minBytes
+		return nil
+	}
+}
+
+// newRequestBody returns the body for a request payload and its
+// Content-Encoding, compressing it if enabled and large enough.
+func (c *Client) newRequestBody(payload []byte) (io.Reader, string, error) {
+	if !c.compress || len(payload) < c.compressMin {
+		return bytes.NewReader(payload), "", nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(payload); err != nil {
+		return nil, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, "gzip", nil
+}
+
+// responseBody returns a reader for the decoded response body.
+// The transport already decodes gzip for requests it added Accept-Encoding
+// to; this handles responses to requests that set it explicitly.
+func responseBody(resp *http.Response) (io.Reader, error) {
+	switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip":
+		return gzip.NewReader(resp.Body)
+	default:
+		return nil, fmt.Errorf("client: unsupported response Content-Encoding %q", resp.Header.Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// gzipServer wraps the test API with gzip request decoding and response
+// encoding, and counts the bytes sent over the wire in both directions.
+func gzipServer(t testing.TB, tok *llama3.Tokenizer, wireBytes *atomic.Int64) *httptest.Server {
+	t.Helper()
+	api := newTestServer(t, tok).Config.Handler
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		wireBytes.Add(int64(len(body)))
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(strings.NewReader(string(body)))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body, _ = io.ReadAll(zr)
+		}
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, r)
+
+		out := rec.Body.Bytes()
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			var buf strings.Builder
+			zw := gzip.NewWriter(&buf)
+			_, _ = zw.Write(out)
+			_ = zw.Close()
+			out = []byte(buf.String())
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+		wireBytes.Add(int64(len(out)))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(out)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientCompression(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	var wire atomic.Int64
+	srv := gzipServer(t, tok, &wire)
+	ctx := context.Background()
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 200)
+	want := tok.Encode(text, nil)
+
+	var plainBytes int64
+	for _, compress := range []bool{false, true} {
+		var opts []Option
+		if compress {
+			opts = append(opts, WithCompression(1024))
+		}
+		c, err := New(srv.URL, opts...)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+
+		wire.Store(0)
+		got, err := c.Encode(ctx, text, nil)
+		if err != nil {
+			t.Fatalf("compress=%v: unexpected error: %v", compress, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("compress=%v: expected %d tokens, got %d", compress, len(want), len(got))
+		}
+
+		if !compress {
+			plainBytes = wire.Load()
+		} else if wire.Load()*4 > plainBytes {
+			t.Errorf("Expected gzip to cut wire bytes at least 4x: %d -> %d", plainBytes, wire.Load())
+		}
+	}
+}
+
+func BenchmarkClientEncode(b *testing.B) {
+	tok, err := llama3.New()
+	if err != nil {
+		b.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	ctx := context.Background()
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 1000)
+
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"identity", nil},
+		{"gzip", []Option{WithCompression(1024)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var wire atomic.Int64
+			srv := gzipServer(b, tok, &wire)
+			c, _ := New(srv.URL, bc.opts...)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Encode(ctx, text, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(wire.Load())/float64(b.N), "wire-B/op")
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/agentstation/tokenizer"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// Ensure Remote implements the model-independent interfaces.
+var (
+	_ tokenizer.Tokenizer = (*Remote)(nil)
+	_ tokenizer.Counter   = (*Remote)(nil)
+)
+
+// Remote is a Client as a model-independent tokenizer.Tokenizer, so code
+// written against package tokenizer can switch between in-process and remote
+// tokenization by changing the constructor:
+//
+//	tok, err := c.Tokenizer(ctx) // instead of tokenizer.Get("llama3")
+//
+// Like the in-process adapters, its Encode adds neither BOS nor EOS. The
+// interface has no errors, so a failed call returns nil tokens, empty text
+// or a zero count, and Err reports the first failure. It is safe for
+// concurrent use.
+type Remote struct {
+	c         *Client
+	ctx       context.Context
+	vocabSize int
+	special   map[string]int
+
+	mu  sync.Mutex
+	err error
+}
+
+// Tokenizer returns c as a tokenizer.Tokenizer whose calls use ctx. It asks
+// the service, or the fallback tokenizer if the service is unavailable, for
+// the vocabulary size and special tokens.
+func (c *Client) Tokenizer(ctx context.Context) (*Remote, error) {
+	r := &Remote{c: c, ctx: ctx}
+
+	var info llama3.Info
+	remote := true
+	err := c.call(ctx, InfoPath+"?model="+url.QueryEscape(c.model), nil, &info, func(tok *llama3.Tokenizer) {
+		remote = false
+		g := tok.Generic()
+		r.vocabSize, r.special = g.VocabSize(), g.SpecialTokens()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !remote {
+		return r, nil
+	}
+
+	// Llama 3 special tokens follow the regular tokens and all have the
+	// form <|name|>, so they can be decoded at once and split
+	ids := make([]int, 0, info.VocabSize-info.RegularTokens)
+	for id := info.RegularTokens; id < info.VocabSize; id++ {
+		ids = append(ids, id)
+	}
+	text, err := c.Decode(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	names := strings.SplitAfter(text, "|>")
+	names = names[:len(names)-1] // text ends with "|>"
+	if len(names) != len(ids) {
+		return nil, fmt.Errorf("client: %s: got %d special tokens for %d IDs", DecodePath, len(names), len(ids))
+	}
+
+	r.vocabSize = info.VocabSize
+	r.special = make(map[string]int, len(ids))
+	for i, name := range names {
+		r.special[name] = ids[i]
+	}
+	return r, nil
+}
+
+// Encode returns the tokens of text without BOS/EOS, or nil on failure.
+func (r *Remote) Encode(text string) []int {
+	tokens, err := r.c.Encode(r.ctx, text, &llama3.EncodeOptions{})
+	r.setErr(err)
+	return tokens
+}
+
+// Decode returns the text of tokens, or "" on failure.
+func (r *Remote) Decode(tokens []int) string {
+	text, err := r.c.Decode(r.ctx, tokens)
+	r.setErr(err)
+	return text
+}
+
+// Count returns the number of tokens of text without BOS/EOS, or 0 on
+// failure.
+func (r *Remote) Count(text string) int {
+	n, err := r.c.Count(r.ctx, text)
+	r.setErr(err)
+	return n
+}
+
+// VocabSize returns the number of token IDs, including special tokens.
+func (r *Remote) VocabSize() int {
+	return r.vocabSize
+}
+
+// SpecialTokens returns the special tokens by their text. The caller may
+// modify the returned map.
+func (r *Remote) SpecialTokens() map[string]int {
+	return maps.Clone(r.special)
+}
+
+// Err returns the error of the first call that failed, or nil.
+func (r *Remote) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// setErr records err if it is the first failure.
+func (r *Remote) setErr(err error) {
+	if err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestRemote(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	local := tok.Generic()
+	srv := newTestServer(t, tok)
+	ctx := context.Background()
+	text := "Hello world! 🦙<|eot_id|>"
+
+	check := func(t *testing.T, c *Client) *Remote {
+		t.Helper()
+		r, err := c.Tokenizer(ctx)
+		if err != nil {
+			t.Fatalf("Tokenizer() error: %v", err)
+		}
+		if got, want := r.Encode(text), local.Encode(text); !equalInts(got, want) {
+			t.Errorf("Encode = %v, want %v", got, want)
+		}
+		if got := r.Decode(local.Encode(text)); got != text {
+			t.Errorf("Decode = %q, want %q", got, text)
+		}
+		if got, want := r.Count(text), len(local.Encode(text)); got != want {
+			t.Errorf("Count = %d, want %d", got, want)
+		}
+		if r.VocabSize() != local.VocabSize() {
+			t.Errorf("VocabSize = %d, want %d", r.VocabSize(), local.VocabSize())
+		}
+		if !maps.Equal(r.SpecialTokens(), local.SpecialTokens()) {
+			t.Error("SpecialTokens differ from the in-process tokenizer")
+		}
+		return r
+	}
+
+	t.Run("remote", func(t *testing.T) {
+		c, err := New(srv.URL)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		if r := check(t, c); r.Err() != nil {
+			t.Errorf("Unexpected Err: %v", r.Err())
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		c, err := New("http://127.0.0.1:1", WithFallback(tok))
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		check(t, c)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		c, err := New(srv.URL)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		r, err := c.Tokenizer(ctx)
+		if err != nil {
+			t.Fatalf("Tokenizer() error: %v", err)
+		}
+		srv.Close()
+		if tokens := r.Encode(text); tokens != nil {
+			t.Errorf("Expected nil tokens, got %v", tokens)
+		}
+		if r.Err() == nil {
+			t.Error("Expected Err to report the failed call")
+		}
+		if _, err := c.Tokenizer(ctx); err == nil {
+			t.Error("Expected error from an unavailable service")
+		}
+	})
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+// Command tokenizer-gen generates the main package of a tokenizer binary that
+// includes additional backends registered with the cli package.
+//
+// Usage:
+//
+//	tokenizer-gen [-o main.go] [-no-builtin] <backend-package>...
+//
+// For example, to build a tokenizer binary with a GPT-2 backend:
+//
+//	mkdir mytokenizer && cd mytokenizer
+//	go mod init example.com/mytokenizer
+//	go run github.com/agentstation/tokenizer/cmd/tokenizer-gen github.com/acme/gpt2/gpt2cmd
+//	go mod tidy && go build
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// builtinBackends are the backends of the core tokenizer binary.
+var builtinBackends = []string{
+	"github.com/agentstation/tokenizer/llama3/cmd/llama3",
+}
+
+var mainTemplate = template.Must(template.New("main").Parse(`// Code generated by tokenizer-gen. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/agentstation/tokenizer/cli"
+
+	// Tokenizer backends
+{{- range .}}
+	_ "{{.}}"
+{{- end}}
+)
+
+var (
+	// Version information (set by build flags).
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+	goVersion = ""
+	builtBy   = ""
+)
+
+func main() {
+	rootCmd, err := cli.NewRootCommand(cli.BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: goVersion,
+		BuiltBy:   builtBy,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`))
+
+func main() {
+	output := flag.String("o", "main.go", "output file, or - for stdout")
+	noBuiltin := flag.Bool("no-builtin", false, "leave out the built-in llama3 backend and the commands it registers:\nllama3, config, lsp, hook, snapshot, cost, lint-templates, optimize,\nconformance and serve")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: tokenizer-gen [-o main.go] [-no-builtin] <backend-package>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	src, err := generate(flag.Args(), !*noBuiltin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tokenizer-gen:", err)
+		os.Exit(1)
+	}
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = os.WriteFile(*output, src, 0o644) // #nosec G306 - generated source is not secret
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tokenizer-gen:", err)
+		os.Exit(1)
+	}
+}
+
+// generate returns the formatted main package importing the given backend
+// packages, plus the built-in ones if builtin is set.
+func generate(backends []string, builtin bool) ([]byte, error) {
+	var imports []string
+	if builtin {
+		imports = append(imports, builtinBackends...)
+	}
+
+	seen := make(map[string]bool)
+	for _, pkg := range imports {
+		seen[pkg] = true
+	}
+	for _, pkg := range backends {
+		if pkg == "" || strings.ContainsAny(pkg, "\" \t\n") {
+			return nil, fmt.Errorf("invalid package path %q", pkg)
+		}
+		if !seen[pkg] {
+			seen[pkg] = true
+			imports = append(imports, pkg)
+		}
+	}
+	if len(imports) == 0 {
+		return nil, fmt.Errorf("no backends to include")
+	}
+
+	var buf bytes.Buffer
+	if err := mainTemplate.Execute(&buf, imports); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		backends []string
+		builtin  bool
+		want     []string // imports, sorted as gofmt sorts them
+		wantErr  bool
+	}{
+		{"builtin", nil, true, builtinBackends, false},
+		{"extra", []string{"example.com/gpt2cmd"}, true, []string{"example.com/gpt2cmd", builtinBackends[0]}, false},
+		{"no_builtin", []string{"example.com/gpt2cmd", "example.com/gpt2cmd"}, false, []string{"example.com/gpt2cmd"}, false},
+		{"duplicate_builtin", builtinBackends, true, builtinBackends, false},
+		{"nothing", nil, false, nil, true},
+		{"invalid_path", []string{`bad "path`}, true, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := generate(tt.backends, tt.builtin)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got\n%s", src)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("generate failed: %v", err)
+			}
+			var imports []string
+			for _, line := range strings.Split(string(src), "\n") {
+				if path, ok := strings.CutPrefix(strings.TrimSpace(line), "_ "); ok {
+					imports = append(imports, strings.Trim(path, `"`))
+				}
+			}
+			if strings.Join(imports, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("Expected imports %v, got %v", tt.want, imports)
+			}
+		})
+	}
+}
+
+// TestGenerateBuild builds a binary from a generated main package with the
+// built-in backends and one more, and checks that it serves the commands of
+// both.
+func TestGenerateBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping build in short mode")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("Skipping build: go command not found")
+	}
+
+	// The packages are built inside the module, under testdata so that
+	// ./... patterns skip them.
+	if err := os.MkdirAll("testdata", 0o750); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	dir, err := os.MkdirTemp("testdata", "build")
+	if err != nil {
+		t.Fatalf("MkdirTemp failed: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+		_ = os.Remove("testdata") // if empty
+	})
+
+	backend := filepath.Join(dir, "echocmd")
+	if err := os.MkdirAll(backend, 0o750); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	echo := `package echocmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/cli"
+)
+
+func init() {
+	cli.Register("echo", func() *cobra.Command {
+		return &cobra.Command{Use: "echo", Short: "Echo from a generated backend"}
+	})
+}
+`
+	if err := os.WriteFile(filepath.Join(backend, "echo.go"), []byte(echo), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	src, err := generate([]string{"github.com/agentstation/tokenizer/cmd/tokenizer-gen/" + filepath.ToSlash(backend)}, true)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), src, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	bin := filepath.Join(t.TempDir(), "tokenizer")
+	build := exec.Command(goTool, "build", "-o", bin, "./"+filepath.ToSlash(dir)) // #nosec G204 - test builds its own package
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build failed: %v\n%s", err, out)
+	}
+
+	var out bytes.Buffer
+	help := exec.Command(bin, "--help") // #nosec G204 - binary built above
+	help.Stdout = &out
+	if err := help.Run(); err != nil {
+		t.Fatalf("tokenizer --help failed: %v", err)
+	}
+	for _, command := range []string{"echo", "llama3", "config", "serve"} {
+		if !strings.Contains(out.String(), command) {
+			t.Errorf("Expected the %s command in the help output:\n%s", command, &out)
+		}
+	}
+}
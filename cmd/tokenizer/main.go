@@ -3,18 +3,35 @@ package main
 import (
 	"fmt"
 	"os"
+
+	"github.com/agentstation/tokenizer/cli"
+
+	// Built-in tokenizer backends
+	_ "github.com/agentstation/tokenizer/llama3/cmd/llama3"
 )
 
 var (
 	// Version information (set by build flags).
 	version   = "dev"
-	commit    = "none"
-	buildDate = "unknown"
-	goVersion = "unknown"
-	builtBy   = "source"
+	commit    = ""
+	buildDate = ""
+	goVersion = ""
+	builtBy   = ""
 )
 
 func main() {
+	rootCmd, err := cli.NewRootCommand(cli.BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: goVersion,
+		BuiltBy:   builtBy,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -0,0 +1,136 @@
+package tokenizer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// compressVersion is the first byte of data written by CompressTokens.
+const compressVersion = 1
+
+// minRun is the shortest run of a repeated token stored as a run.
+const minRun = 3
+
+// MaxDecompressedTokens is the most tokens DecompressTokens returns.
+const MaxDecompressedTokens = 1 << 24
+
+// ErrCorruptTokens is returned by DecompressTokens for malformed data.
+var ErrCorruptTokens = errors.New("corrupt compressed tokens")
+
+// ErrTooManyTokens is returned by DecompressTokens for data holding more
+// tokens than allowed.
+var ErrTooManyTokens = errors.New("too many compressed tokens")
+
+// CompressTokens encodes a token sequence compactly for storage. Token IDs
+// must fit in 32 bits, as they do for every supported vocabulary.
+//
+// The format, version 1, is:
+//
+//	version  byte     always 1
+//	count    uvarint  number of tokens
+//	entries  ...      until count tokens are produced
+//
+// Each entry is a uvarint v. v>>1 is the zigzag-encoded difference between
+// the token and the previous token (0 before the first), so nearby IDs take
+// one or two bytes. If v&1 is set, a uvarint n follows and the token is
+// repeated n+3 times; shorter repeats are stored as entries with a
+// difference of 0.
+//
+// The output is not entropy coded. It compresses further with a general
+// purpose compressor such as zstd, which callers can apply on top; this module
+// does not depend on one.
+func CompressTokens(tokens []int) []byte {
+	dst := make([]byte, 0, 1+binary.MaxVarintLen64+len(tokens)*2)
+	dst = append(dst, compressVersion)
+	dst = binary.AppendUvarint(dst, uint64(len(tokens)))
+
+	prev := 0
+	for i := 0; i < len(tokens); {
+		token := tokens[i]
+		run := 1
+		for i+run < len(tokens) && tokens[i+run] == token {
+			run++
+		}
+
+		delta := zigzag(int64(token - prev))
+		if run >= minRun {
+			dst = binary.AppendUvarint(dst, delta<<1|1)
+			dst = binary.AppendUvarint(dst, uint64(run-minRun))
+			i += run
+		} else {
+			dst = binary.AppendUvarint(dst, delta<<1)
+			i++
+		}
+		prev = token
+	}
+	return dst
+}
+
+// DecompressTokens decodes data written by CompressTokens. It returns an
+// error wrapping ErrCorruptTokens if data is malformed, and one wrapping
+// ErrTooManyTokens if it holds more than MaxDecompressedTokens tokens: runs
+// let a few bytes stand for millions of tokens.
+func DecompressTokens(data []byte) ([]int, error) {
+	return DecompressTokensLimit(data, MaxDecompressedTokens)
+}
+
+// DecompressTokensLimit is like DecompressTokens, but allows up to maxTokens
+// tokens instead of MaxDecompressedTokens.
+func DecompressTokensLimit(data []byte, maxTokens int) ([]int, error) {
+	if len(data) == 0 || data[0] != compressVersion {
+		return nil, fmt.Errorf("%w: unknown format version", ErrCorruptTokens)
+	}
+	data = data[1:]
+
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: bad token count", ErrCorruptTokens)
+	}
+	data = data[n:]
+	if count > uint64(max(maxTokens, 0)) {
+		return nil, fmt.Errorf("%w: %d tokens, maximum is %d", ErrTooManyTokens, count, maxTokens)
+	}
+
+	// Runs let a few bytes stand for many tokens, so the count is only
+	// trusted as far as the data can back it before growing the slice.
+	tokens := make([]int, 0, min(count, uint64(len(data))))
+	prev := 0
+	for uint64(len(tokens)) < count {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("%w: truncated at token %d", ErrCorruptTokens, len(tokens))
+		}
+		data = data[n:]
+
+		token := prev + int(unzigzag(v>>1))
+		run := uint64(1)
+		if v&1 == 1 {
+			extra, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("%w: truncated run at token %d", ErrCorruptTokens, len(tokens))
+			}
+			data = data[n:]
+			run = extra + minRun
+			if run < extra || run > count-uint64(len(tokens)) {
+				return nil, fmt.Errorf("%w: run exceeds token count", ErrCorruptTokens)
+			}
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, token)
+		}
+		prev = token
+	}
+	if len(data) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrCorruptTokens, len(data))
+	}
+	return tokens, nil
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63) // #nosec G115 - zigzag encoding reinterprets the bits
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1) // #nosec G115 - zigzag decoding reinterprets the bits
+}
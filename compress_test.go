@@ -0,0 +1,95 @@
+package tokenizer
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCompressTokens(t *testing.T) {
+	random := make([]int, 5000)
+	rng := rand.New(rand.NewSource(1))
+	for i := range random {
+		random[i] = rng.Intn(128256)
+	}
+
+	tests := []struct {
+		name   string
+		tokens []int
+	}{
+		{"empty", []int{}},
+		{"single", []int{128000}},
+		{"sentence", []int{128000, 9906, 11, 1917, 0, 128001}},
+		{"short repeats", []int{220, 220, 5, 5, 7}},
+		{"runs", []int{1, 198, 198, 198, 198, 198, 198, 2, 2, 2}},
+		{"extremes", []int{math.MaxInt32, math.MinInt32, 0, -1, math.MaxInt32}},
+		{"random", random},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := CompressTokens(tt.tokens)
+			got, err := DecompressTokens(data)
+			if err != nil {
+				t.Fatalf("DecompressTokens failed: %v", err)
+			}
+			if !EqualTokens(got, tt.tokens) {
+				t.Errorf("Expected %v, got %v", tt.tokens, got)
+			}
+		})
+	}
+
+	if got, want := CompressTokens([]int{198, 198, 198, 198, 198}), []byte{1, 5, 0x99, 0x06, 2}; string(got) != string(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if n := len(CompressTokens(random)); n > 3*len(random)+4 {
+		t.Errorf("Expected at most 3 bytes per token, got %d bytes", n)
+	}
+}
+
+func TestDecompressTokensCorrupt(t *testing.T) {
+	valid := CompressTokens([]int{9906, 11, 11, 11, 11, 1917})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"version", append([]byte{2}, valid[1:]...)},
+		{"truncated", valid[:len(valid)-1]},
+		{"trailing", append(append([]byte(nil), valid...), 0)},
+		{"run too long", []byte{1, 2, 0x03, 0x05}},
+		{"huge count", []byte{1, 0xff, 0xff, 0x03, 0x03, 0x7f}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecompressTokens(tt.data); !errors.Is(err, ErrCorruptTokens) {
+				t.Errorf("Expected ErrCorruptTokens, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDecompressTokensLimit(t *testing.T) {
+	// Ten bytes claiming 64M copies of one token
+	hostile := []byte{1, 0x80, 0x80, 0x80, 0x20, 0x01, 0xfd, 0xff, 0xff, 0x1f}
+	if _, err := DecompressTokens(hostile); !errors.Is(err, ErrTooManyTokens) {
+		t.Errorf("Expected ErrTooManyTokens, got %v", err)
+	}
+	if _, err := DecompressTokens([]byte{1, 0xff, 0xff, 0xff, 0xff, 0x0f, 0x03, 0x7f}); !errors.Is(err, ErrTooManyTokens) {
+		t.Errorf("Expected ErrTooManyTokens for a huge count, got %v", err)
+	}
+
+	data := CompressTokens([]int{7, 7, 7, 7, 7, 7, 7, 7, 7, 7})
+	if _, err := DecompressTokensLimit(data, 9); !errors.Is(err, ErrTooManyTokens) {
+		t.Errorf("Expected ErrTooManyTokens over the limit, got %v", err)
+	}
+	if got, err := DecompressTokensLimit(data, 10); err != nil || len(got) != 10 {
+		t.Errorf("Expected 10 tokens at the limit, got %d (%v)", len(got), err)
+	}
+	if _, err := DecompressTokensLimit(CompressTokens(nil), -1); err != nil {
+		t.Errorf("Expected no tokens to fit a negative limit, got %v", err)
+	}
+}
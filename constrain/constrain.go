@@ -0,0 +1,284 @@
+// Package constrain computes which tokens a model may generate next so that
+// its output keeps matching a constraint, such as a regular expression. The
+// resulting masks are meant for logit processors of inference engines like
+// llama.cpp and vLLM.
+//
+// Constraints are automata over the bytes of the output: CompileRegexp
+// builds one from a regular expression, and NewJSON one that allows valid
+// JSON. A Vocab holds the bytes each token decodes to, and Mask walks them
+// through the automaton, so tokens that split a UTF-8 character or span
+// several parts of the constraint are handled exactly:
+//
+//	re, err := constrain.CompileRegexp(`[0-9]{1,3}(\.[0-9]{1,3}){3}`)
+//	if err != nil {
+//		return err
+//	}
+//	session := constrain.FromLlama3(tokenizer).NewSession(re)
+//	for !session.Done() {
+//		session.Mask().Apply(logits)
+//		// sample id from logits
+//		if err := session.Advance(id); err != nil {
+//			return err
+//		}
+//	}
+package constrain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// ErrTokenNotAllowed is returned when a token would violate the constraint.
+var ErrTokenNotAllowed = errors.New("token not allowed")
+
+// Rejected is the state returned by Automaton.Next for a byte the constraint
+// does not allow.
+const Rejected = -1
+
+// An Automaton recognizes the outputs a constraint allows, one byte at a
+// time. States are non-negative integers chosen by the automaton.
+//
+// Implementations must be safe for concurrent use when they are shared
+// between sessions.
+type Automaton interface {
+	// Start returns the state before any output.
+	Start() int
+
+	// Next returns the state after b is appended to the output in state,
+	// or Rejected if no allowed output continues that way.
+	Next(state int, b byte) int
+
+	// Accepting reports whether the output in state is complete, so that
+	// generation may end.
+	Accepting(state int) bool
+}
+
+// Vocab is the vocabulary of a tokenizer, prepared for computing masks. It is
+// safe for concurrent use.
+type Vocab struct {
+	tokens [][]byte
+	end    []int
+
+	// Tokens in byte order, with the length of the prefix each shares with
+	// the previous one, so Mask can step shared prefixes only once.
+	order []int32
+	lcp   []int32
+	depth int // length of the longest token
+}
+
+// NewVocab prepares a vocabulary for computing masks. tokens[id] holds the
+// bytes token id decodes to; empty entries, as for special tokens, are never
+// allowed. end lists the tokens that stop generation, such as end-of-turn
+// tokens, which are allowed once the output is complete.
+func NewVocab(tokens [][]byte, end ...int) *Vocab {
+	v := &Vocab{tokens: tokens, end: end}
+	for id, token := range tokens {
+		if len(token) > 0 {
+			v.order = append(v.order, int32(id)) // #nosec G115 - vocabularies are far below 2^31 tokens
+			v.depth = max(v.depth, len(token))
+		}
+	}
+	slices.SortFunc(v.order, func(a, b int32) int {
+		return bytes.Compare(tokens[a], tokens[b])
+	})
+
+	v.lcp = make([]int32, len(v.order))
+	for i := 1; i < len(v.order); i++ {
+		prev, cur := tokens[v.order[i-1]], tokens[v.order[i]]
+		n := 0
+		for n < len(prev) && n < len(cur) && prev[n] == cur[n] {
+			n++
+		}
+		v.lcp[i] = int32(n) // #nosec G115 - bounded by the token length
+	}
+	return v
+}
+
+// FromLlama3 prepares the vocabulary of a Llama 3 tokenizer. Special tokens
+// are never allowed, except <|end_of_text|> and <|eot_id|>, which end
+// generation.
+func FromLlama3(t *llama3.Tokenizer) *Vocab {
+	vocab := t.Vocab()
+	tokens := make([][]byte, vocab.Len())
+	for id := range tokens {
+		if !vocab.IsSpecial(id) {
+			tokens[id] = t.DecodeBytes([]int{id})
+		}
+	}
+
+	var end []int
+	for _, tok := range []llama3.SpecialToken{llama3.EndOfText, llama3.EOT} {
+		if id := t.ID(tok); id >= 0 {
+			end = append(end, id)
+		}
+	}
+	return NewVocab(tokens, end...)
+}
+
+// Len returns the number of token IDs, which is the length of masks.
+func (v *Vocab) Len() int {
+	return len(v.tokens)
+}
+
+// Mask returns the tokens that may follow the output in state: those whose
+// bytes a accepts from state and, if the output is complete, the end tokens.
+func (v *Vocab) Mask(a Automaton, state int) Mask {
+	mask := make(Mask, (len(v.tokens)+63)/64)
+	if state == Rejected {
+		return mask
+	}
+
+	states := make([]int, v.depth+1)
+	states[0] = state
+	known := 0 // bytes of the current token with a state in states
+	for i := 0; i < len(v.order); i++ {
+		token := v.tokens[v.order[i]]
+		depth := min(int(v.lcp[i]), known)
+		for depth < len(token) {
+			next := a.Next(states[depth], token[depth])
+			if next == Rejected {
+				break
+			}
+			depth++
+			states[depth] = next
+		}
+		known = depth
+		if depth < len(token) {
+			// Skip the tokens that share the rejected prefix
+			for i+1 < len(v.order) && int(v.lcp[i+1]) > depth {
+				i++
+			}
+			continue
+		}
+		mask.add(int(v.order[i]))
+	}
+
+	if a.Accepting(state) {
+		for _, id := range v.end {
+			mask.add(id)
+		}
+	}
+	return mask
+}
+
+// Mask is a set of token IDs, stored as a bitset.
+type Mask []uint64
+
+func (m Mask) add(id int) {
+	if id >= 0 && id/64 < len(m) {
+		m[id/64] |= 1 << (id % 64)
+	}
+}
+
+// Has reports whether id is in the mask.
+func (m Mask) Has(id int) bool {
+	return id >= 0 && id/64 < len(m) && m[id/64]&(1<<(id%64)) != 0
+}
+
+// Count returns the number of tokens in the mask.
+func (m Mask) Count() int {
+	n := 0
+	for _, w := range m {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// IDs returns the token IDs in the mask in increasing order.
+func (m Mask) IDs() []int {
+	ids := make([]int, 0, m.Count())
+	for i, w := range m {
+		for w != 0 {
+			ids = append(ids, i*64+bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+	return ids
+}
+
+// Apply sets the logits of the tokens not in the mask to negative infinity,
+// so they are never sampled.
+func (m Mask) Apply(logits []float32) {
+	for id := range logits {
+		if !m.Has(id) {
+			logits[id] = float32(math.Inf(-1))
+		}
+	}
+}
+
+// Session tracks the output of one generation under a constraint. Masks are
+// cached per automaton state, so states that repeat, as in long lists, are
+// computed once. A Session is not safe for concurrent use.
+type Session struct {
+	vocab *Vocab
+	a     Automaton
+	state int
+	done  bool
+	masks map[int]Mask
+}
+
+// NewSession starts a generation constrained by a.
+func (v *Vocab) NewSession(a Automaton) *Session {
+	return &Session{vocab: v, a: a, state: a.Start(), masks: make(map[int]Mask)}
+}
+
+// Mask returns the tokens allowed next. The returned mask is shared and must
+// not be modified.
+func (s *Session) Mask() Mask {
+	if s.done {
+		return make(Mask, (s.vocab.Len()+63)/64)
+	}
+	mask, ok := s.masks[s.state]
+	if !ok {
+		mask = s.vocab.Mask(s.a, s.state)
+		s.masks[s.state] = mask
+	}
+	return mask
+}
+
+// Advance appends token id to the output. It returns ErrTokenNotAllowed,
+// leaving the session unchanged, if id is not in Mask.
+func (s *Session) Advance(id int) error {
+	if s.done || id < 0 || id >= s.vocab.Len() {
+		return fmt.Errorf("token %d: %w", id, ErrTokenNotAllowed)
+	}
+	if slices.Contains(s.vocab.end, id) && s.a.Accepting(s.state) {
+		s.done = true
+		return nil
+	}
+
+	token := s.vocab.tokens[id]
+	state := s.state
+	for _, b := range token {
+		if state = s.a.Next(state, b); state == Rejected {
+			break
+		}
+	}
+	if len(token) == 0 || state == Rejected {
+		return fmt.Errorf("token %d: %w", id, ErrTokenNotAllowed)
+	}
+	s.state = state
+	return nil
+}
+
+// State returns the automaton state of the output so far.
+func (s *Session) State() int {
+	return s.state
+}
+
+// Complete reports whether the output so far satisfies the constraint, so an
+// end token is allowed.
+func (s *Session) Complete() bool {
+	return !s.done && s.a.Accepting(s.state)
+}
+
+// Done reports whether an end token has been accepted.
+func (s *Session) Done() bool {
+	return s.done
+}
@@ -0,0 +1,217 @@
+package constrain
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"slices"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// testTokens is a small vocabulary with tokens spanning several characters
+// and tokens splitting a UTF-8 character ("é" is 0xC3 0xA9).
+var testTokens = [][]byte{
+	[]byte("a"), []byte("b"), []byte("ab"), []byte("abc"), []byte("c"),
+	[]byte("1"), []byte("12"), []byte("123"), []byte(" "), []byte("-"),
+	{0xC3}, {0xA9}, []byte("é"), []byte("\n"), nil, // 14 is a special token
+}
+
+const testEnd = 14
+
+func newTestVocab() *Vocab {
+	return NewVocab(testTokens, testEnd)
+}
+
+func mustCompile(t *testing.T, pattern string) *Regexp {
+	t.Helper()
+	re, err := CompileRegexp(pattern)
+	if err != nil {
+		t.Fatalf("CompileRegexp(%q) failed: %v", pattern, err)
+	}
+	return re
+}
+
+func TestRegexpAutomaton(t *testing.T) {
+	tests := []struct {
+		pattern string
+		input   string
+		want    bool
+	}{
+		{`ab+c`, "abbbc", true},
+		{`ab+c`, "ac", false},
+		{`ab+c`, "abcx", false},
+		{`[0-9]{2,3}`, "123", true},
+		{`[0-9]{2,3}`, "1", false},
+		{`[0-9]{2,3}`, "1234", false},
+		{`café|tea`, "café", true},
+		{`café|tea`, "cafe", false},
+		{`\p{Greek}+`, "λόγος", true},
+		{`\p{Greek}+`, "logos", false},
+		{`(?i)yes|no`, "YeS", true},
+		{`a\b.*`, "a b", true},
+		{`a\b.*`, "ab", false},
+		{`^(\w+ ?)*$`, "one two", true},
+		{`.*`, "", true},
+		{`.+`, "", false},
+		{`[^a]`, "\xff", false}, // invalid UTF-8 never matches
+	}
+
+	for _, tt := range tests {
+		re := mustCompile(t, tt.pattern)
+		state := re.Start()
+		for i := 0; i < len(tt.input) && state != Rejected; i++ {
+			state = re.Next(state, tt.input[i])
+		}
+		got := state != Rejected && re.Accepting(state)
+		if got != tt.want {
+			t.Errorf("%q on %q: expected %v, got %v", tt.pattern, tt.input, tt.want, got)
+		}
+		if !utf8.ValidString(tt.input) {
+			continue // the regexp package reads invalid bytes as U+FFFD
+		}
+		if want := regexp.MustCompile(`^(?:` + tt.pattern + `)$`).MatchString(tt.input); got != want {
+			t.Errorf("%q on %q: expected regexp package result %v, got %v", tt.pattern, tt.input, want, got)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	vocab := newTestVocab()
+
+	tests := []struct {
+		name    string
+		pattern string
+		prefix  string
+		want    []int
+	}{
+		{"start", `ab*c`, "", []int{0, 2, 3}},
+		{"middle", `ab*c`, "a", []int{1, 4}},
+		{"complete", `ab*c`, "abc", []int{testEnd}},
+		{"complete or longer", `[0-9]+`, "1", []int{5, 6, 7, testEnd}},
+		{"split character", `é+`, "", []int{10, 12}},
+		{"continuation byte", `é+`, "\xC3", []int{11}},
+		{"no special tokens", `.*`, "", []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 12, testEnd}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := mustCompile(t, tt.pattern)
+			state := re.Start()
+			for i := 0; i < len(tt.prefix); i++ {
+				state = re.Next(state, tt.prefix[i])
+			}
+			got := vocab.Mask(re, state).IDs()
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestMaskMatchesBruteForce checks the shared-prefix walk of Mask against
+// stepping every token on its own.
+func TestMaskMatchesBruteForce(t *testing.T) {
+	vocab := newTestVocab()
+	for _, pattern := range []string{`(ab|c)*1?`, `[a-c]{2}[0-9]+`, `a?é*\n`, `[ -]+`} {
+		re := mustCompile(t, pattern)
+		states := []int{re.Start()}
+		for _, b := range []byte("abc12") {
+			if s := re.Next(states[len(states)-1], b); s != Rejected {
+				states = append(states, s)
+			}
+		}
+		for _, state := range states {
+			mask := vocab.Mask(re, state)
+			for id, token := range testTokens {
+				s := state
+				for i := 0; i < len(token) && s != Rejected; i++ {
+					s = re.Next(s, token[i])
+				}
+				want := len(token) > 0 && s != Rejected || id == testEnd && re.Accepting(state)
+				if mask.Has(id) != want {
+					t.Errorf("%q state %d token %d: expected %v, got %v", pattern, state, id, want, mask.Has(id))
+				}
+			}
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	session := newTestVocab().NewSession(mustCompile(t, `ab*c`))
+
+	if session.Complete() {
+		t.Error("Expected an empty output to be incomplete")
+	}
+	if err := session.Advance(1); !errors.Is(err, ErrTokenNotAllowed) {
+		t.Errorf("Expected ErrTokenNotAllowed for \"b\" first, got %v", err)
+	}
+	for _, id := range []int{2, 1, 4} { // "ab", "b", "c"
+		if !session.Mask().Has(id) {
+			t.Fatalf("Expected token %d in the mask", id)
+		}
+		if err := session.Advance(id); err != nil {
+			t.Fatalf("Advance(%d) failed: %v", id, err)
+		}
+	}
+	if !session.Complete() {
+		t.Error("Expected \"abbc\" to be complete")
+	}
+	if err := session.Advance(testEnd); err != nil {
+		t.Fatalf("Advance(end) failed: %v", err)
+	}
+	if !session.Done() || session.Mask().Count() != 0 {
+		t.Error("Expected no tokens allowed after the end token")
+	}
+	if err := session.Advance(0); !errors.Is(err, ErrTokenNotAllowed) {
+		t.Errorf("Expected ErrTokenNotAllowed after the end, got %v", err)
+	}
+}
+
+func TestMaskApply(t *testing.T) {
+	mask := make(Mask, 1)
+	mask.add(1)
+	logits := []float32{1, 2, 3}
+	mask.Apply(logits)
+	if !math.IsInf(float64(logits[0]), -1) || logits[1] != 2 || !math.IsInf(float64(logits[2]), -1) {
+		t.Errorf("Expected only token 1 to keep its logit, got %v", logits)
+	}
+}
+
+func TestFromLlama3(t *testing.T) {
+	tokenizer, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	vocab := FromLlama3(tokenizer)
+	if vocab.Len() != tokenizer.VocabSize() {
+		t.Errorf("Expected %d tokens, got %d", tokenizer.VocabSize(), vocab.Len())
+	}
+
+	re := mustCompile(t, `[0-9]{1,3}(\.[0-9]{1,3}){3}`)
+	session := vocab.NewSession(re)
+	for _, id := range tokenizer.Encode("192.168.0.1", &llama3.EncodeOptions{}) {
+		if err := session.Advance(id); err != nil {
+			t.Fatalf("Advance(%d) failed: %v", id, err)
+		}
+	}
+	if !session.Complete() {
+		t.Error("Expected an IP address to complete the constraint")
+	}
+
+	eot, _ := tokenizer.GetSpecialTokenID("<|eot_id|>")
+	eos, _ := tokenizer.GetSpecialTokenID("<|end_of_text|>")
+	bos, _ := tokenizer.GetSpecialTokenID("<|begin_of_text|>")
+	mask := session.Mask()
+	if !mask.Has(eot) || !mask.Has(eos) || mask.Has(bos) {
+		t.Error("Expected the end tokens but not <|begin_of_text|> in the mask")
+	}
+	digits := regexp.MustCompile(`^[0-9]{1,2}$`)
+	for _, id := range mask.IDs() {
+		if text := tokenizer.Decode([]int{id}); id != eot && id != eos && !digits.MatchString(text) {
+			t.Errorf("Expected at most two more digits, got token %d %q", id, text)
+		}
+	}
+}
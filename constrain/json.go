@@ -0,0 +1,372 @@
+package constrain
+
+import (
+	"sync"
+)
+
+// DefaultJSONMaxDepth is the nesting limit of JSON automata when
+// JSONOptions.MaxDepth is zero.
+const DefaultJSONMaxDepth = 32
+
+// JSONOptions configures a JSON automaton.
+type JSONOptions struct {
+	// ObjectOnly requires the output to be a JSON object, as chat APIs do in
+	// JSON mode. Otherwise any JSON value is allowed.
+	ObjectOnly bool
+
+	// Compact forbids whitespace outside strings, so a model cannot pad its
+	// output with spaces and newlines indefinitely.
+	Compact bool
+
+	// MaxDepth limits the nesting of objects and arrays.
+	// Zero means DefaultJSONMaxDepth.
+	MaxDepth int
+}
+
+// JSON is an Automaton allowing the outputs that are valid JSON (RFC 8259).
+// It is safe for concurrent use.
+type JSON struct {
+	opts JSONOptions
+
+	mu     sync.Mutex
+	states []jsonState
+	next   [][256]int32
+	index  map[jsonState]int
+}
+
+// Parser modes of jsonState.
+const (
+	jsonValue       uint8 = iota // a value is expected
+	jsonArrayStart               // after '[': a value or ']'
+	jsonObjectStart              // after '{': a key or '}'
+	jsonKey                      // after ',' in an object: a key
+	jsonColon                    // after a key: ':'
+	jsonAfterValue               // after a value: ',', a closing bracket or the end
+	jsonString                   // in a string
+	jsonEscape                   // after '\' in a string
+	jsonUnicode                  // in a \u escape, n hex digits to go
+	jsonUTF8                     // in a multi-byte character, n bytes to go
+	jsonLiteral                  // in true, false or null, n bytes read
+	jsonMinus                    // after a leading '-'
+	jsonZero                     // after a leading 0
+	jsonInt                      // in the integer digits
+	jsonDot                      // after '.'
+	jsonFrac                     // in the fraction digits
+	jsonExp                      // after 'e' or 'E'
+	jsonExpSign                  // after the exponent sign
+	jsonExpDigits                // in the exponent digits
+)
+
+// jsonLiterals are the literal names, indexed by jsonState.x.
+var jsonLiterals = [...]string{"true", "false", "null"}
+
+// jsonState is the state of a JSON parser.
+type jsonState struct {
+	stack string // '{' or '[' for each open container, innermost last
+	mode  uint8
+	key   bool  // the string is an object key
+	n     uint8 // bytes or digits to go, or read, depending on mode
+	x     uint8 // lead byte of a multi-byte character, or literal index
+}
+
+// NewJSON returns a JSON automaton. opts may be nil.
+func NewJSON(opts *JSONOptions) *JSON {
+	j := &JSON{index: make(map[jsonState]int)}
+	if opts != nil {
+		j.opts = *opts
+	}
+	if j.opts.MaxDepth <= 0 {
+		j.opts.MaxDepth = DefaultJSONMaxDepth
+	}
+	j.intern(jsonState{mode: jsonValue})
+	return j
+}
+
+// Start returns the state before any output.
+func (j *JSON) Start() int {
+	return 0
+}
+
+// Next returns the state after b, or Rejected.
+func (j *JSON) Next(state int, b byte) int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if next := j.next[state][b]; next != unknown {
+		return int(next)
+	}
+	next := Rejected
+	if s, ok := j.step(j.states[state], b); ok {
+		next = j.intern(s)
+	}
+	j.next[state][b] = int32(next) // #nosec G115 - state counts are far below 2^31
+	return next
+}
+
+// Accepting reports whether the output in state is a complete JSON text.
+func (j *JSON) Accepting(state int) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s := j.states[state]
+	if s.stack != "" {
+		return false
+	}
+	switch s.mode {
+	case jsonAfterValue, jsonZero, jsonInt, jsonFrac, jsonExpDigits:
+		return true
+	}
+	return false
+}
+
+// intern returns the ID of s, adding it if it is new.
+func (j *JSON) intern(s jsonState) int {
+	if id, ok := j.index[s]; ok {
+		return id
+	}
+	id := len(j.states)
+	j.states = append(j.states, s)
+	var next [256]int32
+	for i := range next {
+		next[i] = unknown
+	}
+	j.next = append(j.next, next)
+	j.index[s] = id
+	return id
+}
+
+// step returns the state after b and whether b is allowed.
+func (j *JSON) step(s jsonState, b byte) (jsonState, bool) {
+	switch s.mode {
+	case jsonValue, jsonArrayStart:
+		if j.space(b) {
+			return s, true
+		}
+		if b == ']' && s.mode == jsonArrayStart {
+			return j.close(s, '[')
+		}
+		return j.value(s, b)
+
+	case jsonObjectStart, jsonKey:
+		switch {
+		case j.space(b):
+			return s, true
+		case b == '"':
+			return jsonState{stack: s.stack, mode: jsonString, key: true}, true
+		case b == '}' && s.mode == jsonObjectStart:
+			return j.close(s, '{')
+		}
+
+	case jsonColon:
+		switch {
+		case j.space(b):
+			return s, true
+		case b == ':':
+			return jsonState{stack: s.stack, mode: jsonValue}, true
+		}
+
+	case jsonAfterValue:
+		return j.afterValue(s, b)
+
+	case jsonString:
+		switch {
+		case b == '"' && s.key:
+			return jsonState{stack: s.stack, mode: jsonColon}, true
+		case b == '"':
+			return jsonState{stack: s.stack, mode: jsonAfterValue}, true
+		case b == '\\':
+			s.mode = jsonEscape
+			return s, true
+		case b < 0x20:
+			return s, false // control characters must be escaped
+		case b < 0x80:
+			return s, true
+		case b >= 0xC2 && b <= 0xDF:
+			s.mode, s.n, s.x = jsonUTF8, 1, b
+			return s, true
+		case b >= 0xE0 && b <= 0xEF:
+			s.mode, s.n, s.x = jsonUTF8, 2, b
+			return s, true
+		case b >= 0xF0 && b <= 0xF4:
+			s.mode, s.n, s.x = jsonUTF8, 3, b
+			return s, true
+		}
+
+	case jsonEscape:
+		switch b {
+		case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			s.mode = jsonString
+			return s, true
+		case 'u':
+			s.mode, s.n = jsonUnicode, 4
+			return s, true
+		}
+
+	case jsonUnicode:
+		if isHexDigit(b) {
+			if s.n--; s.n == 0 {
+				s.mode = jsonString
+			}
+			return s, true
+		}
+
+	case jsonUTF8:
+		// The byte after the lead byte has a narrower range for some lead
+		// bytes, to rule out overlong forms, surrogates and values above
+		// U+10FFFF.
+		lo, hi := byte(0x80), byte(0xBF)
+		switch s.x {
+		case 0xE0:
+			lo = 0xA0
+		case 0xED:
+			hi = 0x9F
+		case 0xF0:
+			lo = 0x90
+		case 0xF4:
+			hi = 0x8F
+		}
+		if b >= lo && b <= hi {
+			s.x = 0
+			if s.n--; s.n == 0 {
+				s.mode = jsonString
+			}
+			return s, true
+		}
+
+	case jsonLiteral:
+		literal := jsonLiterals[s.x]
+		if b == literal[s.n] {
+			if s.n++; int(s.n) == len(literal) {
+				return jsonState{stack: s.stack, mode: jsonAfterValue}, true
+			}
+			return s, true
+		}
+
+	case jsonMinus:
+		switch {
+		case b == '0':
+			s.mode = jsonZero
+			return s, true
+		case b >= '1' && b <= '9':
+			s.mode = jsonInt
+			return s, true
+		}
+
+	case jsonZero, jsonInt, jsonFrac, jsonExpDigits:
+		switch {
+		case isDigit(b) && s.mode != jsonZero:
+			return s, true
+		case b == '.' && (s.mode == jsonZero || s.mode == jsonInt):
+			s.mode = jsonDot
+			return s, true
+		case (b == 'e' || b == 'E') && s.mode != jsonExpDigits:
+			s.mode = jsonExp
+			return s, true
+		}
+		// The number ends before b
+		return j.afterValue(jsonState{stack: s.stack, mode: jsonAfterValue}, b)
+
+	case jsonDot:
+		if isDigit(b) {
+			s.mode = jsonFrac
+			return s, true
+		}
+
+	case jsonExp:
+		switch {
+		case b == '+' || b == '-':
+			s.mode = jsonExpSign
+			return s, true
+		case isDigit(b):
+			s.mode = jsonExpDigits
+			return s, true
+		}
+
+	case jsonExpSign:
+		if isDigit(b) {
+			s.mode = jsonExpDigits
+			return s, true
+		}
+	}
+	return s, false
+}
+
+// value starts a value with b.
+func (j *JSON) value(s jsonState, b byte) (jsonState, bool) {
+	if j.opts.ObjectOnly && s.stack == "" && b != '{' {
+		return s, false
+	}
+	next := jsonState{stack: s.stack}
+	switch {
+	case b == '{' || b == '[':
+		if len(s.stack) >= j.opts.MaxDepth {
+			return s, false
+		}
+		next.stack += string(b)
+		next.mode = jsonObjectStart
+		if b == '[' {
+			next.mode = jsonArrayStart
+		}
+	case b == '"':
+		next.mode = jsonString
+	case b == '-':
+		next.mode = jsonMinus
+	case b == '0':
+		next.mode = jsonZero
+	case b >= '1' && b <= '9':
+		next.mode = jsonInt
+	case b == 't' || b == 'f' || b == 'n':
+		next.mode, next.n = jsonLiteral, 1
+		for i, literal := range jsonLiterals {
+			if literal[0] == b {
+				next.x = uint8(i) // #nosec G115 - three literals
+			}
+		}
+	default:
+		return s, false
+	}
+	return next, true
+}
+
+// afterValue handles b after a complete value.
+func (j *JSON) afterValue(s jsonState, b byte) (jsonState, bool) {
+	if j.space(b) {
+		return s, true
+	}
+	if s.stack == "" {
+		return s, false
+	}
+	top := s.stack[len(s.stack)-1]
+	switch {
+	case b == ',' && top == '[':
+		return jsonState{stack: s.stack, mode: jsonValue}, true
+	case b == ',' && top == '{':
+		return jsonState{stack: s.stack, mode: jsonKey}, true
+	case b == ']':
+		return j.close(s, '[')
+	case b == '}':
+		return j.close(s, '{')
+	}
+	return s, false
+}
+
+// close ends the innermost container if it was opened with open.
+func (j *JSON) close(s jsonState, open byte) (jsonState, bool) {
+	if s.stack == "" || s.stack[len(s.stack)-1] != open {
+		return s, false
+	}
+	return jsonState{stack: s.stack[:len(s.stack)-1], mode: jsonAfterValue}, true
+}
+
+// space reports whether b is whitespace allowed between tokens.
+func (j *JSON) space(b byte) bool {
+	return !j.opts.Compact && (b == ' ' || b == '\t' || b == '\n' || b == '\r')
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isHexDigit(b byte) bool {
+	return isDigit(b) || b >= 'a' && b <= 'f' || b >= 'A' && b <= 'F'
+}
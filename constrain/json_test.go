@@ -0,0 +1,142 @@
+package constrain
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// runAutomaton feeds input to a and reports whether it is accepted.
+func runAutomaton(a Automaton, input string) bool {
+	state := a.Start()
+	for i := 0; i < len(input) && state != Rejected; i++ {
+		state = a.Next(state, input[i])
+	}
+	return state != Rejected && a.Accepting(state)
+}
+
+func TestJSONAutomaton(t *testing.T) {
+	inputs := []string{
+		`{}`, `[]`, `""`, `0`, `-0`, `12`, `-1.5e+10`, `1E3`, `0.25`, `true`, `false`, `null`,
+		` { "a" : [ 1 , 2.0 , "x" ] , "b" : { } } `,
+		`{"nested": [[[]]], "esc": "\"\\\/\b\f\n\r\té"}`,
+		`"ünïcödé ✓ 🙂"`, `["🙂"]`,
+		`{`, `[1,]`, `{"a"}`, `{"a":}`, `{"a":1,}`, `{,}`, `[1 2]`, `01`, `-`, `1.`, `1e`, `.5`, `+1`,
+		`tru`, `nul`, `truex`, `"abc`, "\"a\x01\"", `"\x"`, `"\u12"`, `"\u12G4"`, `}`, `]`, `[}`, `{]`,
+		"\"\xC0\xAF\"", "\"\xED\xA0\x80\"", "\"\xF4\x90\x80\x80\"", "\"\xE0\x80\x80\"", "\"\xFF\"",
+		`{"a":1}{}`, `1 2`, ``, `   `,
+	}
+
+	tests := []struct {
+		name   string
+		opts   *JSONOptions
+		accept func(string) bool
+	}{
+		{"any value", nil, validJSON},
+		{"object only", &JSONOptions{ObjectOnly: true}, func(s string) bool {
+			return validJSON(s) && strings.HasPrefix(strings.TrimSpace(s), "{")
+		}},
+		{"compact", &JSONOptions{Compact: true}, func(s string) bool {
+			return validJSON(s) && !strings.ContainsAny(stripStrings(s), " \t\n\r")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := NewJSON(tt.opts)
+			for _, input := range inputs {
+				if got, want := runAutomaton(j, input), tt.accept(input); got != want {
+					t.Errorf("%q: expected %v, got %v", input, want, got)
+				}
+			}
+		})
+	}
+}
+
+// validJSON reports whether s is JSON in valid UTF-8, which RFC 8259
+// requires but json.Valid does not check.
+func validJSON(s string) bool {
+	return json.Valid([]byte(s)) && utf8.ValidString(s)
+}
+
+// stripStrings removes the contents of the JSON strings in s.
+func stripStrings(s string) string {
+	var b strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+			b.WriteByte(c)
+		case !inString:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func TestJSONMaxDepth(t *testing.T) {
+	j := NewJSON(&JSONOptions{MaxDepth: 3})
+	if !runAutomaton(j, `[[[1]]]`) {
+		t.Error("Expected depth 3 to be allowed")
+	}
+	if runAutomaton(j, `[[[[1]]]]`) {
+		t.Error("Expected depth 4 to be rejected")
+	}
+}
+
+func TestJSONMask(t *testing.T) {
+	tokenizer, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	vocab := FromLlama3(tokenizer)
+	j := NewJSON(&JSONOptions{ObjectOnly: true})
+
+	text := `{"name": "Ada Lovelace", "born": 1815, "tags": ["math", "poetry"], "alive": false}`
+	session := vocab.NewSession(j)
+	prefix := ""
+	for _, id := range tokenizer.Encode(text, &llama3.EncodeOptions{}) {
+		mask := session.Mask()
+		if !mask.Has(id) {
+			t.Fatalf("Expected token %d %q after %q in the mask", id, tokenizer.Decode([]int{id}), prefix)
+		}
+		if err := session.Advance(id); err != nil {
+			t.Fatalf("Advance(%d) failed: %v", id, err)
+		}
+		prefix += tokenizer.Decode([]int{id})
+	}
+	if !session.Complete() {
+		t.Fatal("Expected the object to complete the constraint")
+	}
+
+	// Every token in a mask keeps the output a valid JSON prefix
+	eot, _ := tokenizer.GetSpecialTokenID("<|eot_id|>")
+	for _, partial := range []string{`{"name": "Ada`, `{"born": 18`, `{"tags": [`, `{"alive": f`} {
+		state := j.Start()
+		for i := 0; i < len(partial); i++ {
+			state = j.Next(state, partial[i])
+		}
+		mask := vocab.Mask(j, state)
+		if mask.Count() == 0 || mask.Has(eot) {
+			t.Errorf("%q: expected a non-empty mask without end tokens, got %d tokens", partial, mask.Count())
+		}
+		for _, id := range mask.IDs() {
+			s := state
+			for _, b := range tokenizer.DecodeBytes([]int{id}) {
+				s = j.Next(s, b)
+			}
+			if s == Rejected {
+				t.Fatalf("%q: token %d %q in the mask is rejected", partial, id, tokenizer.Decode([]int{id}))
+			}
+		}
+	}
+}
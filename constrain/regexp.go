@@ -0,0 +1,242 @@
+package constrain
+
+import (
+	"encoding/binary"
+	"regexp/syntax"
+	"slices"
+	"sync"
+	"unicode/utf8"
+)
+
+// unknown marks a transition of a Regexp state that is not computed yet.
+const unknown = -2
+
+// Regexp is an Automaton allowing the outputs that match a regular
+// expression in full. It is safe for concurrent use.
+//
+// Outputs must be valid UTF-8. The expression is simulated as a DFA over
+// bytes, whose states are built as masks need them. Case-insensitive
+// classes may allow a token that starts a multi-byte character no
+// completion of which matches; the next mask then is empty.
+type Regexp struct {
+	prog *syntax.Prog
+
+	mu     sync.Mutex
+	states []*regexpState
+	index  map[string]int
+}
+
+// regexpState is a DFA state: the threads of the program waiting for the
+// next character, the character before them, and the leading bytes of a
+// character not yet complete.
+type regexpState struct {
+	pcs     []uint32
+	prev    rune // -1 at the start of the output
+	partial []byte
+	accept  bool
+	next    [256]int32
+}
+
+// CompileRegexp parses a regular expression in the syntax of the regexp
+// package. Outputs must match it in full, as if it were enclosed in ^ and $.
+func CompileRegexp(pattern string) (*Regexp, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Regexp{prog: prog, index: make(map[string]int)}
+	r.intern([]uint32{uint32(prog.Start)}, -1, nil) // #nosec G115 - program counters are small
+	return r, nil
+}
+
+// Start returns the state before any output.
+func (r *Regexp) Start() int {
+	return 0
+}
+
+// Next returns the state after b, or Rejected.
+func (r *Regexp) Next(state int, b byte) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.states[state]
+	if next := s.next[b]; next != unknown {
+		return int(next)
+	}
+	next := r.step(s, b)
+	s.next[b] = int32(next) // #nosec G115 - state counts are far below 2^31
+	return next
+}
+
+// Accepting reports whether the output in state matches the expression.
+func (r *Regexp) Accepting(state int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.states[state].accept
+}
+
+// step computes the transition of s on b.
+func (r *Regexp) step(s *regexpState, b byte) int {
+	partial := append(s.partial[:len(s.partial):len(s.partial)], b)
+	if !utf8.FullRune(partial) {
+		// A character is incomplete: continue if some thread accepts a
+		// character that starts with these bytes.
+		lo, hi := runeRange(partial)
+		for _, pc := range r.closure(s.pcs, s.prev, lo) {
+			if overlaps(&r.prog.Inst[pc], lo, hi) {
+				return r.intern(s.pcs, s.prev, partial)
+			}
+		}
+		return Rejected
+	}
+
+	c, size := utf8.DecodeRune(partial)
+	if c == utf8.RuneError && size <= 1 {
+		return Rejected
+	}
+	var pcs []uint32
+	for _, pc := range r.closure(s.pcs, s.prev, c) {
+		if inst := &r.prog.Inst[pc]; inst.Op != syntax.InstMatch && matchRune(inst, c) {
+			pcs = append(pcs, inst.Out)
+		}
+	}
+	if len(pcs) == 0 {
+		return Rejected
+	}
+	slices.Sort(pcs)
+	return r.intern(slices.Compact(pcs), c, nil)
+}
+
+// intern returns the ID of the state, adding it if it is new.
+func (r *Regexp) intern(pcs []uint32, prev rune, partial []byte) int {
+	// Only the class of the previous character matters to assertions
+	switch {
+	case prev < 0, prev == '\n':
+	case syntax.IsWordChar(prev):
+		prev = 'a'
+	default:
+		prev = ' '
+	}
+
+	key := make([]byte, 0, 8+len(partial)+4*len(pcs))
+	key = binary.AppendVarint(key, int64(prev))
+	key = append(key, byte(len(partial)))
+	key = append(key, partial...)
+	for _, pc := range pcs {
+		key = binary.AppendUvarint(key, uint64(pc))
+	}
+	if id, ok := r.index[string(key)]; ok {
+		return id
+	}
+
+	s := &regexpState{pcs: pcs, prev: prev, partial: partial}
+	for i := range s.next {
+		s.next[i] = unknown
+	}
+	if len(partial) == 0 {
+		for _, pc := range r.closure(pcs, prev, -1) {
+			if r.prog.Inst[pc].Op == syntax.InstMatch {
+				s.accept = true
+				break
+			}
+		}
+	}
+	id := len(r.states)
+	r.states = append(r.states, s)
+	r.index[string(key)] = id
+	return id
+}
+
+// closure returns the instructions that consume a character or match,
+// reachable from pcs before next, which is -1 at the end of the output.
+func (r *Regexp) closure(pcs []uint32, prev, next rune) []uint32 {
+	context := syntax.EmptyOpContext(prev, next)
+	seen := make(map[uint32]bool)
+	var out []uint32
+	var visit func(pc uint32)
+	visit = func(pc uint32) {
+		if seen[pc] {
+			return
+		}
+		seen[pc] = true
+		inst := &r.prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			visit(inst.Out)
+			visit(inst.Arg)
+		case syntax.InstCapture, syntax.InstNop:
+			visit(inst.Out)
+		case syntax.InstEmptyWidth:
+			if syntax.EmptyOp(inst.Arg)&^context == 0 {
+				visit(inst.Out)
+			}
+		case syntax.InstMatch, syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			out = append(out, pc)
+		}
+	}
+	for _, pc := range pcs {
+		visit(pc)
+	}
+	return out
+}
+
+// matchRune reports whether the consuming instruction inst matches c.
+func matchRune(inst *syntax.Inst, c rune) bool {
+	switch inst.Op {
+	case syntax.InstRuneAny:
+		return true
+	case syntax.InstRuneAnyNotNL:
+		return c != '\n'
+	default:
+		return inst.MatchRune(c)
+	}
+}
+
+// overlaps reports whether inst may match a character in [lo, hi].
+func overlaps(inst *syntax.Inst, lo, hi rune) bool {
+	switch inst.Op {
+	case syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+		return true
+	case syntax.InstMatch:
+		return false
+	}
+	if syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+		return true // the folded forms are not listed
+	}
+	runes := inst.Rune
+	if len(runes) == 1 {
+		return lo <= runes[0] && runes[0] <= hi
+	}
+	for i := 0; i+1 < len(runes); i += 2 {
+		if runes[i] <= hi && lo <= runes[i+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// runeRange returns the smallest and largest character whose UTF-8
+// encoding starts with the incomplete prefix p.
+func runeRange(p []byte) (lo, hi rune) {
+	var size int
+	var first, least, most rune
+	switch {
+	case p[0] >= 0xF0:
+		size, first, least, most = 4, rune(p[0]&0x07), 0x10000, utf8.MaxRune
+	case p[0] >= 0xE0:
+		size, first, least, most = 3, rune(p[0]&0x0F), 0x800, 0xFFFF
+	default:
+		size, first, least, most = 2, rune(p[0]&0x1F), 0x80, 0x7FF
+	}
+	v := first
+	for _, b := range p[1:] {
+		v = v<<6 | rune(b&0x3F)
+	}
+	rest := 6 * (size - len(p))
+	return max(v<<rest, least), min(v<<rest|(1<<rest-1), most)
+}
@@ -0,0 +1,152 @@
+// Package tokenizerotel instruments tokenizers and the tokenization service
+// with OpenTelemetry.
+//
+// It lives in its own module so the tokenizer packages keep their
+// standard-library-only dependency set; only applications that import this
+// package pull in OpenTelemetry.
+//
+//	tok, err := llama3.New(tokenizerotel.WithOTel(tracerProvider, meterProvider))
+package tokenizerotel
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// ScopeName is the instrumentation scope used for tracers and meters.
+const ScopeName = "github.com/agentstation/tokenizer"
+
+// DefaultMinBytes is the default size threshold for instrumented operations.
+// Smaller operations take microseconds, and a span would cost more than the
+// tokenization itself.
+const DefaultMinBytes = 4096
+
+// Option configures the tokenizer instrumentation.
+type Option func(*settings)
+
+type settings struct {
+	minBytes int
+}
+
+// WithMinBytes sets the minimum text size of an instrumented operation.
+func WithMinBytes(n int) Option {
+	return func(s *settings) {
+		s.minBytes = n
+	}
+}
+
+// Observer records tokenizer operations as spans and metrics.
+// It implements llama3.Observer.
+type Observer struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	tokens   metric.Int64Counter
+	bytes    metric.Int64Counter
+}
+
+// NewObserver creates an Observer using the given providers. A nil provider
+// selects the corresponding global provider.
+func NewObserver(tp trace.TracerProvider, mp metric.MeterProvider) (*Observer, error) {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(ScopeName)
+
+	duration, err := meter.Float64Histogram("tokenizer.operation.duration",
+		metric.WithDescription("Duration of tokenizer operations."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := meter.Int64Counter("tokenizer.tokens",
+		metric.WithDescription("Tokens produced or decoded."),
+		metric.WithUnit("{token}"))
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := meter.Int64Counter("tokenizer.bytes",
+		metric.WithDescription("Bytes of text encoded or decoded."),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Observer{
+		tracer:   tp.Tracer(ScopeName),
+		duration: duration,
+		tokens:   tokens,
+		bytes:    bytes,
+	}, nil
+}
+
+// Observe records o as a span with its original timing and updates the
+// operation metrics. The span is a child of the span in o.Context, so
+// operations run with EncodeContext appear in the caller's trace; operations
+// without a context get root spans.
+func (obs *Observer) Observe(o llama3.Observation) {
+	ctx := o.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	op := attribute.String("tokenizer.operation", string(o.Op))
+
+	_, span := obs.tracer.Start(ctx, "tokenizer."+string(o.Op),
+		trace.WithTimestamp(o.Start),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			op,
+			attribute.Int("tokenizer.bytes", o.Bytes),
+			attribute.Int("tokenizer.tokens", o.Tokens),
+		))
+	span.End(trace.WithTimestamp(o.Start.Add(o.Duration)))
+
+	attrs := metric.WithAttributes(op)
+	obs.duration.Record(ctx, o.Duration.Seconds(), attrs)
+	obs.tokens.Add(ctx, int64(o.Tokens), attrs)
+	obs.bytes.Add(ctx, int64(o.Bytes), attrs)
+}
+
+// WithOTel instruments a llama3 tokenizer with spans and metrics for encode
+// and decode operations over the size threshold (DefaultMinBytes unless set
+// with WithMinBytes). Errors creating instruments are reported to the global
+// OpenTelemetry error handler, and instrumentation is skipped.
+func WithOTel(tp trace.TracerProvider, mp metric.MeterProvider, opts ...Option) llama3.Option {
+	s := settings{minBytes: DefaultMinBytes}
+	for _, opt := range opts {
+		opt(&s)
+	}
+
+	obs, err := NewObserver(tp, mp)
+	if err != nil {
+		otel.Handle(err)
+		return llama3.WithObserver(nil, 0)
+	}
+	return llama3.WithObserver(obs, s.minBytes)
+}
+
+// Handler wraps a tokenization service handler with the standard
+// OpenTelemetry HTTP server instrumentation.
+func Handler(h http.Handler, tp trace.TracerProvider, mp metric.MeterProvider) http.Handler {
+	var opts []otelhttp.Option
+	if tp != nil {
+		opts = append(opts, otelhttp.WithTracerProvider(tp))
+	}
+	if mp != nil {
+		opts = append(opts, otelhttp.WithMeterProvider(mp))
+	}
+	return otelhttp.NewHandler(h, "tokenizer", opts...)
+}
+
+// Make sure the Observer keeps satisfying llama3.Observer.
+var _ llama3.Observer = (*Observer)(nil)
@@ -0,0 +1,106 @@
+package tokenizerotel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestWithOTel(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	tok, err := llama3.New(WithOTel(tp, mp, WithMinBytes(100)))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	long := strings.Repeat("The quick brown fox. ", 10)
+	tokens := tok.Encode(long, nil)
+	tok.Encode("short", nil)
+	tok.Decode(tokens)
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(ended))
+	}
+	if ended[0].Name() != "tokenizer.encode" || ended[1].Name() != "tokenizer.decode" {
+		t.Errorf("Unexpected span names: %q, %q", ended[0].Name(), ended[1].Name())
+	}
+	if !ended[0].EndTime().After(ended[0].StartTime()) {
+		t.Error("Expected span to cover the operation")
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+	var total int64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name != "tokenizer.tokens" {
+				continue
+			}
+			for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+				total += dp.Value
+			}
+		}
+	}
+	if want := int64(2 * len(tokens)); total != want {
+		t.Errorf("Expected %d tokens recorded, got %d", want, total)
+	}
+}
+
+func TestObserverParentSpan(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	tok, err := llama3.New(WithOTel(tp, nil, WithMinBytes(0)))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	if _, err := tok.EncodeContext(ctx, "Hello, world!", nil); err != nil {
+		t.Fatalf("EncodeContext failed: %v", err)
+	}
+	parent.End()
+	tok.Encode("Hello, world!", nil)
+
+	ended := spans.Ended()
+	if len(ended) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(ended))
+	}
+	child, root := ended[0], ended[2]
+	if child.Parent().SpanID() != parent.SpanContext().SpanID() || child.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Errorf("Expected the EncodeContext span to be a child of the caller's span, got parent %v", child.Parent().SpanID())
+	}
+	if root.Parent().IsValid() {
+		t.Errorf("Expected the Encode span to be a root span, got parent %v", root.Parent().SpanID())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), tp, nil)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/count", nil))
+	if len(spans.Ended()) != 1 {
+		t.Errorf("Expected 1 server span, got %d", len(spans.Ended()))
+	}
+}
@@ -0,0 +1,113 @@
+// Package cost turns token counts into dollar estimates from per-1k-token
+// prices, so tools that count tokens can report what a request costs:
+//
+//	price := cost.Pricing{Input: 0.003, Output: 0.015}
+//	estimate := price.Estimate(inputTokens, 500)
+//	fmt.Printf("$%.4f\n", estimate.Total)
+//
+// Prices are configuration, not data of this package: they differ between
+// providers and change over time. Prices reads a table of them from JSON.
+package cost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+)
+
+// Pricing is the price of a model's tokens in dollars per 1,000 tokens.
+type Pricing struct {
+	Input  float64 `json:"input"`  // per 1,000 prompt tokens
+	Output float64 `json:"output"` // per 1,000 generated tokens
+}
+
+// Validate checks that the prices are finite and not negative.
+func (p Pricing) Validate() error {
+	for _, price := range []float64{p.Input, p.Output} {
+		if price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+			return fmt.Errorf("invalid price %v: must be a non-negative number", price)
+		}
+	}
+	return nil
+}
+
+// Estimate is the cost of a request.
+type Estimate struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	InputCost    float64 `json:"input_cost"`
+	OutputCost   float64 `json:"output_cost"`
+	Total        float64 `json:"total"`
+}
+
+// Estimate returns the cost of a request with inputTokens prompt tokens and
+// outputTokens generated tokens.
+func (p Pricing) Estimate(inputTokens, outputTokens int) Estimate {
+	e := Estimate{
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		InputCost:    float64(inputTokens) * p.Input / 1000,
+		OutputCost:   float64(outputTokens) * p.Output / 1000,
+	}
+	e.Total = e.InputCost + e.OutputCost
+	return e
+}
+
+// Add returns the combined cost of e and other, as for the requests of a
+// batch.
+func (e Estimate) Add(other Estimate) Estimate {
+	return Estimate{
+		InputTokens:  e.InputTokens + other.InputTokens,
+		OutputTokens: e.OutputTokens + other.OutputTokens,
+		InputCost:    e.InputCost + other.InputCost,
+		OutputCost:   e.OutputCost + other.OutputCost,
+		Total:        e.Total + other.Total,
+	}
+}
+
+// Prices maps model names to their pricing.
+type Prices map[string]Pricing
+
+// ParsePrices parses a JSON object mapping model names to prices:
+//
+//	{
+//	  "llama-3.1-8b": {"input": 0.0002, "output": 0.0002},
+//	  "llama-3.1-405b": {"input": 0.003, "output": 0.003}
+//	}
+func ParsePrices(data []byte) (Prices, error) {
+	var prices Prices
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("invalid prices: %w", err)
+	}
+	if len(prices) == 0 {
+		return nil, errors.New("invalid prices: no models")
+	}
+	for model, p := range prices {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("model %q: %w", model, err)
+		}
+	}
+	return prices, nil
+}
+
+// LoadPrices reads a price table in the format of ParsePrices from a file.
+func LoadPrices(path string) (Prices, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path is provided by the caller
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prices: %w", err)
+	}
+	return ParsePrices(data)
+}
+
+// Models returns the model names in sorted order.
+func (p Prices) Models() []string {
+	models := make([]string, 0, len(p))
+	for model := range p {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
@@ -0,0 +1,76 @@
+package cost
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	price := Pricing{Input: 0.003, Output: 0.015}
+	e := price.Estimate(2000, 500)
+
+	want := Estimate{InputTokens: 2000, OutputTokens: 500, InputCost: 0.006, OutputCost: 0.0075, Total: 0.0135}
+	if e.InputTokens != want.InputTokens || e.OutputTokens != want.OutputTokens ||
+		!near(e.InputCost, want.InputCost) || !near(e.OutputCost, want.OutputCost) || !near(e.Total, want.Total) {
+		t.Errorf("Expected %+v, got %+v", want, e)
+	}
+
+	sum := e.Add(price.Estimate(1000, 0))
+	if sum.InputTokens != 3000 || sum.OutputTokens != 500 || !near(sum.Total, 0.0165) {
+		t.Errorf("Expected 3000/500 tokens for $0.0165, got %+v", sum)
+	}
+}
+
+func TestParsePrices(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", `{"b": {"input": 0.001, "output": 0.002}, "a": {"input": 0, "output": 0}}`, false},
+		{"not json", `{`, true},
+		{"empty", `{}`, true},
+		{"negative", `{"a": {"input": -1, "output": 0}}`, true},
+		{"wrong type", `{"a": {"input": "free"}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices, err := ParsePrices([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && !slices.Equal(prices.Models(), []string{"a", "b"}) {
+				t.Errorf("Expected models [a b], got %v", prices.Models())
+			}
+		})
+	}
+}
+
+func TestLoadPrices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	if err := os.WriteFile(path, []byte(`{"m": {"input": 0.5, "output": 1.5}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	prices, err := LoadPrices(path)
+	if err != nil {
+		t.Fatalf("LoadPrices failed: %v", err)
+	}
+	if prices["m"] != (Pricing{Input: 0.5, Output: 1.5}) {
+		t.Errorf("Expected {0.5 1.5}, got %+v", prices["m"])
+	}
+
+	if _, err := LoadPrices(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if err := (Pricing{Input: math.NaN()}).Validate(); err == nil {
+		t.Error("Expected an error for a NaN price")
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
@@ -0,0 +1,155 @@
+package llama3
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// addedTokens is a snapshot of the special tokens added with
+// AddSpecialTokens. A snapshot is never modified once published.
+type addedTokens struct {
+	first  int            // ID of tokens[0], the size of the loaded vocabulary
+	tokens []string       // added tokens in ID order
+	lookup map[string]int // token to ID
+}
+
+// addedVocab holds the current addedTokens snapshot. Readers load it with
+// one atomic load and no locking; mu only serializes AddSpecialTokens
+// calls. Views made with WithCache share it with their tokenizer.
+type addedVocab struct {
+	mu      sync.Mutex
+	current atomic.Pointer[addedTokens]
+}
+
+// AddSpecialTokens adds special tokens to the vocabulary at run time, with
+// IDs following the last token, and returns the ID of each. Tokens already
+// in the vocabulary keep their IDs, so adding is idempotent. Tokens must
+// have the form <|name|>, and name must not contain "|>".
+//
+// Encode recognizes the new tokens and Decode, TokenText, TokenID and
+// VocabSize report them as soon as AddSpecialTokens returns, as do
+// FitString, Decorations, ScriptStats, GGUFMetadata and scanners created
+// afterwards. Calls in progress are not disturbed: the loaded vocabulary
+// never changes, and the added tokens are copied on write. Each call copies
+// the tokens added before it into a new lookup table and publishes the copy
+// with an atomic pointer swap, so reads never take a lock. The price is memory and time
+// proportional to the number of added tokens on every call; add tokens in
+// one call where possible. A replaced table is freed once no call still
+// uses it.
+//
+// Added tokens are not kept by WriteSnapshot or PruneVocabulary.
+func (t *Tokenizer) AddSpecialTokens(tokens ...string) ([]int, error) {
+	for _, token := range tokens {
+		if len(token) < 4 || !isSpecialToken(token) || strings.Index(token[2:], "|>") != len(token)-4 {
+			return nil, NewTokenError("add special token", token, ErrInvalidToken)
+		}
+	}
+
+	t.added.mu.Lock()
+	defer t.added.mu.Unlock()
+
+	old := t.added.current.Load()
+	next := &addedTokens{first: len(t.tokens)}
+	if old != nil {
+		next.tokens = append(next.tokens, old.tokens...)
+	}
+	ids := make([]int, len(tokens))
+	lookup := make(map[string]int, len(next.tokens)+len(tokens))
+	for i, token := range next.tokens {
+		lookup[token] = next.first + i
+	}
+	for i, token := range tokens {
+		if id, ok := t.tokenLookup[token]; ok {
+			ids[i] = id
+			continue
+		}
+		if id, ok := lookup[token]; ok {
+			ids[i] = id
+			continue
+		}
+		ids[i] = next.first + len(next.tokens)
+		lookup[token] = ids[i]
+		next.tokens = append(next.tokens, token)
+	}
+	next.lookup = lookup
+
+	if old == nil || len(next.tokens) > len(old.tokens) {
+		t.added.current.Store(next)
+	}
+	return ids, nil
+}
+
+// loadAdded returns the current snapshot of added tokens, or nil if none
+// were added.
+func (t *Tokenizer) loadAdded() *addedTokens {
+	if t.added == nil {
+		return nil
+	}
+	return t.added.current.Load()
+}
+
+// specialTokenID returns the ID of the special token s, loaded or added.
+func (t *Tokenizer) specialTokenID(s string) (int, bool) {
+	if id, ok := t.tokenLookup[s]; ok {
+		return id, true
+	}
+	if a := t.loadAdded(); a != nil {
+		id, ok := a.lookup[s]
+		return id, ok
+	}
+	return 0, false
+}
+
+// addedToken returns the text of id if it is an added token.
+func (t *Tokenizer) addedToken(id int) (string, bool) {
+	a := t.loadAdded()
+	if a == nil || id < a.first || id >= a.first+len(a.tokens) {
+		return "", false
+	}
+	return a.tokens[id-a.first], true
+}
+
+// numAdded returns the number of added tokens.
+func (t *Tokenizer) numAdded() int {
+	if a := t.loadAdded(); a != nil {
+		return len(a.tokens)
+	}
+	return 0
+}
+
+// tokenBytes returns the bytes id decodes to, for loaded and added tokens
+// alike, and false if id is not in the vocabulary. Every lookup of a token's
+// bytes by ID outside the encoder goes through it or tokenLen.
+func (t *Tokenizer) tokenBytes(id int) ([]byte, bool) {
+	if id >= 0 && id < len(t.tokens) {
+		return decodeTokenBytes(t.tokens[id]), true
+	}
+	if text, ok := t.addedToken(id); ok {
+		return []byte(text), true
+	}
+	return nil, false
+}
+
+// tokenLen returns the number of bytes id decodes to, like tokenBytes
+// without allocating, and false if id is not in the vocabulary.
+func (t *Tokenizer) tokenLen(id int) (int, bool) {
+	if id >= 0 && id < len(t.tokens) {
+		// Tokens hold one rune per byte; special tokens are ASCII
+		return utf8.RuneCountInString(t.tokens[id]), true
+	}
+	if text, ok := t.addedToken(id); ok {
+		return len(text), true
+	}
+	return 0, false
+}
+
+// specialTokens returns the loaded and added special tokens in ID order.
+func (t *Tokenizer) specialTokens() []string {
+	special := t.tokens[t.regularLen:len(t.tokens):len(t.tokens)]
+	if a := t.loadAdded(); a != nil {
+		special = append(special, a.tokens...)
+	}
+	return special
+}
@@ -0,0 +1,149 @@
+package llama3
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAddSpecialTokens(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	size := tokenizer.VocabSize()
+	before := tokenizer.Encode("<|tool|>x", noSpecialTokens)
+
+	ids, err := tokenizer.AddSpecialTokens("<|tool|>", "<|eot_id|>", "<|tool|>", "<|result|>")
+	if err != nil {
+		t.Fatalf("AddSpecialTokens failed: %v", err)
+	}
+	want := []int{size, tokenizer.ID(EOT), size, size + 1}
+	if !equalIntSlices(ids, want) {
+		t.Errorf("Expected IDs %v, got %v", want, ids)
+	}
+	if got := tokenizer.VocabSize(); got != size+2 {
+		t.Errorf("Expected vocabulary size %d, got %d", size+2, got)
+	}
+
+	x := tokenizer.Encode("x", noSpecialTokens)
+	if got := tokenizer.Encode("<|tool|>x", noSpecialTokens); !equalIntSlices(got, append([]int{size}, x...)) {
+		t.Errorf("Expected <|tool|> to encode as %d, got %v (was %v)", size, got, before)
+	}
+	if got := tokenizer.Decode([]int{size + 1, x[0]}); got != "<|result|>x" {
+		t.Errorf("Expected %q, got %q", "<|result|>x", got)
+	}
+	if text, err := tokenizer.TokenText(size); err != nil || text != "<|tool|>" {
+		t.Errorf("Expected TokenText %q, got %q, %v", "<|tool|>", text, err)
+	}
+	if id, ok := tokenizer.TokenID("<|result|>"); !ok || id != size+1 || !tokenizer.IsSpecialID(id) {
+		t.Errorf("Expected special token %d, got %d, %v", size+1, id, ok)
+	}
+	if id, err := tokenizer.GetSpecialTokenID("<|tool|>"); err != nil || id != size {
+		t.Errorf("Expected ID %d, got %d, %v", size, id, err)
+	}
+
+	again, err := tokenizer.AddSpecialTokens("<|result|>")
+	if err != nil || len(again) != 1 || again[0] != size+1 || tokenizer.VocabSize() != size+2 {
+		t.Errorf("Expected adding again to return %d, got %v, %v", size+1, again, err)
+	}
+
+	for _, token := range []string{"tool", "<|tool", "<|>", "<|a|>b|>"} {
+		if _, err := tokenizer.AddSpecialTokens(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Token %q: expected ErrInvalidToken, got %v", token, err)
+		}
+	}
+}
+
+func TestAddedTokenLookups(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	ids, err := tokenizer.AddSpecialTokens("<|tool|>")
+	if err != nil {
+		t.Fatalf("AddSpecialTokens failed: %v", err)
+	}
+	tool := ids[0]
+	text := "call <|tool|> now"
+	tokens := tokenizer.Encode(text, noSpecialTokens)
+	if !slices.Contains(tokens, tool) {
+		t.Fatalf("Expected %v to contain %d", tokens, tool)
+	}
+
+	if got := tokenizer.FitString(text, len(tokens)-1, 0); got != "call <|tool|>" {
+		t.Errorf("Expected FitString to keep the added token, got %q", got)
+	}
+	total := 0
+	for _, st := range tokenizer.ScriptStats(text) {
+		total += st.Tokens
+	}
+	if total != len(tokens) {
+		t.Errorf("Expected ScriptStats to count %d tokens, got %d", len(tokens), total)
+	}
+	d, err := tokenizer.Decorations(text, tokens, PositionUTF8)
+	if err != nil {
+		t.Fatalf("Decorations failed: %v", err)
+	}
+	if i := slices.Index(tokens, tool); d.Lengths[i] != len("<|tool|>") {
+		t.Errorf("Expected decoration length %d, got %d", len("<|tool|>"), d.Lengths[i])
+	}
+
+	adapter := &tokenizerAdapter{tokenizer}
+	if !slices.Contains(adapter.SpecialTokens(), "<|tool|>") {
+		t.Error("Expected the scanner's special tokens to include <|tool|>")
+	}
+	if id, ok := (generic{tokenizer}).SpecialTokens()["<|tool|>"]; !ok || id != tool {
+		t.Errorf("Expected registry special token %d, got %d, %v", tool, id, ok)
+	}
+	var scanned []int
+	scanner := tokenizer.NewScanner(strings.NewReader(text), WithEncodeOptions(noSpecialTokens), WithBufferSize(8))
+	for scanner.Scan() {
+		scanned = append(scanned, scanner.Token())
+	}
+	if !slices.Equal(scanned, tokens) {
+		t.Errorf("Expected scanner tokens %v, got %v", tokens, scanned)
+	}
+	for _, kv := range tokenizer.GGUFMetadata() {
+		if kv.Key == "tokenizer.ggml.tokens" && kv.Value.([]string)[tool] != "<|tool|>" {
+			t.Errorf("Expected GGUF token %d to be <|tool|>", tool)
+		}
+	}
+}
+
+func TestAddSpecialTokensConcurrent(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	const text = "Hello, world! <|begin_of_text|> and <|new_0|>"
+	before := tokenizer.Encode("Hello, world! <|begin_of_text|> and ", noSpecialTokens)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				got := tokenizer.Encode(text, noSpecialTokens)
+				if !equalIntSlices(got[:len(before)], before) {
+					t.Errorf("Expected prefix %v, got %v", before, got)
+					return
+				}
+			}
+		}()
+	}
+	for i := range 50 {
+		if _, err := tokenizer.AddSpecialTokens(fmt.Sprintf("<|new_%d|>", i)); err != nil {
+			t.Errorf("AddSpecialTokens failed: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if got := tokenizer.Encode(text, noSpecialTokens); got[len(got)-1] != tokenizer.VocabSize()-50 {
+		t.Errorf("Expected <|new_0|> to encode as %d, got %v", tokenizer.VocabSize()-50, got)
+	}
+}
@@ -0,0 +1,88 @@
+package llama3
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/agentstation/tokenizer/llama3/internal/pretokenizer"
+)
+
+// WithAtomicPatterns pre-tokenizes each match of the regular expressions
+// patterns as one unit, before the Llama 3 pre-tokenizer splits the rest of
+// the text. Domain strings such as ticket IDs or URLs then go through BPE
+// whole instead of being cut at punctuation:
+//
+//	llama3.New(llama3.WithAtomicPatterns(`[A-Z]+-[0-9]+`, `https?://\S+`))
+//
+// Patterns use the syntax of the regexp package and are matched leftmost
+// first; where two match at the same offset, the earlier pattern wins.
+// Patterns must not match empty text. The option may be given more than
+// once to add patterns.
+//
+// This intentionally departs from the reference tokenizer: text containing
+// a match encodes to different, often fewer, tokens than the model saw in
+// training, and Retokenize always encodes in full. Use it only for models
+// or pipelines that expect it. Snapshots do not record the patterns; pass
+// the option to NewFromSnapshot as well.
+func WithAtomicPatterns(patterns ...string) Option {
+	return func(cfg *config) error {
+		if err := validatePatterns("atomic_patterns", patterns); err != nil {
+			return err
+		}
+		cfg.atomicPatterns = append(cfg.atomicPatterns, patterns...)
+		return nil
+	}
+}
+
+// validatePatterns checks that each of patterns compiles and does not match
+// empty text, reporting errors for the config field.
+func validatePatterns(field string, patterns []string) error {
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return NewConfigError(field, p, err)
+		}
+		if re.MatchString("") {
+			return NewConfigError(field, p, errors.New("pattern matches empty text"))
+		}
+	}
+	return nil
+}
+
+// compilePatterns returns one expression matching any of patterns,
+// preferring earlier ones, or nil if there are none.
+func compilePatterns(field string, patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	alternatives := make([]string, len(patterns))
+	for i, p := range patterns {
+		alternatives[i] = "(?:" + p + ")"
+	}
+	re, err := regexp.Compile(strings.Join(alternatives, "|"))
+	if err != nil {
+		return nil, NewConfigError(field, patterns, err)
+	}
+	return re, nil
+}
+
+// appendPretokens appends the pre-tokens of text, before byte-level
+// encoding, to dst: each match of the atomic patterns whole, and the text
+// between them as split by the Llama 3 pre-tokenizer. As there, each invalid
+// UTF-8 byte of a match becomes U+FFFD.
+func (t *Tokenizer) appendPretokens(dst []string, text string) []string {
+	if t.atomic == nil {
+		return pretokenizer.AppendTokenize(dst, text)
+	}
+	last := 0
+	for _, m := range t.atomic.FindAllStringIndex(text, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		dst = pretokenizer.AppendTokenize(dst, text[last:m[0]])
+		dst = append(dst, encodedText(text[m[0]:m[1]]))
+		last = m[1]
+	}
+	return pretokenizer.AppendTokenize(dst, text[last:])
+}
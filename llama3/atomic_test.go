@@ -0,0 +1,91 @@
+package llama3
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestWithAtomicPatterns(t *testing.T) {
+	tokenizer, err := New(WithAtomicPatterns(`[A-Z]+-[0-9]+`, `https?://\S+`))
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	reference, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	text := "Fixed JIRA-1234, see https://example.com/issues?id=1234 for details."
+	pretokens := tokenizer.PreTokenize(text)
+	for _, want := range []string{"JIRA-1234", "https://example.com/issues?id=1234"} {
+		if !slices.Contains(pretokens, want) {
+			t.Errorf("Expected pre-token %q, got %q", want, pretokens)
+		}
+	}
+	if slices.Contains(reference.PreTokenize(text), "JIRA-1234") {
+		t.Error("Expected the reference pre-tokenizer to split JIRA-1234")
+	}
+
+	tokens := tokenizer.Encode(text, noSpecialTokens)
+	if got := tokenizer.Decode(tokens); got != text {
+		t.Errorf("Expected round trip to %q, got %q", text, got)
+	}
+	if got := tokenizer.NewEncodeSession().Encode(text, noSpecialTokens); !equalIntSlices(got, tokens) {
+		t.Errorf("Expected session to encode %v, got %v", tokens, got)
+	}
+	if got := tokenizer.Retokenize("Fixed JIRA-12", text, tokenizer.Encode("Fixed JIRA-12", noSpecialTokens), Span{Start: 13, End: 13}); !equalIntSlices(got, tokens) {
+		t.Errorf("Expected Retokenize to encode %v, got %v", tokens, got)
+	}
+
+	// Text without matches encodes as in the reference
+	plain := "The quick brown fox jumps over the lazy dog."
+	if got, want := tokenizer.Encode(plain, nil), reference.Encode(plain, nil); !equalIntSlices(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Earlier patterns win at the same offset
+	ordered, err := New(WithAtomicPatterns(`ab`), WithAtomicPatterns(`abc`))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	if got := ordered.PreTokenize("abc"); !slices.Equal(got, []string{"ab", "c"}) {
+		t.Errorf("Expected [ab c], got %q", got)
+	}
+}
+
+func TestAtomicPatternsInvalidUTF8(t *testing.T) {
+	tokenizer, err := New(WithAtomicPatterns(`https?://\S+`))
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	// Invalid bytes in a match are U+FFFD, as outside matches
+	for _, text := range []string{"see http://x\xff\xfe y", "http://\xc3 \xff", "a http://x\xe6\x97\xa5\x97"} {
+		tokens := tokenizer.Encode(text, noSpecialTokens)
+		if got, want := tokenizer.Decode(tokens), encodedText(text); got != want {
+			t.Errorf("%q: expected one U+FFFD per invalid byte, %q, got %q", text, want, got)
+		}
+
+		spans := tokenizer.EncodeWithOffsets(text, noSpecialTokens)
+		end := 0
+		for i, span := range spans {
+			if span.ID != tokens[i] || span.Start != end || span.End < span.Start {
+				t.Fatalf("%q: span %d is %+v after offset %d", text, i, span, end)
+			}
+			end = span.End
+		}
+		if len(spans) != len(tokens) || end != len(text) {
+			t.Errorf("%q: expected %d spans covering %d bytes, got %d covering %d", text, len(tokens), len(text), len(spans), end)
+		}
+	}
+}
+
+func TestWithAtomicPatternsErrors(t *testing.T) {
+	for _, pattern := range []string{`[A-Z`, `a*`} {
+		var configErr *ConfigError
+		if _, err := New(WithAtomicPatterns(pattern)); !errors.As(err, &configErr) || configErr.Field != "atomic_patterns" {
+			t.Errorf("WithAtomicPatterns(%q): expected atomic_patterns ConfigError, got %v", pattern, err)
+		}
+	}
+}
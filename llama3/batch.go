@@ -0,0 +1,75 @@
+package llama3
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// batchChunkSize is the number of texts an EncodeBatch worker claims at a
+// time, so short texts do not contend on the shared counter.
+const batchChunkSize = 16
+
+// EncodeBatch encodes each of texts like Encode, spreading the work over up
+// to GOMAXPROCS goroutines. It returns the tokens of texts[i] at index i.
+//
+// Each worker encodes through its own EncodeSession, so pre-tokens repeated
+// across the batch, such as common words, are merged once per worker and
+// served from its private cache afterwards, in front of the tokenizer's
+// shared cache. This makes EncodeBatch the fast way to tokenize many short
+// documents, e.g. when indexing a corpus; small batches are encoded on the
+// calling goroutine.
+//
+// opts applies to every text. Diagnostics are per call, so opts.Diag is
+// ignored.
+func (t *Tokenizer) EncodeBatch(texts []string, opts *EncodeOptions) [][]int {
+	if opts == nil {
+		opts = defaultEncodeOptions()
+	} else if opts.Diag != nil {
+		o := *opts
+		o.Diag = nil
+		opts = &o
+	}
+
+	results := make([][]int, len(texts))
+	workers := min(runtime.GOMAXPROCS(0), (len(texts)+batchChunkSize-1)/batchChunkSize)
+	if workers <= 1 {
+		t.encodeBatch(texts, results, opts, t.NewEncodeSession())
+		return results
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			session := t.NewEncodeSession()
+			for {
+				start := int(next.Add(batchChunkSize)) - batchChunkSize
+				if start >= len(texts) {
+					return
+				}
+				end := min(start+batchChunkSize, len(texts))
+				t.encodeBatch(texts[start:end], results[start:end], opts, session)
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// encodeBatch encodes texts into results with session, leaving the session's
+// output buffer alone so each result has its own backing array.
+func (t *Tokenizer) encodeBatch(texts []string, results [][]int, opts *EncodeOptions, session *EncodeSession) {
+	for i, text := range texts {
+		if t.observer != nil && len(text) >= t.observeMinBytes {
+			start := time.Now()
+			results[i] = t.appendTokens(nil, text, opts, session)
+			t.observe(OpEncode, start, len(text), len(results[i]))
+			continue
+		}
+		results[i] = t.appendTokens(nil, text, opts, session)
+	}
+}
@@ -0,0 +1,48 @@
+package llama3
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEncodeBatch(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	var texts []string
+	for i := 0; i < 500; i++ {
+		texts = append(texts, fmt.Sprintf("Document %d: the quick brown fox jumps over the lazy dog.", i))
+	}
+	texts = append(texts, "", "<|begin_of_text|>Hello<|eot_id|>", "Привет мир 日本語 😀")
+
+	for _, n := range []int{0, 1, 3, len(texts)} {
+		for _, opts := range []*EncodeOptions{nil, noSpecialTokens, {BOS: true, EOS: true}} {
+			got := tokenizer.EncodeBatch(texts[:n], opts)
+			if len(got) != n {
+				t.Fatalf("Expected %d results, got %d", n, len(got))
+			}
+			for i, text := range texts[:n] {
+				if want := tokenizer.Encode(text, opts); !equalIntSlices(got[i], want) {
+					t.Errorf("EncodeBatch[%d] (%.20q): expected %v, got %v", i, text, want, got[i])
+				}
+			}
+		}
+	}
+
+	// Results do not share backing arrays
+	got := tokenizer.EncodeBatch([]string{"hello", "world"}, noSpecialTokens)
+	want := tokenizer.Encode("world", noSpecialTokens)
+	_ = append(got[0][:len(got[0])], 1, 2, 3)
+	if !equalIntSlices(got[1], want) {
+		t.Errorf("Expected %v, got %v", want, got[1])
+	}
+
+	// Diag is ignored rather than shared between workers
+	diag := &EncodeDiag{}
+	tokenizer.EncodeBatch(texts, &EncodeOptions{Diag: diag})
+	if diag.Pretokens != 0 {
+		t.Errorf("Expected Diag to be left alone, got %d pretokens", diag.Pretokens)
+	}
+}
@@ -1,6 +1,7 @@
 package llama3
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -35,6 +36,89 @@ func BenchmarkEncode(b *testing.B) {
 	}
 }
 
+func BenchmarkEncodeSession(b *testing.B) {
+	tokenizer, err := New()
+	if err != nil {
+		b.Skip("Skipping benchmark: Llama 3 data not available")
+	}
+
+	session := tokenizer.NewEncodeSession()
+	text := "The quick brown fox jumps over the lazy dog."
+	opts := &EncodeOptions{BOS: false, EOS: false}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = session.Encode(text, opts)
+	}
+}
+
+// chatTranscript returns a chat of turns messages in the Llama 3 format,
+// dense with special tokens.
+func chatTranscript(turns int) string {
+	var sb strings.Builder
+	sb.WriteString("<|begin_of_text|>")
+	for i := 0; i < turns; i++ {
+		role, content := "user", "What is the capital of France?"
+		if i%2 == 1 {
+			role, content = "assistant", "Paris."
+		}
+		sb.WriteString("<|start_header_id|>" + role + "<|end_header_id|>\n\n" + content + "<|eot_id|>")
+	}
+	return sb.String()
+}
+
+func BenchmarkEncodeChatTranscript(b *testing.B) {
+	tokenizer, err := New()
+	if err != nil {
+		b.Skip("Skipping benchmark: Llama 3 data not available")
+	}
+
+	text := chatTranscript(50)
+	opts := &EncodeOptions{BOS: false, EOS: false}
+
+	b.Run("Encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = tokenizer.Encode(text, opts)
+		}
+	})
+	b.Run("Session", func(b *testing.B) {
+		session := tokenizer.NewEncodeSession()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = session.Encode(text, opts)
+		}
+	})
+}
+
+func BenchmarkEncodeBatch(b *testing.B) {
+	tokenizer, err := New()
+	if err != nil {
+		b.Skip("Skipping benchmark: Llama 3 data not available")
+	}
+
+	texts := make([]string, 10000)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("Document %d: the quick brown fox jumps over the lazy dog.", i)
+	}
+	opts := &EncodeOptions{BOS: false, EOS: false}
+
+	b.Run("Loop", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, text := range texts {
+				_ = tokenizer.Encode(text, opts)
+			}
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = tokenizer.EncodeBatch(texts, opts)
+		}
+	})
+}
+
 func BenchmarkDecode(b *testing.B) {
 	tokenizer, err := New()
 	if err != nil {
@@ -51,6 +135,25 @@ func BenchmarkDecode(b *testing.B) {
 	}
 }
 
+func BenchmarkSplit(b *testing.B) {
+	tokenizer, err := New()
+	if err != nil {
+		b.Skip("Skipping benchmark: Llama 3 data not available")
+	}
+
+	text := strings.Repeat(splitterDocument(), 10)
+	splitter, err := tokenizer.NewSplitter(256, 32)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(text)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = splitter.Split(text)
+	}
+}
+
 // =============================================================================
 // Text Type Benchmarks
 // =============================================================================
@@ -0,0 +1,60 @@
+package llama3
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"english", "The quick brown fox jumps over the lazy dog. "},
+		{"code", "func main() {\n\tfor i := 0; i < 10; i++ {\n\t\tfmt.Println(i)\n\t}\n}\n"},
+		{"logs", "2024-01-01T00:00:00Z   INFO      request    id=1234     status=200\n"},
+		{"json", `{"name": "widget", "id": 12345, "tags": ["a", "b"], "price": 9.99}` + "\n"},
+		{"cyrillic", "Привет мир, это пример текста на русском языке. "},
+		{"cjk", "日本語のテキストを処理するためのトークナイザーです。"},
+		{"hangul", "안녕하세요 세계. 토크나이저는 텍스트를 나눕니다. "},
+		{"emoji", "😀🎉👍🏽"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Long enough to be extrapolated from the sample
+			text := strings.Repeat(tt.text, 2*capacitySample/len(tt.text)+1)
+			actual := len(tokenizer.Encode(text, nil))
+			estimate := tokenizer.estimateTokens(text)
+			if ratio := float64(estimate) / float64(actual); ratio < 0.75 || ratio > 1.33 {
+				t.Errorf("Expected an estimate near %d tokens, got %d", actual, estimate)
+			}
+		})
+	}
+
+	if n := tokenizer.estimateTokens(""); n != 2 {
+		t.Errorf("Expected capacity 2 for BOS/EOS, got %d", n)
+	}
+}
+
+func TestWithBytesPerToken(t *testing.T) {
+	tokenizer, err := New(WithBytesPerToken(2))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	if n := tokenizer.estimateTokens(strings.Repeat("a", 100)); n != 52 {
+		t.Errorf("Expected capacity 52, got %d", n)
+	}
+
+	for _, ratio := range []float64{0, -1, 0.1, math.Inf(1), math.NaN()} {
+		if _, err := New(WithBytesPerToken(ratio)); err == nil {
+			t.Errorf("Expected an error for ratio %v", ratio)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package llama3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidChat indicates text or tokens that do not follow the Llama 3
+// chat format.
+var ErrInvalidChat = errors.New("invalid chat format")
+
+// Message is one message of a chat.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ParseChat parses a conversation in the Llama 3 instruct format back into
+// its messages:
+//
+//	<|begin_of_text|><|start_header_id|>user<|end_header_id|>
+//
+//	Hi!<|eot_id|><|start_header_id|>assistant<|end_header_id|>
+//
+//	Hello.<|eot_id|>
+//
+// Each message is a role between <|start_header_id|> and <|end_header_id|>,
+// then its content up to <|eot_id|> or <|eom_id|>, without the blank line
+// that follows the header. <|begin_of_text|> and <|end_of_text|> may start
+// and end the text. A final message without an end, such as the empty
+// assistant message of a prompt awaiting generation, is returned as far as
+// it goes. Other special tokens in content are kept as text.
+func ParseChat(text string) ([]Message, error) {
+	var p chatParser
+	var buf [segmentBufSize]segment
+	for _, seg := range appendSegments(buf[:0], text, isDefaultSpecialToken) {
+		if seg.Special {
+			if err := p.special(seg.Text); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := p.text(seg.Text); err != nil {
+			return nil, err
+		}
+	}
+	return p.finish()
+}
+
+// ParseChatTokens parses the tokens of a conversation in the Llama 3
+// instruct format back into its messages, like ParseChat. Only special
+// tokens end roles and messages: text that spells one, such as a message
+// quoting "<|eot_id|>", stays content, which ParseChat cannot tell apart.
+func (t *Tokenizer) ParseChatTokens(tokens []int) ([]Message, error) {
+	var p chatParser
+	start := 0
+	for i, id := range tokens {
+		tok := t.chatToken(id)
+		if tok == 0 {
+			continue
+		}
+		if err := p.text(t.Decode(tokens[start:i])); err != nil {
+			return nil, err
+		}
+		if err := p.special(tok.String()); err != nil {
+			return nil, err
+		}
+		start = i + 1
+	}
+	if err := p.text(t.Decode(tokens[start:])); err != nil {
+		return nil, err
+	}
+	return p.finish()
+}
+
+// chatToken returns the SpecialToken of id if it structures a chat, or 0.
+func (t *Tokenizer) chatToken(id int) SpecialToken {
+	if id < t.regularLen {
+		return 0
+	}
+	for _, tok := range []SpecialToken{BeginOfText, EndOfText, StartHeader, EndHeader, EOT, EOM} {
+		if t.specialIDs[tok] == id {
+			return tok
+		}
+	}
+	return 0
+}
+
+// chatState is the part of a message a chatParser is in.
+type chatState int
+
+const (
+	chatBetween chatState = iota // between messages
+	chatRole                     // after <|start_header_id|>
+	chatContent                  // after <|end_header_id|>
+	chatEnded                    // after <|end_of_text|>
+)
+
+// chatParser parses the pieces of a chat: special tokens and the text
+// between them.
+type chatParser struct {
+	state    chatState
+	messages []Message
+	role     strings.Builder
+	content  strings.Builder
+	started  bool // any piece seen, for <|begin_of_text|>
+}
+
+func (p *chatParser) text(s string) error {
+	if s == "" {
+		return nil
+	}
+	p.started = true
+	switch p.state {
+	case chatRole:
+		p.role.WriteString(s)
+	case chatContent:
+		p.content.WriteString(s)
+	default:
+		if strings.TrimSpace(s) != "" {
+			return fmt.Errorf("%w: text outside a message: %q", ErrInvalidChat, truncateChat(s))
+		}
+	}
+	return nil
+}
+
+func (p *chatParser) special(token string) error {
+	first := !p.started
+	p.started = true
+	if p.state == chatEnded {
+		return fmt.Errorf("%w: %s after %s", ErrInvalidChat, token, endOfTextToken)
+	}
+
+	switch token {
+	case beginOfTextToken:
+		if !first {
+			return fmt.Errorf("%w: %s after the start", ErrInvalidChat, token)
+		}
+	case endOfTextToken:
+		if p.state == chatContent {
+			p.endMessage()
+		} else if p.state == chatRole {
+			return fmt.Errorf("%w: %s in a role", ErrInvalidChat, token)
+		}
+		p.state = chatEnded
+	case StartHeader.String():
+		if p.state != chatBetween {
+			return fmt.Errorf("%w: %s before the previous message ended", ErrInvalidChat, token)
+		}
+		p.state = chatRole
+	case EndHeader.String():
+		if p.state != chatRole {
+			return fmt.Errorf("%w: %s without %s", ErrInvalidChat, token, StartHeader)
+		}
+		p.state = chatContent
+	case EOT.String(), EOM.String():
+		if p.state != chatContent {
+			return fmt.Errorf("%w: %s outside a message", ErrInvalidChat, token)
+		}
+		p.endMessage()
+		p.state = chatBetween
+	default:
+		// Other special tokens, such as <|python_tag|>, are content
+		if p.state != chatContent {
+			return fmt.Errorf("%w: %s outside a message", ErrInvalidChat, token)
+		}
+		p.content.WriteString(token)
+	}
+	return nil
+}
+
+func (p *chatParser) endMessage() {
+	p.messages = append(p.messages, Message{
+		Role:    p.role.String(),
+		Content: strings.TrimPrefix(p.content.String(), "\n\n"),
+	})
+	p.role.Reset()
+	p.content.Reset()
+}
+
+func (p *chatParser) finish() ([]Message, error) {
+	switch p.state {
+	case chatRole:
+		return nil, fmt.Errorf("%w: role without %s", ErrInvalidChat, EndHeader)
+	case chatContent:
+		p.endMessage()
+	}
+	return p.messages, nil
+}
+
+// truncateChat shortens text quoted in errors.
+func truncateChat(s string) string {
+	const limit = 40
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "..."
+}
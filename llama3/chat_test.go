@@ -0,0 +1,105 @@
+package llama3
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseChat(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Message
+	}{
+		{"empty", "", nil},
+		{
+			"conversation",
+			"<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\nBe brief.<|eot_id|>" +
+				"<|start_header_id|>user<|end_header_id|>\n\nHi!<|eot_id|>" +
+				"<|start_header_id|>assistant<|end_header_id|>\n\nHello.<|eot_id|>",
+			[]Message{{"system", "Be brief."}, {"user", "Hi!"}, {"assistant", "Hello."}},
+		},
+		{
+			"generation_prompt",
+			"<|start_header_id|>user<|end_header_id|>\n\nHi!<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\n",
+			[]Message{{"user", "Hi!"}, {"assistant", ""}},
+		},
+		{
+			"tool_call",
+			"<|start_header_id|>assistant<|end_header_id|>\n\n<|python_tag|>search(\"x\")<|eom_id|><|end_of_text|>",
+			[]Message{{"assistant", "<|python_tag|>search(\"x\")"}},
+		},
+		{
+			"multiline_content",
+			"<|start_header_id|>user<|end_header_id|>\n\nline 1\n\nline 2\n<|eot_id|>\n",
+			[]Message{{"user", "line 1\n\nline 2\n"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChat(tt.text)
+			if err != nil {
+				t.Fatalf("ParseChat() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseChat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseChatErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"text_outside", "hello<|start_header_id|>user<|end_header_id|>\n\nHi<|eot_id|>"},
+		{"unterminated_role", "<|start_header_id|>user"},
+		{"header_in_content", "<|start_header_id|>user<|end_header_id|>\n\nHi<|start_header_id|>"},
+		{"eot_outside", "<|eot_id|>"},
+		{"end_header_alone", "<|end_header_id|>"},
+		{"late_begin", "<|start_header_id|>user<|end_header_id|>Hi<|eot_id|><|begin_of_text|>"},
+		{"after_end", "<|end_of_text|><|start_header_id|>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseChat(tt.text); !errors.Is(err, ErrInvalidChat) {
+				t.Errorf("ParseChat() error = %v, want ErrInvalidChat", err)
+			}
+		})
+	}
+}
+
+func TestParseChatTokens(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	text := "<|begin_of_text|><|start_header_id|>user<|end_header_id|>\n\nHi!<|eot_id|>" +
+		"<|start_header_id|>assistant<|end_header_id|>\n\nHello.<|eot_id|>"
+	got, err := tokenizer.ParseChatTokens(tokenizer.Encode(text, noSpecialTokens))
+	if err != nil {
+		t.Fatalf("ParseChatTokens() error = %v", err)
+	}
+	if want := []Message{{"user", "Hi!"}, {"assistant", "Hello."}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseChatTokens() = %q, want %q", got, want)
+	}
+
+	// Text that spells a special token is content
+	tokens := []int{tokenizer.ID(StartHeader)}
+	tokens = append(tokens, tokenizer.Encode("user", noSpecialTokens)...)
+	tokens = append(tokens, tokenizer.ID(EndHeader))
+	tokens = append(tokens, tokenizer.Encode("\n\nWhat does <", noSpecialTokens)...)
+	tokens = append(tokens, tokenizer.Encode("|eot_id|> do?", noSpecialTokens)...)
+	tokens = append(tokens, tokenizer.ID(EOT))
+
+	got, err = tokenizer.ParseChatTokens(tokens)
+	if err != nil {
+		t.Fatalf("ParseChatTokens() error = %v", err)
+	}
+	if want := []Message{{"user", "What does <|eot_id|> do?"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseChatTokens() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package llama3
+
+import "strings"
+
+// ChatFormat encodes conversations in the Llama 3 instruct format:
+//
+//	<|begin_of_text|><|start_header_id|>system<|end_header_id|>
+//
+//	You are helpful.<|eot_id|><|start_header_id|>user<|end_header_id|>
+//
+//	Hi!<|eot_id|>
+//
+// Roles and content are always encoded as text, so content that spells a
+// special token such as "<|eot_id|>" cannot end its message early, and
+// ParseChatTokens returns the messages unchanged. Content is trimmed of
+// surrounding whitespace, as in the reference template.
+type ChatFormat struct {
+	t *Tokenizer
+}
+
+// NewChatFormat returns the chat format of t. It fails with ErrTokenNotFound
+// if t lacks the special tokens of the format.
+func (t *Tokenizer) NewChatFormat() (*ChatFormat, error) {
+	for _, tok := range []SpecialToken{BeginOfText, StartHeader, EndHeader, EOT} {
+		if t.specialIDs[tok] < 0 {
+			return nil, NewTokenError("chat format", tok.String(), ErrTokenNotFound)
+		}
+	}
+	return &ChatFormat{t: t}, nil
+}
+
+// Encode returns the tokens of a complete conversation: BOS, then each
+// message.
+func (f *ChatFormat) Encode(messages []Message) []int {
+	dst := []int{f.t.specialIDs[BeginOfText]}
+	for _, m := range messages {
+		dst = f.AppendMessage(dst, m)
+	}
+	return dst
+}
+
+// EncodePrompt returns the tokens of a prompt for the next reply: the
+// conversation as in Encode, then the header of an assistant message for
+// the model to complete.
+func (f *ChatFormat) EncodePrompt(messages []Message) []int {
+	return f.AppendHeader(f.Encode(messages), "assistant")
+}
+
+// AppendHeader appends the header of a message with role to dst: the role
+// between <|start_header_id|> and <|end_header_id|>, then a blank line.
+func (f *ChatFormat) AppendHeader(dst []int, role string) []int {
+	dst = append(dst, f.t.specialIDs[StartHeader])
+	dst = f.t.appendText(dst, role)
+	dst = append(dst, f.t.specialIDs[EndHeader])
+	return f.t.appendText(dst, "\n\n")
+}
+
+// AppendMessage appends a message to dst: its header, its trimmed content
+// and <|eot_id|>.
+func (f *ChatFormat) AppendMessage(dst []int, m Message) []int {
+	dst = f.AppendHeader(dst, m.Role)
+	dst = f.t.appendText(dst, strings.TrimSpace(m.Content))
+	return append(dst, f.t.specialIDs[EOT])
+}
+
+// Count returns the number of tokens of Encode(messages) without building
+// the whole sequence.
+func (f *ChatFormat) Count(messages []Message) int {
+	n := 1 // BOS
+	var buf []int
+	for _, m := range messages {
+		buf = f.AppendMessage(buf[:0], m)
+		n += len(buf)
+	}
+	return n
+}
+
+// CountPrompt returns the number of tokens of EncodePrompt(messages), to
+// check a conversation against a context window before sending it.
+func (f *ChatFormat) CountPrompt(messages []Message) int {
+	var header [8]int
+	return f.Count(messages) + len(f.AppendHeader(header[:0], "assistant"))
+}
+
+// appendText appends the tokens of text to dst, encoding special token text
+// as ordinary text.
+func (t *Tokenizer) appendText(dst []int, text string) []int {
+	for p := range t.textPieces(text, nil) {
+		dst = append(dst, t.performBPE(string(p.Pretoken))...)
+	}
+	return dst
+}
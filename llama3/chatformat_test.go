@@ -0,0 +1,76 @@
+package llama3
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestChatFormat(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	format, err := tokenizer.NewChatFormat()
+	if err != nil {
+		t.Fatalf("NewChatFormat() error = %v", err)
+	}
+
+	messages := []Message{
+		{"system", "You are helpful."},
+		{"user", "  Quote <|eot_id|> for me 🤖\n"},
+		{"assistant", "<|eot_id|>"},
+	}
+
+	t.Run("encode", func(t *testing.T) {
+		tokens := format.Encode(messages)
+		want := "<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\nYou are helpful.<|eot_id|>" +
+			"<|start_header_id|>user<|end_header_id|>\n\nQuote <|eot_id|> for me 🤖<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\n<|eot_id|><|eot_id|>"
+		if got := tokenizer.Decode(tokens); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+		if n := format.Count(messages); n != len(tokens) {
+			t.Errorf("Expected Count %d, got %d", len(tokens), n)
+		}
+
+		// Quoted special tokens stay content
+		parsed, err := tokenizer.ParseChatTokens(tokens)
+		if err != nil {
+			t.Fatalf("ParseChatTokens() error = %v", err)
+		}
+		wantMessages := []Message{messages[0], {"user", "Quote <|eot_id|> for me 🤖"}, messages[2]}
+		if !reflect.DeepEqual(parsed, wantMessages) {
+			t.Errorf("Expected messages %q, got %q", wantMessages, parsed)
+		}
+	})
+
+	t.Run("prompt", func(t *testing.T) {
+		tokens := format.EncodePrompt(messages[:1])
+		want := "<|begin_of_text|><|start_header_id|>system<|end_header_id|>\n\nYou are helpful.<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\n"
+		if got := tokenizer.Decode(tokens); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+		if n := format.CountPrompt(messages[:1]); n != len(tokens) {
+			t.Errorf("Expected CountPrompt %d, got %d", len(tokens), n)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		if got := format.Encode(nil); !equalIntSlices(got, []int{tokenizer.BOSTokenID()}) {
+			t.Errorf("Expected only BOS, got %v", got)
+		}
+		if n := format.Count(nil); n != 1 {
+			t.Errorf("Expected Count 1, got %d", n)
+		}
+	})
+}
+
+func TestNewChatFormatMissingTokens(t *testing.T) {
+	tokenizer := &Tokenizer{}
+	tokenizer.resolveSpecialIDs()
+	if _, err := tokenizer.NewChatFormat(); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Expected ErrTokenNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package llama3
+
+// ChatEventKind is the kind of a ChatEvent.
+type ChatEventKind int
+
+// Kinds of chat events, in the order a message produces them.
+const (
+	MessageStart ChatEventKind = iota + 1 // <|start_header_id|>
+	MessageRole                           // <|end_header_id|>, with Role
+	ContentDelta                          // content, with Text
+	MessageEnd                            // <|eot_id|>, <|eom_id|> or <|end_of_text|>, with End
+)
+
+// ChatEvent is an event of a ChatStream.
+type ChatEvent struct {
+	Kind ChatEventKind
+	Role string // for MessageRole
+	Text string // for ContentDelta: whole UTF-8 characters
+	// End is the token that ended the message for MessageEnd: EOT, EOM or
+	// EndOfText, or 0 if a new header or Close cut it short.
+	End SpecialToken
+}
+
+// ChatStream splits generated tokens into chat events as they arrive, for
+// agent frameworks that show a reply while it streams in or act on a tool
+// call as soon as it ends:
+//
+//	stream := tokenizer.NewChatStream("assistant")
+//	for id := range generated {
+//	    for _, ev := range stream.Push(id) {
+//	        switch ev.Kind {
+//	        case llama3.ContentDelta:
+//	            fmt.Print(ev.Text)
+//	        case llama3.MessageEnd:
+//	            // ev.End is llama3.EOM for a tool call awaiting its result
+//	        }
+//	    }
+//	}
+//
+// Messages follow the Llama 3 instruct format, as in ParseChat: the blank
+// line after a header is not content, other special tokens in content are
+// content, and tokens between messages are ignored. A ChatStream is not safe
+// for concurrent use.
+type ChatStream struct {
+	t       *Tokenizer
+	state   chatState
+	role    []byte
+	pending []byte // content not yet emitted: a partial character or leading newlines
+	started bool   // content seen since the header
+	events  []ChatEvent
+	queued  bool // events holds the events of NewChatStream, not yet returned
+}
+
+// NewChatStream returns a stream that splits tokens of t into chat events.
+// role is the role of the message a prompt left open, as the prompt of a
+// reply ends with <|start_header_id|>assistant<|end_header_id|>, so the
+// first tokens are its content; the first Push then reports MessageStart
+// and MessageRole for it. If role is empty the tokens start between
+// messages.
+func (t *Tokenizer) NewChatStream(role string) *ChatStream {
+	s := &ChatStream{t: t}
+	if role != "" {
+		s.events = append(s.events,
+			ChatEvent{Kind: MessageStart},
+			ChatEvent{Kind: MessageRole, Role: role})
+		s.state = chatContent
+		s.started = true // the prompt holds the blank line
+		s.queued = true
+	}
+	return s
+}
+
+// Push adds the next token and returns the events it completes. The
+// returned slice is reused by the next call.
+func (s *ChatStream) Push(id int) []ChatEvent {
+	if !s.queued {
+		s.events = s.events[:0]
+	}
+	s.queued = false
+
+	switch tok := s.t.chatToken(id); tok {
+	case StartHeader:
+		if s.state == chatContent {
+			s.endMessage(0)
+		}
+		s.events = append(s.events, ChatEvent{Kind: MessageStart})
+		s.role = s.role[:0]
+		s.state = chatRole
+	case EndHeader:
+		if s.state == chatRole {
+			s.events = append(s.events, ChatEvent{Kind: MessageRole, Role: string(s.role)})
+			s.state = chatContent
+			s.started = false
+		}
+	case EOT, EOM, EndOfText:
+		if s.state == chatContent {
+			s.endMessage(tok)
+		}
+		s.state = chatBetween
+	case BeginOfText:
+		// Starts a sequence, not a message
+	default:
+		switch s.state {
+		case chatRole:
+			s.role = append(s.role, s.t.decodeBytes([]int{id})...)
+		case chatContent:
+			s.pending = append(s.pending, s.t.decodeBytes([]int{id})...)
+			s.emitContent(false)
+		}
+	}
+	return s.events
+}
+
+// Close ends the stream and returns the events of what remains: the rest of
+// an unfinished message, which ends with End 0.
+func (s *ChatStream) Close() []ChatEvent {
+	if !s.queued {
+		s.events = s.events[:0]
+	}
+	s.queued = false
+	if s.state == chatContent {
+		s.endMessage(0)
+	}
+	s.state = chatBetween
+	return s.events
+}
+
+// emitContent reports the pending content as a delta, holding back a
+// partial UTF-8 character, or all of it at the end of the message, and the
+// blank line after the header.
+func (s *ChatStream) emitContent(end bool) {
+	if !s.started {
+		const blankLine = "\n\n"
+		n := min(len(s.pending), len(blankLine))
+		if string(s.pending[:n]) == blankLine[:n] && n < len(blankLine) && !end {
+			return // may still become the blank line
+		}
+		if n == len(blankLine) && string(s.pending[:n]) == blankLine {
+			s.pending = append(s.pending[:0], s.pending[n:]...)
+		}
+		s.started = true
+	}
+
+	cut := len(s.pending)
+	if !end {
+		cut = lastRuneBoundary(s.pending)
+	}
+	if cut == 0 {
+		return
+	}
+	s.events = append(s.events, ChatEvent{Kind: ContentDelta, Text: string(s.pending[:cut])})
+	s.pending = append(s.pending[:0], s.pending[cut:]...)
+}
+
+func (s *ChatStream) endMessage(end SpecialToken) {
+	s.emitContent(true)
+	s.events = append(s.events, ChatEvent{Kind: MessageEnd, End: end})
+	s.pending = s.pending[:0]
+}
@@ -0,0 +1,90 @@
+package llama3
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// collectChat pushes tokens through a ChatStream and returns the messages it
+// reports, with each message's content joined from its deltas.
+func collectChat(t *testing.T, stream *ChatStream, tokens []int) ([]Message, []SpecialToken) {
+	t.Helper()
+	var messages []Message
+	var ends []SpecialToken
+	var content strings.Builder
+	handle := func(events []ChatEvent) {
+		for _, ev := range events {
+			switch ev.Kind {
+			case MessageStart:
+				messages = append(messages, Message{})
+				content.Reset()
+			case MessageRole:
+				messages[len(messages)-1].Role = ev.Role
+			case ContentDelta:
+				if !utf8.ValidString(ev.Text) {
+					t.Errorf("Delta %q is not valid UTF-8", ev.Text)
+				}
+				content.WriteString(ev.Text)
+			case MessageEnd:
+				messages[len(messages)-1].Content = content.String()
+				ends = append(ends, ev.End)
+			}
+		}
+	}
+	for _, id := range tokens {
+		handle(stream.Push(id))
+	}
+	handle(stream.Close())
+	return messages, ends
+}
+
+func TestChatStream(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	t.Run("transcript", func(t *testing.T) {
+		text := "<|begin_of_text|><|start_header_id|>user<|end_header_id|>\n\nWho are you? 🤖<|eot_id|>" +
+			"<|start_header_id|>assistant<|end_header_id|>\n\n<|python_tag|>lookup(\"me\")<|eom_id|>" +
+			"<|start_header_id|>ipython<|end_header_id|>\n\n\n\nresult<|eot_id|>"
+		messages, ends := collectChat(t, tokenizer.NewChatStream(""), tokenizer.Encode(text, noSpecialTokens))
+
+		want, err := ParseChat(text)
+		if err != nil {
+			t.Fatalf("ParseChat() error = %v", err)
+		}
+		if !reflect.DeepEqual(messages, want) {
+			t.Errorf("Expected messages %q, got %q", want, messages)
+		}
+		if wantEnds := []SpecialToken{EOT, EOM, EOT}; !reflect.DeepEqual(ends, wantEnds) {
+			t.Errorf("Expected ends %v, got %v", wantEnds, ends)
+		}
+	})
+
+	t.Run("open_role", func(t *testing.T) {
+		tokens := tokenizer.Encode("Bonjour, ça va ? 😀<|eot_id|><|start_header_id|>user<|end_header_id|>\n\nOui", noSpecialTokens)
+		messages, ends := collectChat(t, tokenizer.NewChatStream("assistant"), tokens)
+
+		want := []Message{{"assistant", "Bonjour, ça va ? 😀"}, {"user", "Oui"}}
+		if !reflect.DeepEqual(messages, want) {
+			t.Errorf("Expected messages %q, got %q", want, messages)
+		}
+		if wantEnds := []SpecialToken{EOT, 0}; !reflect.DeepEqual(ends, wantEnds) {
+			t.Errorf("Expected ends %v, got %v", wantEnds, ends)
+		}
+	})
+
+	t.Run("open_role_without_tokens", func(t *testing.T) {
+		events := tokenizer.NewChatStream("assistant").Close()
+		kinds := make([]ChatEventKind, len(events))
+		for i, ev := range events {
+			kinds[i] = ev.Kind
+		}
+		if want := []ChatEventKind{MessageStart, MessageRole, MessageEnd}; !reflect.DeepEqual(kinds, want) {
+			t.Errorf("Expected events %v, got %v", want, kinds)
+		}
+	})
+}
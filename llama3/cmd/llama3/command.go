@@ -2,9 +2,9 @@
 package llama3cmd
 
 import (
-	"os"
-
 	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/cli"
 )
 
 // Command returns the llama3 command tree for the tokenizer CLI.
@@ -19,6 +19,7 @@ func Command() *cobra.Command {
 		bos       bool
 		eos       bool
 		metrics   bool
+		crlf      string
 	)
 
 	cmd := &cobra.Command{
@@ -30,9 +31,13 @@ The Llama 3 tokenizer uses byte-level BPE (Byte Pair Encoding) with a
 vocabulary of 128,256 tokens (128,000 regular tokens + 256 special tokens).
 
 Available commands:
-  encode - Encode text to token IDs (default when text is provided)
-  decode - Decode token IDs to text
-  info   - Display tokenizer information`,
+  encode     - Encode text to token IDs (default when text is provided)
+  encode-dir - Tokenize every file of a directory in parallel
+  decode     - Decode token IDs to text
+  count      - Count the tokens of files, optionally watching for changes
+  info       - Display tokenizer information
+  prune      - Build a reduced vocabulary from token frequencies
+  stats      - Show token efficiency by writing system`,
 		Example: `  # Encode text (explicit)
   tokenizer llama3 encode "Hello, world!"
   
@@ -88,13 +93,13 @@ Available commands:
 				encCount = count
 				encCountOnly = countOnly
 				encMetrics = metrics
+				encCRLF = crlf
 
 				return encodeCmd.Execute()
 			}
 
 			// No args provided - check if stdin is piped
-			stat, _ := os.Stdin.Stat()
-			if (stat.Mode() & os.ModeCharDevice) == 0 {
+			if !cli.IsTerminal(cmd.InOrStdin()) {
 				// Data is being piped to stdin, use encode
 				encodeCmd := newEncodeCmd()
 				encodeCmd.SetOut(cmd.OutOrStdout())
@@ -108,6 +113,7 @@ Available commands:
 				encCount = count
 				encCountOnly = countOnly
 				encMetrics = metrics
+				encCRLF = crlf
 
 				return encodeCmd.RunE(encodeCmd, []string{})
 			}
@@ -124,12 +130,17 @@ Available commands:
 	cmd.PersistentFlags().BoolVar(&bos, "bos", true, "Add beginning of sequence token")
 	cmd.PersistentFlags().BoolVar(&eos, "eos", true, "Add end of sequence token")
 	cmd.PersistentFlags().BoolVar(&metrics, "metrics", false, "Show performance metrics")
+	cmd.PersistentFlags().StringVar(&crlf, "crlf", cli.CRLFAuto, "Line endings: auto, keep, lf")
 
 	// Add subcommands
 	cmd.AddCommand(
 		newEncodeCmd(),
+		newEncodeDirCmd(),
 		newDecodeCmd(),
+		newCountCmd(),
 		newInfoCmd(),
+		newPruneCmd(),
+		newStatsCmd(),
 	)
 
 	return cmd
@@ -0,0 +1,231 @@
+package llama3cmd
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer"
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/llama3"
+	tokentesting "github.com/agentstation/tokenizer/llama3/internal/testing"
+)
+
+// referenceVectors are known encodings for the embedded Llama 3 vocabulary.
+var referenceVectors = []struct {
+	input    string
+	expected []int
+}{
+	{"grabbed", []int{59312, 2788}},
+	{" grabbed", []int{30418}},
+	{"           grabbed", []int{1881, 30418}},
+	{"This is a test sentence.", []int{2028, 374, 264, 1296, 11914, 13}},
+	{"\n", []int{198}},
+	{" \n", []int{720}},
+	{"Hello, world!", []int{9906, 11, 1917, 0}},
+}
+
+// ConfigCommand returns the config command tree for the tokenizer CLI.
+// It operates on the JSON config files read by llama3.NewFromConfig.
+func ConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Tokenizer config file operations",
+		Long: `Work with declarative tokenizer config files.
+
+Available commands:
+  show     - Print the effective settings of a config file
+  validate - Check a config file and the tokenizer it describes`,
+	}
+
+	cmd.AddCommand(newConfigShowCmd())
+	cmd.AddCommand(newConfigValidateCmd())
+
+	return cmd
+}
+
+// newConfigShowCmd creates the config show subcommand.
+func newConfigShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <file>",
+		Short: "Print the effective settings of a tokenizer config file",
+		Long: `Print the effective settings of a tokenizer config file.
+
+The tokenizer the config describes is constructed, and its settings are
+printed with defaults filled in, as reported by the tokenizer itself. Use
+validate to also run the self-test.`,
+		Example: `  # Show the settings a config resolves to
+  tokenizer config show tokenizer.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, tokenizer, err := loadConfigTokenizer(args[0])
+			if err != nil {
+				return err
+			}
+			printEffectiveConfig(cmd.OutOrStdout(), cfg, tokenizer)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// newConfigValidateCmd creates the config validate subcommand.
+func newConfigValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate <file>",
+		Short: "Validate a tokenizer config file",
+		Long: `Validate a tokenizer config file without deploying it.
+
+The config is parsed and checked, the tokenizer it describes is constructed,
+and a self-test of encode/decode round trips is run against it. When the
+embedded vocabulary is used, encodings are also compared with known reference
+vectors. The resolved effective settings are printed.
+
+The command exits with an error if any step fails.`,
+		Example: `  # Validate a config before deploying it
+  tokenizer config validate tokenizer.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: runConfigValidate,
+	}
+
+	return cmd
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+
+	cfg, tokenizer, err := loadConfigTokenizer(args[0])
+	if err != nil {
+		return err
+	}
+
+	printEffectiveConfig(out, cfg, tokenizer)
+
+	failures := runSelfTest(out, cfg, tokenizer)
+	if failures > 0 {
+		return fmt.Errorf("self-test failed: %d check(s) failed", failures)
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, cli.Colorize(out, cli.ColorGreen, "Config is valid."))
+	return nil
+}
+
+// loadConfigTokenizer loads and checks the config file at path and constructs
+// the tokenizer it describes.
+func loadConfigTokenizer(path string) (*llama3.Config, *llama3.Tokenizer, error) {
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	tokenizer, err := newFromConfigFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize tokenizer: %w", err)
+	}
+	return cfg, tokenizer, nil
+}
+
+// printEffectiveConfig prints the settings of the tokenizer built from cfg.
+func printEffectiveConfig(out io.Writer, cfg *llama3.Config, tokenizer *llama3.Tokenizer) {
+	info := tokenizer.Info()
+	source := orDefault(cfg.Vocabulary.Source, llama3.VocabSourceEmbedded)
+
+	fmt.Fprintln(out, "Effective Settings:")
+	fmt.Fprintf(out, "  Vocabulary Source: %s\n", source)
+	if source == llama3.VocabSourceFiles {
+		fmt.Fprintf(out, "  Vocabulary File:   %s\n", cfg.Vocabulary.VocabFile)
+		fmt.Fprintf(out, "  Merges File:       %s\n", cfg.Vocabulary.MergesFile)
+	}
+	fmt.Fprintf(out, "  Vocabulary Size:   %d tokens\n", info.VocabSize)
+	switch info.Cache {
+	case llama3.CacheTypeLRU:
+		fmt.Fprintf(out, "  Cache:             %s (%d entries)\n", info.Cache, info.CacheSize)
+	case llama3.CacheTypeAdaptive:
+		fmt.Fprintf(out, "  Cache:             %s (%d to %d entries)\n", info.Cache, info.CacheMinSize, info.CacheMaxSize)
+	default:
+		fmt.Fprintf(out, "  Cache:             %s\n", info.Cache)
+	}
+	profile := "default"
+	if cfg.SpecialTokenProfile != "" {
+		p, _ := llama3.LookupSpecialTokenProfile(cfg.SpecialTokenProfile) // checked by Validate
+		profile = p.Name
+	} else if len(cfg.SpecialTokens) > 0 {
+		profile = "custom"
+	}
+	fmt.Fprintf(out, "  Special Tokens:    %d (%s)\n", info.SpecialTokens, profile)
+	if len(cfg.ExtraSpecialTokens) > 0 {
+		fmt.Fprintf(out, "  Extra Tokens:      %s\n", strings.Join(cfg.ExtraSpecialTokens, " "))
+	}
+	if info.MergeOverrides > 0 {
+		fmt.Fprintf(out, "  Merge Overrides:   %d\n", info.MergeOverrides)
+	}
+	for _, p := range info.AtomicPatterns {
+		fmt.Fprintf(out, "  Atomic Pattern:    %s\n", p)
+	}
+	for _, p := range info.OptimisticPatterns {
+		fmt.Fprintf(out, "  Optimistic:        %s\n", p)
+	}
+	fmt.Fprintf(out, "  Normalizer:        %s\n", orDefault(cfg.Normalizer, "none"))
+	fmt.Fprintf(out, "  Pre-tokenizer:     %s\n", orDefault(cfg.PreTokenizer, "llama3"))
+}
+
+// runSelfTest checks the tokenizer and returns the number of failed checks.
+func runSelfTest(out io.Writer, cfg *llama3.Config, tok *llama3.Tokenizer) int {
+	opts := &llama3.EncodeOptions{BOS: false, EOS: false}
+	failures := 0
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Self-test:")
+
+	cases := tokentesting.GenerateTestCases()
+	passed := 0
+	for _, tc := range cases {
+		if got := tok.Decode(tok.Encode(tc.Input, opts)); got != tc.Input {
+			fmt.Fprintf(out, "  %s round trip %q (%s): got %q\n", cli.Colorize(out, cli.ColorRed, "FAIL"), tc.Input, tc.Description, got)
+			continue
+		}
+		passed++
+	}
+	failures += len(cases) - passed
+	fmt.Fprintf(out, "  Round trips:       %d/%d passed\n", passed, len(cases))
+
+	if orDefault(cfg.Vocabulary.Source, llama3.VocabSourceEmbedded) != llama3.VocabSourceEmbedded {
+		fmt.Fprintf(out, "  Reference vectors: skipped (custom vocabulary)\n")
+		return failures
+	}
+	if paths := tok.Info().Paths; slices.Contains(paths, llama3.PathMergeOverrides) {
+		fmt.Fprintf(out, "  Reference vectors: skipped (merge overrides)\n")
+		return failures
+	} else if slices.Contains(paths, llama3.PathAtomicPatterns) {
+		fmt.Fprintf(out, "  Reference vectors: skipped (atomic patterns)\n")
+		return failures
+	}
+
+	passed = 0
+	for _, v := range referenceVectors {
+		if got := tok.Encode(v.input, opts); !tokenizer.EqualTokens(got, v.expected) {
+			fmt.Fprintf(out, "  %s encode %q: got %v, want %v\n", cli.Colorize(out, cli.ColorRed, "FAIL"), v.input, got, v.expected)
+			continue
+		}
+		passed++
+	}
+	failures += len(referenceVectors) - passed
+	fmt.Fprintf(out, "  Reference vectors: %d/%d passed\n", passed, len(referenceVectors))
+
+	return failures
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
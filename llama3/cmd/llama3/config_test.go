@@ -0,0 +1,54 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestConfigCommandGolden(t *testing.T) {
+	if tok, err := llama3.New(); err != nil || tok.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	configs, err := filepath.Glob(filepath.Join("testdata", "config", "*.json"))
+	if err != nil || len(configs) == 0 {
+		t.Fatalf("Expected config files in testdata, got %v (%v)", configs, err)
+	}
+	for _, config := range configs {
+		for _, sub := range []string{"show", "validate"} {
+			name := strings.TrimSuffix(filepath.Base(config), ".json") + "." + sub
+			t.Run(name, func(t *testing.T) {
+				cmd := ConfigCommand()
+				var out bytes.Buffer
+				cmd.SetOut(&out)
+				cmd.SetErr(&bytes.Buffer{})
+				cmd.SetArgs([]string{sub, config})
+				if err := cmd.Execute(); err != nil {
+					t.Fatalf("config %s failed: %v", sub, err)
+				}
+
+				golden := filepath.Join("testdata", "config", name+".golden")
+				if *update {
+					if err := os.WriteFile(golden, out.Bytes(), 0o600); err != nil {
+						t.Fatalf("WriteFile failed: %v", err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("ReadFile failed: %v", err)
+				}
+				if got := out.String(); got != string(want) {
+					t.Errorf("Expected output of %s:\n%s\ngot:\n%s", golden, want, got)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !tokenizer_tiny
+
+package llama3cmd
+
+import "github.com/agentstation/tokenizer/llama3"
+
+// loadConfigFile reads the config file at path.
+func loadConfigFile(path string) (*llama3.Config, error) {
+	return llama3.LoadConfig(path)
+}
+
+// newFromConfigFile constructs the tokenizer described by the config file
+// at path.
+func newFromConfigFile(path string) (*llama3.Tokenizer, error) {
+	return llama3.NewFromConfig(path)
+}
@@ -0,0 +1,23 @@
+//go:build tokenizer_tiny
+
+package llama3cmd
+
+import (
+	"errors"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// errNoConfigFiles is returned for config files in tokenizer_tiny builds,
+// whose llama3 package leaves out reading JSON.
+var errNoConfigFiles = errors.New("config files are not supported in tokenizer_tiny builds")
+
+// loadConfigFile reports errNoConfigFiles.
+func loadConfigFile(string) (*llama3.Config, error) {
+	return nil, errNoConfigFiles
+}
+
+// newFromConfigFile reports errNoConfigFiles.
+func newFromConfigFile(string) (*llama3.Tokenizer, error) {
+	return nil, errNoConfigFiles
+}
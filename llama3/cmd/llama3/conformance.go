@@ -0,0 +1,371 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// Reference implementations known to the conformance command.
+const (
+	referenceJS       = "js"       // llama3-tokenizer-js, run with Node.js
+	referenceHF       = "hf"       // Hugging Face tokenizers, run with Python
+	referenceLlamaCpp = "llamacpp" // llama.cpp's llama-tokenize
+)
+
+// jsEncodeScript encodes the JSON array of strings on stdin with the
+// llama3-tokenizer-js bundle given as its argument.
+const jsEncodeScript = `
+const { pathToFileURL } = await import("node:url");
+const { default: tokenizer } = await import(pathToFileURL(process.argv[1]).href);
+const chunks = [];
+for await (const chunk of process.stdin) chunks.push(chunk);
+const inputs = JSON.parse(Buffer.concat(chunks).toString("utf8"));
+process.stdout.write(JSON.stringify(inputs.map((s) => tokenizer.encode(s, { bos: false, eos: false }))));
+`
+
+// hfEncodeScript encodes the JSON array of strings on stdin with the
+// tokenizer.json given as its argument.
+const hfEncodeScript = `
+import json, sys
+from tokenizers import Tokenizer
+tokenizer = Tokenizer.from_file(sys.argv[1])
+inputs = json.load(sys.stdin)
+json.dump([tokenizer.encode(s, add_special_tokens=False).ids for s in inputs], sys.stdout)
+`
+
+// conformanceReference is another tokenizer implementation to compare with.
+type conformanceReference struct {
+	name   string
+	encode func(ctx context.Context, inputs []string) ([][]int, error)
+}
+
+// conformanceSources locates the reference implementations.
+type conformanceSources struct {
+	jsBundle      string
+	node          string
+	hfTokenizer   string
+	python        string
+	llamaTokenize string
+	llamaModel    string
+}
+
+// ConformanceCommand returns the conformance command, which reports where
+// other Llama 3 tokenizer implementations encode a corpus differently.
+func ConformanceCommand() *cobra.Command {
+	var against []string
+	var corpus, output string
+	var src conformanceSources
+
+	cmd := &cobra.Command{
+		Use:   "conformance --against js,hf,llamacpp --corpus dir",
+		Short: "Compare the tokenizer with other implementations on a corpus",
+		Long: `Encode every file of a corpus with this tokenizer and with other Llama 3
+tokenizer implementations, and report each input on which they differ: the
+first differing token's position and byte offset, and the token each side
+produced there.
+
+Each file under --corpus, including subdirectories, is one input, encoded
+as is without BOS/EOS. The implementations are:
+
+  js        llama3-tokenizer-js, run with Node.js; needs --js-bundle
+  hf        Hugging Face tokenizers, run with Python; needs --hf-tokenizer
+  llamacpp  llama.cpp's llama-tokenize; needs --llamacpp-model
+
+The command fails if any implementation differs on any input, so it can
+track parity in CI.`,
+		Example: `  # Compare with Hugging Face tokenizers and llama.cpp
+  tokenizer conformance --against hf,llamacpp --corpus testdata/corpus \
+    --hf-tokenizer Meta-Llama-3-8B/tokenizer.json \
+    --llamacpp-model Meta-Llama-3-8B.gguf
+
+  # Machine-readable report
+  tokenizer conformance --against js --js-bundle llama3-tokenizer.js --corpus docs/ -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "table" && output != "json" {
+				return fmt.Errorf("unknown output format %q: use table or json", output)
+			}
+			if corpus == "" {
+				return errors.New("--corpus is required")
+			}
+			refs, err := conformanceReferences(against, src)
+			if err != nil {
+				return err
+			}
+			inputs, err := corpusFiles(corpus)
+			if err != nil {
+				return err
+			}
+			tokenizer, err := llama3.New()
+			if err != nil {
+				return fmt.Errorf("failed to initialize tokenizer: %w", err)
+			}
+
+			report, err := runConformance(cmd.Context(), tokenizer, refs, inputs)
+			if err != nil {
+				return err
+			}
+			if err := printConformanceReport(cmd.OutOrStdout(), report, output); err != nil {
+				return err
+			}
+			if n := len(report.Divergences); n > 0 {
+				return fmt.Errorf("%d divergence(s) from other implementations", n)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&against, "against", nil, "Implementations to compare with: js, hf, llamacpp")
+	cmd.Flags().StringVar(&corpus, "corpus", "", "Directory of input files")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&src.jsBundle, "js-bundle", "", "llama3-tokenizer-js bundle with baked data, for js")
+	cmd.Flags().StringVar(&src.node, "node", "node", "Node.js executable, for js")
+	cmd.Flags().StringVar(&src.hfTokenizer, "hf-tokenizer", "", "Hugging Face tokenizer.json of Llama 3, for hf")
+	cmd.Flags().StringVar(&src.python, "python", "python3", "Python executable with the tokenizers package, for hf")
+	cmd.Flags().StringVar(&src.llamaModel, "llamacpp-model", "", "GGUF model of Llama 3, for llamacpp")
+	cmd.Flags().StringVar(&src.llamaTokenize, "llama-tokenize", "llama-tokenize", "llama.cpp's llama-tokenize executable, for llamacpp")
+	_ = cmd.MarkFlagRequired("against")
+
+	return cmd
+}
+
+// conformanceReferences returns the implementations named by --against.
+func conformanceReferences(names []string, src conformanceSources) ([]conformanceReference, error) {
+	var refs []conformanceReference
+	for _, name := range names {
+		switch name {
+		case referenceJS:
+			if src.jsBundle == "" {
+				return nil, errors.New("js needs --js-bundle")
+			}
+			refs = append(refs, conformanceReference{name: name, encode: func(ctx context.Context, inputs []string) ([][]int, error) {
+				return runEncodeScript(ctx, inputs, src.node, "--input-type=module", "-e", jsEncodeScript, src.jsBundle)
+			}})
+		case referenceHF:
+			if src.hfTokenizer == "" {
+				return nil, errors.New("hf needs --hf-tokenizer")
+			}
+			refs = append(refs, conformanceReference{name: name, encode: func(ctx context.Context, inputs []string) ([][]int, error) {
+				return runEncodeScript(ctx, inputs, src.python, "-c", hfEncodeScript, src.hfTokenizer)
+			}})
+		case referenceLlamaCpp:
+			if src.llamaModel == "" {
+				return nil, errors.New("llamacpp needs --llamacpp-model")
+			}
+			refs = append(refs, conformanceReference{name: name, encode: func(ctx context.Context, inputs []string) ([][]int, error) {
+				return runLlamaTokenize(ctx, inputs, src.llamaTokenize, src.llamaModel)
+			}})
+		default:
+			return nil, fmt.Errorf("unknown implementation %q: use js, hf or llamacpp", name)
+		}
+	}
+	return refs, nil
+}
+
+// runEncodeScript runs a program that reads the inputs as a JSON array of
+// strings on stdin and writes their tokens as a JSON array of arrays.
+func runEncodeScript(ctx context.Context, inputs []string, name string, args ...string) ([][]int, error) {
+	stdin, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, err
+	}
+	c := exec.CommandContext(ctx, name, args...) // #nosec G204 - the program is chosen by the caller
+	c.Stdin = bytes.NewReader(stdin)
+	var stderr bytes.Buffer
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var tokens [][]int
+	if err := json.Unmarshal(out, &tokens); err != nil {
+		return nil, fmt.Errorf("%s: invalid output: %w", name, err)
+	}
+	if len(tokens) != len(inputs) {
+		return nil, fmt.Errorf("%s: expected tokens for %d inputs, got %d", name, len(inputs), len(tokens))
+	}
+	return tokens, nil
+}
+
+// runLlamaTokenize encodes each input with llama.cpp's llama-tokenize,
+// which prints the token IDs as a list on its last line of output.
+func runLlamaTokenize(ctx context.Context, inputs []string, name, model string) ([][]int, error) {
+	tokens := make([][]int, len(inputs))
+	for i, input := range inputs {
+		c := exec.CommandContext(ctx, name, "-m", model, "--stdin", "--ids", "--no-bos", "--log-disable") // #nosec G204 - the program is chosen by the caller
+		c.Stdin = strings.NewReader(input)
+		var stderr bytes.Buffer
+		c.Stderr = &stderr
+		out, err := c.Output()
+		if err != nil {
+			return nil, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if err := json.Unmarshal([]byte(lines[len(lines)-1]), &tokens[i]); err != nil {
+			return nil, fmt.Errorf("%s: invalid output: %w", name, err)
+		}
+	}
+	return tokens, nil
+}
+
+// conformanceInput is one corpus file.
+type conformanceInput struct {
+	path string
+	text string
+}
+
+// corpusFiles reads the files under dir in lexical order.
+func corpusFiles(dir string) ([]conformanceInput, error) {
+	var inputs []conformanceInput
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path) // #nosec G304 - path is under the caller's corpus
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(data) {
+			return fmt.Errorf("%s is not valid UTF-8", path)
+		}
+		inputs = append(inputs, conformanceInput{path: path, text: string(data)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no files found in %s", dir)
+	}
+	return inputs, nil
+}
+
+// conformanceSummary counts the inputs on which an implementation agrees.
+type conformanceSummary struct {
+	Implementation string `json:"implementation"`
+	Inputs         int    `json:"inputs"`
+	Divergent      int    `json:"divergent"`
+}
+
+// conformanceDivergence is the first difference on one input.
+type conformanceDivergence struct {
+	Implementation string `json:"implementation"`
+	Input          string `json:"input"`
+	Position       int    `json:"position"` // Index of the first differing token
+	Offset         int    `json:"offset"`   // Byte offset in the input where it starts
+	Ours           *int   `json:"ours"`     // nil if our tokens end first
+	Theirs         *int   `json:"theirs"`   // nil if their tokens end first
+	OursText       string `json:"ours_text,omitempty"`
+	TheirsText     string `json:"theirs_text,omitempty"`
+}
+
+// conformanceReport is the result of the conformance command.
+type conformanceReport struct {
+	Summary     []conformanceSummary    `json:"summary"`
+	Divergences []conformanceDivergence `json:"divergences"`
+}
+
+// runConformance encodes inputs with tokenizer and each reference and
+// collects the divergences.
+func runConformance(ctx context.Context, tokenizer *llama3.Tokenizer, refs []conformanceReference, inputs []conformanceInput) (*conformanceReport, error) {
+	texts := make([]string, len(inputs))
+	for i, in := range inputs {
+		texts[i] = in.text
+	}
+	ours := tokenizer.EncodeBatch(texts, &llama3.EncodeOptions{BOS: false, EOS: false})
+
+	report := &conformanceReport{Divergences: []conformanceDivergence{}}
+	for _, ref := range refs {
+		theirs, err := ref.encode(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref.name, err)
+		}
+		summary := conformanceSummary{Implementation: ref.name, Inputs: len(inputs)}
+		for i, in := range inputs {
+			d, ok := firstDivergence(tokenizer, ours[i], theirs[i])
+			if !ok {
+				continue
+			}
+			d.Implementation = ref.name
+			d.Input = in.path
+			report.Divergences = append(report.Divergences, d)
+			summary.Divergent++
+		}
+		report.Summary = append(report.Summary, summary)
+	}
+	return report, nil
+}
+
+// firstDivergence compares two encodings of the same text and describes
+// where they first differ, if they do.
+func firstDivergence(tokenizer *llama3.Tokenizer, ours, theirs []int) (conformanceDivergence, bool) {
+	pos := 0
+	for pos < len(ours) && pos < len(theirs) && ours[pos] == theirs[pos] {
+		pos++
+	}
+	if pos == len(ours) && pos == len(theirs) {
+		return conformanceDivergence{}, false
+	}
+
+	d := conformanceDivergence{Position: pos, Offset: len(tokenizer.DecodeBytes(ours[:pos]))}
+	if pos < len(ours) {
+		d.Ours = &ours[pos]
+		d.OursText, _ = tokenizer.TokenText(ours[pos])
+	}
+	if pos < len(theirs) {
+		d.Theirs = &theirs[pos]
+		d.TheirsText, _ = tokenizer.TokenText(theirs[pos])
+	}
+	return d, true
+}
+
+func printConformanceReport(w io.Writer, report *conformanceReport, output string) error {
+	if output == "json" {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if len(report.Divergences) > 0 {
+		fmt.Fprintln(tw, "IMPLEMENTATION\tINPUT\tPOSITION\tOFFSET\tOURS\tTHEIRS\t")
+		for _, d := range report.Divergences {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\t%s\t\n", d.Implementation, d.Input, d.Position, d.Offset,
+				formatConformanceToken(d.Ours, d.OursText), formatConformanceToken(d.Theirs, d.TheirsText))
+		}
+		fmt.Fprintln(tw)
+	}
+	for _, s := range report.Summary {
+		fmt.Fprintf(tw, "%s: %d of %d inputs agree\n", s.Implementation, s.Inputs-s.Divergent, s.Inputs)
+	}
+	return tw.Flush()
+}
+
+// formatConformanceToken formats a token as its ID and quoted text.
+func formatConformanceToken(id *int, text string) string {
+	if id == nil {
+		return "(end)"
+	}
+	return strconv.Itoa(*id) + " " + strconv.Quote(text)
+}
@@ -0,0 +1,96 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestRunConformance(t *testing.T) {
+	tokenizer, err := llama3.New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	inputs := []conformanceInput{
+		{path: "a.txt", text: "Hello world"},
+		{path: "b.txt", text: "Hello, world!"},
+	}
+	opts := &llama3.EncodeOptions{BOS: false, EOS: false}
+	hello := tokenizer.Encode("Hello", opts)[0]
+	world := tokenizer.Encode(" world", opts)[0]
+
+	agree := conformanceReference{name: "same", encode: func(_ context.Context, texts []string) ([][]int, error) {
+		return tokenizer.EncodeBatch(texts, opts), nil
+	}}
+	differ := conformanceReference{name: "other", encode: func(_ context.Context, texts []string) ([][]int, error) {
+		tokens := tokenizer.EncodeBatch(texts, opts)
+		tokens[0] = []int{hello, 1174, world}    // diverges after "Hello"
+		tokens[1] = tokens[1][:len(tokens[1])-1] // ends early
+		return tokens, nil
+	}}
+
+	report, err := runConformance(context.Background(), tokenizer, []conformanceReference{agree, differ}, inputs)
+	if err != nil {
+		t.Fatalf("runConformance failed: %v", err)
+	}
+	if len(report.Summary) != 2 || report.Summary[0].Divergent != 0 || report.Summary[1].Divergent != 2 {
+		t.Fatalf("Expected 0 and 2 divergent inputs, got %+v", report.Summary)
+	}
+
+	first := report.Divergences[0]
+	if first.Input != "a.txt" || first.Position != 1 || first.Offset != len("Hello") {
+		t.Errorf("Expected a divergence at token 1, offset 5 of a.txt, got %+v", first)
+	}
+	if first.Ours == nil || *first.Ours != world || first.OursText != " world" || first.Theirs == nil || *first.Theirs != 1174 {
+		t.Errorf("Expected our %q against their token 1174, got %+v", " world", first)
+	}
+	if second := report.Divergences[1]; second.Ours == nil || second.Theirs != nil {
+		t.Errorf("Expected their tokens to end first, got %+v", second)
+	}
+
+	var out bytes.Buffer
+	if err := printConformanceReport(&out, report, "table"); err != nil {
+		t.Fatalf("printConformanceReport failed: %v", err)
+	}
+	for _, want := range []string{"a.txt", "(end)", "same: 2 of 2 inputs agree", "other: 0 of 2 inputs agree"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected table to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestConformanceCommandErrors(t *testing.T) {
+	corpus := t.TempDir()
+	if err := os.WriteFile(filepath.Join(corpus, "a.txt"), []byte("text"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"unknown implementation", []string{"--against", "sentencepiece", "--corpus", corpus}, "unknown implementation"},
+		{"missing source", []string{"--against", "hf", "--corpus", corpus}, "--hf-tokenizer"},
+		{"missing corpus", []string{"--against", "js", "--js-bundle", "x.js"}, "--corpus"},
+		{"empty corpus", []string{"--against", "js", "--js-bundle", "x.js", "--corpus", t.TempDir()}, "no files"},
+		{"output", []string{"--against", "js", "--corpus", corpus, "-o", "xml"}, "unknown output format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := ConformanceCommand()
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package llama3cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/cost"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// CostCommand returns the cost command, which estimates the price of
+// requests from their token counts.
+func CostCommand() *cobra.Command {
+	var inputPrice, outputPrice float64
+	var pricesPath, output, crlf string
+	var models []string
+	var outputTokens int
+
+	cmd := &cobra.Command{
+		Use:   "cost [file...]",
+		Short: "Estimate the price of prompts",
+		Long: `Count the tokens of prompts and estimate what sending them costs.
+
+Each file is one request, or stdin if no file is given. --output-tokens adds
+the expected number of generated tokens per request. Prices are in dollars
+per 1,000 tokens, given with --input-price and --output-price or read from a
+JSON file of models with --prices:
+
+  {"llama-3.1-8b": {"input": 0.0002, "output": 0.0002}}
+
+With --prices, every model in the file is estimated unless --model selects
+some. Prompts are counted with the Llama 3 tokenizer, without BOS/EOS.`,
+		Example: `  # Estimate a prompt with an expected 500-token answer
+  tokenizer cost --input-price 0.003 --output-price 0.015 --output-tokens 500 prompt.md
+
+  # Compare models over a prompt set
+  tokenizer cost --prices prices.json prompts/*.md`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputTokens < 0 {
+				return errors.New("--output-tokens must not be negative")
+			}
+			if output != "table" && output != "json" {
+				return fmt.Errorf("unknown output format %q: use table or json", output)
+			}
+			prices, err := selectPrices(cmd, pricesPath, models, cost.Pricing{Input: inputPrice, Output: outputPrice})
+			if err != nil {
+				return err
+			}
+
+			count, err := newCounter(&llama3.EncodeOptions{}, crlf)
+			if err != nil {
+				return err
+			}
+			var inputs []int
+			if len(args) == 0 {
+				data, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return fmt.Errorf("failed to read input: %w", err)
+				}
+				n, err := count(data)
+				if err != nil {
+					return err
+				}
+				inputs = append(inputs, n)
+			}
+			for _, path := range args {
+				data, err := os.ReadFile(path) // #nosec G304 - path is provided by the caller
+				if err != nil {
+					return fmt.Errorf("failed to read input: %w", err)
+				}
+				n, err := count(data)
+				if err != nil {
+					return err
+				}
+				inputs = append(inputs, n)
+			}
+
+			estimates := make([]modelEstimate, 0, len(prices))
+			for _, model := range prices.Models() {
+				var total cost.Estimate
+				for _, n := range inputs {
+					total = total.Add(prices[model].Estimate(n, outputTokens))
+				}
+				estimates = append(estimates, modelEstimate{Model: model, Estimate: total})
+			}
+			return printEstimates(cmd.OutOrStdout(), estimates, output)
+		},
+	}
+
+	cmd.Flags().Float64Var(&inputPrice, "input-price", 0, "Dollars per 1,000 prompt tokens")
+	cmd.Flags().Float64Var(&outputPrice, "output-price", 0, "Dollars per 1,000 generated tokens")
+	cmd.Flags().StringVar(&pricesPath, "prices", "", "JSON file of prices per model")
+	cmd.Flags().StringArrayVar(&models, "model", nil, "Model of the prices file to estimate, repeatable (default: all)")
+	cmd.Flags().IntVar(&outputTokens, "output-tokens", 0, "Expected generated tokens per request")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&crlf, "crlf", cli.CRLFAuto, "Line endings: auto, keep, lf")
+	cmd.MarkFlagsMutuallyExclusive("prices", "input-price")
+	cmd.MarkFlagsMutuallyExclusive("prices", "output-price")
+
+	return cmd
+}
+
+// modelEstimate is the estimate of one model.
+type modelEstimate struct {
+	Model string `json:"model"`
+	cost.Estimate
+}
+
+// selectPrices returns the prices to estimate: the models of the prices file,
+// or the prices given by flags.
+func selectPrices(cmd *cobra.Command, path string, models []string, flagPrice cost.Pricing) (cost.Prices, error) {
+	if path == "" {
+		if len(models) > 0 {
+			return nil, errors.New("--model requires --prices")
+		}
+		if !cmd.Flags().Changed("input-price") && !cmd.Flags().Changed("output-price") {
+			return nil, errors.New("set --input-price and --output-price, or --prices")
+		}
+		if err := flagPrice.Validate(); err != nil {
+			return nil, err
+		}
+		return cost.Prices{"custom": flagPrice}, nil
+	}
+
+	prices, err := cost.LoadPrices(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		return prices, nil
+	}
+	selected := make(cost.Prices, len(models))
+	for _, model := range models {
+		p, ok := prices[model]
+		if !ok {
+			return nil, fmt.Errorf("model %q not found in %s", model, path)
+		}
+		selected[model] = p
+	}
+	return selected, nil
+}
+
+func printEstimates(w io.Writer, estimates []modelEstimate, output string) error {
+	if output == "json" {
+		data, err := json.Marshal(estimates)
+		if err != nil {
+			return fmt.Errorf("failed to marshal estimates: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODEL\tINPUT TOKENS\tOUTPUT TOKENS\tINPUT COST\tOUTPUT COST\tTOTAL\t")
+	for _, e := range estimates {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t$%.4f\t$%.4f\t$%.4f\t\n",
+			e.Model, e.InputTokens, e.OutputTokens, e.InputCost, e.OutputCost, e.Total)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,66 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCostCommand(t *testing.T) {
+	dir := t.TempDir()
+	prices := filepath.Join(dir, "prices.json")
+	if err := os.WriteFile(prices, []byte(`{"small": {"input": 1, "output": 2}, "large": {"input": 10, "output": 20}}`), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	run := func(stdin string, args ...string) (string, error) {
+		cmd := CostCommand()
+		var out bytes.Buffer
+		cmd.SetIn(strings.NewReader(stdin))
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	// "Hello, world!" is 4 tokens
+	out, err := run("Hello, world!", "--prices", prices, "--model", "small", "--output-tokens", "1000", "-o", "json")
+	if err != nil {
+		t.Fatalf("cost failed: %v", err)
+	}
+	var estimates []modelEstimate
+	if err := json.Unmarshal([]byte(out), &estimates); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+	if len(estimates) != 1 || estimates[0].Model != "small" || estimates[0].InputTokens != 4 || estimates[0].OutputTokens != 1000 {
+		t.Fatalf("Expected one estimate of small for 4+1000 tokens, got %+v", estimates)
+	}
+	if total := estimates[0].Total; total < 2.0039 || total > 2.0041 {
+		t.Errorf("Expected a total of $2.004, got %v", total)
+	}
+
+	out, err = run("Hello, world!", "--input-price", "3", "--output-price", "15")
+	if err != nil {
+		t.Fatalf("cost failed: %v", err)
+	}
+	if !strings.Contains(out, "custom") || !strings.Contains(out, "$0.0120") {
+		t.Errorf("Expected a custom row costing $0.0120, got %q", out)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"--model", "small"},
+		{"--prices", prices, "--model", "missing"},
+		{"--prices", prices, "--input-price", "1"},
+		{"--input-price", "-1"},
+		{"--input-price", "1", "-o", "xml"},
+	} {
+		if _, err := run("text", args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// Timing of count --watch.
+const (
+	watchPollInterval = 100 * time.Millisecond // how often the file is checked
+	watchDebounce     = 150 * time.Millisecond // quiet time before a change is counted
+)
+
+// newCountCmd creates the count subcommand.
+func newCountCmd() *cobra.Command {
+	var bos, eos, watch bool
+	var crlf string
+	var budget int
+
+	cmd := &cobra.Command{
+		Use:   "count [file...]",
+		Short: "Count the tokens of files or stdin",
+		Long: `Count the tokens of each file, or of stdin if no file is given. With more
+than one file, a total is printed as well.
+
+--max sets a token budget: files over it are reported and the command exits
+with an error.
+
+--watch keeps counting one file: it is counted again whenever it changes and
+the new count is printed with the difference from the previous one, which is
+handy while editing a prompt. Changes are detected by polling, and bursts of
+writes, as editors make when saving, are counted once.`,
+		Example: `  # Count a prompt
+  tokenizer llama3 count prompt.md
+
+  # Fail if any prompt is over 4096 tokens
+  tokenizer llama3 count --max 4096 prompts/*.md
+
+  # Recount the prompt every time it is saved
+  tokenizer llama3 count --watch prompt.md`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			count, err := newCounter(&llama3.EncodeOptions{BOS: bos, EOS: eos}, crlf)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if watch {
+				if len(args) != 1 {
+					return errors.New("--watch requires exactly one file")
+				}
+				ctx := cmd.Context()
+				if ctx == nil {
+					ctx = context.Background()
+				}
+				return watchCount(ctx, out, cmd.ErrOrStderr(), args[0], count, watchPollInterval, watchDebounce)
+			}
+
+			if len(args) == 0 {
+				data, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return fmt.Errorf("failed to read input: %w", err)
+				}
+				n, err := count(data)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, n)
+				if budget > 0 && n > budget {
+					return fmt.Errorf("input has %d tokens, over the budget of %d", n, budget)
+				}
+				return nil
+			}
+
+			total, over := 0, 0
+			for _, path := range args {
+				data, err := os.ReadFile(path) // #nosec G304 - path is provided by the caller
+				if err != nil {
+					return fmt.Errorf("failed to read input: %w", err)
+				}
+				n, err := count(data)
+				if err != nil {
+					return err
+				}
+				total += n
+				fmt.Fprintf(out, "%d\t%s\n", n, path)
+				if budget > 0 && n > budget {
+					over++
+				}
+			}
+			if len(args) > 1 {
+				fmt.Fprintf(out, "%d\ttotal\n", total)
+			}
+			if over > 0 {
+				return fmt.Errorf("%d of %d files over the budget of %d tokens", over, len(args), budget)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&bos, "bos", true, "Add beginning of sequence token")
+	cmd.Flags().BoolVar(&eos, "eos", true, "Add end of sequence token")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Count again whenever the file changes")
+	cmd.Flags().IntVar(&budget, "max", 0, "Token budget per file; exit with an error if a file exceeds it")
+	cmd.Flags().StringVar(&crlf, "crlf", cli.CRLFAuto, "Line endings: auto, keep, lf")
+
+	return cmd
+}
+
+// newCounter returns a function counting the tokens of text with the Llama 3
+// tokenizer, after normalizing it as encode normalizes stdin: UTF-16 with a
+// byte order mark is converted to UTF-8, and line endings as selected by crlf.
+func newCounter(opts *llama3.EncodeOptions, crlf string) (func([]byte) (int, error), error) {
+	tokenizer, err := llama3.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tokenizer: %w", err)
+	}
+	return func(data []byte) (int, error) {
+		input, err := cli.NormalizeInput(bytes.NewReader(data), crlf)
+		if err != nil {
+			return 0, err
+		}
+		text, err := io.ReadAll(input)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read input: %w", err)
+		}
+		return tokenizer.Count(string(text), opts), nil
+	}, nil
+}
+
+// watchCount prints the token count of path and then, until ctx is done,
+// the new count and its difference each time the file changes. A change is
+// counted once the file's size and modification time have stayed the same
+// for debounce. Errors reading the file, such as while an editor replaces
+// it, are reported to errOut and watching continues.
+func watchCount(ctx context.Context, out, errOut io.Writer, path string, count func([]byte) (int, error), poll, debounce time.Duration) error {
+	type version struct {
+		size    int64
+		modTime time.Time
+	}
+	stat := func() (version, error) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return version{}, err
+		}
+		return version{info.Size(), info.ModTime()}, nil
+	}
+
+	last := -1 // last count printed
+	recount := func() {
+		data, err := os.ReadFile(path) // #nosec G304 - path is provided by the caller
+		if err == nil {
+			var n int
+			if n, err = count(data); err == nil {
+				if last < 0 {
+					fmt.Fprintf(out, "%s: %d tokens\n", path, n)
+				} else {
+					fmt.Fprintf(out, "%s: %d tokens (%+d)\n", path, n, n-last)
+				}
+				last = n
+				return
+			}
+		}
+		fmt.Fprintf(errOut, "%s: %v\n", path, err)
+	}
+
+	counted, err := stat()
+	if err != nil {
+		return fmt.Errorf("failed to watch input: %w", err)
+	}
+	recount()
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	seen, seenAt := counted, time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			v, err := stat()
+			if err != nil {
+				continue // the file may be briefly missing while it is replaced
+			}
+			if v != seen {
+				seen, seenAt = v, now
+				continue
+			}
+			if v != counted && now.Sub(seenAt) >= debounce {
+				counted = v
+				recount()
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// syncBuffer is a bytes.Buffer safe for one writer and one reader.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.md")
+	if err := os.WriteFile(path, []byte("one two three"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	count := func(data []byte) (int, error) {
+		return len(strings.Fields(string(data))), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out, errOut syncBuffer
+	done := make(chan error, 1)
+	go func() {
+		done <- watchCount(ctx, &out, &errOut, path, count, 5*time.Millisecond, 20*time.Millisecond)
+	}()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !strings.Contains(out.String(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected output to contain %q, got %q", want, out.String())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	waitFor(path + ": 3 tokens\n")
+	if err := os.WriteFile(path, []byte("one two three four five"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	waitFor(path + ": 5 tokens (+2)\n")
+	if err := os.WriteFile(path, []byte("one"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	waitFor(path + ": 1 tokens (-4)\n")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected nil error after cancel, got %v", err)
+	}
+	if errOut.String() != "" {
+		t.Errorf("Expected no errors, got %q", errOut.String())
+	}
+}
+
+func TestNewCounter(t *testing.T) {
+	tokenizer, err := llama3.New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	opts := &llama3.EncodeOptions{BOS: true, EOS: false}
+	count, err := newCounter(opts, cli.CRLFLF)
+	if err != nil {
+		t.Fatalf("newCounter failed: %v", err)
+	}
+
+	text := "Héllo,\nwörld! 👋"
+	utf16LE := []byte{0xFF, 0xFE}
+	utf16BE := []byte{0xFE, 0xFF}
+	for _, u := range utf16.Encode([]rune(text)) {
+		utf16LE = append(utf16LE, byte(u), byte(u>>8))
+		utf16BE = append(utf16BE, byte(u>>8), byte(u))
+	}
+
+	want := len(tokenizer.Encode(text, opts))
+	for name, data := range map[string][]byte{
+		"utf8":     []byte(text),
+		"crlf":     []byte(strings.ReplaceAll(text, "\n", "\r\n")),
+		"utf16_le": utf16LE,
+		"utf16_be": utf16BE,
+	} {
+		if got, err := count(data); err != nil || got != want {
+			t.Errorf("%s: expected %d tokens, got %d (%v)", name, want, got, err)
+		}
+	}
+}
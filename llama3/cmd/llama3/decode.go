@@ -3,12 +3,12 @@ package llama3cmd
 import (
 	"bufio"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/agentstation/tokenizer/cli"
 	"github.com/agentstation/tokenizer/llama3"
 )
 
@@ -50,7 +50,8 @@ skipped using the --skip-special flag.`,
 	return cmd
 }
 
-func runDecode(_ *cobra.Command, args []string) error {
+func runDecode(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
 	// Initialize tokenizer
 	tokenizer, err := llama3.New()
 	if err != nil {
@@ -69,8 +70,12 @@ func runDecode(_ *cobra.Command, args []string) error {
 			tokens = append(tokens, token)
 		}
 	} else {
-		// Read from stdin
-		scanner := bufio.NewScanner(os.Stdin)
+		// Read from stdin, which may be UTF-16 when piped from PowerShell
+		input, err := cli.NormalizeInput(cmd.InOrStdin(), cli.CRLFKeep)
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(input)
 		scanner.Split(bufio.ScanWords)
 		for scanner.Scan() {
 			token, err := strconv.Atoi(scanner.Text())
@@ -117,6 +122,6 @@ func runDecode(_ *cobra.Command, args []string) error {
 		}
 	}
 
-	fmt.Print(text)
+	fmt.Fprint(out, text)
 	return nil
 }
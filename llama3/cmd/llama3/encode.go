@@ -1,15 +1,17 @@
 package llama3cmd
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/agentstation/tokenizer/cli"
 	"github.com/agentstation/tokenizer/llama3"
 )
 
@@ -21,6 +23,7 @@ var (
 	encCount     bool
 	encCountOnly bool
 	encMetrics   bool
+	encCRLF      string
 )
 
 // newEncodeCmd creates the encode subcommand.
@@ -33,6 +36,14 @@ func newEncodeCmd() *cobra.Command {
 If no text is provided as an argument, reads from stdin.
 By default, adds beginning-of-sequence (BOS) and end-of-sequence (EOS) tokens.
 
+Line endings are controlled with --crlf:
+  - auto: Convert CRLF to LF on Windows, keep it elsewhere (default)
+  - keep: Count CR characters as part of the text
+  - lf: Convert CRLF to LF, so files saved on Windows count the same everywhere
+
+Input that starts with a UTF-16 byte order mark, as written by Windows
+PowerShell, is converted to UTF-8.
+
 The output format can be:
   - space: Space-separated token IDs (default)
   - newline: One token ID per line
@@ -56,7 +67,10 @@ The output format can be:
   tokenizer llama3 encode --count "Hello"
   
   # Show only the token count
-  tokenizer llama3 encode --count-only "Hello"`,
+  tokenizer llama3 encode --count-only "Hello"
+  
+  # Count a file saved with Windows line endings as if it used LF
+  tokenizer llama3 encode --crlf=lf --count-only < notes.txt`,
 		RunE: runEncode,
 	}
 
@@ -67,11 +81,37 @@ The output format can be:
 	cmd.Flags().BoolVar(&encCount, "count", false, "Show token count with output")
 	cmd.Flags().BoolVar(&encCountOnly, "count-only", false, "Show only token count (no tokens)")
 	cmd.Flags().BoolVar(&encMetrics, "metrics", false, "Show performance metrics")
+	cmd.Flags().StringVar(&encCRLF, "crlf", cli.CRLFAuto, "Line endings: auto, keep, lf")
 
 	return cmd
 }
 
-func runEncode(_ *cobra.Command, args []string) error {
+// tokenizedPeekSize is how much of stdin encode checks for input that is
+// already tokenized.
+const tokenizedPeekSize = 4096
+
+// warnTokenizedInput warns on w if text looks like token IDs or byte-level
+// token text rather than text to encode.
+func warnTokenizedInput(w io.Writer, text string) {
+	if llama3.LooksLikeTokenizedText(text) {
+		fmt.Fprintln(w, "warning: input looks already tokenized (token IDs or byte-level token text); use \"tokenizer llama3 decode\" to decode it")
+	}
+}
+
+// peekWords returns the start of r's input without consuming it, cut after
+// the last whitespace if the input goes on.
+func peekWords(r *bufio.Reader) string {
+	data, err := r.Peek(tokenizedPeekSize)
+	if err == nil {
+		if i := bytes.LastIndexAny(data, " \t\n\r"); i >= 0 {
+			data = data[:i]
+		}
+	}
+	return string(data)
+}
+
+func runEncode(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
 	var startTime time.Time
 	if encMetrics {
 		startTime = time.Now()
@@ -88,17 +128,28 @@ func runEncode(_ *cobra.Command, args []string) error {
 	var inputBytes int
 
 	if len(args) > 0 {
-		text := strings.Join(args, " ")
+		text, err := cli.NormalizeText(strings.Join(args, " "), encCRLF)
+		if err != nil {
+			return err
+		}
 		inputBytes = len(text)
 		reader = strings.NewReader(text)
+		warnTokenizedInput(cmd.ErrOrStderr(), text)
 	} else {
 		// For stdin, wrap with counting reader if metrics enabled
+		input, err := cli.NormalizeInput(cmd.InOrStdin(), encCRLF)
+		if err != nil {
+			return err
+		}
+		peeked := bufio.NewReaderSize(input, tokenizedPeekSize)
+		warnTokenizedInput(cmd.ErrOrStderr(), peekWords(peeked))
+		input = peeked
 		if encMetrics {
-			cr := &countingReader{Reader: os.Stdin}
+			cr := &countingReader{Reader: input}
 			reader = cr
 			defer func() { inputBytes = cr.bytesRead }()
 		} else {
-			reader = os.Stdin
+			reader = input
 		}
 	}
 
@@ -139,9 +190,9 @@ func runEncode(_ *cobra.Command, args []string) error {
 			if err != nil {
 				return fmt.Errorf("failed to marshal count: %w", err)
 			}
-			fmt.Println(string(data))
+			fmt.Fprintln(out, string(data))
 		default:
-			fmt.Println(len(tokens))
+			fmt.Fprintln(out, len(tokens))
 		}
 		return nil
 	}
@@ -167,37 +218,37 @@ func runEncode(_ *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to marshal output: %w", err)
 		}
-		fmt.Println(string(data))
+		fmt.Fprintln(out, string(data))
 	case "newline":
 		if encCount {
-			fmt.Printf("count: %d\n", len(tokens))
+			fmt.Fprintf(out, "count: %d\n", len(tokens))
 		}
 		for _, token := range tokens {
-			fmt.Println(token)
+			fmt.Fprintln(out, token)
 		}
 		if encMetrics {
-			fmt.Println("metrics:")
-			fmt.Printf("  latency: %s\n", formatLatency(encodeDuration))
-			fmt.Printf("  tps: %d\n", calculateTPS(len(tokens), encodeDuration))
-			fmt.Printf("  input_bytes: %d\n", inputBytes)
+			fmt.Fprintln(out, "metrics:")
+			fmt.Fprintf(out, "  latency: %s\n", formatLatency(encodeDuration))
+			fmt.Fprintf(out, "  tps: %d\n", calculateTPS(len(tokens), encodeDuration))
+			fmt.Fprintf(out, "  input_bytes: %d\n", inputBytes)
 		}
 	case "space":
 		if encCount {
-			fmt.Printf("count: %d\n", len(tokens))
-			fmt.Print("tokens: ")
+			fmt.Fprintf(out, "count: %d\n", len(tokens))
+			fmt.Fprint(out, "tokens: ")
 		}
 		for i, token := range tokens {
 			if i > 0 {
-				fmt.Print(" ")
+				fmt.Fprint(out, " ")
 			}
-			fmt.Print(token)
+			fmt.Fprint(out, token)
 		}
-		fmt.Println()
+		fmt.Fprintln(out)
 		if encMetrics {
-			fmt.Println("metrics:")
-			fmt.Printf("  latency: %s\n", formatLatency(encodeDuration))
-			fmt.Printf("  tps: %d\n", calculateTPS(len(tokens), encodeDuration))
-			fmt.Printf("  input_bytes: %d\n", inputBytes)
+			fmt.Fprintln(out, "metrics:")
+			fmt.Fprintf(out, "  latency: %s\n", formatLatency(encodeDuration))
+			fmt.Fprintf(out, "  tps: %d\n", calculateTPS(len(tokens), encodeDuration))
+			fmt.Fprintf(out, "  input_bytes: %d\n", inputBytes)
 		}
 	default:
 		return fmt.Errorf("unknown output format: %s", encOutput)
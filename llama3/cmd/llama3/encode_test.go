@@ -0,0 +1,46 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestEncodeWarnsOnTokenizedInput(t *testing.T) {
+	if tokenizer, err := llama3.New(); err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		warn  bool
+	}{
+		{"token IDs on stdin", []string{"encode"}, "128000 9906 11 1917 0 128001\n", true},
+		{"byte-level argument", []string{"encode", "HelloĠworldĠagain"}, "", true},
+		{"text on stdin", []string{"encode"}, "Hello, world!\n", false},
+		{"long input", []string{"encode"}, strings.Repeat("12345 ", 2000), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			cmd := Command()
+			cmd.SetOut(&out)
+			cmd.SetErr(&errOut)
+			cmd.SetIn(strings.NewReader(tt.stdin))
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got := strings.Contains(errOut.String(), "looks already tokenized"); got != tt.warn {
+				t.Errorf("Expected warning %v, got stderr %q", tt.warn, errOut.String())
+			}
+			if out.Len() == 0 {
+				t.Error("Expected tokens on stdout")
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package llama3cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/llama3"
+	"github.com/agentstation/tokenizer/tokenio"
+)
+
+// encodeDirResult is the token count of one file tokenized by encode-dir.
+type encodeDirResult struct {
+	Path   string `json:"path"`
+	Tokens int    `json:"tokens"`
+}
+
+// encodeDirOptions configures encodeDir.
+type encodeDirOptions struct {
+	encode  *llama3.EncodeOptions
+	crlf    string
+	workers int
+	outDir  string         // token files are written here if not empty
+	format  tokenio.Format // format of the token files
+
+	// previous holds the token files of the last run into outDir by input
+	// path, if it used the same settings. Those whose input is unchanged
+	// are kept instead of encoded again.
+	previous map[string]encodeDirShard
+}
+
+// newEncodeDirCmd creates the encode-dir subcommand.
+func newEncodeDirCmd() *cobra.Command {
+	var include, exclude []string
+	var outDir, format, output, crlf string
+	var workers int
+	var bos, eos bool
+
+	cmd := &cobra.Command{
+		Use:   "encode-dir dir",
+		Short: "Tokenize every file of a directory in parallel",
+		Long: `Walk a directory and tokenize its files with a pool of workers, printing
+the token count of each file and the total.
+
+Files are selected with --include and --exclude patterns matched against paths
+relative to the directory, in which * matches within a path segment and **
+matches any number of segments. Without --include every file is tokenized.
+
+With --out, the tokens of each file are also written to <out>/<path>.tokens in
+the format of --format, mirroring the directory tree: u32, uvarint or
+compressed for binary files, or ndjson for a JSON array per file (written to
+<path>.jsonl). Files of the output directory are skipped when it is inside the
+input directory.
+
+With --out, encode-dir also writes <out>/manifest.json, which lists each token
+file with its token count, the SHA-256 of its input and of itself, and the
+SHA-256 of the tokenizer data. A later run into the same directory with the
+same tokenizer and flags keeps the token files whose input is unchanged and
+whose hash still matches, and encodes only the others.`,
+		Example: `  # Count the tokens of a corpus
+  tokenizer llama3 encode-dir corpus/ --include "**/*.md"
+
+  # Write uint32 token files for training, on 16 workers
+  tokenizer llama3 encode-dir corpus/ --out tokens/ --workers 16 --eos=false
+
+  # Per-file counts as JSON
+  tokenizer llama3 encode-dir corpus/ --exclude "**/.git/**" -o json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "table" && output != "json" {
+				return fmt.Errorf("unknown output format %q: use table or json", output)
+			}
+			if workers < 1 {
+				return errors.New("--workers must be positive")
+			}
+			for _, g := range slices.Concat(include, exclude) {
+				if err := checkGlob(g); err != nil {
+					return err
+				}
+			}
+			f, err := tokenio.ParseFormat(format)
+			if err != nil {
+				return err
+			}
+
+			files, err := encodeDirFiles(args[0], include, exclude, outDir)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				return fmt.Errorf("no files to tokenize in %s", args[0])
+			}
+
+			tokenizer, err := llama3.New()
+			if err != nil {
+				return fmt.Errorf("failed to initialize tokenizer: %w", err)
+			}
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			opts := encodeDirOptions{
+				encode:  &llama3.EncodeOptions{BOS: bos, EOS: eos},
+				crlf:    crlf,
+				workers: workers,
+				outDir:  outDir,
+				format:  f,
+			}
+
+			var manifest *encodeDirManifest
+			if outDir != "" {
+				hash, err := tokenizerSHA256(tokenizer)
+				if err != nil {
+					return err
+				}
+				manifest = &encodeDirManifest{
+					Version:   encodeDirManifestVersion,
+					Tokenizer: hash,
+					Format:    string(f),
+					BOS:       bos,
+					EOS:       eos,
+					CRLF:      crlf,
+				}
+				previous, err := readEncodeDirManifest(outDir)
+				if err != nil {
+					return err
+				}
+				if previous != nil && previous.sameSettings(manifest) {
+					opts.previous = make(map[string]encodeDirShard, len(previous.Files))
+					for _, shard := range previous.Files {
+						opts.previous[shard.Path] = shard
+					}
+				}
+			}
+
+			shards, err := encodeDir(ctx, tokenizer, args[0], files, opts)
+			if err != nil {
+				return err
+			}
+			results := make([]encodeDirResult, len(shards))
+			for i, shard := range shards {
+				results[i] = shard.encodeDirResult
+			}
+			if manifest != nil {
+				manifest.Files = shards
+				for _, r := range results {
+					manifest.Total += r.Tokens
+				}
+				if err := writeEncodeDirManifest(outDir, manifest); err != nil {
+					return err
+				}
+			}
+			return printEncodeDirResults(cmd.OutOrStdout(), results, output)
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&include, "include", nil, "Glob of files to tokenize (repeatable; default all)")
+	cmd.Flags().StringArrayVar(&exclude, "exclude", nil, "Glob of files to skip (repeatable)")
+	cmd.Flags().IntVarP(&workers, "workers", "j", runtime.GOMAXPROCS(0), "Number of files tokenized at once")
+	cmd.Flags().StringVar(&outDir, "out", "", "Directory to write token files to")
+	cmd.Flags().StringVar(&format, "format", string(tokenio.U32), "Token file format: u32, uvarint, ndjson, compressed")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().BoolVar(&bos, "bos", true, "Add beginning of sequence token")
+	cmd.Flags().BoolVar(&eos, "eos", true, "Add end of sequence token")
+	cmd.Flags().StringVar(&crlf, "crlf", cli.CRLFAuto, "Line endings: auto, keep, lf")
+
+	return cmd
+}
+
+// encodeDirFiles returns the slash-separated paths, relative to root, of
+// the regular files under root that match one of include, or any file if
+// include is empty, and none of exclude. The directory skip is not walked.
+func encodeDirFiles(root string, include, exclude []string, skip string) ([]string, error) {
+	if skip != "" {
+		var err error
+		if skip, err = filepath.Abs(skip); err != nil {
+			return nil, fmt.Errorf("failed to resolve output directory: %w", err)
+		}
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if skip != "" {
+				if abs, err := filepath.Abs(path); err == nil && abs == skip {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if (len(include) == 0 || matchAnyGlob(include, rel)) && !matchAnyGlob(exclude, rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk input directory: %w", err)
+	}
+	return files, nil
+}
+
+// encodeDir tokenizes the files, given relative to root, on opts.workers
+// goroutines and returns their token counts and token files in the order of
+// files. It stops at the first error or when ctx is done.
+func encodeDir(ctx context.Context, tokenizer *llama3.Tokenizer, root string, files []string, opts encodeDirOptions) ([]encodeDirShard, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	results := make([]encodeDirShard, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(opts.workers, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session := tokenizer.NewEncodeSession()
+			for i := range jobs {
+				shard, err := encodeDirFile(session, root, files[i], opts)
+				if err != nil {
+					fail(fmt.Errorf("%s: %w", files[i], err))
+					continue
+				}
+				results[i] = shard
+			}
+		}()
+	}
+
+feed:
+	for i := range files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// encodeDirFile tokenizes one file and, if opts.outDir is set, writes its
+// tokens, unless opts.previous has a token file of the same input. It
+// returns the number of tokens and the token file.
+func encodeDirFile(session *llama3.EncodeSession, root, rel string, opts encodeDirOptions) (encodeDirShard, error) {
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(rel))) // #nosec G304 - path is under the caller's directory
+	if err != nil {
+		return encodeDirShard{}, fmt.Errorf("failed to read input: %w", err)
+	}
+	shard := encodeDirShard{encodeDirResult: encodeDirResult{Path: rel}}
+	if opts.outDir != "" {
+		sum := sha256.Sum256(data)
+		shard.InputSHA256 = hex.EncodeToString(sum[:])
+		shard.Shard = rel + ".tokens"
+		if opts.format == tokenio.NDJSON {
+			shard.Shard = rel + ".jsonl"
+		}
+	}
+	path := filepath.Join(opts.outDir, filepath.FromSlash(shard.Shard))
+
+	if prev, ok := opts.previous[rel]; ok && prev.Shard == shard.Shard && prev.InputSHA256 == shard.InputSHA256 {
+		if hash, err := fileSHA256(path); err == nil && hash == prev.SHA256 {
+			return prev, nil
+		}
+	}
+
+	text, err := cli.NormalizeText(string(data), opts.crlf)
+	if err != nil {
+		return encodeDirShard{}, err
+	}
+	tokens := session.Encode(text, opts.encode)
+	shard.Tokens = len(tokens)
+	if opts.outDir == "" {
+		return shard, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return encodeDirShard{}, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	f, err := os.Create(path) // #nosec G304 - path is under the caller's directory
+	if err != nil {
+		return encodeDirShard{}, fmt.Errorf("failed to create output: %w", err)
+	}
+	h := sha256.New()
+	w, err := tokenio.NewWriter(io.MultiWriter(f, h), opts.format)
+	if err == nil {
+		if err = w.Write(tokens); err == nil {
+			err = w.Flush()
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return encodeDirShard{}, fmt.Errorf("failed to write output: %w", err)
+	}
+	shard.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return shard, nil
+}
+
+// printEncodeDirResults prints the token count of each file and the total
+// as a table or JSON.
+func printEncodeDirResults(w io.Writer, results []encodeDirResult, output string) error {
+	total := 0
+	for _, r := range results {
+		total += r.Tokens
+	}
+
+	if output == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Files []encodeDirResult `json:"files"`
+			Total int               `json:"total"`
+		}{results, total})
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(w, "%d\t%s\n", r.Tokens, r.Path)
+	}
+	fmt.Fprintf(w, "%d\ttotal\n", total)
+	return nil
+}
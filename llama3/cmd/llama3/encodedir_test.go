@@ -0,0 +1,241 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/llama3"
+	"github.com/agentstation/tokenizer/tokenio"
+)
+
+func TestEncodeDirFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.md", "b.txt", "docs/c.md", "docs/deep/d.md", "out/a.md.tokens"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             []string
+	}{
+		{"all", nil, nil, []string{"a.md", "b.txt", "docs/c.md", "docs/deep/d.md"}},
+		{"include", []string{"**/*.md"}, nil, []string{"a.md", "docs/c.md", "docs/deep/d.md"}},
+		{"exclude", nil, []string{"docs/**"}, []string{"a.md", "b.txt"}},
+		{"both", []string{"**/*.md"}, []string{"**/deep/*"}, []string{"a.md", "docs/c.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeDirFiles(root, tt.include, tt.exclude, filepath.Join(root, "out"))
+			if err != nil {
+				t.Fatalf("encodeDirFiles failed: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEncodeDir(t *testing.T) {
+	tokenizer, err := llama3.New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	root := t.TempDir()
+	texts := map[string]string{"a.txt": "Hello world", "sub/b.txt": "Hello, world!\r\n", "sub/c.txt": ""}
+	for name, text := range texts {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	files := []string{"a.txt", "sub/b.txt", "sub/c.txt"}
+
+	for _, format := range []tokenio.Format{tokenio.U32, tokenio.NDJSON} {
+		t.Run(string(format), func(t *testing.T) {
+			out := t.TempDir()
+			opts := encodeDirOptions{
+				encode:  &llama3.EncodeOptions{BOS: true, EOS: false},
+				crlf:    cli.CRLFLF,
+				workers: 2,
+				outDir:  out,
+				format:  format,
+			}
+			results, err := encodeDir(context.Background(), tokenizer, root, files, opts)
+			if err != nil {
+				t.Fatalf("encodeDir failed: %v", err)
+			}
+
+			ext := ".tokens"
+			if format == tokenio.NDJSON {
+				ext = ".jsonl"
+			}
+			for i, name := range files {
+				want := tokenizer.Encode(strings.ReplaceAll(texts[name], "\r\n", "\n"), opts.encode)
+				if results[i].Path != name || results[i].Tokens != len(want) {
+					t.Errorf("Expected %s with %d tokens, got %+v", name, len(want), results[i])
+				}
+
+				f, err := os.Open(filepath.Join(out, filepath.FromSlash(name)+ext))
+				if err != nil {
+					t.Fatalf("Open failed: %v", err)
+				}
+				r, err := tokenio.NewReader(f, format)
+				if err != nil {
+					t.Fatalf("NewReader failed: %v", err)
+				}
+				got, err := r.Read()
+				_ = f.Close()
+				if err != nil {
+					t.Fatalf("Read failed: %v", err)
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("%s: expected tokens %v, got %v", name, want, got)
+				}
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	results := []encodeDirResult{{Path: "a.txt", Tokens: 3}, {Path: "sub/b.txt", Tokens: 5}}
+	if err := printEncodeDirResults(&buf, results, "table"); err != nil {
+		t.Fatalf("printEncodeDirResults failed: %v", err)
+	}
+	if want := "3\ta.txt\n5\tsub/b.txt\n8\ttotal\n"; buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+
+	if _, err := encodeDir(context.Background(), tokenizer, root, []string{"a.txt", "missing.txt"}, encodeDirOptions{workers: 4}); err == nil || !strings.Contains(err.Error(), "missing.txt") {
+		t.Errorf("Expected an error naming missing.txt, got %v", err)
+	}
+}
+
+func TestEncodeDirManifest(t *testing.T) {
+	tokenizer, err := llama3.New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	root, out := t.TempDir(), t.TempDir()
+	write := func(name, text string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	write("a.txt", "Hello world")
+	write("sub/b.txt", "Hello, world!")
+
+	run := func(args ...string) *encodeDirManifest {
+		t.Helper()
+		cmd := newEncodeDirCmd()
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(append([]string{root, "--out", out}, args...))
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("encode-dir failed: %v", err)
+		}
+		m, err := readEncodeDirManifest(out)
+		if err != nil || m == nil {
+			t.Fatalf("Expected a manifest, got %v, %v", m, err)
+		}
+		return m
+	}
+
+	m := run()
+	if len(m.Files) != 2 || m.Files[0].Path != "a.txt" || m.Files[1].Shard != "sub/b.txt.tokens" {
+		t.Fatalf("Unexpected manifest files %+v", m.Files)
+	}
+	if want, _ := tokenizerSHA256(tokenizer); m.Tokenizer != want || m.Format != string(tokenio.U32) || !m.EOS {
+		t.Errorf("Unexpected manifest settings %+v", m)
+	}
+	total := 0
+	for _, f := range m.Files {
+		if hash, err := fileSHA256(filepath.Join(out, filepath.FromSlash(f.Shard))); err != nil || hash != f.SHA256 {
+			t.Errorf("%s: expected hash %s, got %s, %v", f.Shard, f.SHA256, hash, err)
+		}
+		if hash, _ := fileSHA256(filepath.Join(root, filepath.FromSlash(f.Path))); hash != f.InputSHA256 {
+			t.Errorf("%s: expected input hash %s, got %s", f.Path, f.InputSHA256, hash)
+		}
+		total += f.Tokens
+	}
+	if m.Total != total {
+		t.Errorf("Expected total %d, got %d", total, m.Total)
+	}
+
+	// Unchanged inputs keep their entries: mark a.txt to see it is reused
+	m.Files[0].Tokens = 999
+	if err := writeEncodeDirManifest(out, m); err != nil {
+		t.Fatalf("writeEncodeDirManifest failed: %v", err)
+	}
+	write("sub/b.txt", "Hello, world! Again.")
+	m = run()
+	if m.Files[0].Tokens != 999 {
+		t.Errorf("Expected a.txt to be reused, got %+v", m.Files[0])
+	}
+	if want := len(tokenizer.Encode("Hello, world! Again.", nil)); m.Files[1].Tokens != want {
+		t.Errorf("Expected sub/b.txt to be encoded again with %d tokens, got %+v", want, m.Files[1])
+	}
+
+	// A token file that no longer matches is written again
+	if err := os.WriteFile(filepath.Join(out, "a.txt.tokens"), nil, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if m = run(); m.Files[0].Tokens == 999 {
+		t.Errorf("Expected a.txt to be encoded again, got %+v", m.Files[0])
+	}
+
+	// Other settings encode everything again
+	m.Files[0].Tokens = 999
+	if err := writeEncodeDirManifest(out, m); err != nil {
+		t.Fatalf("writeEncodeDirManifest failed: %v", err)
+	}
+	if m = run("--eos=false"); m.Files[0].Tokens == 999 || m.EOS {
+		t.Errorf("Expected a.txt to be encoded again without EOS, got %+v", m.Files[0])
+	}
+}
+
+func TestEncodeDirCommandErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"output", []string{t.TempDir(), "-o", "xml"}, "unknown output format"},
+		{"workers", []string{t.TempDir(), "--workers", "0"}, "--workers"},
+		{"glob", []string{t.TempDir(), "--include", "[a"}, "invalid glob"},
+		{"format", []string{t.TempDir(), "--format", "csv"}, "csv"},
+		{"empty", []string{t.TempDir()}, "no files"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newEncodeDirCmd()
+			cmd.SetOut(&bytes.Buffer{})
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetArgs(tt.args)
+			if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// hookMarker identifies pre-commit hooks written by hook install.
+const hookMarker = "# Installed by tokenizer hook install."
+
+// HookCommand returns the hook command, which enforces prompt token budgets
+// with a git pre-commit hook.
+func HookCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Enforce prompt token budgets with a git pre-commit hook",
+		Long: `Install a git pre-commit hook that counts the tokens of staged prompt files
+and blocks the commit if any file is over budget.
+
+Files are selected with --glob patterns relative to the repository root, in
+which * matches within a path segment and ** matches any number of segments.
+The staged content is counted, as by "tokenizer llama3 count --bos=false
+--eos=false", so unstaged edits do not affect the result.`,
+		Example: `  # Keep every prompt under 4096 tokens
+  tokenizer hook install --max 4096 --glob "prompts/**"`,
+	}
+
+	cmd.AddCommand(newHookInstallCmd(), newHookRunCmd())
+	return cmd
+}
+
+func newHookInstallCmd() *cobra.Command {
+	var budget int
+	var globs []string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the pre-commit hook in the current repository",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if budget < 1 {
+				return errors.New("--max must be positive")
+			}
+			for _, g := range globs {
+				if err := checkGlob(g); err != nil {
+					return err
+				}
+			}
+
+			hooks, err := git("rev-parse", "--git-path", "hooks")
+			if err != nil {
+				return err
+			}
+			hookPath := filepath.Join(strings.TrimSpace(string(hooks)), "pre-commit")
+			if existing, err := os.ReadFile(hookPath); err == nil && !bytes.Contains(existing, []byte(hookMarker)) && !force {
+				return fmt.Errorf("%s exists and was not installed by tokenizer; use --force to replace it", hookPath)
+			}
+
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to locate the tokenizer binary: %w", err)
+			}
+			if err := os.MkdirAll(filepath.Dir(hookPath), 0o755); err != nil { // #nosec G301 - git hooks directories are not secret
+				return fmt.Errorf("failed to create hooks directory: %w", err)
+			}
+			if err := os.WriteFile(hookPath, []byte(hookScript(exe, budget, globs)), 0o755); err != nil { // #nosec G306 - hooks must be executable
+				return fmt.Errorf("failed to write hook: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Installed %s: %s must stay within %d tokens\n", hookPath, strings.Join(globs, ", "), budget)
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&budget, "max", 0, "Token budget per file (required)")
+	cmd.Flags().StringArrayVar(&globs, "glob", nil, "Pattern of prompt files, repeatable (required)")
+	cmd.Flags().BoolVar(&force, "force", false, "Replace an existing pre-commit hook")
+	_ = cmd.MarkFlagRequired("max")
+	_ = cmd.MarkFlagRequired("glob")
+
+	return cmd
+}
+
+func newHookRunCmd() *cobra.Command {
+	var budget int
+	var globs []string
+
+	cmd := &cobra.Command{
+		Use:    "run",
+		Short:  "Check staged files against a token budget (run by the hook)",
+		Args:   cobra.NoArgs,
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			count, err := newCounter(&llama3.EncodeOptions{}, cli.CRLFKeep)
+			if err != nil {
+				return err
+			}
+			return checkStaged(cmd.ErrOrStderr(), budget, globs, count)
+		},
+	}
+
+	cmd.Flags().IntVar(&budget, "max", 0, "Token budget per file")
+	cmd.Flags().StringArrayVar(&globs, "glob", nil, "Pattern of prompt files, repeatable")
+
+	return cmd
+}
+
+// hookScript returns a pre-commit hook that runs hook run with the given
+// budget.
+func hookScript(exe string, budget int, globs []string) string {
+	args := []string{shellQuote(exe), "hook", "run", "--max", strconv.Itoa(budget)}
+	for _, g := range globs {
+		args = append(args, "--glob", shellQuote(g))
+	}
+	return "#!/bin/sh\n" + hookMarker + "\n" +
+		"# Blocks commits of prompt files over the token budget.\n" +
+		"exec " + strings.Join(args, " ") + "\n"
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// checkStaged counts the staged content of the files matching globs and
+// reports those over budget to w.
+func checkStaged(w io.Writer, budget int, globs []string, count func([]byte) (int, error)) error {
+	out, err := git("diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR")
+	if err != nil {
+		return err
+	}
+
+	var over []string
+	for _, name := range strings.Split(strings.TrimRight(string(out), "\x00"), "\x00") {
+		if name == "" || !matchAnyGlob(globs, name) {
+			continue
+		}
+		data, err := git("show", ":"+name)
+		if err != nil {
+			return err
+		}
+		n, err := count(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if n > budget {
+			fmt.Fprintf(w, "%s: %d tokens, over the budget of %d\n", name, n, budget)
+			over = append(over, name)
+		}
+	}
+	if len(over) > 0 {
+		return fmt.Errorf("commit blocked: %d file(s) over the token budget", len(over))
+	}
+	return nil
+}
+
+// git runs git with args and returns its standard output.
+func git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// checkGlob reports whether pattern is a valid glob.
+func checkGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchAnyGlob reports whether name matches one of the patterns.
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matchGlob(strings.Split(p, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches path segments against pattern segments, where a "**"
+// segment matches any number of path segments.
+func matchGlob(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if matchGlob(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
@@ -0,0 +1,94 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"prompts/**", "prompts/a.md", true},
+		{"prompts/**", "prompts/sub/dir/a.md", true},
+		{"prompts/**", "other/a.md", false},
+		{"prompts/*.md", "prompts/a.md", true},
+		{"prompts/*.md", "prompts/sub/a.md", false},
+		{"**/*.prompt", "a.prompt", true},
+		{"**/*.prompt", "x/y/a.prompt", true},
+		{"**/*.prompt", "x/y/a.md", false},
+		{"a/**/b.md", "a/b.md", true},
+		{"a/**/b.md", "a/x/y/b.md", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchAnyGlob([]string{tt.pattern}, tt.name); got != tt.want {
+			t.Errorf("matchAnyGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+	if err := checkGlob("prompts/[.md"); err == nil {
+		t.Error("Expected error for a malformed glob")
+	}
+}
+
+func TestHookScript(t *testing.T) {
+	script := hookScript("/opt/my tools/tokenizer", 4096, []string{"prompts/**", "it's/*.md"})
+	want := `exec '/opt/my tools/tokenizer' hook run --max 4096 --glob 'prompts/**' --glob 'it'\''s/*.md'`
+	if !strings.HasPrefix(script, "#!/bin/sh\n"+hookMarker+"\n") || !strings.Contains(script, want) {
+		t.Errorf("Expected script to contain %q, got:\n%s", want, script)
+	}
+}
+
+func TestCheckStaged(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if _, err := git("init", "-q"); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+
+	files := map[string]string{
+		"prompts/small.md":     "a few words",
+		"prompts/sub/large.md": strings.Repeat("word ", 20),
+		"notes/large.md":       strings.Repeat("word ", 20),
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	if _, err := git("add", "."); err != nil {
+		t.Fatalf("git add failed: %v", err)
+	}
+	// Unstaged edits are not counted
+	if err := os.WriteFile("prompts/small.md", []byte(strings.Repeat("word ", 20)), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	count := func(data []byte) (int, error) {
+		return len(strings.Fields(string(data))), nil
+	}
+	var out bytes.Buffer
+	err := checkStaged(&out, 10, []string{"prompts/**"}, count)
+	if err == nil {
+		t.Fatal("Expected the large prompt to block the commit")
+	}
+	if want := "prompts/sub/large.md: 20 tokens, over the budget of 10\n"; out.String() != want {
+		t.Errorf("Expected %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	if err := checkStaged(&out, 100, []string{"prompts/**"}, count); err != nil {
+		t.Errorf("Expected no error within budget, got %v: %s", err, out.String())
+	}
+}
@@ -2,6 +2,7 @@ package llama3cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -10,6 +11,8 @@ import (
 
 // newInfoCmd creates the info subcommand.
 func newInfoCmd() *cobra.Command {
+	var verbose bool
+
 	cmd := &cobra.Command{
 		Use:   "info",
 		Short: "Display tokenizer information",
@@ -17,37 +20,48 @@ func newInfoCmd() *cobra.Command {
 special tokens, and other relevant details.
 
 This command is useful for understanding the tokenizer's capabilities and
-configuration.`,
+configuration. With --verbose it also shows the build and the implementation
+paths active in this process; include that output in performance bug
+reports.`,
 		Example: `  # Show tokenizer information
-  tokenizer llama3 info`,
-		RunE: runInfo,
+  tokenizer llama3 info
+
+  # Include the active implementation variant
+  tokenizer llama3 info --verbose`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runInfo(cmd, verbose)
+		},
 	}
 
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Show the build and active implementation paths")
+
 	return cmd
 }
 
-func runInfo(_ *cobra.Command, _ []string) error {
+func runInfo(cmd *cobra.Command, verbose bool) error {
+	out := cmd.OutOrStdout()
 	// Initialize tokenizer
 	tokenizer, err := llama3.New()
 	if err != nil {
 		return fmt.Errorf("failed to initialize tokenizer: %w", err)
 	}
 
-	fmt.Println("Llama 3 Tokenizer Information")
-	fmt.Println("=============================")
-	fmt.Println()
+	fmt.Fprintln(out, "Llama 3 Tokenizer Information")
+	fmt.Fprintln(out, "=============================")
+	fmt.Fprintln(out)
 
 	// Basic information
-	fmt.Println("Model Details:")
-	fmt.Printf("  Model Type:        Llama 3 (Meta)\n")
-	fmt.Printf("  Tokenizer Type:    Byte-level BPE\n")
-	fmt.Printf("  Vocabulary Size:   %d tokens\n", tokenizer.VocabSize())
-	fmt.Printf("  Regular Tokens:    %d\n", 128000)
-	fmt.Printf("  Special Tokens:    %d\n", 256)
-	fmt.Println()
+	fmt.Fprintln(out, "Model Details:")
+	fmt.Fprintf(out, "  Model Type:        Llama 3 (Meta)\n")
+	fmt.Fprintf(out, "  Tokenizer Type:    Byte-level BPE\n")
+	fmt.Fprintf(out, "  Vocabulary Size:   %d tokens\n", tokenizer.VocabSize())
+	info := tokenizer.Info()
+	fmt.Fprintf(out, "  Regular Tokens:    %d\n", info.RegularTokens)
+	fmt.Fprintf(out, "  Special Tokens:    %d\n", info.SpecialTokens)
+	fmt.Fprintln(out)
 
 	// Special token examples
-	fmt.Println("Special Token Examples:")
+	fmt.Fprintln(out, "Special Token Examples:")
 	specialTokens := []struct {
 		name  string
 		token string
@@ -67,27 +81,45 @@ func runInfo(_ *cobra.Command, _ []string) error {
 
 	for _, st := range specialTokens {
 		if id, err := tokenizer.GetSpecialTokenID(st.token); err == nil {
-			fmt.Printf("  %-18s %-30s -> %d\n", st.name+":", st.token, id)
+			fmt.Fprintf(out, "  %-18s %-30s -> %d\n", st.name+":", st.token, id)
 		}
 	}
 
-	fmt.Println()
-	fmt.Printf("  ... and %d more reserved special tokens\n", 245)
-	fmt.Println()
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "  ... and %d more reserved special tokens\n", 245)
+	fmt.Fprintln(out)
 
 	// Encoding characteristics
-	fmt.Println("Encoding Characteristics:")
-	fmt.Printf("  Byte-level:        Yes (handles any byte sequence)\n")
-	fmt.Printf("  Whitespace:        Preserved (including multiple spaces)\n")
-	fmt.Printf("  Case Sensitive:    Yes\n")
-	fmt.Printf("  Unicode Support:   Full (via byte encoding)\n")
-	fmt.Println()
+	fmt.Fprintln(out, "Encoding Characteristics:")
+	fmt.Fprintf(out, "  Byte-level:        Yes (handles any byte sequence)\n")
+	fmt.Fprintf(out, "  Whitespace:        Preserved (including multiple spaces)\n")
+	fmt.Fprintf(out, "  Case Sensitive:    Yes\n")
+	fmt.Fprintf(out, "  Unicode Support:   Full (via byte encoding)\n")
+	fmt.Fprintln(out)
 
 	// Performance features
-	fmt.Println("Performance Features:")
-	fmt.Printf("  BPE Cache:         Enabled (LRU cache)\n")
-	fmt.Printf("  Streaming:         Supported (via Scanner interface)\n")
-	fmt.Printf("  Thread Safe:       Yes (with proper usage)\n")
+	fmt.Fprintln(out, "Performance Features:")
+	fmt.Fprintf(out, "  BPE Cache:         Enabled (LRU cache)\n")
+	fmt.Fprintf(out, "  Streaming:         Supported (via Scanner interface)\n")
+	fmt.Fprintf(out, "  Thread Safe:       Yes (with proper usage)\n")
+
+	if verbose {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Implementation:")
+		fmt.Fprintf(out, "  Build:             %s\n", info.Build)
+		fmt.Fprintf(out, "  Go:                %s %s/%s, GOMAXPROCS=%d\n", info.GoVersion, info.GOOS, info.GOARCH, info.MaxProcs)
+		cache := info.Cache
+		if info.CacheSize > 0 {
+			cache += fmt.Sprintf(" (%d entries)", info.CacheSize)
+		}
+		fmt.Fprintf(out, "  Cache:             %s\n", cache)
+		if info.ParallelMinBytes > 0 {
+			fmt.Fprintf(out, "  Parallel Pretoken: inputs of %d bytes or more\n", info.ParallelMinBytes)
+		} else {
+			fmt.Fprintf(out, "  Parallel Pretoken: off\n")
+		}
+		fmt.Fprintf(out, "  Active Paths:      %s\n", strings.Join(info.Paths, ", "))
+	}
 
 	return nil
 }
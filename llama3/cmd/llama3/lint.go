@@ -0,0 +1,226 @@
+package llama3cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/analysis"
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// LintTemplatesCommand returns the lint-templates command, which reports
+// the worst-case token budgets of text/template prompt files.
+func LintTemplatesCommand() *cobra.Command {
+	var budget, varTokens, iterations int
+	var vars, exts []string
+	var output, crlf string
+
+	cmd := &cobra.Command{
+		Use:   "lint-templates path...",
+		Short: "Report worst-case token budgets of prompt templates",
+		Long: `Parse Go text/template prompt files and report the worst-case number of
+tokens each template can produce.
+
+Static text is counted with the Llama 3 tokenizer, without BOS/EOS. Each
+action that prints a value, such as {{.Question}}, is estimated at
+--var-tokens, or at the size given for its pipeline with --var. The worst
+case takes the longer branch of each if and with, and repeats the body of
+each range --range times. Templates defined in the same file are expanded;
+others are estimated like values.
+
+A path is a template file, a directory, whose files with a template
+extension are linted, or a directory followed by /... to include its
+subdirectories. With --max, templates over the budget are reported and the
+command fails.`,
+		Example: `  # Check every prompt against an 8k context
+  tokenizer lint-templates --max 8192 ./prompts/...
+
+  # Allow for long documents in one field
+  tokenizer lint-templates --var .Document=4000 --var-tokens 50 prompts/qa.tmpl`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if budget < 0 || varTokens < 1 || iterations < 1 {
+				return errors.New("--max must not be negative, and --var-tokens and --range must be positive")
+			}
+			if output != "table" && output != "json" {
+				return fmt.Errorf("unknown output format %q: use table or json", output)
+			}
+			opts := &analysis.TemplateOptions{VarTokens: varTokens, RangeIterations: iterations}
+			var err error
+			if opts.Vars, err = parseVarEstimates(vars); err != nil {
+				return err
+			}
+
+			files, err := templateFiles(args, exts)
+			if err != nil {
+				return err
+			}
+			tokenizer, err := llama3.New()
+			if err != nil {
+				return fmt.Errorf("failed to initialize tokenizer: %w", err)
+			}
+
+			var results []templateResult
+			for _, path := range files {
+				data, err := os.ReadFile(path) // #nosec G304 - path is provided by the caller
+				if err != nil {
+					return fmt.Errorf("failed to read template: %w", err)
+				}
+				text, err := cli.NormalizeText(string(data), crlf)
+				if err != nil {
+					return err
+				}
+				reports, err := analysis.TemplateBudgets(tokenizer, filepath.Base(path), text, opts)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				for _, r := range reports {
+					results = append(results, templateResult{
+						File:           path,
+						TemplateReport: r,
+						Over:           budget > 0 && r.WorstCase > budget,
+					})
+				}
+			}
+
+			if err := printTemplateResults(cmd.OutOrStdout(), results, output); err != nil {
+				return err
+			}
+			over := 0
+			for _, r := range results {
+				if r.Over {
+					over++
+				}
+			}
+			if over > 0 {
+				return fmt.Errorf("%d template(s) over the budget of %d tokens", over, budget)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&budget, "max", 0, "Token budget per template (default: no budget)")
+	cmd.Flags().IntVar(&varTokens, "var-tokens", analysis.DefaultVarTokens, "Estimated tokens per printed value")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "Estimate for one pipeline as pipeline=tokens, e.g. .Document=4000, repeatable")
+	cmd.Flags().IntVar(&iterations, "range", analysis.DefaultRangeIterations, "Assumed iterations of each range")
+	cmd.Flags().StringSliceVar(&exts, "ext", []string{".tmpl", ".tpl", ".gotmpl"}, "Extensions of template files in directories")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&crlf, "crlf", cli.CRLFAuto, "Line endings: auto, keep, lf")
+
+	return cmd
+}
+
+// templateResult is the budget of one template of a file.
+type templateResult struct {
+	File string `json:"file"`
+	analysis.TemplateReport
+	Over bool `json:"over_budget,omitempty"`
+}
+
+// parseVarEstimates parses pipeline=tokens estimates. The pipeline is split
+// at the last "=", as pipelines may contain "=" in string arguments.
+func parseVarEstimates(vars []string) (map[string]int, error) {
+	estimates := make(map[string]int, len(vars))
+	for _, v := range vars {
+		i := strings.LastIndex(v, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid --var %q: use pipeline=tokens", v)
+		}
+		n, err := strconv.Atoi(v[i+1:])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid --var %q: tokens must be a non-negative integer", v)
+		}
+		estimates[strings.TrimSpace(v[:i])] = n
+	}
+	return estimates, nil
+}
+
+// templateFiles expands the paths of lint-templates into template files in
+// sorted order.
+func templateFiles(paths, exts []string) ([]string, error) {
+	isTemplate := func(name string) bool {
+		return slices.Contains(exts, filepath.Ext(name))
+	}
+
+	var files []string
+	for _, p := range paths {
+		if slash := filepath.ToSlash(p); slash == "..." || strings.HasSuffix(slash, "/...") {
+			root := strings.TrimSuffix(strings.TrimSuffix(slash, "..."), "/")
+			if root == "" {
+				root = "."
+			}
+			err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() && isTemplate(path) {
+					files = append(files, path)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list templates: %w", err)
+			}
+			continue
+		}
+
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list templates: %w", err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() && isTemplate(e.Name()) {
+				files = append(files, filepath.Join(p, e.Name()))
+			}
+		}
+	}
+
+	slices.Sort(files)
+	files = slices.Compact(files)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no template files with extension %s found", strings.Join(exts, ", "))
+	}
+	return files, nil
+}
+
+func printTemplateResults(w io.Writer, results []templateResult, output string) error {
+	if output == "json" {
+		data, err := json.Marshal(results)
+		if err != nil {
+			return fmt.Errorf("failed to marshal results: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tTEMPLATE\tSTATIC\tVARS\tVAR TOKENS\tWORST CASE\t")
+	for _, r := range results {
+		worst := strconv.Itoa(r.WorstCase)
+		if r.Over {
+			worst += " (over budget)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\t\n", r.File, r.Name, r.StaticTokens, r.Vars, r.VarTokens, worst)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,82 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintTemplatesCommand(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"system.tmpl":    "You are a helpful assistant.",
+		"qa.tmpl":        "Context: {{.Document}}\nQuestion: {{.Question}}",
+		"notes.txt":      "not a template",
+		"nested/a.tmpl":  "{{range .Items}}- {{.}}\n{{end}}",
+		"broken/x.tmpl":  "{{if .A}}",
+		"broken/ok.tmpl": "fine",
+	}
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+
+	run := func(args ...string) (string, error) {
+		cmd := LintTemplatesCommand()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("-o", "json", "--var", ".Document=1000", "--var-tokens", "10", dir)
+	if err != nil {
+		t.Fatalf("lint-templates failed: %v", err)
+	}
+	var results []templateResult
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+	if len(results) != 2 || results[0].Name != "qa.tmpl" || results[1].Name != "system.tmpl" {
+		t.Fatalf("Expected qa.tmpl and system.tmpl, got %+v", results)
+	}
+	if qa := results[0]; qa.Vars != 2 || qa.VarTokens != 1010 || qa.WorstCase != qa.StaticTokens+1010 {
+		t.Errorf("Expected 2 values estimated at 1010 tokens, got %+v", qa)
+	}
+
+	out, err = run("--max", "100", "--var-tokens", "10", filepath.Join(dir, "qa.tmpl"), filepath.Join(dir, "nested")+"/...")
+	if err != nil {
+		t.Fatalf("lint-templates failed: %v", err)
+	}
+	if !strings.Contains(out, "a.tmpl") || strings.Contains(out, "over budget") {
+		t.Errorf("Expected a.tmpl within budget, got %q", out)
+	}
+
+	out, err = run("--max", "20", dir)
+	if err == nil || !strings.Contains(err.Error(), "1 template(s) over") || !strings.Contains(out, "(over budget)") {
+		t.Errorf("Expected qa.tmpl over budget, got %q, %v", out, err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{dir + "/..."}, // broken/x.tmpl
+		{filepath.Join(dir, "missing")},
+		{"--var", "Document", dir},
+		{"--ext", ".md", dir},
+		{"-o", "xml", dir},
+	} {
+		if _, err := run(args...); err == nil {
+			t.Errorf("Expected an error for %v", args)
+		}
+	}
+}
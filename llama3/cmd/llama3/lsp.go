@@ -0,0 +1,443 @@
+package llama3cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// LSPCommand returns the lsp command, which serves live token counts to
+// editors over the Language Server Protocol.
+func LSPCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Serve token counts to editors over the Language Server Protocol",
+		Long: `Run a language server on stdin and stdout that keeps token counts of open
+documents up to date as they are edited.
+
+The server speaks JSON-RPC 2.0 with LSP framing. Documents are synced
+incrementally, and each change re-tokenizes only the edited region, so a
+plugin can show a live count without running the CLI on every keystroke.
+
+Besides the standard lifecycle and text document sync messages, the server
+uses two methods of its own:
+
+  tokenizer/tokenCount   notification sent after every open and change,
+                         with params {"uri", "version", "count"}
+  tokenizer/decorations  request with params {"textDocument": {"uri"}},
+                         returning the token boundaries in the format
+                         described in docs/decorations.md
+
+Positions are UTF-16 code units unless the client offers "utf-8" in
+general.positionEncodings. Counts use the Llama 3 tokenizer without BOS and
+EOS tokens.`,
+		Example: `  # Run as a language server (started by the editor plugin)
+  tokenizer lsp`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			tokenizer, err := llama3.New()
+			if err != nil {
+				return fmt.Errorf("failed to initialize tokenizer: %w", err)
+			}
+			return newLSPServer(tokenizer, cmd.InOrStdin(), cmd.OutOrStdout()).serve()
+		},
+	}
+
+	// Language clients commonly pass --stdio; it is the only transport.
+	cmd.Flags().Bool("stdio", true, "Communicate over stdin and stdout")
+	_ = cmd.Flags().MarkHidden("stdio")
+
+	return cmd
+}
+
+// JSON-RPC and LSP error codes.
+const (
+	lspParseError           = -32700
+	lspInvalidRequest       = -32600
+	lspMethodNotFound       = -32601
+	lspInvalidParams        = -32602
+	lspServerNotInitialized = -32002
+)
+
+// lspMaxMessageBytes limits the body of a message, so that a bad
+// Content-Length cannot make the server allocate without bound.
+const lspMaxMessageBytes = 64 << 20
+
+// lspTextDocumentSyncIncremental is TextDocumentSyncKind.Incremental.
+const lspTextDocumentSyncIncremental = 2
+
+// noBOSEOS encodes document text as it is, without special tokens around it.
+var noBOSEOS = &llama3.EncodeOptions{BOS: false, EOS: false}
+
+type lspRequest struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type lspResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result"`
+}
+
+type lspErrorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   lspError        `json:"error"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextDocument struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+	Text    string `json:"text"`
+}
+
+type lspContentChange struct {
+	Range *lspRange `json:"range"`
+	Text  string    `json:"text"`
+}
+
+// lspTokenCount is the params of the tokenizer/tokenCount notification.
+type lspTokenCount struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+	Count   int    `json:"count"`
+}
+
+// lspDecorations is the result of the tokenizer/decorations request: the
+// decorations format with the document version they belong to.
+type lspDecorations struct {
+	*llama3.Decorations
+	DocumentVersion int `json:"documentVersion"`
+}
+
+// lspDocument is an open document and its current tokens.
+type lspDocument struct {
+	text    string
+	version int
+	tokens  []int
+}
+
+// lspServer handles one client connection. Messages are handled in order on
+// a single goroutine.
+type lspServer struct {
+	tokenizer *llama3.Tokenizer
+	in        *bufio.Reader
+	out       io.Writer
+
+	docs         map[string]*lspDocument
+	encoding     string // llama3.PositionUTF16 or llama3.PositionUTF8
+	initialized  bool
+	shuttingDown bool
+}
+
+func newLSPServer(tokenizer *llama3.Tokenizer, in io.Reader, out io.Writer) *lspServer {
+	return &lspServer{
+		tokenizer: tokenizer,
+		in:        bufio.NewReader(in),
+		out:       out,
+		docs:      make(map[string]*lspDocument),
+		encoding:  llama3.PositionUTF16,
+	}
+}
+
+// serve handles messages until the exit notification or the end of input.
+func (s *lspServer) serve() error {
+	for {
+		body, err := s.readMessage()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req lspRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.replyError(json.RawMessage("null"), lspParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shuttingDown {
+				return errors.New("lsp: exit without shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(&req); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches one message. Only write errors are returned; protocol
+// errors are sent to the client.
+func (s *lspServer) handle(req *lspRequest) error {
+	isRequest := len(req.ID) > 0
+	switch {
+	case s.shuttingDown && isRequest:
+		return s.replyError(req.ID, lspInvalidRequest, "server is shutting down")
+	case !s.initialized && req.Method != "initialize":
+		if isRequest {
+			return s.replyError(req.ID, lspServerNotInitialized, "server not initialized")
+		}
+		return nil
+	}
+
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req)
+	case "initialized":
+		return nil
+	case "shutdown":
+		s.shuttingDown = true
+		return s.reply(req.ID, nil)
+	case "textDocument/didOpen":
+		return s.didOpen(req.Params)
+	case "textDocument/didChange":
+		return s.didChange(req.Params)
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument lspTextDocument `json:"textDocument"`
+		}
+		if json.Unmarshal(req.Params, &params) == nil {
+			delete(s.docs, params.TextDocument.URI)
+		}
+		return nil
+	case "tokenizer/decorations":
+		return s.decorations(req)
+	}
+
+	if isRequest {
+		return s.replyError(req.ID, lspMethodNotFound, "method not found: "+req.Method)
+	}
+	return nil // unknown notifications, including $/ methods, are ignored
+}
+
+func (s *lspServer) initialize(req *lspRequest) error {
+	var params struct {
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return s.replyError(req.ID, lspInvalidParams, err.Error())
+	}
+
+	for _, e := range params.Capabilities.General.PositionEncodings {
+		if e == llama3.PositionUTF8 {
+			s.encoding = e
+		}
+	}
+	s.initialized = true
+
+	return s.reply(req.ID, map[string]any{
+		"capabilities": map[string]any{
+			"positionEncoding": s.encoding,
+			"textDocumentSync": map[string]any{
+				"openClose": true,
+				"change":    lspTextDocumentSyncIncremental,
+			},
+		},
+		"serverInfo": map[string]string{"name": "tokenizer"},
+	})
+}
+
+func (s *lspServer) didOpen(raw json.RawMessage) error {
+	var params struct {
+		TextDocument lspTextDocument `json:"textDocument"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil // notifications cannot fail
+	}
+
+	doc := &lspDocument{
+		text:    params.TextDocument.Text,
+		version: params.TextDocument.Version,
+		tokens:  s.tokenizer.Encode(params.TextDocument.Text, noBOSEOS),
+	}
+	s.docs[params.TextDocument.URI] = doc
+	return s.notifyCount(params.TextDocument.URI, doc)
+}
+
+func (s *lspServer) didChange(raw json.RawMessage) error {
+	var params struct {
+		TextDocument   lspTextDocument    `json:"textDocument"`
+		ContentChanges []lspContentChange `json:"contentChanges"`
+	}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+
+	for _, change := range params.ContentChanges {
+		if change.Range == nil {
+			doc.text = change.Text
+			doc.tokens = s.tokenizer.Encode(change.Text, noBOSEOS)
+			continue
+		}
+
+		edit := llama3.Span{
+			Start: s.offset(doc.text, change.Range.Start),
+			End:   s.offset(doc.text, change.Range.End),
+		}
+		if edit.End < edit.Start {
+			edit.Start, edit.End = edit.End, edit.Start
+		}
+		newText := doc.text[:edit.Start] + change.Text + doc.text[edit.End:]
+		doc.tokens = s.tokenizer.Retokenize(doc.text, newText, doc.tokens, edit)
+		doc.text = newText
+	}
+	doc.version = params.TextDocument.Version
+	return s.notifyCount(params.TextDocument.URI, doc)
+}
+
+func (s *lspServer) decorations(req *lspRequest) error {
+	var params struct {
+		TextDocument lspTextDocument `json:"textDocument"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return s.replyError(req.ID, lspInvalidParams, err.Error())
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return s.replyError(req.ID, lspInvalidParams, "document not open: "+params.TextDocument.URI)
+	}
+
+	decorations, err := s.tokenizer.Decorations(doc.text, doc.tokens, s.encoding)
+	if err != nil {
+		return s.replyError(req.ID, lspInvalidParams, err.Error())
+	}
+	return s.reply(req.ID, lspDecorations{Decorations: decorations, DocumentVersion: doc.version})
+}
+
+func (s *lspServer) notifyCount(uri string, doc *lspDocument) error {
+	return s.writeMessage(lspNotification{
+		JSONRPC: "2.0",
+		Method:  "tokenizer/tokenCount",
+		Params:  lspTokenCount{URI: uri, Version: doc.version, Count: len(doc.tokens)},
+	})
+}
+
+// offset converts a position to a byte offset in text. Positions past the end
+// of a line or of the text are clamped, as the protocol requires.
+func (s *lspServer) offset(text string, pos lspPosition) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+
+	for units := 0; units < pos.Character && offset < len(text) && text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		if s.encoding == llama3.PositionUTF8 {
+			units += size
+		} else {
+			units += utf16.RuneLen(r)
+		}
+		if units > pos.Character {
+			break // inside a surrogate pair
+		}
+		offset += size
+	}
+	return offset
+}
+
+func (s *lspServer) reply(id json.RawMessage, result any) error {
+	return s.writeMessage(lspResponse{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *lspServer) replyError(id json.RawMessage, code int, message string) error {
+	return s.writeMessage(lspErrorResponse{JSONRPC: "2.0", ID: id, Error: lspError{Code: code, Message: message}})
+}
+
+// readMessage reads one message body framed by a Content-Length header.
+func (s *lspServer) readMessage() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) && line == "" && length < 0 {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("lsp: reading header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil || length < 0 {
+				return nil, fmt.Errorf("lsp: invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("lsp: missing Content-Length header")
+	}
+	if length > lspMaxMessageBytes {
+		return nil, fmt.Errorf("lsp: message of %d bytes, maximum is %d", length, lspMaxMessageBytes)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, fmt.Errorf("lsp: reading body: %w", err)
+	}
+	return body, nil
+}
+
+// writeMessage writes v as a message framed by a Content-Length header.
+func (s *lspServer) writeMessage(v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = s.out.Write(body)
+	return err
+}
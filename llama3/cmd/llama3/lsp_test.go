@@ -0,0 +1,162 @@
+package llama3cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// lspSession frames messages for the server and parses its replies.
+type lspSession struct {
+	input bytes.Buffer
+}
+
+func (l *lspSession) send(t *testing.T, msg string) {
+	t.Helper()
+	if !json.Valid([]byte(msg)) {
+		t.Fatalf("Invalid test message %s", msg)
+	}
+	fmt.Fprintf(&l.input, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+}
+
+func (l *lspSession) run(t *testing.T) ([]map[string]any, error) {
+	t.Helper()
+	tokenizer, err := llama3.New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	var out bytes.Buffer
+	serveErr := newLSPServer(tokenizer, &l.input, &out).serve()
+
+	var replies []map[string]any
+	reader := &lspServer{in: bufio.NewReader(&out)}
+	for out.Len() > 0 || reader.in.Buffered() > 0 {
+		body, err := reader.readMessage()
+		if err != nil {
+			t.Fatalf("Failed to read reply: %v", err)
+		}
+		var reply map[string]any
+		if err := json.Unmarshal(body, &reply); err != nil {
+			t.Fatalf("Invalid reply %s: %v", body, err)
+		}
+		replies = append(replies, reply)
+	}
+	return replies, serveErr
+}
+
+func TestLSPServer(t *testing.T) {
+	const uri = "file:///notes.txt"
+	text := "Hello, world!\nSecond line 世界 here.\n"
+	edited := "Hello, world!\nSecond line 世界 is here.\n"
+
+	var session lspSession
+	session.send(t, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{}}}`)
+	session.send(t, `{"jsonrpc":"2.0","method":"initialized","params":{}}`)
+	open, _ := json.Marshal(text)
+	session.send(t, `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"`+uri+`","languageId":"plaintext","version":1,"text":`+string(open)+`}}}`)
+	// Insert "is " after "世界 ", at UTF-16 character 15 of line 1
+	session.send(t, `{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"`+uri+`","version":2},"contentChanges":[{"range":{"start":{"line":1,"character":15},"end":{"line":1,"character":15}},"text":"is "}]}}`)
+	session.send(t, `{"jsonrpc":"2.0","id":2,"method":"tokenizer/decorations","params":{"textDocument":{"uri":"`+uri+`"}}}`)
+	session.send(t, `{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{}}`)
+	session.send(t, `{"jsonrpc":"2.0","id":4,"method":"shutdown"}`)
+	session.send(t, `{"jsonrpc":"2.0","method":"exit"}`)
+
+	replies, err := session.run(t)
+	if err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+	if len(replies) != 6 {
+		t.Fatalf("Expected 6 replies, got %d: %v", len(replies), replies)
+	}
+
+	capabilities := replies[0]["result"].(map[string]any)["capabilities"].(map[string]any)
+	if capabilities["positionEncoding"] != "utf-16" {
+		t.Errorf("Expected utf-16 positions, got %v", capabilities["positionEncoding"])
+	}
+
+	tokenizer, _ := llama3.New()
+	for i, want := range []struct {
+		text    string
+		version float64
+	}{{text, 1}, {edited, 2}} {
+		params := replies[i+1]["params"].(map[string]any)
+		count := len(tokenizer.Encode(want.text, noBOSEOS))
+		if replies[i+1]["method"] != "tokenizer/tokenCount" || params["count"] != float64(count) || params["version"] != want.version {
+			t.Errorf("Expected count %d at version %v, got %v", count, want.version, replies[i+1])
+		}
+	}
+
+	decorations := replies[3]["result"].(map[string]any)
+	wantTokens := tokenizer.Encode(edited, noBOSEOS)
+	if decorations["documentVersion"] != float64(2) || decorations["count"] != float64(len(wantTokens)) {
+		t.Errorf("Expected %d tokens at version 2, got %v", len(wantTokens), decorations)
+	}
+	total := 0.0
+	for _, n := range decorations["lengths"].([]any) {
+		total += n.(float64)
+	}
+	if want := len(utf16.Encode([]rune(edited))); total != float64(want) {
+		t.Errorf("Expected lengths to add up to %d UTF-16 units, got %v", want, total)
+	}
+
+	if replies[4]["error"].(map[string]any)["code"] != float64(lspMethodNotFound) {
+		t.Errorf("Expected method not found for hover, got %v", replies[4])
+	}
+	if result, ok := replies[5]["result"]; !ok || result != nil {
+		t.Errorf("Expected a null shutdown result, got %v", replies[5])
+	}
+}
+
+func TestLSPServerErrors(t *testing.T) {
+	var session lspSession
+	session.send(t, `{"jsonrpc":"2.0","id":1,"method":"tokenizer/decorations","params":{}}`)
+	session.send(t, `{"jsonrpc":"2.0","method":"exit"}`)
+
+	replies, err := session.run(t)
+	if err == nil {
+		t.Error("Expected an error for exit without shutdown")
+	}
+	if len(replies) != 1 || replies[0]["error"].(map[string]any)["code"] != float64(lspServerNotInitialized) {
+		t.Errorf("Expected server not initialized, got %v", replies)
+	}
+}
+
+func TestLSPServerMessageLimit(t *testing.T) {
+	var session lspSession
+	fmt.Fprintf(&session.input, "Content-Length: %d\r\n\r\n{}", int64(1)<<40)
+
+	_, err := session.run(t)
+	if err == nil || !strings.Contains(err.Error(), "maximum") {
+		t.Errorf("Expected an error for a message over the limit, got %v", err)
+	}
+}
+
+func TestLSPOffset(t *testing.T) {
+	text := "ab\n😀c\n"
+	tests := []struct {
+		encoding string
+		pos      lspPosition
+		want     int
+	}{
+		{llama3.PositionUTF16, lspPosition{0, 1}, 1},
+		{llama3.PositionUTF16, lspPosition{0, 9}, 2}, // clamped to the line end
+		{llama3.PositionUTF16, lspPosition{1, 2}, 7}, // after the surrogate pair
+		{llama3.PositionUTF16, lspPosition{1, 1}, 3}, // inside the surrogate pair
+		{llama3.PositionUTF8, lspPosition{1, 4}, 7},
+		{llama3.PositionUTF16, lspPosition{5, 0}, len(text)},
+	}
+
+	for _, tt := range tests {
+		s := &lspServer{encoding: tt.encoding}
+		if got := s.offset(text, tt.pos); got != tt.want {
+			t.Errorf("offset(%v, %s): expected %d, got %d", tt.pos, tt.encoding, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package llama3cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// encodeDirManifestName is the file of the output directory encode-dir
+// writes its manifest to.
+const encodeDirManifestName = "manifest.json"
+
+// encodeDirManifestVersion is the version of the manifest format.
+const encodeDirManifestVersion = 1
+
+// encodeDirManifest lists the token files encode-dir wrote, with the hashes
+// to verify them and to skip unchanged inputs on the next run.
+type encodeDirManifest struct {
+	Version int `json:"version"`
+	// Tokenizer is the SHA-256 of the tokenizer's snapshot, which changes
+	// with its vocabulary, special tokens or merges.
+	Tokenizer string           `json:"tokenizer"`
+	Format    string           `json:"format"`
+	BOS       bool             `json:"bos"`
+	EOS       bool             `json:"eos"`
+	CRLF      string           `json:"crlf"`
+	Files     []encodeDirShard `json:"files"`
+	Total     int              `json:"total"`
+}
+
+// encodeDirShard is the token file of one input file, in the manifest.
+type encodeDirShard struct {
+	encodeDirResult
+	Shard       string `json:"shard"`        // token file, slash-separated and relative to the output directory
+	InputSHA256 string `json:"input_sha256"` // of the input file
+	SHA256      string `json:"sha256"`       // of the token file
+}
+
+// sameSettings reports whether the token files of m were written with the
+// tokenizer and options of n, so they can be reused.
+func (m *encodeDirManifest) sameSettings(n *encodeDirManifest) bool {
+	return m.Version == n.Version && m.Tokenizer == n.Tokenizer && m.Format == n.Format &&
+		m.BOS == n.BOS && m.EOS == n.EOS && m.CRLF == n.CRLF
+}
+
+// tokenizerSHA256 returns the SHA-256 of the tokenizer's snapshot.
+func tokenizerSHA256(tokenizer *llama3.Tokenizer) (string, error) {
+	h := sha256.New()
+	if err := tokenizer.WriteSnapshot(h); err != nil {
+		return "", fmt.Errorf("failed to hash tokenizer: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileSHA256 returns the SHA-256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path) // #nosec G304 - path is under the caller's directory
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// readEncodeDirManifest reads the manifest of outDir. It returns nil if
+// there is none.
+func readEncodeDirManifest(outDir string) (*encodeDirManifest, error) {
+	path := filepath.Join(outDir, encodeDirManifestName)
+	data, err := os.ReadFile(path) // #nosec G304 - path is under the caller's directory
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var m encodeDirManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// writeEncodeDirManifest writes m to outDir. The file is renamed into place
+// so a failed run leaves the previous manifest.
+func writeEncodeDirManifest(outDir string, m *encodeDirManifest) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.MkdirAll(outDir, 0o750); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(outDir, encodeDirManifestName+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(outDir, encodeDirManifestName))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
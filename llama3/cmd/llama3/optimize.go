@@ -0,0 +1,119 @@
+package llama3cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/analysis"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// OptimizeCommand returns the optimize command, which suggests rewrites
+// that make a prompt shorter in tokens without changing how it reads.
+func OptimizeCommand() *cobra.Command {
+	var rules []string
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "optimize [file]",
+		Short: "Suggest token-saving whitespace and punctuation rewrites (experimental)",
+		Long: `Look for rewrites of whitespace and punctuation that make a prompt shorter
+in tokens without changing how it reads, and report what each one saves.
+
+The rules are:
+
+  crlf            "\r\n" line endings become "\n"
+  trailing-space  spaces and tabs before a line break are removed
+  double-space    runs of spaces inside a line become one space
+  blank-lines     three or more line breaks become two
+  smart-quotes    typographic quotes become ASCII quotes
+  nbsp            no-break spaces become spaces
+  zero-width      zero-width spaces and BOMs are removed
+  ellipsis        "…" becomes "..."
+
+Each rewrite is tried in order with the ones before it applied and kept only
+if it saves tokens, so a straight quote that merges worse with its
+neighbours is not suggested. The search is greedy and may miss combinations
+that only save together. Text is counted with the Llama 3 tokenizer,
+without BOS/EOS; the input is read from the file, or from stdin if none is
+given, as is.
+
+This command is experimental: its rules and output may change.`,
+		Example: `  # Show the rewrites of a prompt and what they save
+  tokenizer optimize prompt.md
+
+  # Write the optimized prompt, using only some rules
+  tokenizer optimize --rule double-space,trailing-space -o text prompt.md > short.md`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "table" && output != "json" && output != "text" {
+				return fmt.Errorf("unknown output format %q: use table, json or text", output)
+			}
+
+			var data []byte
+			var err error
+			if len(args) == 0 || args[0] == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(args[0]) // #nosec G304 - path is provided by the caller
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read input: %w", err)
+			}
+
+			tokenizer, err := llama3.New()
+			if err != nil {
+				return fmt.Errorf("failed to initialize tokenizer: %w", err)
+			}
+			report, err := analysis.Optimize(tokenizer, string(data), rules...)
+			if err != nil {
+				return err
+			}
+			return printOptimizeReport(cmd.OutOrStdout(), report, output)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&rules, "rule", nil, "Rules to apply, comma-separated (default: all)")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json, text (the optimized input)")
+
+	return cmd
+}
+
+func printOptimizeReport(w io.Writer, report analysis.OptimizeReport, output string) error {
+	switch output {
+	case "json":
+		data, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "text":
+		_, err := io.WriteString(w, report.Optimized)
+		return err
+	}
+
+	if len(report.Rewrites) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "LINE\tRULE\tREWRITE\tSAVED\t")
+		for _, r := range report.Rewrites {
+			fmt.Fprintf(tw, "%d\t%s\t%s -> %s\t%d\t\n", r.Line, r.Rule, strconv.Quote(r.Old), strconv.Quote(r.New), r.Saved)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	summary := fmt.Sprintf("%d -> %d tokens, saved %d", report.Tokens, report.OptimizedTokens, report.Saved())
+	if report.Tokens > 0 {
+		summary += fmt.Sprintf(" (%.1f%%)", 100*float64(report.Saved())/float64(report.Tokens))
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
@@ -0,0 +1,59 @@
+package llama3cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/analysis"
+)
+
+func TestOptimizeCommand(t *testing.T) {
+	run := func(input string, args ...string) (string, error) {
+		cmd := OptimizeCommand()
+		var out bytes.Buffer
+		cmd.SetIn(strings.NewReader(input))
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	input := "Hello\u200b world.   \nNext"
+	out, err := run(input, "-o", "json")
+	if err != nil {
+		t.Fatalf("optimize failed: %v", err)
+	}
+	var report analysis.OptimizeReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("Expected JSON output, got %q: %v", out, err)
+	}
+	if report.Saved() <= 0 || len(report.Rewrites) == 0 {
+		t.Errorf("Expected token savings, got %+v", report)
+	}
+
+	out, err = run(input, "-o", "text", "--rule", "zero-width")
+	if err != nil {
+		t.Fatalf("optimize failed: %v", err)
+	}
+	if want := "Hello world.   \nNext"; out != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+
+	out, err = run(input)
+	if err != nil {
+		t.Fatalf("optimize failed: %v", err)
+	}
+	if !strings.Contains(out, "zero-width") || !strings.Contains(out, "saved") {
+		t.Errorf("Expected a table of rewrites and a summary, got %q", out)
+	}
+
+	if _, err := run(input, "--rule", "bogus"); err == nil {
+		t.Error("Expected an error for an unknown rule")
+	}
+	if _, err := run(input, "-o", "yaml"); err == nil {
+		t.Error("Expected an error for an unknown output format")
+	}
+}
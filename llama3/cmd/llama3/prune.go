@@ -0,0 +1,84 @@
+package llama3cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+var (
+	// Prune command flags.
+	pruneFreqs     string
+	pruneSize      int
+	pruneVocabOut  string
+	pruneMergesOut string
+)
+
+// newPruneCmd creates the prune subcommand.
+func newPruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Build a reduced vocabulary from token frequencies",
+		Long: `Reduce the vocabulary to the most frequent tokens for embedded use.
+
+The frequency file has one "<token-id> <count>" pair per line. Lines starting
+with # are ignored. The 256 byte-level tokens are always kept, so any input can
+still be encoded. Each kept token also keeps the tokens it is merged from, and
+only merges that stay inside the reduced vocabulary are written.
+
+The output files can be loaded with llama3.WithDataFiles or with a config file
+that uses the "files" vocabulary source. Special tokens are added at load time
+and are not part of the output.`,
+		Example: `  # Count token frequencies over a corpus
+  tokenizer llama3 encode --bos=false --eos=false -o newline < corpus.txt |
+    sort | uniq -c | awk '{print $2, $1}' > freqs.txt
+
+  # Keep the 16,000 most useful tokens
+  tokenizer llama3 prune --freqs freqs.txt --size 16000 \
+    --vocab-out vocab.txt --merges-out merges.txt`,
+		Args: cobra.NoArgs,
+		RunE: runPrune,
+	}
+
+	cmd.Flags().StringVar(&pruneFreqs, "freqs", "", "Token frequency file (required)")
+	cmd.Flags().IntVar(&pruneSize, "size", 32000, "Maximum number of regular tokens to keep")
+	cmd.Flags().StringVar(&pruneVocabOut, "vocab-out", "vocab.txt", "Output vocabulary file")
+	cmd.Flags().StringVar(&pruneMergesOut, "merges-out", "merges.txt", "Output merges file")
+	_ = cmd.MarkFlagRequired("freqs")
+
+	return cmd
+}
+
+func runPrune(cmd *cobra.Command, _ []string) error {
+	f, err := os.Open(pruneFreqs)
+	if err != nil {
+		return fmt.Errorf("failed to open frequency file: %w", err)
+	}
+	freqs, err := llama3.ReadTokenFrequencies(f)
+	_ = f.Close()
+	if err != nil {
+		return err
+	}
+
+	tokenizer, err := llama3.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize tokenizer: %w", err)
+	}
+
+	pruned, err := tokenizer.PruneVocabulary(freqs, pruneSize)
+	if err != nil {
+		return err
+	}
+	if err := pruned.WriteFiles(pruneVocabOut, pruneMergesOut); err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Kept %d of %d regular tokens and %d merges\n", len(pruned.Tokens), tokenizer.VocabSize()-256, len(pruned.MergePairs)/2)
+	fmt.Fprintf(out, "  Vocabulary: %s\n", pruneVocabOut)
+	fmt.Fprintf(out, "  Merges:     %s\n", pruneMergesOut)
+	return nil
+}
@@ -0,0 +1,20 @@
+package llama3cmd
+
+import (
+	"github.com/agentstation/tokenizer/cli"
+)
+
+// Importing this package adds the llama3, config, lsp, hook, snapshot, cost,
+// lint-templates, optimize, conformance and serve commands to the CLI.
+func init() {
+	cli.Register("llama3", Command)
+	cli.Register("config", ConfigCommand)
+	cli.Register("lsp", LSPCommand)
+	cli.Register("hook", HookCommand)
+	cli.Register("snapshot", SnapshotCommand)
+	cli.Register("cost", CostCommand)
+	cli.Register("lint-templates", LintTemplatesCommand)
+	cli.Register("optimize", OptimizeCommand)
+	cli.Register("conformance", ConformanceCommand)
+	cli.Register("serve", ServeCommand)
+}
@@ -0,0 +1,259 @@
+package llama3cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/llama3"
+	"github.com/agentstation/tokenizer/server"
+)
+
+// serveShutdownTimeout is how long serve waits for requests in flight when
+// it is stopped.
+const serveShutdownTimeout = 10 * time.Second
+
+// ServeCommand returns the serve command, which runs a tokenization service
+// over HTTP.
+func ServeCommand() *cobra.Command {
+	var listen, configPath string
+	var opts serveOptions
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve encode, decode and count over HTTP",
+		Long: `Run an HTTP server that encodes, decodes and counts tokens with one warm Llama 3
+tokenizer, so services in any language can share a canonical token count
+without running the CLI per request. It serves the API of the Go client
+package (github.com/agentstation/tokenizer/client):
+
+  POST /v1/encode        {"text": "...", "bos": true, "eos": true} -> {"tokens": [...]}
+  POST /v1/decode        {"tokens": [...]} -> {"text": "..."}
+  POST /v1/count         {"text": "..."} -> {"count": n}
+  POST /v1/encode/batch  {"texts": [...], "bos": true} -> {"results": [{"tokens": [...]}, ...]}
+  POST /v1/count/batch   {"texts": [...]} -> {"results": [{"count": n}, ...]}
+  GET  /v1/info          tokenizer information
+  GET  /healthz          200 once the tokenizer is loaded
+
+"bos" and "eos" default to false, and counts never include them. Requests may
+name the tokenizer in a "model" field: "" or "llama3". Request bodies may be
+gzip-compressed, and responses are compressed for clients accepting gzip.
+Errors are returned as {"error": "..."} with a 4xx or 5xx status.
+
+--api-key-file requires an API key on every request except /healthz, sent as
+"Authorization: Bearer <key>" or in an X-API-Key header. Each line of the file
+holds a key, optionally preceded by the identity it authenticates as. With
+--tls-cert and --tls-key the server speaks HTTPS; --client-ca additionally
+requires client certificates signed by that CA, authenticating clients by
+their certificate's common name. --rate limits requests per identity.
+
+The server stops gracefully on SIGINT or SIGTERM.`,
+		Example: `  # Serve on port 8080
+  tokenizer serve --listen :8080
+
+  # Count tokens from another service
+  curl -s localhost:8080/v1/count -d '{"text": "Hello, world!"}'
+
+  # Require API keys, allowing 10 requests per second per key
+  tokenizer serve --api-key-file keys.txt --rate 10 --burst 20
+
+  # Require client certificates
+  tokenizer serve --tls-cert server.pem --tls-key server-key.pem --client-ca ca.pem`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			var tokenizer *llama3.Tokenizer
+			var err error
+			if configPath != "" {
+				tokenizer, err = newFromConfigFile(configPath)
+			} else {
+				tokenizer, err = llama3.New()
+			}
+			if err != nil {
+				return fmt.Errorf("failed to initialize tokenizer: %w", err)
+			}
+			handler, err := newServeHandler(tokenizer, opts)
+			if err != nil {
+				return err
+			}
+			tlsConfig, err := opts.tlsConfig()
+			if err != nil {
+				return err
+			}
+
+			ln, err := net.Listen("tcp", listen)
+			if err != nil {
+				return fmt.Errorf("failed to listen: %w", err)
+			}
+			if tlsConfig != nil {
+				ln = tls.NewListener(ln, tlsConfig)
+			}
+			ctx := cmd.Context()
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Serving on %s\n", ln.Addr())
+			return serveTokenizer(ctx, ln, handler)
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", ":8080", "Address to listen on")
+	cmd.Flags().StringVar(&configPath, "config", "", "Tokenizer config file (default: embedded vocabulary)")
+	cmd.Flags().Int64Var(&opts.maxBody, "max-body", server.DefaultMaxBodyBytes, "Maximum request body size in bytes, after decompression")
+	cmd.Flags().IntVar(&opts.maxBatch, "max-batch", server.DefaultMaxBatchSize, "Maximum number of texts in a batch request")
+	cmd.Flags().IntVar(&opts.maxText, "max-text", 0, "Maximum size of each text in bytes (default: no limit)")
+	cmd.Flags().StringVar(&opts.apiKeyFile, "api-key-file", "", "File of API keys to require, one per line")
+	cmd.Flags().StringVar(&opts.tlsCert, "tls-cert", "", "TLS certificate file")
+	cmd.Flags().StringVar(&opts.tlsKey, "tls-key", "", "TLS private key file")
+	cmd.Flags().StringVar(&opts.clientCA, "client-ca", "", "CA file to verify required client certificates against")
+	cmd.Flags().Float64Var(&opts.rate.Rate, "rate", 0, "Requests per second allowed per identity (default: no limit)")
+	cmd.Flags().IntVar(&opts.rate.Burst, "burst", 1, "Requests allowed in a burst with --rate")
+
+	return cmd
+}
+
+// serveOptions configures the handler and listener of serve.
+type serveOptions struct {
+	maxBody    int64
+	maxBatch   int
+	maxText    int
+	apiKeyFile string
+	tlsCert    string
+	tlsKey     string
+	clientCA   string
+	rate       server.Limit
+}
+
+// newServeHandler returns the HTTP API of serve: the server.Handler of
+// tokenizer behind the authentication and rate limiting of opts, and an
+// unauthenticated health check.
+func newServeHandler(tokenizer *llama3.Tokenizer, opts serveOptions) (http.Handler, error) {
+	handlerOpts := []server.Option{server.WithMaxBodyBytes(opts.maxBody), server.WithMaxBatchSize(opts.maxBatch)}
+	if opts.maxText > 0 {
+		handlerOpts = append(handlerOpts, server.WithMaxTextBytes(opts.maxText))
+	}
+	h, err := server.NewHandler(tokenizer, handlerOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var api http.Handler = h
+	if opts.rate.Rate > 0 {
+		api = server.NewRateLimiter(opts.rate, nil).Middleware(api)
+	}
+	var authenticators []server.Authenticator
+	if opts.apiKeyFile != "" {
+		keys, err := readAPIKeys(opts.apiKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, server.APIKeys(keys))
+	}
+	if opts.clientCA != "" {
+		authenticators = append(authenticators, server.ClientCerts())
+	}
+	if len(authenticators) > 0 {
+		api = server.RequireAuth(server.AnyOf(authenticators...), api)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", api)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux, nil
+}
+
+// readAPIKeys reads an API key file, mapping each key to its identity. Each
+// line holds a key, optionally preceded by an identity; the identity of a
+// key alone is its line number. Blank lines and lines starting with # are
+// skipped.
+func readAPIKeys(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+	keys := make(map[string]string)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0 || strings.HasPrefix(fields[0], "#"):
+		case len(fields) == 1:
+			keys[fields[0]] = fmt.Sprintf("key-%d", i+1)
+		case len(fields) == 2:
+			keys[fields[1]] = fields[0]
+		default:
+			return nil, fmt.Errorf("%s:%d: expected a key, optionally preceded by an identity", path, i+1)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s: no API keys", path)
+	}
+	return keys, nil
+}
+
+// tlsConfig returns the TLS config of the listener, or nil to serve plain
+// HTTP.
+func (o *serveOptions) tlsConfig() (*tls.Config, error) {
+	if o.tlsCert == "" && o.tlsKey == "" {
+		if o.clientCA != "" {
+			return nil, errors.New("--client-ca requires --tls-cert and --tls-key")
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(o.tlsCert, o.tlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	if o.clientCA == "" {
+		return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+	}
+	pem, err := os.ReadFile(o.clientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no PEM certificates", o.clientCA)
+	}
+	return server.MutualTLSConfig(cert, pool), nil
+}
+
+// serveTokenizer serves handler on ln until ctx is done, then shuts down,
+// waiting up to serveShutdownTimeout for requests in flight.
+func serveTokenizer(ctx context.Context, ln net.Listener, handler http.Handler) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
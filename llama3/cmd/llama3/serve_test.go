@@ -0,0 +1,162 @@
+package llama3cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/agentstation/tokenizer/client"
+	"github.com/agentstation/tokenizer/llama3"
+	"github.com/agentstation/tokenizer/server"
+)
+
+// newServeTestServer serves newServeHandler with opts, which default to the
+// flag defaults.
+func newServeTestServer(t *testing.T, opts serveOptions) (*llama3.Tokenizer, *httptest.Server) {
+	t.Helper()
+	tokenizer, err := llama3.New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	if opts.maxBody == 0 {
+		opts.maxBody = server.DefaultMaxBodyBytes
+	}
+	if opts.maxBatch == 0 {
+		opts.maxBatch = server.DefaultMaxBatchSize
+	}
+	handler, err := newServeHandler(tokenizer, opts)
+	if err != nil {
+		t.Fatalf("newServeHandler failed: %v", err)
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return tokenizer, srv
+}
+
+func TestServeClient(t *testing.T) {
+	tokenizer, srv := newServeTestServer(t, serveOptions{})
+	ctx := context.Background()
+
+	for _, opts := range []client.Option{client.WithModel(llama3.Name), client.WithCompression(0)} {
+		c, err := client.New(srv.URL, opts)
+		if err != nil {
+			t.Fatalf("client.New failed: %v", err)
+		}
+		if n, err := c.Count(ctx, "Hello, world!"); err != nil || n != 4 {
+			t.Errorf("Expected count 4, got %d (%v)", n, err)
+		}
+		tokens, err := c.Encode(ctx, "Hello, world!", nil)
+		if want := tokenizer.Encode("Hello, world!", nil); err != nil || !slices.Equal(tokens, want) {
+			t.Errorf("Expected %v, got %v (%v)", want, tokens, err)
+		}
+		if text, err := c.Decode(ctx, []int{9906, 11, 1917, 0}); err != nil || text != "Hello, world!" {
+			t.Errorf("Expected Hello, world!, got %q (%v)", text, err)
+		}
+		counts, err := c.CountBatch(ctx, []string{"Hello", "Hello, world!"})
+		if err != nil || len(counts) != 2 || counts[0].Count != 1 || counts[1].Count != 4 {
+			t.Errorf("Expected batch counts 1 and 4, got %+v (%v)", counts, err)
+		}
+		results, err := c.EncodeBatch(ctx, []string{"Hello"}, &llama3.EncodeOptions{BOS: true})
+		if err != nil || len(results) != 1 || !slices.Equal(results[0].Tokens, []int{128000, 9906}) {
+			t.Errorf("Expected batch tokens [128000 9906], got %+v (%v)", results, err)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/v1/info")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var info llama3.Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil || info.VocabSize != tokenizer.VocabSize() {
+		t.Errorf("Expected info with %d tokens, got %+v (%v)", tokenizer.VocabSize(), info, err)
+	}
+}
+
+func TestServeAuth(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(keyFile, []byte("# keys\nteam-a secret\n\nother\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, srv := newServeTestServer(t, serveOptions{apiKeyFile: keyFile, rate: server.Limit{Rate: 0.001, Burst: 1}})
+	ctx := context.Background()
+
+	var apiErr *client.APIError
+	anonymous, _ := client.New(srv.URL)
+	if _, err := anonymous.Count(ctx, "Hello"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without an API key, got %v", err)
+	}
+	resp, err := http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected /healthz without an API key to be 200, got %d", resp.StatusCode)
+	}
+
+	for _, key := range []string{"secret", "other"} {
+		c, _ := client.New(srv.URL, client.WithAPIKey(key))
+		if n, err := c.Count(ctx, "Hello, world!"); err != nil || n != 4 {
+			t.Errorf("Key %q: expected count 4, got %d (%v)", key, n, err)
+		}
+		if _, err := c.Count(ctx, "Hello, world!"); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Key %q: expected 429 over the rate limit, got %v", key, err)
+		}
+	}
+}
+
+func TestServeOptions(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.txt")
+	empty := filepath.Join(dir, "empty.txt")
+	_ = os.WriteFile(invalid, []byte("a b c\n"), 0o600)
+	_ = os.WriteFile(empty, []byte("# none\n"), 0o600)
+	for _, path := range []string{invalid, empty, filepath.Join(dir, "missing.txt")} {
+		if _, err := readAPIKeys(path); err == nil {
+			t.Errorf("Expected an error reading %s", filepath.Base(path))
+		}
+	}
+
+	if _, err := (&serveOptions{clientCA: "ca.pem"}).tlsConfig(); err == nil {
+		t.Error("Expected an error for --client-ca without a certificate")
+	}
+	if cfg, err := (&serveOptions{}).tlsConfig(); cfg != nil || err != nil {
+		t.Errorf("Expected plain HTTP by default, got %v (%v)", cfg, err)
+	}
+}
+
+func TestServeTokenizerShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveTokenizer(ctx, ln, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+	}()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", resp.StatusCode)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package llama3cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/agentstation/tokenizer/cli"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// newStatsCmd creates the stats subcommand.
+func newStatsCmd() *cobra.Command {
+	var output, crlf string
+
+	cmd := &cobra.Command{
+		Use:   "stats [file...]",
+		Short: "Show token efficiency by writing system",
+		Long: `Encode text and break down characters and tokens by Unicode script (Latin,
+Cyrillic, Han, ...), showing which languages of a dataset cost the most tokens
+per character.
+
+Digits, punctuation and whitespace count toward the script of the text around
+them. Input is read from the files, or from stdin if none are given.`,
+		Example: `  # Compare token costs across the languages of a prompt set
+  tokenizer llama3 stats prompts/*.txt
+
+  # As JSON
+  cat prompts.txt | tokenizer llama3 stats --output json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "table" && output != "json" {
+				return fmt.Errorf("unknown output format %q: use table or json", output)
+			}
+
+			tokenizer, err := llama3.New()
+			if err != nil {
+				return fmt.Errorf("failed to initialize tokenizer: %w", err)
+			}
+
+			var stats []llama3.ScriptStats
+			add := func(r io.Reader) error {
+				input, err := cli.NormalizeInput(r, crlf)
+				if err != nil {
+					return err
+				}
+				data, err := io.ReadAll(input)
+				if err != nil {
+					return fmt.Errorf("failed to read input: %w", err)
+				}
+				stats = mergeScriptStats(stats, tokenizer.ScriptStats(string(data)))
+				return nil
+			}
+
+			if len(args) == 0 {
+				if err := add(cmd.InOrStdin()); err != nil {
+					return err
+				}
+			}
+			for _, path := range args {
+				f, err := os.Open(path) // #nosec G304 - path is provided by the caller
+				if err != nil {
+					return fmt.Errorf("failed to open input: %w", err)
+				}
+				err = add(f)
+				_ = f.Close()
+				if err != nil {
+					return err
+				}
+			}
+
+			return printScriptStats(cmd.OutOrStdout(), stats, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format: table, json")
+	cmd.Flags().StringVar(&crlf, "crlf", cli.CRLFAuto, "Line endings: auto, keep, lf")
+
+	return cmd
+}
+
+// mergeScriptStats adds the counts of b to a and sorts the result by tokens.
+func mergeScriptStats(a, b []llama3.ScriptStats) []llama3.ScriptStats {
+	for _, s := range b {
+		i := slices.IndexFunc(a, func(t llama3.ScriptStats) bool { return t.Script == s.Script })
+		if i < 0 {
+			a = append(a, s)
+			continue
+		}
+		a[i].Chars += s.Chars
+		a[i].Bytes += s.Bytes
+		a[i].Tokens += s.Tokens
+	}
+	slices.SortStableFunc(a, func(x, y llama3.ScriptStats) int {
+		return cmp.Compare(y.Tokens, x.Tokens)
+	})
+	return a
+}
+
+func printScriptStats(w io.Writer, stats []llama3.ScriptStats, output string) error {
+	total := 0
+	for _, s := range stats {
+		total += s.Tokens
+	}
+
+	if output == "json" {
+		type row struct {
+			llama3.ScriptStats
+			TokensPerChar float64 `json:"tokens_per_char"`
+		}
+		rows := make([]row, len(stats))
+		for i, s := range stats {
+			rows[i] = row{s, s.TokensPerChar()}
+		}
+		data, err := json.Marshal(map[string]any{"scripts": rows, "tokens": total})
+		if err != nil {
+			return fmt.Errorf("failed to marshal stats: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCRIPT\tCHARS\tTOKENS\tTOKENS/CHAR\tSHARE\t")
+	for _, s := range stats {
+		share := 0.0
+		if total > 0 {
+			share = 100 * float64(s.Tokens) / float64(total)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.3f\t%.1f%%\t\n", s.Script, s.Chars, s.Tokens, s.TokensPerChar(), share)
+	}
+	return tw.Flush()
+}
@@ -1,72 +1,62 @@
+//go:build !tokenizer_tiny
+
 // Command generate-vectors creates test vectors for tokenizer validation.
+//
+// The expected tokens come from the official Hugging Face tokenizer.json of
+// Llama 3, loaded with llama3.WithHFTokenizerJSON, so the vectors check the
+// embedded vocabulary data and the encoder against the published tokenizer.
 package main
 
 import (
-	"context"
+	"bufio"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"time"
+
+	"github.com/agentstation/tokenizer/llama3"
 )
 
+// vector is one line of the output file.
+type vector struct {
+	Input    string `json:"input"`
+	Expected []int  `json:"expected"`
+}
+
 func main() {
 	var (
-		output = flag.String("output", "test_vectors.jsonl", "Output file for test vectors")
-		count  = flag.Int("count", 100, "Number of test vectors to generate")
+		tokenizerJSON = flag.String("tokenizer-json", "tokenizer.json", "Hugging Face tokenizer.json of Llama 3")
+		output        = flag.String("output", "test_vectors.jsonl", "Output file for test vectors")
+		count         = flag.Int("count", 100, "Number of test vectors to generate")
 	)
 	flag.Parse()
 
-	// Check if Node.js is available
-	if _, err := exec.LookPath("node"); err != nil {
-		log.Fatal("Node.js is required but not found in PATH")
-	}
-
-	// Check if JS tokenizer exists
-	jsPath := filepath.Join(os.Getenv("HOME"), "src/github.com/belladoreai/llama3-tokenizer-js/bundle/llama3-tokenizer-with-baked-data.js")
-	if _, err := os.Stat(jsPath); os.IsNotExist(err) {
-		log.Fatalf("JS tokenizer not found at %s", jsPath)
+	tokenizer, err := llama3.New(llama3.WithHFTokenizerJSON(*tokenizerJSON))
+	if err != nil {
+		log.Fatalf("Failed to load tokenizer: %v", err)
 	}
 
 	// Generate test inputs
 	inputs := generateTestInputs(*count)
 
-	// Create JS script
-	jsContent := `import llama3Tokenizer from '` + jsPath + `';
-
-const inputs = ` + toJSON(inputs) + `;
-
-inputs.forEach(input => {
-    const tokens = llama3Tokenizer.encode(input, {bos: false, eos: false});
-    console.log(JSON.stringify({input, expected: tokens}));
-});
-`
-
-	// Write to temporary file
-	tmpFile := filepath.Join(os.TempDir(), "generate_vectors.js")
-	if err := os.WriteFile(tmpFile, []byte(jsContent), 0600); err != nil {
-		log.Fatalf("Failed to write JS file: %v", err)
+	f, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("Failed to create output file: %v", err)
 	}
-	defer func() {
-		if err := os.Remove(tmpFile); err != nil {
-			log.Printf("Failed to remove temporary file: %v", err)
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	opts := &llama3.EncodeOptions{BOS: false, EOS: false}
+	for _, input := range inputs {
+		if err := enc.Encode(vector{Input: input, Expected: tokenizer.Encode(input, opts)}); err != nil {
+			log.Fatalf("Failed to write test vector: %v", err)
 		}
-	}()
-
-	// Run the script with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	cmd := exec.CommandContext(ctx, "node", tmpFile) // #nosec G204 - tmpFile is safely constructed
-	outputBytes, err := cmd.Output()
-	if err != nil {
-		log.Fatalf("Failed to run JS script: %v", err)
 	}
-
-	// Write output
-	if err := os.WriteFile(*output, outputBytes, 0600); err != nil {
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Failed to write output file: %v", err)
+	}
+	if err := f.Close(); err != nil {
 		log.Fatalf("Failed to write output file: %v", err)
 	}
 
@@ -187,8 +177,3 @@ func generateTestInputs(count int) []string {
 
 	return inputs[:count]
 }
-
-func toJSON(v interface{}) string {
-	b, _ := json.Marshal(v)
-	return string(b)
-}
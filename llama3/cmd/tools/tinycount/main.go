@@ -0,0 +1,40 @@
+// Command tinycount counts Llama 3 tokens on stdin using vocabulary files.
+//
+// It is the smoke-test program for the tokenizer_tiny build, which leaves out
+// the embedded vocabulary and JSON config support:
+//
+//	go build -tags tokenizer_tiny ./llama3/cmd/tools/tinycount
+//	tinygo build -tags tokenizer_tiny ./llama3/cmd/tools/tinycount
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: tinycount <vocab-file> <merges-file> < input")
+		os.Exit(2)
+	}
+
+	tokenizer, err := llama3.New(llama3.WithDataFiles(os.Args[1], os.Args[2]))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	count := 0
+	scanner := tokenizer.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println(count)
+}
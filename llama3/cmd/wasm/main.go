@@ -0,0 +1,133 @@
+//go:build js && wasm
+
+// Command wasm exposes the Llama 3 tokenizer to JavaScript, so web frontends
+// can show live token counts identical to those of Go services.
+//
+// It sets globalThis.llama3Tokenizer to an object with the functions:
+//
+//	load(vocab, merges)     load the vocabulary; with no arguments, the
+//	                        embedded data is used
+//	encode(text, bos, eos)  token IDs as a Uint32Array
+//	decode(tokens)          text of an array of token IDs
+//	count(text, bos, eos)   number of tokens
+//
+// vocab and merges are the contents of the vocabulary and merges files, as
+// strings or Uint8Arrays. The functions return an Error instead of throwing;
+// tokenizer.mjs wraps them in a friendlier API. Build with tokenizer_tiny to
+// leave the embedded data out of the binary and fetch it instead:
+//
+//	GOOS=js GOARCH=wasm go build -tags tokenizer_tiny -o tokenizer.wasm ./llama3/cmd/wasm
+//	tinygo build -target wasm -tags tokenizer_tiny -o tokenizer.wasm ./llama3/cmd/wasm
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+var errNotLoaded = errors.New("tokenizer not loaded, call load first")
+
+// tokenizer is the tokenizer of the last successful load.
+var tokenizer *llama3.Tokenizer
+
+func main() {
+	js.Global().Set("llama3Tokenizer", js.ValueOf(map[string]any{
+		"load":   js.FuncOf(load),
+		"encode": js.FuncOf(encode),
+		"decode": js.FuncOf(decode),
+		"count":  js.FuncOf(count),
+	}))
+	select {} // keep the functions callable
+}
+
+func load(_ js.Value, args []js.Value) any {
+	var t *llama3.Tokenizer
+	var err error
+	if len(args) >= 2 && args[0].Truthy() {
+		t, err = llama3.New(llama3.WithData(bytesArg(args[0]), bytesArg(args[1])))
+	} else {
+		t, err = llama3.New()
+	}
+	if err != nil {
+		return jsError(err)
+	}
+	tokenizer = t
+	return js.Undefined()
+}
+
+func encode(_ js.Value, args []js.Value) any {
+	if tokenizer == nil {
+		return jsError(errNotLoaded)
+	}
+	tokens := tokenizer.Encode(stringArg(args, 0), encodeOptions(args))
+
+	// Copy the IDs through a byte buffer, one call instead of one per token
+	buf := make([]byte, 4*len(tokens))
+	for i, id := range tokens {
+		binary.LittleEndian.PutUint32(buf[4*i:], uint32(id)) // #nosec G115 - token IDs are not negative
+	}
+	array := js.Global().Get("Uint8Array").New(len(buf))
+	js.CopyBytesToJS(array, buf)
+	return js.Global().Get("Uint32Array").New(array.Get("buffer"))
+}
+
+func decode(_ js.Value, args []js.Value) any {
+	if tokenizer == nil {
+		return jsError(errNotLoaded)
+	}
+	if len(args) == 0 {
+		return jsError(errors.New("decode: missing tokens"))
+	}
+	tokens := make([]int, args[0].Length())
+	for i := range tokens {
+		tokens[i] = args[0].Index(i).Int()
+	}
+	text, err := tokenizer.DecodeStrict(tokens)
+	if err != nil {
+		return jsError(err)
+	}
+	return text
+}
+
+func count(_ js.Value, args []js.Value) any {
+	if tokenizer == nil {
+		return jsError(errNotLoaded)
+	}
+	return tokenizer.Count(stringArg(args, 0), encodeOptions(args))
+}
+
+// encodeOptions returns the options of the bos and eos arguments of encode
+// and count, which are off unless given.
+func encodeOptions(args []js.Value) *llama3.EncodeOptions {
+	return &llama3.EncodeOptions{
+		BOS: len(args) > 1 && args[1].Truthy(),
+		EOS: len(args) > 2 && args[2].Truthy(),
+	}
+}
+
+// stringArg returns argument i as a string, or "" if it is missing.
+func stringArg(args []js.Value, i int) string {
+	if i >= len(args) || args[i].Type() != js.TypeString {
+		return ""
+	}
+	return args[i].String()
+}
+
+// bytesArg returns the contents of a string or Uint8Array argument.
+func bytesArg(v js.Value) []byte {
+	if v.Type() == js.TypeString {
+		return []byte(v.String())
+	}
+	b := make([]byte, v.Length())
+	js.CopyBytesToGo(b, v)
+	return b
+}
+
+// jsError returns err as a JavaScript Error.
+func jsError(err error) js.Value {
+	return js.Global().Get("Error").New(fmt.Sprint(err))
+}
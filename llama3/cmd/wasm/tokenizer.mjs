@@ -0,0 +1,98 @@
+// Llama 3 tokenizer for the browser, running the Go tokenizer compiled to
+// WebAssembly, so token counts match those of Go services exactly.
+//
+// Load wasm_exec.js from the Go (or TinyGo) distribution first, which
+// defines the global Go class, then:
+//
+//   import { loadTokenizer } from "./tokenizer.mjs";
+//
+//   const tokenizer = await loadTokenizer({
+//     wasm: "tokenizer.wasm",
+//     vocab: "vocab_base64.txt",
+//     merges: "merges_binary.txt",
+//   });
+//   tokenizer.count("Hello, world!"); // 4
+//
+// vocab and merges are fetched in parallel with the WebAssembly module. Leave
+// them out for a binary built with the embedded vocabulary.
+
+/**
+ * Loads the tokenizer.
+ *
+ * @param {object} [options]
+ * @param {string|URL} [options.wasm] URL of tokenizer.wasm
+ * @param {string|URL} [options.vocab] URL of the vocabulary file
+ * @param {string|URL} [options.merges] URL of the merges file
+ * @returns {Promise<Tokenizer>}
+ */
+export async function loadTokenizer({ wasm = "tokenizer.wasm", vocab, merges } = {}) {
+  if (!vocab !== !merges) {
+    throw new Error("vocab and merges must be given together");
+  }
+  const data = vocab ? Promise.all([fetchText(vocab), fetchText(merges)]) : Promise.resolve([]);
+
+  const go = new Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(wasm), go.importObject);
+  go.run(instance); // returns once main blocks, leaving the functions set
+  const api = globalThis.llama3Tokenizer;
+
+  check(api.load(...(await data)));
+  return new Tokenizer(api);
+}
+
+/** Tokenizer encodes and decodes Llama 3 tokens. */
+export class Tokenizer {
+  #api;
+
+  constructor(api) {
+    this.#api = api;
+  }
+
+  /**
+   * Encodes text as token IDs, without BOS and EOS unless requested.
+   *
+   * @param {string} text
+   * @param {{bos?: boolean, eos?: boolean}} [options]
+   * @returns {Uint32Array}
+   */
+  encode(text, { bos = false, eos = false } = {}) {
+    return check(this.#api.encode(text, bos, eos));
+  }
+
+  /**
+   * Decodes token IDs as text, throwing for IDs outside the vocabulary.
+   *
+   * @param {ArrayLike<number>} tokens
+   * @returns {string}
+   */
+  decode(tokens) {
+    return check(this.#api.decode(tokens));
+  }
+
+  /**
+   * Counts the tokens of text, without encoding it.
+   *
+   * @param {string} text
+   * @param {{bos?: boolean, eos?: boolean}} [options]
+   * @returns {number}
+   */
+  count(text, { bos = false, eos = false } = {}) {
+    return check(this.#api.count(text, bos, eos));
+  }
+}
+
+async function fetchText(url) {
+  const response = await fetch(url);
+  if (!response.ok) {
+    throw new Error(`fetch ${url}: ${response.status} ${response.statusText}`);
+  }
+  return response.text();
+}
+
+// check throws the errors returned by the Go functions.
+function check(result) {
+  if (result instanceof Error) {
+    throw result;
+  }
+  return result;
+}
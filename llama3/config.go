@@ -0,0 +1,192 @@
+package llama3
+
+import (
+	"errors"
+)
+
+// Vocabulary sources accepted in Config.
+const (
+	VocabSourceEmbedded = "embedded" // the vocabulary compiled into the package
+	VocabSourceFiles    = "files"    // base64 vocabulary and merges files
+)
+
+// Cache types accepted in Config.
+const (
+	CacheTypeLRU       = "lru"       // bounded LRU cache of Size entries
+	CacheTypeUnbounded = "unbounded" // cache every pre-token
+	CacheTypeAdaptive  = "adaptive"  // LRU cache sized between MinSize and MaxSize
+)
+
+// Config describes a tokenizer declaratively, so deployments can define it in
+// configuration management instead of code. It is read from JSON by
+// LoadConfig and NewFromConfig, which tokenizer_tiny builds leave out:
+//
+//	{
+//	  "vocabulary": {"source": "files", "vocab_file": "vocab.txt", "merges_file": "merges.txt"},
+//	  "cache": {"type": "lru", "size": 100000},
+//	  "extra_special_tokens": ["<|tool_call|>"]
+//	}
+type Config struct {
+	Vocabulary VocabularyConfig `json:"vocabulary"`
+	Cache      CacheConfig      `json:"cache"`
+
+	// SpecialTokenProfile names a built-in special token profile, such as
+	// "llama3.2-vision", to use instead of the default special tokens.
+	SpecialTokenProfile string `json:"special_token_profile,omitempty"`
+	// SpecialTokens replaces the default Llama 3 special tokens.
+	SpecialTokens []string `json:"special_tokens,omitempty"`
+	// ExtraSpecialTokens are appended to the special tokens.
+	ExtraSpecialTokens []string `json:"extra_special_tokens,omitempty"`
+
+	// Normalizer names the text normalizer. Llama 3 applies none, so only
+	// "" and "none" are accepted.
+	Normalizer string `json:"normalizer,omitempty"`
+	// PreTokenizer names the pre-tokenizer profile. Only "" and "llama3"
+	// are accepted.
+	PreTokenizer string `json:"pretokenizer,omitempty"`
+
+	// MergeOverrides changes merge priorities; see WithMergeOverrides.
+	MergeOverrides []MergeOverride `json:"merge_overrides,omitempty"`
+	// AtomicPatterns are pre-tokenized whole; see WithAtomicPatterns.
+	AtomicPatterns []string `json:"atomic_patterns,omitempty"`
+	// OptimisticPatterns are counted as special tokens by OptimisticCount;
+	// see WithOptimisticPatterns.
+	OptimisticPatterns []string `json:"optimistic_patterns,omitempty"`
+}
+
+// VocabularyConfig selects where vocabulary data is loaded from.
+type VocabularyConfig struct {
+	// Source is VocabSourceEmbedded (the default) or VocabSourceFiles.
+	Source string `json:"source,omitempty"`
+	// VocabFile and MergesFile are required for VocabSourceFiles. Relative
+	// paths are resolved against the directory of the config file.
+	VocabFile  string `json:"vocab_file,omitempty"`
+	MergesFile string `json:"merges_file,omitempty"`
+}
+
+// CacheConfig configures the BPE cache.
+type CacheConfig struct {
+	// Type is CacheTypeUnbounded, CacheTypeLRU or CacheTypeAdaptive. When
+	// empty, the build's default applies: unbounded, or a small LRU in
+	// tokenizer_tiny builds.
+	Type string `json:"type,omitempty"`
+	// Size is the maximum number of entries of an LRU cache.
+	Size int `json:"size,omitempty"`
+	// MinSize and MaxSize bound an adaptive cache; see WithAdaptiveCache.
+	MinSize int `json:"min_size,omitempty"`
+	MaxSize int `json:"max_size,omitempty"`
+}
+
+// Validate checks the config for unsupported or inconsistent values without
+// loading any data.
+func (c *Config) Validate() error {
+	_, err := c.Options()
+	return err
+}
+
+// Options converts the config into tokenizer options.
+func (c *Config) Options() ([]Option, error) {
+	var opts []Option
+
+	switch c.Vocabulary.Source {
+	case "", VocabSourceEmbedded:
+		if c.Vocabulary.VocabFile != "" || c.Vocabulary.MergesFile != "" {
+			return nil, NewConfigError("vocabulary.source", c.Vocabulary.Source,
+				errors.New("vocab_file and merges_file require source \"files\""))
+		}
+	case VocabSourceFiles:
+		if c.Vocabulary.VocabFile == "" || c.Vocabulary.MergesFile == "" {
+			return nil, NewConfigError("vocabulary.source", c.Vocabulary.Source,
+				errors.New("vocab_file and merges_file are required"))
+		}
+		opts = append(opts, WithDataFiles(c.Vocabulary.VocabFile, c.Vocabulary.MergesFile))
+	default:
+		return nil, NewConfigError("vocabulary.source", c.Vocabulary.Source, errors.New("unknown source"))
+	}
+
+	if c.Cache.Type != CacheTypeAdaptive && (c.Cache.MinSize != 0 || c.Cache.MaxSize != 0) {
+		return nil, NewConfigError("cache.min_size", c.Cache.MinSize, errors.New("min_size and max_size require cache type \"adaptive\""))
+	}
+	switch c.Cache.Type {
+	case "", CacheTypeUnbounded:
+		if c.Cache.Size != 0 {
+			return nil, NewConfigError("cache.size", c.Cache.Size, errors.New("size requires cache type \"lru\""))
+		}
+		if c.Cache.Type == CacheTypeUnbounded {
+			opts = append(opts, WithCacheSize(0))
+		}
+	case CacheTypeLRU:
+		if c.Cache.Size <= 0 {
+			return nil, NewConfigError("cache.size", c.Cache.Size, errors.New("lru cache size must be positive"))
+		}
+		opts = append(opts, WithCacheSize(c.Cache.Size))
+	case CacheTypeAdaptive:
+		if c.Cache.Size != 0 {
+			return nil, NewConfigError("cache.size", c.Cache.Size, errors.New("adaptive cache uses min_size and max_size"))
+		}
+		if c.Cache.MinSize < 1 || c.Cache.MaxSize < c.Cache.MinSize {
+			return nil, NewConfigError("cache.min_size", c.Cache.MinSize, errors.New("adaptive cache sizes must satisfy 1 <= min_size <= max_size"))
+		}
+		opts = append(opts, WithAdaptiveCache(c.Cache.MinSize, c.Cache.MaxSize))
+	default:
+		return nil, NewConfigError("cache.type", c.Cache.Type, errors.New("unknown cache type"))
+	}
+
+	if c.SpecialTokenProfile != "" && len(c.SpecialTokens) > 0 {
+		return nil, NewConfigError("special_token_profile", c.SpecialTokenProfile,
+			errors.New("special_token_profile and special_tokens are exclusive"))
+	}
+	if c.SpecialTokenProfile != "" || len(c.SpecialTokens) > 0 || len(c.ExtraSpecialTokens) > 0 {
+		special := c.SpecialTokens
+		if c.SpecialTokenProfile != "" {
+			profile, err := LookupSpecialTokenProfile(c.SpecialTokenProfile)
+			if err != nil {
+				return nil, NewConfigError("special_token_profile", c.SpecialTokenProfile, err)
+			}
+			special = profile.Tokens
+		}
+		if len(special) == 0 {
+			special = getDefaultSpecialTokens()
+		}
+		special = append(special[:len(special):len(special)], c.ExtraSpecialTokens...)
+
+		opt := WithSpecialTokens(special)
+		if err := opt(&config{}); err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+
+	if len(c.MergeOverrides) > 0 {
+		opt := WithMergeOverrides(c.MergeOverrides...)
+		if err := opt(&config{}); err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+
+	if len(c.AtomicPatterns) > 0 {
+		opt := WithAtomicPatterns(c.AtomicPatterns...)
+		if err := opt(&config{}); err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+
+	if len(c.OptimisticPatterns) > 0 {
+		opt := WithOptimisticPatterns(c.OptimisticPatterns...)
+		if err := opt(&config{}); err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+
+	if c.Normalizer != "" && c.Normalizer != "none" {
+		return nil, NewConfigError("normalizer", c.Normalizer, errors.New("unsupported normalizer"))
+	}
+	if c.PreTokenizer != "" && c.PreTokenizer != "llama3" {
+		return nil, NewConfigError("pretokenizer", c.PreTokenizer, errors.New("unsupported pre-tokenizer profile"))
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,67 @@
+//go:build !tokenizer_tiny
+
+package llama3
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// JSON config loading is left out of tokenizer_tiny builds to avoid the
+// reflection-based encoding/json package. Config can still be built in code
+// and applied with Config.Options.
+
+// LoadConfig reads a JSON tokenizer config from path. Unknown fields are
+// rejected so typos do not silently fall back to defaults. Relative file paths
+// in the config are resolved against the config file's directory.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path is provided by the caller
+	if err != nil {
+		return nil, NewDataError("read config", path, err)
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, NewDataError("parse config", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for _, p := range []*string{&cfg.Vocabulary.VocabFile, &cfg.Vocabulary.MergesFile} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses a JSON tokenizer config. File paths are used as given.
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after config object")
+	}
+	return &cfg, nil
+}
+
+// NewFromConfig creates a tokenizer from the JSON config file at path.
+// Options in opts are applied after those from the config and take precedence.
+func NewFromConfig(path string, opts ...Option) (*Tokenizer, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfgOpts, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+	return New(append(cfgOpts, opts...)...)
+}
@@ -0,0 +1,82 @@
+//go:build !tokenizer_tiny
+
+package llama3
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestNewFromConfig(t *testing.T) {
+	path := writeConfig(t, `{
+		"vocabulary": {"source": "embedded"},
+		"cache": {"type": "lru", "size": 1000},
+		"extra_special_tokens": ["<|tool_call|>"],
+		"merge_overrides": [{"left": "h", "right": "e", "rank": 0}],
+		"normalizer": "none",
+		"pretokenizer": "llama3"
+	}`)
+
+	tokenizer, err := NewFromConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer from config: %v", err)
+	}
+
+	if got := tokenizer.VocabSize(); got != 128257 {
+		t.Errorf("Expected vocab size 128257, got %d", got)
+	}
+	id, err := tokenizer.GetSpecialTokenID("<|tool_call|>")
+	if err != nil || id != 128256 {
+		t.Errorf("Expected <|tool_call|> = 128256, got %d (%v)", id, err)
+	}
+	if _, err := tokenizer.GetSpecialTokenID(beginOfTextToken); err != nil {
+		t.Errorf("Expected default special tokens to be kept: %v", err)
+	}
+
+	text := "Hello, world!"
+	if got, want := tokenizer.Decode(tokenizer.Encode(text, nil)), beginOfTextToken+text+endOfTextToken; got != want {
+		t.Errorf("Round trip = %q, want %q", got, want)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("relative_paths", func(t *testing.T) {
+		path := writeConfig(t, `{"vocabulary": {"source": "files", "vocab_file": "vocab.txt", "merges_file": "/data/merges.txt"}}`)
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if want := filepath.Join(filepath.Dir(path), "vocab.txt"); cfg.Vocabulary.VocabFile != want {
+			t.Errorf("Expected vocab file %q, got %q", want, cfg.Vocabulary.VocabFile)
+		}
+		if cfg.Vocabulary.MergesFile != "/data/merges.txt" {
+			t.Errorf("Expected absolute merges file to be kept, got %q", cfg.Vocabulary.MergesFile)
+		}
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+		var dataErr *DataError
+		if !errors.As(err, &dataErr) {
+			t.Errorf("Expected DataError, got %v", err)
+		}
+	})
+
+	t.Run("unknown_field", func(t *testing.T) {
+		_, err := LoadConfig(writeConfig(t, `{"cache": {"kind": "lru"}}`))
+		if err == nil {
+			t.Error("Expected error for unknown field")
+		}
+	})
+}
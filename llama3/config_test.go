@@ -0,0 +1,57 @@
+package llama3
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		field string
+	}{
+		{"empty", Config{}, ""},
+		{"files_without_paths", Config{Vocabulary: VocabularyConfig{Source: VocabSourceFiles}}, "vocabulary.source"},
+		{"embedded_with_paths", Config{Vocabulary: VocabularyConfig{VocabFile: "vocab.txt"}}, "vocabulary.source"},
+		{"unknown_source", Config{Vocabulary: VocabularyConfig{Source: "s3"}}, "vocabulary.source"},
+		{"lru_without_size", Config{Cache: CacheConfig{Type: CacheTypeLRU}}, "cache.size"},
+		{"unbounded_with_size", Config{Cache: CacheConfig{Size: 10}}, "cache.size"},
+		{"unknown_cache", Config{Cache: CacheConfig{Type: "arc"}}, "cache.type"},
+		{"adaptive", Config{Cache: CacheConfig{Type: CacheTypeAdaptive, MinSize: 1024, MaxSize: 65536}}, ""},
+		{"adaptive_with_size", Config{Cache: CacheConfig{Type: CacheTypeAdaptive, Size: 10, MinSize: 1, MaxSize: 10}}, "cache.size"},
+		{"adaptive_inverted", Config{Cache: CacheConfig{Type: CacheTypeAdaptive, MinSize: 100, MaxSize: 10}}, "cache.min_size"},
+		{"lru_with_min_size", Config{Cache: CacheConfig{Type: CacheTypeLRU, Size: 10, MinSize: 1}}, "cache.min_size"},
+		{"invalid_special", Config{ExtraSpecialTokens: []string{"tool"}}, "special_tokens"},
+		{"duplicate_special", Config{ExtraSpecialTokens: []string{beginOfTextToken}}, "special_tokens"},
+		{"profile", Config{SpecialTokenProfile: ProfileLlama32Vision, ExtraSpecialTokens: []string{"<|tool_call|>"}}, ""},
+		{"unknown_profile", Config{SpecialTokenProfile: "llama2"}, "special_token_profile"},
+		{"profile_and_special", Config{SpecialTokenProfile: ProfileLlama30, SpecialTokens: []string{beginOfTextToken}}, "special_token_profile"},
+		{"normalizer", Config{Normalizer: "nfkc"}, "normalizer"},
+		{"pretokenizer", Config{PreTokenizer: "gpt2"}, "pretokenizer"},
+		{"merge_override", Config{MergeOverrides: []MergeOverride{{Left: "Ġt"}}}, "merge_overrides"},
+		{"atomic_patterns", Config{AtomicPatterns: []string{`[A-Z]+-[0-9]+`}}, ""},
+		{"invalid_atomic_pattern", Config{AtomicPatterns: []string{`[A-Z`}}, "atomic_patterns"},
+		{"optimistic_patterns", Config{OptimisticPatterns: []string{`\[/?INST\]`}}, ""},
+		{"empty_optimistic_pattern", Config{OptimisticPatterns: []string{`x*`}}, "optimistic_patterns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("Expected ConfigError, got %v", err)
+			}
+			if configErr.Field != tt.field {
+				t.Errorf("Expected field %q, got %q", tt.field, configErr.Field)
+			}
+		})
+	}
+}
@@ -9,19 +9,14 @@ const (
 	totalVocabSize    = baseVocabSize + specialTokenCount
 )
 
-// Cache configuration.
-const (
-	defaultCacheSize = 0 // 0 means unlimited
-)
-
 // BPE configuration.
 const (
-	estimatedTokensPerCharacter = 4 // Rough estimate for initial slice capacity
-	bytesPerMerge               = 3 // Number of bytes to read for each merge
+	bytesPerMerge = 3 // Number of bytes to read for each merge
 )
 
 // Special token constants.
 const (
 	beginOfTextToken = "<|begin_of_text|>"
 	endOfTextToken   = "<|end_of_text|>" // #nosec G101 - Not a credential, just a special token marker
+	endOfTurnToken   = "<|eot_id|>"
 )
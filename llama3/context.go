@@ -0,0 +1,80 @@
+package llama3
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Cancellation is checked every contextCheckTokens tokens or pre-tokens, so
+// it costs little on the hot path and still stops within microseconds.
+const contextCheckTokens = 1024
+
+// EncodeContext encodes text like Encode, but stops and returns ctx.Err()
+// if ctx is canceled or its deadline passes before encoding finishes.
+func (t *Tokenizer) EncodeContext(ctx context.Context, text string, opts *EncodeOptions) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if t.observer != nil && len(text) >= t.observeMinBytes {
+		start := time.Now()
+		output, err := t.appendTokensContext(ctx, nil, text, opts, nil)
+		if err == nil {
+			t.observeContext(ctx, OpEncode, start, len(text), len(output))
+		}
+		return output, err
+	}
+	return t.appendTokensContext(ctx, nil, text, opts, nil)
+}
+
+// ProcessContext is Process stopping when ctx is done: it returns the
+// number of tokens written so far and ctx.Err().
+func (t *Tokenizer) ProcessContext(ctx context.Context, r io.Reader, w io.Writer) (int64, error) {
+	return t.processTokens(ctx, r, w, ProcessUint32)
+}
+
+// TokenStreamContext is TokenStream stopping when ctx is done. The tokens
+// channel is then closed and ctx.Err() sent on the error channel; the
+// goroutine does not wait for the tokens to be received.
+func (t *Tokenizer) TokenStreamContext(ctx context.Context, r io.Reader) (<-chan int, <-chan error) {
+	tokens := make(chan int, 100)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(tokens)
+		defer close(errc)
+
+		scan := t.NewScanner(&contextReader{ctx: ctx, r: r})
+		for scan.Scan() {
+			select {
+			case tokens <- scan.Token():
+			case <-ctx.Done():
+				errc <- ctx.Err()
+				return
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			errc <- err
+		} else if err := scan.Err(); err != nil {
+			errc <- err
+		}
+	}()
+
+	return tokens, errc
+}
+
+// contextReader is a reader that fails with ctx.Err() once ctx is done, so
+// scanners stop reading input for a canceled operation.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read reads from the underlying reader unless ctx is done.
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
@@ -0,0 +1,132 @@
+package llama3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countdownContext is canceled after its Err method has been called n times.
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Done() <-chan struct{} {
+	return make(chan struct{}) // can be canceled, but never signals it
+}
+
+func (c *countdownContext) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+// repeatReader reads text over and over, without end.
+type repeatReader struct {
+	text string
+	pos  int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		c := copy(p[n:], r.text[r.pos:])
+		n += c
+		r.pos = (r.pos + c) % len(r.text)
+	}
+	return n, nil
+}
+
+func TestEncodeContext(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	texts := []string{
+		"",
+		"Hello, world!",
+		"<|begin_of_text|>Hello<|eot_id|> again",
+		strings.Repeat("The quick brown fox jumps over the lazy dog. ", 1000),
+	}
+	for _, text := range texts {
+		for _, opts := range []*EncodeOptions{nil, noSpecialTokens} {
+			got, err := tokenizer.EncodeContext(context.Background(), text, opts)
+			if err != nil {
+				t.Fatalf("EncodeContext failed: %v", err)
+			}
+			if want := tokenizer.Encode(text, opts); !equalIntSlices(got, want) {
+				t.Errorf("EncodeContext(%.20q): expected %v, got %v", text, want, got)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tokenizer.EncodeContext(ctx, "Hello", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// Canceled partway through a long text
+	ctx = &countdownContext{Context: context.Background(), n: 2}
+	if tokens, err := tokenizer.EncodeContext(ctx, texts[3], nil); !errors.Is(err, context.Canceled) || tokens != nil {
+		t.Errorf("Expected context.Canceled and no tokens, got %v and %d tokens", err, len(tokens))
+	}
+}
+
+func TestProcessContext(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	input := strings.Repeat("test ", 1000)
+	var got, want bytes.Buffer
+	n, err := tokenizer.ProcessContext(context.Background(), strings.NewReader(input), &got)
+	if err != nil {
+		t.Fatalf("ProcessContext failed: %v", err)
+	}
+	if _, err := tokenizer.Process(strings.NewReader(input), &want); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) || int(n)*4 != got.Len() {
+		t.Errorf("Expected the output of Process, got %d tokens in %d bytes", n, got.Len())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var out bytes.Buffer
+	n, err = tokenizer.ProcessContext(ctx, &repeatReader{text: "endless input "}, &out)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if int(n)*4 != out.Len() {
+		t.Errorf("Expected %d bytes for %d tokens, got %d", n*4, n, out.Len())
+	}
+}
+
+func TestTokenStreamContext(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tokens, errc := tokenizer.TokenStreamContext(ctx, &repeatReader{text: "endless input "})
+	for i := 0; i < 10; i++ {
+		<-tokens
+	}
+	cancel()
+	for range tokens {
+		// Drain until the stream stops
+	}
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package llama3
+
+import (
+	"io"
+	"time"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3/internal/encoding"
+)
+
+// decodeToBufferSize is the size of the chunks DecodeTo writes.
+const decodeToBufferSize = 32 * 1024
+
+// DecodeOptions controls DecodeWithOptions.
+type DecodeOptions struct {
+	// ReplaceInvalid decodes token IDs outside the vocabulary as U+FFFD
+	// instead of failing.
+	ReplaceInvalid bool
+}
+
+// DecodeStrict converts a sequence of token IDs back to text like Decode,
+// but fails on the first ID outside the vocabulary instead of skipping it.
+// The error is a *TokenError with the ID and its position in tokenIDs, and
+// wraps ErrInvalidTokenID.
+func (t *Tokenizer) DecodeStrict(tokenIDs []int) (string, error) {
+	return t.DecodeWithOptions(tokenIDs, nil)
+}
+
+// DecodeWithOptions converts a sequence of token IDs back to text, handling
+// IDs outside the vocabulary as opts says. A nil opts is DecodeStrict.
+func (t *Tokenizer) DecodeWithOptions(tokenIDs []int, opts *DecodeOptions) (string, error) {
+	var start time.Time
+	if t.observer != nil {
+		start = time.Now()
+	}
+
+	out := make([]byte, 0, len(tokenIDs)*bytesPerMerge)
+	for i, tokenID := range tokenIDs {
+		if tokenID < 0 || tokenID >= len(t.tokens) {
+			if text, ok := t.addedToken(tokenID); ok {
+				out = append(out, text...)
+				continue
+			}
+			if opts == nil || !opts.ReplaceInvalid {
+				return "", NewTokenPositionError("decode", tokenID, i, ErrInvalidTokenID)
+			}
+			out = utf8.AppendRune(out, utf8.RuneError)
+			continue
+		}
+		out = encoding.AppendTokenBytes(out, t.tokens[tokenID])
+	}
+
+	if t.observer != nil && len(out) >= t.observeMinBytes {
+		t.observe(OpDecode, start, len(out), len(tokenIDs))
+	}
+	return string(out), nil
+}
+
+// AppendDecode appends the decoded bytes of tokenIDs to dst and returns the
+// extended slice, like DecodeBytes without allocating when dst has room.
+// Invalid token IDs are skipped.
+func (t *Tokenizer) AppendDecode(dst []byte, tokenIDs []int) []byte {
+	if t.observer == nil {
+		return t.appendDecode(dst, tokenIDs)
+	}
+
+	start := time.Now()
+	n := len(dst)
+	dst = t.appendDecode(dst, tokenIDs)
+	if len(dst)-n >= t.observeMinBytes {
+		t.observe(OpDecode, start, len(dst)-n, len(tokenIDs))
+	}
+	return dst
+}
+
+// appendDecode implements AppendDecode.
+func (t *Tokenizer) appendDecode(dst []byte, tokenIDs []int) []byte {
+	for _, tokenID := range tokenIDs {
+		if tokenID < 0 || tokenID >= len(t.tokens) {
+			if text, ok := t.addedToken(tokenID); ok {
+				dst = append(dst, text...)
+			}
+			continue // Skip invalid token IDs
+		}
+		dst = encoding.AppendTokenBytes(dst, t.tokens[tokenID])
+	}
+	return dst
+}
+
+// DecodeTo writes the decoded bytes of tokenIDs to w in chunks and returns
+// the number of bytes written, so multi-megabyte decodes, such as documents
+// restored from token archives, never hold the whole text. Invalid token IDs
+// are skipped, as in Decode.
+func (t *Tokenizer) DecodeTo(w io.Writer, tokenIDs []int) (int, error) {
+	var start time.Time
+	if t.observer != nil {
+		start = time.Now()
+	}
+
+	buf := make([]byte, 0, decodeToBufferSize)
+	written := 0
+	for i := 0; i < len(tokenIDs); {
+		// Tokens average a few bytes; buf grows if a chunk does not fit
+		end := min(i+decodeToBufferSize/8, len(tokenIDs))
+		buf = t.appendDecode(buf[:0], tokenIDs[i:end])
+		i = end
+		if len(buf) == 0 {
+			continue
+		}
+		n, err := w.Write(buf)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+
+	if t.observer != nil && written >= t.observeMinBytes {
+		t.observe(OpDecode, start, written, len(tokenIDs))
+	}
+	return written, nil
+}
@@ -0,0 +1,109 @@
+package llama3
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeStrict(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	valid := tokenizer.Encode("Hello, world!", nil)
+	got, err := tokenizer.DecodeStrict(valid)
+	if err != nil {
+		t.Fatalf("DecodeStrict failed: %v", err)
+	}
+	if want := tokenizer.Decode(valid); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	hello := tokenizer.Encode("Hello", noSpecialTokens)
+	tests := []struct {
+		name     string
+		tokens   []int
+		id       int
+		position int
+		replaced string
+	}{
+		{"negative", append([]int{-1}, hello...), -1, 0, "\uFFFDHello"},
+		{"too_large", append(hello, tokenizer.VocabSize(), 1<<30), tokenizer.VocabSize(), len(hello), "Hello\uFFFD\uFFFD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tokenizer.DecodeStrict(tt.tokens)
+			var tokenErr *TokenError
+			if !errors.As(err, &tokenErr) || !errors.Is(err, ErrInvalidTokenID) {
+				t.Fatalf("Expected a TokenError wrapping ErrInvalidTokenID, got %v", err)
+			}
+			if tokenErr.TokenID != tt.id || tokenErr.Position != tt.position {
+				t.Errorf("Expected ID %d at %d, got %d at %d", tt.id, tt.position, tokenErr.TokenID, tokenErr.Position)
+			}
+
+			got, err := tokenizer.DecodeWithOptions(tt.tokens, &DecodeOptions{ReplaceInvalid: true})
+			if err != nil || got != tt.replaced {
+				t.Errorf("Expected %q, got %q, %v", tt.replaced, got, err)
+			}
+		})
+	}
+}
+
+func TestAppendDecodeAndDecodeTo(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	long := tokenizer.Encode(strings.Repeat("Reconstructed from a token archive. 日本語 ", 5000), nil)
+	for _, tokens := range [][]int{nil, {-1, 999999}, tokenizer.Encode("Hello, world!", nil), append(long, -1)} {
+		want := tokenizer.Decode(tokens)
+
+		prefix := []byte("prefix:")
+		if got := tokenizer.AppendDecode(prefix, tokens); string(got) != "prefix:"+want {
+			t.Errorf("AppendDecode: expected %.40q, got %.40q", "prefix:"+want, got)
+		}
+
+		var buf bytes.Buffer
+		n, err := tokenizer.DecodeTo(&buf, tokens)
+		if err != nil {
+			t.Fatalf("DecodeTo failed: %v", err)
+		}
+		if n != len(want) || buf.String() != want {
+			t.Errorf("DecodeTo: expected %d bytes %.40q, got %d bytes %.40q", len(want), want, n, buf.String())
+		}
+	}
+
+	// Write errors stop the decode
+	writeErr := errors.New("disk full")
+	n, err := tokenizer.DecodeTo(&failingWriter{limit: 100, err: writeErr}, long)
+	if !errors.Is(err, writeErr) || n != 100 {
+		t.Errorf("Expected %v after 100 bytes, got %v after %d", writeErr, err, n)
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = tokenizer.AppendDecode(make([]byte, 0, 64), []int{9906, 11, 1917, 0})
+	})
+	if allocs > 1 {
+		t.Errorf("Expected at most 1 allocation, got %v", allocs)
+	}
+}
+
+// failingWriter accepts limit bytes, then fails with err.
+type failingWriter struct {
+	limit int
+	err   error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, w.err
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
@@ -0,0 +1,82 @@
+package llama3
+
+import (
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// DecorationsVersion is the version of the Decorations JSON format written by
+// this package. It changes only when existing fields change meaning; new
+// fields may be added within a version.
+const DecorationsVersion = 1
+
+// Position encodings for Decorations lengths.
+const (
+	PositionUTF16 = "utf-16" // UTF-16 code units, as used by VS Code and LSP
+	PositionUTF8  = "utf-8"  // bytes
+)
+
+// Decorations describes token boundaries in a compact form for editor
+// plugins. It is the JSON format documented in docs/decorations.md.
+//
+// Token i covers the text from the sum of Lengths[:i] to the sum of
+// Lengths[:i+1], counted in Encoding units. With UTF-16 positions, a token
+// boundary inside a character is moved to the start of that character, so a
+// token can have length 0.
+type Decorations struct {
+	Version  int    `json:"version"`
+	Encoding string `json:"encoding"`
+	Count    int    `json:"count"`
+	IDs      []int  `json:"ids"`
+	Lengths  []int  `json:"lengths"`
+}
+
+// Decorations returns the token boundaries of text for editor decorations.
+// tokens must be the encoding of text without BOS and EOS tokens, as returned
+// by Retokenize. encoding is PositionUTF16 or PositionUTF8.
+func (t *Tokenizer) Decorations(text string, tokens []int, encoding string) (*Decorations, error) {
+	if encoding != PositionUTF16 && encoding != PositionUTF8 {
+		return nil, NewConfigError("encoding", encoding, fmt.Errorf("must be %q or %q", PositionUTF16, PositionUTF8))
+	}
+
+	d := &Decorations{
+		Version:  DecorationsVersion,
+		Encoding: encoding,
+		Count:    len(tokens),
+		IDs:      tokens,
+		Lengths:  make([]int, len(tokens)),
+	}
+
+	// end is the byte offset of the current token's end; offset and units
+	// track the last whole character before it.
+	end, offset, units := 0, 0, 0
+	for i, id := range tokens {
+		n, ok := t.tokenLen(id)
+		if !ok {
+			return nil, NewTokenIDError("decorations", id, ErrInvalidTokenID)
+		}
+		if end += n; end > len(text) {
+			break
+		}
+		if encoding == PositionUTF8 {
+			d.Lengths[i] = n
+			continue
+		}
+
+		start := units
+		for offset < end {
+			r, size := utf8.DecodeRuneInString(text[offset:])
+			if offset+size > end {
+				break
+			}
+			offset += size
+			units += utf16.RuneLen(r)
+		}
+		d.Lengths[i] = units - start
+	}
+	if end != len(text) {
+		return nil, NewTokenError("decorations", "", fmt.Errorf("tokens cover %d bytes of a %d byte text", end, len(text)))
+	}
+	return d, nil
+}
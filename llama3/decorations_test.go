@@ -0,0 +1,70 @@
+package llama3
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDecorations(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	text := "Hi 😀 there"
+	tokens := tokenizer.Encode(text, noSpecialTokens)
+
+	tests := []struct {
+		encoding string
+		total    int
+	}{
+		{PositionUTF16, 11},
+		{PositionUTF8, len(text)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			d, err := tokenizer.Decorations(text, tokens, tt.encoding)
+			if err != nil {
+				t.Fatalf("Decorations failed: %v", err)
+			}
+			if d.Version != DecorationsVersion || d.Encoding != tt.encoding || d.Count != len(tokens) {
+				t.Errorf("Expected version %d, %s and %d tokens, got %+v", DecorationsVersion, tt.encoding, len(tokens), d)
+			}
+			total := 0
+			for _, n := range d.Lengths {
+				total += n
+			}
+			if total != tt.total {
+				t.Errorf("Expected lengths to add up to %d, got %d (%v)", tt.total, total, d.Lengths)
+			}
+		})
+	}
+
+	d, _ := tokenizer.Decorations("Hello", []int{9906}, PositionUTF16)
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := `{"version":1,"encoding":"utf-16","count":1,"ids":[9906],"lengths":[5]}`; string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+}
+
+func TestDecorationsErrors(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	var configErr *ConfigError
+	if _, err := tokenizer.Decorations("Hello", []int{9906}, "utf-32"); !errors.As(err, &configErr) {
+		t.Errorf("Expected ConfigError for an unknown encoding, got %v", err)
+	}
+	if _, err := tokenizer.Decorations("Hello", []int{-1}, PositionUTF8); !errors.Is(err, ErrInvalidTokenID) {
+		t.Errorf("Expected ErrInvalidTokenID, got %v", err)
+	}
+	if _, err := tokenizer.Decorations("Hello!", []int{9906}, PositionUTF8); err == nil {
+		t.Error("Expected an error for tokens that do not cover the text")
+	}
+}
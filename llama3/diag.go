@@ -0,0 +1,55 @@
+package llama3
+
+// EncodeDiag receives diagnostics of one encode call, to find out why a
+// particular request is slow without profiling the whole process. Set
+// EncodeOptions.Diag to a zero EncodeDiag; Encode and AppendTokens reset
+// and fill it. Options carrying a Diag must not be shared between
+// concurrent calls.
+type EncodeDiag struct {
+	Pretokens       int    // pre-tokens passed to BPE
+	LongestPretoken string // text of the longest pre-token
+	SpecialTokens   int    // special tokens matched in the text, excluding BOS/EOS
+	CacheHits       int    // pre-tokens found in the BPE cache
+	CacheMisses     int    // pre-tokens merged by BPE
+
+	// TokenLengths[n] is the number of tokens of n bytes produced from the
+	// text, excluding special tokens.
+	TokenLengths []int
+}
+
+// appendBPE appends the tokens of pretoken to dst, recording it in diag if
+// diag is not nil.
+func (t *Tokenizer) appendBPE(dst []int, pretoken string, diag *EncodeDiag) []int {
+	if diag == nil {
+		return append(dst, t.performBPE(pretoken)...)
+	}
+
+	diag.Pretokens++
+	// Pre-tokens are byte-level encoded, with up to 2 bytes per input byte
+	if len(pretoken) > len(diag.LongestPretoken) {
+		if text := string(decodeTokenBytes(pretoken)); len(text) > len(diag.LongestPretoken) {
+			diag.LongestPretoken = text
+		}
+	}
+
+	var tokens []int
+	cached := false
+	if cache := t.processor.Cache; cache != nil {
+		tokens, cached = cache.Get(pretoken)
+	}
+	if cached {
+		diag.CacheHits++
+	} else {
+		diag.CacheMisses++
+		tokens = t.processor.PerformBPEMiss(pretoken)
+	}
+
+	for _, id := range tokens {
+		n, _ := t.tokenLen(id)
+		for len(diag.TokenLengths) <= n {
+			diag.TokenLengths = append(diag.TokenLengths, 0)
+		}
+		diag.TokenLengths[n]++
+	}
+	return append(dst, tokens...)
+}
@@ -0,0 +1,70 @@
+package llama3
+
+import (
+	"testing"
+
+	"github.com/agentstation/tokenizer/bpe"
+)
+
+func TestEncodeDiag(t *testing.T) {
+	tokenizer, err := New(WithCacheSize(100))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	text := "hello world<|eot_id|> hello internationalization"
+	diag := &EncodeDiag{}
+	opts := &EncodeOptions{BOS: true, EOS: true, Diag: diag}
+	tokens := tokenizer.Encode(text, opts)
+
+	if !equalIntSlices(tokens, tokenizer.Encode(text, nil)) {
+		t.Errorf("Expected Diag not to change the tokens, got %v", tokens)
+	}
+	if diag.Pretokens != 4 {
+		t.Errorf("Expected 4 pretokens, got %d", diag.Pretokens)
+	}
+	if diag.LongestPretoken != " internationalization" {
+		t.Errorf("Expected longest pretoken %q, got %q", " internationalization", diag.LongestPretoken)
+	}
+	if diag.SpecialTokens != 1 {
+		t.Errorf("Expected 1 special token, got %d", diag.SpecialTokens)
+	}
+	// "hello" and " hello" are different pretokens, so nothing repeats yet
+	if diag.CacheHits != 0 || diag.CacheMisses != 4 {
+		t.Errorf("Expected 0 hits and 4 misses, got %d and %d", diag.CacheHits, diag.CacheMisses)
+	}
+	sum := 0
+	for _, n := range diag.TokenLengths {
+		sum += n
+	}
+	if want := len(tokens) - 3; sum != want { // BOS, <|eot_id|> and EOS
+		t.Errorf("Expected %d tokens in the histogram, got %d", want, sum)
+	}
+
+	// Diag is reset by each call
+	tokenizer.AppendTokens(nil, text, opts)
+	if diag.Pretokens != 4 || diag.CacheHits != 4 || diag.CacheMisses != 0 {
+		t.Errorf("Expected 4 pretokens, all cached, got %+v", diag)
+	}
+
+	cache := &countingCache{Cache: bpe.NewLRU(10)}
+	view := tokenizer.WithCache(cache)
+	view.Encode(text, opts)
+	if diag.CacheHits != 0 {
+		t.Errorf("Expected a view to use its own cache, got %d hits", diag.CacheHits)
+	}
+	if cache.gets != diag.Pretokens {
+		t.Errorf("Expected one cache lookup per pretoken, got %d for %d", cache.gets, diag.Pretokens)
+	}
+}
+
+// countingCache counts the lookups of a cache.
+type countingCache struct {
+	bpe.Cache
+	gets int
+}
+
+func (c *countingCache) Get(key string) ([]int, bool) {
+	c.gets++
+	return c.Cache.Get(key)
+}
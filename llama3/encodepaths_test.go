@@ -0,0 +1,145 @@
+package llama3
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	testutils "github.com/agentstation/tokenizer/llama3/internal/testing"
+)
+
+// encodePaths returns every way of encoding a text to its tokens, so tests
+// can check that they agree with Encode.
+func encodePaths(tb testing.TB, tokenizer *Tokenizer) map[string]func(text string, opts *EncodeOptions) []int {
+	session := tokenizer.NewEncodeSession()
+	ctx, cancel := context.WithCancel(context.Background()) // checked, but not canceled
+	tb.Cleanup(cancel)
+	return map[string]func(string, *EncodeOptions) []int{
+		"AppendTokens": func(text string, opts *EncodeOptions) []int {
+			return tokenizer.AppendTokens(nil, text, opts)
+		},
+		"AppendTokens with prefix": func(text string, opts *EncodeOptions) []int {
+			prefix := []int{1, 2, 3}
+			return tokenizer.AppendTokens(prefix[:3:3], text, opts)[3:]
+		},
+		"EncodeSession": func(text string, opts *EncodeOptions) []int {
+			return append([]int(nil), session.Encode(text, opts)...)
+		},
+		"EncodeContext": func(text string, opts *EncodeOptions) []int {
+			tokens, err := tokenizer.EncodeContext(ctx, text, opts)
+			if err != nil {
+				return nil
+			}
+			return tokens
+		},
+		"EncodeBatch": func(text string, opts *EncodeOptions) []int {
+			return tokenizer.EncodeBatch([]string{text}, opts)[0]
+		},
+		"EncodeWords": func(text string, opts *EncodeOptions) []int {
+			tokens, _ := tokenizer.EncodeWords(text, opts)
+			return tokens
+		},
+		"Encode with Diag": func(text string, opts *EncodeOptions) []int {
+			diagOpts := *defaultEncodeOptions()
+			if opts != nil {
+				diagOpts = *opts
+			}
+			diagOpts.Diag = &EncodeDiag{}
+			return tokenizer.Encode(text, &diagOpts)
+		},
+		"EncodeWithOffsets": func(text string, opts *EncodeOptions) []int {
+			var tokens []int
+			for _, span := range tokenizer.EncodeWithOffsets(text, opts) {
+				tokens = append(tokens, span.ID)
+			}
+			return tokens
+		},
+	}
+}
+
+// encodePathOptions are the options the encode paths are compared under.
+var encodePathOptions = []*EncodeOptions{
+	nil,
+	noSpecialTokens,
+	{BOS: true, EOS: true, SuffixSpecials: []string{"<|eot_id|>"}},
+}
+
+func checkEncodePaths(t *testing.T, tokenizer *Tokenizer, paths map[string]func(string, *EncodeOptions) []int, text string) {
+	t.Helper()
+	for _, opts := range encodePathOptions {
+		want := tokenizer.Encode(text, opts)
+		for name, encode := range paths {
+			if got := encode(text, opts); !equalIntSlices(got, want) {
+				t.Errorf("%s(%q, %+v): expected %v, got %v", name, text, opts, want, got)
+			}
+		}
+		if got := tokenizer.Count(text, opts); got != len(want) {
+			t.Errorf("Count(%q, %+v): expected %d, got %d", text, opts, len(want), got)
+		}
+	}
+
+	// OptimisticCount agrees with Encode when every pseudo-special token is
+	// a special token
+	known := true
+	for _, seg := range tokenizer.appendOptimisticSegments(nil, text) {
+		if _, ok := tokenizer.specialTokenID(seg.Text); seg.Special && !ok {
+			known = false
+		}
+	}
+	if want := len(tokenizer.Encode(text, nil)); known && tokenizer.OptimisticCount(text) != want {
+		t.Errorf("OptimisticCount(%q): expected %d, got %d", text, want, tokenizer.OptimisticCount(text))
+	}
+
+	// Chat content is encoded like text without special tokens
+	special := false
+	for _, seg := range appendSegments(nil, text, tokenizer.hasSpecialToken) {
+		special = special || seg.Special
+	}
+	if want := tokenizer.Encode(text, noSpecialTokens); !special && !equalIntSlices(tokenizer.appendText(nil, text), want) {
+		t.Errorf("appendText(%q): expected %v, got %v", text, want, tokenizer.appendText(nil, text))
+	}
+}
+
+func TestEncodePathsAgree(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	atomic, err := New(WithAtomicPatterns(`Case \d+`, `https?://\S+`))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	texts := []string{
+		"See Case 1234 at https://example.com/a?b=c <|eot_id|>Case 5",
+		"<|begin_of_text|><|start_header_id|>user<|end_header_id|>\n\nHi<|eot_id|>",
+		"<|unknown_special|> text <|eot_id",
+		strings.Repeat("Long text with numbers 12345 and spaces   \n", 500),
+		"invalid \xff\xfe utf-8",
+	}
+	for _, tc := range testutils.GenerateTestCases() {
+		texts = append(texts, tc.Input)
+	}
+	for _, tok := range []*Tokenizer{tokenizer, atomic} {
+		paths := encodePaths(t, tok)
+		for _, text := range texts {
+			checkEncodePaths(t, tok, paths, text)
+		}
+	}
+}
+
+func FuzzEncodePaths(f *testing.F) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		f.Skip("Skipping tests: Llama 3 data not available")
+	}
+	paths := encodePaths(f, tokenizer)
+
+	for _, tc := range testutils.GenerateTestCases() {
+		f.Add(tc.Input)
+	}
+	f.Add("<|begin_of_text|>Hello<|eot_id|>")
+	f.Fuzz(func(t *testing.T, text string) {
+		checkEncodePaths(t, tokenizer, paths, text)
+	})
+}
@@ -3,6 +3,8 @@ package llama3
 import (
 	"errors"
 	"fmt"
+
+	"github.com/agentstation/tokenizer/llama3/internal/vocabulary"
 )
 
 // Common errors.
@@ -18,6 +20,9 @@ var (
 
 	// ErrInvalidTokenID indicates an invalid token ID was provided.
 	ErrInvalidTokenID = errors.New("invalid token ID")
+
+	// ErrLimitExceeded indicates vocabulary data exceeded a LoadLimits field.
+	ErrLimitExceeded = vocabulary.ErrLimitExceeded
 )
 
 // DataError represents an error related to tokenizer data loading or processing.
@@ -40,16 +45,20 @@ func (e *DataError) Unwrap() error {
 
 // TokenError represents an error related to token operations.
 type TokenError struct {
-	Token   string // The token that caused the error
-	TokenID int    // The token ID if applicable
-	Op      string // Operation that failed
-	Err     error  // Underlying error
+	Token    string // The token that caused the error
+	TokenID  int    // The token ID if applicable
+	Position int    // Index of the token in its sequence, -1 if not applicable
+	Op       string // Operation that failed
+	Err      error  // Underlying error
 }
 
 func (e *TokenError) Error() string {
 	if e.Token != "" {
 		return fmt.Sprintf("token error: %s %q: %v", e.Op, e.Token, e.Err)
 	}
+	if e.Position >= 0 {
+		return fmt.Sprintf("token error: %s token_id=%d at position %d: %v", e.Op, e.TokenID, e.Position, e.Err)
+	}
 	if e.TokenID != 0 {
 		return fmt.Sprintf("token error: %s token_id=%d: %v", e.Op, e.TokenID, e.Err)
 	}
@@ -84,12 +93,18 @@ func NewDataError(op, path string, err error) error {
 
 // NewTokenError creates a new TokenError.
 func NewTokenError(op, token string, err error) error {
-	return &TokenError{Op: op, Token: token, Err: err}
+	return &TokenError{Op: op, Token: token, Position: -1, Err: err}
 }
 
 // NewTokenIDError creates a new TokenError with a token ID.
 func NewTokenIDError(op string, tokenID int, err error) error {
-	return &TokenError{Op: op, TokenID: tokenID, Err: err}
+	return &TokenError{Op: op, TokenID: tokenID, Position: -1, Err: err}
+}
+
+// NewTokenPositionError creates a new TokenError for the token ID at a
+// position of a sequence.
+func NewTokenPositionError(op string, tokenID, position int, err error) error {
+	return &TokenError{Op: op, TokenID: tokenID, Position: position, Err: err}
 }
 
 // NewConfigError creates a new ConfigError.
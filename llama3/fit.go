@@ -0,0 +1,154 @@
+package llama3
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// fitLookahead is the number of runes past maxRunes that FitString encodes,
+// so the tokens around the cut are the same as in the full text.
+const fitLookahead = 64
+
+// FitString returns the longest prefix of text that has at most maxTokens
+// tokens (excluding BOS/EOS) and at most maxRunes runes, for UI snippets
+// that must respect a model budget and a layout at once. A limit of zero or
+// less is not enforced.
+//
+// The prefix never ends inside a token of text's encoding or inside a
+// grapheme cluster, such as a letter with combining accents or an emoji
+// sequence, so it may be shorter than the limits allow. Grapheme clusters
+// follow the main rules of Unicode text segmentation (UAX #29).
+func (t *Tokenizer) FitString(text string, maxTokens, maxRunes int) string {
+	if maxRunes > 0 {
+		// Only the text up to the rune limit can be kept
+		end, n := 0, 0
+		for end < len(text) && n < maxRunes+fitLookahead {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+			n++
+		}
+		text = text[:end]
+	}
+
+	tokens := t.Encode(text, noSpecialTokens)
+	if (maxTokens <= 0 || len(tokens) <= maxTokens) && (maxRunes <= 0 || utf8.RuneCountInString(text) <= maxRunes) {
+		return text
+	}
+
+	// Candidate cuts are the token ends within both limits, longest first.
+	// Token ends are offsets in the encoded text, in which each invalid byte
+	// of text is U+FFFD.
+	encoded, inputOffset := encodedText(text), encodedOffsets(text)
+	ends := make([]int, 0, len(tokens))
+	end, runes := 0, 0
+	for i, id := range tokens {
+		if maxTokens > 0 && i >= maxTokens {
+			break
+		}
+		token, _ := t.tokenBytes(id)
+		runes += utf8.RuneCount(token)
+		if maxRunes > 0 && runes > maxRunes {
+			break
+		}
+		end += len(token)
+		ends = append(ends, end)
+	}
+
+	for i := len(ends) - 1; i >= 0; i-- {
+		if ends[i] < len(encoded) && !utf8.RuneStart(encoded[ends[i]]) || !graphemeBoundary(encoded, ends[i]) {
+			continue
+		}
+		prefix := text[:inputOffset(ends[i])]
+		// A prefix encodes to the same tokens except, rarely, at its end
+		if maxTokens <= 0 || len(t.Encode(prefix, noSpecialTokens)) <= maxTokens {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// graphemeBoundary reports whether a grapheme cluster boundary falls at
+// byte offset i of s, which must be at a rune boundary.
+func graphemeBoundary(s string, i int) bool {
+	if i <= 0 || i >= len(s) {
+		return true
+	}
+	prev, _ := utf8.DecodeLastRuneInString(s[:i])
+	next, _ := utf8.DecodeRuneInString(s[i:])
+
+	switch {
+	case prev == '\r' && next == '\n':
+		return false
+	case prev == '\r' || prev == '\n' || next == '\r' || next == '\n':
+		return true
+	case isGraphemeExtend(next):
+		return false
+	case prev == '\u200d':
+		return false // zero-width joiner sequences, as in emoji
+	case isHangulJamo(next) && (isHangulJamo(prev) || isHangulSyllable(prev)):
+		return !hangulJoins(prev, next)
+	case isRegionalIndicator(prev) && isRegionalIndicator(next):
+		// Flags are pairs of regional indicators: break after an even count
+		count := 0
+		for j := i; j > 0; {
+			r, size := utf8.DecodeLastRuneInString(s[:j])
+			if !isRegionalIndicator(r) {
+				break
+			}
+			count++
+			j -= size
+		}
+		return count%2 == 0
+	case isHangulSyllable(next) && prev >= 0x1100 && prev <= 0x115F:
+		return false // a leading consonant joins a syllable
+	}
+	return true
+}
+
+// isGraphemeExtend reports whether r extends the preceding grapheme cluster:
+// combining marks, joiners, variation selectors and emoji modifiers and tags.
+func isGraphemeExtend(r rune) bool {
+	switch {
+	case r == '\u200c' || r == '\u200d':
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F, r >= 0xE0100 && r <= 0xE01EF:
+		return true // variation selectors
+	case r >= 0x1F3FB && r <= 0x1F3FF:
+		return true // emoji skin tone modifiers
+	case r >= 0xE0020 && r <= 0xE007F:
+		return true // emoji tags
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc)
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+func isHangulJamo(r rune) bool {
+	return r >= 0x1100 && r <= 0x11FF
+}
+
+func isHangulSyllable(r rune) bool {
+	return r >= 0xAC00 && r <= 0xD7A3
+}
+
+// hangulJoins reports whether the conjoining jamo next continues the
+// syllable ending in prev.
+func hangulJoins(prev, next rune) bool {
+	leading := func(r rune) bool { return r >= 0x1100 && r <= 0x115F }
+	vowel := func(r rune) bool { return r >= 0x1160 && r <= 0x11A7 }
+	trailing := func(r rune) bool { return r >= 0x11A8 && r <= 0x11FF }
+	// A syllable without a final consonant (LV) is a multiple of 28 from U+AC00
+	lv := isHangulSyllable(prev) && (prev-0xAC00)%28 == 0
+
+	switch {
+	case leading(prev):
+		return leading(next) || vowel(next)
+	case vowel(prev) || lv:
+		return vowel(next) || trailing(next)
+	case trailing(prev) || isHangulSyllable(prev):
+		return trailing(next)
+	}
+	return false
+}
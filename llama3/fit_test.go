@@ -0,0 +1,119 @@
+package llama3
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFitString(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		maxRunes  int
+		want      string
+	}{
+		{"fits", "Hello, world!", 10, 20, "Hello, world!"},
+		{"no limits", "Hello, world!", 0, 0, "Hello, world!"},
+		{"token limit", "Hello, world!", 2, 0, "Hello,"},
+		{"rune limit", "Hello, world!", 0, 9, "Hello,"},
+		{"rune limit inside a token", "The quick brown fox", 0, 12, "The quick"},
+		{"both limits", "The quick brown fox", 3, 15, "The quick brown"},
+		{"rune limit wins", "The quick brown fox", 10, 11, "The quick"},
+		{"zero runes fit", "Hello", 0, 1, ""},
+		{"special tokens are text", "<|eot_id|>Hello", 1, 0, "<|eot_id|>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tokenizer.FitString(tt.text, tt.maxTokens, tt.maxRunes)
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFitStringLimits(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	text := strings.Repeat("Grüße aus Köln, cafe\u0301, 日本語のテキスト, 🇯🇵🇫🇷 and 👋🏽👩\u200d👩\u200d👧 too. ", 40)
+
+	for _, maxTokens := range []int{0, 1, 7, 50, 400} {
+		for _, maxRunes := range []int{0, 1, 13, 100, 1000} {
+			got := tokenizer.FitString(text, maxTokens, maxRunes)
+			if !strings.HasPrefix(text, got) || !utf8.ValidString(got) {
+				t.Fatalf("FitString(%d, %d) = %q is not a valid prefix", maxTokens, maxRunes, got)
+			}
+			if n := len(tokenizer.Encode(got, noSpecialTokens)); maxTokens > 0 && n > maxTokens {
+				t.Errorf("FitString(%d, %d) has %d tokens", maxTokens, maxRunes, n)
+			}
+			if n := utf8.RuneCountInString(got); maxRunes > 0 && n > maxRunes {
+				t.Errorf("FitString(%d, %d) has %d runes", maxTokens, maxRunes, n)
+			}
+			if !graphemeBoundary(text, len(got)) {
+				t.Errorf("FitString(%d, %d) = %q ends inside a grapheme cluster", maxTokens, maxRunes, got)
+			}
+		}
+	}
+}
+
+func TestFitStringInvalidUTF8(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	for _, text := range []string{"ab\xff\xfe\xfd\xfc cd ef gh ij", "\x80\x80 x\xe6\x97 y \xff", "caf\xc3 ok ok ok"} {
+		for _, maxTokens := range []int{0, 1, 2, 3, 4, 8} {
+			for _, maxRunes := range []int{0, 1, 3, 6, 10} {
+				got := tokenizer.FitString(text, maxTokens, maxRunes)
+				if !strings.HasPrefix(text, got) {
+					t.Fatalf("FitString(%q, %d, %d) = %q is not a prefix", text, maxTokens, maxRunes, got)
+				}
+				if n := len(tokenizer.Encode(got, noSpecialTokens)); maxTokens > 0 && n > maxTokens {
+					t.Errorf("FitString(%q, %d, %d) = %q has %d tokens", text, maxTokens, maxRunes, got, n)
+				}
+				if n := utf8.RuneCountInString(got); maxRunes > 0 && n > maxRunes {
+					t.Errorf("FitString(%q, %d, %d) = %q has %d runes", text, maxTokens, maxRunes, got, n)
+				}
+			}
+		}
+	}
+	if got := tokenizer.FitString("ab\xff\xfe\xfd\xfc cd ef gh ij", 4, 0); got == "" {
+		t.Error("Expected a non-empty prefix")
+	}
+}
+
+func TestGraphemeBoundary(t *testing.T) {
+	tests := []struct {
+		text string
+		at   int
+		want bool
+	}{
+		{"ab", 1, true},
+		{"\r\n", 1, false},
+		{"é", 1, false},
+		{"👩‍👧", len("👩"), false},
+		{"👩‍👧", len("👩‍"), false},
+		{"👋🏽", len("👋"), false},
+		{"🇯🇵🇫🇷", len("🇯🇵"), true},
+		{"🇯🇵🇫🇷", len("🇯"), false},
+		{"각", len("ᄀ"), false},
+		{"각", len("가"), false},
+		{"한국", len("한"), true},
+	}
+	for _, tt := range tests {
+		if got := graphemeBoundary(tt.text, tt.at); got != tt.want {
+			t.Errorf("graphemeBoundary(%q, %d): expected %v, got %v", tt.text, tt.at, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package llama3
+
+import (
+	"io"
+	"slices"
+
+	"github.com/agentstation/tokenizer/bpe"
+	"github.com/agentstation/tokenizer/llama3/internal/gguf"
+	"github.com/agentstation/tokenizer/llama3/internal/vocabulary"
+)
+
+// GGUF token types of tokenizer.ggml.token_type, as defined by llama.cpp.
+const (
+	ggufTokenNormal  int32 = 1
+	ggufTokenControl int32 = 3
+)
+
+// GGUFKeyValue is a key-value pair of GGUF metadata, the header of llama.cpp
+// model files. Value is a bool, string, sized integer or float, or a slice
+// of one of them, such as []string for the token list.
+type GGUFKeyValue struct {
+	Key   string
+	Value any
+}
+
+// GGUFMetadata returns the tokenizer section of GGUF metadata for the
+// tokenizer's vocabulary, in the form llama.cpp's converter writes for
+// Llama 3 models, so custom GGUF models can be assembled with a tokenizer
+// that matches this one exactly:
+//
+//   - tokenizer.ggml.model "gpt2" and tokenizer.ggml.pre "llama-bpe";
+//   - tokenizer.ggml.tokens, every token in ID order, regular tokens in
+//     their byte-level form (" hello" is "Ġhello");
+//   - tokenizer.ggml.scores, all 0, as BPE vocabularies have no scores;
+//   - tokenizer.ggml.token_type, 1 (normal) for regular tokens and 3
+//     (control) for special tokens;
+//   - tokenizer.ggml.merges, "left right" in priority order;
+//   - the IDs of <|begin_of_text|>, <|end_of_text|> and <|eot_id|> as
+//     tokenizer.ggml.bos_token_id, eos_token_id and eot_token_id, where the
+//     special tokens include them, and tokenizer.ggml.add_bos_token.
+//
+// Tokens added with AddSpecialTokens are included. Chat templates and model
+// parameters are not.
+func (t *Tokenizer) GGUFMetadata() []GGUFKeyValue {
+	size := t.VocabSize()
+	tokens := make([]string, size)
+	types := make([]int32, size)
+	for id := range size {
+		switch {
+		case id < t.regularLen:
+			tokens[id], types[id] = t.tokens[id], ggufTokenNormal
+		case id < len(t.tokens):
+			tokens[id], types[id] = t.tokens[id], ggufTokenControl
+		default:
+			tokens[id], _ = t.addedToken(id)
+			types[id] = ggufTokenControl
+		}
+	}
+
+	type merge struct {
+		key uint64
+		bpe.Merge
+	}
+	ranked := make([]merge, 0, len(t.merges))
+	for key, m := range t.merges {
+		ranked = append(ranked, merge{key, m})
+	}
+	slices.SortFunc(ranked, func(a, b merge) int { return a.Rank - b.Rank })
+	merges := make([]string, len(ranked))
+	for i, m := range ranked {
+		merges[i] = t.getMergeIdentifier(int(m.key>>32), int(m.key&0xffffffff))
+	}
+
+	kvs := []GGUFKeyValue{
+		{"tokenizer.ggml.model", "gpt2"},
+		{"tokenizer.ggml.pre", "llama-bpe"},
+		{"tokenizer.ggml.tokens", tokens},
+		{"tokenizer.ggml.scores", make([]float32, size)},
+		{"tokenizer.ggml.token_type", types},
+		{"tokenizer.ggml.merges", merges},
+	}
+	for _, special := range []struct {
+		key string
+		tok SpecialToken
+	}{
+		{"tokenizer.ggml.bos_token_id", BeginOfText},
+		{"tokenizer.ggml.eos_token_id", EndOfText},
+		{"tokenizer.ggml.eot_token_id", EOT},
+	} {
+		if id := t.specialIDs[special.tok]; id >= 0 {
+			kvs = append(kvs, GGUFKeyValue{special.key, uint32(id)}) // #nosec G115 - token IDs are not negative
+		}
+	}
+	return append(kvs, GGUFKeyValue{"tokenizer.ggml.add_bos_token", t.specialIDs[BeginOfText] >= 0})
+}
+
+// WriteGGUF writes a vocabulary-only GGUF file: general.architecture
+// "llama", the metadata of GGUFMetadata and extra, and no tensors. extra
+// adds keys, such as general.name or the llama.* model parameters llama.cpp
+// needs to load the file; a key that is already present has its value
+// replaced.
+func (t *Tokenizer) WriteGGUF(w io.Writer, extra ...GGUFKeyValue) error {
+	kvs := append([]GGUFKeyValue{{"general.architecture", "llama"}}, t.GGUFMetadata()...)
+	index := make(map[string]int, len(kvs)+len(extra))
+	for i, kv := range kvs {
+		index[kv.Key] = i
+	}
+	for _, kv := range extra {
+		if i, ok := index[kv.Key]; ok {
+			kvs[i] = kv
+			continue
+		}
+		index[kv.Key] = len(kvs)
+		kvs = append(kvs, kv)
+	}
+
+	out := make([]gguf.KeyValue, len(kvs))
+	for i, kv := range kvs {
+		out[i] = gguf.KeyValue(kv)
+	}
+	if err := gguf.Write(w, out); err != nil {
+		return NewDataError("write GGUF", "", err)
+	}
+	return nil
+}
+
+// WithGGUF loads the vocabulary, merges and special tokens from the metadata
+// of a GGUF model file, so the tokenizer matches a local llama.cpp model
+// exactly:
+//
+//	llama3.New(llama3.WithGGUF("Meta-Llama-3-8B-Instruct.Q4_K_M.gguf"))
+//
+// The file must have a byte-level BPE tokenizer (tokenizer.ggml.model
+// "gpt2") whose special tokens, the tokens not of the normal type, follow
+// the regular tokens, as in Llama 3 models and the files written by
+// WriteGGUF. The special tokens are used as with WithHFTokenizerJSON, and
+// pre-tokenization is likewise always Llama 3's. Only the metadata at the
+// start of the file is read, never the tensor data; the MaxFileSize load
+// limit applies to the metadata.
+func WithGGUF(path string) Option {
+	return func(cfg *config) error {
+		if path == "" {
+			return NewConfigError("gguf", path, ErrDataNotFound)
+		}
+		// This will be handled in tokenizer initialization
+		cfg.dataLoader = &ggufLoaderMarker{path: path}
+		return nil
+	}
+}
+
+// ggufLoaderMarker is a placeholder that will be replaced with a
+// ggufVocabularySource once the load limits are known.
+type ggufLoaderMarker struct {
+	path string
+}
+
+func (g *ggufLoaderMarker) LoadVocabulary() ([]string, error) {
+	panic("ggufLoaderMarker should be replaced before use")
+}
+
+func (g *ggufLoaderMarker) LoadMerges() (map[string]int, error) {
+	panic("ggufLoaderMarker should be replaced before use")
+}
+
+func (g *ggufLoaderMarker) source(limits vocabulary.Limits, t *Tokenizer) VocabularyDataLoader {
+	return &ggufVocabularySource{parsedVocabularySource{path: g.path, limits: limits, t: t}}
+}
+
+// ggufVocabularySource loads vocabulary data from a GGUF file. The file is
+// parsed once, by LoadVocabulary.
+type ggufVocabularySource struct {
+	parsedVocabularySource
+}
+
+func (g *ggufVocabularySource) LoadVocabulary() ([]string, error) {
+	data, err := vocabulary.LoadGGUF(g.path, g.limits)
+	if err != nil {
+		return nil, NewDataError("load vocabulary", g.path, err)
+	}
+	g.data = data
+	return data.Vocab, nil
+}
@@ -0,0 +1,134 @@
+package llama3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutils "github.com/agentstation/tokenizer/llama3/internal/testing"
+)
+
+func TestGGUFMetadata(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	kvs := map[string]any{}
+	for _, kv := range tokenizer.GGUFMetadata() {
+		kvs[kv.Key] = kv.Value
+	}
+	tokens, _ := kvs["tokenizer.ggml.tokens"].([]string)
+	types, _ := kvs["tokenizer.ggml.token_type"].([]int32)
+	scores, _ := kvs["tokenizer.ggml.scores"].([]float32)
+	merges, _ := kvs["tokenizer.ggml.merges"].([]string)
+
+	size := tokenizer.VocabSize()
+	if len(tokens) != size || len(types) != size || len(scores) != size {
+		t.Fatalf("Expected %d tokens, types and scores, got %d, %d and %d", size, len(tokens), len(types), len(scores))
+	}
+	hello, _ := tokenizer.TokenID(" hello")
+	if tokens[hello] != "Ġhello" || types[hello] != ggufTokenNormal {
+		t.Errorf("Expected normal token %q, got %q of type %d", "Ġhello", tokens[hello], types[hello])
+	}
+	if eot := tokenizer.EOTTokenID(); tokens[eot] != "<|eot_id|>" || types[eot] != ggufTokenControl {
+		t.Errorf("Expected control token <|eot_id|>, got %q of type %d", tokens[eot], types[eot])
+	}
+	if len(merges) != len(tokenizer.merges) || merges[0] != "Ġ Ġ" {
+		t.Errorf("Expected %d merges starting with %q, got %d starting with %q", len(tokenizer.merges), "Ġ Ġ", len(merges), merges[0])
+	}
+	for key, want := range map[string]any{
+		"tokenizer.ggml.model":         "gpt2",
+		"tokenizer.ggml.pre":           "llama-bpe",
+		"tokenizer.ggml.bos_token_id":  uint32(tokenizer.BOSTokenID()),
+		"tokenizer.ggml.eos_token_id":  uint32(tokenizer.EOSTokenID()),
+		"tokenizer.ggml.eot_token_id":  uint32(tokenizer.EOTTokenID()),
+		"tokenizer.ggml.add_bos_token": true,
+	} {
+		if kvs[key] != want {
+			t.Errorf("%s: expected %v, got %v", key, want, kvs[key])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tokenizer.WriteGGUF(&buf, GGUFKeyValue{"general.name", "test"}, GGUFKeyValue{"tokenizer.ggml.pre", "custom"}); err != nil {
+		t.Fatalf("WriteGGUF failed: %v", err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("GGUF")) || len(data)%32 != 0 {
+		t.Fatalf("Expected an aligned GGUF file, got %d bytes starting %q", len(data), data[:min(4, len(data))])
+	}
+	if n := binary.LittleEndian.Uint64(data[16:]); int(n) != len(kvs)+2 {
+		t.Errorf("Expected %d keys, got %d", len(kvs)+2, n)
+	}
+	if strings.Contains(string(data), "llama-bpe") || !strings.Contains(string(data), "custom") {
+		t.Error("Expected tokenizer.ggml.pre to be replaced")
+	}
+}
+
+func TestWithGGUF(t *testing.T) {
+	reference, err := New()
+	if err != nil || reference.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	var buf bytes.Buffer
+	if err := reference.WriteGGUF(&buf); err != nil {
+		t.Fatalf("WriteGGUF failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	tokenizer, err := New(WithGGUF(path))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if tokenizer.VocabSize() != reference.VocabSize() {
+		t.Errorf("Expected %d tokens, got %d", reference.VocabSize(), tokenizer.VocabSize())
+	}
+	if id, ok := tokenizer.TokenID("<|eot_id|>"); !ok || !tokenizer.IsSpecialID(id) {
+		t.Errorf("Expected <|eot_id|> to be a special token, got %d, %v", id, ok)
+	}
+
+	inputs := []string{"<|begin_of_text|>Hello<|eot_id|>"}
+	for _, tc := range testutils.GenerateTestCases() {
+		inputs = append(inputs, tc.Input)
+	}
+	for _, input := range inputs {
+		want := reference.Encode(input, nil)
+		if got := tokenizer.Encode(input, nil); !equalIntSlices(got, want) {
+			t.Errorf("Encode(%q): expected %v, got %v", input, want, got)
+		}
+	}
+
+	// The metadata limit applies, not the size of the whole file
+	_, err = New(WithGGUF(path), WithLoadLimits(LoadLimits{MaxFileSize: 1 << 10}))
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded, got %v", err)
+	}
+}
+
+func TestWithGGUFErrors(t *testing.T) {
+	dir := t.TempDir()
+	notGGUF := filepath.Join(dir, "model.bin")
+	if err := os.WriteFile(notGGUF, []byte("not a model file"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing.gguf"), notGGUF} {
+		var dataErr *DataError
+		if _, err := New(WithGGUF(path)); !errors.As(err, &dataErr) {
+			t.Errorf("%s: expected DataError, got %v", path, err)
+		}
+	}
+
+	var configErr *ConfigError
+	if _, err := New(WithGGUF("")); !errors.As(err, &configErr) {
+		t.Errorf("Expected ConfigError for an empty path, got %v", err)
+	}
+}
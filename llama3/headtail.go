@@ -0,0 +1,212 @@
+package llama3
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tailBlockSize is the minimum number of bytes read per step when scanning
+// a file backwards for TailTokens.
+const tailBlockSize = 64 * 1024
+
+// HeadTokens returns the first n tokens of the text read from r, without
+// BOS/EOS tokens. Only as much of r is read as needed to produce n tokens,
+// as LimitReaderByTokens does, and the result matches the first n tokens of
+// Encode on the full input unless r holds a pre-token longer than 1MiB.
+func (t *Tokenizer) HeadTokens(r io.Reader, n int) ([]int, error) {
+	if n <= 0 {
+		return []int{}, nil
+	}
+
+	data, err := io.ReadAll(LimitReaderByTokens(r, t, n))
+	if err != nil {
+		return nil, fmt.Errorf("read head: %w", err)
+	}
+
+	tokens := t.Encode(string(data), noSpecialTokens)
+	if len(tokens) > n {
+		tokens = tokens[:n]
+	}
+	return tokens, nil
+}
+
+// TailTokens returns the last n tokens of the file at path, without BOS/EOS
+// tokens. The file is scanned backwards in blocks and only the tail region
+// is tokenized, starting from a pre-token boundary so the result matches the
+// last n tokens of Encode on the full file.
+//
+// At most 1MiB is scanned back for a boundary: text with none for that long,
+// such as a long run of letters, is cut at a character boundary instead,
+// which may change the tokens at the cut. With WithAtomicPatterns, which can
+// match across pre-tokens, the only boundaries are after special tokens.
+func (t *Tokenizer) TailTokens(path string, n int) ([]int, error) {
+	if n <= 0 {
+		return []int{}, nil
+	}
+
+	f, err := os.Open(path) // #nosec G304 - path is provided by the caller
+	if err != nil {
+		return nil, NewDataError("open", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, NewDataError("stat", path, err)
+	}
+	size := info.Size()
+
+	blockSize := int64(tailBlockSize)
+	if perToken := int64(n) * 16; perToken > blockSize {
+		blockSize = perToken
+	}
+
+	b := t.newTailBoundaries()
+
+	// tokens are those of the file from a boundary to the end, and buf
+	// holds the bytes from offset up to that boundary, not yet tokenized.
+	// Each byte is tokenized once and searched for a boundary once, and buf
+	// is cut after maxPendingCountBytes, so scanning back is linear in the
+	// bytes read.
+	var tokens []int
+	var buf []byte
+	offset := size
+	for {
+		start := max(offset-blockSize, 0)
+
+		block := make([]byte, offset-start, offset-start+int64(len(buf)))
+		if _, err := f.ReadAt(block, start); err != nil && err != io.EOF {
+			return nil, NewDataError("read", path, err)
+		}
+		buf = append(block, buf...)
+		offset = start
+
+		// Tokenize from the first boundary unless we reached the start.
+		// Offsets below b.margin lack the context to decide, so the new
+		// block and the margin of the previous one are searched.
+		cut := 0
+		if offset > 0 {
+			cut = b.first(buf, b.margin, min(len(block)+b.margin, len(buf)))
+			if cut == 0 && len(buf) > maxPendingCountBytes {
+				cut = firstRuneStart(buf, b.margin)
+			}
+		}
+		if offset == 0 || cut > 0 {
+			tokens = append(t.Encode(string(buf[cut:]), noSpecialTokens), tokens...)
+			buf = buf[:cut]
+		}
+		if len(tokens) >= n || offset == 0 {
+			if len(tokens) > n {
+				tokens = tokens[len(tokens)-n:]
+			}
+			return tokens, nil
+		}
+	}
+}
+
+// tailBoundaries finds offsets at which Encode starts a new pre-token
+// whatever text precedes them.
+type tailBoundaries struct {
+	special [][]byte // Special tokens, which Encode matches before pre-tokenizing
+	atomic  bool     // Atomic patterns can span pre-tokens: only special tokens end one
+	margin  int      // Bytes of preceding context needed to decide an offset
+}
+
+func (t *Tokenizer) newTailBoundaries() *tailBoundaries {
+	b := &tailBoundaries{atomic: t.atomic != nil, margin: utf8.UTFMax}
+	for _, s := range t.specialTokens() {
+		b.special = append(b.special, []byte(s))
+		b.margin = max(b.margin, len(s))
+	}
+	return b
+}
+
+// first returns the first boundary in buf at an offset in [from, to), or 0
+// if there is none.
+func (b *tailBoundaries) first(buf []byte, from, to int) int {
+	for i := from; i < to; i++ {
+		if b.isBoundary(buf, i) {
+			return i
+		}
+	}
+	return 0
+}
+
+// isBoundary reports whether offset i of buf is a boundary. Offsets inside
+// a special token never are.
+func (b *tailBoundaries) isBoundary(buf []byte, i int) bool {
+	if b.atomic {
+		return b.endsSpecial(buf[:i]) && !b.inSpecial(buf, i)
+	}
+	return isPreTokenBoundary(buf, i) && !b.inSpecial(buf, i)
+}
+
+// endsSpecial reports whether text ends with a special token.
+func (b *tailBoundaries) endsSpecial(text []byte) bool {
+	for _, s := range b.special {
+		if bytes.HasSuffix(text, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// inSpecial reports whether an occurrence of a special token spans offset i
+// of buf.
+func (b *tailBoundaries) inSpecial(buf []byte, i int) bool {
+	for _, s := range b.special {
+		from := max(i-len(s)+1, 0)
+		to := min(i+len(s)-1, len(buf))
+		if from < to && bytes.Contains(buf[from:to], s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPreTokenBoundary reports whether the Llama 3 pre-tokenizer starts a
+// pre-token at offset i of buf however the text before buf continues. No
+// pattern looks behind the position it matches at, so this holds wherever
+// the pre-token that ends at i cannot continue past it:
+//
+//   - a letter run ends before a non-letter, and a digit run before a non-digit
+//   - a newline ends before anything but whitespace
+//   - a punctuation run ends before a digit or before whitespace other than a
+//     newline, which it could otherwise take
+//
+// Punctuation before a letter is not a boundary, as it can prefix the word,
+// and neither is any offset next to an invalid UTF-8 sequence.
+func isPreTokenBoundary(buf []byte, i int) bool {
+	a, _ := utf8.DecodeLastRune(buf[:i])
+	b, _ := utf8.DecodeRune(buf[i:])
+	if a == utf8.RuneError || b == utf8.RuneError {
+		return false
+	}
+	switch {
+	case unicode.IsLetter(a):
+		return !unicode.IsLetter(b)
+	case unicode.IsDigit(a):
+		return !unicode.IsDigit(b)
+	case a == '\r' || a == '\n':
+		return !unicode.IsSpace(b)
+	case unicode.IsSpace(a):
+		return false
+	default:
+		return unicode.IsDigit(b) || (unicode.IsSpace(b) && b != '\r' && b != '\n')
+	}
+}
+
+// firstRuneStart returns the first offset at or after from that starts a
+// UTF-8 sequence, or 0 if there is none.
+func firstRuneStart(buf []byte, from int) int {
+	for i := from; i < len(buf); i++ {
+		if utf8.RuneStart(buf[i]) {
+			return i
+		}
+	}
+	return 0
+}
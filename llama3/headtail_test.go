@@ -0,0 +1,209 @@
+package llama3
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHeadTokens(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	input := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 500) + "Fin."
+	full := tokenizer.Encode(input, &EncodeOptions{BOS: false, EOS: false})
+
+	for _, n := range []int{0, 1, 17, 100, len(full), len(full) + 10} {
+		got, err := tokenizer.HeadTokens(strings.NewReader(input), n)
+		if err != nil {
+			t.Fatalf("HeadTokens(%d) error: %v", n, err)
+		}
+
+		want := full
+		if n < len(full) {
+			want = full[:n]
+		}
+		if !equalIntSlices(got, want) {
+			t.Errorf("HeadTokens(%d) = %d tokens, want %d", n, len(got), len(want))
+		}
+	}
+}
+
+func TestTailTokens(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	input := "Start. " + strings.Repeat("Hello 世界, the quick brown fox! ", 5000) + "End of file.\n"
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	full := tokenizer.Encode(input, &EncodeOptions{BOS: false, EOS: false})
+
+	for _, n := range []int{0, 1, 25, 10000, len(full), len(full) + 10} {
+		got, err := tokenizer.TailTokens(path, n)
+		if err != nil {
+			t.Fatalf("TailTokens(%d) error: %v", n, err)
+		}
+
+		want := full
+		if n < len(full) {
+			want = full[len(full)-n:]
+		}
+		if !equalIntSlices(got, want) {
+			t.Errorf("TailTokens(%d) = %d tokens, want %d", n, len(got), len(want))
+		}
+	}
+
+	path = filepath.Join(t.TempDir(), "tail.txt")
+
+	t.Run("unicode_spaces", func(t *testing.T) {
+		// Blocks start inside characters such as U+3000, and whitespace runs
+		// mix Unicode spaces with ASCII ones
+		input := strings.Repeat("x\u3000  yy a\u00a0  b 世界 ", 9000)
+		// The first block of a file of tailBlockSize+2 bytes starts inside the
+		// U+00A0 at offset 1, and has too few tokens to stop there
+		exact := "a\u00a0  b " + strings.Repeat("=", tailBlockSize-len("a\u00a0  b ")+2)
+		for _, input := range []string{input, exact} {
+			if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+				t.Fatalf("Failed to write input: %v", err)
+			}
+			full := tokenizer.Encode(input, noSpecialTokens)
+			for _, n := range []int{1, 3, 5000, 30000, len(full)} {
+				n = min(n, len(full))
+				got, err := tokenizer.TailTokens(path, n)
+				if err != nil {
+					t.Fatalf("TailTokens(%d) error: %v", n, err)
+				}
+				if !equalIntSlices(got, full[len(full)-n:]) {
+					t.Errorf("TailTokens(%d) differs from the end of Encode", n)
+				}
+			}
+		}
+	})
+
+	t.Run("random", func(t *testing.T) {
+		// Blocks start anywhere in letters, digit runs, punctuation,
+		// newlines and special tokens
+		parts := []string{"a", "Zoë", "世界", "123", "4", ",", "...", "(", "'s", " ", "  ", "\n", "\r\n", "\t", "\u00a0", "<|eot_id|>", "<|", "|>", "\xff"}
+		rng := rand.New(rand.NewSource(1))
+		for range 3 {
+			var b strings.Builder
+			for b.Len() < 3*tailBlockSize {
+				b.WriteString(parts[rng.Intn(len(parts))])
+			}
+			input := b.String()
+			if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+				t.Fatalf("Failed to write input: %v", err)
+			}
+			full := tokenizer.Encode(input, noSpecialTokens)
+			for _, n := range []int{1, 7, 5000, 40000, len(full)} {
+				n = min(n, len(full))
+				got, err := tokenizer.TailTokens(path, n)
+				if err != nil {
+					t.Fatalf("TailTokens(%d) error: %v", n, err)
+				}
+				if !equalIntSlices(got, full[len(full)-n:]) {
+					t.Errorf("TailTokens(%d) differs from the end of Encode", n)
+				}
+			}
+		}
+	})
+
+	t.Run("no_spaces", func(t *testing.T) {
+		// Text without spaces has boundaries between letters and
+		// punctuation, and a file that is one pre-token is cut after
+		// a bounded scan rather than read whole
+		csv := strings.Repeat("field,42;", 1<<20)
+		run := strings.Repeat("x", 8<<20) + " end."
+		for _, tc := range []struct {
+			input string
+			n     int
+		}{{csv, 3}, {run, 2}} {
+			if err := os.WriteFile(path, []byte(tc.input), 0600); err != nil {
+				t.Fatalf("Failed to write input: %v", err)
+			}
+			got, err := tokenizer.TailTokens(path, tc.n)
+			if err != nil {
+				t.Fatalf("TailTokens(%d) error: %v", tc.n, err)
+			}
+			want := tokenizer.Encode(tc.input[len(tc.input)-64:], noSpecialTokens)
+			if !equalIntSlices(got, want[len(want)-tc.n:]) {
+				t.Errorf("TailTokens(%d) = %v, want %v", tc.n, got, want[len(want)-tc.n:])
+			}
+		}
+	})
+
+	t.Run("atomic_patterns", func(t *testing.T) {
+		atomic, err := New(WithAtomicPatterns(`Case[ \d]+`))
+		if err != nil {
+			t.Fatalf("Failed to create tokenizer: %v", err)
+		}
+		// Blocks start inside matches that span many pre-tokens
+		input := strings.Repeat("See Case"+strings.Repeat(" 12", 300)+".<|eot_id|>", 200)
+		if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+			t.Fatalf("Failed to write input: %v", err)
+		}
+		full := atomic.Encode(input, noSpecialTokens)
+		for _, n := range []int{1, 9, 30000} {
+			got, err := atomic.TailTokens(path, n)
+			if err != nil {
+				t.Fatalf("TailTokens(%d) error: %v", n, err)
+			}
+			if !equalIntSlices(got, full[len(full)-n:]) {
+				t.Errorf("TailTokens(%d) differs from the end of Encode", n)
+			}
+		}
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		if _, err := tokenizer.TailTokens(filepath.Join(t.TempDir(), "missing"), 10); err == nil {
+			t.Error("Expected error for missing file")
+		}
+	})
+}
+
+func TestTailBoundaries(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	atomic, err := New(WithAtomicPatterns(`Case[ \d]+`))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	// Encoding the text on either side of a boundary found in any suffix
+	// of the input gives the tokens of the whole input
+	parts := []string{"a", "Zoë", "世界", "123", "4", ",", "...", "(", "'s", " ", "  ", "\n", "\r\n", "\t", " ", "<|eot_id|>", "<|", "|>", "\xff", "Case", " 12"}
+	rng := rand.New(rand.NewSource(1))
+	for _, tok := range []*Tokenizer{tokenizer, atomic} {
+		b := tok.newTailBoundaries()
+		for range 200 {
+			var sb strings.Builder
+			for range 1 + rng.Intn(40) {
+				sb.WriteString(parts[rng.Intn(len(parts))])
+			}
+			input := sb.String()
+			full := tok.Encode(input, noSpecialTokens)
+			for start := range len(input) {
+				cut := b.first([]byte(input[start:]), b.margin, len(input)-start)
+				if cut == 0 {
+					continue
+				}
+				cut += start
+				split := append(tok.Encode(input[:cut], noSpecialTokens), tok.Encode(input[cut:], noSpecialTokens)...)
+				if !equalIntSlices(split, full) {
+					t.Errorf("atomic=%v: splitting %q at %d changes its tokens", tok.atomic != nil, input, cut)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+//go:build !tokenizer_tiny
+
+package llama3
+
+import (
+	"github.com/agentstation/tokenizer/llama3/internal/vocabulary"
+)
+
+// WithHFTokenizerJSON loads the vocabulary, merges and special tokens from a
+// Hugging Face tokenizer.json file, such as the one published with the
+// official Llama 3 model weights, instead of the embedded data:
+//
+//	llama3.New(llama3.WithHFTokenizerJSON("Meta-Llama-3-8B/tokenizer.json"))
+//
+// The file must describe a byte-level BPE model whose added tokens take the
+// IDs following the regular tokens, as Llama 3's do. The added tokens become
+// the special tokens unless WithSpecialTokens or WithSpecialTokenProfile is
+// also given; if the file has none, the default Llama 3 special tokens are
+// used. Only the vocabulary and merges are read: pre-tokenization is always
+// Llama 3's. The file is checked against the load limits like WithDataFiles.
+func WithHFTokenizerJSON(path string) Option {
+	return func(cfg *config) error {
+		if path == "" {
+			return NewConfigError("hf_tokenizer_json", path, ErrDataNotFound)
+		}
+		// This will be handled in tokenizer initialization
+		cfg.dataLoader = &hfLoaderMarker{path: path}
+		return nil
+	}
+}
+
+// hfLoaderMarker is a placeholder that will be replaced with an
+// hfVocabularySource once the load limits are known.
+type hfLoaderMarker struct {
+	path string
+}
+
+func (h *hfLoaderMarker) LoadVocabulary() ([]string, error) {
+	panic("hfLoaderMarker should be replaced before use")
+}
+
+func (h *hfLoaderMarker) LoadMerges() (map[string]int, error) {
+	panic("hfLoaderMarker should be replaced before use")
+}
+
+func (h *hfLoaderMarker) source(limits vocabulary.Limits, t *Tokenizer) VocabularyDataLoader {
+	return &hfVocabularySource{parsedVocabularySource{path: h.path, limits: limits, t: t}}
+}
+
+// hfVocabularySource loads vocabulary data from a tokenizer.json file. The
+// file is parsed once, by LoadVocabulary.
+type hfVocabularySource struct {
+	parsedVocabularySource
+}
+
+func (h *hfVocabularySource) LoadVocabulary() ([]string, error) {
+	data, err := vocabulary.LoadHFTokenizerJSON(h.path, h.limits)
+	if err != nil {
+		return nil, NewDataError("load vocabulary", h.path, err)
+	}
+	h.data = data
+	return data.Vocab, nil
+}
@@ -0,0 +1,128 @@
+//go:build !tokenizer_tiny
+
+package llama3
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	testutils "github.com/agentstation/tokenizer/llama3/internal/testing"
+)
+
+// writeHFTokenizerJSON writes the data of tokenizer as a tokenizer.json file
+// in the layout of the official Llama 3 release, with tokens in byte-level
+// form.
+func writeHFTokenizerJSON(t *testing.T, tokenizer *Tokenizer) string {
+	t.Helper()
+
+	type addedToken struct {
+		ID      int    `json:"id"`
+		Content string `json:"content"`
+		Special bool   `json:"special"`
+	}
+	vocab := make(map[string]int)
+	var added []addedToken
+	for id, text := range tokenizer.tokens {
+		if tokenizer.IsSpecialID(id) {
+			added = append(added, addedToken{ID: id, Content: text, Special: true})
+		} else {
+			vocab[text] = id
+		}
+	}
+	var merges [][2]string
+	for m := range tokenizer.Vocab().Merges() {
+		merges = append(merges, [2]string{tokenizer.tokens[m.Left], tokenizer.tokens[m.Right]})
+	}
+
+	doc := map[string]any{
+		"version":      "1.0",
+		"added_tokens": added,
+		"model": map[string]any{
+			"type":   "BPE",
+			"vocab":  vocab,
+			"merges": merges,
+		},
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return path
+}
+
+func TestWithHFTokenizerJSON(t *testing.T) {
+	reference, err := New()
+	if err != nil || reference.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	path := writeHFTokenizerJSON(t, reference)
+
+	tokenizer, err := New(WithHFTokenizerJSON(path))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if tokenizer.VocabSize() != reference.VocabSize() {
+		t.Errorf("Expected %d tokens, got %d", reference.VocabSize(), tokenizer.VocabSize())
+	}
+	if id, ok := tokenizer.TokenID("<|eot_id|>"); !ok || !tokenizer.IsSpecialID(id) {
+		t.Errorf("Expected <|eot_id|> to be a special token, got %d, %v", id, ok)
+	}
+
+	inputs := []string{"<|begin_of_text|>Hello<|eot_id|>"}
+	for _, tc := range testutils.GenerateTestCases() {
+		inputs = append(inputs, tc.Input)
+	}
+	for _, input := range inputs {
+		want := reference.Encode(input, nil)
+		if got := tokenizer.Encode(input, nil); !equalIntSlices(got, want) {
+			t.Errorf("Encode(%q): expected %v, got %v", input, want, got)
+		}
+	}
+}
+
+func TestWithHFTokenizerJSONErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		limits LoadLimits
+		want   error
+	}{
+		{"missing file", filepath.Join(dir, "missing.json"), DefaultLoadLimits(), os.ErrNotExist},
+		{"limit", write("big.json", `{"model": {"type": "BPE", "vocab": {"a": 0, "b": 1}}}`), LoadLimits{MaxTokens: 1}, ErrLimitExceeded},
+		{"not BPE", write("wordpiece.json", `{"model": {"type": "WordPiece", "vocab": {"a": 0}}}`), DefaultLoadLimits(), nil},
+		{"gap", write("gap.json", `{"model": {"type": "BPE", "vocab": {"a": 0, "b": 2}}}`), DefaultLoadLimits(), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(WithHFTokenizerJSON(tt.path), WithLoadLimits(tt.limits))
+			var dataErr *DataError
+			if !errors.As(err, &dataErr) {
+				t.Fatalf("Expected DataError, got %v", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	var configErr *ConfigError
+	if _, err := New(WithHFTokenizerJSON("")); !errors.As(err, &configErr) {
+		t.Errorf("Expected ConfigError for an empty path, got %v", err)
+	}
+}
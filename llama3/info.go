@@ -0,0 +1,112 @@
+package llama3
+
+import (
+	"runtime"
+	"slices"
+
+	"github.com/agentstation/tokenizer/bpe"
+	"github.com/agentstation/tokenizer/llama3/internal/pretokenizer"
+)
+
+// Implementation paths reported in Info.Paths.
+const (
+	PathStateMachine        = "state-machine-pretokenizer" // hand-written pre-tokenizer instead of a regexp
+	PathParallelPretokenize = "parallel-pretokenize"       // large inputs pre-tokenized on several goroutines
+	PathBPECache            = "bpe-cache"                  // BPE results cached per pre-token
+	PathMappedSnapshot      = "mapped-snapshot"            // token text in a shared read-only mapping
+	PathAtomicPatterns      = "atomic-patterns"            // WithAtomicPatterns, departing from the reference
+	PathMergeOverrides      = "merge-overrides"            // WithMergeOverrides, departing from the reference
+)
+
+// Info describes a tokenizer and the implementation serving it in this
+// process, for diagnostics and performance bug reports.
+type Info struct {
+	Build     string `json:"build"` // "default", or "tiny" for tokenizer_tiny builds
+	GoVersion string `json:"go_version"`
+	GOOS      string `json:"goos"`
+	GOARCH    string `json:"goarch"`
+	MaxProcs  int    `json:"max_procs"` // GOMAXPROCS
+
+	VocabSize     int `json:"vocab_size"`
+	RegularTokens int `json:"regular_tokens"`
+	SpecialTokens int `json:"special_tokens"`
+
+	Cache     string `json:"cache"`      // "none", "unbounded", "lru", "adaptive" or "custom"
+	CacheSize int    `json:"cache_size"` // current maximum entries, 0 if unbounded or unknown
+
+	// CacheMinSize and CacheMaxSize bound the capacity of an adaptive
+	// cache, 0 for other caches.
+	CacheMinSize int `json:"cache_min_size,omitempty"`
+	CacheMaxSize int `json:"cache_max_size,omitempty"`
+
+	// AtomicPatterns and OptimisticPatterns are the patterns given to
+	// WithAtomicPatterns and WithOptimisticPatterns, and MergeOverrides
+	// the number of merges changed by WithMergeOverrides.
+	AtomicPatterns     []string `json:"atomic_patterns,omitempty"`
+	OptimisticPatterns []string `json:"optimistic_patterns,omitempty"`
+	MergeOverrides     int      `json:"merge_overrides,omitempty"`
+
+	// ParallelMinBytes is the input size from which pre-tokenization runs
+	// on several goroutines, 0 if it never does.
+	ParallelMinBytes int `json:"parallel_min_bytes"`
+
+	// Paths lists the optimized and non-reference code paths active for
+	// the tokenizer, such as PathParallelPretokenize.
+	Paths []string `json:"paths"`
+}
+
+// Info returns a description of t and of the implementation variant
+// encoding with it.
+func (t *Tokenizer) Info() Info {
+	info := Info{
+		Build:              buildProfile,
+		GoVersion:          runtime.Version(),
+		GOOS:               runtime.GOOS,
+		GOARCH:             runtime.GOARCH,
+		MaxProcs:           runtime.GOMAXPROCS(0),
+		VocabSize:          t.VocabSize(),
+		RegularTokens:      t.regularLen,
+		SpecialTokens:      t.VocabSize() - t.regularLen,
+		ParallelMinBytes:   pretokenizer.ParallelMinBytes(),
+		AtomicPatterns:     slices.Clone(t.atomicPatterns),
+		OptimisticPatterns: slices.Clone(t.optimisticPatterns),
+		MergeOverrides:     t.mergeOverrides,
+		Paths:              []string{PathStateMachine},
+	}
+
+	switch t.cache.(type) {
+	case nil:
+		info.Cache = "none"
+	case *bpe.SimpleCache:
+		info.Cache = "unbounded"
+	case *bpe.LRUCache:
+		info.Cache = "lru"
+	case *bpe.AdaptiveLRU:
+		info.Cache = "adaptive"
+	default:
+		info.Cache = "custom"
+	}
+	if c, ok := t.cache.(interface{ Capacity() int }); ok {
+		info.CacheSize = c.Capacity()
+	}
+	if c, ok := t.cache.(*bpe.AdaptiveLRU); ok {
+		info.CacheMinSize, info.CacheMaxSize = c.Bounds()
+	}
+
+	if info.ParallelMinBytes > 0 {
+		info.Paths = append(info.Paths, PathParallelPretokenize)
+	}
+	if t.cache != nil {
+		info.Paths = append(info.Paths, PathBPECache)
+	}
+	if t.mapped {
+		info.Paths = append(info.Paths, PathMappedSnapshot)
+	}
+	if t.atomic != nil {
+		info.Paths = append(info.Paths, PathAtomicPatterns)
+	}
+	if t.processor.SkipVocabLookup {
+		info.Paths = append(info.Paths, PathMergeOverrides)
+	}
+	return info
+}
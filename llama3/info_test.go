@@ -0,0 +1,94 @@
+package llama3
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestInfo(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	info := tokenizer.Info()
+	if info.VocabSize != tokenizer.VocabSize() || info.RegularTokens+info.SpecialTokens != info.VocabSize {
+		t.Errorf("Expected %d tokens split into regular and special, got %+v", tokenizer.VocabSize(), info)
+	}
+	if info.Build != buildProfile || info.GoVersion == "" || info.MaxProcs < 1 {
+		t.Errorf("Expected build and runtime details, got %+v", info)
+	}
+	for _, path := range []string{PathStateMachine, PathBPECache} {
+		if !slices.Contains(info.Paths, path) {
+			t.Errorf("Expected path %q, got %v", path, info.Paths)
+		}
+	}
+	if (info.ParallelMinBytes > 0) != slices.Contains(info.Paths, PathParallelPretokenize) {
+		t.Errorf("Expected %s exactly when ParallelMinBytes is set, got %+v", PathParallelPretokenize, info)
+	}
+
+	tests := []struct {
+		name      string
+		tokenizer func() (*Tokenizer, error)
+		cache     string
+		cacheSize int
+		path      string
+	}{
+		{"lru", func() (*Tokenizer, error) { return New(WithCacheSize(100)) }, "lru", 100, PathBPECache},
+		{"adaptive", func() (*Tokenizer, error) { return New(WithAdaptiveCache(10, 1000)) }, "adaptive", 10, PathBPECache},
+		{"no_cache", func() (*Tokenizer, error) { return tokenizer.WithCache(nil), nil }, "none", 0, PathStateMachine},
+		{"atomic_patterns", func() (*Tokenizer, error) { return New(WithAtomicPatterns(`[A-Z]+-[0-9]+`)) }, "unbounded", 0, PathAtomicPatterns},
+		{"merge_overrides", func() (*Tokenizer, error) {
+			return New(WithMergeOverrides(MergeOverride{Left: "Ġt", Right: "he", Disable: true}))
+		}, "unbounded", 0, PathMergeOverrides},
+		{"mapped_snapshot", func() (*Tokenizer, error) {
+			path := filepath.Join(t.TempDir(), "llama3.snap")
+			if err := tokenizer.WriteSnapshotFile(path); err != nil {
+				return nil, err
+			}
+			return NewFromMappedSnapshot(path)
+		}, "unbounded", 0, PathMappedSnapshot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, err := tt.tokenizer()
+			if err != nil {
+				t.Fatalf("Failed to create tokenizer: %v", err)
+			}
+			info := tok.Info()
+			if info.Cache != tt.cache || info.CacheSize != tt.cacheSize {
+				t.Errorf("Expected cache %s of %d, got %s of %d", tt.cache, tt.cacheSize, info.Cache, info.CacheSize)
+			}
+			if !slices.Contains(info.Paths, tt.path) {
+				t.Errorf("Expected path %q, got %v", tt.path, info.Paths)
+			}
+			if tt.cache == "none" && slices.Contains(info.Paths, PathBPECache) {
+				t.Errorf("Expected no %s path, got %v", PathBPECache, info.Paths)
+			}
+		})
+	}
+}
+
+func TestInfoConfiguration(t *testing.T) {
+	tokenizer, err := New(
+		WithAdaptiveCache(10, 1000),
+		WithAtomicPatterns(`[A-Z]+-[0-9]+`),
+		WithOptimisticPatterns(`\{\{[a-z]+\}\}`),
+		WithMergeOverrides(MergeOverride{Left: "Ġt", Right: "he", Disable: true}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	info := tokenizer.Info()
+	if info.CacheMinSize != 10 || info.CacheMaxSize != 1000 {
+		t.Errorf("Expected adaptive cache bounds 10 and 1000, got %d and %d", info.CacheMinSize, info.CacheMaxSize)
+	}
+	if !slices.Equal(info.AtomicPatterns, []string{`[A-Z]+-[0-9]+`}) || !slices.Equal(info.OptimisticPatterns, []string{`\{\{[a-z]+\}\}`}) {
+		t.Errorf("Expected the configured patterns, got %q and %q", info.AtomicPatterns, info.OptimisticPatterns)
+	}
+	if info.MergeOverrides != 1 {
+		t.Errorf("Expected 1 merge override, got %d", info.MergeOverrides)
+	}
+}
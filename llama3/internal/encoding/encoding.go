@@ -3,6 +3,7 @@ package encoding
 
 import (
 	"strings"
+	"unicode/utf8"
 )
 
 // Constants for byte mapping ranges.
@@ -21,11 +22,17 @@ var (
 	BytesToUnicode map[byte]rune
 	// UnicodeToBytes maps unicode characters back to byte values for decoding.
 	UnicodeToBytes map[rune]byte
+
+	// byteRunes is BytesToUnicode as an array, for AppendBytes.
+	byteRunes [256]rune
 )
 
 func init() {
 	// Initialize byte-to-unicode mappings
 	BytesToUnicode, UnicodeToBytes = CreateByteMappings()
+	for b, r := range BytesToUnicode {
+		byteRunes[b] = r
+	}
 }
 
 // CreateByteMappings creates the byte-to-unicode and unicode-to-byte mappings
@@ -95,17 +102,29 @@ func EncodeBytes(data []byte) string {
 	return sb.String()
 }
 
+// AppendBytes appends the byte-level representation of data to dst, as
+// EncodeBytes does, without allocating when dst has room.
+func AppendBytes(dst []byte, data string) []byte {
+	for i := 0; i < len(data); i++ {
+		dst = utf8.AppendRune(dst, byteRunes[data[i]])
+	}
+	return dst
+}
+
 // DecodeTokenBytes converts a token string back to UTF-8 bytes.
 // This reverses the encoding performed by EncodeBytes, restoring the
 // original byte sequence from the Unicode representation.
 func DecodeTokenBytes(token string) []byte {
-	result := make([]byte, 0, len(token))
+	return AppendTokenBytes(make([]byte, 0, len(token)), token)
+}
 
+// AppendTokenBytes appends the UTF-8 bytes of token to dst, as
+// DecodeTokenBytes returns them, without allocating when dst has room.
+func AppendTokenBytes(dst []byte, token string) []byte {
 	for _, r := range token {
 		if b, ok := UnicodeToBytes[r]; ok {
-			result = append(result, b)
+			dst = append(dst, b)
 		}
 	}
-
-	return result
+	return dst
 }
@@ -0,0 +1,186 @@
+// Package gguf reads and writes the metadata of GGUF files, the model format
+// of llama.cpp.
+//
+// A GGUF file starts with the magic "GGUF", a version, the number of
+// tensors and the number of metadata key-value pairs, followed by the pairs
+// and the tensor infos. All integers are little-endian; strings are a
+// uint64 length followed by the bytes; arrays are an element type, a uint64
+// count and the elements.
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// File format constants.
+const (
+	Magic     = "GGUF"
+	Version   = 3
+	Alignment = 32 // default alignment of the tensor data
+)
+
+// ValueType is the type of a metadata value.
+type ValueType uint32
+
+// Metadata value types.
+const (
+	TypeUint8 ValueType = iota
+	TypeInt8
+	TypeUint16
+	TypeInt16
+	TypeUint32
+	TypeInt32
+	TypeFloat32
+	TypeBool
+	TypeString
+	TypeArray
+	TypeUint64
+	TypeInt64
+	TypeFloat64
+)
+
+// KeyValue is a metadata key-value pair. Value is a bool, string, sized
+// integer or float, or a slice of one of them.
+type KeyValue struct {
+	Key   string
+	Value any
+}
+
+// Write writes a GGUF file holding the metadata kvs and no tensors, padded
+// to Alignment.
+func Write(w io.Writer, kvs []KeyValue) error {
+	bw := bufio.NewWriter(w)
+	var b []byte
+	b = append(b, Magic...)
+	b = binary.LittleEndian.AppendUint32(b, Version)
+	b = binary.LittleEndian.AppendUint64(b, 0) // tensors
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(kvs)))
+	size := 0
+
+	for _, kv := range kvs {
+		var err error
+		b = appendString(b, kv.Key)
+		if b, err = appendValue(b, kv.Value, true); err != nil {
+			return fmt.Errorf("metadata %s: %w", kv.Key, err)
+		}
+		if len(b) > 64<<10 {
+			if _, err := bw.Write(b); err != nil {
+				return err
+			}
+			size += len(b)
+			b = b[:0]
+		}
+	}
+
+	size += len(b)
+	for size%Alignment != 0 {
+		b = append(b, 0)
+		size++
+	}
+	if _, err := bw.Write(b); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// appendString appends s as a GGUF string.
+func appendString(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendValue appends v, preceded by its type if typed is set.
+func appendValue(b []byte, v any, typed bool) ([]byte, error) {
+	tag := func(t ValueType) {
+		if typed {
+			b = binary.LittleEndian.AppendUint32(b, uint32(t))
+		}
+	}
+	switch v := v.(type) {
+	case uint8:
+		tag(TypeUint8)
+		return append(b, v), nil
+	case int8:
+		tag(TypeInt8)
+		return append(b, byte(v)), nil
+	case uint16:
+		tag(TypeUint16)
+		return binary.LittleEndian.AppendUint16(b, v), nil
+	case int16:
+		tag(TypeInt16)
+		return binary.LittleEndian.AppendUint16(b, uint16(v)), nil // #nosec G115 - two's complement encoding
+	case uint32:
+		tag(TypeUint32)
+		return binary.LittleEndian.AppendUint32(b, v), nil
+	case int32:
+		tag(TypeInt32)
+		return binary.LittleEndian.AppendUint32(b, uint32(v)), nil // #nosec G115 - two's complement encoding
+	case float32:
+		tag(TypeFloat32)
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(v)), nil
+	case bool:
+		tag(TypeBool)
+		if v {
+			return append(b, 1), nil
+		}
+		return append(b, 0), nil
+	case string:
+		tag(TypeString)
+		return appendString(b, v), nil
+	case uint64:
+		tag(TypeUint64)
+		return binary.LittleEndian.AppendUint64(b, v), nil
+	case int64:
+		tag(TypeInt64)
+		return binary.LittleEndian.AppendUint64(b, uint64(v)), nil // #nosec G115 - two's complement encoding
+	case float64:
+		tag(TypeFloat64)
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v)), nil
+	case []uint8:
+		return appendArray(b, TypeUint8, v, typed)
+	case []int8:
+		return appendArray(b, TypeInt8, v, typed)
+	case []uint16:
+		return appendArray(b, TypeUint16, v, typed)
+	case []int16:
+		return appendArray(b, TypeInt16, v, typed)
+	case []uint32:
+		return appendArray(b, TypeUint32, v, typed)
+	case []int32:
+		return appendArray(b, TypeInt32, v, typed)
+	case []float32:
+		return appendArray(b, TypeFloat32, v, typed)
+	case []bool:
+		return appendArray(b, TypeBool, v, typed)
+	case []string:
+		return appendArray(b, TypeString, v, typed)
+	case []uint64:
+		return appendArray(b, TypeUint64, v, typed)
+	case []int64:
+		return appendArray(b, TypeInt64, v, typed)
+	case []float64:
+		return appendArray(b, TypeFloat64, v, typed)
+	}
+	return b, fmt.Errorf("unsupported value type %T", v)
+}
+
+// appendArray appends the array of elements of type t, preceded by
+// TypeArray if typed is set.
+func appendArray[E any](b []byte, t ValueType, elems []E, typed bool) ([]byte, error) {
+	if typed {
+		b = binary.LittleEndian.AppendUint32(b, uint32(TypeArray))
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(t))
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(elems)))
+	for _, e := range elems {
+		var err error
+		if b, err = appendValue(b, e, false); err != nil {
+			return b, err
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,136 @@
+package gguf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	kvs := []KeyValue{
+		{"a", uint32(7)},
+		{"b", []string{"x", "yz"}},
+		{"c", true},
+	}
+	if err := Write(&buf, kvs); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	var want []byte
+	le := binary.LittleEndian
+	want = append(want, "GGUF"...)
+	want = le.AppendUint32(want, 3)
+	want = le.AppendUint64(want, 0)
+	want = le.AppendUint64(want, 3)
+	str := func(s string) {
+		want = le.AppendUint64(want, uint64(len(s)))
+		want = append(want, s...)
+	}
+	str("a")
+	want = le.AppendUint32(want, uint32(TypeUint32))
+	want = le.AppendUint32(want, 7)
+	str("b")
+	want = le.AppendUint32(want, uint32(TypeArray))
+	want = le.AppendUint32(want, uint32(TypeString))
+	want = le.AppendUint64(want, 2)
+	str("x")
+	str("yz")
+	str("c")
+	want = le.AppendUint32(want, uint32(TypeBool))
+	want = append(want, 1)
+	for len(want)%Alignment != 0 {
+		want = append(want, 0)
+	}
+
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Expected\n%x\ngot\n%x", want, buf.Bytes())
+	}
+
+	if err := Write(&bytes.Buffer{}, []KeyValue{{"bad", 1}}); err == nil {
+		t.Error("Expected an error for an int value")
+	}
+}
+
+func TestRead(t *testing.T) {
+	kvs := []KeyValue{
+		{"u8", uint8(1)},
+		{"i8", int8(-2)},
+		{"u16", uint16(3)},
+		{"i16", int16(-4)},
+		{"u32", uint32(5)},
+		{"i32", int32(-6)},
+		{"f32", float32(0.5)},
+		{"bool", true},
+		{"string", "hello"},
+		{"u64", uint64(7)},
+		{"i64", int64(-8)},
+		{"f64", float64(0.25)},
+		{"strings", []string{"Ġa", "b", ""}},
+		{"int32s", []int32{1, -1}},
+		{"empty", []float32{}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, kvs); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	got, err := Read(bytes.NewReader(buf.Bytes()), 0)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, kvs) {
+		t.Errorf("Expected %v, got %v", kvs, got)
+	}
+
+	if _, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Errorf("Expected metadata within the limit to be read, got %v", err)
+	}
+	if _, err := Read(bytes.NewReader(buf.Bytes()), 64); !errors.Is(err, ErrMetadataTooLarge) {
+		t.Errorf("Expected ErrMetadataTooLarge, got %v", err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, []KeyValue{{"a", []string{"x", "y"}}}); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	valid := buf.Bytes()
+	le := binary.LittleEndian
+
+	header := func(version uint32, kvs uint64) []byte {
+		b := append([]byte(Magic), le.AppendUint32(nil, version)...)
+		b = le.AppendUint64(b, 0)
+		return le.AppendUint64(b, kvs)
+	}
+	nested := header(3, 1)
+	nested = le.AppendUint64(nested, 1)
+	nested = append(nested, 'a')
+	nested = le.AppendUint32(nested, uint32(TypeArray))
+	nested = le.AppendUint32(nested, uint32(TypeArray))
+	nested = le.AppendUint64(nested, 0)
+	huge := header(3, 1)
+	huge = le.AppendUint64(huge, math.MaxUint64) // key length
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"magic", append([]byte("GGML"), valid[4:]...)},
+		{"version", header(1, 0)},
+		{"truncated", valid[:len(valid)-40]},
+		{"nested array", nested},
+		{"huge string", huge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Read(bytes.NewReader(tt.data), 0); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ErrMetadataTooLarge is returned by Read when the metadata is larger than
+// its size limit.
+var ErrMetadataTooLarge = errors.New("GGUF metadata too large")
+
+// maxPrealloc bounds the capacity allocated up front for an array, so that
+// counts in a malicious file cannot allocate more memory than its data.
+const maxPrealloc = 1 << 12
+
+// Read reads the metadata key-value pairs at the start of a GGUF file of
+// version 2 or 3, stopping before the tensor infos, so only the header of a
+// model file is read. Values have the Go types written by Write: uint32 for
+// TypeUint32, []string for an array of strings, and so on. Nested arrays are
+// not supported.
+//
+// If maxSize is positive, Read fails with ErrMetadataTooLarge instead of
+// reading more than maxSize bytes.
+func Read(r io.Reader, maxSize int64) ([]KeyValue, error) {
+	d := &decoder{r: bufio.NewReader(r), left: maxSize, limited: maxSize > 0}
+
+	magic := d.bytes(4)
+	if d.err == nil && string(magic) != Magic {
+		return nil, fmt.Errorf("not a GGUF file: magic %q", magic)
+	}
+	version := d.uint32()
+	if d.err == nil && version != 2 && version != 3 {
+		return nil, fmt.Errorf("unsupported GGUF version %d", version)
+	}
+	d.uint64() // tensors
+	count := d.uint64()
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	kvs := make([]KeyValue, 0, min(count, maxPrealloc))
+	for range count {
+		key := d.string()
+		typ := ValueType(d.uint32())
+		if d.err != nil {
+			return nil, d.err
+		}
+		var value any
+		if typ == TypeArray {
+			value = d.array()
+		} else {
+			value = d.value(typ)
+		}
+		if d.err != nil {
+			return nil, fmt.Errorf("metadata %s: %w", key, d.err)
+		}
+		kvs = append(kvs, KeyValue{key, value})
+	}
+	return kvs, nil
+}
+
+// decoder reads GGUF values, keeping the first error.
+type decoder struct {
+	r       *bufio.Reader
+	left    int64 // bytes left under the size limit
+	limited bool
+	err     error
+}
+
+// bytes reads the next n bytes.
+func (d *decoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if d.limited && n > uint64(d.left) { // #nosec G115 - left is not negative
+		d.err = ErrMetadataTooLarge
+		return nil
+	}
+	var b []byte
+	var err error
+	if n <= maxPrealloc {
+		b = make([]byte, n)
+		_, err = io.ReadFull(d.r, b)
+	} else {
+		// Grow the buffer while reading, rather than trusting n up front
+		b, err = io.ReadAll(io.LimitReader(d.r, int64(min(n, math.MaxInt64)))) // #nosec G115 - n is capped
+		if err == nil && uint64(len(b)) < n {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		d.err = err
+		return nil
+	}
+	d.left -= int64(len(b))
+	return b
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.uint64()))
+}
+
+// value reads a value of type t, other than an array.
+func (d *decoder) value(t ValueType) any {
+	switch t {
+	case TypeUint8:
+		return readScalar(d, 1, func(b []byte) uint8 { return b[0] })
+	case TypeInt8:
+		return readScalar(d, 1, func(b []byte) int8 { return int8(b[0]) }) // #nosec G115 - two's complement encoding
+	case TypeUint16:
+		return readScalar(d, 2, binary.LittleEndian.Uint16)
+	case TypeInt16:
+		return readScalar(d, 2, func(b []byte) int16 { return int16(binary.LittleEndian.Uint16(b)) }) // #nosec G115 - two's complement encoding
+	case TypeUint32:
+		return readScalar(d, 4, binary.LittleEndian.Uint32)
+	case TypeInt32:
+		return readScalar(d, 4, func(b []byte) int32 { return int32(binary.LittleEndian.Uint32(b)) }) // #nosec G115 - two's complement encoding
+	case TypeFloat32:
+		return readScalar(d, 4, func(b []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(b)) })
+	case TypeBool:
+		return readScalar(d, 1, func(b []byte) bool { return b[0] != 0 })
+	case TypeString:
+		return d.string()
+	case TypeUint64:
+		return readScalar(d, 8, binary.LittleEndian.Uint64)
+	case TypeInt64:
+		return readScalar(d, 8, func(b []byte) int64 { return int64(binary.LittleEndian.Uint64(b)) }) // #nosec G115 - two's complement encoding
+	case TypeFloat64:
+		return readScalar(d, 8, func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) })
+	}
+	d.fail(fmt.Errorf("unsupported value type %d", t))
+	return nil
+}
+
+// array reads an array value, after its TypeArray tag.
+func (d *decoder) array() any {
+	t := ValueType(d.uint32())
+	n := d.uint64()
+	if d.err != nil {
+		return nil
+	}
+	switch t {
+	case TypeUint8:
+		return readArray[uint8](d, t, n)
+	case TypeInt8:
+		return readArray[int8](d, t, n)
+	case TypeUint16:
+		return readArray[uint16](d, t, n)
+	case TypeInt16:
+		return readArray[int16](d, t, n)
+	case TypeUint32:
+		return readArray[uint32](d, t, n)
+	case TypeInt32:
+		return readArray[int32](d, t, n)
+	case TypeFloat32:
+		return readArray[float32](d, t, n)
+	case TypeBool:
+		return readArray[bool](d, t, n)
+	case TypeString:
+		return readArray[string](d, t, n)
+	case TypeUint64:
+		return readArray[uint64](d, t, n)
+	case TypeInt64:
+		return readArray[int64](d, t, n)
+	case TypeFloat64:
+		return readArray[float64](d, t, n)
+	}
+	d.fail(fmt.Errorf("unsupported array element type %d", t))
+	return nil
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// readScalar reads a fixed-size value of size bytes.
+func readScalar[E any](d *decoder, size uint64, decode func([]byte) E) E {
+	if b := d.bytes(size); b != nil {
+		return decode(b)
+	}
+	var zero E
+	return zero
+}
+
+// readArray reads n elements of type t, which have the Go type E.
+func readArray[E any](d *decoder, t ValueType, n uint64) []E {
+	elems := make([]E, 0, min(n, maxPrealloc))
+	for range n {
+		v := d.value(t)
+		if d.err != nil {
+			return nil
+		}
+		elems = append(elems, v.(E))
+	}
+	return elems
+}
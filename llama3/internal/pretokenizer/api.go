@@ -6,4 +6,8 @@ const (
 	defaultStateMachineTokenCapacity = 32   // Initial capacity for state machine tokens
 	defaultTokenBufferCapacity       = 64   // Initial capacity for token buffers
 	maxPooledTokenBufferCapacity     = 1024 // Maximum capacity for pooled token buffers
+
+	// Sharding configuration.
+	parallelMinBytes = 256 * 1024 // Minimum input size for concurrent pre-tokenization
+	minShardBytes    = 64 * 1024  // Minimum bytes per shard
 )
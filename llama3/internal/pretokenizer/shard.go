@@ -0,0 +1,121 @@
+package pretokenizer
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// shardCount returns the number of shards to split an input of n bytes into.
+func shardCount(n int) int {
+	shards := n / minShardBytes
+	if procs := runtime.GOMAXPROCS(0); shards > procs {
+		shards = procs
+	}
+	return shards
+}
+
+// tokenizeSharded splits text into up to n shards at safe boundaries,
+// pre-tokenizes them concurrently and concatenates the results in order.
+// The output is identical to tokenize(text).
+func tokenizeSharded(text string, n int) []string {
+	bounds := shardBoundaries(text, n)
+	if len(bounds) <= 2 {
+		return tokenize(text)
+	}
+
+	results := make([][]string, len(bounds)-1)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = tokenize(text[bounds[i]:bounds[i+1]])
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, r := range results {
+		total += len(r)
+	}
+	tokens := make([]string, 0, total)
+	for _, r := range results {
+		tokens = append(tokens, r...)
+	}
+	return tokens
+}
+
+// shardBoundaries returns the start offsets of up to n shards of roughly
+// equal size, followed by len(text). Every inner offset is a safe boundary.
+func shardBoundaries(text string, n int) []int {
+	bounds := make([]int, 1, n+1)
+	size := len(text) / n
+
+	for i := 1; i < n; i++ {
+		next := nextSafeBoundary(text, i*size)
+		if next < 0 {
+			break
+		}
+		if next > bounds[len(bounds)-1] {
+			bounds = append(bounds, next)
+		}
+	}
+
+	return append(bounds, len(text))
+}
+
+// nextSafeBoundary returns the first safe boundary at or after from, or -1.
+//
+// A boundary directly after a newline and before an ASCII letter or digit is
+// safe: no pattern matches across it, and every pattern that ends there stops
+// on the letter or digit exactly as it stops at the end of the input. So the
+// text on either side pre-tokenizes the same way on its own.
+func nextSafeBoundary(text string, from int) int {
+	for from < len(text) {
+		i := strings.IndexByte(text[from:], '\n')
+		if i < 0 {
+			return -1
+		}
+		at := from + i + 1
+		if at < len(text) && isASCIIAlnum(text[at]) {
+			return at
+		}
+		from = at
+	}
+	return -1
+}
+
+// IsSafeBoundary reports whether text can be split at byte offset at so that
+// both sides pre-tokenize on their own exactly as they do within text.
+//
+// Besides the newline rule of nextSafeBoundary, a space between two ASCII
+// letters is safe: the letters before it end a pre-token at the space, and
+// the space starts the next one, whatever precedes it. Both rules only look at
+// the bytes next to at, so the boundary stays safe under edits elsewhere.
+func IsSafeBoundary(text string, at int) bool {
+	if at <= 0 || at >= len(text) {
+		return false
+	}
+	if text[at-1] == '\n' {
+		return isASCIIAlnum(text[at])
+	}
+	return text[at] == ' ' && at+1 < len(text) && isASCIILetter(text[at-1]) && isASCIILetter(text[at+1])
+}
+
+func isASCIILetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func isASCIIAlnum(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// ParallelMinBytes returns the size from which inputs are pre-tokenized
+// concurrently, or 0 if GOMAXPROCS leaves no room for more than one shard.
+func ParallelMinBytes() int {
+	if runtime.GOMAXPROCS(0) < 2 {
+		return 0
+	}
+	return max(parallelMinBytes, 2*minShardBytes)
+}
@@ -0,0 +1,111 @@
+package pretokenizer
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeSharded(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	pieces := []string{
+		"word", " word", "Word", "  ", "\t", "\n", "\n\n", "\r\n", " \n", "  \n\n",
+		"!", "?!\n", "...", "'s", "'LL", "123", "45678", "世界", "🦙", "é", " \n\n!",
+	}
+
+	for i := 0; i < 200; i++ {
+		var sb strings.Builder
+		for j := 20 + rng.Intn(300); j >= 0; j-- {
+			sb.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		text := sb.String()
+		want := tokenize(text)
+
+		for _, n := range []int{2, 3, 8, 32} {
+			got := tokenizeSharded(text, n)
+			if len(got) != len(want) {
+				t.Fatalf("Shards %d for %q: got %d tokens, want %d", n, text, len(got), len(want))
+			}
+			for k := range want {
+				if got[k] != want[k] {
+					t.Fatalf("Shards %d for %q: token %d = %q, want %q", n, text, k, got[k], want[k])
+				}
+			}
+		}
+	}
+}
+
+func TestShardBoundaries(t *testing.T) {
+	text := strings.Repeat("Paragraph one.\n\nAnd 2 more lines\n 3\n", 50)
+
+	bounds := shardBoundaries(text, 8)
+	if len(bounds) < 3 {
+		t.Fatalf("Expected several shards, got boundaries %v", bounds)
+	}
+	if bounds[0] != 0 || bounds[len(bounds)-1] != len(text) {
+		t.Errorf("Expected boundaries to span the text, got %v", bounds)
+	}
+	for i, b := range bounds[1 : len(bounds)-1] {
+		if b <= bounds[i] {
+			t.Errorf("Boundaries not increasing: %v", bounds)
+		}
+		if text[b-1] != '\n' || !isASCIIAlnum(text[b]) {
+			t.Errorf("Unsafe boundary at %d: %q", b, text[b-1:b+1])
+		}
+	}
+
+	// No safe boundary: a single shard
+	if bounds := shardBoundaries(strings.Repeat("\n ", 100), 4); len(bounds) != 2 {
+		t.Errorf("Expected a single shard, got boundaries %v", bounds)
+	}
+}
+
+func TestIsSafeBoundary(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	pieces := []string{
+		"word", " word", "Word", " ", "  ", "\t", "\n", "\n\n", "\r\n", " \n",
+		"!", "?!\n", "'s", "'LL", " 'd", "123", "世界", "🦙", "é", " é", "a b",
+	}
+
+	checked := 0
+	for i := 0; i < 200; i++ {
+		var sb strings.Builder
+		for j := 10 + rng.Intn(60); j >= 0; j-- {
+			sb.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		text := sb.String()
+		want := strings.Join(tokenize(text), "|")
+
+		for at := 0; at <= len(text); at++ {
+			if !IsSafeBoundary(text, at) {
+				continue
+			}
+			checked++
+			got := strings.Join(append(tokenize(text[:at]), tokenize(text[at:])...), "|")
+			if got != want {
+				t.Fatalf("Split of %q at %d: got %q, want %q", text, at, got, want)
+			}
+		}
+	}
+	if checked == 0 {
+		t.Fatal("No safe boundaries found")
+	}
+}
+
+func BenchmarkTokenizeLarge(b *testing.B) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog.\nIt's 123 o'clock!\n\n", 16384)
+
+	b.Run("serial", func(b *testing.B) {
+		b.SetBytes(int64(len(text)))
+		for i := 0; i < b.N; i++ {
+			_ = tokenize(text)
+		}
+	})
+
+	b.Run("sharded", func(b *testing.B) {
+		b.SetBytes(int64(len(text)))
+		for i := 0; i < b.N; i++ {
+			_ = Tokenize(text)
+		}
+	})
+}
@@ -49,8 +49,36 @@ func putStateMachine(sm *stateMachine) {
 // Tokenize performs pre-tokenization on the input text using a pooled state machine.
 // It splits text into words, numbers, punctuation, and whitespace according to the
 // Llama 3 tokenization rules. The function is optimized for memory efficiency by
-// reusing state machines and token buffers from pools.
+// reusing state machines and token buffers from pools. Large inputs are split
+// into shards that are pre-tokenized concurrently.
 func Tokenize(text string) []string {
+	if len(text) >= parallelMinBytes {
+		if shards := shardCount(len(text)); shards > 1 {
+			return tokenizeSharded(text, shards)
+		}
+	}
+	return tokenize(text)
+}
+
+// AppendTokenize appends the pre-tokens of text to dst, like Tokenize, so
+// callers that pre-tokenize in a loop can reuse one slice.
+func AppendTokenize(dst []string, text string) []string {
+	if len(text) >= parallelMinBytes {
+		if shards := shardCount(len(text)); shards > 1 {
+			return append(dst, tokenizeSharded(text, shards)...)
+		}
+	}
+	return appendTokenize(dst, text)
+}
+
+// tokenize pre-tokenizes text on the calling goroutine.
+func tokenize(text string) []string {
+	return appendTokenize(nil, text)
+}
+
+// appendTokenize appends the pre-tokens of text to dst on the calling
+// goroutine.
+func appendTokenize(dst []string, text string) []string {
 	sm := getStateMachine(text)
 
 	// Use pooled token buffer for better memory efficiency
@@ -63,8 +91,10 @@ func Tokenize(text string) []string {
 	}
 
 	// Copy results before returning to pool
-	result := make([]string, len(sm.tokens))
-	copy(result, sm.tokens)
+	if dst == nil {
+		dst = make([]string, 0, len(sm.tokens))
+	}
+	result := append(dst, sm.tokens...)
 
 	// Return token buffer to pool
 	if cap(sm.tokens) <= 1024 {
@@ -44,6 +44,84 @@ func IsSpecialToken(token string) bool {
 	return strings.HasPrefix(token, "<|") && strings.HasSuffix(token, "|>")
 }
 
+// defaultSpecialTokens holds the strings SpecialTokenRegex matches.
+var defaultSpecialTokens = func() map[string]struct{} {
+	set := make(map[string]struct{}, 256)
+	for _, token := range GetDefaultSpecialTokens(256) {
+		set[token] = struct{}{}
+	}
+	return set
+}()
+
+// IsDefaultSpecialToken reports whether token is a Llama 3 special token, as
+// matched by SpecialTokenRegex.
+func IsDefaultSpecialToken(token string) bool {
+	_, ok := defaultSpecialTokens[token]
+	return ok
+}
+
+// LooksLikeSpecialToken reports whether token is <|name|> with a name of
+// ASCII letters, digits and underscores, as matched by
+// OptimisticSpecialTokenRegex.
+func LooksLikeSpecialToken(token string) bool {
+	if len(token) < 5 || !IsSpecialToken(token) {
+		return false
+	}
+	for i := 2; i < len(token)-2; i++ {
+		c := token[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Segment is a piece of text split at special tokens: one special token, or
+// the text between them.
+type Segment struct {
+	Text    string
+	Special bool // Text is a special token accepted by the split's match function
+}
+
+// AppendSegments appends the segments of text, split at the special tokens
+// match accepts, to dst. Callers learn which segments are special tokens
+// from the split itself instead of matching each segment again.
+//
+// Candidates are the spans from each "<|" to the next "|>", checked with
+// match, so match must only accept names without "<|" and "|", as
+// IsDefaultSpecialToken and LooksLikeSpecialToken do. The segments are those
+// of SplitBySpecialTokens with the corresponding regex, found without
+// running it.
+func AppendSegments(dst []Segment, text string, match func(string) bool) []Segment {
+	lastEnd, pos := 0, 0
+	for {
+		i := strings.Index(text[pos:], "<|")
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		j := strings.Index(text[start+2:], "|>")
+		if j < 0 {
+			break // no later candidate can close either
+		}
+		end := start + 2 + j + 2
+		if !match(text[start:end]) {
+			pos = start + 1
+			continue
+		}
+
+		if start > lastEnd {
+			dst = append(dst, Segment{Text: text[lastEnd:start]})
+		}
+		dst = append(dst, Segment{Text: text[start:end], Special: true})
+		lastEnd, pos = end, end
+	}
+	if lastEnd < len(text) {
+		dst = append(dst, Segment{Text: text[lastEnd:]})
+	}
+	return dst
+}
+
 // SplitBySpecialTokens splits text by special tokens while preserving the tokens.
 func SplitBySpecialTokens(text string, regex *regexp.Regexp) []string {
 	if text == "" {
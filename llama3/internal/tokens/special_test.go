@@ -0,0 +1,82 @@
+package tokens
+
+import (
+	"math/rand"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAppendSegmentsMatchesRegexSplit(t *testing.T) {
+	inputs := []string{
+		"",
+		"plain text",
+		"<|eot_id|>",
+		"a<|eot_id|>b<|start_header_id|>user<|end_header_id|>",
+		"<|foo<|eot_id|>",
+		"<|eot_id|><|eot_id|>",
+		"<|not_special|> <|reserved_special_token_247|> <|reserved_special_token_248|>",
+		"<|eot_id|",
+		"|><|",
+		"<||>",
+		"<|<|a|>|>",
+	}
+
+	// Random strings over the pieces special tokens are made of
+	pieces := []string{"<", "|", ">", "<|", "|>", "eot_id", "x", "_", "9", " ", "é"}
+	rng := rand.New(rand.NewSource(1)) // #nosec G404 - deterministic test input
+	for i := 0; i < 2000; i++ {
+		var sb strings.Builder
+		for n := rng.Intn(12); n > 0; n-- {
+			sb.WriteString(pieces[rng.Intn(len(pieces))])
+		}
+		inputs = append(inputs, sb.String())
+	}
+
+	tests := []struct {
+		name  string
+		regex *regexp.Regexp
+		match func(string) bool
+	}{
+		{"default", SpecialTokenRegex, IsDefaultSpecialToken},
+		{"optimistic", OptimisticSpecialTokenRegex, LooksLikeSpecialToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, input := range inputs {
+				want := SplitBySpecialTokens(input, tt.regex)
+				var got []string
+				for _, seg := range AppendSegments(nil, input, tt.match) {
+					if seg.Special != tt.regex.MatchString(seg.Text) {
+						t.Errorf("AppendSegments(%q): segment %q has Special = %v", input, seg.Text, seg.Special)
+					}
+					got = append(got, seg.Text)
+				}
+				if !slices.Equal(got, want) && !(len(got) == 0 && len(want) == 0) {
+					t.Errorf("AppendSegments(%q) = %q, want %q", input, got, want)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSplitSpecialTokens(b *testing.B) {
+	text := strings.Repeat("<|start_header_id|>user<|end_header_id|>\n\nWhat is the capital of France?<|eot_id|>", 50)
+
+	b.Run("Regexp", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, part := range SplitBySpecialTokens(text, SpecialTokenRegex) {
+				_ = SpecialTokenRegex.MatchString(part)
+			}
+		}
+	})
+	b.Run("Segments", func(b *testing.B) {
+		var segments []Segment
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			segments = AppendSegments(segments[:0], text, IsDefaultSpecialToken)
+		}
+	})
+}
@@ -14,6 +14,12 @@ const (
 // DecodeVocabulary decodes the base64-encoded vocabulary data.
 // Returns a slice of tokens indexed by their token ID.
 func DecodeVocabulary(vocabBase64 string) ([]string, error) {
+	return decodeVocabulary(vocabBase64, Limits{})
+}
+
+// decodeVocabulary implements DecodeVocabulary, refusing vocabularies that
+// exceed limits before they are split into tokens.
+func decodeVocabulary(vocabBase64 string, limits Limits) ([]string, error) {
 	// Decode base64
 	decoded, err := base64.StdEncoding.DecodeString(vocabBase64)
 	if err != nil {
@@ -22,6 +28,9 @@ func DecodeVocabulary(vocabBase64 string) ([]string, error) {
 
 	// Split by newlines to get individual tokens
 	vocabString := string(decoded)
+	if limits.MaxTokens > 0 && strings.Count(vocabString, "\n") > limits.MaxTokens {
+		return nil, fmt.Errorf("%w: more than %d tokens", ErrLimitExceeded, limits.MaxTokens)
+	}
 	tokens := strings.Split(vocabString, "\n")
 
 	// Filter out empty tokens
@@ -31,6 +40,9 @@ func DecodeVocabulary(vocabBase64 string) ([]string, error) {
 			result = append(result, token)
 		}
 	}
+	if err := limits.CheckTokens(result); err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
@@ -70,6 +82,19 @@ func DecompressMergeRules(mergesBinary string, vocabByID []string, getMergeIdent
 	return merges, nil
 }
 
+// DecompressMergePairs decompresses the base64-encoded merge data into a flat
+// list of token ID pairs in priority order: [left0, right0, left1, right1, ...].
+// The merge at pair index i has priority i+1.
+func DecompressMergePairs(mergesBinary string) ([]int, error) {
+	decoded, err := base64.StdEncoding.DecodeString(mergesBinary)
+	if err != nil {
+		return nil, fmt.Errorf("decode merges base64: %w", err)
+	}
+
+	tokenIDs := unpackTokenPairIDs(decoded)
+	return tokenIDs[:len(tokenIDs)&^1], nil
+}
+
 // unpackTokenPairIDs unpacks 17-bit integers from a byte array.
 // This is used to decode the compressed merge data where each pair represents token IDs.
 func unpackTokenPairIDs(data []byte) []int {
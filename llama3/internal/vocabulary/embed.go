@@ -1,3 +1,5 @@
+//go:build !tokenizer_tiny
+
 // Package vocabulary contains embedded vocabulary data files for the Llama 3 tokenizer.
 // These files are from the llama3-tokenizer-js project:
 // https://github.com/belladoreai/llama3-tokenizer-js
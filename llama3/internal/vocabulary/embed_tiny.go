@@ -0,0 +1,13 @@
+//go:build tokenizer_tiny
+
+package vocabulary
+
+// The tokenizer_tiny build leaves out the embedded vocabulary, which adds
+// about 3MB to the binary. Data must be supplied from files or a custom
+// loader instead.
+
+// EmbeddedVocabulary is empty in tokenizer_tiny builds.
+var EmbeddedVocabulary string
+
+// EmbeddedMergeRules is empty in tokenizer_tiny builds.
+var EmbeddedMergeRules string
@@ -0,0 +1,53 @@
+package vocabulary
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// maxMergeID is the largest token ID that fits in bitsPerMergeID bits.
+const maxMergeID = 1<<bitsPerMergeID - 1
+
+// EncodeVocabulary encodes tokens in the format read by DecodeVocabulary.
+// Tokens must be non-empty and must not contain newlines.
+func EncodeVocabulary(tokens []string) (string, error) {
+	for id, token := range tokens {
+		if token == "" || strings.Contains(token, "\n") {
+			return "", fmt.Errorf("token %d cannot be encoded: %q", id, token)
+		}
+	}
+	return base64.StdEncoding.EncodeToString([]byte(strings.Join(tokens, "\n"))), nil
+}
+
+// CompressMergePairs encodes a flat list of token ID pairs in priority order
+// in the format read by DecompressMergePairs.
+func CompressMergePairs(pairs []int) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("odd number of merge token IDs: %d", len(pairs))
+	}
+	for _, id := range pairs {
+		if id < 0 || id > maxMergeID {
+			return "", fmt.Errorf("merge token ID %d does not fit in %d bits", id, bitsPerMergeID)
+		}
+	}
+	return base64.StdEncoding.EncodeToString(packTokenPairIDs(pairs)), nil
+}
+
+// packTokenPairIDs packs integers into 17-bit big-endian fields, the inverse
+// of unpackTokenPairIDs. The final byte is zero-padded.
+func packTokenPairIDs(tokenIDs []int) []byte {
+	data := make([]byte, (len(tokenIDs)*bitsPerMergeID+7)/8)
+
+	bitIndex := 0
+	for _, tokenID := range tokenIDs {
+		for bit := bitsPerMergeID - 1; bit >= 0; bit-- {
+			if tokenID&(1<<bit) != 0 {
+				data[bitIndex/8] |= 0x80 >> (bitIndex % 8)
+			}
+			bitIndex++
+		}
+	}
+
+	return data
+}
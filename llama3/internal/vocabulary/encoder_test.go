@@ -0,0 +1,89 @@
+package vocabulary
+
+import (
+	"testing"
+)
+
+func TestCompressMergePairsRoundTrip(t *testing.T) {
+	tests := [][]int{
+		{},
+		{0, 1},
+		{maxMergeID, 0, 1, maxMergeID},
+		{256, 257, 1000, 127999, 42, 7, 65535, 65536},
+	}
+
+	for _, pairs := range tests {
+		packed, err := CompressMergePairs(pairs)
+		if err != nil {
+			t.Fatalf("CompressMergePairs(%v) failed: %v", pairs, err)
+		}
+		got, err := DecompressMergePairs(packed)
+		if err != nil {
+			t.Fatalf("DecompressMergePairs failed: %v", err)
+		}
+		if len(got) != len(pairs) {
+			t.Fatalf("Expected %d IDs, got %d (%v)", len(pairs), len(got), got)
+		}
+		for i := range pairs {
+			if got[i] != pairs[i] {
+				t.Errorf("Expected %v, got %v", pairs, got)
+				break
+			}
+		}
+	}
+
+	if _, err := CompressMergePairs([]int{1, maxMergeID + 1}); err == nil {
+		t.Error("Expected error for an ID wider than 17 bits")
+	}
+	if _, err := CompressMergePairs([]int{1}); err == nil {
+		t.Error("Expected error for an odd number of IDs")
+	}
+}
+
+func TestEncodeVocabularyRoundTrip(t *testing.T) {
+	tokens := []string{"a", "Ġthe", "Ċ", "ĠĠ"}
+
+	encoded, err := EncodeVocabulary(tokens)
+	if err != nil {
+		t.Fatalf("EncodeVocabulary failed: %v", err)
+	}
+	got, err := DecodeVocabulary(encoded)
+	if err != nil {
+		t.Fatalf("DecodeVocabulary failed: %v", err)
+	}
+	if len(got) != len(tokens) {
+		t.Fatalf("Expected %d tokens, got %d", len(tokens), len(got))
+	}
+	for i := range tokens {
+		if got[i] != tokens[i] {
+			t.Errorf("Expected token %d to be %q, got %q", i, tokens[i], got[i])
+		}
+	}
+
+	if _, err := EncodeVocabulary([]string{"a", ""}); err == nil {
+		t.Error("Expected error for an empty token")
+	}
+}
+
+func TestEmbeddedMergesRepack(t *testing.T) {
+	pairs, err := DecompressMergePairs(EmbeddedMergeRules)
+	if err != nil {
+		t.Fatalf("DecompressMergePairs failed: %v", err)
+	}
+	packed, err := CompressMergePairs(pairs)
+	if err != nil {
+		t.Fatalf("CompressMergePairs failed: %v", err)
+	}
+	repacked, err := DecompressMergePairs(packed)
+	if err != nil {
+		t.Fatalf("DecompressMergePairs failed: %v", err)
+	}
+	if len(repacked) != len(pairs) {
+		t.Fatalf("Expected %d IDs, got %d", len(pairs), len(repacked))
+	}
+	for i := range pairs {
+		if repacked[i] != pairs[i] {
+			t.Fatalf("Expected ID %d at %d, got %d", pairs[i], i, repacked[i])
+		}
+	}
+}
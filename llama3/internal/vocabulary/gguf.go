@@ -0,0 +1,122 @@
+package vocabulary
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/agentstation/tokenizer/llama3/internal/gguf"
+)
+
+// ggufTokenNormal is the tokenizer.ggml.token_type of regular tokens.
+const ggufTokenNormal = 1
+
+// LoadGGUF reads the tokenizer metadata of a GGUF model file, refusing
+// data that exceeds limits. Only the metadata at the start of the file is
+// read; MaxFileSize bounds its size rather than that of the whole file,
+// which is mostly tensor data.
+func LoadGGUF(path string, limits Limits) (*HFTokenizer, error) {
+	f, err := os.Open(path) // #nosec G304 - path is provided by the caller
+	if err != nil {
+		return nil, fmt.Errorf("read GGUF %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	kvs, err := gguf.Read(f, limits.MaxFileSize)
+	if errors.Is(err, gguf.ErrMetadataTooLarge) {
+		err = fmt.Errorf("%w: metadata is larger than %d bytes", ErrLimitExceeded, limits.MaxFileSize)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read GGUF %s: %w", path, err)
+	}
+	data, err := ParseGGUF(kvs, limits)
+	if err != nil {
+		return nil, fmt.Errorf("parse GGUF %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// ParseGGUF extracts the byte-level BPE data from GGUF metadata, in the
+// form used for tokenizer.json files.
+//
+// tokenizer.ggml.model must be "gpt2", llama.cpp's name for byte-level BPE.
+// Tokens of tokenizer.ggml.token_type normal are the regular tokens; all
+// others, such as control tokens, are special and must follow the regular
+// tokens, as in Llama 3 models. Merges naming unknown tokens are rejected.
+func ParseGGUF(kvs []gguf.KeyValue, limits Limits) (*HFTokenizer, error) {
+	meta := make(map[string]any, len(kvs))
+	for _, kv := range kvs {
+		meta[kv.Key] = kv.Value
+	}
+	if model, _ := meta["tokenizer.ggml.model"].(string); model != "gpt2" {
+		return nil, fmt.Errorf("tokenizer model %q is not gpt2 (byte-level BPE)", model)
+	}
+	tokens, ok := meta["tokenizer.ggml.tokens"].([]string)
+	if !ok {
+		return nil, errors.New("tokenizer.ggml.tokens is missing or not a string array")
+	}
+	if err := limits.CheckTokens(tokens); err != nil {
+		return nil, err
+	}
+
+	regularLen := len(tokens)
+	if raw, ok := meta["tokenizer.ggml.token_type"]; ok {
+		types, ok := raw.([]int32)
+		if !ok || len(types) != len(tokens) {
+			return nil, errors.New("tokenizer.ggml.token_type is not an int32 array with a type per token")
+		}
+		regularLen = 0
+		for regularLen < len(types) && types[regularLen] == ggufTokenNormal {
+			regularLen++
+		}
+		for id := regularLen; id < len(types); id++ {
+			if types[id] == ggufTokenNormal {
+				return nil, fmt.Errorf("regular token %d %q follows special tokens", id, tokens[id])
+			}
+		}
+	}
+
+	lookup := make(map[string]int, regularLen)
+	for id, token := range tokens[:regularLen] {
+		if token == "" {
+			return nil, fmt.Errorf("token %d is empty", id)
+		}
+		if _, dup := lookup[token]; dup {
+			return nil, fmt.Errorf("token %q appears more than once", token)
+		}
+		lookup[token] = id
+	}
+
+	var rules []string
+	if raw, ok := meta["tokenizer.ggml.merges"]; ok {
+		if rules, ok = raw.([]string); !ok {
+			return nil, errors.New("tokenizer.ggml.merges is not a string array")
+		}
+	}
+	if err := limits.CheckMerges(len(rules)); err != nil {
+		return nil, err
+	}
+	merges := make([]int, 0, 2*len(rules))
+	for i, rule := range rules {
+		left, right, ok := strings.Cut(rule, " ")
+		if !ok {
+			return nil, fmt.Errorf("merge %d: %q has no space", i, rule)
+		}
+		leftID, ok := lookup[left]
+		if !ok {
+			return nil, fmt.Errorf("merge %d: unknown token %q", i, left)
+		}
+		rightID, ok := lookup[right]
+		if !ok {
+			return nil, fmt.Errorf("merge %d: unknown token %q", i, right)
+		}
+		merges = append(merges, leftID, rightID)
+	}
+
+	return &HFTokenizer{
+		Vocab:   tokens[:regularLen:regularLen],
+		Merges:  merges,
+		Special: tokens[regularLen:],
+	}, nil
+}
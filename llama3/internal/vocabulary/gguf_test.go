@@ -0,0 +1,63 @@
+package vocabulary
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3/internal/gguf"
+)
+
+func TestParseGGUF(t *testing.T) {
+	kvs := []gguf.KeyValue{
+		kv("tokenizer.ggml.model", "gpt2"),
+		kv("tokenizer.ggml.tokens", []string{"a", "b", "ab", "<|begin_of_text|>", "<|eot_id|>"}),
+		kv("tokenizer.ggml.token_type", []int32{1, 1, 1, 3, 3}),
+		kv("tokenizer.ggml.merges", []string{"a b"}),
+	}
+	data, err := ParseGGUF(kvs, Limits{})
+	if err != nil {
+		t.Fatalf("ParseGGUF failed: %v", err)
+	}
+	if want := []string{"a", "b", "ab"}; !slices.Equal(data.Vocab, want) {
+		t.Errorf("Expected vocabulary %q, got %q", want, data.Vocab)
+	}
+	if want := []int{0, 1}; !slices.Equal(data.Merges, want) {
+		t.Errorf("Expected merges %v, got %v", want, data.Merges)
+	}
+	if want := []string{"<|begin_of_text|>", "<|eot_id|>"}; !slices.Equal(data.Special, want) {
+		t.Errorf("Expected special tokens %q, got %q", want, data.Special)
+	}
+
+	if _, err := ParseGGUF(kvs, Limits{MaxTokens: 4}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded, got %v", err)
+	}
+}
+
+func TestParseGGUFErrors(t *testing.T) {
+	gpt2 := kv("tokenizer.ggml.model", "gpt2")
+	tokens := kv("tokenizer.ggml.tokens", []string{"a", "<|x|>"})
+	tests := []struct {
+		name string
+		kvs  []gguf.KeyValue
+	}{
+		{"not gpt2", []gguf.KeyValue{kv("tokenizer.ggml.model", "llama"), tokens}},
+		{"no tokens", []gguf.KeyValue{gpt2}},
+		{"type count", []gguf.KeyValue{gpt2, tokens, kv("tokenizer.ggml.token_type", []int32{1})}},
+		{"regular after special", []gguf.KeyValue{gpt2, tokens, kv("tokenizer.ggml.token_type", []int32{3, 1})}},
+		{"duplicate token", []gguf.KeyValue{gpt2, kv("tokenizer.ggml.tokens", []string{"a", "a"})}},
+		{"unknown merge token", []gguf.KeyValue{gpt2, tokens, kv("tokenizer.ggml.merges", []string{"a z"})}},
+		{"merge without space", []gguf.KeyValue{gpt2, tokens, kv("tokenizer.ggml.merges", []string{"aa"})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseGGUF(tt.kvs, Limits{}); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
+
+func kv(key string, value any) gguf.KeyValue {
+	return gguf.KeyValue{Key: key, Value: value}
+}
@@ -0,0 +1,127 @@
+//go:build !tokenizer_tiny
+
+package vocabulary
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// hfTokenizerJSON is the part of tokenizer.json read by LoadHFTokenizerJSON.
+type hfTokenizerJSON struct {
+	AddedTokens []hfAddedToken `json:"added_tokens"`
+	Model       struct {
+		Type   string            `json:"type"`
+		Vocab  map[string]int    `json:"vocab"`
+		Merges []json.RawMessage `json:"merges"`
+	} `json:"model"`
+}
+
+// hfAddedToken is an entry of added_tokens, such as a special token.
+type hfAddedToken struct {
+	ID      int    `json:"id"`
+	Content string `json:"content"`
+}
+
+// LoadHFTokenizerJSON reads a Hugging Face tokenizer.json file with a BPE
+// model, refusing data that exceeds limits.
+func LoadHFTokenizerJSON(path string, limits Limits) (*HFTokenizer, error) {
+	data, err := limits.readFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tokenizer.json %s: %w", path, err)
+	}
+	hf, err := ParseHFTokenizerJSON(data, limits)
+	if err != nil {
+		return nil, fmt.Errorf("parse tokenizer.json %s: %w", path, err)
+	}
+	return hf, nil
+}
+
+// ParseHFTokenizerJSON parses the contents of a Hugging Face tokenizer.json
+// file with a BPE model.
+//
+// The regular token IDs must run from 0 without gaps, and the added tokens
+// must take the IDs directly after them, as in the Llama 3 release. Merges
+// may be given as "left right" strings or as [left, right] pairs; merges
+// naming unknown tokens are rejected.
+func ParseHFTokenizerJSON(data []byte, limits Limits) (*HFTokenizer, error) {
+	var doc hfTokenizerJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Model.Type != "BPE" {
+		return nil, fmt.Errorf("model type %q is not BPE", doc.Model.Type)
+	}
+
+	if limits.MaxTokens > 0 && len(doc.Model.Vocab) > limits.MaxTokens {
+		return nil, fmt.Errorf("%w: %d tokens, maximum is %d", ErrLimitExceeded, len(doc.Model.Vocab), limits.MaxTokens)
+	}
+	vocab := make([]string, len(doc.Model.Vocab))
+	for token, id := range doc.Model.Vocab {
+		if id < 0 || id >= len(vocab) || vocab[id] != "" {
+			return nil, fmt.Errorf("token %q has ID %d, IDs must run from 0 to %d without gaps", token, id, len(vocab)-1)
+		}
+		if token == "" {
+			return nil, fmt.Errorf("token %d is empty", id)
+		}
+		vocab[id] = token
+	}
+	if err := limits.CheckTokens(vocab); err != nil {
+		return nil, err
+	}
+
+	added := slices.Clone(doc.AddedTokens)
+	slices.SortFunc(added, func(a, b hfAddedToken) int { return a.ID - b.ID })
+	special := make([]string, len(added))
+	for i, tok := range added {
+		if tok.ID != len(vocab)+i {
+			return nil, fmt.Errorf("added token %q has ID %d, expected %d after the regular tokens", tok.Content, tok.ID, len(vocab)+i)
+		}
+		special[i] = tok.Content
+	}
+
+	if err := limits.CheckMerges(len(doc.Model.Merges)); err != nil {
+		return nil, err
+	}
+	merges := make([]int, 0, 2*len(doc.Model.Merges))
+	for i, raw := range doc.Model.Merges {
+		left, right, err := parseHFMerge(raw)
+		if err != nil {
+			return nil, fmt.Errorf("merge %d: %w", i, err)
+		}
+		leftID, ok := doc.Model.Vocab[left]
+		if !ok {
+			return nil, fmt.Errorf("merge %d: unknown token %q", i, left)
+		}
+		rightID, ok := doc.Model.Vocab[right]
+		if !ok {
+			return nil, fmt.Errorf("merge %d: unknown token %q", i, right)
+		}
+		merges = append(merges, leftID, rightID)
+	}
+
+	return &HFTokenizer{Vocab: vocab, Merges: merges, Special: special}, nil
+}
+
+// parseHFMerge parses a merge written as "left right" or ["left", "right"].
+func parseHFMerge(raw json.RawMessage) (left, right string, err error) {
+	var pair []string
+	if err := json.Unmarshal(raw, &pair); err == nil {
+		if len(pair) != 2 {
+			return "", "", fmt.Errorf("expected 2 tokens, got %d", len(pair))
+		}
+		return pair[0], pair[1], nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", "", fmt.Errorf("expected a string or a pair of strings: %s", raw)
+	}
+	left, right, ok := strings.Cut(s, " ")
+	if !ok {
+		return "", "", fmt.Errorf("merge %q has no space", s)
+	}
+	return left, right, nil
+}
@@ -0,0 +1,57 @@
+//go:build !tokenizer_tiny
+
+package vocabulary
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseHFTokenizerJSON(t *testing.T) {
+	data := `{
+		"added_tokens": [
+			{"id": 5, "content": "<|eot_id|>", "special": true},
+			{"id": 4, "content": "<|begin_of_text|>", "special": true}
+		],
+		"model": {
+			"type": "BPE",
+			"vocab": {"a": 0, "b": 1, "ab": 2, "abb": 3},
+			"merges": ["a b", ["ab", "b"]]
+		}
+	}`
+	hf, err := ParseHFTokenizerJSON([]byte(data), Limits{})
+	if err != nil {
+		t.Fatalf("ParseHFTokenizerJSON failed: %v", err)
+	}
+	if want := []string{"a", "b", "ab", "abb"}; !slices.Equal(hf.Vocab, want) {
+		t.Errorf("Expected vocabulary %q, got %q", want, hf.Vocab)
+	}
+	if want := []int{0, 1, 2, 1}; !slices.Equal(hf.Merges, want) {
+		t.Errorf("Expected merges %v, got %v", want, hf.Merges)
+	}
+	if want := []string{"<|begin_of_text|>", "<|eot_id|>"}; !slices.Equal(hf.Special, want) {
+		t.Errorf("Expected special tokens %q, got %q", want, hf.Special)
+	}
+}
+
+func TestParseHFTokenizerJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid JSON", `{`},
+		{"not BPE", `{"model": {"type": "Unigram"}}`},
+		{"duplicate ID", `{"model": {"type": "BPE", "vocab": {"a": 0, "b": 0}}}`},
+		{"added token gap", `{"added_tokens": [{"id": 2, "content": "<|x|>"}], "model": {"type": "BPE", "vocab": {"a": 0}}}`},
+		{"unknown merge token", `{"model": {"type": "BPE", "vocab": {"a": 0}, "merges": ["a z"]}}`},
+		{"merge without space", `{"model": {"type": "BPE", "vocab": {"a": 0}, "merges": ["aa"]}}`},
+		{"merge triple", `{"model": {"type": "BPE", "vocab": {"a": 0}, "merges": [["a", "a", "a"]]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseHFTokenizerJSON([]byte(tt.data), Limits{}); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package vocabulary
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ErrLimitExceeded is returned when vocabulary data exceeds a Limits field.
+var ErrLimitExceeded = errors.New("data limit exceeded")
+
+// Limits bounds the vocabulary data accepted from untrusted sources, so that a
+// malicious file cannot make loading allocate unbounded memory. Zero fields
+// are not checked.
+type Limits struct {
+	MaxFileSize    int64 // Bytes per data file
+	MaxTokens      int   // Vocabulary entries
+	MaxTokenLength int   // Bytes per vocabulary entry
+	MaxMerges      int   // Merge rules
+}
+
+// CheckTokens checks a decoded vocabulary.
+func (l Limits) CheckTokens(tokens []string) error {
+	if l.MaxTokens > 0 && len(tokens) > l.MaxTokens {
+		return fmt.Errorf("%w: %d tokens, maximum is %d", ErrLimitExceeded, len(tokens), l.MaxTokens)
+	}
+	if l.MaxTokenLength > 0 {
+		for id, token := range tokens {
+			if len(token) > l.MaxTokenLength {
+				return fmt.Errorf("%w: token %d is %d bytes, maximum is %d", ErrLimitExceeded, id, len(token), l.MaxTokenLength)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckMerges checks a number of merge rules.
+func (l Limits) CheckMerges(n int) error {
+	if l.MaxMerges > 0 && n > l.MaxMerges {
+		return fmt.Errorf("%w: %d merges, maximum is %d", ErrLimitExceeded, n, l.MaxMerges)
+	}
+	return nil
+}
+
+// CheckMergesData checks the number of merge rules in packed merges data
+// before it is decompressed.
+func (l Limits) CheckMergesData(mergesBinary string) error {
+	bits := base64.StdEncoding.DecodedLen(len(mergesBinary)) * 8
+	return l.CheckMerges(bits / (2 * bitsPerMergeID))
+}
+
+// checkSize checks the size of data held in memory against MaxFileSize.
+func (l Limits) checkSize(n int) error {
+	if l.MaxFileSize > 0 && int64(n) > l.MaxFileSize {
+		return fmt.Errorf("%w: data is larger than %d bytes", ErrLimitExceeded, l.MaxFileSize)
+	}
+	return nil
+}
+
+// readFile reads a whole file, refusing files larger than MaxFileSize.
+func (l Limits) readFile(path string) ([]byte, error) {
+	if l.MaxFileSize <= 0 {
+		return os.ReadFile(path) // #nosec G304 - path is provided by the caller
+	}
+
+	f, err := os.Open(path) // #nosec G304 - path is provided by the caller
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	// Read one byte past the limit to detect larger files, including files
+	// that grow while being read.
+	data, err := io.ReadAll(io.LimitReader(f, l.MaxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > l.MaxFileSize {
+		return nil, fmt.Errorf("%w: file is larger than %d bytes", ErrLimitExceeded, l.MaxFileSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,52 @@
+package vocabulary
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLimitsReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	for _, max := range []int64{0, 10, 11} {
+		if data, err := (Limits{MaxFileSize: max}).readFile(path); err != nil || len(data) != 10 {
+			t.Errorf("MaxFileSize %d: expected 10 bytes, got %d (%v)", max, len(data), err)
+		}
+	}
+	if _, err := (Limits{MaxFileSize: 9}).readFile(path); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded, got %v", err)
+	}
+}
+
+func TestLimitsCheckMergesData(t *testing.T) {
+	packed, err := CompressMergePairs([]int{1, 2, 3, 4, 5, 6})
+	if err != nil {
+		t.Fatalf("CompressMergePairs failed: %v", err)
+	}
+	if err := (Limits{MaxMerges: 3}).CheckMergesData(packed); err != nil {
+		t.Errorf("Expected 3 merges to pass, got %v", err)
+	}
+	if err := (Limits{MaxMerges: 2}).CheckMergesData(packed); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected ErrLimitExceeded, got %v", err)
+	}
+}
+
+func TestDecodeVocabularyLimits(t *testing.T) {
+	encoded, err := EncodeVocabulary([]string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("EncodeVocabulary failed: %v", err)
+	}
+	if _, err := decodeVocabulary(encoded, Limits{MaxTokens: 3, MaxTokenLength: 3}); err != nil {
+		t.Errorf("Expected the vocabulary to pass, got %v", err)
+	}
+	for _, limits := range []Limits{{MaxTokens: 2}, {MaxTokenLength: 2}} {
+		if _, err := decodeVocabulary(encoded, limits); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%+v: expected ErrLimitExceeded, got %v", limits, err)
+		}
+	}
+}
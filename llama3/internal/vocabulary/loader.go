@@ -2,7 +2,6 @@ package vocabulary
 
 import (
 	"fmt"
-	"os"
 )
 
 // EmbeddedDataLoader implements data loading using the default embedded data.
@@ -49,6 +48,7 @@ func (d *EmbeddedDataLoader) LoadMergesData() (string, error) {
 type FileLoader struct {
 	VocabPath  string
 	MergesPath string
+	Limits     Limits // Bounds on the data read; zero fields are not checked
 }
 
 // NewFileLoader creates a loader that reads from files.
@@ -61,18 +61,56 @@ func NewFileLoader(vocabPath, mergesPath string) *FileLoader {
 
 // LoadVocabulary reads and decodes vocabulary from a file.
 func (f *FileLoader) LoadVocabulary() ([]string, error) {
-	data, err := os.ReadFile(f.VocabPath)
+	data, err := f.Limits.readFile(f.VocabPath)
 	if err != nil {
 		return nil, fmt.Errorf("read vocabulary file %s: %w", f.VocabPath, err)
 	}
-	return DecodeVocabulary(string(data))
+	return decodeVocabulary(string(data), f.Limits)
 }
 
 // LoadMergesData reads merges data from a file.
 func (f *FileLoader) LoadMergesData() (string, error) {
-	data, err := os.ReadFile(f.MergesPath)
+	data, err := f.Limits.readFile(f.MergesPath)
 	if err != nil {
 		return "", fmt.Errorf("read merges file %s: %w", f.MergesPath, err)
 	}
+	if err := f.Limits.CheckMergesData(string(data)); err != nil {
+		return "", fmt.Errorf("read merges file %s: %w", f.MergesPath, err)
+	}
 	return string(data), nil
 }
+
+// MemoryLoader implements data loading from data held in memory, in the
+// format of the files read by FileLoader.
+type MemoryLoader struct {
+	Vocab  string
+	Merges string
+	Limits Limits // Bounds on the data; zero fields are not checked
+}
+
+// LoadVocabulary decodes the vocabulary data.
+func (m *MemoryLoader) LoadVocabulary() ([]string, error) {
+	if err := m.Limits.checkSize(len(m.Vocab)); err != nil {
+		return nil, fmt.Errorf("vocabulary data: %w", err)
+	}
+	return decodeVocabulary(m.Vocab, m.Limits)
+}
+
+// LoadMergesData returns the merges data.
+func (m *MemoryLoader) LoadMergesData() (string, error) {
+	if err := m.Limits.checkSize(len(m.Merges)); err != nil {
+		return "", fmt.Errorf("merges data: %w", err)
+	}
+	if err := m.Limits.CheckMergesData(m.Merges); err != nil {
+		return "", fmt.Errorf("merges data: %w", err)
+	}
+	return m.Merges, nil
+}
+
+// HFTokenizer is the byte-level BPE data of a Hugging Face tokenizer.json
+// or GGUF file, in the form used by the tokenizer.
+type HFTokenizer struct {
+	Vocab   []string // Regular tokens indexed by ID
+	Merges  []int    // Token ID pairs in priority order, as returned by DecompressMergePairs
+	Special []string // Added tokens, whose IDs follow the regular tokens
+}
@@ -0,0 +1,59 @@
+package llama3
+
+import "iter"
+
+// TokenText returns the text of the token with the given ID, as Decode
+// would produce it for the token alone, or ErrInvalidTokenID if the ID is
+// not in the vocabulary. Byte-level tokens may hold part of a UTF-8
+// character, so the text is not always valid UTF-8.
+func (t *Tokenizer) TokenText(id int) (string, error) {
+	if id < 0 || id >= len(t.tokens) {
+		if text, ok := t.addedToken(id); ok {
+			return text, nil
+		}
+		return "", NewTokenIDError("token text", id, ErrInvalidTokenID)
+	}
+	if id >= t.regularLen {
+		return t.tokens[id], nil
+	}
+	return string(decodeTokenBytes(t.tokens[id])), nil
+}
+
+// TokenID returns the ID of the token whose text is text, such as " hello"
+// or "<|eot_id|>", and whether there is one. It is the inverse of TokenText.
+func (t *Tokenizer) TokenID(text string) (int, bool) {
+	if id, ok := t.specialTokenID(text); ok && id >= t.regularLen {
+		return id, true
+	}
+	id, ok := t.tokenLookup[encodeBytes([]byte(text))]
+	if !ok || id >= t.regularLen {
+		return 0, false
+	}
+	return id, true
+}
+
+// IsSpecialID reports whether id is a special token, such as
+// <|begin_of_text|>, rather than a token of text.
+func (t *Tokenizer) IsSpecialID(id int) bool {
+	return id >= t.regularLen && id < t.VocabSize()
+}
+
+// Vocabulary returns an iterator over the IDs and texts of all tokens, in
+// ID order, with texts as returned by TokenText. It is meant for tools such
+// as builders of logit bias maps:
+//
+//	for id, text := range tokenizer.Vocabulary() {
+//	    if strings.Contains(text, "\n") {
+//	        bias[id] = -100
+//	    }
+//	}
+func (t *Tokenizer) Vocabulary() iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		for id := range t.VocabSize() {
+			text, _ := t.TokenText(id)
+			if !yield(id, text) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package llama3
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVocabularyIntrospection(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	tests := []struct {
+		text    string
+		special bool
+	}{
+		{"Hello", false},
+		{" world", false},
+		{"\n\n", false},
+		{"<|begin_of_text|>", true},
+		{"<|eot_id|>", true},
+	}
+	for _, tt := range tests {
+		id, ok := tokenizer.TokenID(tt.text)
+		if !ok {
+			t.Errorf("TokenID(%q): expected a token", tt.text)
+			continue
+		}
+		if got, err := tokenizer.TokenText(id); err != nil || got != tt.text {
+			t.Errorf("TokenText(%d): expected %q, got %q, %v", id, tt.text, got, err)
+		}
+		if got := tokenizer.IsSpecialID(id); got != tt.special {
+			t.Errorf("IsSpecialID(%d): expected %v, got %v", id, tt.special, got)
+		}
+		if tt.special {
+			continue
+		}
+		if want := tokenizer.Encode(tt.text, noSpecialTokens); len(want) != 1 || want[0] != id {
+			t.Errorf("TokenID(%q): expected the token of Encode %v, got %d", tt.text, want, id)
+		}
+	}
+
+	if id, ok := tokenizer.TokenID("not one token at all"); ok {
+		t.Errorf("Expected no token, got %d", id)
+	}
+	for _, id := range []int{-1, tokenizer.VocabSize()} {
+		if _, err := tokenizer.TokenText(id); !errors.Is(err, ErrInvalidTokenID) {
+			t.Errorf("TokenText(%d): expected ErrInvalidTokenID, got %v", id, err)
+		}
+		if tokenizer.IsSpecialID(id) {
+			t.Errorf("IsSpecialID(%d): expected false", id)
+		}
+	}
+
+	n := 0
+	for id, text := range tokenizer.Vocabulary() {
+		if id != n {
+			t.Fatalf("Expected ID %d, got %d", n, id)
+		}
+		if want := tokenizer.Decode([]int{id}); text != want {
+			t.Fatalf("Vocabulary: expected %q for %d, got %q", want, id, text)
+		}
+		n++
+	}
+	if n != tokenizer.VocabSize() {
+		t.Errorf("Expected %d tokens, got %d", tokenizer.VocabSize(), n)
+	}
+
+	// Stops when the loop breaks
+	n = 0
+	for range tokenizer.Vocabulary() {
+		if n++; n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Errorf("Expected 10 iterations, got %d", n)
+	}
+}
@@ -0,0 +1,49 @@
+package llama3
+
+// Approximate per-item sizes used by MemoryUsage, in bytes.
+const (
+	stringHeaderSize = 16 // string header on 64-bit platforms
+	intSize          = 8
+	mergeKeySize     = 8  // uint64 pair key
+	mergeValueSize   = 16 // bpe.Merge
+	// mapOverheadFactor accounts for empty slots and control bytes in Go maps,
+	// which are grown in powers of two.
+	mapOverheadFactor = 2
+	// cacheEntrySize approximates one BPE cache entry: the key, a short token
+	// slice and the map/list bookkeeping around them.
+	cacheEntrySize = 160
+)
+
+// MemoryUsage returns an estimate of the heap memory held by the tokenizer in
+// bytes: the vocabulary, the token lookup and merge tables, and the current
+// contents of the BPE cache. The estimate is meant for budgeting, such as
+// deciding which tokenizers to keep loaded, not for exact accounting.
+func (t *Tokenizer) MemoryUsage() int64 {
+	var size int64
+
+	// Vocabulary
+	size += int64(len(t.tokens)) * stringHeaderSize
+	for _, token := range t.tokens {
+		size += int64(len(token))
+	}
+
+	// Lookup tables; the lookup keys share the vocabulary's string data
+	size += int64(len(t.tokenLookup)) * (stringHeaderSize + intSize) * mapOverheadFactor
+	size += int64(len(t.merges)) * (mergeKeySize + mergeValueSize) * mapOverheadFactor
+
+	// Special tokens added at run time
+	if a := t.loadAdded(); a != nil {
+		size += int64(len(a.tokens)) * stringHeaderSize
+		for _, token := range a.tokens {
+			size += int64(len(token))
+		}
+		size += int64(len(a.lookup)) * (stringHeaderSize + intSize) * mapOverheadFactor
+	}
+
+	// BPE cache
+	if c, ok := t.cache.(interface{ Len() int }); ok {
+		size += int64(c.Len()) * cacheEntrySize
+	}
+
+	return size
+}
@@ -0,0 +1,35 @@
+package llama3
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	measured := int64(after.HeapAlloc) - int64(before.HeapAlloc)
+
+	// The estimate should be within a factor of two of the measured heap
+	estimate := tokenizer.MemoryUsage()
+	if measured > 0 && (estimate < measured/2 || estimate > measured*2) {
+		t.Errorf("Estimate %d bytes is far from measured %d bytes", estimate, measured)
+	}
+
+	// Cached entries add to the estimate
+	tokenizer.Encode("The quick brown fox jumps over the lazy dog.", nil)
+	if grown := tokenizer.MemoryUsage(); grown <= estimate {
+		t.Errorf("Expected usage to grow after caching, got %d <= %d", grown, estimate)
+	}
+
+	runtime.KeepAlive(tokenizer)
+}
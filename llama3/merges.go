@@ -0,0 +1,127 @@
+package llama3
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+	"slices"
+
+	"github.com/agentstation/tokenizer/bpe"
+)
+
+// ErrMergeNotFound indicates that a pair of tokens has no merge rule.
+var ErrMergeNotFound = errors.New("merge not found")
+
+// MergeOverride changes the priority of one merge rule, or removes it.
+//
+// Left and Right name the two tokens that are merged, in the byte-level form
+// used by the vocabulary and by merge lists such as tokenizer.json: a leading
+// space is written as "Ġ", so the merge of " t" and "he" is {Left: "Ġt",
+// Right: "he"}.
+type MergeOverride struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
+
+	// Rank is the new priority of the merge. Lower ranks are applied first;
+	// the loaded merges have ranks 1 through the number of merges, so a
+	// rank of 0 makes a merge win over all others.
+	Rank int `json:"rank,omitempty"`
+
+	// Disable removes the merge, so the two tokens are never combined.
+	// Rank is ignored.
+	Disable bool `json:"disable,omitempty"`
+}
+
+// WithMergeOverrides changes merge priorities when the tokenizer is loaded,
+// for studying tokenization ablations. Overrides are applied in order after
+// the merges are loaded and before anything is cached, so a tokenizer never
+// serves results computed with the original ranks. Loading fails with
+// ErrTokenNotFound or ErrMergeNotFound if an override does not name an
+// existing merge.
+//
+// Normally a pre-token that is itself a vocabulary token is encoded as that
+// token without running BPE. With overrides, BPE always runs, so disabling
+// a merge also affects words the vocabulary contains whole.
+func WithMergeOverrides(overrides ...MergeOverride) Option {
+	return func(cfg *config) error {
+		for _, o := range overrides {
+			if o.Left == "" || o.Right == "" {
+				return NewConfigError("merge_overrides", o, ErrInvalidToken)
+			}
+		}
+		cfg.mergeOverrides = append(cfg.mergeOverrides, overrides...)
+		return nil
+	}
+}
+
+// applyMergeOverrides updates merges in place.
+func applyMergeOverrides(merges map[uint64]bpe.Merge, lookup map[string]int, overrides []MergeOverride) error {
+	for _, o := range overrides {
+		left, ok := lookup[o.Left]
+		if !ok {
+			return NewConfigError("merge_overrides", o, NewTokenError("lookup", o.Left, ErrTokenNotFound))
+		}
+		right, ok := lookup[o.Right]
+		if !ok {
+			return NewConfigError("merge_overrides", o, NewTokenError("lookup", o.Right, ErrTokenNotFound))
+		}
+
+		key := bpe.PairKey(left, right)
+		merge, ok := merges[key]
+		if !ok {
+			return NewConfigError("merge_overrides", o, NewTokenError("merge", o.Left+" "+o.Right, ErrMergeNotFound))
+		}
+
+		if o.Disable {
+			delete(merges, key)
+			continue
+		}
+		merge.Rank = o.Rank
+		merges[key] = merge
+	}
+	return nil
+}
+
+// RankedMerge is a merge rule of the tokenizer: Left and Right are merged
+// into Result, and merges with lower ranks are applied first.
+type RankedMerge struct {
+	Left   int // Token ID of the left token
+	Right  int // Token ID of the right token
+	Result int // Token ID of the merged token
+	Rank   int
+}
+
+// MergeRank returns the rank of the merge of tokens a and b, and whether the
+// tokenizer merges them at all. Encoding combines adjacent tokens in order of
+// increasing rank, so the rank tells which of two overlapping pairs wins.
+func (v *Vocab) MergeRank(a, b int) (rank int, ok bool) {
+	if a < 0 || b < 0 {
+		return 0, false
+	}
+	merge, ok := v.t.merges[bpe.PairKey(a, b)]
+	return merge.Rank, ok
+}
+
+// Merges returns an iterator over all merge rules in order of increasing
+// rank, ties broken by token IDs.
+func (v *Vocab) Merges() iter.Seq[RankedMerge] {
+	return func(yield func(RankedMerge) bool) {
+		merges := make([]RankedMerge, 0, len(v.t.merges))
+		for key, m := range v.t.merges {
+			merges = append(merges, RankedMerge{
+				Left:   int(key >> 32),        // #nosec G115 - keys hold two token IDs
+				Right:  int(key & 0xffffffff), // #nosec G115 - keys hold two token IDs
+				Result: m.ID,
+				Rank:   m.Rank,
+			})
+		}
+		slices.SortFunc(merges, func(a, b RankedMerge) int {
+			return cmp.Or(cmp.Compare(a.Rank, b.Rank), cmp.Compare(a.Left, b.Left), cmp.Compare(a.Right, b.Right))
+		})
+		for _, m := range merges {
+			if !yield(m) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package llama3
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/agentstation/tokenizer/bpe"
+)
+
+func bpePairKey(t *testing.T, tokenizer *Tokenizer, left, right string) uint64 {
+	t.Helper()
+	l, ok := tokenizer.tokenLookup[left]
+	if !ok {
+		t.Fatalf("Token %q not found", left)
+	}
+	r, ok := tokenizer.tokenLookup[right]
+	if !ok {
+		t.Fatalf("Token %q not found", right)
+	}
+	return bpe.PairKey(l, r)
+}
+
+func TestWithMergeOverrides(t *testing.T) {
+	baseline, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	// " the" is a single token, reachable by three different merges
+	text := " the"
+	if got := baseline.Encode(text, noSpecialTokens); len(got) != 1 {
+		t.Fatalf("Expected %q to be a single token, got %v", text, got)
+	}
+
+	t.Run("disable", func(t *testing.T) {
+		tokenizer, err := New(WithMergeOverrides(
+			MergeOverride{Left: "Ġ", Right: "the", Disable: true},
+			MergeOverride{Left: "Ġt", Right: "he", Disable: true},
+			MergeOverride{Left: "Ġth", Right: "e", Disable: true},
+		))
+		if err != nil {
+			t.Fatalf("Failed to create tokenizer: %v", err)
+		}
+
+		tokens := tokenizer.Encode(text, noSpecialTokens)
+		if len(tokens) < 2 {
+			t.Errorf("Expected %q to split without the merge, got %v", text, tokens)
+		}
+		if got := tokenizer.Decode(tokens); got != text {
+			t.Errorf("Round trip = %q, want %q", got, text)
+		}
+
+		// Other tokenizers are unaffected
+		if got := baseline.Encode(text, noSpecialTokens); len(got) != 1 {
+			t.Errorf("Baseline changed: %v", got)
+		}
+	})
+
+	t.Run("rank", func(t *testing.T) {
+		tokenizer, err := New(WithMergeOverrides(MergeOverride{Left: "h", Right: "e", Rank: 0}))
+		if err != nil {
+			t.Fatalf("Failed to create tokenizer: %v", err)
+		}
+
+		key := bpePairKey(t, tokenizer, "h", "e")
+		if got := tokenizer.merges[key].Rank; got != 0 {
+			t.Errorf("Expected rank 0, got %d", got)
+		}
+		if got := baseline.merges[key].Rank; got == 0 {
+			t.Error("Baseline rank changed")
+		}
+
+		for _, input := range []string{"hello there", "the theme", "Hehehe"} {
+			if got := tokenizer.Decode(tokenizer.Encode(input, noSpecialTokens)); got != input {
+				t.Errorf("Round trip = %q, want %q", got, input)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			override MergeOverride
+			want     error
+		}{
+			{"empty", MergeOverride{Left: "", Right: "he"}, ErrInvalidToken},
+			{"unknown_token", MergeOverride{Left: "Ġt", Right: "<not a token>"}, ErrTokenNotFound},
+			{"not_a_merge", MergeOverride{Left: "Ġthe", Right: "Ġthe"}, ErrMergeNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				_, err := New(WithMergeOverrides(tt.override))
+				var configErr *ConfigError
+				if !errors.As(err, &configErr) || !errors.Is(err, tt.want) {
+					t.Errorf("Expected ConfigError wrapping %v, got %v", tt.want, err)
+				}
+			})
+		}
+	})
+}
+
+func TestVocabMerges(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	vocab := tokenizer.Vocab()
+
+	space, the := tokenizer.tokenLookup["Ġ"], tokenizer.tokenLookup["the"]
+	rank, ok := vocab.MergeRank(space, the)
+	if !ok {
+		t.Fatal(`Expected "Ġ" and "the" to merge`)
+	}
+	if _, ok := vocab.MergeRank(the, space); ok {
+		t.Error(`Expected "the" and "Ġ" not to merge`)
+	}
+	if _, ok := vocab.MergeRank(-1, the); ok {
+		t.Error("Expected no merge for a negative token ID")
+	}
+
+	count, prev := 0, -1
+	found := false
+	for m := range vocab.Merges() {
+		if m.Rank < prev {
+			t.Fatalf("Expected merges in rank order, got rank %d after %d", m.Rank, prev)
+		}
+		prev = m.Rank
+		if got, ok := vocab.MergeRank(m.Left, m.Right); !ok || got != m.Rank {
+			t.Fatalf("MergeRank(%d, %d) = %d, %v; expected %d, true", m.Left, m.Right, got, ok, m.Rank)
+		}
+		if m.Left == space && m.Right == the {
+			found = true
+			if m.Rank != rank || tokenizer.tokens[m.Result] != "Ġthe" {
+				t.Errorf(`Expected merge of "Ġ" and "the" with rank %d into "Ġthe", got %+v`, rank, m)
+			}
+		}
+		count++
+	}
+	if count != len(tokenizer.merges) {
+		t.Errorf("Expected %d merges, got %d", len(tokenizer.merges), count)
+	}
+	if !found {
+		t.Error(`Expected the merge of "Ġ" and "the" in Merges`)
+	}
+
+	for range vocab.Merges() {
+		break // stopping early must not panic
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package llama3
+
+import "os"
+
+// mapFile reads the file at path, on platforms without memory mapping.
+func mapFile(path string) (data []byte, unmap func() error, err error) {
+	data, err = os.ReadFile(path) // #nosec G304 - path is provided by the caller
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package llama3
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mapFile maps the file at path into memory read-only. Its pages are the
+// file's page cache pages, shared by every process that maps the file.
+func mapFile(path string) (data []byte, unmap func() error, err error) {
+	f, err := os.Open(path) // #nosec G304 - path is provided by the caller
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	size := info.Size()
+	if size <= 0 || size != int64(int(size)) {
+		return nil, nil, errors.New("file is empty or too large to map")
+	}
+	data, err = syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
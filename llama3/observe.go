@@ -0,0 +1,81 @@
+package llama3
+
+import (
+	"context"
+	"time"
+)
+
+// Operation names an instrumented tokenizer operation.
+type Operation string
+
+// Instrumented operations.
+const (
+	OpEncode Operation = "encode" // Encode, EncodeBytes, AppendTokens and each text of EncodeBatch
+	OpDecode Operation = "decode" // Decode, DecodeBytes, AppendDecode, DecodeTo and the strict variants
+)
+
+// Observation describes one completed tokenizer operation.
+type Observation struct {
+	Op       Operation
+	Start    time.Time
+	Duration time.Duration
+	Bytes    int // size of the text: the input for encode, the output for decode
+	Tokens   int // number of tokens produced or decoded
+
+	// Context is the context the operation ran with, such as that of
+	// EncodeContext, so observers can parent spans to the caller's. It is
+	// nil for operations taking no context.
+	Context context.Context
+}
+
+// Observer receives observations of tokenizer operations, e.g. to record
+// tracing spans or metrics. Observe is called synchronously after the
+// operation and must be safe for concurrent use.
+type Observer interface {
+	Observe(Observation)
+}
+
+// ObserverFunc adapts a function to an Observer.
+type ObserverFunc func(Observation)
+
+// Observe calls f(o).
+func (f ObserverFunc) Observe(o Observation) {
+	f(o)
+}
+
+// WithObserver reports encode and decode operations whose text is at least
+// minBytes long to o. Small operations are skipped so instrumentation does not
+// dominate their cost. The package has no tracing dependencies; adapters for
+// systems such as OpenTelemetry implement Observer.
+func WithObserver(o Observer, minBytes int) Option {
+	return func(cfg *config) error {
+		if minBytes < 0 {
+			return NewConfigError("observe_min_bytes", minBytes, ErrInvalidToken)
+		}
+		cfg.observer = o
+		cfg.observeMinBytes = minBytes
+		return nil
+	}
+}
+
+// observe reports a completed operation.
+func (t *Tokenizer) observe(op Operation, start time.Time, bytes, tokens int) {
+	t.observer.Observe(newObservation(op, start, bytes, tokens))
+}
+
+// observeContext reports a completed operation that ran with ctx.
+func (t *Tokenizer) observeContext(ctx context.Context, op Operation, start time.Time, bytes, tokens int) {
+	o := newObservation(op, start, bytes, tokens)
+	o.Context = ctx
+	t.observer.Observe(o)
+}
+
+func newObservation(op Operation, start time.Time, bytes, tokens int) Observation {
+	return Observation{
+		Op:       op,
+		Start:    start,
+		Duration: time.Since(start),
+		Bytes:    bytes,
+		Tokens:   tokens,
+	}
+}
@@ -0,0 +1,58 @@
+package llama3
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWithObserver(t *testing.T) {
+	var got []Observation
+	tokenizer, err := New(WithObserver(ObserverFunc(func(o Observation) {
+		got = append(got, o)
+	}), 16))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	long := strings.Repeat("hello world ", 4)
+	tokens := tokenizer.Encode(long, nil)
+	tokenizer.Encode("short", nil)
+	tokenizer.AppendTokens([]int{1, 2}, long, noSpecialTokens)
+	tokenizer.Decode(tokens)
+	tokenizer.Decode(tokens[1:2])
+
+	want := []struct {
+		op     Operation
+		bytes  int
+		tokens int
+	}{
+		{OpEncode, len(long), len(tokens)},
+		{OpEncode, len(long), len(tokens) - 2},
+		{OpDecode, len(beginOfTextToken + long + endOfTextToken), len(tokens)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d observations, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		o := got[i]
+		if o.Op != w.op || o.Bytes != w.bytes || o.Tokens != w.tokens {
+			t.Errorf("Observation %d = %+v, want %+v", i, o, w)
+		}
+		if o.Start.IsZero() || o.Duration < 0 {
+			t.Errorf("Observation %d has invalid timing: %+v", i, o)
+		}
+		if o.Context != nil {
+			t.Errorf("Observation %d has a context without EncodeContext", i)
+		}
+	}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "caller")
+	if _, err := tokenizer.EncodeContext(ctx, long, nil); err != nil {
+		t.Fatalf("EncodeContext failed: %v", err)
+	}
+	if o := got[len(got)-1]; o.Context == nil || o.Context.Value(key{}) != "caller" {
+		t.Errorf("Expected the observation of EncodeContext to carry its context, got %v", o.Context)
+	}
+}
@@ -0,0 +1,108 @@
+package llama3
+
+import "unicode/utf8"
+
+// TokenSpan is a token and the byte range of the input it encodes.
+type TokenSpan struct {
+	ID int
+	Span
+}
+
+// EncodeWithOffsets encodes text like Encode and returns each token with the
+// byte range of text it encodes, for highlighting tokens or splitting text
+// into chunks that keep track of their source.
+//
+// The spans of the tokens of text are contiguous and cover it: text[Start:End]
+// is the text of each token, except where an invalid UTF-8 byte, encoded as
+// the three bytes of U+FFFD, is split between tokens; the byte then belongs
+// to the token holding the end of U+FFFD. BOS has the empty span [0, 0), and
+// EOS tokens [len(text), len(text)). opts.Diag is not filled.
+func (t *Tokenizer) EncodeWithOffsets(text string, opts *EncodeOptions) []TokenSpan {
+	if opts == nil {
+		opts = defaultEncodeOptions()
+	}
+
+	spans := make([]TokenSpan, 0, t.estimateTokens(text))
+	if id := t.specialIDs[BeginOfText]; opts.BOS && id >= 0 {
+		spans = append(spans, TokenSpan{ID: id})
+	}
+
+	// Tokens hold one byte per rune of the encoded text, in which each
+	// invalid byte of the input is U+FFFD. encoded is the offset in the
+	// encoded form of the segment at pos.
+	pos := -1
+	var inputOffset func(int) int
+	encoded := 0
+	var buf [segmentBufSize]segment
+	segments := appendSegments(buf[:0], text, t.hasSpecialToken)
+	for p := range t.pieces(segments, t.specialTokenID, nil) {
+		if p.ID >= 0 {
+			spans = append(spans, TokenSpan{ID: p.ID, Span: Span{Start: p.Offset, End: p.Offset + len(p.Segment)}})
+			continue
+		}
+
+		if p.Offset != pos {
+			pos = p.Offset
+			inputOffset = encodedOffsets(p.Segment)
+			encoded = 0
+		}
+		for _, id := range t.performBPE(string(p.Pretoken)) {
+			start := encoded
+			n, _ := t.tokenLen(id)
+			encoded += n
+			spans = append(spans, TokenSpan{ID: id, Span: Span{
+				Start: pos + inputOffset(start),
+				End:   pos + inputOffset(encoded),
+			}})
+		}
+	}
+
+	var eos [4]int
+	for _, id := range t.appendEOS(eos[:0], opts) {
+		spans = append(spans, TokenSpan{ID: id, Span: Span{Start: len(text), End: len(text)}})
+	}
+	return spans
+}
+
+// encodedText returns the form of s that Encode tokenizes, in which each
+// invalid UTF-8 byte is replaced by U+FFFD.
+func encodedText(s string) string {
+	if utf8.ValidString(s) {
+		return s
+	}
+	b := make([]byte, 0, len(s)+16)
+	for _, r := range s {
+		b = utf8.AppendRune(b, r) // invalid bytes range as U+FFFD
+	}
+	return string(b)
+}
+
+// encodedOffsets returns a function that maps an offset in the encoded form
+// of s, where each invalid UTF-8 byte is replaced by U+FFFD, to the offset in
+// s. Offsets inside a replacement map to the start of its byte.
+func encodedOffsets(s string) func(int) int {
+	if utf8.ValidString(s) {
+		return func(offset int) int { return offset }
+	}
+
+	// inputOffset[i] is the offset in s of encoded byte i
+	inputOffset := make([]int, 0, len(s)+16)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			inputOffset = append(inputOffset, i, i, i) // the three bytes of U+FFFD
+			i++
+			continue
+		}
+		for j := 0; j < size; j++ {
+			inputOffset = append(inputOffset, i+j)
+		}
+		i += size
+	}
+	return func(offset int) int {
+		if offset >= len(inputOffset) {
+			return len(s)
+		}
+		return inputOffset[offset]
+	}
+}
@@ -0,0 +1,64 @@
+package llama3
+
+import (
+	"testing"
+)
+
+func TestEncodeWithOffsets(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", ""},
+		{"ascii", "The quick brown fox jumps over the lazy dog."},
+		{"multibyte", "héllo wörld, 你好世界 🎉🎉"},
+		{"whitespace", "  a\t\tb  \n\n  c  "},
+		{"special_tokens", "<|start_header_id|>user<|end_header_id|>\n\nHi!<|eot_id|>"},
+		{"invalid_utf8", "ab\xffcd \xe2\x82 wor\x80ld"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := tokenizer.EncodeWithOffsets(tt.text, noSpecialTokens)
+
+			want := tokenizer.Encode(tt.text, noSpecialTokens)
+			if len(spans) != len(want) {
+				t.Fatalf("Expected %d spans, got %d", len(want), len(spans))
+			}
+			end := 0
+			for i, span := range spans {
+				if span.ID != want[i] {
+					t.Errorf("Token %d: expected ID %d, got %d", i, want[i], span.ID)
+				}
+				if span.Start != end || span.End < span.Start {
+					t.Errorf("Token %d: span [%d, %d) does not follow %d", i, span.Start, span.End, end)
+				}
+				end = span.End
+				if tt.name != "invalid_utf8" {
+					if got, want := tt.text[span.Start:span.End], tokenizer.Decode([]int{span.ID}); got != want {
+						t.Errorf("Token %d: span text %q, expected %q", i, got, want)
+					}
+				}
+			}
+			if end != len(tt.text) {
+				t.Errorf("Expected spans to end at %d, got %d", len(tt.text), end)
+			}
+		})
+	}
+
+	t.Run("bos_eos", func(t *testing.T) {
+		text := "Hello"
+		spans := tokenizer.EncodeWithOffsets(text, nil)
+		first, last := spans[0], spans[len(spans)-1]
+		if first.ID != tokenizer.BOSTokenID() || first.Span != (Span{0, 0}) {
+			t.Errorf("Expected BOS with an empty span at 0, got %+v", first)
+		}
+		if last.ID != tokenizer.EOSTokenID() || last.Span != (Span{len(text), len(text)}) {
+			t.Errorf("Expected EOS with an empty span at %d, got %+v", len(text), last)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package llama3
+
+// DefaultOptimisticPattern is the pattern OptimisticCount counts as special
+// tokens unless WithOptimisticPatterns is given: <|name|> with a name of
+// ASCII letters, digits and underscores, as in Llama 3 and ChatML.
+const DefaultOptimisticPattern = `<\|[a-zA-Z0-9_]+\|>`
+
+// WithOptimisticPatterns sets the regular expressions whose matches
+// OptimisticCount counts as one special token each, for prompts destined
+// for models fine-tuned with other markers:
+//
+//	// Mistral-style instructions and sentence markers
+//	llama3.New(llama3.WithOptimisticPatterns(`\[/?INST\]`, `</?s>`))
+//
+// The patterns replace DefaultOptimisticPattern; pass it as well to keep
+// counting <|name|> tokens. Patterns use the syntax of the regexp package
+// and are matched leftmost first; where two match at the same offset, the
+// earlier pattern wins. Patterns must not match empty text. The option may
+// be given more than once to add patterns. Encode and the other methods are
+// not affected.
+func WithOptimisticPatterns(patterns ...string) Option {
+	return func(cfg *config) error {
+		if err := validatePatterns("optimistic_patterns", patterns); err != nil {
+			return err
+		}
+		cfg.optimisticPatterns = append(cfg.optimisticPatterns, patterns...)
+		return nil
+	}
+}
+
+// appendOptimisticSegments appends the segments of text to dst, split at
+// the pseudo-special tokens OptimisticCount recognizes.
+func (t *Tokenizer) appendOptimisticSegments(dst []segment, text string) []segment {
+	if t.optimistic == nil {
+		return appendSegments(dst, text, looksLikeSpecialToken)
+	}
+	last := 0
+	for _, m := range t.optimistic.FindAllStringIndex(text, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		if last < m[0] {
+			dst = append(dst, segment{Text: text[last:m[0]]})
+		}
+		dst = append(dst, segment{Text: text[m[0]:m[1]], Special: true})
+		last = m[1]
+	}
+	if last < len(text) {
+		dst = append(dst, segment{Text: text[last:]})
+	}
+	return dst
+}
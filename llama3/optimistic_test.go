@@ -0,0 +1,53 @@
+package llama3
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithOptimisticPatterns(t *testing.T) {
+	reference, err := New()
+	if err != nil || reference.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	inst, err := New(WithOptimisticPatterns(`\[/?INST\]`, `</?s>`))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	both, err := New(WithOptimisticPatterns(`\[/?INST\]`), WithOptimisticPatterns(DefaultOptimisticPattern))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	text := func(s string) int { return len(reference.Encode(s, noSpecialTokens)) }
+	tests := []struct {
+		name  string
+		tok   *Tokenizer
+		input string
+		want  int
+	}{
+		{"default", reference, "<|im_start|>user Hi<|im_end|>", 2 + 1 + text("user Hi") + 1},
+		{"default ignores INST", reference, "[INST] Hi [/INST]", 2 + text("[INST] Hi [/INST]")},
+		{"INST", inst, "<s>[INST] Hi [/INST]", 2 + 1 + 1 + text(" Hi ") + 1},
+		{"patterns replace default", inst, "<|im_start|>Hi", 2 + text("<|im_start|>Hi")},
+		{"default added", both, "<|im_start|>[INST] Hi", 2 + 1 + 1 + text(" Hi")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tok.OptimisticCount(tt.input); got != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	if got, want := len(inst.Encode("[INST] Hi [/INST]", nil)), len(reference.Encode("[INST] Hi [/INST]", nil)); got != want {
+		t.Errorf("Expected Encode to ignore the patterns, got %d, want %d", got, want)
+	}
+
+	for _, p := range []string{`[`, `x*`} {
+		var configErr *ConfigError
+		if _, err := New(WithOptimisticPatterns(p)); !errors.As(err, &configErr) {
+			t.Errorf("Pattern %q: expected ConfigError, got %v", p, err)
+		}
+	}
+}
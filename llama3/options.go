@@ -1,12 +1,33 @@
 package llama3
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agentstation/tokenizer/llama3/internal/vocabulary"
+)
 
 // config holds configuration during tokenizer creation.
 type config struct {
 	dataLoader    VocabularyDataLoader
 	specialTokens []string
 	cacheSize     int
+	cacheMinSize  int // > 0 selects an adaptive cache of cacheMinSize to cacheSize entries
+	loadProgress  LoadProgressFunc
+
+	mergeOverrides  []MergeOverride
+	skipVocabLookup bool // set by snapshots of tokenizers with merge overrides
+	loadLimits      *LoadLimits
+
+	observer        Observer
+	observeMinBytes int
+
+	bytesPerToken float64 // 0 estimates capacity from each text
+
+	atomicPatterns []string // pre-tokenized whole, see WithAtomicPatterns
+
+	optimisticPatterns []string // pseudo-special tokens, see WithOptimisticPatterns
 }
 
 // Option is a functional option for configuring a Tokenizer.
@@ -35,6 +56,19 @@ func WithSpecialTokens(tokens []string) Option {
 	}
 }
 
+// WithSpecialTokenProfile sets the special tokens to those of profile, such
+// as a built-in profile from LookupSpecialTokenProfile or one defined for a
+// checkpoint that repurposes reserved tokens. Encode recognizes every token
+// of the profile in text.
+func WithSpecialTokenProfile(profile SpecialTokenProfile) Option {
+	return func(cfg *config) error {
+		if len(profile.Tokens) == 0 {
+			return NewConfigError("special_token_profile", profile.Name, errors.New("profile has no tokens"))
+		}
+		return WithSpecialTokens(profile.Tokens)(cfg)
+	}
+}
+
 // WithCacheSize sets the maximum size of the BPE cache.
 // Set to 0 to disable caching. Default is unlimited.
 func WithCacheSize(size int) Option {
@@ -43,6 +77,24 @@ func WithCacheSize(size int) Option {
 			return NewConfigError("cache_size", size, ErrInvalidToken)
 		}
 		cfg.cacheSize = size
+		cfg.cacheMinSize = 0
+		return nil
+	}
+}
+
+// WithAdaptiveCache uses an LRU cache that sizes itself between minSize and
+// maxSize entries instead of a fixed WithCacheSize. It starts at minSize,
+// doubles while it is full and its hit rate is below 90% and growing still
+// raises the hit rate, and halves when the Go runtime nears its soft memory
+// limit (GOMEMLIMIT). See bpe.AdaptiveLRU for details.
+func WithAdaptiveCache(minSize, maxSize int) Option {
+	return func(cfg *config) error {
+		if minSize < 1 || maxSize < minSize {
+			return NewConfigError("adaptive_cache", [2]int{minSize, maxSize},
+				errors.New("sizes must satisfy 1 <= min <= max"))
+		}
+		cfg.cacheMinSize = minSize
+		cfg.cacheSize = maxSize
 		return nil
 	}
 }
@@ -72,3 +124,78 @@ func WithDataFiles(vocabPath, mergesPath string) Option {
 		return nil
 	}
 }
+
+// WithData loads vocabulary and merges from memory, in the format of the
+// files of WithDataFiles, such as data fetched over the network by a
+// WebAssembly build without the embedded data. The data is checked against
+// the load limits like files are.
+func WithData(vocab, merges []byte) Option {
+	return func(cfg *config) error {
+		if len(vocab) == 0 {
+			return NewConfigError("data", nil, ErrDataNotFound)
+		}
+		// This will be handled in tokenizer initialization
+		cfg.dataLoader = &fileLoaderMarker{
+			data: &vocabulary.MemoryLoader{Vocab: string(vocab), Merges: string(merges)},
+		}
+		return nil
+	}
+}
+
+// LoadLimits bounds the vocabulary data accepted from files and custom data
+// loaders, so that loading a third-party vocabulary cannot allocate unbounded
+// memory. Zero fields are not checked. The embedded vocabulary is trusted and
+// not checked.
+type LoadLimits struct {
+	MaxFileSize    int64 // Bytes per vocabulary or merges file
+	MaxTokens      int   // Regular vocabulary entries
+	MaxTokenLength int   // Bytes per vocabulary entry, as stored in the file
+	MaxMerges      int   // Merge rules
+}
+
+// DefaultLoadLimits returns the limits used when WithLoadLimits is not given.
+// They allow vocabularies several times the size of Llama 3's.
+func DefaultLoadLimits() LoadLimits {
+	return LoadLimits{
+		MaxFileSize:    64 << 20,
+		MaxTokens:      1 << 20,
+		MaxTokenLength: 1024,
+		MaxMerges:      1 << 21,
+	}
+}
+
+// WithLoadLimits replaces the default limits on vocabulary data read by
+// WithDataFiles, WithHFTokenizerJSON and WithDataLoader. Data exceeding a limit makes New fail
+// with a DataError wrapping ErrLimitExceeded.
+func WithLoadLimits(limits LoadLimits) Option {
+	return func(cfg *config) error {
+		if limits.MaxFileSize < 0 || limits.MaxTokens < 0 || limits.MaxTokenLength < 0 || limits.MaxMerges < 0 {
+			return NewConfigError("load_limits", limits, fmt.Errorf("limits must not be negative"))
+		}
+		cfg.loadLimits = &limits
+		return nil
+	}
+}
+
+// LoadProgressFunc receives tokenizer loading progress. stage is one of the
+// LoadStage constants and names the step that is starting; pct is the overall
+// completion percentage (0-100) when it starts.
+type LoadProgressFunc func(stage string, pct float64)
+
+// Loading stages reported to a LoadProgressFunc, in order.
+const (
+	LoadStageVocabulary = "vocabulary" // decoding the vocabulary
+	LoadStageLookup     = "lookup"     // building the token lookup table
+	LoadStageMerges     = "merges"     // decoding and indexing the merge rules
+	LoadStageReady      = "ready"      // loading finished, reported at 100
+)
+
+// WithLoadProgress sets a callback that is invoked as New moves through the
+// loading stages, so services can report startup progress.
+// The callback runs synchronously on the goroutine calling New.
+func WithLoadProgress(fn LoadProgressFunc) Option {
+	return func(cfg *config) error {
+		cfg.loadProgress = fn
+		return nil
+	}
+}
@@ -1,7 +1,13 @@
 package llama3
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/agentstation/tokenizer/bpe"
 )
 
 func TestNewWithOptions(t *testing.T) {
@@ -85,3 +91,154 @@ func TestWithCacheSize(t *testing.T) {
 		})
 	}
 }
+
+func TestWithAdaptiveCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+		wantErr  bool
+	}{
+		{"valid", 1024, 65536, false},
+		{"equal", 100, 100, false},
+		{"zero min", 0, 100, true},
+		{"max below min", 100, 10, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WithAdaptiveCache(tt.min, tt.max)(&config{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WithAdaptiveCache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	tokenizer, err := New(WithAdaptiveCache(16, 1024))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	if _, ok := tokenizer.cache.(*bpe.AdaptiveLRU); !ok {
+		t.Errorf("Expected *bpe.AdaptiveLRU, got %T", tokenizer.cache)
+	}
+	text := "The quick brown fox jumps over the lazy dog."
+	if got := tokenizer.Decode(tokenizer.Encode(text, &EncodeOptions{})); got != text {
+		t.Errorf("Expected %q, got %q", text, got)
+	}
+
+	// A later WithCacheSize replaces the adaptive cache
+	tokenizer, err = New(WithAdaptiveCache(16, 1024), WithCacheSize(100))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	if _, ok := tokenizer.cache.(*bpe.LRUCache); !ok {
+		t.Errorf("Expected *bpe.LRUCache, got %T", tokenizer.cache)
+	}
+}
+
+func TestWithData(t *testing.T) {
+	source, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	pruned, err := source.PruneVocabulary(nil, 1024)
+	if err != nil {
+		t.Fatalf("PruneVocabulary failed: %v", err)
+	}
+	dir := t.TempDir()
+	vocabPath := filepath.Join(dir, "vocab.txt")
+	mergesPath := filepath.Join(dir, "merges.txt")
+	if err := pruned.WriteFiles(vocabPath, mergesPath); err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+	vocab, err := os.ReadFile(vocabPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	merges, err := os.ReadFile(mergesPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	reference, err := New(WithDataFiles(vocabPath, mergesPath))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	tokenizer, err := New(WithData(vocab, merges))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	if tokenizer.VocabSize() != reference.VocabSize() {
+		t.Errorf("Expected %d tokens, got %d", reference.VocabSize(), tokenizer.VocabSize())
+	}
+	input := "<|begin_of_text|>Hello, world! 1234"
+	if got, want := tokenizer.Encode(input, nil), reference.Encode(input, nil); !equalIntSlices(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	_, err = New(WithData(vocab, merges), WithLoadLimits(LoadLimits{MaxFileSize: 100}))
+	var dataErr *DataError
+	if !errors.As(err, &dataErr) || !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected DataError wrapping ErrLimitExceeded, got %v", err)
+	}
+
+	var configErr *ConfigError
+	if _, err := New(WithData(nil, nil)); !errors.As(err, &configErr) {
+		t.Errorf("Expected ConfigError for missing data, got %v", err)
+	}
+}
+
+func TestWithLoadLimits(t *testing.T) {
+	source, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	pruned, err := source.PruneVocabulary(nil, 256)
+	if err != nil {
+		t.Fatalf("PruneVocabulary failed: %v", err)
+	}
+	dir := t.TempDir()
+	vocabPath := filepath.Join(dir, "vocab.txt")
+	mergesPath := filepath.Join(dir, "merges.txt")
+	if err := pruned.WriteFiles(vocabPath, mergesPath); err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+
+	if _, err := New(WithDataFiles(vocabPath, mergesPath)); err != nil {
+		t.Fatalf("Expected the default limits to accept the files, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		limits LoadLimits
+	}{
+		{"file size", LoadLimits{MaxFileSize: 100}},
+		{"tokens", LoadLimits{MaxTokens: 255}},
+		{"token length", LoadLimits{MaxTokenLength: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(WithDataFiles(vocabPath, mergesPath), WithLoadLimits(tt.limits))
+			var dataErr *DataError
+			if !errors.As(err, &dataErr) || !errors.Is(err, ErrLimitExceeded) {
+				t.Errorf("Expected DataError wrapping ErrLimitExceeded, got %v", err)
+			}
+		})
+	}
+
+	t.Run("custom loader", func(t *testing.T) {
+		loader := VocabularyDataLoaderFunc{
+			VocabFunc:  func() ([]string, error) { return []string{"a", strings.Repeat("b", 2000)}, nil },
+			MergesFunc: func() (map[string]int, error) { return map[string]int{}, nil },
+		}
+		if _, err := New(WithDataLoader(loader)); !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("Expected ErrLimitExceeded for a 2000-byte token, got %v", err)
+		}
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		var configErr *ConfigError
+		if _, err := New(WithLoadLimits(LoadLimits{MaxMerges: -1})); !errors.As(err, &configErr) {
+			t.Errorf("Expected ConfigError, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package llama3
+
+// PairOptions controls how two texts are encoded together by EncodePair.
+type PairOptions struct {
+	// BOS adds the beginning-of-text token before the first text.
+	BOS bool
+	// EOS adds the end-of-text token after the second text.
+	EOS bool
+	// Separator is the special token placed between the two texts.
+	// If empty, <|end_of_text|> is used.
+	Separator string
+}
+
+// defaultPairOptions returns the default pair encoding options.
+func defaultPairOptions() *PairOptions {
+	return &PairOptions{
+		BOS:       true,
+		EOS:       true,
+		Separator: endOfTextToken,
+	}
+}
+
+// EncodePair encodes a text pair (A, B) as a single sequence for cross-encoder
+// style models and returns the token IDs together with their token-type
+// (segment) IDs.
+//
+// The sequence layout is:
+//
+//	[BOS] A <separator> B [EOS]
+//
+// Tokens up to and including the separator have type ID 0; tokens of B and
+// the trailing EOS have type ID 1. If opts is nil, BOS, EOS and the
+// <|end_of_text|> separator are used.
+func (t *Tokenizer) EncodePair(a, b string, opts *PairOptions) (tokens []int, typeIDs []int, err error) {
+	if opts == nil {
+		opts = defaultPairOptions()
+	}
+
+	separator := opts.Separator
+	if separator == "" {
+		separator = endOfTextToken
+	}
+	sepID, err := t.GetSpecialTokenID(separator)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	first := t.Encode(a, &EncodeOptions{BOS: opts.BOS, EOS: false})
+	second := t.Encode(b, &EncodeOptions{BOS: false, EOS: opts.EOS})
+
+	total := len(first) + 1 + len(second)
+	tokens = make([]int, 0, total)
+	typeIDs = make([]int, total)
+
+	tokens = append(tokens, first...)
+	tokens = append(tokens, sepID)
+	tokens = append(tokens, second...)
+
+	for i := len(first) + 1; i < total; i++ {
+		typeIDs[i] = 1
+	}
+
+	return tokens, typeIDs, nil
+}
@@ -0,0 +1,67 @@
+package llama3
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEncodePair(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	noSpecial := &EncodeOptions{BOS: false, EOS: false}
+	a := tokenizer.Encode("What is a llama?", noSpecial)
+	b := tokenizer.Encode("A domesticated South American camelid.", noSpecial)
+
+	t.Run("default_options", func(t *testing.T) {
+		tokens, typeIDs, err := tokenizer.EncodePair("What is a llama?", "A domesticated South American camelid.", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		want := []int{128000}
+		want = append(want, a...)
+		want = append(want, 128001)
+		want = append(want, b...)
+		want = append(want, 128001)
+		if !equalIntSlices(tokens, want) {
+			t.Errorf("Tokens = %v, want %v", tokens, want)
+		}
+
+		if len(typeIDs) != len(tokens) {
+			t.Fatalf("Expected %d type IDs, got %d", len(tokens), len(typeIDs))
+		}
+		for i, typeID := range typeIDs {
+			wantType := 0
+			if i > len(a)+1 {
+				wantType = 1
+			}
+			if typeID != wantType {
+				t.Errorf("typeIDs[%d] = %d, want %d", i, typeID, wantType)
+			}
+		}
+	})
+
+	t.Run("custom_separator", func(t *testing.T) {
+		tokens, typeIDs, err := tokenizer.EncodePair("a", "b", &PairOptions{Separator: "<|eot_id|>"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		eot, _ := tokenizer.GetSpecialTokenID("<|eot_id|>")
+		if len(tokens) != 3 || tokens[1] != eot {
+			t.Errorf("Expected [a, eot, b], got %v", tokens)
+		}
+		if !equalIntSlices(typeIDs, []int{0, 0, 1}) {
+			t.Errorf("Type IDs = %v, want [0 0 1]", typeIDs)
+		}
+	})
+
+	t.Run("invalid_separator", func(t *testing.T) {
+		_, _, err := tokenizer.EncodePair("a", "b", &PairOptions{Separator: "<|missing|>"})
+		if !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("Expected ErrTokenNotFound, got %v", err)
+		}
+	})
+}
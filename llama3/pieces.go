@@ -0,0 +1,74 @@
+package llama3
+
+import (
+	"iter"
+
+	"github.com/agentstation/tokenizer/llama3/internal/encoding"
+)
+
+// piece is a step of encoding text: a special token, or a pre-token of the
+// text between special tokens, which BPE turns into tokens.
+type piece struct {
+	// ID is the ID of a special token, or -1 for a pre-token.
+	ID int
+	// Pretoken is the byte-level encoded pre-token, valid until the next
+	// piece.
+	Pretoken []byte
+	// Segment is the segment of the input the piece is in, and Offset the
+	// byte offset of Segment in the input.
+	Segment string
+	Offset  int
+}
+
+// encodeScratch holds the buffers pieces works in, which an EncodeSession
+// keeps between encodes.
+type encodeScratch struct {
+	segments  []segment
+	pretokens []string
+	buf       []byte
+}
+
+// pieces returns the pieces of the consecutive segments of an input. A
+// special segment is a special token if special returns its ID, and text
+// otherwise, as Encode treats special tokens the tokenizer lacks. Every
+// encode method iterates pieces, so they agree on how text is split.
+func (t *Tokenizer) pieces(segments []segment, special func(string) (int, bool), scratch *encodeScratch) iter.Seq[piece] {
+	return func(yield func(piece) bool) {
+		if scratch == nil {
+			scratch = &encodeScratch{}
+		}
+		defer func() { clear(scratch.pretokens) }() // release the text's pre-tokens
+
+		offset := 0
+		for _, seg := range segments {
+			if seg.Special {
+				if id, ok := special(seg.Text); ok {
+					if !yield(piece{ID: id, Segment: seg.Text, Offset: offset}) {
+						return
+					}
+					offset += len(seg.Text)
+					continue
+				}
+			}
+
+			scratch.pretokens = t.appendPretokens(scratch.pretokens[:0], seg.Text)
+			for _, part := range scratch.pretokens {
+				if part == "" {
+					continue
+				}
+				scratch.buf = encoding.AppendBytes(scratch.buf[:0], part)
+				if !yield(piece{ID: -1, Pretoken: scratch.buf, Segment: seg.Text, Offset: offset}) {
+					return
+				}
+			}
+			clear(scratch.pretokens)
+			offset += len(seg.Text)
+		}
+	}
+}
+
+// textPieces returns the pieces of text, which is encoded as ordinary text
+// even where it spells a special token.
+func (t *Tokenizer) textPieces(text string, scratch *encodeScratch) iter.Seq[piece] {
+	return t.pieces([]segment{{Text: text}}, t.specialTokenID, scratch)
+}
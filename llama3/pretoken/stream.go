@@ -0,0 +1,268 @@
+// Package pretoken exposes Llama 3 pre-tokenization for incremental input.
+//
+// Pre-tokenization splits text into the words, numbers, punctuation runs and
+// whitespace runs that BPE is applied to. Split performs it on a complete
+// string. Stream performs it on input that arrives in arbitrary chunks and
+// produces exactly the same pre-tokens, so streaming consumers such as the
+// scanner or syntax-aware maskers can work on pre-token boundaries without
+// buffering the whole input.
+package pretoken
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3/internal/pretokenizer"
+)
+
+// PreToken is a pre-token produced by a Stream.
+type PreToken struct {
+	// Text is the input bytes covered by the pre-token. Concatenating the
+	// Text of all pre-tokens reproduces the input exactly.
+	Text string
+	// Offset is the byte offset of Text in the stream.
+	Offset int64
+	// Special reports whether Text is one of the stream's special tokens.
+	Special bool
+}
+
+// Split pre-tokenizes a complete text. Special tokens are not recognized.
+func Split(text string) []string {
+	return pretokenizer.Tokenize(text)
+}
+
+// Stream pre-tokenizes input fed to it in chunks.
+//
+// A pre-token is only returned once no further input can change it: the
+// state machine matches greedily, so the last pre-token of the buffered
+// input, an incomplete UTF-8 sequence and a possible prefix of a special
+// token are held back until more input arrives or Flush is called. The
+// pre-tokens returned over the life of a stream are the same as those of
+// splitting the whole input at once.
+//
+// A Stream is not safe for concurrent use.
+type Stream struct {
+	buf    []byte // Input that has not been returned yet
+	offset int64  // Stream offset of buf[0]
+
+	special      map[string]bool // Special tokens, nil if none
+	specialLens  []int           // Distinct special token lengths, longest first
+	specialFirst [256]bool       // First bytes of special tokens
+}
+
+// Option configures a Stream.
+type Option func(*Stream)
+
+// WithSpecialTokens makes the stream recognize the given special tokens.
+// Matches are returned as single pre-tokens with Special set, and the text
+// between them is pre-tokenized separately, as Encode does. Where several
+// tokens match at the same position, the longest is used.
+func WithSpecialTokens(tokens []string) Option {
+	return func(s *Stream) {
+		s.special = nil
+		s.specialLens = nil
+		s.specialFirst = [256]bool{}
+
+		for _, tok := range tokens {
+			if tok == "" {
+				continue
+			}
+			if s.special == nil {
+				s.special = make(map[string]bool, len(tokens))
+			}
+			if !s.special[tok] {
+				s.special[tok] = true
+				s.specialFirst[tok[0]] = true
+				s.specialLens = appendLen(s.specialLens, len(tok))
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(s.specialLens)))
+	}
+}
+
+// appendLen appends n to lens if it is not already present.
+func appendLen(lens []int, n int) []int {
+	for _, l := range lens {
+		if l == n {
+			return lens
+		}
+	}
+	return append(lens, n)
+}
+
+// NewStream creates a pre-tokenizer stream.
+func NewStream(opts ...Option) *Stream {
+	s := &Stream{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Feed adds p to the stream and returns the pre-tokens that are complete.
+// The stream copies p, so the caller may reuse it.
+func (s *Stream) Feed(p []byte) []PreToken {
+	s.buf = append(s.buf, p...)
+	return s.process(false)
+}
+
+// Flush returns the remaining pre-tokens, treating the buffered input as the
+// end of the stream. The stream can be reused afterwards; offsets continue
+// from where it stopped.
+func (s *Stream) Flush() []PreToken {
+	return s.process(true)
+}
+
+// Buffered returns the number of input bytes held back by the stream.
+func (s *Stream) Buffered() int {
+	return len(s.buf)
+}
+
+// Offset returns the stream offset of the next byte to be returned.
+func (s *Stream) Offset() int64 {
+	return s.offset
+}
+
+// Reset discards buffered input and restarts offsets at zero.
+func (s *Stream) Reset() {
+	s.buf = s.buf[:0]
+	s.offset = 0
+}
+
+// process returns the pre-tokens in buf that are complete, or all of them
+// if final is set, and removes them from buf.
+func (s *Stream) process(final bool) []PreToken {
+	n := len(s.buf)
+	if !final {
+		n = completeUTF8(s.buf)
+	}
+	text := string(s.buf[:n])
+
+	var out []PreToken
+	pos := 0
+
+	// Text before each special token is complete
+	for {
+		start, end := s.nextSpecial(text, pos)
+		if start < 0 {
+			break
+		}
+		out = s.appendSplit(out, text[pos:start], pos)
+		out = append(out, PreToken{Text: text[start:end], Offset: s.offset + int64(start), Special: true})
+		pos = end
+	}
+
+	end := len(text)
+	if !final {
+		end = pos + s.specialPrefix(text[pos:])
+	}
+
+	tail := text[pos:end]
+	if final {
+		out = s.appendSplit(out, tail, pos)
+		pos = end
+	} else if parts := pretokenizer.Tokenize(tail); len(parts) > 1 {
+		// The last pre-token may still grow, hold it back
+		var done int
+		out, done = s.appendParts(out, tail, parts[:len(parts)-1], pos)
+		pos += done
+	}
+
+	s.offset += int64(pos)
+	s.buf = s.buf[:copy(s.buf, s.buf[pos:])]
+	return out
+}
+
+// appendSplit appends the pre-tokens of text, which starts at buf offset at.
+func (s *Stream) appendSplit(out []PreToken, text string, at int) []PreToken {
+	if text == "" {
+		return out
+	}
+	out, _ = s.appendParts(out, text, pretokenizer.Tokenize(text), at)
+	return out
+}
+
+// appendParts appends the leading pre-tokens parts of text, which starts at
+// buf offset at, and returns the number of bytes they cover.
+func (s *Stream) appendParts(out []PreToken, text string, parts []string, at int) ([]PreToken, int) {
+	start := 0
+	for _, part := range parts {
+		end := start + runeSpan(text[start:], part)
+		out = append(out, PreToken{Text: text[start:end], Offset: s.offset + int64(at+start)})
+		start = end
+	}
+	return out, start
+}
+
+// nextSpecial returns the bounds of the first special token in text at or
+// after from, or -1, -1 if there is none.
+func (s *Stream) nextSpecial(text string, from int) (start, end int) {
+	if s.special == nil {
+		return -1, -1
+	}
+
+	for i := from; i < len(text); i++ {
+		if !s.specialFirst[text[i]] {
+			continue
+		}
+		for _, n := range s.specialLens {
+			if i+n <= len(text) && s.special[text[i:i+n]] {
+				return i, i + n
+			}
+		}
+	}
+	return -1, -1
+}
+
+// specialPrefix returns the length of text without a trailing proper prefix
+// of a special token.
+func (s *Stream) specialPrefix(text string) int {
+	if s.special == nil {
+		return len(text)
+	}
+
+	from := len(text) - s.specialLens[0] + 1
+	if from < 0 {
+		from = 0
+	}
+	for i := from; i < len(text); i++ {
+		if !s.specialFirst[text[i]] {
+			continue
+		}
+		for tok := range s.special {
+			if len(tok) > len(text)-i && strings.HasPrefix(tok, text[i:]) {
+				return i
+			}
+		}
+	}
+	return len(text)
+}
+
+// runeSpan returns the number of leading bytes of text covered by part. The
+// pre-tokenizer works on runes and replaces invalid bytes with U+FFFD, so
+// part is measured in runes rather than bytes.
+func runeSpan(text, part string) int {
+	runes := utf8.RuneCountInString(part)
+
+	n := 0
+	for ; runes > 0 && n < len(text); runes-- {
+		_, size := utf8.DecodeRuneInString(text[n:])
+		n += size
+	}
+	return n
+}
+
+// completeUTF8 returns the length of buf without a trailing incomplete UTF-8
+// sequence.
+func completeUTF8(buf []byte) int {
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if utf8.FullRune(buf[i:]) {
+				return len(buf)
+			}
+			return i
+		}
+	}
+	return len(buf)
+}
@@ -0,0 +1,191 @@
+package pretoken
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	tokentesting "github.com/agentstation/tokenizer/llama3/internal/testing"
+	"github.com/agentstation/tokenizer/llama3/internal/tokens"
+)
+
+// streamAll feeds input to s in chunks of the given sizes, cycling through
+// them, and returns all pre-tokens.
+func streamAll(s *Stream, input string, sizes ...int) []PreToken {
+	var out []PreToken
+	for i := 0; len(input) > 0; i++ {
+		n := sizes[i%len(sizes)]
+		if n > len(input) {
+			n = len(input)
+		}
+		out = append(out, s.Feed([]byte(input[:n]))...)
+		input = input[n:]
+	}
+	return append(out, s.Flush()...)
+}
+
+func texts(pretokens []PreToken) []string {
+	out := make([]string, len(pretokens))
+	for i, p := range pretokens {
+		out[i] = p.Text
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitSpecial pre-tokenizes input the way Encode does: split by special
+// tokens first, then pre-tokenize the text between them.
+func splitSpecial(input string) []string {
+	regex := tokens.SpecialTokenRegex
+
+	var out []string
+	for _, part := range tokens.SplitBySpecialTokens(input, regex) {
+		if regex.MatchString(part) {
+			out = append(out, part)
+			continue
+		}
+		out = append(out, Split(part)...)
+	}
+	return out
+}
+
+func streamInputs() []string {
+	inputs := []string{
+		"Hello, world!",
+		"a   b\t\t c  \n\n  d",
+		"word   ",
+		"   \t\t\t   \n\n\n   word",
+		"I'll they're WE'VE it's don't 'Ll",
+		"1234567 89 0",
+		"Hello 世界 🦙 café",
+		"!!! ??? ...\r\n\r\nnext",
+		"tabs\tand\tspaces  everywhere  ",
+	}
+	for _, tc := range tokentesting.GenerateTestCases() {
+		inputs = append(inputs, tc.Input)
+	}
+	return inputs
+}
+
+func TestStreamMatchesSplit(t *testing.T) {
+	for _, input := range streamInputs() {
+		want := Split(input)
+		for size := 1; size <= 8; size++ {
+			got := texts(streamAll(NewStream(), input, size))
+			if !equalStrings(got, want) {
+				t.Errorf("Chunk size %d for %q: got %q, want %q", size, input, got, want)
+			}
+		}
+	}
+}
+
+func TestStreamRandomChunks(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	words := []string{"the", " quick", "  ", "\n", "\r\n", "'s", "'ll", "123", "4567", "!", "?!", " ", "\t", "世界", "🦙", "é", "x", "<|eot_id|>", "<|", "|>"}
+	special := []string{"<|eot_id|>"}
+
+	for i := 0; i < 500; i++ {
+		var sb strings.Builder
+		for j := rng.Intn(40); j >= 0; j-- {
+			sb.WriteString(words[rng.Intn(len(words))])
+		}
+		input := sb.String()
+
+		sizes := []int{1 + rng.Intn(5), 1 + rng.Intn(11), 1 + rng.Intn(3)}
+		got := texts(streamAll(NewStream(), input, sizes...))
+		if want := Split(input); !equalStrings(got, want) {
+			t.Fatalf("Chunk sizes %v for %q: got %q, want %q", sizes, input, got, want)
+		}
+
+		got = texts(streamAll(NewStream(WithSpecialTokens(special)), input, sizes...))
+		if want := splitSpecial(input); !equalStrings(got, want) {
+			t.Fatalf("Chunk sizes %v for %q with special tokens: got %q, want %q", sizes, input, got, want)
+		}
+	}
+}
+
+func TestStreamSpecialTokens(t *testing.T) {
+	special := []string{"<|begin_of_text|>", "<|eot_id|>", "<|start_header_id|>", "<|end_header_id|>"}
+	regex := tokens.SpecialTokenRegex
+
+	inputs := []string{
+		"<|begin_of_text|><|start_header_id|>user<|end_header_id|>\n\nHi there!<|eot_id|>",
+		"a <|eot_id|> b",
+		"<|eot_id|>\n\n<|eot_id|>",
+		"not <|special|> and <| eot_id|>",
+		"trailing <|eot_i",
+	}
+
+	for _, input := range inputs {
+		want := splitSpecial(input)
+		for size := 1; size <= 6; size++ {
+			pretokens := streamAll(NewStream(WithSpecialTokens(special)), input, size)
+			if got := texts(pretokens); !equalStrings(got, want) {
+				t.Errorf("Chunk size %d for %q: got %q, want %q", size, input, got, want)
+			}
+			for _, p := range pretokens {
+				if p.Special != regex.MatchString(p.Text) {
+					t.Errorf("Pre-token %q: Special = %v", p.Text, p.Special)
+				}
+			}
+		}
+	}
+}
+
+func TestStreamOffsets(t *testing.T) {
+	input := "Hello 世界\xff\xfe  world\n"
+	s := NewStream()
+
+	pretokens := streamAll(s, input, 3)
+	var sb strings.Builder
+	for _, p := range pretokens {
+		if p.Offset != int64(sb.Len()) {
+			t.Errorf("Pre-token %q: expected offset %d, got %d", p.Text, sb.Len(), p.Offset)
+		}
+		sb.WriteString(p.Text)
+	}
+	if sb.String() != input {
+		t.Errorf("Concatenated pre-tokens = %q, want %q", sb.String(), input)
+	}
+	if s.Offset() != int64(len(input)) {
+		t.Errorf("Expected stream offset %d, got %d", len(input), s.Offset())
+	}
+	if s.Buffered() != 0 {
+		t.Errorf("Expected no buffered bytes after Flush, got %d", s.Buffered())
+	}
+}
+
+func TestStreamHoldsIncompleteInput(t *testing.T) {
+	s := NewStream(WithSpecialTokens([]string{"<|eot_id|>"}))
+
+	tests := []struct {
+		feed string
+		want []string
+	}{
+		{"Hello", nil},                  // word may continue
+		{" wor", []string{"Hello"}},     // " wor" may continue
+		{"ld <|eo", []string{" world"}}, // " <|eo" may be a special token
+		{"t_id|>", []string{" ", "<|eot_id|>"}},
+		{"\xe4\xb8", nil}, // incomplete UTF-8
+	}
+	for _, tt := range tests {
+		if got := texts(s.Feed([]byte(tt.feed))); !equalStrings(got, tt.want) {
+			t.Errorf("Feed(%q) = %q, want %q", tt.feed, got, tt.want)
+		}
+	}
+
+	if got := texts(s.Flush()); !equalStrings(got, []string{"\xe4\xb8"}) {
+		t.Errorf("Flush() = %q", got)
+	}
+}
@@ -0,0 +1,165 @@
+package llama3
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ProcessFormat is the encoding of the token IDs ProcessWithOptions writes.
+type ProcessFormat int
+
+// Token encodings for ProcessOptions.
+const (
+	// ProcessUint32 writes each ID as a 4-byte little-endian integer, as
+	// Process does.
+	ProcessUint32 ProcessFormat = iota
+	// ProcessUint16 writes each ID as a 2-byte little-endian integer. It
+	// halves the output of vocabularies of up to 65,536 tokens, such as
+	// pruned ones; the full Llama 3 vocabulary needs 17 bits, and a larger
+	// ID is an ErrInvalidTokenID error.
+	ProcessUint16
+	// ProcessVarint writes each ID as an unsigned varint, as in protocol
+	// buffers: one byte for IDs below 128, two below 16,384 and three for
+	// the rest of the Llama 3 vocabulary.
+	ProcessVarint
+)
+
+// npyHeaderSize is the size of the .npy headers ProcessWithOptions writes.
+// The header is padded to a fixed size so that it can be rewritten with the
+// token count once the input ends.
+const npyHeaderSize = 128
+
+// ProcessOptions controls how ProcessWithOptions writes tokens.
+type ProcessOptions struct {
+	// Format is the encoding of each token ID. The zero value is
+	// ProcessUint32.
+	Format ProcessFormat
+
+	// NPY writes a NumPy .npy header before the tokens, making the output a
+	// one-dimensional array of dtype <u4 or <u2 that can be memory-mapped
+	// with numpy.load(path, mmap_mode="r"). The header holds the number of
+	// tokens, so w must be an io.WriteSeeker, such as an *os.File; the
+	// header is rewritten when processing stops, even with an error. NPY
+	// cannot be used with ProcessVarint.
+	NPY bool
+}
+
+// ProcessWithOptions is ProcessContext writing tokens as opts says. A nil
+// opts writes 4-byte little-endian IDs, like ProcessContext.
+func (t *Tokenizer) ProcessWithOptions(ctx context.Context, r io.Reader, w io.Writer, opts *ProcessOptions) (int64, error) {
+	if opts == nil {
+		opts = &ProcessOptions{}
+	}
+	var dtype string
+	switch opts.Format {
+	case ProcessUint32:
+		dtype = "<u4"
+	case ProcessUint16:
+		dtype = "<u2"
+	case ProcessVarint:
+		if opts.NPY {
+			return 0, errors.New("process: NPY output needs a fixed-size format")
+		}
+	default:
+		return 0, fmt.Errorf("process: unknown format %d", opts.Format)
+	}
+	if !opts.NPY {
+		return t.processTokens(ctx, r, w, opts.Format)
+	}
+
+	ws, ok := w.(io.WriteSeeker)
+	if !ok {
+		return 0, errors.New("process: NPY output needs an io.WriteSeeker")
+	}
+	start, err := ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("write header: %w", err)
+	}
+	if _, err := ws.Write(npyHeader(dtype, 0)); err != nil {
+		return 0, fmt.Errorf("write header: %w", err)
+	}
+
+	count, err := t.processTokens(ctx, r, ws, opts.Format)
+	if herr := rewriteNPYHeader(ws, start, dtype, count); err == nil {
+		err = herr
+	}
+	return count, err
+}
+
+// processTokens writes the tokens of r to w in format.
+func (t *Tokenizer) processTokens(ctx context.Context, r io.Reader, w io.Writer, format ProcessFormat) (int64, error) {
+	scan := t.NewScanner(&contextReader{ctx: ctx, r: r})
+
+	var count int64
+	var buf [binary.MaxVarintLen64]byte
+	for scan.Scan() {
+		if count%contextCheckTokens == 0 {
+			if err := ctx.Err(); err != nil {
+				return count, err
+			}
+		}
+
+		token := scan.Token()
+		var b []byte
+		switch format {
+		case ProcessUint16:
+			if token > math.MaxUint16 {
+				return count, NewTokenPositionError("process", token, int(count), ErrInvalidTokenID)
+			}
+			b = binary.LittleEndian.AppendUint16(buf[:0], uint16(token))
+		case ProcessVarint:
+			b = binary.AppendUvarint(buf[:0], uint64(token))
+		default:
+			b = binary.LittleEndian.AppendUint32(buf[:0], uint32(token)) // #nosec G115 - token IDs are small and non-negative
+		}
+
+		if _, err := w.Write(b); err != nil {
+			return count, fmt.Errorf("write token: %w", err)
+		}
+		count++
+	}
+
+	if err := ctx.Err(); err != nil {
+		return count, err
+	}
+	if err := scan.Err(); err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// npyHeader returns a version 1.0 .npy header of npyHeaderSize bytes for a
+// one-dimensional array of n elements of dtype.
+func npyHeader(dtype string, n int64) []byte {
+	dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d,), }", dtype, n)
+	header := make([]byte, 0, npyHeaderSize)
+	header = append(header, "\x93NUMPY\x01\x00"...)
+	header = binary.LittleEndian.AppendUint16(header, npyHeaderSize-10)
+	header = append(header, dict...)
+	for len(header) < npyHeaderSize-1 {
+		header = append(header, ' ')
+	}
+	return append(header, '\n')
+}
+
+// rewriteNPYHeader overwrites the header written at start with one for n
+// elements and returns to the end of the output.
+func rewriteNPYHeader(ws io.WriteSeeker, start int64, dtype string, n int64) error {
+	end, err := ws.Seek(0, io.SeekCurrent)
+	if err == nil {
+		if _, err = ws.Seek(start, io.SeekStart); err == nil {
+			if _, err = ws.Write(npyHeader(dtype, n)); err == nil {
+				_, err = ws.Seek(end, io.SeekStart)
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package llama3
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessWithOptions(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	input := strings.Repeat("Hello, world! ", 100)
+	ctx := context.Background()
+
+	var u32 bytes.Buffer
+	n, err := tokenizer.ProcessWithOptions(ctx, strings.NewReader(input), &u32, nil)
+	if err != nil {
+		t.Fatalf("ProcessWithOptions failed: %v", err)
+	}
+	var want []int
+	for b := u32.Bytes(); len(b) > 0; b = b[4:] {
+		want = append(want, int(binary.LittleEndian.Uint32(b)))
+	}
+	if int(n) != len(want) {
+		t.Fatalf("Expected %d tokens, got %d", len(want), n)
+	}
+
+	t.Run("varint", func(t *testing.T) {
+		var out bytes.Buffer
+		if _, err := tokenizer.ProcessWithOptions(ctx, strings.NewReader(input), &out, &ProcessOptions{Format: ProcessVarint}); err != nil {
+			t.Fatalf("ProcessWithOptions failed: %v", err)
+		}
+		var got []int
+		for b := out.Bytes(); len(b) > 0; {
+			id, k := binary.Uvarint(b)
+			if k <= 0 {
+				t.Fatalf("Invalid varint at %d", out.Len()-len(b))
+			}
+			got = append(got, int(id))
+			b = b[k:]
+		}
+		if !equalIntSlices(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+		if out.Len() >= u32.Len() {
+			t.Errorf("Expected fewer than %d bytes, got %d", u32.Len(), out.Len())
+		}
+	})
+
+	t.Run("uint16", func(t *testing.T) {
+		var out bytes.Buffer
+		if _, err := tokenizer.ProcessWithOptions(ctx, strings.NewReader(input), &out, &ProcessOptions{Format: ProcessUint16}); err != nil {
+			t.Fatalf("ProcessWithOptions failed: %v", err)
+		}
+		var got []int
+		for b := out.Bytes(); len(b) > 0; b = b[2:] {
+			got = append(got, int(binary.LittleEndian.Uint16(b)))
+		}
+		if !equalIntSlices(got, want) {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+
+		// <|eot_id|> is 128009, which does not fit
+		_, err := tokenizer.ProcessWithOptions(ctx, strings.NewReader("Hi<|eot_id|>"), &out, &ProcessOptions{Format: ProcessUint16})
+		var tokenErr *TokenError
+		if !errors.Is(err, ErrInvalidTokenID) || !errors.As(err, &tokenErr) || tokenErr.Position != 1 {
+			t.Errorf("Expected ErrInvalidTokenID at position 1, got %v", err)
+		}
+	})
+
+	t.Run("npy", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "tokens.npy")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		_, err = tokenizer.ProcessWithOptions(ctx, strings.NewReader(input), f, &ProcessOptions{NPY: true})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			t.Fatalf("ProcessWithOptions failed: %v", err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		if len(data) != npyHeaderSize+u32.Len() || !bytes.Equal(data[npyHeaderSize:], u32.Bytes()) {
+			t.Fatalf("Expected a %d-byte header and the uint32 tokens, got %d bytes", npyHeaderSize, len(data))
+		}
+		if !bytes.HasPrefix(data, []byte("\x93NUMPY\x01\x00")) || int(binary.LittleEndian.Uint16(data[8:])) != npyHeaderSize-10 || data[npyHeaderSize-1] != '\n' {
+			t.Errorf("Expected a version 1.0 header, got %q", data[:npyHeaderSize])
+		}
+		if dict := fmt.Sprintf("{'descr': '<u4', 'fortran_order': False, 'shape': (%d,), }", n); !bytes.Contains(data[:npyHeaderSize], []byte(dict)) {
+			t.Errorf("Expected header %q, got %q", dict, data[:npyHeaderSize])
+		}
+	})
+
+	tests := []struct {
+		name string
+		w    io.Writer
+		opts *ProcessOptions
+	}{
+		{"unknown format", &bytes.Buffer{}, &ProcessOptions{Format: 7}},
+		{"npy varint", &os.File{}, &ProcessOptions{Format: ProcessVarint, NPY: true}},
+		{"npy without seek", &bytes.Buffer{}, &ProcessOptions{NPY: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := tokenizer.ProcessWithOptions(ctx, strings.NewReader(input), tt.w, tt.opts)
+			if err == nil || n != 0 {
+				t.Errorf("Expected an error and no tokens, got %d, %v", n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package llama3
+
+import (
+	"testing"
+
+	"github.com/agentstation/tokenizer/bpe"
+	"github.com/agentstation/tokenizer/llama3/internal/vocabulary"
+)
+
+func TestMergeTableInvariants(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	t.Run("matches_merge_rules", func(t *testing.T) {
+		// The pair-based table used by the built-in sources must agree with
+		// the table built from LoadMerges for custom data loaders.
+		mergeRules, err := (&embeddedVocabularySource{t: tokenizer}).LoadMerges()
+		if err != nil {
+			t.Fatalf("Failed to load merges: %v", err)
+		}
+		fromRules := bpe.BuildMerges(mergeRules, tokenizer.tokenLookup)
+
+		if len(fromRules) != len(tokenizer.merges) {
+			t.Fatalf("Expected %d merges, got %d", len(fromRules), len(tokenizer.merges))
+		}
+		for key, want := range fromRules {
+			if got := tokenizer.merges[key]; got != want {
+				t.Fatalf("Merge %x = %+v, want %+v", key, got, want)
+			}
+		}
+	})
+
+	t.Run("merged_token_text", func(t *testing.T) {
+		loader := vocabulary.NewDefaultLoader()
+		mergesData, err := loader.LoadMergesData()
+		if err != nil {
+			t.Fatalf("Failed to load merges data: %v", err)
+		}
+		pairs, err := vocabulary.DecompressMergePairs(mergesData)
+		if err != nil {
+			t.Fatalf("Failed to decompress merges: %v", err)
+		}
+
+		for i := 0; i < len(pairs); i += 2 {
+			left, right := pairs[i], pairs[i+1]
+			merge, ok := tokenizer.merges[bpe.PairKey(left, right)]
+			if !ok {
+				continue
+			}
+			if got, want := tokenizer.tokens[merge.ID], tokenizer.tokens[left]+tokenizer.tokens[right]; got != want {
+				t.Fatalf("Merge %d: token %d is %q, want %q", i/2, merge.ID, got, want)
+			}
+			if merge.Rank < 1 || merge.Rank > i/2+1 {
+				t.Fatalf("Merge %d: unexpected rank %d", i/2, merge.Rank)
+			}
+		}
+	})
+}
+
+func TestProcessorMatchesEncode(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	// A processor without a cache must produce the same tokens as Encode
+	processor := tokenizer.Processor()
+	processor.Cache = nil
+	if tokenizer.Processor().Cache == nil {
+		t.Fatal("Expected changes to the processor not to affect the tokenizer")
+	}
+
+	texts := []string{
+		"Hello, world!",
+		"The quick brown fox jumps over the lazy dog.",
+		"   multiple   spaces\n\nand newlines\t",
+		"numbers 1234567890 and symbols !@#$%^&*()",
+		"unicode: café, naïve, 日本語, 🦙🦙🦙",
+		"supercalifragilisticexpialidocious antidisestablishmentarianism",
+	}
+
+	for _, text := range texts {
+		var got []int
+		for _, pretoken := range tokenizer.PreTokenize(text) {
+			got = append(got, processor.PerformBPE(pretoken)...)
+		}
+		want := tokenizer.Encode(text, noSpecialTokens)
+		if !equalIntSlices(got, want) {
+			t.Errorf("Text %q: processor = %v, Encode = %v", text, got, want)
+		}
+	}
+}
+
+func TestPerformBPECachedAllocs(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	pretoken := tokenizer.PreTokenize(" tokenization")[0]
+	tokenizer.performBPE(pretoken)
+
+	// A cache hit must not allocate, including for the processor itself
+	allocs := testing.AllocsPerRun(100, func() {
+		tokenizer.performBPE(pretoken)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected 0 allocations for a cached pre-token, got %v", allocs)
+	}
+}
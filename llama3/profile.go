@@ -0,0 +1,11 @@
+//go:build !tokenizer_tiny
+
+package llama3
+
+// Configuration of the default build. See profile_tiny.go for the
+// tokenizer_tiny build.
+const (
+	buildProfile = "default" // reported by Info
+
+	defaultCacheSize = 0 // 0 means unlimited
+)
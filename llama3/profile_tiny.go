@@ -0,0 +1,12 @@
+//go:build tokenizer_tiny
+
+package llama3
+
+// Configuration of tokenizer_tiny builds, which target devices with
+// little memory. The cache is bounded by default so a long-running process
+// cannot grow it without limit; WithCacheSize(0) restores an unbounded cache.
+const (
+	buildProfile = "tiny" // reported by Info
+
+	defaultCacheSize = 4096
+)
@@ -0,0 +1,201 @@
+package llama3
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agentstation/tokenizer/llama3/internal/encoding"
+	"github.com/agentstation/tokenizer/llama3/internal/vocabulary"
+)
+
+// PrunedVocabulary is a reduced vocabulary produced by PruneVocabulary.
+//
+// Token IDs are renumbered densely in their original order. Special tokens are
+// not included; they are appended when the vocabulary is loaded, as with the
+// embedded data.
+type PrunedVocabulary struct {
+	Tokens     []string // Regular tokens indexed by their new ID
+	MergePairs []int    // Merges as [left0, right0, left1, right1, ...] in priority order
+	OldIDs     []int    // Original token ID of each new ID
+}
+
+// PruneVocabulary reduces the regular vocabulary to at most size tokens,
+// keeping the most frequent tokens in freqs (token ID to count).
+//
+// The result stays closed under encoding: the 256 byte-level tokens are always
+// kept so any input can be encoded, every kept token keeps the tokens it is
+// merged from, and only merges whose inputs and result are all kept survive.
+// A frequent token is skipped if it does not fit in size together with the
+// tokens it needs. Tokens absent from freqs are kept only when another token
+// needs them.
+func (t *Tokenizer) PruneVocabulary(freqs map[int]int64, size int) (*PrunedVocabulary, error) {
+	if size < len(encoding.BytesToUnicode) {
+		return nil, NewConfigError("size", size, fmt.Errorf("must be at least %d to keep the byte-level tokens", len(encoding.BytesToUnicode)))
+	}
+
+	// The lowest-ranked merge producing each token is how BPE first reaches it
+	type parentMerge struct{ left, right, rank int }
+	parents := make(map[int]parentMerge, len(t.merges))
+	for key, merge := range t.merges {
+		left, right := int(key>>32), int(uint32(key)) // #nosec G115 - keys hold two 32-bit IDs
+		if p, ok := parents[merge.ID]; !ok || merge.Rank < p.rank {
+			parents[merge.ID] = parentMerge{left, right, merge.Rank}
+		}
+	}
+
+	keep := make([]bool, t.regularLen)
+	kept := 0
+	for b := 0; b < 256; b++ {
+		token := string(encoding.BytesToUnicode[byte(b)])
+		id, ok := t.tokenLookup[token]
+		if !ok || id >= t.regularLen {
+			return nil, NewTokenError("prune vocabulary", token, ErrTokenNotFound)
+		}
+		if !keep[id] {
+			keep[id] = true
+			kept++
+		}
+	}
+
+	candidates := make([]int, 0, len(freqs))
+	for id := range freqs {
+		if id < 0 || id >= len(t.tokens) {
+			return nil, NewTokenIDError("prune vocabulary", id, ErrInvalidTokenID)
+		}
+		if id < t.regularLen {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if freqs[a] != freqs[b] {
+			return freqs[a] > freqs[b]
+		}
+		return a < b
+	})
+
+	var closure []int
+	var collect func(id int)
+	collect = func(id int) {
+		if keep[id] {
+			return
+		}
+		for _, seen := range closure {
+			if seen == id {
+				return
+			}
+		}
+		closure = append(closure, id)
+		if p, ok := parents[id]; ok {
+			collect(p.left)
+			collect(p.right)
+		}
+	}
+
+	for _, id := range candidates {
+		if kept == size {
+			break
+		}
+		closure = closure[:0]
+		collect(id)
+		if kept+len(closure) > size {
+			continue
+		}
+		for _, c := range closure {
+			keep[c] = true
+		}
+		kept += len(closure)
+	}
+
+	pruned := &PrunedVocabulary{
+		Tokens: make([]string, 0, kept),
+		OldIDs: make([]int, 0, kept),
+	}
+	newIDs := make([]int, t.regularLen)
+	for id, ok := range keep {
+		if ok {
+			newIDs[id] = len(pruned.Tokens)
+			pruned.Tokens = append(pruned.Tokens, t.tokens[id])
+			pruned.OldIDs = append(pruned.OldIDs, id)
+		}
+	}
+
+	type keptMerge struct{ left, right, rank int }
+	var merges []keptMerge
+	for key, merge := range t.merges {
+		left, right := int(key>>32), int(uint32(key)) // #nosec G115 - keys hold two 32-bit IDs
+		if merge.ID < t.regularLen && keep[merge.ID] && left < t.regularLen && keep[left] && right < t.regularLen && keep[right] {
+			merges = append(merges, keptMerge{newIDs[left], newIDs[right], merge.Rank})
+		}
+	}
+	sort.Slice(merges, func(i, j int) bool {
+		if merges[i].rank != merges[j].rank {
+			return merges[i].rank < merges[j].rank
+		}
+		return merges[i].left < merges[j].left
+	})
+	pruned.MergePairs = make([]int, 0, 2*len(merges))
+	for _, m := range merges {
+		pruned.MergePairs = append(pruned.MergePairs, m.left, m.right)
+	}
+
+	return pruned, nil
+}
+
+// WriteFiles writes the vocabulary and merges in the formats read by
+// WithDataFiles.
+func (p *PrunedVocabulary) WriteFiles(vocabPath, mergesPath string) error {
+	vocab, err := vocabulary.EncodeVocabulary(p.Tokens)
+	if err != nil {
+		return NewDataError("encode vocabulary", vocabPath, err)
+	}
+	merges, err := vocabulary.CompressMergePairs(p.MergePairs)
+	if err != nil {
+		return NewDataError("compress merges", mergesPath, err)
+	}
+
+	if err := os.WriteFile(vocabPath, []byte(vocab), 0o644); err != nil { // #nosec G306 - data files are not secret
+		return NewDataError("write vocabulary", vocabPath, err)
+	}
+	if err := os.WriteFile(mergesPath, []byte(merges), 0o644); err != nil { // #nosec G306 - data files are not secret
+		return NewDataError("write merges", mergesPath, err)
+	}
+	return nil
+}
+
+// ReadTokenFrequencies parses token frequencies for PruneVocabulary.
+// Each line holds a token ID and a count separated by whitespace; blank lines
+// and lines starting with # are ignored. Repeated IDs are summed.
+func ReadTokenFrequencies(r io.Reader) (map[int]int64, error) {
+	freqs := make(map[int]int64)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, NewDataError("parse frequencies", "", fmt.Errorf("line %d: expected token ID and count", line))
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, NewDataError("parse frequencies", "", fmt.Errorf("line %d: %w", line, err))
+		}
+		count, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, NewDataError("parse frequencies", "", fmt.Errorf("line %d: %w", line, err))
+		}
+		freqs[id] += count
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, NewDataError("read frequencies", "", err)
+	}
+	return freqs, nil
+}
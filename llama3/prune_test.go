@@ -0,0 +1,132 @@
+package llama3
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPruneVocabulary(t *testing.T) {
+	source, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	corpus := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 4) +
+		"func main() { fmt.Println(\"hello, world\") }\n" +
+		"Tokenizers split text into pieces; the pieces map to IDs.\n"
+	freqs := make(map[int]int64)
+	for _, id := range source.Encode(corpus, noSpecialTokens) {
+		freqs[id]++
+	}
+
+	const size = 2000
+	pruned, err := source.PruneVocabulary(freqs, size)
+	if err != nil {
+		t.Fatalf("PruneVocabulary failed: %v", err)
+	}
+	if len(pruned.Tokens) > size {
+		t.Errorf("Expected at most %d tokens, got %d", size, len(pruned.Tokens))
+	}
+	if len(pruned.OldIDs) != len(pruned.Tokens) {
+		t.Fatalf("Expected %d old IDs, got %d", len(pruned.Tokens), len(pruned.OldIDs))
+	}
+
+	dir := t.TempDir()
+	vocabPath := filepath.Join(dir, "vocab.txt")
+	mergesPath := filepath.Join(dir, "merges.txt")
+	if err := pruned.WriteFiles(vocabPath, mergesPath); err != nil {
+		t.Fatalf("WriteFiles failed: %v", err)
+	}
+
+	tokenizer, err := New(WithDataFiles(vocabPath, mergesPath))
+	if err != nil {
+		t.Fatalf("Failed to load pruned vocabulary: %v", err)
+	}
+	if got, want := tokenizer.VocabSize(), len(pruned.Tokens)+specialTokenCount; got != want {
+		t.Errorf("Expected vocabulary size %d, got %d", want, got)
+	}
+	if got, want := len(tokenizer.merges), len(pruned.MergePairs)/2; got != want {
+		t.Errorf("Expected %d merges, got %d", want, got)
+	}
+
+	t.Run("corpus", func(t *testing.T) {
+		want := source.Encode(corpus, noSpecialTokens)
+		got := tokenizer.Encode(corpus, noSpecialTokens)
+		if len(got) != len(want) {
+			t.Fatalf("Expected %d tokens, got %d", len(want), len(got))
+		}
+		for i := range got {
+			if pruned.OldIDs[got[i]] != want[i] {
+				t.Fatalf("Token %d: expected original ID %d, got %d", i, want[i], pruned.OldIDs[got[i]])
+			}
+		}
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		for _, text := range []string{
+			"Completely unrelated vocabulary: photosynthesis",
+			"日本語のテキスト 🚀 émojis",
+			"\x00\x01 control\tcharacters\r\n",
+		} {
+			tokens := tokenizer.Encode(text, noSpecialTokens)
+			for _, id := range tokens {
+				if id < 0 || id >= len(pruned.Tokens) {
+					t.Errorf("Encode(%q) produced out-of-range ID %d", text, id)
+				}
+			}
+			if got := tokenizer.Decode(tokens); got != text {
+				t.Errorf("Expected %q to round-trip, got %q", text, got)
+			}
+		}
+	})
+
+	t.Run("special tokens", func(t *testing.T) {
+		tokens := tokenizer.Encode("hi", nil)
+		if len(tokens) < 2 || tokenizer.Decode(tokens[:1]) != beginOfTextToken {
+			t.Errorf("Expected %s first, got %v", beginOfTextToken, tokens)
+		}
+	})
+}
+
+func TestPruneVocabularyErrors(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	_, err = tokenizer.PruneVocabulary(nil, 100)
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Errorf("Expected ConfigError for a size below the byte tokens, got %v", err)
+	}
+
+	_, err = tokenizer.PruneVocabulary(map[int]int64{tokenizer.VocabSize(): 1}, 1000)
+	if !errors.Is(err, ErrInvalidTokenID) {
+		t.Errorf("Expected ErrInvalidTokenID, got %v", err)
+	}
+
+	// Only byte tokens fit, so nothing else is kept
+	pruned, err := tokenizer.PruneVocabulary(map[int]int64{1820: 5}, 256)
+	if err != nil {
+		t.Fatalf("PruneVocabulary failed: %v", err)
+	}
+	if len(pruned.Tokens) != 256 || len(pruned.MergePairs) != 0 {
+		t.Errorf("Expected 256 tokens and no merges, got %d and %d", len(pruned.Tokens), len(pruned.MergePairs)/2)
+	}
+}
+
+func TestReadTokenFrequencies(t *testing.T) {
+	freqs, err := ReadTokenFrequencies(strings.NewReader("# id count\n9906 12\n\n11\t3\n9906 1\n"))
+	if err != nil {
+		t.Fatalf("ReadTokenFrequencies failed: %v", err)
+	}
+	if freqs[9906] != 13 || freqs[11] != 3 || len(freqs) != 2 {
+		t.Errorf("Expected map[9906:13 11:3], got %v", freqs)
+	}
+
+	if _, err := ReadTokenFrequencies(strings.NewReader("9906\n")); err == nil {
+		t.Error("Expected error for a line without a count")
+	}
+}
@@ -0,0 +1,196 @@
+package llama3
+
+import (
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3/pretoken"
+)
+
+// maxPendingCountBytes bounds the text held back by LimitReaderByTokens
+// while it waits for a pre-token to end, as the scanner's default
+// WithMaxBuffer does. Longer pre-tokens are counted in chunks of this size,
+// which may differ slightly from a whole-string count.
+const maxPendingCountBytes = 1024 * 1024
+
+// noSpecialTokens encodes text without BOS/EOS tokens.
+var noSpecialTokens = &EncodeOptions{BOS: false, EOS: false}
+
+// tokenCounter incrementally counts the tokens of text that arrives in chunks.
+// The text is pre-tokenized as it arrives, as the scanner does, and counted up
+// to the last pre-token that later input cannot merge with. The remainder is
+// held until more data arrives or flush is called, so the running count
+// matches what Encode would produce for the concatenated input.
+type tokenCounter struct {
+	t       *Tokenizer
+	stream  *pretoken.Stream
+	held    []byte // Pre-tokens that may still merge with later input
+	ready   []byte // Complete pre-tokens not counted yet
+	holdAll bool   // Atomic patterns can span pre-tokens: count only at special tokens
+	maxHeld int    // Held bytes that force a count, 0 for never
+	count   int
+}
+
+// newTokenCounter returns a counter for t. If maxHeld is positive, held text
+// is counted once it reaches maxHeld bytes even if it may continue.
+func newTokenCounter(t *Tokenizer, maxHeld int) *tokenCounter {
+	return &tokenCounter{
+		t:       t,
+		stream:  pretoken.NewStream(pretoken.WithSpecialTokens(t.specialTokens())),
+		holdAll: t.atomic != nil,
+		maxHeld: maxHeld,
+	}
+}
+
+// write adds p to the counter, counting all text up to the last safe boundary.
+func (c *tokenCounter) write(p []byte) {
+	c.hold(c.stream.Feed(p))
+	if c.maxHeld > 0 && c.stream.Buffered()+len(c.held) >= c.maxHeld {
+		c.hold(c.stream.Flush())
+		c.ready = append(c.ready, c.held...)
+		c.held = c.held[:0]
+	}
+	c.countReady()
+}
+
+// flush counts any text still held back.
+func (c *tokenCounter) flush() {
+	c.hold(c.stream.Flush())
+	c.ready = append(c.ready, c.held...)
+	c.held = c.held[:0]
+	c.countReady()
+}
+
+// hold appends pretokens to the held text and moves it to ready whenever it
+// ends in a pre-token that cannot merge with what follows: one ending in
+// whitespace may still become part of a longer whitespace run or of the
+// next word.
+func (c *tokenCounter) hold(pretokens []pretoken.PreToken) {
+	for _, p := range pretokens {
+		c.held = append(c.held, p.Text...)
+		if p.Special || (!c.holdAll && !endsInSpace(p.Text)) {
+			c.ready = append(c.ready, c.held...)
+			c.held = c.held[:0]
+		}
+	}
+}
+
+// countReady counts the ready text.
+func (c *tokenCounter) countReady() {
+	if len(c.ready) == 0 {
+		return
+	}
+	c.count += c.t.Count(string(c.ready), noSpecialTokens)
+	c.ready = c.ready[:0]
+}
+
+// endsInSpace reports whether text ends with a whitespace character, as
+// the pre-tokenizer defines it.
+func endsInSpace(text string) bool {
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return unicode.IsSpace(r)
+}
+
+// lastRuneBoundary returns the offset of the last complete UTF-8 sequence end in buf.
+func lastRuneBoundary(buf []byte) int {
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if utf8.FullRune(buf[i:]) {
+				return len(buf)
+			}
+			return i
+		}
+	}
+	return len(buf)
+}
+
+// tokenLimitedReader stops reading from its source once n tokens have been read.
+type tokenLimitedReader struct {
+	r       io.Reader
+	counter *tokenCounter
+	n       int
+	done    bool
+}
+
+// LimitReaderByTokens returns a Reader that reads from r until the text read so
+// far contains at least n tokens (excluding BOS/EOS), then reports io.EOF.
+//
+// The upstream reader is not read any further once the budget is met, which
+// makes this useful when the source is expensive (e.g. a network stream) and
+// only the head of the text is needed. Tokens are counted at pre-token
+// boundaries, as the scanner splits its input, so the returned text may extend
+// past the n-th token by the pre-token being read plus one read of slack; trim
+// it with Encode if an exact cut is required. A pre-token longer than 1MiB is
+// counted in pieces, which can end the text a few tokens short of n.
+func LimitReaderByTokens(r io.Reader, tok *Tokenizer, n int) io.Reader {
+	return &tokenLimitedReader{
+		r:       r,
+		counter: newTokenCounter(tok, maxPendingCountBytes),
+		n:       n,
+		done:    n <= 0,
+	}
+}
+
+func (l *tokenLimitedReader) Read(p []byte) (int, error) {
+	if l.done {
+		return 0, io.EOF
+	}
+
+	n, err := l.r.Read(p)
+	if n > 0 {
+		l.counter.write(p[:n])
+		if l.counter.count >= l.n {
+			l.done = true
+		}
+	}
+	if err == io.EOF {
+		l.done = true
+	}
+	return n, err
+}
+
+// CountingReader passes bytes through from an underlying reader unchanged
+// while keeping a running count of their tokens (excluding BOS/EOS).
+//
+// It lets a proxy count the tokens of a request body while forwarding it,
+// without buffering or reading the body twice: only the current pre-token is
+// held, so memory grows with the longest pre-token rather than the body. With
+// WithAtomicPatterns, which can match across pre-tokens, text is held until a
+// special token or EOF. A CountingReader is not safe for concurrent use.
+type CountingReader struct {
+	r       io.Reader
+	counter *tokenCounter
+	eof     bool
+}
+
+// NewCountingReader returns a CountingReader that reads from r and counts
+// tokens with tok.
+func NewCountingReader(r io.Reader, tok *Tokenizer) *CountingReader {
+	return &CountingReader{
+		r:       r,
+		counter: newTokenCounter(tok, 0),
+	}
+}
+
+// Read reads from the underlying reader and counts the bytes read.
+func (c *CountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.counter.write(p[:n])
+	}
+	if err == io.EOF && !c.eof {
+		c.eof = true
+		c.counter.flush()
+	}
+	return n, err
+}
+
+// Tokens returns the number of tokens in the text read so far.
+//
+// Pre-tokens that later input could still change are held back, so while
+// reading is in progress the count may lag by the last word. Once Read has returned io.EOF the count is exact and equals
+// len(Encode(text, &EncodeOptions{})) for everything read.
+func (c *CountingReader) Tokens() int {
+	return c.counter.count
+}
@@ -0,0 +1,219 @@
+package llama3
+
+import (
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestTokenCounter(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	inputs := []string{
+		"The quick brown fox jumps over the lazy dog.",
+		"Hello,  world!\n\n  Multiple   spaces\tand tabs.",
+		"Unicode: 世界 🦙 café naïve",
+		"<|begin_of_text|>Special tokens <|eot_id|> inline",
+		strings.Repeat("word ", 200),
+	}
+
+	for _, input := range inputs {
+		expected := len(tokenizer.Encode(input, &EncodeOptions{BOS: false, EOS: false}))
+
+		for _, chunkSize := range []int{1, 3, 7, 64} {
+			counter := newTokenCounter(tokenizer, 0)
+			for i := 0; i < len(input); i += chunkSize {
+				end := i + chunkSize
+				if end > len(input) {
+					end = len(input)
+				}
+				counter.write([]byte(input[i:end]))
+			}
+			counter.flush()
+
+			if counter.count != expected {
+				t.Errorf("chunk=%d input=%q: got %d tokens, want %d", chunkSize, input, counter.count, expected)
+			}
+		}
+	}
+}
+
+func TestTokenCounterUnicodeSpaces(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	inputs := []string{"a\u00a0  b", "x\u3000  yy", "end\u2028 \u2003  next", "tab\t\u00a0 \u00a0 x"}
+	spaces := []string{" ", "  ", "\u00a0", "\u3000", "\u2009", "\u0085", "\t", "\n", "\xff"}
+	rng := rand.New(rand.NewSource(1))
+	for range 300 {
+		var b strings.Builder
+		for range 1 + rng.Intn(12) {
+			if rng.Intn(2) == 0 {
+				b.WriteString(spaces[rng.Intn(len(spaces))])
+			} else {
+				b.WriteString([]string{"a", "yy", "42", "世", "!"}[rng.Intn(5)])
+			}
+		}
+		inputs = append(inputs, b.String())
+	}
+
+	for _, input := range inputs {
+		expected := len(tokenizer.Encode(input, noSpecialTokens))
+		for _, chunkSize := range []int{1, 2, 3, 5} {
+			counter := newTokenCounter(tokenizer, 0)
+			for i := 0; i < len(input); i += chunkSize {
+				counter.write([]byte(input[i:min(i+chunkSize, len(input))]))
+			}
+			counter.flush()
+
+			if counter.count != expected {
+				t.Errorf("chunk=%d input=%q: got %d tokens, want %d", chunkSize, input, counter.count, expected)
+			}
+		}
+	}
+}
+
+func TestLimitReaderByTokens(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	input := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 1000)
+
+	t.Run("stops_reading_upstream", func(t *testing.T) {
+		src := &slowReader{data: []byte(input), chunkSize: 64}
+		data, err := io.ReadAll(LimitReaderByTokens(src, tokenizer, 50))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		count := len(tokenizer.Encode(string(data), &EncodeOptions{BOS: false, EOS: false}))
+		if count < 50 {
+			t.Errorf("Expected at least 50 tokens, got %d", count)
+		}
+		if len(data) > 512 {
+			t.Errorf("Read %d bytes, expected the reader to stop early", len(data))
+		}
+		if !strings.HasPrefix(input, string(data)) {
+			t.Error("Returned data is not a prefix of the input")
+		}
+	})
+
+	t.Run("no_spaces", func(t *testing.T) {
+		input := strings.Repeat("a,", 50000)
+		src := &slowReader{data: []byte(input), chunkSize: 64}
+		data, err := io.ReadAll(LimitReaderByTokens(src, tokenizer, 1000))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		count := len(tokenizer.Encode(string(data), noSpecialTokens))
+		if count < 1000 {
+			t.Errorf("Expected at least 1000 tokens, got %d", count)
+		}
+		if len(data) > 4096 {
+			t.Errorf("Read %d bytes, expected the reader to stop early", len(data))
+		}
+	})
+
+	t.Run("short_input", func(t *testing.T) {
+		data, err := io.ReadAll(LimitReaderByTokens(strings.NewReader("Hello world"), tokenizer, 50))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if string(data) != "Hello world" {
+			t.Errorf("Expected full input, got %q", data)
+		}
+	})
+
+	t.Run("zero_budget", func(t *testing.T) {
+		data, err := io.ReadAll(LimitReaderByTokens(strings.NewReader(input), tokenizer, 0))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(data) != 0 {
+			t.Errorf("Expected no data, got %d bytes", len(data))
+		}
+	})
+}
+
+func TestCountingReader(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	input := strings.Repeat("Hello,  world! Unicode 世界 🦙 café. ", 100)
+	expected := len(tokenizer.Encode(input, &EncodeOptions{BOS: false, EOS: false}))
+
+	for _, chunkSize := range []int{1, 7, 4096} {
+		src := &slowReader{data: []byte(input), chunkSize: chunkSize}
+		cr := NewCountingReader(src, tokenizer)
+
+		var out strings.Builder
+		if _, err := io.Copy(&out, cr); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if out.String() != input {
+			t.Errorf("chunk=%d: output differs from input", chunkSize)
+		}
+		if cr.Tokens() != expected {
+			t.Errorf("chunk=%d: got %d tokens, want %d", chunkSize, cr.Tokens(), expected)
+		}
+	}
+}
+
+func TestCountingReaderExact(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	// The count at EOF is exact for any split of the input, including
+	// whitespace runs that mix Unicode spaces with ASCII ones and long
+	// inputs without spaces
+	inputs := []string{
+		"a\u00a0  b", "x\u3000  yy", strings.Repeat("word\u00a0  next\u3000 ", 50), "a \xff  b",
+		strings.Repeat("a,", 50000), strings.Repeat("1234567", 20000),
+	}
+	for _, input := range inputs {
+		expected := len(tokenizer.Encode(input, noSpecialTokens))
+		for _, chunkSize := range []int{1, 2, 5, 4096} {
+			cr := NewCountingReader(&slowReader{data: []byte(input), chunkSize: chunkSize}, tokenizer)
+			if _, err := io.Copy(io.Discard, cr); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cr.Tokens() != expected {
+				t.Errorf("chunk=%d input=%q: got %d tokens, want %d", chunkSize, input, cr.Tokens(), expected)
+			}
+		}
+	}
+}
+
+func TestCountingReaderAtomicPatterns(t *testing.T) {
+	tokenizer, err := New(WithAtomicPatterns(`Case \d+`))
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	// Atomic matches span the space that ends a Llama 3 pre-token
+	input := strings.Repeat("See Case 1234 and Case 56. ", 100) + "<|eot_id|>Case 7"
+	expected := len(tokenizer.Encode(input, noSpecialTokens))
+	for _, chunkSize := range []int{1, 3, 8, 4096} {
+		cr := NewCountingReader(&slowReader{data: []byte(input), chunkSize: chunkSize}, tokenizer)
+		if _, err := io.Copy(io.Discard, cr); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cr.Tokens() != expected {
+			t.Errorf("chunk=%d: got %d tokens, want %d", chunkSize, cr.Tokens(), expected)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package llama3
+
+import (
+	"github.com/agentstation/tokenizer"
+)
+
+// Name is the name the Llama 3 tokenizer is registered under with
+// tokenizer.Register.
+const Name = "llama3"
+
+func init() {
+	tokenizer.Register(Name, func() (tokenizer.Tokenizer, error) {
+		t, err := New()
+		if err != nil {
+			return nil, err
+		}
+		return t.Generic(), nil
+	})
+}
+
+// Generic returns t as a model-independent tokenizer.Tokenizer, for code
+// that works with any model family. Its Encode adds neither BOS nor EOS.
+func (t *Tokenizer) Generic() tokenizer.Tokenizer {
+	return generic{t}
+}
+
+// generic adapts a Tokenizer to tokenizer.Tokenizer.
+type generic struct {
+	*Tokenizer
+}
+
+func (g generic) Encode(text string) []int {
+	return g.Tokenizer.Encode(text, noSpecialTokens)
+}
+
+func (g generic) Count(text string) int {
+	return g.Tokenizer.Count(text, noSpecialTokens)
+}
+
+func (g generic) SpecialTokens() map[string]int {
+	tokens := g.specialTokens()
+	special := make(map[string]int, len(tokens))
+	for i, token := range tokens {
+		special[token] = g.regularLen + i
+	}
+	return special
+}
@@ -0,0 +1,36 @@
+package llama3
+
+import (
+	"testing"
+
+	"github.com/agentstation/tokenizer"
+)
+
+func TestRegistered(t *testing.T) {
+	tok, err := tokenizer.Get(Name)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	want := []int{9906, 11, 1917, 0, 128009}
+	if got := tok.Encode("Hello, world!<|eot_id|>"); !equalIntSlices(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := tok.Decode(want); got != "Hello, world!<|eot_id|>" {
+		t.Errorf("Expected %q, got %q", "Hello, world!<|eot_id|>", got)
+	}
+	if tok.VocabSize() != 128256 {
+		t.Errorf("Expected 128256 tokens, got %d", tok.VocabSize())
+	}
+
+	special := tok.SpecialTokens()
+	if len(special) != 256 || special["<|begin_of_text|>"] != 128000 || special["<|eot_id|>"] != 128009 {
+		t.Errorf("Expected 256 special tokens with <|eot_id|> at 128009, got %d", len(special))
+	}
+
+	text := "Hello, world!<|eot_id|>"
+	counts, err := tokenizer.CountAll(text, []string{Name})
+	if err != nil || counts[Name] != len(want) {
+		t.Errorf("Expected CountAll to count %d tokens, got %v (%v)", len(want), counts, err)
+	}
+}
@@ -0,0 +1,115 @@
+package llama3
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3/internal/pretokenizer"
+)
+
+// Span is a half-open byte range [Start, End) of a text.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Retokenize returns the tokens of newText given the tokens of oldText, for
+// editors that keep a live token count while text is typed.
+//
+// edit is the range of oldText that was replaced; newText is oldText with that
+// range replaced by the inserted text. oldTokens must be the encoding of
+// oldText without BOS and EOS tokens.
+//
+// Only the region between the nearest safe split points around the edit is
+// encoded again, and the old tokens on either side are reused. A safe split
+// point is a line start before an ASCII letter or digit, or a space between
+// two ASCII letters, outside any text that may be part of a special token
+// such as "<|tool call|>". The result is identical to encoding newText from
+// scratch.
+// If the edit does not fit the texts or oldTokens do not match oldText,
+// newText is encoded in full.
+func (t *Tokenizer) Retokenize(oldText, newText string, oldTokens []int, edit Span) []int {
+	opts := &EncodeOptions{BOS: false, EOS: false}
+
+	inserted := len(newText) - len(oldText) + edit.End - edit.Start
+	if t.atomic != nil {
+		return t.Encode(newText, opts) // matches may span safe split points
+	}
+	if edit.Start < 0 || edit.End < edit.Start || edit.End > len(oldText) || inserted < 0 {
+		return t.Encode(newText, opts)
+	}
+	// Token byte lengths only add up to the text length for valid UTF-8
+	if !utf8.ValidString(oldText) || !utf8.ValidString(newText) {
+		return t.Encode(newText, opts)
+	}
+	delta := inserted - (edit.End - edit.Start)
+	window := t.spacedSpecialLen()
+
+	// Find the last safe token boundary before the edit and the first one
+	// after it. Both need their neighbouring bytes unchanged by the edit, and
+	// no special token of newText may span them.
+	startToken, start := 0, 0
+	endToken, end := len(oldTokens), len(oldText)
+	offset := 0
+	for i, id := range oldTokens {
+		if offset+1 < edit.Start && pretokenizer.IsSafeBoundary(oldText, offset) && !inSpecialCandidate(oldText, offset, window) {
+			startToken, start = i, offset
+		}
+		if endToken == len(oldTokens) && offset > edit.End && pretokenizer.IsSafeBoundary(oldText, offset) && !inSpecialCandidate(newText, offset+delta, window) {
+			endToken, end = i, offset
+		}
+		n, ok := t.tokenLen(id)
+		if !ok {
+			return t.Encode(newText, opts)
+		}
+		offset += n
+	}
+	if offset != len(oldText) {
+		return t.Encode(newText, opts) // oldTokens do not match oldText
+	}
+
+	// The text outside the re-encoded region must be unchanged, and the
+	// reused tokens on either side of it must match the text at their offsets
+	if newText[:start] != oldText[:start] || newText[end+delta:] != oldText[end:] {
+		return t.Encode(newText, opts)
+	}
+	tokenText := func(id int) string {
+		b, _ := t.tokenBytes(id)
+		return string(b)
+	}
+	if startToken > 0 && !strings.HasSuffix(oldText[:start], tokenText(oldTokens[startToken-1])) ||
+		endToken < len(oldTokens) && !strings.HasPrefix(oldText[end:], tokenText(oldTokens[endToken])) {
+		return t.Encode(newText, opts)
+	}
+
+	middle := t.Encode(newText[start:end+delta], opts)
+	tokens := make([]int, 0, startToken+len(middle)+len(oldTokens)-endToken)
+	tokens = append(tokens, oldTokens[:startToken]...)
+	tokens = append(tokens, middle...)
+	return append(tokens, oldTokens[endToken:]...)
+}
+
+// spacedSpecialLen returns the length of the longest special token, loaded
+// or added, that contains whitespace, or 0 if there is none. Only those can
+// span a safe split point.
+func (t *Tokenizer) spacedSpecialLen() int {
+	n := 0
+	for _, token := range t.specialTokens() {
+		if len(token) > n && strings.ContainsAny(token, " \t\n\r\v\f") {
+			n = len(token)
+		}
+	}
+	return n
+}
+
+// inSpecialCandidate reports whether a special token of at most n bytes
+// could span offset at of text: whether an opening "<|" starts less than n
+// bytes before at and is not closed by "|>" before it.
+func inSpecialCandidate(text string, at, n int) bool {
+	if n == 0 {
+		return false
+	}
+	from := max(at-n+1, 0)
+	open := strings.LastIndex(text[from:at], "<|")
+	return open >= 0 && !strings.Contains(text[from+open+2:at], "|>")
+}
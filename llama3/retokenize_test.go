@@ -0,0 +1,125 @@
+package llama3
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRetokenize(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	base := strings.Repeat("The quick brown fox jumps over the lazy dog.\n"+
+		"func main() { return 42 }\n\n"+
+		"Numbers 12345 and contractions don't matter<|eot_id|> here.\n", 20)
+	inserts := []string{"", "a", " ", "\n", "word", " new text", "\n\nHeading\n", "'s", "123", "世界", "<|eot_id|>", "\r\n"}
+
+	rng := rand.New(rand.NewSource(1))
+	oldText := base
+	oldTokens := tokenizer.Encode(oldText, noSpecialTokens)
+	for i := 0; i < 300; i++ {
+		start := rng.Intn(len(oldText) + 1)
+		end := start + rng.Intn(min(8, len(oldText)-start)+1)
+		for start > 0 && !utf8.RuneStart(oldText[start]) {
+			start-- // keep the text valid UTF-8
+		}
+		for end < len(oldText) && !utf8.RuneStart(oldText[end]) {
+			end++
+		}
+		insert := inserts[rng.Intn(len(inserts))]
+		newText := oldText[:start] + insert + oldText[end:]
+
+		got := tokenizer.Retokenize(oldText, newText, oldTokens, Span{Start: start, End: end})
+		want := tokenizer.Encode(newText, noSpecialTokens)
+		if !equalIntSlices(got, want) {
+			t.Fatalf("Edit %d: replacing [%d,%d) with %q: got %d tokens, want %d", i, start, end, insert, len(got), len(want))
+		}
+
+		oldText, oldTokens = newText, got
+	}
+}
+
+func TestRetokenizeSpacedSpecialTokens(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	if _, err := tokenizer.AddSpecialTokens("<|tool call|>", "<|a b c d|>"); err != nil {
+		t.Fatalf("AddSpecialTokens failed: %v", err)
+	}
+
+	tests := []struct {
+		oldText, newText string
+		edit             Span
+	}{
+		{"please <|tool call", "please <|tool call|>", Span{18, 18}},
+		{"please <|tool call|> now", "please <|tool call now", Span{18, 20}},
+		{"x <tool call|> more words", "x <|tool call|> more words", Span{3, 3}},
+		{"run <|a b c d|> then", "run <|a b c X|> then", Span{12, 13}},
+		{"run <|a b c d then", "run <|a b c d|> then", Span{13, 13}},
+	}
+	for _, tt := range tests {
+		want := tokenizer.Encode(tt.newText, noSpecialTokens)
+		got := tokenizer.Retokenize(tt.oldText, tt.newText, tokenizer.Encode(tt.oldText, noSpecialTokens), tt.edit)
+		if !equalIntSlices(got, want) {
+			t.Errorf("%q -> %q: expected %v, got %v", tt.oldText, tt.newText, want, got)
+		}
+	}
+}
+
+func TestRetokenizeFallback(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	oldText := strings.Repeat("Hello world and more words\n", 10)
+	newText := oldText[:50] + "XYZ" + oldText[50:]
+	want := tokenizer.Encode(newText, noSpecialTokens)
+
+	tests := []struct {
+		name      string
+		oldTokens []int
+		edit      Span
+	}{
+		{"stale tokens", tokenizer.Encode("something else", noSpecialTokens), Span{50, 50}},
+		{"bos token", tokenizer.Encode(oldText, nil), Span{50, 50}},
+		{"edit out of range", tokenizer.Encode(oldText, noSpecialTokens), Span{50, len(oldText) + 1}},
+		{"wrong edit", tokenizer.Encode(oldText, noSpecialTokens), Span{10, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tokenizer.Retokenize(oldText, newText, tt.oldTokens, tt.edit); !equalIntSlices(got, want) {
+				t.Errorf("Expected a full encode of %d tokens, got %d tokens", len(want), len(got))
+			}
+		})
+	}
+}
+
+func BenchmarkRetokenize(b *testing.B) {
+	tokenizer, err := New()
+	if err != nil {
+		b.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	oldText := strings.Repeat("The quick brown fox jumps over the lazy dog.\n", 2000)
+	oldTokens := tokenizer.Encode(oldText, noSpecialTokens)
+	at := len(oldText) / 2
+	newText := oldText[:at] + "x" + oldText[at:]
+
+	b.Run("Retokenize", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tokenizer.Retokenize(oldText, newText, oldTokens, Span{Start: at, End: at})
+		}
+	})
+	b.Run("Encode", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tokenizer.Encode(newText, noSpecialTokens)
+		}
+	})
+}
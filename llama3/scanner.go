@@ -1,7 +1,7 @@
 package llama3
 
 import (
-	"fmt"
+	"context"
 	"io"
 
 	"github.com/agentstation/tokenizer/llama3/scanner"
@@ -17,8 +17,9 @@ type Scanner interface {
 	// Valid only after a successful call to Scan.
 	Token() int
 
-	// Text returns the text that produced the current token.
-	// Valid only after a successful call to Scan.
+	// Text returns the input text that produced the current token, or ""
+	// for BOS and EOS. A token holding part of a UTF-8 character has part
+	// of its bytes as text. Valid only after a successful call to Scan.
 	Text() string
 
 	// Err returns the first error encountered during scanning.
@@ -39,15 +40,47 @@ var (
 	// Default is 1MB.
 	WithMaxBuffer = scanner.WithMaxBuffer
 
+	// WithMaxTotalTokens limits the number of tokens the scanner will emit.
+	// When the limit is reached, Scan returns false and Err returns a *LimitError.
+	WithMaxTotalTokens = scanner.WithMaxTotalTokens
+
+	// WithMaxInputBytes limits the number of bytes read from the input.
+	// When the input is larger, Scan returns false and Err returns a *LimitError.
+	WithMaxInputBytes = scanner.WithMaxInputBytes
+
+	// WithExactBoundaries guarantees tokens identical to Encode for any
+	// input, buffering long pre-tokens whole instead of splitting them at
+	// WithMaxBuffer. With WithAtomicPatterns, the input is only split after
+	// special tokens.
+	WithExactBoundaries = scanner.WithExactBoundaries
+
 	// WithEncodeOptions sets encoding options for the scanner.
 	WithEncodeOptions = func(opts *EncodeOptions) ScannerOption {
 		return scanner.WithEncodeOptions(&scanner.EncodeOptions{
-			BOS: opts.BOS,
-			EOS: opts.EOS,
+			BOS:            opts.BOS,
+			EOS:            opts.EOS,
+			SuffixSpecials: opts.SuffixSpecials,
 		})
 	}
 )
 
+// LimitError reports that a scanner limit set with WithMaxTotalTokens or
+// WithMaxInputBytes was exceeded.
+type LimitError = scanner.LimitError
+
+// ScanError reports an error reading the scanner's input, with the text
+// being tokenized at the time.
+type ScanError = scanner.ScanError
+
+// Scanner limit errors - these are re-exported from the scanner package.
+var (
+	// ErrTokenLimit indicates the scanner stopped at its token limit.
+	ErrTokenLimit = scanner.ErrTokenLimit
+
+	// ErrInputLimit indicates the scanner stopped at its input size limit.
+	ErrInputLimit = scanner.ErrInputLimit
+)
+
 // tokenizerAdapter adapts Tokenizer to the scanner.Tokenizer interface.
 type tokenizerAdapter struct {
 	*Tokenizer
@@ -56,11 +89,24 @@ type tokenizerAdapter struct {
 // Encode adapts the Encode method.
 func (ta *tokenizerAdapter) Encode(text string, opts *scanner.EncodeOptions) []int {
 	return ta.Tokenizer.Encode(text, &EncodeOptions{
-		BOS: opts.BOS,
-		EOS: opts.EOS,
+		BOS:            opts.BOS,
+		EOS:            opts.EOS,
+		SuffixSpecials: opts.SuffixSpecials,
 	})
 }
 
+// SpecialTokens returns the special tokens Encode splits text on, so the
+// scanner never splits its input inside one.
+func (ta *tokenizerAdapter) SpecialTokens() []string {
+	return ta.specialTokens()
+}
+
+// SplitsOnPreTokens reports whether Encode is consistent on Llama 3
+// pre-token boundaries, which atomic patterns can span.
+func (ta *tokenizerAdapter) SplitsOnPreTokens() bool {
+	return ta.atomic == nil
+}
+
 // NewScanner creates a scanner for streaming tokenization.
 // The scanner processes input with bounded memory usage, making it suitable
 // for large files or continuous streams.
@@ -72,61 +118,20 @@ func (t *Tokenizer) NewScanner(r io.Reader, opts ...ScannerOption) Scanner {
 }
 
 // Process handles large files with controlled memory usage.
-// It reads from r, tokenizes the content, and writes token IDs to w.
+// It reads from r, tokenizes the content, and writes token IDs to w as
+// 4-byte little-endian integers.
 // Returns the number of tokens written and any error encountered.
+// Use ProcessContext to be able to cancel it, and ProcessWithOptions for
+// other encodings.
 func (t *Tokenizer) Process(r io.Reader, w io.Writer) (int64, error) {
-	scan := t.NewScanner(r)
-
-	var count int64
-	for scan.Scan() {
-		token := scan.Token()
-
-		// Write token as binary (4 bytes, little-endian)
-		buf := make([]byte, 4)
-		buf[0] = byte(token)
-		buf[1] = byte(token >> 8)
-		buf[2] = byte(token >> 16)
-		buf[3] = byte(token >> 24)
-
-		if _, err := w.Write(buf); err != nil {
-			return count, fmt.Errorf("write token: %w", err)
-		}
-		count++
-	}
-
-	if err := scan.Err(); err != nil {
-		return count, err
-	}
-
-	return count, nil
+	return t.ProcessContext(context.Background(), r, w)
 }
 
 // TokenStream provides channel-based streaming for concurrent processing.
 // The tokens channel will be closed when scanning completes.
-// Any error will be sent on the error channel.
+// Any error will be sent on the error channel. The stream runs until the
+// input ends and its tokens are received; use TokenStreamContext to be able
+// to abandon it.
 func (t *Tokenizer) TokenStream(r io.Reader) (<-chan int, <-chan error) {
-	tokens := make(chan int, 100)
-	errc := make(chan error, 1)
-
-	go func() {
-		defer close(tokens)
-		defer close(errc)
-
-		scan := t.NewScanner(r)
-		for scan.Scan() {
-			select {
-			case tokens <- scan.Token():
-				// Token sent successfully
-			default:
-				// Channel full, this provides backpressure
-				tokens <- scan.Token()
-			}
-		}
-
-		if err := scan.Err(); err != nil {
-			errc <- err
-		}
-	}()
-
-	return tokens, errc
+	return t.TokenStreamContext(context.Background(), r)
 }
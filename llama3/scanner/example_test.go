@@ -0,0 +1,74 @@
+package scanner_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agentstation/tokenizer/llama3/scanner"
+)
+
+// wordTokenizer is a toy adapter that assigns IDs to words in order of
+// first appearance, standing in for a real tokenizer.
+type wordTokenizer struct {
+	ids map[string]int
+}
+
+func (w *wordTokenizer) Encode(text string, opts *scanner.EncodeOptions) []int {
+	var tokens []int
+	if opts.BOS {
+		tokens = append(tokens, 0)
+	}
+	for _, word := range strings.Fields(text) {
+		id, ok := w.ids[word]
+		if !ok {
+			id = len(w.ids) + 2 // 0 and 1 are BOS and EOS
+			w.ids[word] = id
+		}
+		tokens = append(tokens, id)
+	}
+	return tokens
+}
+
+func (w *wordTokenizer) GetSpecialTokenID(token string) (int, error) {
+	switch token {
+	case "<|begin_of_text|>":
+		return 0, nil
+	case "<|end_of_text|>":
+		return 1, nil
+	}
+	return 0, errors.New("unknown special token")
+}
+
+func Example() {
+	adapter := &wordTokenizer{ids: map[string]int{}}
+	s := scanner.NewWithOptions(adapter, strings.NewReader("the cat saw the dog"),
+		scanner.WithEncodeOptions(&scanner.EncodeOptions{BOS: true, EOS: true}),
+		scanner.WithBufferSize(8),
+	)
+
+	for s.Scan() {
+		fmt.Print(s.Token(), " ")
+	}
+	if err := s.Err(); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println()
+	// Output: 0 2 3 4 2 5 1
+}
+
+func ExampleWithMaxTotalTokens() {
+	adapter := &wordTokenizer{ids: map[string]int{}}
+	s := scanner.NewWithOptions(adapter, strings.NewReader("one two three four"),
+		scanner.WithMaxTotalTokens(2))
+
+	for s.Scan() {
+		fmt.Println(s.Token())
+	}
+	var limit *scanner.LimitError
+	fmt.Println(errors.As(s.Err(), &limit), errors.Is(s.Err(), scanner.ErrTokenLimit))
+	// Output:
+	// 2
+	// 3
+	// true true
+}
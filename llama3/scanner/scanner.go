@@ -1,30 +1,106 @@
-// Package scanner provides buffered token scanning capabilities.
+// Package scanner tokenizes text streams with bounded memory, one token at a
+// time, for any tokenizer that implements the small Tokenizer interface.
+//
+// The llama3 package wraps it as Tokenizer.NewScanner. Use this package
+// directly to stream with a custom tokenizer adapter, for example one that
+// adds instrumentation or maps IDs to another vocabulary:
+//
+//	s := scanner.NewWithOptions(adapter, r, scanner.WithMaxTotalTokens(4096))
+//	for s.Scan() {
+//		process(s.Token())
+//	}
+//	if err := s.Err(); err != nil {
+//		return err
+//	}
+//
+// Input is split only between pre-tokens of the Llama 3 pattern, and never
+// inside a special token reported by a SpecialTokenLister, so the tokens
+// match encoding the whole input at once whenever Encode is consistent on
+// pre-token boundaries. Two cases break this by default: a pre-token longer
+// than WithMaxBuffer is split, and tokenizers that pre-tokenize differently,
+// which report it through PreTokenSplitter, are split on Llama 3 boundaries
+// anyway. WithExactBoundaries makes the tokens identical to Encode for any
+// input.
+//
+// This package is public API: its exported identifiers follow the module's
+// semantic versioning and do not change incompatibly within a major version.
 package scanner
 
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3/pretoken"
 )
 
 // Tokenizer is the interface required for tokenizing text.
 type Tokenizer interface {
+	// Encode converts a chunk of the input to token IDs. The scanner
+	// passes BOS for the first chunk only and never sets EOS, whose tokens
+	// it appends itself.
 	Encode(text string, opts *EncodeOptions) []int
+
+	// GetSpecialTokenID returns the ID of a special token, such as
+	// <|begin_of_text|>, or an error if the tokenizer has none.
 	GetSpecialTokenID(token string) (int, error)
 }
 
+// PreTokenSplitter is implemented by tokenizers that report whether Encode
+// is consistent on Llama 3 pre-token boundaries, that is whether encoding
+// text in pieces split between two pre-tokens gives the same tokens as
+// encoding it whole. Tokenizers that do not implement it are assumed to be.
+type PreTokenSplitter interface {
+	SplitsOnPreTokens() bool
+}
+
+// TokenTexter is implemented by tokenizers that return the text of a token,
+// the input bytes it stands for. The scanner uses it to report the text of
+// each token from Text.
+type TokenTexter interface {
+	TokenText(id int) (string, error)
+}
+
+// SpecialTokenLister is implemented by tokenizers that report the special
+// tokens Encode recognizes in text. The scanner never splits its input inside
+// one of them.
+type SpecialTokenLister interface {
+	SpecialTokens() []string
+}
+
 // EncodeOptions mirrors the options from the main package.
 type EncodeOptions struct {
 	BOS bool
 	EOS bool
+	// SuffixSpecials replaces <|end_of_text|> when EOS is true.
+	SuffixSpecials []string
 }
 
-// Scanner is the interface for streaming tokenization.
+// Scanner is the interface for streaming tokenization, following the
+// bufio.Scanner pattern.
 type Scanner interface {
+	// Scan advances to the next token. It returns false at the end of the
+	// input or on error.
 	Scan() bool
+
+	// Token returns the most recent token ID produced by Scan.
 	Token() int
+
+	// Text returns the input text that produced the current token, or ""
+	// for the BOS and EOS tokens the scanner adds. Byte-level tokens may
+	// hold part of a UTF-8 character, so the text of a single token is not
+	// always valid UTF-8. The texts of all tokens concatenate to the input,
+	// except that each byte of invalid UTF-8 in the input comes back as
+	// U+FFFD, the character Encode tokenizes it as. Text needs a tokenizer
+	// implementing TokenTexter and returns "" for others.
 	Text() string
+
+	// Err returns the first error encountered, or nil at the end of the
+	// input.
 	Err() error
 }
 
@@ -34,11 +110,19 @@ type scanner struct {
 	r *bufio.Reader
 
 	// Buffers
-	textBuf  bytes.Buffer // Accumulated text to tokenize
-	tokens   []int        // Buffered tokens
-	tokIndex int          // Current position in tokens buffer
-	lastText string       // Text for current token
-	pending  []byte       // Pending bytes from incomplete UTF-8 sequence
+	stream   *pretoken.Stream // Pre-tokenizes input to find safe split points
+	readBuf  []byte           // Buffer for reads from r
+	textBuf  bytes.Buffer     // Complete pre-tokens ready to tokenize
+	tokens   []int            // Buffered tokens
+	tokIndex int              // Current position in tokens buffer
+	pending  []byte           // Pending bytes from incomplete UTF-8 sequence
+	held     bytes.Buffer     // Complete pre-tokens held back, see writePreTokens
+
+	// Token texts, computed on the first call to Text for the buffered tokens
+	chunk       string   // Input text the buffered tokens were encoded from
+	chunkFirst  int      // Index in tokens of the first token of chunk
+	chunkTokens int      // Number of tokens encoded from chunk
+	texts       []string // Text of each buffered token, nil until computed
 
 	// State
 	err     error
@@ -46,9 +130,16 @@ type scanner struct {
 	sentBOS bool // Track if we've sent BOS token
 
 	// Options
-	opts      *EncodeOptions
-	bufSize   int // Internal buffer size
-	maxBuffer int // Maximum buffer size before forcing tokenization
+	opts          *EncodeOptions
+	bufSize       int   // Internal buffer size
+	maxBuffer     int   // Maximum incomplete input before forcing tokenization
+	maxTokens     int   // Maximum number of tokens to emit (0 = unlimited)
+	maxInputBytes int64 // Maximum number of input bytes to read (0 = unlimited)
+	exact         bool  // Never split inside a pre-token, see WithExactBoundaries
+	holdAll       bool  // Split only after special tokens, for exact scanning of tokenizers not splitting on pre-tokens
+
+	// Limit accounting
+	emitted int // Number of tokens returned by Scan
 }
 
 // Option configures scanner behavior.
@@ -65,7 +156,10 @@ func WithBufferSize(size int) Option {
 }
 
 // WithMaxBuffer sets the maximum buffer size before forcing tokenization.
-// This prevents unbounded memory growth for pathological inputs.
+// Input is normally split only between pre-tokens, so the scanner produces the
+// same tokens as encoding the whole input at once. A single pre-token longer
+// than the limit is split at the limit instead, which prevents unbounded
+// memory growth for pathological inputs, unless WithExactBoundaries is set.
 // Default is 1MB.
 func WithMaxBuffer(size int) Option {
 	return func(s *scanner) {
@@ -75,6 +169,21 @@ func WithMaxBuffer(size int) Option {
 	}
 }
 
+// WithExactBoundaries guarantees that the scanner produces exactly the
+// tokens of encoding the whole input at once, for any input.
+//
+// The scanner then never splits a pre-token, however long: WithMaxBuffer is
+// ignored and a run of millions of spaces is buffered whole, so bound the
+// input with WithMaxInputBytes when it is untrusted. If the tokenizer
+// reports through PreTokenSplitter that it does not split on Llama 3
+// pre-tokens, the input is only split after special tokens, and all of it
+// is buffered if it contains none.
+func WithExactBoundaries() Option {
+	return func(s *scanner) {
+		s.exact = true
+	}
+}
+
 // WithEncodeOptions sets encoding options for the scanner.
 func WithEncodeOptions(opts *EncodeOptions) Option {
 	return func(s *scanner) {
@@ -84,6 +193,30 @@ func WithEncodeOptions(opts *EncodeOptions) Option {
 	}
 }
 
+// WithMaxTotalTokens limits the number of tokens the scanner will emit.
+// Once n tokens have been returned, the next call to Scan returns false
+// and Err reports a *LimitError wrapping ErrTokenLimit.
+// A value of 0 or less means no limit.
+func WithMaxTotalTokens(n int) Option {
+	return func(s *scanner) {
+		if n > 0 {
+			s.maxTokens = n
+		}
+	}
+}
+
+// WithMaxInputBytes limits the number of bytes the scanner will read from
+// its input. If the input is longer than n bytes, Scan returns false and
+// Err reports a *LimitError wrapping ErrInputLimit.
+// A value of 0 or less means no limit.
+func WithMaxInputBytes(n int64) Option {
+	return func(s *scanner) {
+		if n > 0 {
+			s.maxInputBytes = n
+		}
+	}
+}
+
 // New creates a scanner for streaming tokenization with default options.
 func New(t Tokenizer, r io.Reader) Scanner {
 	return NewWithOptions(t, r)
@@ -104,6 +237,21 @@ func NewWithOptions(t Tokenizer, r io.Reader, opts ...Option) Scanner {
 		opt(s)
 	}
 
+	if splitter, ok := t.(PreTokenSplitter); ok && s.exact {
+		s.holdAll = !splitter.SplitsOnPreTokens()
+	}
+
+	if lister, ok := t.(SpecialTokenLister); ok {
+		s.stream = pretoken.NewStream(pretoken.WithSpecialTokens(lister.SpecialTokens()))
+	} else {
+		s.stream = pretoken.NewStream()
+	}
+
+	// Enforce the input limit below the buffered reader
+	if s.maxInputBytes > 0 {
+		r = &limitReader{r: r, remaining: s.maxInputBytes, limit: s.maxInputBytes}
+	}
+
 	// Set reader buffer size
 	s.r = bufio.NewReaderSize(r, s.bufSize)
 
@@ -119,67 +267,78 @@ func (s *scanner) scanBufferedToken() bool {
 	return false
 }
 
-// readMoreData reads data from the input reader into the buffer.
-// Returns true if data was read or EOF was reached.
+// readMoreData reads data from the input reader and buffers the pre-tokens
+// that are complete. Returns true if data was read or EOF was reached.
 func (s *scanner) readMoreData() (bool, error) {
-	buf := make([]byte, s.bufSize)
-	n, err := s.r.Read(buf)
+	if s.readBuf == nil {
+		s.readBuf = make([]byte, s.bufSize)
+	}
+	n, err := s.r.Read(s.readBuf)
 
 	if n > 0 {
-		toWrite := buf[:n]
-		if len(s.pending) > 0 {
-			toWrite = append(s.pending, buf[:n]...)
-			s.pending = nil
-		}
-
-		toWrite = s.handleBufferLimit(toWrite)
-		s.textBuf.Write(toWrite)
+		s.feed(s.readBuf[:n])
 	}
 
 	if err == io.EOF {
 		s.done = true
-		if len(s.pending) > 0 {
-			s.textBuf.Write(s.pending)
-			s.pending = nil
-		}
+		s.writePreTokens(s.stream.Feed(s.pending))
+		s.pending = nil
+		s.writePreTokens(s.stream.Flush())
+		s.textBuf.Write(s.held.Bytes())
+		s.held.Reset()
 		return true, nil
 	}
 
 	return n > 0, err
 }
 
-// handleBufferLimit ensures we don't exceed the buffer limit and handles UTF-8 boundaries.
-// Returns the adjusted byte slice that should be written.
-func (s *scanner) handleBufferLimit(toWrite []byte) []byte {
-	if s.textBuf.Len()+len(toWrite) > s.maxBuffer {
-		maxWrite := s.maxBuffer - s.textBuf.Len()
-		if maxWrite > 0 && maxWrite < len(toWrite) {
-			writeUpTo := findUTF8Boundary(toWrite, maxWrite)
-			if writeUpTo < len(toWrite) {
-				s.pending = make([]byte, len(toWrite)-writeUpTo)
-				copy(s.pending, toWrite[writeUpTo:])
-				return toWrite[:writeUpTo]
-			}
-		}
+// feed passes complete UTF-8 input to the pre-token stream, keeping an
+// incomplete trailing sequence pending.
+func (s *scanner) feed(data []byte) {
+	if len(s.pending) > 0 {
+		data = append(s.pending, data...)
+		s.pending = nil
+	}
+
+	if splitAt := findLastCompleteUTF8(data); splitAt < len(data) {
+		s.pending = make([]byte, len(data)-splitAt)
+		copy(s.pending, data[splitAt:])
+		data = data[:splitAt]
+	}
+
+	s.writePreTokens(s.stream.Feed(data))
+
+	// Force a split inside a pre-token that outgrew the buffer limit
+	if !s.exact && s.stream.Buffered()+s.held.Len() >= s.maxBuffer {
+		s.writePreTokens(s.stream.Flush())
+		s.textBuf.Write(s.held.Bytes())
+		s.held.Reset()
 	}
-	return toWrite
 }
 
-// handleMaxBufferReached handles the case when the buffer reaches its maximum size.
-func (s *scanner) handleMaxBufferReached() {
-	if s.textBuf.Len() == s.maxBuffer && len(s.pending) == 0 {
-		buf := s.textBuf.Bytes()
-		if len(buf) > 0 && !isValidUTF8Ending(buf) {
-			splitAt := findLastCompleteUTF8(buf)
-			if splitAt < len(buf) {
-				s.pending = make([]byte, len(buf)-splitAt)
-				copy(s.pending, buf[splitAt:])
-				s.textBuf.Truncate(splitAt)
-			}
+// writePreTokens appends complete pre-tokens to the text buffer, holding
+// back those that cannot end a chunk yet. Encode gives the last space of a
+// whitespace run to the following word or number, which it cannot do at the
+// end of a chunk, so pre-tokens ending in whitespace are held until a
+// pre-token that does not follows. With holdAll, all pre-tokens are held
+// until a special token ends them.
+func (s *scanner) writePreTokens(pretokens []pretoken.PreToken) {
+	for _, p := range pretokens {
+		s.held.WriteString(p.Text)
+		if p.Special || (!s.holdAll && !endsInSpace(p.Text)) {
+			s.textBuf.Write(s.held.Bytes())
+			s.held.Reset()
 		}
 	}
 }
 
+// endsInSpace reports whether text ends with a whitespace character, as
+// the Llama 3 pre-tokenizer defines it.
+func endsInSpace(text string) bool {
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return unicode.IsSpace(r)
+}
+
 // handleEOFTokens handles adding BOS/EOS tokens when the input is empty at EOF.
 func (s *scanner) handleEOFTokens() bool {
 	if s.textBuf.Len() == 0 {
@@ -191,11 +350,8 @@ func (s *scanner) handleEOFTokens() bool {
 			}
 		}
 		// Handle EOS
-		if s.opts.EOS {
-			if id, err := s.t.GetSpecialTokenID("<|end_of_text|>"); err == nil {
-				s.tokens = append(s.tokens, id)
-			}
-		}
+		s.appendEOS()
+		s.setChunk("", 0, 0)
 		if len(s.tokens) > 0 {
 			s.tokIndex = 0
 			return true
@@ -227,18 +383,65 @@ func (s *scanner) tokenizeBuffer() bool {
 	s.tokens = s.t.Encode(text, chunkOpts)
 	s.textBuf.Reset()
 
-	// Handle EOS if this is the last chunk
-	if s.done && s.opts.EOS {
-		if id, err := s.t.GetSpecialTokenID("<|end_of_text|>"); err == nil {
-			s.tokens = append(s.tokens, id)
+	first := 0
+	if addBOS && len(s.tokens) > 0 {
+		if id, err := s.t.GetSpecialTokenID("<|begin_of_text|>"); err == nil && s.tokens[0] == id {
+			first = 1
 		}
 	}
+	s.setChunk(text, first, len(s.tokens)-first)
+
+	// Handle EOS if this is the last chunk
+	if s.done {
+		s.appendEOS()
+	}
 
 	return len(s.tokens) > 0
 }
 
+// setChunk records that tokens[first:first+n] were encoded from text.
+func (s *scanner) setChunk(text string, first, n int) {
+	s.chunk = text
+	s.chunkFirst = first
+	s.chunkTokens = n
+	s.texts = s.texts[:0]
+}
+
+// appendEOS appends the configured end-of-sequence tokens to the token buffer.
+func (s *scanner) appendEOS() {
+	if !s.opts.EOS {
+		return
+	}
+
+	suffix := s.opts.SuffixSpecials
+	if len(suffix) == 0 {
+		suffix = []string{"<|end_of_text|>"}
+	}
+	for _, token := range suffix {
+		if id, err := s.t.GetSpecialTokenID(token); err == nil {
+			s.tokens = append(s.tokens, id)
+		}
+	}
+}
+
 // Scan advances to the next token.
 func (s *scanner) Scan() bool {
+	if !s.scan() {
+		return false
+	}
+
+	// Only fail once a token beyond the limit actually exists
+	if s.maxTokens > 0 && s.emitted >= s.maxTokens {
+		s.err = &LimitError{Limit: int64(s.maxTokens), Err: ErrTokenLimit}
+		return false
+	}
+
+	s.emitted++
+	return true
+}
+
+// scan advances to the next token without enforcing the token limit.
+func (s *scanner) scan() bool {
 	if s.err != nil {
 		return false
 	}
@@ -277,18 +480,11 @@ func (s *scanner) Scan() bool {
 	return false
 }
 
-// readAndAccumulateText reads data until we have enough to tokenize or reach EOF.
+// readAndAccumulateText reads data until complete pre-tokens are buffered or
+// EOF is reached.
 func (s *scanner) readAndAccumulateText() error {
 	for {
-		// Try to read more data
 		_, err := s.readMoreData()
-
-		// Check if we've hit the maximum buffer size
-		if s.textBuf.Len() >= s.maxBuffer {
-			s.handleMaxBufferReached()
-			break
-		}
-
 		if err != nil {
 			return err
 		}
@@ -301,8 +497,7 @@ func (s *scanner) readAndAccumulateText() error {
 			break
 		}
 
-		// Look for a good tokenization boundary
-		if s.hasTokenizationBoundary() {
+		if s.textBuf.Len() > 0 {
 			break
 		}
 	}
@@ -318,88 +513,48 @@ func (s *scanner) Token() int {
 	return 0
 }
 
-// Text returns the text that produced the current token.
-// Note: This returns the entire chunk that was tokenized, not individual token text.
+// Text returns the input text that produced the current token.
 func (s *scanner) Text() string {
-	return s.lastText
-}
-
-// Err returns any error encountered during scanning.
-func (s *scanner) Err() error {
-	if s.err == io.EOF {
-		return nil
-	}
-	return s.err
-}
-
-// hasTokenizationBoundary checks if the buffer ends at a good tokenization boundary.
-// This helps prevent splitting UTF-8 sequences or words unnecessarily.
-func (s *scanner) hasTokenizationBoundary() bool {
-	if s.textBuf.Len() == 0 {
-		return false
+	i := s.tokIndex - 1
+	if i < 0 || i >= len(s.tokens) {
+		return ""
 	}
-
-	// Get the last few bytes to check
-	buf := s.textBuf.Bytes()
-	if len(buf) == 0 {
-		return false
+	if len(s.texts) == 0 {
+		s.splitChunk()
 	}
-
-	// Check if we're at a whitespace boundary
-	lastByte := buf[len(buf)-1]
-	if lastByte == ' ' || lastByte == '\n' || lastByte == '\t' || lastByte == '\r' {
-		return true
-	}
-
-	// Check if we might be in the middle of a UTF-8 sequence
-	// UTF-8 continuation bytes start with 10xxxxxx
-	if lastByte&0xC0 == 0x80 {
-		// We're in the middle of a UTF-8 sequence, don't split here
-		return false
-	}
-
-	// If buffer is getting large, accept any UTF-8 boundary
-	if s.textBuf.Len() > s.bufSize/2 {
-		return true
-	}
-
-	return false
+	return s.texts[i]
 }
 
-// isValidUTF8Ending checks if the buffer ends at a valid UTF-8 boundary.
-func isValidUTF8Ending(buf []byte) bool {
-	if len(buf) == 0 {
-		return true
+// splitChunk computes the text of each buffered token. Tokens are cut from
+// the chunk so their texts share its memory; if the tokenizer's texts do not
+// line up with the chunk, its own texts are used instead.
+func (s *scanner) splitChunk() {
+	s.texts = append(s.texts[:0], make([]string, len(s.tokens))...)
+	texter, ok := s.t.(TokenTexter)
+	if !ok {
+		return
 	}
 
-	// Check the last byte
-	lastByte := buf[len(buf)-1]
-
-	// ASCII is always valid
-	if lastByte < 0x80 {
-		return true
-	}
-
-	// If it's a continuation byte, we might have an incomplete sequence
-	if lastByte&0xC0 == 0x80 {
-		// This is a continuation byte, not a valid ending
-		return false
+	pos := 0
+	for i := s.chunkFirst; i < s.chunkFirst+s.chunkTokens && i < len(s.tokens); i++ {
+		text, err := texter.TokenText(s.tokens[i])
+		if err != nil {
+			continue
+		}
+		if end := pos + len(text); end <= len(s.chunk) && s.chunk[pos:end] == text {
+			text = s.chunk[pos:end]
+		}
+		s.texts[i] = text
+		pos += len(text)
 	}
+}
 
-	// It's the start of a multi-byte sequence, check if complete
-	expectedLen := 0
-	if lastByte&0xE0 == 0xC0 {
-		expectedLen = 2
-	} else if lastByte&0xF0 == 0xE0 {
-		expectedLen = 3
-	} else if lastByte&0xF8 == 0xF0 {
-		expectedLen = 4
+// Err returns any error encountered during scanning.
+func (s *scanner) Err() error {
+	if s.err == io.EOF {
+		return nil
 	}
-
-	// Count continuation bytes after this start byte
-	// Since this is the last byte, we expect 0 continuation bytes
-	// So this is incomplete
-	return expectedLen <= 1
+	return s.err
 }
 
 // findLastCompleteUTF8 finds the last complete UTF-8 character boundary.
@@ -437,44 +592,63 @@ func findLastCompleteUTF8(buf []byte) int {
 	return len(buf)
 }
 
-// findUTF8Boundary finds the last valid UTF-8 boundary before maxBytes.
-// It returns the number of bytes that can be safely written without
-// splitting a UTF-8 character.
-func findUTF8Boundary(data []byte, maxBytes int) int {
-	if maxBytes >= len(data) {
-		return len(data)
-	}
+// Limit errors.
+var (
+	// ErrTokenLimit indicates the scanner stopped because WithMaxTotalTokens was reached.
+	ErrTokenLimit = errors.New("token limit exceeded")
 
-	// Start from maxBytes and work backwards to find a valid boundary
-	for i := maxBytes; i > 0 && i > maxBytes-4; i-- {
-		if i >= len(data) {
-			continue
-		}
+	// ErrInputLimit indicates the scanner stopped because WithMaxInputBytes was reached.
+	ErrInputLimit = errors.New("input size limit exceeded")
+)
 
-		b := data[i]
-		// Check if this is the start of a UTF-8 sequence or ASCII
-		if b < 0x80 || b&0xC0 != 0x80 {
-			// This is a valid boundary
-			return i
-		}
-	}
+// LimitError reports that a configured scanner limit was exceeded.
+type LimitError struct {
+	Limit int64 // The configured limit
+	Err   error // ErrTokenLimit or ErrInputLimit
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v (limit %d)", e.Err, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
 
-	// If we can't find a good boundary, check from the beginning
-	// of where we want to cut
-	if maxBytes < len(data) {
-		b := data[maxBytes]
-		if b&0xC0 == 0x80 {
-			// We're in the middle of a UTF-8 sequence
-			// Find the start of this sequence
-			for i := maxBytes - 1; i >= 0 && i >= maxBytes-4; i-- {
-				if data[i]&0xC0 != 0x80 {
-					return i
-				}
+// limitReader reads at most limit bytes from r and returns a *LimitError
+// if the underlying reader has more data available.
+type limitReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+// maxConsecutiveEmptyReads is how many times limitReader probes a reader
+// that returns no data and no error, as bufio.Reader does.
+const maxConsecutiveEmptyReads = 100
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Probe for one more byte to distinguish EOF from an oversized input
+		var probe [1]byte
+		for range maxConsecutiveEmptyReads {
+			n, err := l.r.Read(probe[:])
+			if n > 0 {
+				return 0, &LimitError{Limit: l.limit, Err: ErrInputLimit}
+			}
+			if err != nil {
+				return 0, err
 			}
 		}
+		return 0, io.ErrNoProgress
 	}
 
-	return maxBytes
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
 }
 
 // ScanError represents an error during scanning with context.
@@ -0,0 +1,159 @@
+package llama3
+
+import (
+	"cmp"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ScriptCommon names text with no script of its own, such as digits,
+// punctuation and whitespace, when it is not next to text of any script.
+const ScriptCommon = "Common"
+
+// ScriptStats reports how efficiently text in one writing system tokenizes.
+type ScriptStats struct {
+	Script string `json:"script"` // Unicode script name, such as "Latin" or "Han"
+	Chars  int    `json:"chars"`  // Characters (runes) of the script
+	Bytes  int    `json:"bytes"`  // UTF-8 bytes of those characters
+	Tokens int    `json:"tokens"` // Tokens attributed to the script
+}
+
+// TokensPerChar returns the number of tokens per character, the usual measure
+// of how much text in a script costs.
+func (s ScriptStats) TokensPerChar() float64 {
+	if s.Chars == 0 {
+		return 0
+	}
+	return float64(s.Tokens) / float64(s.Chars)
+}
+
+// ScriptStats encodes text and breaks the characters and tokens down by
+// Unicode script, sorted by token count, so the cost of each language in a
+// dataset can be compared. Scripts stand in for languages: they are cheap to
+// detect and separate the languages whose tokenization differs most.
+//
+// Characters without a script of their own (digits, punctuation, spaces and
+// combining marks) count toward the script of the text around them. A token
+// counts toward the script of the first character it covers that has one,
+// or else toward the script its first character counts toward. Text that is
+// not valid UTF-8 is attributed to ScriptCommon.
+func (t *Tokenizer) ScriptStats(text string) []ScriptStats {
+	scripts := resolveScripts(text)
+
+	index := make(map[string]int)
+	var stats []ScriptStats
+	stat := func(name string) *ScriptStats {
+		i, ok := index[name]
+		if !ok {
+			i = len(stats)
+			index[name] = i
+			stats = append(stats, ScriptStats{Script: name})
+		}
+		return &stats[i]
+	}
+
+	for _, s := range scripts {
+		st := stat(s.resolved)
+		st.Chars++
+		st.Bytes += s.size
+	}
+
+	// Walk the tokens' byte spans over the characters
+	tokens := t.Encode(text, &EncodeOptions{})
+	start, c := 0, 0
+	for _, id := range tokens {
+		n, _ := t.tokenLen(id)
+		end := min(start+n, len(text))
+		for c < len(scripts)-1 && scripts[c].offset+scripts[c].size <= start {
+			c++
+		}
+		name := ScriptCommon
+		if c < len(scripts) {
+			name = scripts[c].resolved
+			for i := c; i < len(scripts) && scripts[i].offset < end; i++ {
+				if scripts[i].own {
+					name = scripts[i].resolved
+					break
+				}
+			}
+		}
+		stat(name).Tokens++
+		start = end
+	}
+
+	slices.SortStableFunc(stats, func(a, b ScriptStats) int {
+		return cmp.Compare(b.Tokens, a.Tokens)
+	})
+	return stats
+}
+
+// charScript is the script of one character of a text.
+type charScript struct {
+	offset, size int
+	resolved     string // script the character counts toward
+	own          bool   // whether the character has a script of its own
+}
+
+// resolveScripts returns the script of each character of text, with
+// characters without a script taking the script of the preceding text, or of
+// the following text at the start.
+func resolveScripts(text string) []charScript {
+	chars := make([]charScript, 0, len(text))
+	pending := 0 // characters at the start still waiting for a script
+	current := ""
+	for offset := 0; offset < len(text); {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		c := charScript{offset: offset, size: size}
+		if r == utf8.RuneError && size <= 1 {
+			c.resolved = ScriptCommon
+		} else if name := scriptOf(r); name != "" {
+			c.resolved, c.own = name, true
+			current = name
+			for ; pending > 0; pending-- {
+				chars[len(chars)-pending].resolved = name
+			}
+		} else if current != "" {
+			c.resolved = current
+		} else {
+			c.resolved = ScriptCommon
+			pending++
+		}
+		chars = append(chars, c)
+		offset += size
+	}
+	return chars
+}
+
+// frequentScripts are tried first by scriptOf, roughly in order of how much
+// web text uses them.
+var frequentScripts = []string{
+	"Latin", "Han", "Cyrillic", "Arabic", "Hiragana", "Katakana", "Hangul",
+	"Devanagari", "Greek", "Hebrew", "Thai", "Bengali", "Tamil", "Telugu",
+	"Georgian", "Armenian", "Gujarati", "Kannada", "Malayalam", "Ethiopic",
+}
+
+// scriptOf returns the Unicode script of r, or "" for characters of the
+// Common and Inherited scripts, which are used by text of many scripts.
+func scriptOf(r rune) string {
+	if r < utf8.RuneSelf {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
+			return "Latin"
+		}
+		return ""
+	}
+	if unicode.Is(unicode.Common, r) || unicode.Is(unicode.Inherited, r) {
+		return ""
+	}
+	for _, name := range frequentScripts {
+		if unicode.Is(unicode.Scripts[name], r) {
+			return name
+		}
+	}
+	for name, table := range unicode.Scripts {
+		if unicode.Is(table, r) {
+			return name
+		}
+	}
+	return "" // unassigned code points
+}
@@ -0,0 +1,71 @@
+package llama3
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestScriptStats(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	text := "2024: Hello, world! Привет, мир! 你好，世界。 γειά σου"
+	stats := tokenizer.ScriptStats(text)
+
+	byScript := make(map[string]ScriptStats)
+	chars, bytes, tokens := 0, 0, 0
+	for _, s := range stats {
+		byScript[s.Script] = s
+		chars += s.Chars
+		bytes += s.Bytes
+		tokens += s.Tokens
+	}
+
+	for _, script := range []string{"Latin", "Cyrillic", "Han", "Greek"} {
+		if byScript[script].Tokens == 0 {
+			t.Errorf("Expected tokens for %s, got %+v", script, stats)
+		}
+	}
+	if _, ok := byScript[ScriptCommon]; ok {
+		t.Errorf("Expected digits and punctuation to join a script, got %+v", stats)
+	}
+	if want := utf8.RuneCountInString(text); chars != want {
+		t.Errorf("Expected %d characters, got %d", want, chars)
+	}
+	if bytes != len(text) {
+		t.Errorf("Expected %d bytes, got %d", len(text), bytes)
+	}
+	if want := len(tokenizer.Encode(text, &EncodeOptions{})); tokens != want {
+		t.Errorf("Expected %d tokens, got %d", want, tokens)
+	}
+	// "2024: " precedes the first letter and counts as Latin
+	if got := byScript["Latin"].Chars; got != len("2024: Hello, world! ") {
+		t.Errorf("Expected %d Latin characters, got %d", len("2024: Hello, world! "), got)
+	}
+
+	for i := 1; i < len(stats); i++ {
+		if stats[i].Tokens > stats[i-1].Tokens {
+			t.Errorf("Expected stats sorted by tokens, got %+v", stats)
+		}
+	}
+}
+
+func TestScriptStatsCommon(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	stats := tokenizer.ScriptStats("123 + 456 = 579\xff")
+	if len(stats) != 1 || stats[0].Script != ScriptCommon {
+		t.Fatalf("Expected only %s, got %+v", ScriptCommon, stats)
+	}
+	if stats[0].TokensPerChar() <= 0 {
+		t.Errorf("Expected a positive ratio, got %f", stats[0].TokensPerChar())
+	}
+	if got := tokenizer.ScriptStats(""); len(got) != 0 {
+		t.Errorf("Expected no stats for empty text, got %+v", got)
+	}
+}
@@ -0,0 +1,97 @@
+package llama3
+
+import (
+	"sync"
+	"time"
+)
+
+// sessionCacheSize is the number of pre-tokens an EncodeSession caches
+// before it starts over.
+const sessionCacheSize = 4096
+
+// maxPooledSessionTokens is the output capacity above which Count drops a
+// session instead of pooling it, so one huge text does not pin its buffer.
+const maxPooledSessionTokens = 1 << 16
+
+// countSessions holds the sessions Count encodes with, for any tokenizer.
+var countSessions sync.Pool
+
+// EncodeSession encodes many texts on one goroutine with as little
+// allocation as possible. It keeps its output, pre-token and byte buffers
+// between calls, and a private cache of recent pre-tokens that needs no
+// locking, in front of the tokenizer's shared cache. Pooled buffers can be
+// lost to other goroutines or the garbage collector; a session's cannot.
+//
+// An EncodeSession is not safe for concurrent use. Create one per worker:
+//
+//	session := tokenizer.NewEncodeSession()
+//	for _, doc := range docs {
+//	    total += session.Count(doc, nil)
+//	}
+type EncodeSession struct {
+	t     *Tokenizer
+	out   []int
+	cache map[string][]int
+	encodeScratch
+}
+
+// NewEncodeSession returns a session that encodes with t.
+func (t *Tokenizer) NewEncodeSession() *EncodeSession {
+	return &EncodeSession{t: t, cache: make(map[string][]int)}
+}
+
+// Encode encodes text like Tokenizer.Encode. The returned slice is reused
+// by the next call on the session; copy it to keep it.
+func (s *EncodeSession) Encode(text string, opts *EncodeOptions) []int {
+	t := s.t
+	if t.observer != nil && len(text) >= t.observeMinBytes {
+		start := time.Now()
+		s.out = t.appendTokens(s.out[:0], text, opts, s)
+		t.observe(OpEncode, start, len(text), len(s.out))
+		return s.out
+	}
+	s.out = t.appendTokens(s.out[:0], text, opts, s)
+	return s.out
+}
+
+// Count returns the number of tokens in text, len(Encode(text, opts)).
+func (s *EncodeSession) Count(text string, opts *EncodeOptions) int {
+	return len(s.Encode(text, opts))
+}
+
+// Count returns the number of tokens in text, len(Encode(text, opts)),
+// without allocating a result. It encodes with a pooled EncodeSession, so
+// repeated counts reuse its buffers and pre-token cache, which makes it the
+// fastest way to count many texts from many goroutines. Count is observed as
+// an encode.
+func (t *Tokenizer) Count(text string, opts *EncodeOptions) int {
+	s, _ := countSessions.Get().(*EncodeSession)
+	switch {
+	case s == nil:
+		s = t.NewEncodeSession()
+	case s.t != t:
+		s.t = t
+		clear(s.cache)
+	}
+	n := s.Count(text, opts)
+	if cap(s.out) <= maxPooledSessionTokens {
+		countSessions.Put(s)
+	}
+	return n
+}
+
+// appendBPE appends the tokens of the byte-level encoded pretoken to dst,
+// from the session's cache if it holds them.
+func (s *EncodeSession) appendBPE(dst []int, pretoken []byte) []int {
+	if tokens, ok := s.cache[string(pretoken)]; ok {
+		return append(dst, tokens...)
+	}
+
+	key := string(pretoken)
+	tokens := s.t.performBPE(key)
+	if len(s.cache) >= sessionCacheSize {
+		clear(s.cache)
+	}
+	s.cache[key] = tokens
+	return append(dst, tokens...)
+}
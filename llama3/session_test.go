@@ -0,0 +1,101 @@
+package llama3
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncodeSession(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	session := tokenizer.NewEncodeSession()
+
+	texts := []string{
+		"Hello, world!",
+		"",
+		"<|begin_of_text|>Hello<|eot_id|> again, world",
+		"Привет мир 日本語 😀👍🏽 and   spaces\n\n\ttabs",
+		"Hello, world!", // cached by the session
+		strings.Repeat("The quick brown fox jumps over the lazy dog. ", 2000),
+	}
+	opts := []*EncodeOptions{nil, {}, {BOS: true, EOS: true, SuffixSpecials: []string{"<|eot_id|>"}}}
+
+	for _, text := range texts {
+		for _, o := range opts {
+			want := tokenizer.Encode(text, o)
+			if got := session.Encode(text, o); !equalIntSlices(got, want) {
+				t.Errorf("Encode(%.20q): expected %v, got %v", text, want, got)
+			}
+			if n := session.Count(text, o); n != len(want) {
+				t.Errorf("Count(%.20q): expected %d, got %d", text, len(want), n)
+			}
+		}
+	}
+
+	// Diag is filled as by Tokenizer.Encode
+	diag := &EncodeDiag{}
+	session.Encode("hello world", &EncodeOptions{Diag: diag})
+	if diag.Pretokens != 2 {
+		t.Errorf("Expected 2 pretokens, got %d", diag.Pretokens)
+	}
+}
+
+func TestEncodeSessionAllocs(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	session := tokenizer.NewEncodeSession()
+	text := "The quick brown fox jumps over the lazy dog."
+	session.Encode(text, nil)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		session.Encode(text, nil)
+	})
+	if want := testing.AllocsPerRun(100, func() {
+		tokenizer.Encode(text, nil)
+	}); allocs >= want {
+		t.Errorf("Expected fewer than %v allocations, got %v", want, allocs)
+	}
+}
+
+func TestCount(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	other, err := New(WithSpecialTokens([]string{"<|begin_of_text|>", "<|end_of_text|>"}))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	for _, text := range []string{"", "Hello, world!", "<|eot_id|> done", strings.Repeat("count me ", 100)} {
+		for _, opts := range []*EncodeOptions{nil, noSpecialTokens} {
+			if got, want := tokenizer.Count(text, opts), len(tokenizer.Encode(text, opts)); got != want {
+				t.Errorf("Count(%q): expected %d, got %d", text, want, got)
+			}
+			// Sessions pooled for one tokenizer must not leak into another
+			if got, want := other.Count(text, opts), len(other.Encode(text, opts)); got != want {
+				t.Errorf("Count(%q) with other special tokens: expected %d, got %d", text, want, got)
+			}
+		}
+	}
+
+	if raceEnabled {
+		return // sync.Pool drops items at random under the race detector
+	}
+	text := "The quick brown fox jumps over the lazy dog."
+	tokenizer.Count(text, nil)
+	session := tokenizer.NewEncodeSession()
+	session.Count(text, nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		tokenizer.Count(text, nil)
+	})
+	if want := testing.AllocsPerRun(100, func() {
+		session.Count(text, nil)
+	}); allocs > want {
+		t.Errorf("Expected at most the %v allocations of a session, got %v", want, allocs)
+	}
+}
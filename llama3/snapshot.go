@@ -0,0 +1,290 @@
+package llama3
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"unsafe"
+
+	"github.com/agentstation/tokenizer/bpe"
+)
+
+// snapshotMagic starts every snapshot file.
+const snapshotMagic = "LL3SNAP"
+
+// SnapshotVersion is the version of the snapshot format written by
+// WriteSnapshot.
+const SnapshotVersion = 1
+
+// snapshotSkipVocabLookup is the flag set for tokenizers with merge overrides.
+const snapshotSkipVocabLookup = 1
+
+// WriteSnapshot writes the tokenizer's vocabulary, special tokens and merge
+// table to w in a binary format that NewFromSnapshot loads faster than New
+// builds them, because the merge table is stored resolved to token IDs. A
+// snapshot is built once, for example by an init container, and loaded by
+// every process that starts afterwards.
+//
+// The format is version 1:
+//
+//	magic     "LL3SNAP"
+//	version   byte
+//	flags     byte     1 if merge overrides were applied
+//	regular   uvarint  number of regular tokens, then each as uvarint length and bytes
+//	special   uvarint  number of special tokens, then each as above
+//	merges    uvarint  number of merges, then each as uvarint left, right, rank and result IDs
+//	checksum  uint32   CRC-32 (IEEE) of everything before it, little-endian
+//
+// Merges are sorted by rank. Caches and instrumentation are not part of the
+// snapshot.
+func (t *Tokenizer) WriteSnapshot(w io.Writer) error {
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+
+	var b []byte
+	b = append(b, snapshotMagic...)
+	b = append(b, SnapshotVersion, 0)
+	if t.processor.SkipVocabLookup {
+		b[len(b)-1] = snapshotSkipVocabLookup
+	}
+	appendTokens := func(tokens []string) {
+		b = binary.AppendUvarint(b, uint64(len(tokens)))
+		for _, token := range tokens {
+			b = binary.AppendUvarint(b, uint64(len(token)))
+			b = append(b, token...)
+		}
+	}
+	appendTokens(t.tokens[:t.regularLen])
+	appendTokens(t.tokens[t.regularLen:])
+	if _, err := bw.Write(b); err != nil {
+		return err
+	}
+
+	type merge struct {
+		key uint64
+		bpe.Merge
+	}
+	merges := make([]merge, 0, len(t.merges))
+	for key, m := range t.merges {
+		merges = append(merges, merge{key, m})
+	}
+	slices.SortFunc(merges, func(a, b merge) int { return a.Rank - b.Rank })
+
+	b = binary.AppendUvarint(b[:0], uint64(len(merges)))
+	for _, m := range merges {
+		b = binary.AppendUvarint(b, m.key>>32)
+		b = binary.AppendUvarint(b, m.key&0xffffffff)
+		b = binary.AppendUvarint(b, uint64(m.Rank)) // #nosec G115 - ranks are positive
+		b = binary.AppendUvarint(b, uint64(m.ID))   // #nosec G115 - token IDs are not negative
+		if len(b) > 64<<10 {
+			if _, err := bw.Write(b); err != nil {
+				return err
+			}
+			b = b[:0]
+		}
+	}
+	if _, err := bw.Write(b); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, crc.Sum32())
+}
+
+// WriteSnapshotFile writes a snapshot to path, replacing it atomically so a
+// process loading it never sees a partial file.
+func (t *Tokenizer) WriteSnapshotFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	err = t.WriteSnapshot(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// NewFromSnapshot creates a tokenizer from a snapshot written by
+// WriteSnapshot. opts may set the cache, observer and load limits; options
+// that change the vocabulary, special tokens or merges are rejected, since
+// the snapshot already fixes them.
+func NewFromSnapshot(path string, opts ...Option) (*Tokenizer, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path is provided by the caller
+	if err != nil {
+		return nil, NewDataError("load snapshot", path, err)
+	}
+	s, err := decodeSnapshot(data, false)
+	if err != nil {
+		return nil, NewDataError("load snapshot", path, err)
+	}
+	return New(append(opts[:len(opts):len(opts)], withSnapshot(s))...)
+}
+
+// NewFromMappedSnapshot is NewFromSnapshot for hosts running many
+// tokenizer processes, such as batch shell pipelines. It maps the snapshot
+// file read-only instead of reading it, and the token text of the
+// tokenizer points into the mapping. Every process that maps the same file
+// shares one copy of it in the page cache, and startup copies no token
+// bytes. The lookup tables are still built by each process.
+//
+// The mapping is kept for the life of the process, so call it once per
+// snapshot rather than per request. The file must not be modified in place
+// while mapped; WriteSnapshotFile replaces files atomically, which is safe.
+// Platforms without memory mapping read the file as NewFromSnapshot does.
+func NewFromMappedSnapshot(path string, opts ...Option) (*Tokenizer, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, NewDataError("map snapshot", path, err)
+	}
+	s, err := decodeSnapshot(data, true)
+	if err != nil {
+		_ = unmap()
+		return nil, NewDataError("map snapshot", path, err)
+	}
+	t, err := New(append(opts[:len(opts):len(opts)], withSnapshot(s))...)
+	if err != nil {
+		_ = unmap()
+		return nil, err
+	}
+	t.mapped = true
+	return t, nil
+}
+
+// snapshot is a decoded snapshot file. It is the data loader of tokenizers
+// created from it.
+type snapshot struct {
+	skipVocabLookup bool
+	regular         []string
+	special         []string
+	merges          []uint64 // left, right, rank and ID of each merge
+}
+
+// withSnapshot loads the tokenizer from s.
+func withSnapshot(s *snapshot) Option {
+	return func(cfg *config) error {
+		if cfg.dataLoader != nil || cfg.specialTokens != nil || len(cfg.mergeOverrides) > 0 {
+			return NewConfigError("snapshot", nil,
+				errors.New("vocabulary, special token and merge options cannot be combined with a snapshot"))
+		}
+		cfg.dataLoader = s
+		cfg.specialTokens = s.special
+		cfg.skipVocabLookup = s.skipVocabLookup
+		return nil
+	}
+}
+
+func (s *snapshot) LoadVocabulary() ([]string, error) {
+	return s.regular, nil
+}
+
+func (s *snapshot) LoadMerges() (map[string]int, error) {
+	tokens := append(s.regular[:len(s.regular):len(s.regular)], s.special...)
+	merges := make(map[string]int, len(s.merges)/4)
+	for i := 0; i+3 < len(s.merges); i += 4 {
+		merges[tokens[s.merges[i]]+" "+tokens[s.merges[i+1]]] = int(s.merges[i+2]) // #nosec G115 - checked by decodeSnapshot
+	}
+	return merges, nil
+}
+
+func (s *snapshot) loadMergeTable() (map[uint64]bpe.Merge, error) {
+	merges := make(map[uint64]bpe.Merge, len(s.merges)/4)
+	for i := 0; i+3 < len(s.merges); i += 4 {
+		merges[s.merges[i]<<32|s.merges[i+1]] = bpe.Merge{
+			Rank: int(s.merges[i+2]), // #nosec G115 - checked by decodeSnapshot
+			ID:   int(s.merges[i+3]), // #nosec G115 - checked by decodeSnapshot
+		}
+	}
+	return merges, nil
+}
+
+// decodeSnapshot parses and checks a snapshot file. Tokens alias data if
+// shared is true, which then must never change.
+func decodeSnapshot(data []byte, shared bool) (*snapshot, error) {
+	if len(data) < len(snapshotMagic)+2+4 || string(data[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errors.New("not a tokenizer snapshot")
+	}
+	body, sum := data[:len(data)-4], binary.LittleEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, errors.New("snapshot checksum mismatch")
+	}
+	if v := body[len(snapshotMagic)]; v != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", v)
+	}
+	s := &snapshot{skipVocabLookup: body[len(snapshotMagic)+1]&snapshotSkipVocabLookup != 0}
+
+	// Tokens are substrings of one copy of the data, or of the data itself
+	// if shared, which saves an allocation per token.
+	var rest string
+	if tokens := body[len(snapshotMagic)+2:]; shared && len(tokens) > 0 {
+		rest = unsafe.String(&tokens[0], len(tokens)) // #nosec G103 - data is read-only and never unmapped
+	} else {
+		rest = string(tokens)
+	}
+	readUvarint := func() (uint64, error) {
+		v, n := binary.Uvarint([]byte(rest[:min(len(rest), binary.MaxVarintLen64)]))
+		if n <= 0 {
+			return 0, errors.New("truncated snapshot")
+		}
+		rest = rest[n:]
+		return v, nil
+	}
+	readTokens := func() ([]string, error) {
+		n, err := readUvarint()
+		if err != nil || n > uint64(len(rest)) {
+			return nil, errors.New("truncated snapshot")
+		}
+		tokens := make([]string, n)
+		for i := range tokens {
+			size, err := readUvarint()
+			if err != nil || size > uint64(len(rest)) {
+				return nil, errors.New("truncated snapshot")
+			}
+			tokens[i], rest = rest[:size], rest[size:]
+		}
+		return tokens, nil
+	}
+
+	var err error
+	if s.regular, err = readTokens(); err != nil {
+		return nil, err
+	}
+	if s.special, err = readTokens(); err != nil {
+		return nil, err
+	}
+
+	count, err := readUvarint()
+	if err != nil || count > uint64(len(rest))/4 {
+		return nil, errors.New("truncated snapshot")
+	}
+	vocabSize := uint64(len(s.regular) + len(s.special))
+	s.merges = make([]uint64, 4*count)
+	for i := range s.merges {
+		v, err := readUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if i%4 != 2 && v >= vocabSize || i%4 == 2 && v > 1<<31 {
+			return nil, fmt.Errorf("merge %d out of range", i/4)
+		}
+		s.merges[i] = v
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data in snapshot")
+	}
+	return s, nil
+}
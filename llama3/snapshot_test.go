@@ -0,0 +1,126 @@
+package llama3
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"embedded", nil},
+		{"merge overrides", []Option{WithMergeOverrides(MergeOverride{Left: "Ġt", Right: "he", Disable: true})}},
+		{"special tokens", []Option{WithSpecialTokens([]string{"<|begin_of_text|>", "<|end_of_text|>", "<|custom|>"})}},
+	}
+
+	texts := []string{
+		"Hello, world!",
+		" the theory of the thing",
+		"           grabbed",
+		"<|begin_of_text|>Snapshot <|custom|> tokens<|end_of_text|>",
+		"日本語のテキスト",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original, err := New(tt.opts...)
+			if err != nil {
+				t.Fatalf("Failed to create tokenizer: %v", err)
+			}
+			path := filepath.Join(t.TempDir(), "llama3.snap")
+			if err := original.WriteSnapshotFile(path); err != nil {
+				t.Fatalf("WriteSnapshotFile failed: %v", err)
+			}
+
+			loaded, err := NewFromSnapshot(path, WithCacheSize(100))
+			if err != nil {
+				t.Fatalf("NewFromSnapshot failed: %v", err)
+			}
+			mapped, err := NewFromMappedSnapshot(path)
+			if err != nil {
+				t.Fatalf("NewFromMappedSnapshot failed: %v", err)
+			}
+			for _, tok := range []*Tokenizer{loaded, mapped} {
+				if tok.VocabSize() != original.VocabSize() {
+					t.Errorf("Expected vocab size %d, got %d", original.VocabSize(), tok.VocabSize())
+				}
+				for _, text := range texts {
+					want := original.Encode(text, nil)
+					if got := tok.Encode(text, nil); !equalIntSlices(got, want) {
+						t.Errorf("Encode(%q): expected %v, got %v", text, want, got)
+					}
+					if got := tok.Decode(want); got != original.Decode(want) {
+						t.Errorf("Decode(%v): expected %q, got %q", want, original.Decode(want), got)
+					}
+				}
+			}
+
+			var again bytes.Buffer
+			if err := loaded.WriteSnapshot(&again); err != nil {
+				t.Fatalf("WriteSnapshot failed: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile failed: %v", err)
+			}
+			if !bytes.Equal(again.Bytes(), data) {
+				t.Error("Expected a snapshot of a loaded snapshot to be identical")
+			}
+		})
+	}
+}
+
+func TestNewFromSnapshotErrors(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tokenizer.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("WriteSnapshot failed: %v", err)
+	}
+	valid := buf.Bytes()
+
+	corrupt := bytes.Clone(valid)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	tests := []struct {
+		name string
+		data []byte
+		opts []Option
+	}{
+		{"empty", nil, nil},
+		{"not a snapshot", []byte("not a tokenizer snapshot file"), nil},
+		{"truncated", valid[:len(valid)/2], nil},
+		{"corrupt", corrupt, nil},
+		{"special tokens option", valid, []Option{WithSpecialTokens([]string{"<|x|>"})}},
+		{"merge overrides option", valid, []Option{WithMergeOverrides(MergeOverride{Left: "Ġt", Right: "he", Disable: true})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "llama3.snap")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatalf("WriteFile failed: %v", err)
+			}
+			if _, err := NewFromSnapshot(path, tt.opts...); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+			if _, err := NewFromMappedSnapshot(path, tt.opts...); err == nil {
+				t.Error("Expected an error from NewFromMappedSnapshot, got nil")
+			}
+		})
+	}
+
+	if _, err := NewFromSnapshot(filepath.Join(t.TempDir(), "missing.snap")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist, got %v", err)
+	}
+	if _, err := NewFromMappedSnapshot(filepath.Join(t.TempDir(), "missing.snap")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist, got %v", err)
+	}
+}
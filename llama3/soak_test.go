@@ -0,0 +1,181 @@
+package llama3
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentstation/tokenizer/llama3/pretoken"
+)
+
+// Soak test flags. The soak test only runs when -soak is set, for example:
+//
+//	go test ./llama3 -run TestSoak -v -timeout 0 -args -soak=10m
+var (
+	soakDuration = flag.Duration("soak", 0, "run the soak test for this long")
+	soakInterval = flag.Duration("soak.interval", 10*time.Second, "heap sampling interval of the soak test, at most a tenth of -soak")
+	soakCache    = flag.Int("soak.cache", 10000, "BPE cache size of the soak test tokenizer, 0 for unbounded")
+)
+
+// soakText returns random text that keeps producing new pre-tokens, mixing
+// ASCII words, numbers, punctuation, whitespace, non-Latin scripts and
+// special tokens.
+func soakText(rng *rand.Rand) string {
+	scripts := [][2]rune{{'a', 'z'}, {'A', 'Z'}, {'0', '9'}, {'α', 'ω'}, {'а', 'я'}, {'一', '龥'}, {'🌀', '🙏'}}
+	separators := []string{" ", "  ", "\n", "\n\n", "\t", ", ", ". ", "'s ", "!? ", "<|eot_id|>"}
+
+	var sb strings.Builder
+	for i := rng.Intn(200); i >= 0; i-- {
+		script := scripts[rng.Intn(len(scripts))]
+		for j := 1 + rng.Intn(8); j > 0; j-- {
+			sb.WriteRune(script[0] + rune(rng.Intn(int(script[1]-script[0])+1)))
+		}
+		sb.WriteString(separators[rng.Intn(len(separators))])
+	}
+	return sb.String()
+}
+
+// soakOnce runs one random tokenizer operation on text.
+func soakOnce(t *testing.T, tokenizer *Tokenizer, rng *rand.Rand, text string) {
+	switch rng.Intn(5) {
+	case 0:
+		tokens := tokenizer.Encode(text, nil)
+		if got := tokenizer.Decode(tokens); got != beginOfTextToken+text+endOfTextToken {
+			t.Errorf("Round trip mismatch for %q", text)
+		}
+	case 1:
+		dst := make([]int, 0, 16)
+		_ = tokenizer.AppendTokens(dst, text, noSpecialTokens)
+	case 2:
+		scanner := tokenizer.NewScanner(strings.NewReader(text), WithBufferSize(64))
+		for scanner.Scan() {
+			_ = scanner.Token()
+		}
+		if err := scanner.Err(); err != nil {
+			t.Errorf("Scanner error: %v", err)
+		}
+	case 3:
+		counter := NewCountingReader(strings.NewReader(text), tokenizer)
+		if _, err := io.Copy(io.Discard, counter); err != nil {
+			t.Errorf("CountingReader error: %v", err)
+		}
+	case 4:
+		stream := pretoken.NewStream()
+		data := []byte(text)
+		for len(data) > 0 {
+			n := 1 + rng.Intn(16)
+			if n > len(data) {
+				n = len(data)
+			}
+			_ = stream.Feed(data[:n])
+			data = data[n:]
+		}
+		_ = stream.Flush()
+	}
+}
+
+// TestSoak tokenizes random inputs from several goroutines for the -soak
+// duration while sampling the heap. After a warmup of a fifth of the run the
+// live heap must stay flat: growth beyond that points at leaking pools,
+// scanner buffers or an unbounded cache.
+func TestSoak(t *testing.T) {
+	if *soakDuration <= 0 {
+		t.Skip("Skipping soak test: run with -soak=<duration>")
+	}
+
+	tokenizer, err := New(WithCacheSize(*soakCache))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	deadline := time.Now().Add(*soakDuration)
+	warmup := time.Now().Add(*soakDuration / 5)
+
+	var ops int64
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			n := int64(0)
+			for time.Now().Before(deadline) {
+				soakOnce(t, tokenizer, rng, soakText(rng))
+				n++
+			}
+			mu.Lock()
+			ops += n
+			mu.Unlock()
+		}(int64(w))
+	}
+
+	// Sample the live heap after a forced GC
+	sample := func() (heap uint64, goroutines int) {
+		runtime.GC()
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+		return stats.HeapAlloc, runtime.NumGoroutine()
+	}
+
+	var baseHeap, maxHeap uint64
+	var baseGoroutines int
+	interval := *soakInterval
+	if interval > *soakDuration/10 {
+		interval = *soakDuration / 10
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		if now.After(deadline) {
+			break
+		}
+
+		heap, goroutines := sample()
+		t.Logf("heap=%.1fMB goroutines=%d cache=%d memory=%.1fMB",
+			float64(heap)/(1<<20), goroutines, soakCacheLen(tokenizer), float64(tokenizer.MemoryUsage())/(1<<20))
+
+		switch {
+		case now.Before(warmup):
+		case baseHeap == 0:
+			baseHeap, baseGoroutines = heap, goroutines
+		case heap > maxHeap:
+			maxHeap = heap
+		}
+		if *soakCache > 0 && soakCacheLen(tokenizer) > *soakCache {
+			t.Errorf("Cache holds %d entries, limit is %d", soakCacheLen(tokenizer), *soakCache)
+		}
+		if goroutines > baseGoroutines+1 && baseGoroutines > 0 {
+			t.Errorf("Goroutines grew from %d to %d", baseGoroutines, goroutines)
+		}
+	}
+	wg.Wait()
+
+	t.Logf("%d operations in %v", ops, *soakDuration)
+	if baseHeap == 0 {
+		t.Fatal("No heap samples after warmup")
+	}
+
+	// Allow for noise in the size of in-flight inputs and pool contents
+	if limit := baseHeap + baseHeap/2 + 8<<20; maxHeap > limit {
+		t.Errorf("Live heap grew from %s to %s after warmup", formatBytes(baseHeap), formatBytes(maxHeap))
+	}
+}
+
+// soakCacheLen returns the number of entries in the tokenizer's BPE cache.
+func soakCacheLen(t *Tokenizer) int {
+	if c, ok := t.cache.(interface{ Len() int }); ok {
+		return c.Len()
+	}
+	return 0
+}
+
+func formatBytes(n uint64) string {
+	return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+}
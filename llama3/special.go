@@ -0,0 +1,165 @@
+package llama3
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SpecialToken names a well-known Llama 3 special token, for code that
+// needs one by meaning rather than by its text:
+//
+//	stop := tokenizer.ID(llama3.EOT)
+//
+// The values are stable: new tokens are added at the end, and existing
+// values never change, so they may be stored. The zero value is not a
+// token.
+type SpecialToken int
+
+// Well-known special tokens.
+const (
+	BeginOfText SpecialToken = iota + 1 // <|begin_of_text|>, starts a sequence
+	EndOfText                           // <|end_of_text|>, ends a sequence
+	StartHeader                         // <|start_header_id|>, opens a chat message's role
+	EndHeader                           // <|end_header_id|>, closes a chat message's role
+	EOT                                 // <|eot_id|>, ends a chat turn
+	EOM                                 // <|eom_id|>, ends a message that awaits a tool result
+	PythonTag                           // <|python_tag|>, starts a tool call
+	FinetunePad                         // <|finetune_right_pad_id|>, pads fine-tuning sequences
+	Image                               // <|image|>, stands for an image in Llama 3.2 Vision prompts
+
+	numSpecialTokens // one past the last token, for sizing tables
+)
+
+// specialTokenText is the text of each SpecialToken.
+var specialTokenText = [numSpecialTokens]string{
+	BeginOfText: beginOfTextToken,
+	EndOfText:   endOfTextToken,
+	StartHeader: "<|start_header_id|>",
+	EndHeader:   "<|end_header_id|>",
+	EOT:         endOfTurnToken,
+	EOM:         "<|eom_id|>",
+	PythonTag:   "<|python_tag|>",
+	FinetunePad: "<|finetune_right_pad_id|>",
+	Image:       "<|image|>",
+}
+
+// String returns the token's text, such as "<|eot_id|>", or "" for values
+// that are not tokens.
+func (s SpecialToken) String() string {
+	if s <= 0 || s >= numSpecialTokens {
+		return ""
+	}
+	return specialTokenText[s]
+}
+
+// ID returns the token ID of tok, or -1 if tok is not a SpecialToken or the
+// tokenizer's special tokens do not include it.
+func (t *Tokenizer) ID(tok SpecialToken) int {
+	if tok <= 0 || tok >= numSpecialTokens {
+		return -1
+	}
+	return t.specialIDs[tok]
+}
+
+// resolveSpecialIDs looks up the IDs of the well-known special tokens once,
+// after the tokenizer's special tokens are set.
+func (t *Tokenizer) resolveSpecialIDs() {
+	t.specialIDs[0] = -1
+	for tok := BeginOfText; tok < numSpecialTokens; tok++ {
+		t.specialIDs[tok] = -1
+		if id, ok := t.tokenLookup[specialTokenText[tok]]; ok && id >= t.regularLen {
+			t.specialIDs[tok] = id
+		}
+	}
+}
+
+// hasSpecialToken reports whether s is one of the tokenizer's special tokens,
+// which Encode turns into their IDs.
+func (t *Tokenizer) hasSpecialToken(s string) bool {
+	if id, ok := t.tokenLookup[s]; ok {
+		return id >= t.regularLen
+	}
+	a := t.loadAdded()
+	if a == nil {
+		return false
+	}
+	_, ok := a.lookup[s]
+	return ok
+}
+
+// SpecialTokenProfile is the special tokens of a model family, in ID order
+// from the end of the regular vocabulary. Profiles for checkpoints that give
+// reserved tokens a meaning can be defined from a built-in one:
+//
+//	profile, _ := llama3.LookupSpecialTokenProfile(llama3.ProfileLlama31)
+//	profile.Tokens = slices.Clone(profile.Tokens)
+//	profile.Tokens[2] = "<|tool_call|>" // was <|reserved_special_token_0|>
+//	tokenizer, err := llama3.New(llama3.WithSpecialTokenProfile(profile))
+type SpecialTokenProfile struct {
+	Name   string
+	Tokens []string
+}
+
+// Names of the built-in special token profiles.
+const (
+	// ProfileLlama30 is Llama 3.0, which reserves the IDs Llama 3.1 gave to
+	// <|finetune_right_pad_id|>, <|eom_id|> and <|python_tag|>.
+	ProfileLlama30 = "llama3.0"
+	// ProfileLlama31 is Llama 3.1 and the Llama 3.2 and 3.3 text models. It
+	// is the default.
+	ProfileLlama31 = "llama3.1"
+	// ProfileLlama32Vision is Llama 3.2 Vision: Llama 3.1 and <|image|>,
+	// with ID 128256.
+	ProfileLlama32Vision = "llama3.2-vision"
+)
+
+// ErrUnknownProfile indicates a special token profile name that is not
+// built in.
+var ErrUnknownProfile = errors.New("unknown special token profile")
+
+// specialTokenProfiles builds the tokens of each built-in profile.
+var specialTokenProfiles = map[string]func() []string{
+	ProfileLlama30: func() []string {
+		tokens := []string{
+			beginOfTextToken,
+			endOfTextToken,
+			"<|reserved_special_token_0|>",
+			"<|reserved_special_token_1|>",
+			"<|reserved_special_token_2|>",
+			"<|reserved_special_token_3|>",
+			specialTokenText[StartHeader],
+			specialTokenText[EndHeader],
+			"<|reserved_special_token_4|>",
+			endOfTurnToken,
+		}
+		for i := 5; len(tokens) < specialTokenCount; i++ {
+			tokens = append(tokens, fmt.Sprintf("<|reserved_special_token_%d|>", i))
+		}
+		return tokens
+	},
+	ProfileLlama31: getDefaultSpecialTokens,
+	ProfileLlama32Vision: func() []string {
+		return append(getDefaultSpecialTokens(), specialTokenText[Image])
+	},
+}
+
+// LookupSpecialTokenProfile returns the built-in profile with the given name,
+// or ErrUnknownProfile. The profile's Tokens are a new slice.
+func LookupSpecialTokenProfile(name string) (SpecialTokenProfile, error) {
+	build, ok := specialTokenProfiles[name]
+	if !ok {
+		return SpecialTokenProfile{}, fmt.Errorf("%w: %q", ErrUnknownProfile, name)
+	}
+	return SpecialTokenProfile{Name: name, Tokens: build()}, nil
+}
+
+// SpecialTokenProfiles returns the names of the built-in profiles, sorted.
+func SpecialTokenProfiles() []string {
+	names := make([]string, 0, len(specialTokenProfiles))
+	for name := range specialTokenProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,161 @@
+package llama3
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSpecialTokenID(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	tests := []struct {
+		tok  SpecialToken
+		text string
+		id   int
+	}{
+		{BeginOfText, "<|begin_of_text|>", 128000},
+		{EndOfText, "<|end_of_text|>", 128001},
+		{StartHeader, "<|start_header_id|>", 128006},
+		{EndHeader, "<|end_header_id|>", 128007},
+		{EOT, "<|eot_id|>", 128009},
+		{EOM, "<|eom_id|>", 128008},
+		{PythonTag, "<|python_tag|>", 128010},
+		{FinetunePad, "<|finetune_right_pad_id|>", 128004},
+		{Image, "<|image|>", -1},
+		{0, "", -1},
+		{numSpecialTokens, "", -1},
+	}
+	for _, tt := range tests {
+		if got := tt.tok.String(); got != tt.text {
+			t.Errorf("SpecialToken(%d).String() = %q, want %q", tt.tok, got, tt.text)
+		}
+		if got := tokenizer.ID(tt.tok); got != tt.id {
+			t.Errorf("ID(%d) = %d, want %d", tt.tok, got, tt.id)
+		}
+	}
+
+	custom, err := New(WithSpecialTokens([]string{"<|eom_id|>"}))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	if got := custom.ID(EOM); got != baseVocabSize {
+		t.Errorf("custom ID(EOM) = %d, want %d", got, baseVocabSize)
+	}
+	if got := custom.ID(StartHeader); got != -1 {
+		t.Errorf("custom ID(StartHeader) = %d, want -1", got)
+	}
+}
+
+func TestLookupSpecialTokenProfile(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  map[int]string // tokens by offset from the regular vocabulary
+	}{
+		{ProfileLlama30, 256, map[int]string{4: "<|reserved_special_token_2|>", 8: "<|reserved_special_token_4|>", 9: endOfTurnToken, 255: "<|reserved_special_token_250|>"}},
+		{ProfileLlama31, 256, map[int]string{4: "<|finetune_right_pad_id|>", 8: "<|eom_id|>", 9: endOfTurnToken, 255: "<|reserved_special_token_247|>"}},
+		{ProfileLlama32Vision, 257, map[int]string{8: "<|eom_id|>", 256: "<|image|>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := LookupSpecialTokenProfile(tt.name)
+			if err != nil {
+				t.Fatalf("LookupSpecialTokenProfile() error = %v", err)
+			}
+			if len(profile.Tokens) != tt.count {
+				t.Errorf("Expected %d tokens, got %d", tt.count, len(profile.Tokens))
+			}
+			for i, token := range tt.want {
+				if profile.Tokens[i] != token {
+					t.Errorf("Expected token %d to be %s, got %s", i, token, profile.Tokens[i])
+				}
+			}
+		})
+	}
+
+	if _, err := LookupSpecialTokenProfile("llama2"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Expected ErrUnknownProfile, got %v", err)
+	}
+	if names := SpecialTokenProfiles(); !slices.Equal(names, []string{ProfileLlama30, ProfileLlama31, ProfileLlama32Vision}) {
+		t.Errorf("Unexpected profile names %v", names)
+	}
+}
+
+func TestWithSpecialTokenProfile(t *testing.T) {
+	if tokenizer, err := New(); err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	newWithProfile := func(t *testing.T, profile SpecialTokenProfile) *Tokenizer {
+		t.Helper()
+		tokenizer, err := New(WithSpecialTokenProfile(profile))
+		if err != nil {
+			t.Fatalf("Failed to create tokenizer: %v", err)
+		}
+		return tokenizer
+	}
+
+	t.Run("vision", func(t *testing.T) {
+		profile, _ := LookupSpecialTokenProfile(ProfileLlama32Vision)
+		tokenizer := newWithProfile(t, profile)
+		if got := tokenizer.VocabSize(); got != 128257 {
+			t.Errorf("Expected vocabulary size 128257, got %d", got)
+		}
+		if got := tokenizer.ID(Image); got != 128256 {
+			t.Errorf("Expected ID(Image) 128256, got %d", got)
+		}
+
+		text := "<|image|>Describe this image."
+		tokens := tokenizer.Encode(text, noSpecialTokens)
+		if len(tokens) == 0 || tokens[0] != 128256 {
+			t.Errorf("Expected <|image|> to encode to 128256, got %v", tokens)
+		}
+		if got := tokenizer.Decode(tokens); got != text {
+			t.Errorf("Expected %q, got %q", text, got)
+		}
+
+		var scanned []int
+		scanner := tokenizer.NewScanner(strings.NewReader(strings.Repeat(text, 100)), WithBufferSize(7),
+			WithEncodeOptions(noSpecialTokens))
+		for scanner.Scan() {
+			scanned = append(scanned, scanner.Token())
+		}
+		if want := tokenizer.Encode(strings.Repeat(text, 100), noSpecialTokens); !equalIntSlices(scanned, want) {
+			t.Errorf("Scanner produced %d tokens, Encode %d", len(scanned), len(want))
+		}
+	})
+
+	t.Run("llama3.0", func(t *testing.T) {
+		profile, _ := LookupSpecialTokenProfile(ProfileLlama30)
+		tokenizer := newWithProfile(t, profile)
+		if got := tokenizer.ID(EOM); got != -1 {
+			t.Errorf("Expected ID(EOM) -1, got %d", got)
+		}
+		if got := tokenizer.EOTTokenID(); got != 128009 {
+			t.Errorf("Expected EOT 128009, got %d", got)
+		}
+		if tokens := tokenizer.Encode("<|eom_id|>", noSpecialTokens); len(tokens) < 2 {
+			t.Errorf("Expected <|eom_id|> to encode as text, got %v", tokens)
+		}
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		profile, _ := LookupSpecialTokenProfile(ProfileLlama31)
+		profile.Tokens[2] = "<|tool_call|>"
+		tokenizer := newWithProfile(t, profile)
+		if tokens := tokenizer.Encode("<|tool_call|>", noSpecialTokens); !equalIntSlices(tokens, []int{128002}) {
+			t.Errorf("Expected [128002], got %v", tokens)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var configErr *ConfigError
+		if _, err := New(WithSpecialTokenProfile(SpecialTokenProfile{Name: "none"})); !errors.As(err, &configErr) {
+			t.Errorf("Expected ConfigError, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package llama3
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of a document produced by a Splitter.
+type Chunk struct {
+	Text   string
+	Span       // range of Text in the document
+	Tokens int // number of tokens of Text, without BOS/EOS
+}
+
+// Splitter splits long documents into chunks of at most a number of tokens,
+// for retrieval pipelines that embed or prompt with pieces of a document.
+// A Splitter is safe for concurrent use.
+type Splitter struct {
+	t         *Tokenizer
+	maxTokens int
+	overlap   int
+}
+
+// NewSplitter returns a Splitter that cuts text into chunks of at most
+// maxTokens tokens, each starting about overlap tokens before the end of the
+// previous one so that text near a cut appears in both chunks. overlap must
+// be less than maxTokens; zero means no overlap.
+func (t *Tokenizer) NewSplitter(maxTokens, overlap int) (*Splitter, error) {
+	if maxTokens <= 0 {
+		return nil, NewConfigError("max_tokens", maxTokens, errors.New("must be positive"))
+	}
+	if overlap < 0 || overlap >= maxTokens {
+		return nil, NewConfigError("overlap", overlap, errors.New("must be at least 0 and less than max_tokens"))
+	}
+	return &Splitter{t: t, maxTokens: maxTokens, overlap: overlap}, nil
+}
+
+// Cut levels, from the least to the most preferred place to end a chunk.
+const (
+	cutInsideRune = iota
+	cutToken
+	cutWord
+	cutSentence
+	cutLine
+	cutParagraph
+	cutEnd
+)
+
+// Split splits text into chunks in order. Chunks end at the last paragraph
+// break that leaves them at least half full, or else at the last line break,
+// sentence end, word boundary or token boundary, in that order of
+// preference. Whitespace around chunks is dropped, so Text is exactly
+// text[Start:End], and blank text yields no chunks.
+//
+// Each chunk encodes on its own, without BOS/EOS, to at most maxTokens
+// tokens. Special token text such as "<|eot_id|>" counts as one token, as
+// in Encode. Only a maxTokens too small to hold a character makes chunks
+// cut through it.
+func (s *Splitter) Split(text string) []Chunk {
+	spans := s.t.EncodeWithOffsets(text, noSpecialTokens)
+	minTokens := max(s.overlap+1, s.maxTokens/2)
+
+	var chunks []Chunk
+	type cut struct{ end, level int }
+	var cuts []cut
+	for first := 0; first < len(spans); {
+		// Cuts after each token of the window, best first
+		cuts = cuts[:0]
+		last := min(first+s.maxTokens, len(spans))
+		for i := first + 1; i <= last; i++ {
+			level := cutLevel(text, spans[i-1].End)
+			if i-first < minTokens && level > cutToken && level != cutEnd {
+				level = cutToken // too short for a boundary to matter
+			}
+			cuts = append(cuts, cut{end: i, level: level})
+		}
+		slices.SortStableFunc(cuts, func(a, b cut) int {
+			if a.level != b.level {
+				return b.level - a.level
+			}
+			return b.end - a.end
+		})
+
+		// A piece may encode to more tokens than it spans, rarely, at its
+		// edges; take the best cut that fits
+		end := first + 1
+		chunk := s.chunk(text, spans[first].Start, spans[first].End)
+		for _, c := range cuts {
+			if candidate := s.chunk(text, spans[first].Start, spans[c.end-1].End); candidate.Tokens <= s.maxTokens {
+				chunk, end = candidate, c.end
+				break
+			}
+		}
+		if chunk.Tokens > 0 {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(spans) {
+			break
+		}
+		first = s.overlapStart(text, spans, first, end)
+	}
+	return chunks
+}
+
+// chunk returns the chunk of text[start:end] without surrounding whitespace.
+func (s *Splitter) chunk(text string, start, end int) Chunk {
+	piece := text[start:end]
+	trimmed := strings.TrimLeftFunc(piece, unicode.IsSpace)
+	start += len(piece) - len(trimmed)
+	trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+	if trimmed == "" {
+		return Chunk{Span: Span{Start: start, End: start}}
+	}
+	return Chunk{
+		Text:   trimmed,
+		Span:   Span{Start: start, End: start + len(trimmed)},
+		Tokens: len(s.t.Encode(trimmed, noSpecialTokens)),
+	}
+}
+
+// overlapStart returns the first token of the chunk after the one spanning
+// tokens [first, end): overlap tokens back from end, not counting blank
+// ones, moved forward to a word boundary if the overlap has one.
+func (s *Splitter) overlapStart(text string, spans []TokenSpan, first, end int) int {
+	start, n := end, 0
+	for start > first+1 && n < s.overlap {
+		start--
+		if strings.TrimSpace(text[spans[start].Start:spans[start].End]) != "" {
+			n++
+		}
+	}
+	for i := start; i < end; i++ {
+		if cutLevel(text, spans[i].Start) >= cutWord {
+			return i
+		}
+	}
+	for i := start; i < end; i++ {
+		if cutLevel(text, spans[i].Start) > cutInsideRune {
+			return i
+		}
+	}
+	return end
+}
+
+// cutLevel returns how good a place offset i of text is to end a chunk.
+func cutLevel(text string, i int) int {
+	if i >= len(text) {
+		return cutEnd
+	}
+	if !utf8.RuneStart(text[i]) {
+		return cutInsideRune
+	}
+
+	before := strings.TrimRight(text[:i], " \t\r")
+	switch {
+	case strings.HasSuffix(before, "\n\n"):
+		return cutParagraph
+	case strings.HasSuffix(before, "\n"):
+		return cutLine
+	}
+
+	prev, _ := utf8.DecodeLastRuneInString(text[:i])
+	if strings.ContainsRune("。！？", prev) {
+		return cutSentence // needs no space after it
+	}
+	next, _ := utf8.DecodeRuneInString(text[i:])
+	if unicode.IsSpace(next) {
+		// Closing quotes and brackets may follow the end of a sentence
+		end := strings.TrimRight(text[:i], "\"')]}”’»")
+		if r, _ := utf8.DecodeLastRuneInString(end); strings.ContainsRune(".!?", r) {
+			return cutSentence
+		}
+		return cutWord
+	}
+	if unicode.IsSpace(prev) {
+		return cutWord
+	}
+	return cutToken
+}
@@ -0,0 +1,140 @@
+package llama3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNewSplitterErrors(t *testing.T) {
+	tokenizer := &Tokenizer{}
+	tests := []struct {
+		name      string
+		maxTokens int
+		overlap   int
+		field     string
+	}{
+		{"zero_max", 0, 0, "max_tokens"},
+		{"negative_overlap", 10, -1, "overlap"},
+		{"overlap_too_large", 10, 10, "overlap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tokenizer.NewSplitter(tt.maxTokens, tt.overlap)
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) || configErr.Field != tt.field {
+				t.Errorf("Expected ConfigError for %s, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+// splitterDocument returns paragraphs of sentences, with some non-Latin text.
+func splitterDocument() string {
+	var sb strings.Builder
+	for p := range 12 {
+		for s := range 4 {
+			fmt.Fprintf(&sb, "Paragraph %d, sentence %d talks about tokenizers and chunking. ", p, s)
+		}
+		if p%3 == 0 {
+			sb.WriteString("日本語の文も含まれています。絵文字 🤖 もあります。")
+		}
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+func TestSplitter(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	text := splitterDocument()
+
+	tests := []struct {
+		name      string
+		text      string
+		maxTokens int
+		overlap   int
+	}{
+		{"paragraphs", text, 200, 0},
+		{"sentences", text, 40, 0},
+		{"overlap", text, 40, 10},
+		{"tiny", text, 3, 0},
+		{"cjk", strings.Repeat("日本語の文です。", 40), 16, 4},
+		{"one_character_per_chunk", "🤖🤖🤖", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			splitter, err := tokenizer.NewSplitter(tt.maxTokens, tt.overlap)
+			if err != nil {
+				t.Fatalf("NewSplitter() error = %v", err)
+			}
+			chunks := splitter.Split(tt.text)
+			if len(chunks) == 0 {
+				t.Fatal("Expected chunks, got none")
+			}
+
+			covered := 0
+			for i, c := range chunks {
+				if c.Text != tt.text[c.Start:c.End] {
+					t.Fatalf("Chunk %d: Text %q is not text[%d:%d]", i, c.Text, c.Start, c.End)
+				}
+				if n := len(tokenizer.Encode(c.Text, noSpecialTokens)); n != c.Tokens || n > tt.maxTokens && tt.maxTokens > 1 {
+					t.Errorf("Chunk %d: %d tokens, reported %d, max %d", i, n, c.Tokens, tt.maxTokens)
+				}
+				if tt.maxTokens > 1 && !utf8.ValidString(c.Text) {
+					t.Errorf("Chunk %d: %q is not valid UTF-8", i, c.Text)
+				}
+				if gap := tt.text[covered:max(covered, c.Start)]; strings.TrimSpace(gap) != "" {
+					t.Errorf("Chunk %d: text %q before it is in no chunk", i, gap)
+				}
+				if i > 0 && c.Start <= chunks[i-1].Start {
+					t.Errorf("Chunk %d: starts at %d, not after chunk %d at %d", i, c.Start, i-1, chunks[i-1].Start)
+				}
+				if i > 0 && tt.overlap > 0 && c.Start >= chunks[i-1].End {
+					t.Errorf("Chunk %d: starts at %d, expected overlap with chunk %d ending at %d", i, c.Start, i-1, chunks[i-1].End)
+				}
+				covered = max(covered, c.End)
+			}
+			if rest := tt.text[covered:]; strings.TrimSpace(rest) != "" {
+				t.Errorf("Text %q after the last chunk is in no chunk", rest)
+			}
+		})
+	}
+}
+
+func TestSplitterBoundaries(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	text := splitterDocument()
+
+	t.Run("paragraphs", func(t *testing.T) {
+		splitter, _ := tokenizer.NewSplitter(200, 0)
+		for i, c := range splitter.Split(text) {
+			if rest := strings.TrimLeft(text[c.End:], " "); rest != "" && !strings.HasPrefix(rest, "\n\n") {
+				t.Errorf("Chunk %d ends inside a paragraph: %q", i, c.Text[max(0, len(c.Text)-20):])
+			}
+		}
+	})
+
+	t.Run("sentences", func(t *testing.T) {
+		splitter, _ := tokenizer.NewSplitter(40, 0)
+		for i, c := range splitter.Split(text) {
+			if !strings.HasSuffix(c.Text, ".") && !strings.HasSuffix(c.Text, "。") {
+				t.Errorf("Chunk %d ends inside a sentence: %q", i, c.Text[max(0, len(c.Text)-20):])
+			}
+		}
+	})
+
+	t.Run("blank", func(t *testing.T) {
+		splitter, _ := tokenizer.NewSplitter(10, 0)
+		if chunks := splitter.Split(" \n\n\t "); len(chunks) != 0 {
+			t.Errorf("Expected no chunks, got %q", chunks)
+		}
+	})
+}
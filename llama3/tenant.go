@@ -0,0 +1,28 @@
+package llama3
+
+// WithCache returns a view of the tokenizer that shares its vocabulary and
+// merge tables but keeps BPE results in cache instead of t's cache. A nil
+// cache disables caching for the view.
+//
+// Views are cheap, so a multi-tenant service can load one tokenizer and give
+// each tenant a view with its own bounded cache; one tenant's unusual traffic
+// then only evicts that tenant's entries:
+//
+//	view, ok := views[tenant]
+//	if !ok {
+//	    view = shared.WithCache(bpe.NewLRU(10000))
+//	    views[tenant] = view
+//	}
+//	tokens := view.Encode(text, nil)
+//
+// Views and t may be used concurrently. Configuration such as the observer
+// is shared with t.
+func (t *Tokenizer) WithCache(cache Cache) *Tokenizer {
+	view := *t
+	view.cache = cache
+
+	processor := *t.processor
+	processor.Cache = cache
+	view.processor = &processor
+	return &view
+}
@@ -0,0 +1,44 @@
+package llama3
+
+import (
+	"testing"
+
+	"github.com/agentstation/tokenizer/bpe"
+)
+
+func TestWithCache(t *testing.T) {
+	shared, err := New(WithCacheSize(1000))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	sharedCache := shared.cache.(*bpe.LRUCache)
+
+	text := "One tenant's unusual traffic should not evict another's entries."
+	want := shared.Encode(text, nil)
+
+	tenantCache := bpe.NewLRU(100)
+	view := shared.WithCache(tenantCache)
+	other := "Completely different words populate only the tenant cache."
+	if got := view.Encode(other, nil); !equalIntSlices(got, shared.Encode(other, nil)) {
+		t.Errorf("Expected view and shared tokenizer to agree on %q", other)
+	}
+	if got := view.Encode(text, nil); !equalIntSlices(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if tenantCache.Len() == 0 {
+		t.Error("Expected the view to fill its own cache")
+	}
+	view.Encode("zxqv", nil)
+	if _, ok := sharedCache.Get("zxqv"); ok {
+		t.Error("Expected the view not to write to the shared cache")
+	}
+	if _, ok := tenantCache.Get("zxqv"); !ok {
+		t.Error("Expected the view to write to its own cache")
+	}
+
+	uncached := shared.WithCache(nil)
+	if got := uncached.Encode(text, nil); !equalIntSlices(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
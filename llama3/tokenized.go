@@ -0,0 +1,97 @@
+package llama3
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Thresholds of LooksLikeTokenizedText.
+const (
+	minTokenIDFields   = 3 // integers needed to call a text a list of token IDs
+	maxTokenIDDigits   = 7 // longer integers are not token IDs
+	minByteLevelFields = 2 // stand-in runes needed to call a text byte-level
+)
+
+// byteLevelWhitespace holds the runes that byte-level BPE vocabularies use
+// for space, newline, tab and carriage return, such as the Ġ of "Ġworld".
+var byteLevelWhitespace = func() map[rune]bool {
+	set := make(map[rune]bool)
+	for _, r := range encodeBytes([]byte(" \n\t\r")) {
+		set[r] = true
+	}
+	return set
+}()
+
+// LooksLikeTokenizedText reports whether s looks like the output of a
+// tokenizer rather than text: token IDs, space- or comma-separated or as a
+// JSON array, or byte-level token text such as "HelloĠworld", in which the
+// byte-level alphabet stands in for whitespace. Encoding such input is a
+// common pipeline bug, when an artifact that is already encoded is fed to
+// the tokenizer again.
+//
+// It is a heuristic meant for warnings. Short inputs, such as one or two
+// numbers, are not reported, and rare texts, such as tables of numbers,
+// are.
+func LooksLikeTokenizedText(s string) bool {
+	return looksLikeTokenIDs(s) || looksLikeByteLevelText(s)
+}
+
+// looksLikeTokenIDs reports whether s is a list of at least
+// minTokenIDFields integers separated by whitespace or commas, optionally
+// in brackets.
+func looksLikeTokenIDs(s string) bool {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == ','
+	})
+	if len(fields) < minTokenIDFields {
+		return false
+	}
+	for _, f := range fields {
+		if len(f) > maxTokenIDDigits {
+			return false
+		}
+		for i := 0; i < len(f); i++ {
+			if f[i] < '0' || f[i] > '9' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// looksLikeByteLevelText reports whether byte-level whitespace runes stand
+// in for the whitespace of s: there are at least minByteLevelFields of
+// them, and they outnumber the real whitespace or start at least a quarter
+// of the words, as in a space-separated dump of tokens.
+func looksLikeByteLevelText(s string) bool {
+	standIns, spaces := 0, 0
+	for _, r := range s {
+		switch {
+		case byteLevelWhitespace[r]:
+			standIns++
+		case unicode.IsSpace(r):
+			spaces++
+		}
+	}
+	if standIns < minByteLevelFields {
+		return false
+	}
+	if standIns > spaces {
+		return true
+	}
+
+	// Tokens listed one per word, as in "Hello Ġworld Ġ!"
+	fields := strings.Fields(s)
+	leading := 0
+	for _, f := range fields {
+		if r, _ := utf8.DecodeRuneInString(f); byteLevelWhitespace[r] {
+			leading++
+		}
+	}
+	return leading >= minByteLevelFields && leading*4 >= len(fields)
+}
@@ -0,0 +1,29 @@
+package llama3
+
+import "testing"
+
+func TestLooksLikeTokenizedText(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"128000 9906 11 1917 0 128001", true},
+		{"[128000, 9906, 11, 1917]", true},
+		{"9906\n11\n1917\n", true},
+		{"HelloĠworldĠhowĠareĠyou", true},
+		{"HelloĊĊHiĠthere", true},
+		{"Hello Ġworld Ġ! ĠHow", true},
+		{"Hello, world!", false},
+		{"1917", false},
+		{"12 34", false},
+		{"Call 555 1234 now", false},
+		{"12345678901 12345678902 12345678903", false},
+		{"Il-Ġimgħa li ġejja se nżuru Ġgantija u l-Belt", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := LooksLikeTokenizedText(tt.input); got != tt.want {
+			t.Errorf("LooksLikeTokenizedText(%q): expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+}
@@ -4,10 +4,15 @@
 package llama3
 
 import (
-	"github.com/agentstation/tokenizer/llama3/internal/bpe"
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/agentstation/tokenizer/bpe"
 	"github.com/agentstation/tokenizer/llama3/internal/encoding"
 	"github.com/agentstation/tokenizer/llama3/internal/pretokenizer"
 	"github.com/agentstation/tokenizer/llama3/internal/tokens"
+	"github.com/agentstation/tokenizer/llama3/internal/vocabulary"
 )
 
 // Internal utility functions and variables
@@ -23,11 +28,11 @@ var (
 
 // Special token handling.
 var (
-	// specialTokenRegex matches Llama 3 special tokens.
-	specialTokenRegex = tokens.SpecialTokenRegex
+	// isDefaultSpecialToken reports whether a string is a Llama 3 special token.
+	isDefaultSpecialToken = tokens.IsDefaultSpecialToken
 
-	// optimisticSpecialTokenRegex matches any pattern that looks like a special token.
-	optimisticSpecialTokenRegex = tokens.OptimisticSpecialTokenRegex
+	// looksLikeSpecialToken reports whether a string looks like a special token.
+	looksLikeSpecialToken = tokens.LooksLikeSpecialToken
 )
 
 // getDefaultSpecialTokens returns all Llama 3 special tokens in order.
@@ -40,8 +45,18 @@ func isSpecialToken(token string) bool {
 	return tokens.IsSpecialToken(token)
 }
 
-// splitBySpecialTokens splits text by special tokens while preserving the tokens.
-var splitBySpecialTokens = tokens.SplitBySpecialTokens
+// segment is a special token, or the text between special tokens.
+type segment = tokens.Segment
+
+// appendSegments appends the segments of text, split at the special tokens
+// match accepts, to dst.
+func appendSegments(dst []segment, text string, match func(string) bool) []segment {
+	return tokens.AppendSegments(dst, text, match)
+}
+
+// segmentBufSize is the number of segments encode splits text into without
+// allocating.
+const segmentBufSize = 16
 
 // Encoder is the interface for encoding text to tokens.
 // This interface is useful for testing and creating mock implementations.
@@ -94,13 +109,34 @@ type PreTokenizer interface {
 
 // Tokenizer implements the Llama 3 BPE tokenizer.
 type Tokenizer struct {
-	tokens      []string       // Token ID to text mapping
-	tokenLookup map[string]int // Text to token ID mapping
-	mergeRules  map[string]int // BPE merge rules with priorities
+	tokens      []string             // Token ID to text mapping
+	regularLen  int                  // Number of regular (non-special) tokens
+	tokenLookup map[string]int       // Text to token ID mapping
+	merges      map[uint64]bpe.Merge // BPE merges keyed by token ID pair
+	processor   *bpe.Processor       // BPE engine over tokenLookup, merges and cache
+
+	specialIDs [numSpecialTokens]int // IDs of the well-known special tokens, -1 if absent
 
 	// Cache for BPE results
 	cache     bpeCache
 	cacheSize int // Maximum cache size (0 = unlimited)
+
+	// Optional instrumentation
+	observer        Observer
+	observeMinBytes int
+
+	bytesPerToken float64 // fixed ratio for output capacity, 0 to estimate
+
+	atomic     *regexp.Regexp // matches pre-tokenized whole, nil for reference behavior
+	optimistic *regexp.Regexp // pseudo-special tokens of OptimisticCount, nil for DefaultOptimisticPattern
+	mapped     bool           // token text aliases a mapped snapshot
+
+	// Configuration reported by Info
+	atomicPatterns     []string
+	optimisticPatterns []string
+	mergeOverrides     int
+
+	added *addedVocab // special tokens added at run time, shared with views
 }
 
 // EncodeOptions controls the encoding behavior.
@@ -109,6 +145,12 @@ type EncodeOptions struct {
 	BOS bool
 	// EOS adds the end-of-text token if true (default: true)
 	EOS bool
+	// SuffixSpecials replaces <|end_of_text|> with the given special tokens
+	// when EOS is true, e.g. []string{"<|eot_id|>"} when concatenating chat
+	// documents. Unknown tokens are skipped.
+	SuffixSpecials []string
+	// Diag, if not nil, receives diagnostics of the call.
+	Diag *EncodeDiag
 }
 
 // defaultEncodeOptions returns the default encoding options.
@@ -154,18 +196,58 @@ func New(opts ...Option) (*Tokenizer, error) {
 		}
 	}
 
+	progress := config.loadProgress
+	if progress == nil {
+		progress = func(string, float64) {}
+	}
+
+	atomic, err := compilePatterns("atomic_patterns", config.atomicPatterns)
+	if err != nil {
+		return nil, err
+	}
+	optimistic, err := compilePatterns("optimistic_patterns", config.optimisticPatterns)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create tokenizer with configured cache size
 	t := &Tokenizer{
-		cacheSize: config.cacheSize,
+		cacheSize:       config.cacheSize,
+		observer:        config.observer,
+		observeMinBytes: config.observeMinBytes,
+		bytesPerToken:   config.bytesPerToken,
+		atomic:          atomic,
+		optimistic:      optimistic,
+		added:           &addedVocab{},
+
+		atomicPatterns:     config.atomicPatterns,
+		optimisticPatterns: config.optimisticPatterns,
+		mergeOverrides:     len(config.mergeOverrides),
 	}
 
 	// Initialize cache based on size
-	if t.cacheSize == 0 {
+	switch {
+	case config.cacheMinSize > 0:
+		t.cache = bpe.NewAdaptiveLRU(config.cacheMinSize, config.cacheSize)
+	case t.cacheSize == 0:
 		t.cache = bpe.NewSimple()
-	} else {
+	default:
 		t.cache = newLRUCache(t.cacheSize)
 	}
 
+	// Data from files and custom loaders is checked against the load limits
+	if config.loadLimits == nil {
+		defaults := DefaultLoadLimits()
+		config.loadLimits = &defaults
+	}
+	limits := vocabulary.Limits{
+		MaxFileSize:    config.loadLimits.MaxFileSize,
+		MaxTokens:      config.loadLimits.MaxTokens,
+		MaxTokenLength: config.loadLimits.MaxTokenLength,
+		MaxMerges:      config.loadLimits.MaxMerges,
+	}
+	customLoader := false
+
 	// Create data loader
 	var vocab VocabularyDataLoader
 	if config.dataLoader != nil {
@@ -174,94 +256,100 @@ func New(opts ...Option) (*Tokenizer, error) {
 			vocab = &fileVocabularySource{
 				vocabPath:  marker.vocabPath,
 				mergesPath: marker.mergesPath,
+				data:       marker.data,
+				limits:     limits,
 				t:          t,
 			}
+		} else if marker, ok := config.dataLoader.(sourceMarker); ok {
+			vocab = marker.source(limits, t)
 		} else {
 			vocab = config.dataLoader
+			customLoader = true
 		}
 	} else {
 		vocab = &embeddedVocabularySource{t: t}
 	}
 
 	// Load vocabulary
-	var err error
+	progress(LoadStageVocabulary, 0)
 	t.tokens, err = vocab.LoadVocabulary()
 	if err != nil {
 		return nil, err
 	}
+	if customLoader {
+		if err := limits.CheckTokens(t.tokens); err != nil {
+			return nil, NewDataError("load vocabulary", "", err)
+		}
+	}
 
 	// Add special tokens
+	t.regularLen = len(t.tokens)
 	specialTokens := config.specialTokens
+	if source, ok := vocab.(specialTokenSource); ok && specialTokens == nil {
+		specialTokens = source.loadSpecialTokens()
+	}
 	if specialTokens == nil {
 		specialTokens = getDefaultSpecialTokens()
 	}
 	t.tokens = append(t.tokens, specialTokens...)
 
 	// Build string-to-ID mapping
+	progress(LoadStageLookup, 10)
 	t.tokenLookup = make(map[string]int, len(t.tokens))
 	for id, token := range t.tokens {
 		t.tokenLookup[token] = id
 	}
+	t.resolveSpecialIDs()
 
-	// Load merges
-	t.mergeRules, err = vocab.LoadMerges()
-	if err != nil {
+	// Load merges and index them by token ID pair
+	progress(LoadStageMerges, 30)
+	if source, ok := vocab.(mergePairSource); ok {
+		t.merges, err = source.loadMergeTable()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		mergeRules, err := vocab.LoadMerges()
+		if err != nil {
+			return nil, err
+		}
+		if customLoader {
+			if err := limits.CheckMerges(len(mergeRules)); err != nil {
+				return nil, NewDataError("load merges", "", err)
+			}
+		}
+		t.merges = bpe.BuildMerges(mergeRules, t.tokenLookup)
+	}
+	if err := applyMergeOverrides(t.merges, t.tokenLookup, config.mergeOverrides); err != nil {
 		return nil, err
 	}
 
+	t.processor = &bpe.Processor{
+		TokenLookup:     t.tokenLookup,
+		Merges:          t.merges,
+		Cache:           t.cache,
+		SkipVocabLookup: len(config.mergeOverrides) > 0 || config.skipVocabLookup,
+	}
+
+	progress(LoadStageReady, 100)
 	return t, nil
 }
 
 // Encode converts text into a sequence of token IDs.
 // If opts is nil, default options will be used.
 func (t *Tokenizer) Encode(text string, opts *EncodeOptions) []int {
-	if opts == nil {
-		opts = defaultEncodeOptions()
-	}
-
-	output := make([]int, 0, len(text)/estimatedTokensPerCharacter)
-
-	// Add beginning-of-text token
-	if opts.BOS {
-		if id, err := t.GetSpecialTokenID(beginOfTextToken); err == nil {
-			output = append(output, id)
-		}
-	}
-
-	// Split by special tokens first
-	specialSplits := splitBySpecialTokens(text, specialTokenRegex)
-
-	for _, specialSplit := range specialSplits {
-		// Check if this is a special token
-		if specialTokenRegex.MatchString(specialSplit) && t.tokenLookup[specialSplit] != 0 {
-			output = append(output, t.tokenLookup[specialSplit])
-			continue
-		}
-
-		// Not a special token, process normally
-		// Pre-tokenize using regex
-		pretokens := t.pretokenize(specialSplit)
-
-		// Process each pretoken
-		for _, pretoken := range pretokens {
-			if pretoken == "" {
-				continue
-			}
-
-			// Perform BPE on the pretoken
-			tokenIDs := t.performBPE(pretoken)
-			output = append(output, tokenIDs...)
-		}
-	}
-
-	// Add end-of-text token
-	if opts.EOS {
-		if id, err := t.GetSpecialTokenID(endOfTextToken); err == nil {
-			output = append(output, id)
-		}
+	if t.observer != nil && len(text) >= t.observeMinBytes {
+		start := time.Now()
+		output := t.encode(text, opts)
+		t.observe(OpEncode, start, len(text), len(output))
+		return output
 	}
+	return t.encode(text, opts)
+}
 
-	return output
+// encode implements Encode.
+func (t *Tokenizer) encode(text string, opts *EncodeOptions) []int {
+	return t.appendTokens(nil, text, opts, nil)
 }
 
 // EncodeBytes converts bytes into a sequence of token IDs.
@@ -274,58 +362,114 @@ func (t *Tokenizer) EncodeBytes(data []byte, opts *EncodeOptions) []int {
 // dst can be nil, in which case a new slice is allocated.
 // The resulting slice is returned and may have a different backing array than dst.
 func (t *Tokenizer) AppendTokens(dst []int, text string, opts *EncodeOptions) []int {
+	if t.observer != nil && len(text) >= t.observeMinBytes {
+		start := time.Now()
+		n := len(dst)
+		dst = t.appendTokens(dst, text, opts, nil)
+		t.observe(OpEncode, start, len(text), len(dst)-n)
+		return dst
+	}
+	return t.appendTokens(dst, text, opts, nil)
+}
+
+// appendTokens implements Encode and AppendTokens. A non-nil session
+// supplies scratch buffers and a private cache for the text between special
+// tokens.
+func (t *Tokenizer) appendTokens(dst []int, text string, opts *EncodeOptions, session *EncodeSession) []int {
+	dst, _ = t.appendTokensContext(context.Background(), dst, text, opts, session)
+	return dst
+}
+
+// appendTokensContext is the encode loop behind Encode, AppendTokens,
+// EncodeContext and EncodeSession. It checks ctx every contextCheckTokens
+// pre-tokens if ctx can be canceled, and then returns ctx.Err() once it is
+// done.
+func (t *Tokenizer) appendTokensContext(ctx context.Context, dst []int, text string, opts *EncodeOptions, session *EncodeSession) ([]int, error) {
 	if opts == nil {
 		opts = defaultEncodeOptions()
 	}
+	done := ctx.Done() // nil if ctx can never be canceled
 
 	// Reserve capacity if dst is nil or too small
-	estimatedTokens := len(text)/estimatedTokensPerCharacter + 2 // +2 for BOS/EOS
+	estimatedTokens := t.estimateTokens(text)
 	if cap(dst) < len(dst)+estimatedTokens {
 		newDst := make([]int, len(dst), len(dst)+estimatedTokens)
 		copy(newDst, dst)
 		dst = newDst
 	}
 
+	if opts.Diag != nil {
+		*opts.Diag = EncodeDiag{}
+	}
+
 	// Add beginning-of-text token
-	if opts.BOS {
-		if id, err := t.GetSpecialTokenID(beginOfTextToken); err == nil {
-			dst = append(dst, id)
-		}
+	if id := t.specialIDs[BeginOfText]; opts.BOS && id >= 0 {
+		dst = append(dst, id)
 	}
 
 	// Split by special tokens first
-	specialSplits := splitBySpecialTokens(text, specialTokenRegex)
+	var segments []segment
+	var scratch *encodeScratch
+	if session != nil {
+		session.segments = appendSegments(session.segments[:0], text, t.hasSpecialToken)
+		segments = session.segments
+		scratch = &session.encodeScratch
+	} else {
+		var buf [segmentBufSize]segment
+		segments = appendSegments(buf[:0], text, t.hasSpecialToken)
+	}
 
-	for _, specialSplit := range specialSplits {
-		// Check if this is a special token
-		if specialTokenRegex.MatchString(specialSplit) && t.tokenLookup[specialSplit] != 0 {
-			dst = append(dst, t.tokenLookup[specialSplit])
+	n := 0 // pre-tokens since the last cancellation check
+	for p := range t.pieces(segments, t.specialTokenID, scratch) {
+		if p.ID >= 0 {
+			dst = append(dst, p.ID)
+			if opts.Diag != nil {
+				opts.Diag.SpecialTokens++
+			}
 			continue
 		}
 
-		// Not a special token, process normally
-		// Pre-tokenize using regex
-		pretokens := t.pretokenize(specialSplit)
-
-		// Process each pretoken
-		for _, pretoken := range pretokens {
-			if pretoken == "" {
-				continue
+		if done != nil {
+			if n++; n == contextCheckTokens {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				n = 0
 			}
+		}
 
-			// Perform BPE on the pretoken
-			tokenIDs := t.performBPE(pretoken)
-			dst = append(dst, tokenIDs...)
+		// Perform BPE on the pretoken
+		if session != nil && opts.Diag == nil {
+			dst = session.appendBPE(dst, p.Pretoken)
+			continue
 		}
+		dst = t.appendBPE(dst, string(p.Pretoken), opts.Diag)
 	}
 
-	// Add end-of-text token
-	if opts.EOS {
-		if id, err := t.GetSpecialTokenID(endOfTextToken); err == nil {
+	// Add end-of-text token(s)
+	dst = t.appendEOS(dst, opts)
+
+	return dst, nil
+}
+
+// appendEOS appends the end-of-sequence tokens selected by opts to dst.
+func (t *Tokenizer) appendEOS(dst []int, opts *EncodeOptions) []int {
+	if !opts.EOS {
+		return dst
+	}
+
+	if len(opts.SuffixSpecials) == 0 {
+		if id := t.specialIDs[EndOfText]; id >= 0 {
 			dst = append(dst, id)
 		}
+		return dst
 	}
 
+	for _, token := range opts.SuffixSpecials {
+		if id, err := t.GetSpecialTokenID(token); err == nil {
+			dst = append(dst, id)
+		}
+	}
 	return dst
 }
 
@@ -337,20 +481,21 @@ func (t *Tokenizer) Decode(tokenIDs []int) string {
 // DecodeBytes converts a sequence of token IDs back to UTF-8 bytes.
 // This avoids string allocation and is useful for performance-critical paths.
 func (t *Tokenizer) DecodeBytes(tokenIDs []int) []byte {
-	utf8Bytes := make([]byte, 0, len(tokenIDs)*bytesPerMerge)
-
-	for _, tokenID := range tokenIDs {
-		if tokenID < 0 || tokenID >= len(t.tokens) {
-			continue // Skip invalid token IDs
-		}
+	if t.observer == nil {
+		return t.decodeBytes(tokenIDs)
+	}
 
-		tokenString := t.tokens[tokenID]
-		// Convert from custom byte representation back to UTF-8
-		decodedBytes := decodeTokenBytes(tokenString)
-		utf8Bytes = append(utf8Bytes, decodedBytes...)
+	start := time.Now()
+	out := t.decodeBytes(tokenIDs)
+	if len(out) >= t.observeMinBytes {
+		t.observe(OpDecode, start, len(out), len(tokenIDs))
 	}
+	return out
+}
 
-	return utf8Bytes
+// decodeBytes implements DecodeBytes.
+func (t *Tokenizer) decodeBytes(tokenIDs []int) []byte {
+	return t.appendDecode(make([]byte, 0, len(tokenIDs)*bytesPerMerge), tokenIDs)
 }
 
 // GetSpecialTokenID returns the token ID for a special token string.
@@ -359,7 +504,7 @@ func (t *Tokenizer) GetSpecialTokenID(token string) (int, error) {
 		return 0, NewTokenError("validate special token", token, ErrInvalidToken)
 	}
 
-	id, ok := t.tokenLookup[token]
+	id, ok := t.specialTokenID(token)
 	if !ok {
 		return 0, NewTokenError("get special token ID", token, ErrTokenNotFound)
 	}
@@ -367,49 +512,58 @@ func (t *Tokenizer) GetSpecialTokenID(token string) (int, error) {
 	return id, nil
 }
 
+// BOSTokenID returns the ID of the beginning-of-text token,
+// <|begin_of_text|>, or -1 if the tokenizer's special tokens do not include
+// it.
+func (t *Tokenizer) BOSTokenID() int {
+	return t.specialIDs[BeginOfText]
+}
+
+// EOSTokenID returns the ID of the end-of-text token, <|end_of_text|>, or -1
+// if the tokenizer's special tokens do not include it.
+func (t *Tokenizer) EOSTokenID() int {
+	return t.specialIDs[EndOfText]
+}
+
+// EOTTokenID returns the ID of the end-of-turn token of chat models,
+// <|eot_id|>, or -1 if the tokenizer's special tokens do not include it.
+func (t *Tokenizer) EOTTokenID() int {
+	return t.specialIDs[EOT]
+}
+
 // OptimisticCount returns the token count assuming anything that looks like
 // a special token is actually a special token. This is useful for fine-tuned
-// models with modified special tokens.
+// models with modified special tokens. By default, anything matching
+// DefaultOptimisticPattern looks like a special token; WithOptimisticPatterns
+// sets other patterns.
 func (t *Tokenizer) OptimisticCount(text string) int {
-	// Use optimistic regex that matches any <|...|> pattern
-	output := make([]int, 0, len(text)/estimatedTokensPerCharacter)
+	output := make([]int, 0, t.estimateTokens(text))
 
 	// Always add BOS and EOS for optimistic count
-	if id, err := t.GetSpecialTokenID(beginOfTextToken); err == nil {
+	if id := t.specialIDs[BeginOfText]; id >= 0 {
 		output = append(output, id)
 	}
 
-	// Split by optimistic special token regex
-	specialSplits := splitBySpecialTokens(text, optimisticSpecialTokenRegex)
-
-	for _, specialSplit := range specialSplits {
-		// Check if this looks like a special token
-		if optimisticSpecialTokenRegex.MatchString(specialSplit) {
-			// For optimistic count, we count it as 1 token even if not in vocab
-			if id, ok := t.tokenLookup[specialSplit]; ok {
-				output = append(output, id)
-			} else {
-				// Use a fallback token ID (just use 1 for counting)
-				output = append(output, 1)
-			}
-			continue
+	// Split at the pseudo-special tokens, counting each as 1 token even if
+	// it is not in the vocabulary (using 1 as a fallback ID)
+	var buf [segmentBufSize]segment
+	segments := t.appendOptimisticSegments(buf[:0], text)
+	special := func(s string) (int, bool) {
+		if id, ok := t.specialTokenID(s); ok {
+			return id, true
 		}
-
-		// Not a special token, process normally
-		pretokens := t.pretokenize(specialSplit)
-
-		for _, pretoken := range pretokens {
-			if pretoken == "" {
-				continue
-			}
-
-			tokenIDs := t.performBPE(pretoken)
-			output = append(output, tokenIDs...)
+		return 1, true
+	}
+	for p := range t.pieces(segments, special, nil) {
+		if p.ID >= 0 {
+			output = append(output, p.ID)
+			continue
 		}
+		output = append(output, t.performBPE(string(p.Pretoken))...)
 	}
 
 	// Add EOS
-	if id, err := t.GetSpecialTokenID(endOfTextToken); err == nil {
+	if id := t.specialIDs[EndOfText]; id >= 0 {
 		output = append(output, id)
 	}
 
@@ -418,7 +572,7 @@ func (t *Tokenizer) OptimisticCount(text string) int {
 
 // VocabSize returns the size of the vocabulary including special tokens.
 func (t *Tokenizer) VocabSize() int {
-	return len(t.tokens)
+	return len(t.tokens) + t.numAdded()
 }
 
 // getMergeIdentifier creates a merge identifier string from two token IDs.
@@ -463,15 +617,19 @@ type bpeCache = Cache
 // It iteratively merges the most frequent pairs of adjacent tokens according
 // to the learned merge rules. Results are cached for efficiency.
 func (t *Tokenizer) performBPE(pretoken string) []int {
-	// Create a BPE processor with the tokenizer's data
-	processor := &bpe.Processor{
-		Tokens:      t.tokens,
-		TokenLookup: t.tokenLookup,
-		MergeRules:  t.mergeRules,
-		Cache:       t.cache,
-	}
+	return t.processor.PerformBPE(pretoken)
+}
 
-	return processor.PerformBPE(pretoken)
+// Processor returns a BPE processor over the tokenizer's vocabulary, merges
+// and cache. It is the engine used by Encode, and can be used to apply the
+// Llama 3 merges to pre-tokens produced by PreTokenize.
+//
+// The returned processor is a copy, so setting its fields, such as Cache,
+// does not change the tokenizer. Its token lookup and merge tables are shared
+// with the tokenizer and must not be modified.
+func (t *Tokenizer) Processor() *bpe.Processor {
+	processor := *t.processor
+	return &processor
 }
 
 // EncodeBPE implements the BPE interface.
@@ -489,7 +647,12 @@ func newLRUCache(capacity int) Cache {
 // and byte-level encoding.
 func (t *Tokenizer) pretokenize(text string) []string {
 	// Use pooled state machine for better performance
-	parts := pretokenizer.Tokenize(text)
+	var parts []string
+	if t.atomic == nil {
+		parts = pretokenizer.Tokenize(text)
+	} else {
+		parts = t.appendPretokens(nil, text)
+	}
 
 	// Apply byte-level encoding to each part
 	encoded := make([]string, len(parts))
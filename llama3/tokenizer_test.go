@@ -266,6 +266,54 @@ func TestTokenizerSpecialTokens(t *testing.T) {
 	}
 }
 
+func TestSpecialTokenIDAccessors(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	tests := []struct {
+		name  string
+		got   int
+		token string
+	}{
+		{"bos", tokenizer.BOSTokenID(), "<|begin_of_text|>"},
+		{"eos", tokenizer.EOSTokenID(), "<|end_of_text|>"},
+		{"eot", tokenizer.EOTTokenID(), "<|eot_id|>"},
+	}
+	for _, tt := range tests {
+		want, err := tokenizer.GetSpecialTokenID(tt.token)
+		if err != nil {
+			t.Fatalf("GetSpecialTokenID(%q) error = %v", tt.token, err)
+		}
+		if tt.got != want {
+			t.Errorf("%s ID = %d, want %d", tt.name, tt.got, want)
+		}
+	}
+
+	t.Run("custom_special_tokens", func(t *testing.T) {
+		custom, err := New(WithSpecialTokens([]string{"<|eot_id|>", "<|begin_of_text|>"}))
+		if err != nil {
+			t.Fatalf("Failed to create tokenizer: %v", err)
+		}
+		if got, want := custom.EOTTokenID(), baseVocabSize; got != want {
+			t.Errorf("EOTTokenID() = %d, want %d", got, want)
+		}
+		if got, want := custom.BOSTokenID(), baseVocabSize+1; got != want {
+			t.Errorf("BOSTokenID() = %d, want %d", got, want)
+		}
+		if got := custom.EOSTokenID(); got != -1 {
+			t.Errorf("EOSTokenID() = %d, want -1", got)
+		}
+
+		// Without <|end_of_text|>, Encode adds BOS only
+		tokens := custom.Encode("Hi", nil)
+		if !equalIntSlices(tokens, append([]int{baseVocabSize + 1}, custom.Encode("Hi", noSpecialTokens)...)) {
+			t.Errorf("Encode() = %v, want BOS followed by the text", tokens)
+		}
+	})
+}
+
 func TestTokenizerProperties(t *testing.T) {
 	tokenizer, err := New()
 	if err != nil || tokenizer.VocabSize() == 0 {
@@ -405,6 +453,52 @@ func TestAppendTokensMethod(t *testing.T) {
 	})
 }
 
+// TestSuffixSpecials tests replacing the EOS token with custom special tokens.
+func TestSuffixSpecials(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	eot, _ := tokenizer.GetSpecialTokenID("<|eot_id|>")
+	eos, _ := tokenizer.GetSpecialTokenID("<|end_of_text|>")
+	content := tokenizer.Encode("Hello", &EncodeOptions{BOS: false, EOS: false})
+
+	tests := []struct {
+		name   string
+		opts   *EncodeOptions
+		suffix []int
+	}{
+		{"default_eos", &EncodeOptions{EOS: true}, []int{eos}},
+		{"eot_instead_of_eos", &EncodeOptions{EOS: true, SuffixSpecials: []string{"<|eot_id|>"}}, []int{eot}},
+		{"multiple_tokens", &EncodeOptions{EOS: true, SuffixSpecials: []string{"<|eot_id|>", "<|end_of_text|>"}}, []int{eot, eos}},
+		{"unknown_skipped", &EncodeOptions{EOS: true, SuffixSpecials: []string{"<|missing|>", "<|eot_id|>"}}, []int{eot}},
+		{"eos_disabled", &EncodeOptions{EOS: false, SuffixSpecials: []string{"<|eot_id|>"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := append(append([]int{}, content...), tt.suffix...)
+
+			if got := tokenizer.Encode("Hello", tt.opts); !reflect.DeepEqual(got, want) {
+				t.Errorf("Encode() = %v, want %v", got, want)
+			}
+			if got := tokenizer.AppendTokens(nil, "Hello", tt.opts); !reflect.DeepEqual(got, want) {
+				t.Errorf("AppendTokens() = %v, want %v", got, want)
+			}
+
+			scanner := tokenizer.NewScanner(strings.NewReader("Hello"), WithEncodeOptions(tt.opts))
+			var scanned []int
+			for scanner.Scan() {
+				scanned = append(scanned, scanner.Token())
+			}
+			if !reflect.DeepEqual(scanned, want) {
+				t.Errorf("Scanner = %v, want %v", scanned, want)
+			}
+		})
+	}
+}
+
 // TestOptimisticCount tests the OptimisticCount method.
 func TestOptimisticCount(t *testing.T) {
 	tokenizer, err := New()
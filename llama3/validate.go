@@ -0,0 +1,83 @@
+package llama3
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"github.com/agentstation/tokenizer/llama3/internal/encoding"
+)
+
+// Errors of ValidateTokens.
+var (
+	// ErrInvalidUTF8 indicates tokens that do not decode to valid UTF-8: a
+	// byte-level token continuing no character, or a character left
+	// incomplete by a special token or the end of the sequence.
+	ErrInvalidUTF8 = errors.New("tokens decode to invalid UTF-8")
+
+	// ErrMisplacedSpecialToken indicates a special token where a sequence
+	// cannot hold it, such as <|begin_of_text|> after the first position.
+	ErrMisplacedSpecialToken = errors.New("special token in invalid position")
+)
+
+// ValidateTokens checks that tokens form a sequence the tokenizer could
+// have produced, to sanity-check token data from external systems before
+// training on it. It returns a *TokenError for the first problem found,
+// wrapping:
+//
+//   - ErrInvalidTokenID for an ID outside the vocabulary;
+//   - ErrInvalidUTF8 for tokens that decode to invalid UTF-8, at the token
+//     starting the bad bytes: an orphan continuation byte, or a character
+//     cut short by a special token or the end of the sequence;
+//   - ErrMisplacedSpecialToken for <|begin_of_text|> after the first
+//     position or <|end_of_text|> before the last.
+//
+// Sequences of several documents, packed with <|end_of_text|> and
+// <|begin_of_text|> between them, should be validated one document at a
+// time.
+func (t *Tokenizer) ValidateTokens(tokens []int) error {
+	const op = "validate tokens"
+	bos, eos := t.specialIDs[BeginOfText], t.specialIDs[EndOfText]
+
+	var pending []byte // bytes of an incomplete character
+	pendingPos := 0    // position of the token starting pending
+	var buf []byte
+	for i, id := range tokens {
+		if id < 0 || id >= t.VocabSize() {
+			return NewTokenPositionError(op, id, i, ErrInvalidTokenID)
+		}
+		if t.IsSpecialID(id) {
+			if len(pending) > 0 {
+				return NewTokenPositionError(op, tokens[pendingPos], pendingPos, ErrInvalidUTF8)
+			}
+			if (id == bos && i > 0) || (id == eos && i < len(tokens)-1) {
+				return NewTokenPositionError(op, id, i, ErrMisplacedSpecialToken)
+			}
+			continue
+		}
+
+		// Validate the token's bytes after any incomplete character
+		held := len(pending)
+		buf = encoding.AppendTokenBytes(append(buf[:0], pending...), t.tokens[id])
+		pending = pending[:0]
+		for j := 0; j < len(buf); {
+			r, size := utf8.DecodeRune(buf[j:])
+			if r == utf8.RuneError && size <= 1 {
+				start := i
+				if j < held {
+					start = pendingPos
+				}
+				if !utf8.FullRune(buf[j:]) {
+					pending = append(pending, buf[j:]...) // completed by the next token
+					pendingPos = start
+					break
+				}
+				return NewTokenPositionError(op, tokens[start], start, ErrInvalidUTF8)
+			}
+			j += size
+		}
+	}
+	if len(pending) > 0 {
+		return NewTokenPositionError(op, tokens[pendingPos], pendingPos, ErrInvalidUTF8)
+	}
+	return nil
+}
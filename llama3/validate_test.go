@@ -0,0 +1,60 @@
+package llama3
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateTokens(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil || tokenizer.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+
+	// A character the vocabulary splits across byte-level tokens
+	char := tokenizer.Encode("𒀀", noSpecialTokens)
+	if len(char) < 2 {
+		t.Fatalf("Expected 𒀀 to take several tokens, got %v", char)
+	}
+	hi := tokenizer.Encode("Hi", noSpecialTokens)
+	bos, eos, eot := tokenizer.BOSTokenID(), tokenizer.EOSTokenID(), tokenizer.EOTTokenID()
+	seq := func(parts ...[]int) []int {
+		var tokens []int
+		for _, p := range parts {
+			tokens = append(tokens, p...)
+		}
+		return tokens
+	}
+
+	tests := []struct {
+		name     string
+		tokens   []int
+		err      error
+		position int
+	}{
+		{"valid", tokenizer.Encode("Hello, 世界 🎉 𒀀!<|eot_id|>", nil), nil, 0},
+		{"empty", nil, nil, 0},
+		{"out of range", seq(hi, []int{tokenizer.VocabSize()}), ErrInvalidTokenID, len(hi)},
+		{"negative", []int{-1}, ErrInvalidTokenID, 0},
+		{"orphan continuation", seq(hi, char[1:]), ErrInvalidUTF8, len(hi)},
+		{"cut by the end", seq(hi, char[:1]), ErrInvalidUTF8, len(hi)},
+		{"cut by a special token", seq(char[:1], []int{eot}, char[1:]), ErrInvalidUTF8, 0},
+		{"BOS mid-stream", seq(hi, []int{bos}, hi), ErrMisplacedSpecialToken, len(hi)},
+		{"EOS before the end", seq([]int{bos}, hi, []int{eos}, hi), ErrMisplacedSpecialToken, 1 + len(hi)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tokenizer.ValidateTokens(tt.tokens)
+			if tt.err == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			var tokenErr *TokenError
+			if !errors.Is(err, tt.err) || !errors.As(err, &tokenErr) || tokenErr.Position != tt.position {
+				t.Errorf("Expected %v at position %d, got %v", tt.err, tt.position, err)
+			}
+		})
+	}
+}
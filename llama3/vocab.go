@@ -3,6 +3,9 @@
 package llama3
 
 import (
+	"fmt"
+
+	"github.com/agentstation/tokenizer/bpe"
 	"github.com/agentstation/tokenizer/llama3/internal/vocabulary"
 )
 
@@ -40,6 +43,55 @@ func (d VocabularyDataLoaderFunc) LoadMerges() (map[string]int, error) {
 
 // Internal data loader implementations
 
+// mergePairSource is implemented by the built-in vocabulary sources, which can
+// provide merges directly as token ID pairs. This skips building and parsing
+// the string-keyed merge map returned by LoadMerges.
+type mergePairSource interface {
+	loadMergeTable() (map[uint64]bpe.Merge, error)
+}
+
+// specialTokenSource is implemented by vocabulary sources that define their
+// own special tokens.
+type specialTokenSource interface {
+	loadSpecialTokens() []string
+}
+
+// sourceMarker is implemented by the placeholders of data loaders that read
+// a model file, which New replaces with the source they return once the load
+// limits are known.
+type sourceMarker interface {
+	source(limits vocabulary.Limits, t *Tokenizer) VocabularyDataLoader
+}
+
+// parsedVocabularySource holds the data of a model file, such as a
+// tokenizer.json or GGUF file, parsed by the LoadVocabulary method of the
+// source embedding it.
+type parsedVocabularySource struct {
+	path   string
+	limits vocabulary.Limits
+	t      *Tokenizer
+	data   *vocabulary.HFTokenizer
+}
+
+func (p *parsedVocabularySource) LoadMerges() (map[string]int, error) {
+	merges := make(map[string]int, len(p.data.Merges)/2)
+	for i := 0; i+1 < len(p.data.Merges); i += 2 {
+		merges[p.t.getMergeIdentifier(p.data.Merges[i], p.data.Merges[i+1])] = i/2 + 1
+	}
+	return merges, nil
+}
+
+func (p *parsedVocabularySource) loadMergeTable() (map[uint64]bpe.Merge, error) {
+	return bpe.BuildMergesFromPairs(p.data.Merges, p.t.tokens, p.t.tokenLookup), nil
+}
+
+func (p *parsedVocabularySource) loadSpecialTokens() []string {
+	if len(p.data.Special) == 0 {
+		return nil
+	}
+	return p.data.Special
+}
+
 // embeddedDataLoader loads data from embedded resources.
 // embeddedVocabularySource loads vocabulary data from embedded resources.
 // This is the default source that uses the pre-packaged Llama3 vocabulary.
@@ -48,6 +100,10 @@ type embeddedVocabularySource struct {
 }
 
 func (d *embeddedVocabularySource) LoadVocabulary() ([]string, error) {
+	if vocabulary.EmbeddedVocabulary == "" {
+		// tokenizer_tiny builds leave the embedded data out
+		return nil, NewDataError("load vocabulary", "", fmt.Errorf("%w: no embedded vocabulary in this build, use WithDataFiles", ErrDataNotFound))
+	}
 	loader := vocabulary.NewDefaultLoader()
 	vocab, err := loader.LoadVocabulary()
 	if err != nil {
@@ -71,16 +127,52 @@ func (d *embeddedVocabularySource) LoadMerges() (map[string]int, error) {
 	return merges, nil
 }
 
+func (d *embeddedVocabularySource) loadMergeTable() (map[uint64]bpe.Merge, error) {
+	loader := vocabulary.NewDefaultLoader()
+	mergesData, err := loader.LoadMergesData()
+	if err != nil {
+		return nil, NewDataError("load merges data", "", err)
+	}
+
+	pairs, err := vocabulary.DecompressMergePairs(mergesData)
+	if err != nil {
+		return nil, NewDataError("decompress merges", "", err)
+	}
+
+	return bpe.BuildMergesFromPairs(pairs, d.t.tokens, d.t.tokenLookup), nil
+}
+
 // fileVocabularySource loads vocabulary data from external files.
 // This allows using custom vocabularies instead of the embedded defaults.
 type fileVocabularySource struct {
 	vocabPath  string
 	mergesPath string
+	data       *vocabulary.MemoryLoader // Data of WithData, read instead of files
+	limits     vocabulary.Limits
 	t          *Tokenizer
 }
 
-func (f *fileVocabularySource) LoadVocabulary() ([]string, error) {
+// dataFileLoader loads data in the format of the vocabulary and merges files.
+type dataFileLoader interface {
+	LoadVocabulary() ([]string, error)
+	LoadMergesData() (string, error)
+}
+
+// loader returns a loader for the files or in-memory data that enforces the
+// source's limits.
+func (f *fileVocabularySource) loader() dataFileLoader {
+	if f.data != nil {
+		loader := *f.data
+		loader.Limits = f.limits
+		return &loader
+	}
 	loader := vocabulary.NewFileLoader(f.vocabPath, f.mergesPath)
+	loader.Limits = f.limits
+	return loader
+}
+
+func (f *fileVocabularySource) LoadVocabulary() ([]string, error) {
+	loader := f.loader()
 	vocab, err := loader.LoadVocabulary()
 	if err != nil {
 		return nil, NewDataError("load vocabulary", f.vocabPath, err)
@@ -89,7 +181,7 @@ func (f *fileVocabularySource) LoadVocabulary() ([]string, error) {
 }
 
 func (f *fileVocabularySource) LoadMerges() (map[string]int, error) {
-	loader := vocabulary.NewFileLoader(f.vocabPath, f.mergesPath)
+	loader := f.loader()
 	mergesData, err := loader.LoadMergesData()
 	if err != nil {
 		return nil, NewDataError("load merges", f.mergesPath, err)
@@ -103,10 +195,26 @@ func (f *fileVocabularySource) LoadMerges() (map[string]int, error) {
 	return merges, nil
 }
 
+func (f *fileVocabularySource) loadMergeTable() (map[uint64]bpe.Merge, error) {
+	loader := f.loader()
+	mergesData, err := loader.LoadMergesData()
+	if err != nil {
+		return nil, NewDataError("load merges", f.mergesPath, err)
+	}
+
+	pairs, err := vocabulary.DecompressMergePairs(mergesData)
+	if err != nil {
+		return nil, NewDataError("decompress merges", f.mergesPath, err)
+	}
+
+	return bpe.BuildMergesFromPairs(pairs, f.t.tokens, f.t.tokenLookup), nil
+}
+
 // fileLoaderMarker is a placeholder that will be replaced with the actual file loader.
 type fileLoaderMarker struct {
 	vocabPath  string
 	mergesPath string
+	data       *vocabulary.MemoryLoader
 }
 
 func (f *fileLoaderMarker) LoadVocabulary() ([]string, error) {
@@ -0,0 +1,128 @@
+package llama3
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// Vocab provides read-only access to a tokenizer's vocabulary.
+type Vocab struct {
+	t *Tokenizer
+}
+
+// Vocab returns a read-only view of the tokenizer's vocabulary.
+func (t *Tokenizer) Vocab() *Vocab {
+	return &Vocab{t: t}
+}
+
+// Len returns the number of tokens in the vocabulary, including special tokens.
+func (v *Vocab) Len() int {
+	return len(v.t.tokens)
+}
+
+// IsSpecial reports whether id is a special token, such as
+// <|begin_of_text|>, rather than a token of text.
+func (v *Vocab) IsSpecial(id int) bool {
+	return v.t.IsSpecialID(id)
+}
+
+// TokenMatch is a vocabulary entry returned by Nearest.
+type TokenMatch struct {
+	ID       int     // Token ID
+	Text     string  // Decoded token text
+	Distance float64 // Normalized edit distance in [0, 1]; 0 is an exact match
+}
+
+// Nearest returns the k tokens whose decoded text is closest to s, ordered by
+// increasing normalized edit distance (ties broken by token ID).
+//
+// The distance is the Levenshtein distance over runes divided by the length of
+// the longer string. Matching is case-sensitive and leading spaces are part of
+// the token text, so " hello" and "hello" are distinct but close matches.
+func (v *Vocab) Nearest(s string, k int) []TokenMatch {
+	if k <= 0 {
+		return []TokenMatch{}
+	}
+
+	query := []rune(s)
+	best := make([]TokenMatch, 0, k+1)
+	worst := 1.0
+	scratch := make([]int, 0, 64)
+
+	for id, token := range v.t.tokens {
+		text := string(decodeTokenBytes(token))
+		textLen := utf8.RuneCountInString(text)
+
+		// The length difference is a lower bound on the edit distance
+		longest := max(len(query), textLen)
+		if longest == 0 {
+			continue
+		}
+		diff := len(query) - textLen
+		if diff < 0 {
+			diff = -diff
+		}
+		if len(best) == k && float64(diff)/float64(longest) > worst {
+			continue
+		}
+
+		var dist int
+		dist, scratch = levenshtein(query, []rune(text), scratch)
+		score := float64(dist) / float64(longest)
+		if len(best) == k && score >= worst {
+			continue
+		}
+
+		best = insertMatch(best, TokenMatch{ID: id, Text: text, Distance: score})
+		if len(best) > k {
+			best = best[:k]
+		}
+		if len(best) == k {
+			worst = best[k-1].Distance
+		}
+	}
+
+	return best
+}
+
+// insertMatch inserts m into matches, keeping them sorted by distance then ID.
+func insertMatch(matches []TokenMatch, m TokenMatch) []TokenMatch {
+	i := sort.Search(len(matches), func(i int) bool {
+		if matches[i].Distance != m.Distance {
+			return matches[i].Distance > m.Distance
+		}
+		return matches[i].ID > m.ID
+	})
+	matches = append(matches, TokenMatch{})
+	copy(matches[i+1:], matches[i:])
+	matches[i] = m
+	return matches
+}
+
+// levenshtein computes the edit distance between a and b using a single
+// reusable row. It returns the distance and the (possibly grown) scratch row.
+func levenshtein(a, b []rune, row []int) (int, []int) {
+	if cap(row) < len(b)+1 {
+		row = make([]int, len(b)+1)
+	}
+	row = row[:len(b)+1]
+	for j := range row {
+		row[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur := min(row[j]+1, row[j-1]+1, prev+cost)
+			prev = row[j]
+			row[j] = cur
+		}
+	}
+
+	return row[len(b)], row
+}
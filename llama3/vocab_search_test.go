@@ -0,0 +1,72 @@
+package llama3
+
+import (
+	"testing"
+)
+
+func TestVocabNearest(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	vocab := tokenizer.Vocab()
+
+	if vocab.Len() != tokenizer.VocabSize() {
+		t.Errorf("Vocab.Len() = %d, want %d", vocab.Len(), tokenizer.VocabSize())
+	}
+
+	t.Run("exact_match_first", func(t *testing.T) {
+		matches := vocab.Nearest(" grabbed", 5)
+		if len(matches) != 5 {
+			t.Fatalf("Expected 5 matches, got %d", len(matches))
+		}
+		if matches[0].ID != 30418 || matches[0].Distance != 0 {
+			t.Errorf("Expected exact match 30418 first, got %+v", matches[0])
+		}
+		for i := 1; i < len(matches); i++ {
+			if matches[i].Distance < matches[i-1].Distance {
+				t.Errorf("Matches not sorted by distance: %+v", matches)
+			}
+		}
+	})
+
+	t.Run("close_match", func(t *testing.T) {
+		matches := vocab.Nearest("grabed", 3)
+		found := false
+		for _, m := range matches {
+			if m.Text == "grabbed" || m.Text == " grabbed" || m.Text == "grab" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected a grab* token among %+v", matches)
+		}
+	})
+
+	t.Run("non_positive_k", func(t *testing.T) {
+		if matches := vocab.Nearest("hello", 0); len(matches) != 0 {
+			t.Errorf("Expected no matches, got %d", len(matches))
+		}
+	})
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"世界", "世间", 1},
+	}
+
+	for _, tt := range tests {
+		got, _ := levenshtein([]rune(tt.a), []rune(tt.b), nil)
+		if got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package llama3
+
+import "context"
+
+// warmupText is encoded by Warmup to populate the BPE cache with common
+// pre-tokens and to exercise the pre-tokenizer pools.
+var warmupText = []string{
+	"The quick brown fox jumps over the lazy dog. It's a test, isn't it?",
+	"Hello, world! How are you doing today? I'm fine, thanks for asking.",
+	"This is a sentence with some of the most common words in English: the, of, and, to, in, is, you, that, it, he, was, for, on, are, as, with, his, they, at, be, this, have, from, or, one, had, by, but, not, what, all, were, we, when, your, can, said, there, use, an, each, which, she, do, how, their, if, will, up, other, about, out, many, then, them, these, so, some, her, would, make, like, him, into, time, has, look, two, more, write, go, see, number, no, way, could, people, my, than, first, water, been, call, who, now, find, long, down, day, did, get, come, made, may, part.",
+	"func main() {\n\tfmt.Println(\"hello\")\n\tfor i := 0; i < 10; i++ {\n\t\treturn nil\n\t}\n}\n",
+	"def main():\n    import os\n    print(\"hello\")\n    return None\n",
+	"{\"id\": 123, \"name\": \"example\", \"values\": [1, 2, 3], \"enabled\": true}",
+	"0123456789 1000 2024 3.14 100% $5.00 #1 @user https://example.com/path?q=1",
+	"  \t\n\n   \r\n",
+}
+
+// Warmup pre-populates the BPE cache with every byte-level token and with the
+// pre-tokens of a small sample of common text, so the first requests served
+// after startup do not pay for cold caches.
+//
+// Warmup is safe to call concurrently with encoding. It blocks until done and
+// is typically run in the background:
+//
+//	go func() { _ = tokenizer.Warmup(ctx) }()
+//
+// It returns ctx.Err() if the context is canceled before warmup finishes.
+func (t *Tokenizer) Warmup(ctx context.Context) error {
+	// Byte-level pre-tokens
+	var b [1]byte
+	for i := 0; i < 256; i++ {
+		if i%64 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		b[0] = byte(i)
+		t.performBPE(encodeBytes(b[:]))
+	}
+
+	// Common pre-tokens
+	for _, text := range warmupText {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for _, pretoken := range t.pretokenize(text) {
+			t.performBPE(pretoken)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package llama3
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithLoadProgress(t *testing.T) {
+	type event struct {
+		stage string
+		pct   float64
+	}
+	var events []event
+
+	_, err := New(WithLoadProgress(func(stage string, pct float64) {
+		events = append(events, event{stage, pct})
+	}))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	want := []string{LoadStageVocabulary, LoadStageLookup, LoadStageMerges, LoadStageReady}
+	if len(events) != len(want) {
+		t.Fatalf("Expected %d progress events, got %v", len(want), events)
+	}
+	for i, e := range events {
+		if e.stage != want[i] {
+			t.Errorf("Event %d: expected stage %q, got %q", i, want[i], e.stage)
+		}
+		if i > 0 && e.pct <= events[i-1].pct {
+			t.Errorf("Event %d: progress %v did not increase from %v", i, e.pct, events[i-1].pct)
+		}
+	}
+	if last := events[len(events)-1].pct; last != 100 {
+		t.Errorf("Expected final progress 100, got %v", last)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	if err := tokenizer.Warmup(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, text := range []string{" the", "!", " quick"} {
+		pretoken := tokenizer.pretokenize(text)[0]
+		if _, ok := tokenizer.cache.Get(pretoken); !ok {
+			t.Errorf("Expected %q to be cached after warmup", text)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := tokenizer.Warmup(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package llama3
+
+import (
+	"math"
+	"math/rand"
+)
+
+// NoWord is the word ID assigned to tokens that do not belong to a word,
+// such as BOS/EOS and special tokens.
+const NoWord = -1
+
+// EncodeWords encodes text like Encode and additionally returns, for each
+// token, the index of the word it belongs to. Words are pre-tokens, so a
+// word split into several subword tokens shares a single word ID.
+// Special tokens (including BOS/EOS) are assigned NoWord.
+func (t *Tokenizer) EncodeWords(text string, opts *EncodeOptions) (tokens []int, wordIDs []int) {
+	if opts == nil {
+		opts = defaultEncodeOptions()
+	}
+
+	capacity := t.estimateTokens(text)
+	tokens = make([]int, 0, capacity)
+	wordIDs = make([]int, 0, capacity)
+
+	if id := t.specialIDs[BeginOfText]; opts.BOS && id >= 0 {
+		tokens = append(tokens, id)
+		wordIDs = append(wordIDs, NoWord)
+	}
+
+	word := 0
+	var buf [segmentBufSize]segment
+	segments := appendSegments(buf[:0], text, t.hasSpecialToken)
+	for p := range t.pieces(segments, t.specialTokenID, nil) {
+		if p.ID >= 0 {
+			tokens = append(tokens, p.ID)
+			wordIDs = append(wordIDs, NoWord)
+			continue
+		}
+
+		for _, id := range t.performBPE(string(p.Pretoken)) {
+			tokens = append(tokens, id)
+			wordIDs = append(wordIDs, word)
+		}
+		word++
+	}
+
+	n := len(tokens)
+	tokens = t.appendEOS(tokens, opts)
+	for ; n < len(tokens); n++ {
+		wordIDs = append(wordIDs, NoWord)
+	}
+
+	return tokens, wordIDs
+}
+
+// WholeWordMask selects a ratio of the words in wordIDs at random and returns
+// a mask that is true for every token of each selected word, as used for
+// whole-word masking in masked language model training.
+//
+// wordIDs is typically the second result of EncodeWords. Tokens with NoWord
+// are never masked. At least one word is selected when ratio > 0 and any
+// words are present. Pass a seeded rng for reproducible masks.
+func WholeWordMask(wordIDs []int, ratio float64, rng *rand.Rand) []bool {
+	mask := make([]bool, len(wordIDs))
+
+	// Collect the distinct words in order of appearance
+	var words []int
+	last := NoWord
+	for _, w := range wordIDs {
+		if w != NoWord && w != last {
+			words = append(words, w)
+		}
+		if w != NoWord {
+			last = w
+		}
+	}
+	if len(words) == 0 || ratio <= 0 {
+		return mask
+	}
+
+	n := int(math.Ceil(ratio * float64(len(words))))
+	if n > len(words) {
+		n = len(words)
+	}
+
+	selected := make(map[int]bool, n)
+	for _, i := range rng.Perm(len(words))[:n] {
+		selected[words[i]] = true
+	}
+
+	for i, w := range wordIDs {
+		mask[i] = w != NoWord && selected[w]
+	}
+	return mask
+}
@@ -0,0 +1,101 @@
+package llama3
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEncodeWords(t *testing.T) {
+	tokenizer, err := New()
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	text := "The tokenizer grabbed<|eot_id|> 🦙 llamas"
+	tokens, wordIDs := tokenizer.EncodeWords(text, nil)
+
+	if !equalIntSlices(tokens, tokenizer.Encode(text, nil)) {
+		t.Fatalf("EncodeWords tokens differ from Encode")
+	}
+	if len(wordIDs) != len(tokens) {
+		t.Fatalf("Expected %d word IDs, got %d", len(tokens), len(wordIDs))
+	}
+
+	if wordIDs[0] != NoWord || wordIDs[len(wordIDs)-1] != NoWord {
+		t.Errorf("Expected BOS/EOS to have NoWord, got %v", wordIDs)
+	}
+
+	// Word IDs must be non-decreasing and each word must decode to one pre-token
+	eot, _ := tokenizer.GetSpecialTokenID("<|eot_id|>")
+	last := NoWord
+	for i, w := range wordIDs {
+		if tokens[i] == eot && w != NoWord {
+			t.Errorf("Expected special token to have NoWord")
+		}
+		if w != NoWord && w < last {
+			t.Errorf("Word IDs not monotonic: %v", wordIDs)
+		}
+		if w != NoWord {
+			last = w
+		}
+	}
+
+	// " 🦙" is a single pre-token split into multiple byte-level tokens
+	emoji := tokenizer.Encode(" 🦙", &EncodeOptions{BOS: false, EOS: false})
+	if len(emoji) < 2 {
+		t.Skip("Emoji encodes to a single token")
+	}
+	for i := range tokens {
+		if tokens[i] == emoji[0] {
+			for j := 1; j < len(emoji); j++ {
+				if wordIDs[i+j] != wordIDs[i] {
+					t.Errorf("Expected emoji tokens to share a word ID, got %v", wordIDs[i:i+len(emoji)])
+				}
+			}
+		}
+	}
+}
+
+func TestWholeWordMask(t *testing.T) {
+	wordIDs := []int{NoWord, 0, 0, 1, 2, 2, 2, NoWord, 3, NoWord}
+
+	t.Run("masks_whole_words", func(t *testing.T) {
+		mask := WholeWordMask(wordIDs, 0.5, rand.New(rand.NewSource(1)))
+
+		masked := make(map[int]bool)
+		for i, m := range mask {
+			if m {
+				if wordIDs[i] == NoWord {
+					t.Fatalf("Masked a token without a word at %d", i)
+				}
+				masked[wordIDs[i]] = true
+			}
+		}
+		if len(masked) != 2 {
+			t.Errorf("Expected 2 masked words, got %d", len(masked))
+		}
+		for i, w := range wordIDs {
+			if w != NoWord && masked[w] != mask[i] {
+				t.Errorf("Word %d partially masked: %v", w, mask)
+			}
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		a := WholeWordMask(wordIDs, 0.3, rand.New(rand.NewSource(42)))
+		b := WholeWordMask(wordIDs, 0.3, rand.New(rand.NewSource(42)))
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("Masks differ with the same seed: %v vs %v", a, b)
+			}
+		}
+	})
+
+	t.Run("zero_ratio", func(t *testing.T) {
+		for _, m := range WholeWordMask(wordIDs, 0, rand.New(rand.NewSource(1))) {
+			if m {
+				t.Fatal("Expected no masked tokens")
+			}
+		}
+	})
+}
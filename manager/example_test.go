@@ -0,0 +1,25 @@
+package manager_test
+
+import (
+	"fmt"
+
+	"github.com/agentstation/tokenizer/llama3"
+	"github.com/agentstation/tokenizer/manager"
+)
+
+func Example() {
+	m := manager.New(64 << 20) // 64 MiB
+	_ = m.Register("llama3", func() (manager.Tokenizer, error) {
+		return llama3.New()
+	})
+
+	tok, err := m.Get("llama3")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	tokens := tok.(*llama3.Tokenizer).Encode("Hello world", nil)
+	fmt.Println(len(tokens))
+	// Output: 4
+}
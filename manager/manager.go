@@ -0,0 +1,243 @@
+// Package manager owns a set of named tokenizers, loads them on first use and
+// keeps their combined memory under a budget by unloading the least recently
+// used ones.
+//
+// It is intended for services such as gateways that serve many model families
+// and cannot keep every tokenizer resident:
+//
+//	m := manager.New(64 << 20) // 64 MiB
+//	m.Register("llama3", func() (manager.Tokenizer, error) {
+//	    return llama3.New()
+//	})
+//
+//	tok, err := m.Get("llama3")
+//	if err != nil {
+//	    return err
+//	}
+//	tokens := tok.(*llama3.Tokenizer).Encode(text, nil)
+package manager
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotRegistered is returned when a tokenizer name has no loader.
+	ErrNotRegistered = errors.New("tokenizer not registered")
+	// ErrAlreadyRegistered is returned when registering a name twice.
+	ErrAlreadyRegistered = errors.New("tokenizer already registered")
+)
+
+// Tokenizer is a tokenizer that can report its memory usage.
+// *llama3.Tokenizer implements it.
+type Tokenizer interface {
+	// MemoryUsage returns the approximate heap memory held, in bytes.
+	MemoryUsage() int64
+}
+
+// Loader creates a tokenizer. It is called on first use and again after the
+// tokenizer has been evicted.
+type Loader func() (Tokenizer, error)
+
+// Stat describes a registered tokenizer.
+type Stat struct {
+	Name     string
+	Loaded   bool
+	Memory   int64     // approximate memory in bytes, 0 if not loaded
+	LastUsed time.Time // zero if never loaded
+	Loads    int       // number of times the loader has run successfully
+}
+
+// Manager owns named tokenizers and loads them lazily under a memory budget.
+// It is safe for concurrent use.
+type Manager struct {
+	budget int64
+
+	mu      sync.Mutex
+	loaders map[string]Loader
+	entries map[string]*entry
+	lru     *list.List // of *entry, most recently used at the front
+	loads   map[string]int
+}
+
+// entry is a loaded or loading tokenizer.
+type entry struct {
+	name     string
+	tok      Tokenizer
+	err      error
+	ready    chan struct{} // closed when loading finishes
+	elem     *list.Element // nil while loading
+	lastUsed time.Time
+}
+
+// New creates a manager that keeps the combined memory of loaded tokenizers
+// at or below budget bytes. A budget of 0 or less means no limit.
+func New(budget int64) *Manager {
+	return &Manager{
+		budget:  budget,
+		loaders: make(map[string]Loader),
+		entries: make(map[string]*entry),
+		lru:     list.New(),
+		loads:   make(map[string]int),
+	}
+}
+
+// Register adds a named tokenizer. The loader is not called until the first
+// Get for name.
+func (m *Manager) Register(name string, loader Loader) error {
+	if loader == nil {
+		return fmt.Errorf("register %q: nil loader", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.loaders[name]; ok {
+		return fmt.Errorf("register %q: %w", name, ErrAlreadyRegistered)
+	}
+	m.loaders[name] = loader
+	return nil
+}
+
+// Get returns the named tokenizer, loading it if necessary. Loading one
+// tokenizer may evict others that have not been used recently.
+//
+// Concurrent calls for a tokenizer that is loading wait for the same load.
+// Evicted tokenizers remain usable by callers that already hold them; the
+// manager only drops its reference.
+func (m *Manager) Get(name string) (Tokenizer, error) {
+	m.mu.Lock()
+	if e, ok := m.entries[name]; ok {
+		e.lastUsed = time.Now()
+		if e.elem != nil {
+			m.lru.MoveToFront(e.elem)
+		}
+		m.mu.Unlock()
+
+		<-e.ready
+		return e.tok, e.err
+	}
+
+	loader, ok := m.loaders[name]
+	if !ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("get %q: %w", name, ErrNotRegistered)
+	}
+
+	e := &entry{name: name, ready: make(chan struct{}), lastUsed: time.Now()}
+	m.entries[name] = e
+	m.mu.Unlock()
+
+	tok, err := loader()
+	if err == nil && tok == nil {
+		err = errors.New("loader returned nil tokenizer")
+	}
+
+	m.mu.Lock()
+	if err != nil {
+		e.err = fmt.Errorf("load %q: %w", name, err)
+		delete(m.entries, name) // allow a later retry
+	} else {
+		e.tok = tok
+		e.elem = m.lru.PushFront(e)
+		m.loads[name]++
+		m.evictLocked(e)
+	}
+	m.mu.Unlock()
+	close(e.ready)
+
+	return e.tok, e.err
+}
+
+// Unload drops the named tokenizer if it is loaded. It reports whether a
+// tokenizer was unloaded.
+func (m *Manager) Unload(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[name]
+	if !ok || e.elem == nil {
+		return false
+	}
+	m.removeLocked(e)
+	return true
+}
+
+// MemoryUsage returns the approximate combined memory of the loaded
+// tokenizers in bytes.
+func (m *Manager) MemoryUsage() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usageLocked()
+}
+
+// Stats returns the state of every registered tokenizer, most recently used
+// first, followed by tokenizers that are not loaded in name order.
+func (m *Manager) Stats() []Stat {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]Stat, 0, len(m.loaders))
+	for elem := m.lru.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*entry)
+		stats = append(stats, Stat{
+			Name:     e.name,
+			Loaded:   true,
+			Memory:   e.tok.MemoryUsage(),
+			LastUsed: e.lastUsed,
+			Loads:    m.loads[e.name],
+		})
+	}
+
+	var unloaded []Stat
+	for name := range m.loaders {
+		if e, ok := m.entries[name]; ok && e.elem != nil {
+			continue
+		}
+		unloaded = append(unloaded, Stat{Name: name, Loads: m.loads[name]})
+	}
+	sort.Slice(unloaded, func(i, j int) bool { return unloaded[i].Name < unloaded[j].Name })
+
+	return append(stats, unloaded...)
+}
+
+// evictLocked unloads least recently used tokenizers until the loaded set fits
+// the budget. The tokenizer in keep is never evicted, so a single tokenizer
+// larger than the budget stays loaded.
+func (m *Manager) evictLocked(keep *entry) {
+	if m.budget <= 0 {
+		return
+	}
+
+	usage := m.usageLocked()
+	for elem := m.lru.Back(); elem != nil && usage > m.budget; {
+		e := elem.Value.(*entry)
+		elem = elem.Prev()
+		if e == keep {
+			continue
+		}
+		usage -= e.tok.MemoryUsage()
+		m.removeLocked(e)
+	}
+}
+
+// removeLocked drops a loaded entry.
+func (m *Manager) removeLocked(e *entry) {
+	m.lru.Remove(e.elem)
+	e.elem = nil
+	delete(m.entries, e.name)
+}
+
+// usageLocked sums the memory of the loaded tokenizers.
+func (m *Manager) usageLocked() int64 {
+	var usage int64
+	for elem := m.lru.Front(); elem != nil; elem = elem.Next() {
+		usage += elem.Value.(*entry).tok.MemoryUsage()
+	}
+	return usage
+}
@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeTokenizer reports a fixed memory usage.
+type fakeTokenizer struct {
+	name string
+	size int64
+}
+
+func (f *fakeTokenizer) MemoryUsage() int64 { return f.size }
+
+// register adds a fake tokenizer and returns a counter of its loads.
+func register(t *testing.T, m *Manager, name string, size int64) *int32 {
+	t.Helper()
+	var loads int32
+	err := m.Register(name, func() (Tokenizer, error) {
+		atomic.AddInt32(&loads, 1)
+		return &fakeTokenizer{name: name, size: size}, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register %q: %v", name, err)
+	}
+	return &loads
+}
+
+func TestManagerLazyLoad(t *testing.T) {
+	m := New(0)
+	loads := register(t, m, "a", 10)
+
+	if *loads != 0 {
+		t.Fatalf("Expected no loads before Get, got %d", *loads)
+	}
+
+	for i := 0; i < 3; i++ {
+		tok, err := m.Get("a")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if tok.(*fakeTokenizer).name != "a" {
+			t.Errorf("Expected tokenizer a, got %v", tok)
+		}
+	}
+	if *loads != 1 {
+		t.Errorf("Expected 1 load, got %d", *loads)
+	}
+	if m.MemoryUsage() != 10 {
+		t.Errorf("Expected usage 10, got %d", m.MemoryUsage())
+	}
+}
+
+func TestManagerEviction(t *testing.T) {
+	m := New(25)
+	loadsA := register(t, m, "a", 10)
+	register(t, m, "b", 10)
+	register(t, m, "c", 10)
+
+	mustGet(t, m, "a")
+	mustGet(t, m, "b")
+	mustGet(t, m, "a") // a is now most recently used
+	mustGet(t, m, "c") // evicts b
+
+	stats := m.Stats()
+	want := []struct {
+		name   string
+		loaded bool
+	}{{"c", true}, {"a", true}, {"b", false}}
+	if len(stats) != len(want) {
+		t.Fatalf("Expected %d stats, got %+v", len(want), stats)
+	}
+	for i, w := range want {
+		if stats[i].Name != w.name || stats[i].Loaded != w.loaded {
+			t.Errorf("Stat %d: expected %s loaded=%v, got %+v", i, w.name, w.loaded, stats[i])
+		}
+	}
+	if m.MemoryUsage() != 20 {
+		t.Errorf("Expected usage 20, got %d", m.MemoryUsage())
+	}
+
+	// Reloading an evicted tokenizer evicts the least recently used one
+	mustGet(t, m, "b")
+	mustGet(t, m, "a")
+	if *loadsA != 2 {
+		t.Errorf("Expected a to be loaded twice, got %d", *loadsA)
+	}
+}
+
+func TestManagerOversizedTokenizer(t *testing.T) {
+	m := New(5)
+	register(t, m, "small", 4)
+	register(t, m, "big", 10)
+
+	mustGet(t, m, "small")
+	mustGet(t, m, "big")
+
+	// The most recent tokenizer stays loaded even when it exceeds the budget
+	if m.MemoryUsage() != 10 {
+		t.Errorf("Expected usage 10, got %d", m.MemoryUsage())
+	}
+}
+
+func TestManagerErrors(t *testing.T) {
+	m := New(0)
+
+	if _, err := m.Get("missing"); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("Expected ErrNotRegistered, got %v", err)
+	}
+
+	register(t, m, "a", 1)
+	if err := m.Register("a", func() (Tokenizer, error) { return nil, nil }); !errors.Is(err, ErrAlreadyRegistered) {
+		t.Errorf("Expected ErrAlreadyRegistered, got %v", err)
+	}
+
+	// A failed load is retried on the next Get
+	loadErr := errors.New("boom")
+	fail := true
+	_ = m.Register("flaky", func() (Tokenizer, error) {
+		if fail {
+			return nil, loadErr
+		}
+		return &fakeTokenizer{size: 1}, nil
+	})
+	if _, err := m.Get("flaky"); !errors.Is(err, loadErr) {
+		t.Errorf("Expected load error, got %v", err)
+	}
+	fail = false
+	if _, err := m.Get("flaky"); err != nil {
+		t.Errorf("Expected retry to succeed, got %v", err)
+	}
+}
+
+func TestManagerUnload(t *testing.T) {
+	m := New(0)
+	register(t, m, "a", 10)
+
+	if m.Unload("a") {
+		t.Error("Expected Unload of an unloaded tokenizer to return false")
+	}
+	mustGet(t, m, "a")
+	if !m.Unload("a") {
+		t.Error("Expected Unload to return true")
+	}
+	if m.MemoryUsage() != 0 {
+		t.Errorf("Expected usage 0, got %d", m.MemoryUsage())
+	}
+}
+
+func TestManagerConcurrentGet(t *testing.T) {
+	m := New(0)
+	loads := register(t, m, "a", 10)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Get("a"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if *loads != 1 {
+		t.Errorf("Expected 1 load, got %d", *loads)
+	}
+}
+
+func mustGet(t *testing.T, m *Manager, name string) Tokenizer {
+	t.Helper()
+	tok, err := m.Get(name)
+	if err != nil {
+		t.Fatalf("Failed to get %q: %v", name, err)
+	}
+	return tok
+}
@@ -0,0 +1,139 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Tokenizer is the API every model family's tokenizer offers, so that
+// applications can choose a model at runtime:
+//
+//	tok, err := tokenizer.Get("llama3")
+//	if err != nil {
+//		return err
+//	}
+//	n := len(tok.Encode(prompt))
+//
+// Implementations must be safe for concurrent use.
+type Tokenizer interface {
+	// Encode returns the tokens of text. Special token strings in text,
+	// such as "<|eot_id|>", are encoded as their special tokens; no
+	// tokens marking the start or end of a sequence are added.
+	Encode(text string) []int
+
+	// Decode returns the text of tokens.
+	Decode(tokens []int) string
+
+	// VocabSize returns the number of token IDs, including special tokens.
+	VocabSize() int
+
+	// SpecialTokens returns the special tokens by their text. The caller
+	// may modify the returned map.
+	SpecialTokens() map[string]int
+}
+
+// Counter is implemented by tokenizers that can count the tokens of text
+// faster than encoding it. Count must return len(Encode(text)).
+type Counter interface {
+	Count(text string) int
+}
+
+// ErrUnknownTokenizer is returned by Get for names that are not registered.
+var ErrUnknownTokenizer = errors.New("unknown tokenizer")
+
+// registered is a registered tokenizer, created on first use.
+type registered struct {
+	open func() (Tokenizer, error)
+	once sync.Once
+	tok  Tokenizer
+	err  error
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*registered)
+)
+
+// Register makes a tokenizer available to Get under name. open creates it
+// with default settings; it is called once, on the first Get of name.
+// Packages register their tokenizers from init functions, so importing a
+// model family's package, even for side effects only, makes it available:
+//
+//	import _ "github.com/agentstation/tokenizer/llama3"
+//
+// Register panics if name is empty, open is nil, or the name is already
+// registered.
+func Register(name string, open func() (Tokenizer, error)) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("tokenizer: Register with empty name")
+	}
+	if open == nil {
+		panic("tokenizer: Register " + name + " with nil constructor")
+	}
+	if _, dup := registry[name]; dup {
+		panic("tokenizer: Register called twice for " + name)
+	}
+	registry[name] = &registered{open: open}
+}
+
+// Get returns the tokenizer registered under name, creating it on first use.
+// Later calls return the same tokenizer, or the same error if creating it
+// failed.
+func Get(name string) (Tokenizer, error) {
+	registryMu.Lock()
+	r, ok := registry[name]
+	registryMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q (registered: %v)", ErrUnknownTokenizer, name, Names())
+	}
+
+	r.once.Do(func() {
+		r.tok, r.err = r.open()
+		if r.err != nil {
+			r.err = fmt.Errorf("failed to create tokenizer %q: %w", name, r.err)
+		}
+	})
+	return r.tok, r.err
+}
+
+// Names returns the names of the registered tokenizers in sorted order.
+func Names() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CountAll returns the number of tokens of text for each named tokenizer, as
+// Encode counts them, e.g. to compare the cost of a prompt across model
+// providers. The tokenizers are created on first use as by Get and reused
+// afterwards. With no names, text is counted with every registered
+// tokenizer. It returns an error if a tokenizer cannot be created.
+func CountAll(text string, names []string) (map[string]int, error) {
+	if len(names) == 0 {
+		names = Names()
+	}
+	counts := make(map[string]int, len(names))
+	for _, name := range names {
+		tok, err := Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := tok.(Counter); ok {
+			counts[name] = c.Count(text)
+		} else {
+			counts[name] = len(tok.Encode(text))
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,121 @@
+package tokenizer
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeTokenizer encodes each byte as its value.
+type fakeTokenizer struct{}
+
+func (fakeTokenizer) Encode(text string) []int {
+	tokens := make([]int, len(text))
+	for i := range text {
+		tokens[i] = int(text[i])
+	}
+	return tokens
+}
+
+func (fakeTokenizer) Decode(tokens []int) string {
+	var sb strings.Builder
+	for _, t := range tokens {
+		sb.WriteByte(byte(t))
+	}
+	return sb.String()
+}
+
+func (fakeTokenizer) VocabSize() int                { return 256 }
+func (fakeTokenizer) SpecialTokens() map[string]int { return nil }
+
+func TestRegistry(t *testing.T) {
+	opened := 0
+	Register("test-bytes", func() (Tokenizer, error) {
+		opened++
+		return fakeTokenizer{}, nil
+	})
+	errOpen := errors.New("no data")
+	Register("test-broken", func() (Tokenizer, error) {
+		return nil, errOpen
+	})
+
+	for range 2 {
+		tok, err := Get("test-bytes")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if got := tok.Decode(tok.Encode("hi")); got != "hi" {
+			t.Errorf("Expected a round trip of %q, got %q", "hi", got)
+		}
+	}
+	if opened != 1 {
+		t.Errorf("Expected the tokenizer to be created once, got %d", opened)
+	}
+
+	if _, err := Get("test-broken"); !errors.Is(err, errOpen) {
+		t.Errorf("Expected the constructor's error, got %v", err)
+	}
+	if _, err := Get("missing"); !errors.Is(err, ErrUnknownTokenizer) {
+		t.Errorf("Expected ErrUnknownTokenizer, got %v", err)
+	}
+	if names := Names(); !slices.Contains(names, "test-bytes") || !slices.IsSorted(names) {
+		t.Errorf("Expected sorted names including test-bytes, got %v", names)
+	}
+}
+
+// countingTokenizer counts tokens without encoding them.
+type countingTokenizer struct {
+	fakeTokenizer
+	counted *int
+}
+
+func (c countingTokenizer) Count(text string) int {
+	*c.counted++
+	return len(text)
+}
+
+func TestCountAll(t *testing.T) {
+	counted := 0
+	Register("test-count-bytes", func() (Tokenizer, error) { return fakeTokenizer{}, nil })
+	Register("test-count-counter", func() (Tokenizer, error) { return countingTokenizer{counted: &counted}, nil })
+
+	counts, err := CountAll("hello", []string{"test-count-bytes", "test-count-counter"})
+	if err != nil {
+		t.Fatalf("CountAll failed: %v", err)
+	}
+	if len(counts) != 2 || counts["test-count-bytes"] != 5 || counts["test-count-counter"] != 5 {
+		t.Errorf("Expected 5 tokens from both tokenizers, got %v", counts)
+	}
+	if counted != 1 {
+		t.Errorf("Expected Count to be used, got %d calls", counted)
+	}
+
+	if _, err := CountAll("hello", []string{"test-count-bytes", "missing"}); !errors.Is(err, ErrUnknownTokenizer) {
+		t.Errorf("Expected ErrUnknownTokenizer, got %v", err)
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	open := func() (Tokenizer, error) { return fakeTokenizer{}, nil }
+	Register("test-dup", open)
+
+	tests := []struct {
+		name string
+		open func() (Tokenizer, error)
+	}{
+		{"", open},
+		{"test-nil", nil},
+		{"test-dup", open},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected Register(%q) to panic", tt.name)
+				}
+			}()
+			Register(tt.name, tt.open)
+		}()
+	}
+}
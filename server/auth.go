@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnauthenticated is returned by authenticators that reject a request.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator identifies the caller of a request. It returns a stable
+// identity, such as a key name or certificate subject, used for rate limiting
+// and logging.
+type Authenticator interface {
+	Authenticate(r *http.Request) (identity string, err error)
+}
+
+// AuthenticatorFunc adapts a function to an Authenticator.
+type AuthenticatorFunc func(r *http.Request) (string, error)
+
+// Authenticate calls f(r).
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (string, error) {
+	return f(r)
+}
+
+// APIKeys authenticates requests carrying one of the given API keys, either as
+// "Authorization: Bearer <key>" or in an X-API-Key header. keys maps each API
+// key to the identity it authenticates as.
+func APIKeys(keys map[string]string) Authenticator {
+	// Copy so later changes to the caller's map have no effect
+	known := make(map[string]string, len(keys))
+	for key, identity := range keys {
+		known[key] = identity
+	}
+
+	return AuthenticatorFunc(func(r *http.Request) (string, error) {
+		presented := r.Header.Get("X-API-Key")
+		if auth := r.Header.Get("Authorization"); presented == "" && len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+			presented = strings.TrimSpace(auth[7:])
+		}
+		if presented == "" {
+			return "", ErrUnauthenticated
+		}
+
+		// Compare against every key so timing does not reveal which matched
+		identity, found := "", false
+		for key, id := range known {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(presented)) == 1 {
+				identity, found = id, true
+			}
+		}
+		if !found {
+			return "", ErrUnauthenticated
+		}
+		return identity, nil
+	})
+}
+
+// ClientCerts authenticates requests by their verified TLS client
+// certificate, using the certificate's subject common name as the identity.
+// If allowed is non-empty, only those common names are accepted.
+//
+// The server's TLS config must verify client certificates; see MutualTLSConfig.
+func ClientCerts(allowed ...string) Authenticator {
+	allow := make(map[string]bool, len(allowed))
+	for _, cn := range allowed {
+		allow[cn] = true
+	}
+
+	return AuthenticatorFunc(func(r *http.Request) (string, error) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return "", ErrUnauthenticated
+		}
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if len(allow) > 0 && !allow[cn] {
+			return "", ErrUnauthenticated
+		}
+		return cn, nil
+	})
+}
+
+// AnyOf authenticates a request with the first authenticator that accepts it,
+// e.g. to allow either an API key or a client certificate.
+func AnyOf(authenticators ...Authenticator) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) (string, error) {
+		for _, a := range authenticators {
+			if identity, err := a.Authenticate(r); err == nil {
+				return identity, nil
+			}
+		}
+		return "", ErrUnauthenticated
+	})
+}
+
+// MutualTLSConfig returns a TLS config that requires clients to present a
+// certificate signed by one of clientCAs.
+func MutualTLSConfig(cert tls.Certificate, clientCAs *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// identityKey is the context key for the authenticated identity.
+type identityKey struct{}
+
+// Identity returns the identity stored by RequireAuth, if any.
+func Identity(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(identityKey{}).(string)
+	return identity, ok
+}
+
+// RequireAuth rejects requests that a does not authenticate with 401 and
+// stores the identity of accepted requests in their context.
+func RequireAuth(a Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="tokenizer"`)
+			writeError(w, http.StatusUnauthorized, "unauthenticated")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity)))
+	})
+}
+
+// Limit is a token-bucket rate limit: Rate requests per second on average,
+// with bursts of up to Burst requests.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter limits requests per authenticated identity.
+// Requests without an identity share one bucket.
+type RateLimiter struct {
+	def       Limit
+	overrides map[string]Limit
+	now       func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is the token-bucket state for one identity.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter applying def to every identity, except
+// those with an entry in overrides.
+func NewRateLimiter(def Limit, overrides map[string]Limit) *RateLimiter {
+	o := make(map[string]Limit, len(overrides))
+	for identity, limit := range overrides {
+		o[identity] = limit
+	}
+	return &RateLimiter{
+		def:       def,
+		overrides: o,
+		now:       time.Now,
+		buckets:   make(map[string]*bucket),
+	}
+}
+
+// Allow reports whether a request from identity may proceed now and, if not,
+// how long until it may.
+func (l *RateLimiter) Allow(identity string) (bool, time.Duration) {
+	limit, ok := l.overrides[identity]
+	if !ok {
+		limit = l.def
+	}
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+	burst := math.Max(float64(limit.Burst), 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[identity]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[identity] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+}
+
+// Middleware rejects requests over the caller's limit with 429 and a
+// Retry-After header. Place it inside RequireAuth so the identity is known.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := Identity(r.Context())
+		if ok, wait := l.Allow(identity); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentstation/tokenizer/client"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestAPIKeys(t *testing.T) {
+	auth := APIKeys(map[string]string{"secret-1": "team-a", "secret-2": "team-b"})
+
+	tests := []struct {
+		name     string
+		header   string
+		value    string
+		identity string
+		wantErr  bool
+	}{
+		{"bearer", "Authorization", "Bearer secret-1", "team-a", false},
+		{"bearer_lowercase", "Authorization", "bearer secret-2", "team-b", false},
+		{"x_api_key", "X-API-Key", "secret-2", "team-b", false},
+		{"unknown_key", "Authorization", "Bearer nope", "", true},
+		{"basic_auth", "Authorization", "Basic c2VjcmV0LTE=", "", true},
+		{"missing", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/count", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			identity, err := auth.Authenticate(r)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("Expected ErrUnauthenticated, got %v", err)
+				}
+				return
+			}
+			if err != nil || identity != tt.identity {
+				t.Errorf("Expected identity %q, got %q (%v)", tt.identity, identity, err)
+			}
+		})
+	}
+}
+
+func TestClientCerts(t *testing.T) {
+	withCert := func(cn string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/v1/count", nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return r
+	}
+
+	if identity, err := ClientCerts().Authenticate(withCert("svc-a")); err != nil || identity != "svc-a" {
+		t.Errorf("Expected identity svc-a, got %q (%v)", identity, err)
+	}
+	if _, err := ClientCerts("svc-b").Authenticate(withCert("svc-a")); err == nil {
+		t.Error("Expected a common name outside the allow list to be rejected")
+	}
+	if _, err := ClientCerts().Authenticate(httptest.NewRequest(http.MethodPost, "/", nil)); err == nil {
+		t.Error("Expected a request without TLS to be rejected")
+	}
+
+	// Either credential is accepted
+	auth := AnyOf(APIKeys(map[string]string{"k": "key-user"}), ClientCerts())
+	if identity, err := auth.Authenticate(withCert("svc-a")); err != nil || identity != "svc-a" {
+		t.Errorf("Expected identity svc-a, got %q (%v)", identity, err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(Limit{Rate: 1, Burst: 2}, map[string]Limit{"vip": {Rate: 100, Burst: 100}})
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("Request %d within burst was rejected", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("Expected request over the burst to be rejected")
+	}
+	if wait != time.Second {
+		t.Errorf("Expected 1s wait, got %v", wait)
+	}
+
+	// Buckets are per identity
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("Expected a different identity to have its own bucket")
+	}
+	for i := 0; i < 50; i++ {
+		if ok, _ := l.Allow("vip"); !ok {
+			t.Fatalf("Override limit rejected request %d", i)
+		}
+	}
+
+	// Tokens refill over time
+	now = now.Add(time.Second)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("Expected a request after refill to be allowed")
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	tok, err := llama3.New()
+	if err != nil || tok.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	h, err := NewHandler(tok)
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	var seen string
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = Identity(r.Context())
+		h.ServeHTTP(w, r)
+	})
+	limiter := NewRateLimiter(Limit{Rate: 0.001, Burst: 1}, nil)
+	srv := httptest.NewServer(RequireAuth(APIKeys(map[string]string{"secret": "team-a"}), limiter.Middleware(api)))
+	defer srv.Close()
+	ctx := context.Background()
+
+	anonymous, _ := client.New(srv.URL)
+	_, err = anonymous.Count(ctx, "Hello, world!")
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %v", err)
+	}
+
+	c, _ := client.New(srv.URL, client.WithAPIKey("secret"))
+	if n, err := c.Count(ctx, "Hello, world!"); err != nil || n != 4 {
+		t.Fatalf("Expected count 4, got %d (%v)", n, err)
+	}
+	if seen != "team-a" {
+		t.Errorf("Expected identity team-a in handler, got %q", seen)
+	}
+
+	_, err = c.Count(ctx, "Hello, world!")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected 429, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// requestBody returns a reader for the decoded body of r, or writes an error
+// response and returns nil if its Content-Encoding is not supported. Only
+// gzip is supported, as by the client.
+func requestBody(w http.ResponseWriter, r *http.Request, body io.ReadCloser) io.ReadCloser {
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+		return body
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid gzip body: "+err.Error())
+			return nil
+		}
+		return zr
+	default:
+		writeError(w, http.StatusUnsupportedMediaType, "unsupported Content-Encoding "+r.Header.Get("Content-Encoding"))
+		return nil
+	}
+}
+
+// acceptsGzip reports whether the client of r accepts gzip-compressed
+// responses.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			return strings.ReplaceAll(params, " ", "") != "q=0"
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter compresses a response with gzip.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	zw *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.zw != nil {
+		return
+	}
+	h := g.Header()
+	h.Set("Content-Encoding", "gzip")
+	h.Add("Vary", "Accept-Encoding")
+	h.Del("Content-Length")
+	g.zw = gzip.NewWriter(g.ResponseWriter)
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if g.zw == nil {
+		g.WriteHeader(http.StatusOK)
+	}
+	return g.zw.Write(p)
+}
+
+// close flushes the compressed response, if one was started.
+func (g *gzipResponseWriter) close() error {
+	if g.zw == nil {
+		return nil
+	}
+	return g.zw.Close()
+}
@@ -0,0 +1,298 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/agentstation/tokenizer/client"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// Default limits of a Handler.
+const (
+	DefaultMaxBodyBytes = 16 << 20 // See WithMaxBodyBytes
+	DefaultMaxBatchSize = 1024     // See WithMaxBatchSize
+)
+
+// InfoPath is the path of the tokenizer information served on GET.
+const InfoPath = client.InfoPath
+
+// Handler serves the tokenization service API of package client from
+// in-process tokenizers, so that client.Client can talk to it:
+//
+//	tok, _ := llama3.New()
+//	h, err := server.NewHandler(tok)
+//	if err != nil {
+//		return err
+//	}
+//	http.ListenAndServe(":8080", h)
+//
+// Requests name a tokenizer in their model field; an empty name selects the
+// default tokenizer, which is also served as "llama3". Unknown models get a
+// 404. Handler does not authenticate; wrap it in RequireAuth for that.
+type Handler struct {
+	models   map[string]*llama3.Tokenizer // "" is the default tokenizer
+	maxBody  int64
+	maxBatch int
+	maxText  int // Bytes per text, 0 for no limit
+	mux      *http.ServeMux
+}
+
+// Option configures a Handler.
+type Option func(*Handler) error
+
+// WithModel serves tok to requests naming the model name, in addition to the
+// default tokenizer.
+func WithModel(name string, tok *llama3.Tokenizer) Option {
+	return func(h *Handler) error {
+		if name == "" || tok == nil {
+			return fmt.Errorf("server: invalid model %q", name)
+		}
+		h.models[name] = tok
+		return nil
+	}
+}
+
+// WithMaxBodyBytes limits the size of request bodies. Larger requests get a
+// 413. The default is DefaultMaxBodyBytes.
+func WithMaxBodyBytes(n int64) Option {
+	return func(h *Handler) error {
+		if n <= 0 {
+			return fmt.Errorf("server: invalid body limit %d", n)
+		}
+		h.maxBody = n
+		return nil
+	}
+}
+
+// WithMaxBatchSize limits the number of texts in a batch request. Larger
+// batches get a 413. The default is DefaultMaxBatchSize.
+func WithMaxBatchSize(n int) Option {
+	return func(h *Handler) error {
+		if n <= 0 {
+			return fmt.Errorf("server: invalid batch size limit %d", n)
+		}
+		h.maxBatch = n
+		return nil
+	}
+}
+
+// WithMaxTextBytes limits the size of each text to tokenize. A larger text
+// gets a 413, or an error result in a batch, without failing the other
+// texts. By default only the body size is limited.
+func WithMaxTextBytes(n int) Option {
+	return func(h *Handler) error {
+		if n <= 0 {
+			return fmt.Errorf("server: invalid text size limit %d", n)
+		}
+		h.maxText = n
+		return nil
+	}
+}
+
+// NewHandler creates a handler serving tok as the default tokenizer.
+func NewHandler(tok *llama3.Tokenizer, opts ...Option) (*Handler, error) {
+	if tok == nil {
+		return nil, errors.New("server: nil tokenizer")
+	}
+	h := &Handler{
+		models:   map[string]*llama3.Tokenizer{"": tok, llama3.Name: tok},
+		maxBody:  DefaultMaxBodyBytes,
+		maxBatch: DefaultMaxBatchSize,
+		mux:      http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		if err := opt(h); err != nil {
+			return nil, err
+		}
+	}
+
+	h.mux.HandleFunc("POST "+client.EncodePath, h.encode)
+	h.mux.HandleFunc("POST "+client.DecodePath, h.decode)
+	h.mux.HandleFunc("POST "+client.CountPath, h.count)
+	h.mux.HandleFunc("POST "+client.EncodeBatchPath, h.encodeBatch)
+	h.mux.HandleFunc("POST "+client.CountBatchPath, h.countBatch)
+	h.mux.HandleFunc("GET "+InfoPath, h.info)
+	return h, nil
+}
+
+// ServeHTTP serves the API. Request bodies may be gzip-compressed, and
+// responses are compressed for clients accepting gzip.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if acceptsGzip(r) {
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer func() { _ = gw.close() }()
+		w = gw
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) encode(w http.ResponseWriter, r *http.Request) {
+	var req client.EncodeRequest
+	if !h.readRequest(w, r, &req) {
+		return
+	}
+	tok, ok := h.tokenizer(w, req.Model)
+	if !ok {
+		return
+	}
+	opts, ok := encodeOptions(w, tok, req.BOS, req.EOS, req.SuffixSpecials)
+	if !ok {
+		return
+	}
+	if err := h.checkText(req.Text); err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	tokens := tok.Encode(req.Text, opts)
+	if tokens == nil {
+		tokens = []int{} // encode as [], not null
+	}
+	writeJSON(w, http.StatusOK, client.EncodeResponse{Tokens: tokens})
+}
+
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request) {
+	var req client.DecodeRequest
+	if !h.readRequest(w, r, &req) {
+		return
+	}
+	tok, ok := h.tokenizer(w, req.Model)
+	if !ok {
+		return
+	}
+	text, err := tok.DecodeStrict(req.Tokens)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, client.DecodeResponse{Text: text})
+}
+
+func (h *Handler) count(w http.ResponseWriter, r *http.Request) {
+	var req client.CountRequest
+	if !h.readRequest(w, r, &req) {
+		return
+	}
+	tok, ok := h.tokenizer(w, req.Model)
+	if !ok {
+		return
+	}
+	if err := h.checkText(req.Text); err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, client.CountResponse{Count: tok.Count(req.Text, &llama3.EncodeOptions{})})
+}
+
+func (h *Handler) encodeBatch(w http.ResponseWriter, r *http.Request) {
+	var req client.EncodeBatchRequest
+	if !h.readRequest(w, r, &req) || !h.checkBatch(w, len(req.Texts)) {
+		return
+	}
+	tok, ok := h.tokenizer(w, req.Model)
+	if !ok {
+		return
+	}
+	opts, ok := encodeOptions(w, tok, req.BOS, req.EOS, req.SuffixSpecials)
+	if !ok {
+		return
+	}
+	results := make([]client.EncodeResult, len(req.Texts))
+	for i, text := range req.Texts {
+		if err := h.checkText(text); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Tokens = tok.Encode(text, opts)
+	}
+	writeJSON(w, http.StatusOK, client.EncodeBatchResponse{Results: results})
+}
+
+func (h *Handler) countBatch(w http.ResponseWriter, r *http.Request) {
+	var req client.CountBatchRequest
+	if !h.readRequest(w, r, &req) || !h.checkBatch(w, len(req.Texts)) {
+		return
+	}
+	tok, ok := h.tokenizer(w, req.Model)
+	if !ok {
+		return
+	}
+	results := make([]client.CountResult, len(req.Texts))
+	for i, text := range req.Texts {
+		if err := h.checkText(text); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Count = tok.Count(text, &llama3.EncodeOptions{})
+	}
+	writeJSON(w, http.StatusOK, client.CountBatchResponse{Results: results})
+}
+
+func (h *Handler) info(w http.ResponseWriter, r *http.Request) {
+	tok, ok := h.tokenizer(w, r.URL.Query().Get("model"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, tok.Info())
+}
+
+// tokenizer returns the tokenizer of model, writing a 404 if there is none.
+func (h *Handler) tokenizer(w http.ResponseWriter, model string) (*llama3.Tokenizer, bool) {
+	tok, ok := h.models[model]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown model %q", model))
+	}
+	return tok, ok
+}
+
+// checkBatch writes a 413 and returns false if a batch of n texts is too
+// large.
+func (h *Handler) checkBatch(w http.ResponseWriter, n int) bool {
+	if n > h.maxBatch {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("batch of %d texts, maximum is %d", n, h.maxBatch))
+		return false
+	}
+	return true
+}
+
+// checkText returns an error if text is too large to tokenize.
+func (h *Handler) checkText(text string) error {
+	if h.maxText > 0 && len(text) > h.maxText {
+		return fmt.Errorf("text of %d bytes, maximum is %d", len(text), h.maxText)
+	}
+	return nil
+}
+
+// encodeOptions returns the encode options of a request, writing a 400 if
+// a suffix special token is unknown.
+func encodeOptions(w http.ResponseWriter, tok *llama3.Tokenizer, bos, eos bool, suffix []string) (*llama3.EncodeOptions, bool) {
+	for _, special := range suffix {
+		if _, err := tok.GetSpecialTokenID(special); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown special token %q", special))
+			return nil, false
+		}
+	}
+	return &llama3.EncodeOptions{BOS: bos, EOS: eos, SuffixSpecials: suffix}, true
+}
+
+// readRequest decodes the JSON body of r into v. It writes an error response
+// and returns false if the body is too large or not valid. The limit applies
+// to both the compressed and the decoded size of a gzip body.
+func (h *Handler) readRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	body := requestBody(w, r, http.MaxBytesReader(w, r.Body, h.maxBody))
+	if body == nil {
+		return false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, body, h.maxBody)).Decode(v); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, fmt.Sprintf("invalid request: %v", err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/client"
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+// newTestHandler serves a Handler for the Llama 3 tokenizer.
+func newTestHandler(t *testing.T, opts ...Option) (*llama3.Tokenizer, *httptest.Server) {
+	t.Helper()
+	tok, err := llama3.New()
+	if err != nil || tok.VocabSize() == 0 {
+		t.Skip("Skipping tests: Llama 3 data not available")
+	}
+	h, err := NewHandler(tok, opts...)
+	if err != nil {
+		t.Fatalf("NewHandler failed: %v", err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return tok, srv
+}
+
+func TestHandlerClient(t *testing.T) {
+	tok, srv := newTestHandler(t)
+	ctx := context.Background()
+	text := "Hello, world! <|eot_id|>"
+
+	for _, model := range []string{"", llama3.Name} {
+		c, err := client.New(srv.URL, client.WithModel(model))
+		if err != nil {
+			t.Fatalf("client.New failed: %v", err)
+		}
+
+		for _, opts := range []*llama3.EncodeOptions{nil, {}, {BOS: true, EOS: true, SuffixSpecials: []string{"<|eot_id|>"}}} {
+			tokens, err := c.Encode(ctx, text, opts)
+			if err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			if want := tok.Encode(text, opts); !slices.Equal(tokens, want) {
+				t.Errorf("Model %q, options %+v: expected %v, got %v", model, opts, want, tokens)
+			}
+		}
+
+		n, err := c.Count(ctx, "Hello, world!")
+		if err != nil || n != 4 {
+			t.Errorf("Expected count 4 without BOS and EOS, got %d (%v)", n, err)
+		}
+
+		decoded, err := c.Decode(ctx, tok.Encode(text, nil))
+		if err != nil || decoded != "<|begin_of_text|>"+text+"<|end_of_text|>" {
+			t.Errorf("Expected %q to round-trip, got %q (%v)", text, decoded, err)
+		}
+	}
+
+	var apiErr *client.APIError
+	c, _ := client.New(srv.URL)
+	if _, err := c.Decode(ctx, []int{-1}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid token ID, got %v", err)
+	}
+	if _, err := c.Encode(ctx, text, &llama3.EncodeOptions{EOS: true, SuffixSpecials: []string{"<|nope|>"}}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown suffix token, got %v", err)
+	}
+	unknown, _ := client.New(srv.URL, client.WithModel("gpt-2"))
+	if _, err := unknown.Count(ctx, text); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown model, got %v", err)
+	}
+}
+
+func TestHandlerBatch(t *testing.T) {
+	tok, srv := newTestHandler(t, WithMaxBatchSize(3), WithMaxTextBytes(20))
+	ctx := context.Background()
+	c, _ := client.New(srv.URL)
+	texts := []string{"Hello, world!", "", "this text is longer than twenty bytes"}
+
+	counts, err := c.CountBatch(ctx, texts)
+	if err != nil {
+		t.Fatalf("CountBatch failed: %v", err)
+	}
+	if counts[0].Count != 4 || counts[1].Count != 0 || counts[0].Error != "" || counts[1].Error != "" {
+		t.Errorf("Expected counts 4 and 0, got %+v", counts[:2])
+	}
+	if counts[2].Error == "" {
+		t.Error("Expected an error result for the long text")
+	}
+
+	opts := &llama3.EncodeOptions{BOS: true}
+	results, err := c.EncodeBatch(ctx, texts, opts)
+	if err != nil {
+		t.Fatalf("EncodeBatch failed: %v", err)
+	}
+	for i, text := range texts[:2] {
+		if want := tok.Encode(text, opts); !slices.Equal(results[i].Tokens, want) || results[i].Error != "" {
+			t.Errorf("Text %d: expected %v, got %+v", i, want, results[i])
+		}
+	}
+	if results[2].Error == "" || results[2].Tokens != nil {
+		t.Errorf("Expected an error result for the long text, got %+v", results[2])
+	}
+
+	var apiErr *client.APIError
+	if _, err := c.CountBatch(ctx, []string{"a", "b", "c", "d"}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a batch over the limit, got %v", err)
+	}
+	if _, err := c.Count(ctx, texts[2]); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a text over the limit, got %v", err)
+	}
+}
+
+func TestHandlerLimits(t *testing.T) {
+	_, srv := newTestHandler(t, WithMaxBodyBytes(64))
+	c, _ := client.New(srv.URL)
+
+	var apiErr *client.APIError
+	if _, err := c.Count(context.Background(), string(make([]byte, 100))); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a large body, got %v", err)
+	}
+	if _, err := NewHandler(nil); err == nil {
+		t.Error("Expected an error for a nil tokenizer")
+	}
+	tok, _ := llama3.New()
+	for _, opt := range []Option{WithMaxBatchSize(0), WithMaxTextBytes(-1), WithMaxBodyBytes(0), WithModel("", tok)} {
+		if _, err := NewHandler(tok, opt); err == nil {
+			t.Error("Expected an error for an invalid option")
+		}
+	}
+}
+
+func TestHandlerCompression(t *testing.T) {
+	tok, srv := newTestHandler(t, WithMaxBodyBytes(4<<10))
+	ctx := context.Background()
+	c, err := client.New(srv.URL, client.WithCompression(0))
+	if err != nil {
+		t.Fatalf("client.New failed: %v", err)
+	}
+
+	// Compressed request bodies may decode to more than the body limit
+	text := strings.Repeat("compress me ", 300)
+	tokens, err := c.Encode(ctx, text, nil)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if want := tok.Encode(text, nil); !slices.Equal(tokens, want) {
+		t.Errorf("Expected %d tokens, got %d", len(want), len(tokens))
+	}
+	var apiErr *client.APIError
+	if _, err := c.Count(ctx, strings.Repeat("x", 5<<10)); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a body decoding to more than the limit, got %v", err)
+	}
+	results, err := c.CountBatch(ctx, []string{"Hello, world!", "hi"})
+	if err != nil || len(results) != 2 || results[0].Count != 4 {
+		t.Errorf("Expected batch counts, got %+v (%v)", results, err)
+	}
+
+	// Responses are compressed only for clients accepting gzip
+	for _, accept := range []string{"", "gzip", "br, gzip;q=0"} {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+client.CountPath, strings.NewReader(`{"text": "hi"}`))
+		if accept != "" {
+			req.Header.Set("Accept-Encoding", accept)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if got, want := resp.Header.Get("Content-Encoding"), map[bool]string{true: "gzip"}[accept == "gzip"]; got != want {
+			t.Errorf("Accept-Encoding %q: expected Content-Encoding %q, got %q", accept, want, got)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+client.CountPath, strings.NewReader(`{"text": "hi"}`))
+	req.Header.Set("Content-Encoding", "br")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for an unsupported Content-Encoding, got %s", resp.Status)
+	}
+}
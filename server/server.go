@@ -0,0 +1,22 @@
+// Package server implements the HTTP side of the tokenization service API
+// described in package client.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/agentstation/tokenizer/client"
+)
+
+// writeJSON writes v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, client.ErrorResponse{Error: msg})
+}
@@ -0,0 +1,64 @@
+package tiktoken
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pre-tokenization patterns, as in tiktoken. Go's regexp has no lookahead,
+// so the final "\s+(?!\S)|\s+" becomes "\s+", and split backs off the
+// whitespace before a non-space. \s is spelled out as Unicode White_Space,
+// which it is in tiktoken but not in Go.
+const (
+	space       = `\t\n\v\f\r \x{85}\p{Z}`
+	contraction = `(?i:'s|'t|'re|'ve|'m|'ll|'d)`
+	upper       = `[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]`
+	lower       = `[\p{Ll}\p{Lm}\p{Lo}\p{M}]`
+)
+
+var (
+	cl100kPattern = regexp.MustCompile(contraction +
+		`|[^\r\n\p{L}\p{N}]?\p{L}+` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + space + `\p{L}\p{N}]+[\r\n]*` +
+		`|[` + space + `]*[\r\n]+` +
+		`|[` + space + `]+`)
+
+	o200kPattern = regexp.MustCompile(`[^\r\n\p{L}\p{N}]?` + upper + `*` + lower + `+` + contraction + `?` +
+		`|[^\r\n\p{L}\p{N}]?` + upper + `+` + lower + `*` + contraction + `?` +
+		`|\p{N}{1,3}` +
+		`| ?[^` + space + `\p{L}\p{N}]+[\r\n/]*` +
+		`|[` + space + `]*[\r\n]+` +
+		`|[` + space + `]+`)
+)
+
+// split splits text into pre-tokens with pattern.
+func split(pattern *regexp.Regexp, text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		loc := pattern.FindStringIndex(text)
+		end := 0
+		if loc != nil && loc[0] == 0 {
+			end = loc[1]
+		}
+		if end == 0 {
+			// The patterns match any character; keep going regardless
+			_, end = utf8.DecodeRuneInString(text)
+		}
+
+		// A run of spaces before a non-space leaves its last space to the
+		// next pre-token, as "\s+(?!\S)" does. Runs ending in a line break
+		// matched "\s*[\r\n]+" and are kept whole.
+		last, size := utf8.DecodeLastRuneInString(text[:end])
+		if unicode.IsSpace(last) && last != '\r' && last != '\n' && end > size && end < len(text) {
+			if next, _ := utf8.DecodeRuneInString(text[end:]); !unicode.IsSpace(next) {
+				end -= size
+			}
+		}
+
+		pieces = append(pieces, text[:end])
+		text = text[end:]
+	}
+	return pieces
+}
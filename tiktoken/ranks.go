@@ -0,0 +1,202 @@
+package tiktoken
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" // #nosec G505 - names tiktoken's cache files, not a security use
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/agentstation/tokenizer/bpe"
+)
+
+// defaultCacheSize is the default number of pre-tokens in the BPE cache.
+const defaultCacheSize = 65536
+
+// config holds configuration during tokenizer creation.
+type config struct {
+	ranks     io.Reader
+	ranksPath string
+	cacheSize int
+}
+
+// Option is a functional option for configuring a Tokenizer.
+type Option func(*config) error
+
+// WithRanksFile loads the encoding's ranks from a .tiktoken file, such as
+// cl100k_base.tiktoken as downloaded from OpenAI.
+func WithRanksFile(path string) Option {
+	return func(cfg *config) error {
+		if path == "" {
+			return fmt.Errorf("%w: empty path", ErrRanksNotFound)
+		}
+		cfg.ranksPath = path
+		return nil
+	}
+}
+
+// WithRanks loads the encoding's ranks from r, in the format of .tiktoken
+// files.
+func WithRanks(r io.Reader) Option {
+	return func(cfg *config) error {
+		if r == nil {
+			return fmt.Errorf("%w: nil reader", ErrRanksNotFound)
+		}
+		cfg.ranks = r
+		return nil
+	}
+}
+
+// WithCacheSize sets the maximum number of pre-tokens whose tokens are
+// cached. Zero means unlimited. The default is 65536.
+func WithCacheSize(size int) Option {
+	return func(cfg *config) error {
+		if size < 0 {
+			return fmt.Errorf("invalid cache size %d: must not be negative", size)
+		}
+		cfg.cacheSize = size
+		return nil
+	}
+}
+
+// open returns the rank file selected by cfg, or else the one in the
+// tiktoken cache.
+func (cfg *config) open(name string, enc *encoding) (io.ReadCloser, error) {
+	if cfg.ranks != nil {
+		return io.NopCloser(cfg.ranks), nil
+	}
+	if cfg.ranksPath != "" {
+		f, err := os.Open(cfg.ranksPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s ranks: %w", name, err)
+		}
+		return f, nil
+	}
+
+	var tried []string
+	for _, dir := range cacheDirs() {
+		path := filepath.Join(dir, cacheKey(enc.url))
+		f, err := os.Open(path) // #nosec G304 - path is in the tiktoken cache
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to open %s ranks: %w", name, err)
+		}
+		tried = append(tried, path)
+	}
+	return nil, fmt.Errorf("%w for %s: download %s and use WithRanksFile (looked in %v)",
+		ErrRanksNotFound, name, enc.url, tried)
+}
+
+// cacheDirs returns the directories the tiktoken library caches rank files
+// in: $TIKTOKEN_CACHE_DIR, else $DATA_GYM_CACHE_DIR, else data-gym-cache in
+// the temporary directory.
+func cacheDirs() []string {
+	for _, env := range []string{"TIKTOKEN_CACHE_DIR", "DATA_GYM_CACHE_DIR"} {
+		if dir := os.Getenv(env); dir != "" {
+			return []string{dir}
+		}
+	}
+	return []string{filepath.Join(os.TempDir(), "data-gym-cache")}
+}
+
+// cacheKey returns the name tiktoken caches the file at url under.
+func cacheKey(url string) string {
+	sum := sha1.Sum([]byte(url)) // #nosec G401 - matches tiktoken's cache layout
+	return hex.EncodeToString(sum[:])
+}
+
+// loadRanks reads a rank file, one base64 token and its rank per line, into
+// t.tokens and returns the lookup of byte-mapped tokens by rank.
+func (t *Tokenizer) loadRanks(r io.Reader) (map[string]int, error) {
+	t.tokens = make([]string, t.enc.vocabSize)
+	lookup := make(map[string]int, t.enc.vocabSize)
+
+	scanner := bufio.NewScanner(r)
+	var buf []byte
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		encoded, rankText, ok := bytes.Cut(text, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: expected token and rank", ErrInvalidRanks, line)
+		}
+		token, err := base64.StdEncoding.AppendDecode(nil, encoded)
+		if err != nil || len(token) == 0 {
+			return nil, fmt.Errorf("%w: line %d: invalid token %q", ErrInvalidRanks, line, encoded)
+		}
+		rank, err := strconv.Atoi(string(rankText))
+		if err != nil || rank < 0 || rank >= len(t.tokens) {
+			return nil, fmt.Errorf("%w: line %d: invalid rank %q", ErrInvalidRanks, line, rankText)
+		}
+		if t.tokens[rank] != "" {
+			return nil, fmt.Errorf("%w: line %d: duplicate rank %d", ErrInvalidRanks, line, rank)
+		}
+
+		buf = appendMapped(buf[:0], string(token))
+		t.tokens[rank] = string(token)
+		lookup[string(buf)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ranks: %w", err)
+	}
+
+	// Every byte must be a token, or some text could not be encoded
+	for b := range 256 {
+		if _, ok := lookup[string(byteRune(byte(b)))]; !ok {
+			return nil, fmt.Errorf("%w: no token for byte 0x%02x", ErrInvalidRanks, b)
+		}
+	}
+	for _, id := range t.enc.special {
+		if t.tokens[id] != "" {
+			return nil, fmt.Errorf("%w: rank %d is a special token", ErrInvalidRanks, id)
+		}
+	}
+	return lookup, nil
+}
+
+// buildMerges derives the merge table of the ranks. tiktoken merges the
+// adjacent pair whose concatenation has the lowest rank, so every split of
+// a token into two tokens is a merge with the token's rank.
+func buildMerges(lookup map[string]int) map[uint64]bpe.Merge {
+	merges := make(map[uint64]bpe.Merge, len(lookup)*4)
+	for token, rank := range lookup {
+		runes := []rune(token)
+		for i := 1; i < len(runes); i++ {
+			left, ok := lookup[string(runes[:i])]
+			if !ok {
+				continue
+			}
+			right, ok := lookup[string(runes[i:])]
+			if !ok {
+				continue
+			}
+			merges[bpe.PairKey(left, right)] = bpe.Merge{Rank: rank, ID: rank}
+		}
+	}
+	return merges
+}
+
+// byteRune maps a byte to the rune that stands for it in the BPE engine,
+// which works on runes: U+0100 to U+01FF.
+func byteRune(b byte) rune {
+	return 0x100 + rune(b)
+}
+
+// appendMapped appends the byte-mapped form of s to dst.
+func appendMapped(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		r := byteRune(s[i])
+		dst = append(dst, byte(0xC0|r>>6), byte(0x80|r&0x3F)) // #nosec G115 - two-byte UTF-8 of U+0100..U+01FF
+	}
+	return dst
+}
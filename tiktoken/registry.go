@@ -0,0 +1,48 @@
+package tiktoken
+
+import (
+	"github.com/agentstation/tokenizer"
+)
+
+func init() {
+	for _, name := range []string{CL100kBase, O200kBase} {
+		tokenizer.Register(name, func() (tokenizer.Tokenizer, error) {
+			t, err := New(name)
+			if err != nil {
+				return nil, err
+			}
+			return t.Generic(), nil
+		})
+	}
+}
+
+// Generic returns t as a model-independent tokenizer.Tokenizer, for code
+// that works with any model family.
+func (t *Tokenizer) Generic() tokenizer.Tokenizer {
+	return generic{t}
+}
+
+// generic adapts a Tokenizer to tokenizer.Tokenizer.
+type generic struct {
+	*Tokenizer
+}
+
+// allowSpecial encodes special token strings as special tokens, as
+// tokenizer.Tokenizer requires.
+var allowSpecial = &EncodeOptions{AllowSpecial: true}
+
+func (g generic) Encode(text string) []int {
+	return g.Tokenizer.Encode(text, allowSpecial)
+}
+
+func (g generic) Count(text string) int {
+	return g.Tokenizer.Count(text, allowSpecial)
+}
+
+func (g generic) SpecialTokens() map[string]int {
+	special := make(map[string]int, len(g.enc.special))
+	for token, id := range g.enc.special {
+		special[token] = id
+	}
+	return special
+}
@@ -0,0 +1,97 @@
+package tiktoken
+
+import (
+	"io"
+
+	"github.com/agentstation/tokenizer/llama3/scanner"
+)
+
+// Scanner provides streaming tokenization following the bufio.Scanner
+// pattern. It is the llama3 package's scanner.
+type Scanner = scanner.Scanner
+
+// ScannerOption configures scanner behavior.
+type ScannerOption func(*scannerConfig)
+
+// scannerConfig holds the scanner options and the encoding options, which
+// decide how the scanner splits its input.
+type scannerConfig struct {
+	options []scanner.Option
+	encode  *EncodeOptions
+}
+
+// WithBufferSize is scanner.WithBufferSize.
+func WithBufferSize(size int) ScannerOption {
+	return withScannerOption(scanner.WithBufferSize(size))
+}
+
+// WithMaxBuffer is scanner.WithMaxBuffer.
+func WithMaxBuffer(size int) ScannerOption {
+	return withScannerOption(scanner.WithMaxBuffer(size))
+}
+
+// WithMaxTotalTokens is scanner.WithMaxTotalTokens.
+func WithMaxTotalTokens(n int) ScannerOption {
+	return withScannerOption(scanner.WithMaxTotalTokens(n))
+}
+
+// WithMaxInputBytes is scanner.WithMaxInputBytes.
+func WithMaxInputBytes(n int64) ScannerOption {
+	return withScannerOption(scanner.WithMaxInputBytes(n))
+}
+
+// withScannerOption passes opt to the scanner package.
+func withScannerOption(opt scanner.Option) ScannerOption {
+	return func(c *scannerConfig) {
+		c.options = append(c.options, opt)
+	}
+}
+
+// WithEncodeOptions sets encoding options for the scanner.
+func WithEncodeOptions(opts *EncodeOptions) ScannerOption {
+	return func(c *scannerConfig) {
+		c.encode = opts
+	}
+}
+
+// NewScanner creates a scanner for streaming tokenization with bounded
+// memory. The scanner splits its input between pre-tokens of the Llama 3
+// pattern, which is the cl100k_base pattern, so its tokens match Encode for
+// cl100k_base. For o200k_base a split can rarely fall inside a pre-token,
+// such as between a word and its contraction, once per buffer of input.
+func (t *Tokenizer) NewScanner(r io.Reader, opts ...ScannerOption) Scanner {
+	var cfg scannerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var suffix []string
+	if cfg.encode != nil && cfg.encode.EOS {
+		suffix = []string{endOfText}
+	}
+	options := append([]scanner.Option{
+		scanner.WithEncodeOptions(&scanner.EncodeOptions{EOS: suffix != nil, SuffixSpecials: suffix}),
+	}, cfg.options...)
+	adapter := scannerAdapter{t, cfg.encode != nil && cfg.encode.AllowSpecial}
+	return scanner.NewWithOptions(adapter, r, options...)
+}
+
+// scannerAdapter adapts Tokenizer to the scanner.Tokenizer interface.
+type scannerAdapter struct {
+	*Tokenizer
+	allowSpecial bool
+}
+
+// Encode adapts the Encode method. The scanner appends EOS itself.
+func (a scannerAdapter) Encode(text string, _ *scanner.EncodeOptions) []int {
+	return a.Tokenizer.Encode(text, &EncodeOptions{AllowSpecial: a.allowSpecial})
+}
+
+// SpecialTokens returns the special tokens Encode splits text on, so the
+// scanner never splits its input inside one.
+func (a scannerAdapter) SpecialTokens() []string {
+	if !a.allowSpecial {
+		return nil
+	}
+	return a.specialTokens
+}
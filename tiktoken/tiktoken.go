@@ -0,0 +1,245 @@
+// Package tiktoken implements OpenAI's cl100k_base and o200k_base encodings,
+// the tokenizers of GPT-3.5, GPT-4 and GPT-4o, with the same Encode, Decode
+// and Scanner API as the llama3 package. Tokens match the tiktoken library.
+//
+// The vocabularies are the .tiktoken rank files OpenAI publishes, which
+// are not embedded in this module. New reads them from the cache of the
+// Python tiktoken library, so a machine that has used tiktoken needs no
+// setup, or from a file given with WithRanksFile:
+//
+//	tok, err := tiktoken.New(tiktoken.CL100kBase, tiktoken.WithRanksFile("cl100k_base.tiktoken"))
+//	if err != nil {
+//		return err
+//	}
+//	n := tok.Count(prompt, nil)
+//
+// As with tiktoken, special token strings in text are ordinary text unless
+// EncodeOptions.AllowSpecial is set.
+//
+// Importing the package registers both encodings with tokenizer.Register
+// under their names.
+package tiktoken
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/agentstation/tokenizer/bpe"
+)
+
+// Names of the encodings.
+const (
+	CL100kBase = "cl100k_base" // GPT-3.5 and GPT-4
+	O200kBase  = "o200k_base"  // GPT-4o and later
+)
+
+// endOfText is the special token that separates documents in both encodings.
+const endOfText = "<|endoftext|>"
+
+// Common errors.
+var (
+	// ErrUnknownEncoding is returned by New for names other than CL100kBase
+	// and O200kBase.
+	ErrUnknownEncoding = errors.New("unknown encoding")
+
+	// ErrRanksNotFound indicates that no rank file was given and none was
+	// found in the tiktoken cache.
+	ErrRanksNotFound = errors.New("tiktoken rank file not found")
+
+	// ErrInvalidRanks indicates a malformed rank file.
+	ErrInvalidRanks = errors.New("invalid tiktoken rank file")
+)
+
+// encoding describes one of the supported encodings.
+type encoding struct {
+	url       string         // where OpenAI publishes the rank file
+	pattern   *regexp.Regexp // pre-tokenization pattern
+	special   map[string]int // special tokens by text
+	vocabSize int            // number of token IDs, including special tokens
+}
+
+var encodings = map[string]*encoding{
+	CL100kBase: {
+		url:     "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		pattern: cl100kPattern,
+		special: map[string]int{
+			endOfText:         100257,
+			"<|fim_prefix|>":  100258,
+			"<|fim_middle|>":  100259,
+			"<|fim_suffix|>":  100260,
+			"<|endofprompt|>": 100276,
+		},
+		vocabSize: 100277,
+	},
+	O200kBase: {
+		url:     "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+		pattern: o200kPattern,
+		special: map[string]int{
+			endOfText:         199999,
+			"<|endofprompt|>": 200018,
+		},
+		vocabSize: 200019,
+	},
+}
+
+// Tokenizer encodes and decodes text with one encoding. It is safe for
+// concurrent use.
+type Tokenizer struct {
+	name      string
+	enc       *encoding
+	tokens    []string       // token ID to raw bytes, "" for unused IDs
+	processor *bpe.Processor // BPE engine over the byte-mapped ranks
+
+	specialRegex  *regexp.Regexp // matches the special tokens in text
+	specialTokens []string       // special tokens, longest first
+}
+
+// EncodeOptions controls the encoding behavior.
+type EncodeOptions struct {
+	// EOS appends <|endoftext|>. Unlike llama3, no tokens are added by
+	// default, as with tiktoken.
+	EOS bool
+	// AllowSpecial encodes special token strings in text, such as
+	// "<|endoftext|>", as their special tokens. By default they are encoded
+	// as ordinary text, as tiktoken's encode_ordinary does, so untrusted
+	// text cannot insert special tokens.
+	AllowSpecial bool
+}
+
+// New creates a tokenizer for the named encoding, CL100kBase or O200kBase.
+func New(name string, opts ...Option) (*Tokenizer, error) {
+	enc, ok := encodings[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownEncoding, name)
+	}
+
+	cfg := &config{cacheSize: defaultCacheSize}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	ranks, err := cfg.open(name, enc)
+	if err != nil {
+		return nil, err
+	}
+	defer ranks.Close()
+
+	t := &Tokenizer{name: name, enc: enc}
+	lookup, err := t.loadRanks(ranks)
+	if err != nil {
+		return nil, err
+	}
+
+	var cache bpe.Cache
+	if cfg.cacheSize == 0 {
+		cache = bpe.NewSimple()
+	} else {
+		cache = bpe.NewLRU(cfg.cacheSize)
+	}
+	t.processor = &bpe.Processor{
+		TokenLookup: lookup,
+		Merges:      buildMerges(lookup),
+		Cache:       cache,
+	}
+
+	for token, id := range enc.special {
+		t.tokens[id] = token
+		t.specialTokens = append(t.specialTokens, token)
+	}
+	sort.Slice(t.specialTokens, func(i, j int) bool {
+		a, b := t.specialTokens[i], t.specialTokens[j]
+		return len(a) > len(b) || len(a) == len(b) && a < b
+	})
+	quoted := make([]string, len(t.specialTokens))
+	for i, token := range t.specialTokens {
+		quoted[i] = regexp.QuoteMeta(token)
+	}
+	t.specialRegex = regexp.MustCompile(strings.Join(quoted, "|"))
+
+	return t, nil
+}
+
+// Name returns the name of the tokenizer's encoding.
+func (t *Tokenizer) Name() string {
+	return t.name
+}
+
+// Encode converts text into token IDs. Special token strings in text, such
+// as "<|endoftext|>", are encoded as ordinary text unless opts.AllowSpecial
+// is set.
+func (t *Tokenizer) Encode(text string, opts *EncodeOptions) []int {
+	return t.AppendTokens(make([]int, 0, len(text)/3+1), text, opts)
+}
+
+// AppendTokens appends the tokens of text to dst and returns the extended
+// slice, like Encode.
+func (t *Tokenizer) AppendTokens(dst []int, text string, opts *EncodeOptions) []int {
+	start := 0
+	if opts != nil && opts.AllowSpecial {
+		for _, loc := range t.specialRegex.FindAllStringIndex(text, -1) {
+			dst = t.appendOrdinary(dst, text[start:loc[0]])
+			dst = append(dst, t.enc.special[text[loc[0]:loc[1]]])
+			start = loc[1]
+		}
+	}
+	dst = t.appendOrdinary(dst, text[start:])
+
+	if opts != nil && opts.EOS {
+		dst = append(dst, t.enc.special[endOfText])
+	}
+	return dst
+}
+
+// appendOrdinary appends the tokens of text, which holds no special tokens,
+// to dst.
+func (t *Tokenizer) appendOrdinary(dst []int, text string) []int {
+	var buf []byte
+	for _, piece := range split(t.enc.pattern, text) {
+		buf = appendMapped(buf[:0], piece)
+		dst = append(dst, t.processor.PerformBPE(string(buf))...)
+	}
+	return dst
+}
+
+// Count returns the number of tokens in text, len(Encode(text, opts)).
+func (t *Tokenizer) Count(text string, opts *EncodeOptions) int {
+	return len(t.Encode(text, opts))
+}
+
+// Decode converts a sequence of token IDs back into text. Invalid IDs are
+// skipped.
+func (t *Tokenizer) Decode(tokenIDs []int) string {
+	return string(t.DecodeBytes(tokenIDs))
+}
+
+// DecodeBytes converts a sequence of token IDs back to bytes. A token can
+// hold part of a UTF-8 sequence, so the bytes of a partial sequence of IDs
+// need not be valid UTF-8.
+func (t *Tokenizer) DecodeBytes(tokenIDs []int) []byte {
+	out := make([]byte, 0, len(tokenIDs)*4)
+	for _, id := range tokenIDs {
+		if id >= 0 && id < len(t.tokens) {
+			out = append(out, t.tokens[id]...)
+		}
+	}
+	return out
+}
+
+// VocabSize returns the number of token IDs, including special tokens.
+func (t *Tokenizer) VocabSize() int {
+	return len(t.tokens)
+}
+
+// GetSpecialTokenID returns the token ID for a special token string.
+func (t *Tokenizer) GetSpecialTokenID(token string) (int, error) {
+	id, ok := t.enc.special[token]
+	if !ok {
+		return 0, fmt.Errorf("special token %q not found in %s", token, t.name)
+	}
+	return id, nil
+}
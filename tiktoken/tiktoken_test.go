@@ -0,0 +1,264 @@
+package tiktoken
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// testMerges are the ranks after the 256 bytes in testRanks.
+var testMerges = []string{"he", "ll", "hell", " w", "or", " wor", "ld", " world"}
+
+// testRanks returns a small rank file: every byte, then testMerges.
+func testRanks() string {
+	var sb strings.Builder
+	for b := range 256 {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	for i, token := range testMerges {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), 256+i)
+	}
+	return sb.String()
+}
+
+func newTestTokenizer(t *testing.T, name string) *Tokenizer {
+	t.Helper()
+	tok, err := New(name, WithRanks(strings.NewReader(testRanks())))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+	return tok
+}
+
+func TestEncodeDecode(t *testing.T) {
+	tok := newTestTokenizer(t, CL100kBase)
+
+	tests := []struct {
+		name string
+		text string
+		opts *EncodeOptions
+		want []int
+	}{
+		{"empty", "", nil, []int{}},
+		{"merges_by_rank", "hello", nil, []int{258, 'o'}},
+		{"whole_token", "hello world", nil, []int{258, 'o', 263}},
+		{"special_token", "hi<|endoftext|>", &EncodeOptions{AllowSpecial: true}, []int{'h', 'i', 100257}},
+		{"eos", "hi", &EncodeOptions{EOS: true}, []int{'h', 'i', 100257}},
+		{"bytes", "é", nil, []int{0xC3, 0xA9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tok.Encode(tt.text, tt.opts)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Encode(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+			if decoded := tok.Decode(got); (tt.opts == nil || !tt.opts.EOS) && decoded != tt.text {
+				t.Errorf("Decode(%v) = %q, want %q", got, decoded, tt.text)
+			}
+			if n := tok.Count(tt.text, tt.opts); n != len(tt.want) {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, n, len(tt.want))
+			}
+		})
+	}
+
+	if got := tok.VocabSize(); got != 100277 {
+		t.Errorf("VocabSize() = %d, want 100277", got)
+	}
+	if id, err := tok.GetSpecialTokenID("<|endofprompt|>"); err != nil || id != 100276 {
+		t.Errorf("GetSpecialTokenID(<|endofprompt|>) = %d, %v, want 100276", id, err)
+	}
+	if _, err := tok.GetSpecialTokenID("<|begin_of_text|>"); err == nil {
+		t.Error("Expected error for a Llama 3 special token")
+	}
+}
+
+func TestEncodeSpecialAsText(t *testing.T) {
+	tok := newTestTokenizer(t, CL100kBase)
+
+	text := "hi<|endoftext|>"
+	got := tok.Encode(text, nil)
+	if slices.Contains(got, 100257) {
+		t.Errorf("Encode(%q) = %v, want <|endoftext|> as text", text, got)
+	}
+	if decoded := tok.Decode(got); decoded != text {
+		t.Errorf("Decode(%v) = %q, want %q", got, decoded, text)
+	}
+	if n := tok.Count(text, nil); n != len(got) {
+		t.Errorf("Count(%q) = %d, want %d", text, n, len(got))
+	}
+}
+
+// TestBuildMergesGolden derives the merges of a sample of the cl100k_base
+// rank file: every byte, and the tokens splitting the tokens of a sentence.
+func TestBuildMergesGolden(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "cl100k_base.sample.tiktoken"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	tok, err := New(CL100kBase, WithRanks(f))
+	if err != nil {
+		t.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	// The sample merges as the full rank file does
+	text := "Hello, world! This is a test sentence. grabbed"
+	want := []int{9906, 11, 1917, 0, 1115, 374, 264, 1296, 11914, 13, 30418}
+	if got := tok.Encode(text, nil); !slices.Equal(got, want) {
+		t.Errorf("Encode(%q) = %v, want %v", text, got, want)
+	}
+
+	var lines []string
+	for key, merge := range tok.processor.Merges {
+		left, right := int(key>>32), int(key&0xffffffff)
+		lines = append(lines, fmt.Sprintf("%6d %q + %q\n", merge.Rank, tok.tokens[left], tok.tokens[right]))
+	}
+	slices.Sort(lines)
+	got := strings.Join(lines, "")
+
+	golden := filepath.Join("testdata", "cl100k_base.sample.merges.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if got != string(expected) {
+		t.Errorf("Expected merges of %s:\n%s\ngot:\n%s", golden, expected, got)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		text     string
+		want     []string
+	}{
+		{"words", CL100kBase, "Hello world", []string{"Hello", " world"}},
+		{"contraction", CL100kBase, "I'm here", []string{"I", "'m", " here"}},
+		{"digits", CL100kBase, "12345", []string{"123", "45"}},
+		{"leading_spaces", CL100kBase, "  leading", []string{" ", " leading"}},
+		{"inner_spaces", CL100kBase, "a  b", []string{"a", " ", " b"}},
+		{"trailing_spaces", CL100kBase, "end  ", []string{"end", "  "}},
+		{"newlines", CL100kBase, "line\n\nnext", []string{"line", "\n\n", "next"}},
+		{"spaces_before_newline", CL100kBase, "x   \n", []string{"x", "   \n"}},
+		{"unicode_space", CL100kBase, "a\u00a0\u00a0b", []string{"a", "\u00a0", "\u00a0b"}},
+		{"punctuation", CL100kBase, "foo/bar!!", []string{"foo", "/bar", "!!"}},
+		{"camel_case_joined", CL100kBase, "HelloWorld", []string{"HelloWorld"}},
+		{"camel_case", O200kBase, "HelloWorld", []string{"Hello", "World"}},
+		{"upper_run", O200kBase, "CamelCASE", []string{"Camel", "CASE"}},
+		{"acronym", O200kBase, "HTTPServer", []string{"HTTPServer"}},
+		{"word_contraction", O200kBase, "don't stop", []string{"don't", " stop"}},
+		{"slash_newline", O200kBase, "a+/\nb", []string{"a", "+/\n", "b"}},
+		{"o200k_spaces", O200kBase, "  x", []string{" ", " x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := split(encodings[tt.encoding].pattern, tt.text)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("split(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	t.Setenv("TIKTOKEN_CACHE_DIR", t.TempDir())
+
+	ranks := testRanks()
+	tests := []struct {
+		name string
+		enc  string
+		opts []Option
+		want error
+	}{
+		{"unknown_encoding", "p50k_base", nil, ErrUnknownEncoding},
+		{"not_cached", CL100kBase, nil, ErrRanksNotFound},
+		{"missing_file", CL100kBase, []Option{WithRanksFile(filepath.Join(t.TempDir(), "missing"))}, os.ErrNotExist},
+		{"bad_line", CL100kBase, []Option{WithRanks(strings.NewReader("aGk=\n"))}, ErrInvalidRanks},
+		{"bad_base64", CL100kBase, []Option{WithRanks(strings.NewReader("!!! 1\n"))}, ErrInvalidRanks},
+		{"duplicate_rank", CL100kBase, []Option{WithRanks(strings.NewReader(ranks + "aGk= 0\n"))}, ErrInvalidRanks},
+		{"special_rank", CL100kBase, []Option{WithRanks(strings.NewReader(ranks + "aGk= 100257\n"))}, ErrInvalidRanks},
+		{"missing_byte", CL100kBase, []Option{WithRanks(strings.NewReader("aGk= 0\n"))}, ErrInvalidRanks},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.enc, tt.opts...)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("New() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFromTiktokenCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TIKTOKEN_CACHE_DIR", dir)
+	path := filepath.Join(dir, cacheKey(encodings[O200kBase].url))
+	if err := os.WriteFile(path, []byte(testRanks()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := New(O200kBase)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := tok.Encode("hello<|endoftext|>", &EncodeOptions{AllowSpecial: true}); !slices.Equal(got, []int{258, 'o', 199999}) {
+		t.Errorf("Encode() = %v, want [258 111 199999]", got)
+	}
+}
+
+func TestScannerMatchesEncode(t *testing.T) {
+	tok := newTestTokenizer(t, CL100kBase)
+	text := strings.Repeat("hello world, it's 2024!<|endoftext|>\n", 500)
+
+	for _, allowSpecial := range []bool{false, true} {
+		opts := &EncodeOptions{EOS: true, AllowSpecial: allowSpecial}
+		var got []int
+		scanner := tok.NewScanner(strings.NewReader(text), WithBufferSize(64), WithEncodeOptions(opts))
+		for scanner.Scan() {
+			got = append(got, scanner.Token())
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatalf("Scanner error: %v", err)
+		}
+		if want := tok.Encode(text, opts); !slices.Equal(got, want) {
+			t.Errorf("AllowSpecial=%v: Scanner produced %d tokens, Encode %d", allowSpecial, len(got), len(want))
+		}
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	names := tokenizer.Names()
+	for _, name := range []string{CL100kBase, O200kBase} {
+		if !slices.Contains(names, name) {
+			t.Errorf("Expected %s in %v", name, names)
+		}
+	}
+
+	tok := newTestTokenizer(t, O200kBase)
+	generic := tok.Generic()
+	if got := generic.Encode("hello<|endofprompt|>"); !slices.Equal(got, []int{258, 'o', 200018}) {
+		t.Errorf("Encode() = %v, want [258 111 200018]", got)
+	}
+	if n := generic.(tokenizer.Counter).Count("hello<|endofprompt|>"); n != 3 {
+		t.Errorf("Count() = %d, want 3", n)
+	}
+	if special := generic.SpecialTokens(); len(special) != 2 || special["<|endoftext|>"] != 199999 {
+		t.Errorf("SpecialTokens() = %v", special)
+	}
+}
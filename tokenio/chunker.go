@@ -0,0 +1,142 @@
+package tokenio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// ChunkOptions configures content-defined chunking. Sizes are in tokens.
+type ChunkOptions struct {
+	MinSize int // No boundary before this many tokens
+	AvgSize int // Approximate average chunk size
+	MaxSize int // A boundary is forced at this many tokens
+}
+
+// DefaultChunkOptions returns chunk sizes suited to deduplicating corpora:
+// chunks of about 2,048 tokens, between 512 and 8,192.
+func DefaultChunkOptions() ChunkOptions {
+	return ChunkOptions{MinSize: 512, AvgSize: 2048, MaxSize: 8192}
+}
+
+// Chunker splits a token stream into content-defined chunks.
+//
+// A boundary is placed where a rolling hash over the preceding token IDs
+// matches a bit pattern, so boundaries depend only on nearby content. An
+// insertion or deletion changes the chunks around it, and the chunking
+// resynchronizes after it, which makes identical passages in different
+// corpora produce identical chunks for deduplicated storage.
+//
+// Records of the underlying reader are treated as one flat stream.
+type Chunker struct {
+	r    Reader
+	opts ChunkOptions
+	mask uint64
+
+	buf  []int  // tokens read but not yet returned
+	pos  int    // tokens of buf already hashed
+	hash uint64 // rolling hash of buf[:pos]
+	err  error  // error from r, returned once buf is empty
+}
+
+// NewChunker returns a chunker reading from r.
+func NewChunker(r Reader, opts ChunkOptions) (*Chunker, error) {
+	if opts.MinSize < 1 || opts.AvgSize <= opts.MinSize || opts.MaxSize < opts.AvgSize {
+		return nil, fmt.Errorf("tokenio: chunk sizes must satisfy 1 <= min < avg <= max, got %d, %d, %d", opts.MinSize, opts.AvgSize, opts.MaxSize)
+	}
+
+	// A boundary is found on average every 2^n hashed tokens past the
+	// minimum, with 2^n the power of two at or below avg-min. The mask tests
+	// the top bits, which depend on the last 64 tokens; the low bits would
+	// only see the last n.
+	n := bits.Len(uint(opts.AvgSize-opts.MinSize)) - 1 // #nosec G115 - AvgSize > MinSize was checked
+	var mask uint64
+	if n > 0 {
+		mask = (1<<n - 1) << (64 - n)
+	}
+	return &Chunker{r: r, opts: opts, mask: mask}, nil
+}
+
+// Next returns the next chunk, or io.EOF after the last one.
+func (c *Chunker) Next() ([]int, error) {
+	for {
+		if cut := c.scan(); cut > 0 {
+			chunk := c.buf[:cut:cut]
+			c.buf = c.buf[cut:]
+			c.pos, c.hash = 0, 0
+			return chunk, nil
+		}
+
+		if c.err != nil {
+			if len(c.buf) > 0 {
+				chunk := c.buf
+				c.buf, c.pos, c.hash = nil, 0, 0
+				return chunk, nil
+			}
+			return nil, c.err
+		}
+
+		tokens, err := c.r.Read()
+		if err != nil {
+			c.err = err
+			continue
+		}
+		c.buf = append(c.buf, tokens...)
+	}
+}
+
+// scan hashes buffered tokens and returns the length of the first chunk, or
+// 0 if more tokens are needed.
+func (c *Chunker) scan() int {
+	for ; c.pos < len(c.buf); c.pos++ {
+		c.hash = c.hash<<1 + gear(c.buf[c.pos])
+		size := c.pos + 1
+		if size >= c.opts.MinSize && c.hash&c.mask == 0 || size == c.opts.MaxSize {
+			return size
+		}
+	}
+	return 0
+}
+
+// gear maps a token ID to a pseudo-random 64-bit value (SplitMix64).
+func gear(id int) uint64 {
+	z := uint64(id) + 0x9e3779b97f4a7c15 // #nosec G115 - only the bits matter
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// ChunkTokens splits tokens into content-defined chunks.
+func ChunkTokens(tokens []int, opts ChunkOptions) ([][]int, error) {
+	c, err := NewChunker(&sliceReader{tokens: tokens}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks [][]int
+	for {
+		chunk, err := c.Next()
+		if errors.Is(err, io.EOF) {
+			return chunks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+// sliceReader returns a slice as a single record.
+type sliceReader struct {
+	tokens []int
+	done   bool
+}
+
+func (s *sliceReader) Read() ([]int, error) {
+	if s.done || len(s.tokens) == 0 {
+		return nil, io.EOF
+	}
+	s.done = true
+	return s.tokens, nil
+}
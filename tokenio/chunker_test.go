@@ -0,0 +1,119 @@
+package tokenio
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/agentstation/tokenizer"
+)
+
+func randomTokens(seed int64, n int) []int {
+	rng := rand.New(rand.NewSource(seed))
+	tokens := make([]int, n)
+	for i := range tokens {
+		tokens[i] = rng.Intn(128000)
+	}
+	return tokens
+}
+
+func TestChunkTokens(t *testing.T) {
+	opts := ChunkOptions{MinSize: 64, AvgSize: 256, MaxSize: 1024}
+	tokens := randomTokens(1, 50000)
+
+	chunks, err := ChunkTokens(tokens, opts)
+	if err != nil {
+		t.Fatalf("ChunkTokens failed: %v", err)
+	}
+	var joined []int
+	for i, c := range chunks {
+		if len(c) > opts.MaxSize || len(c) < opts.MinSize && i < len(chunks)-1 {
+			t.Errorf("Chunk %d has %d tokens, outside [%d, %d]", i, len(c), opts.MinSize, opts.MaxSize)
+		}
+		joined = append(joined, c...)
+	}
+	if !tokenizer.EqualTokens(joined, tokens) {
+		t.Fatal("Expected the chunks to join to the input")
+	}
+	if avg := len(tokens) / len(chunks); avg < opts.AvgSize/2 || avg > opts.AvgSize*2 {
+		t.Errorf("Expected an average chunk size near %d, got %d", opts.AvgSize, avg)
+	}
+
+	// An insertion near the start only changes the chunks around it
+	edited := append(append(append([]int(nil), tokens[:100]...), 1, 2, 3), tokens[100:]...)
+	editedChunks, err := ChunkTokens(edited, opts)
+	if err != nil {
+		t.Fatalf("ChunkTokens failed: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, c := range chunks {
+		seen[string(tokenizer.CompressTokens(c))] = true
+	}
+	shared := 0
+	for _, c := range editedChunks {
+		if seen[string(tokenizer.CompressTokens(c))] {
+			shared++
+		}
+	}
+	if shared < len(editedChunks)-3 {
+		t.Errorf("Expected all but a few of %d chunks to be shared, got %d", len(editedChunks), shared)
+	}
+}
+
+func TestChunkerRecords(t *testing.T) {
+	// Record boundaries of the reader do not affect the chunks
+	tokens := randomTokens(2, 20000)
+	want, err := ChunkTokens(tokens, DefaultChunkOptions())
+	if err != nil {
+		t.Fatalf("ChunkTokens failed: %v", err)
+	}
+
+	c, err := NewChunker(&recordReader{tokens: tokens, size: 333}, DefaultChunkOptions())
+	if err != nil {
+		t.Fatalf("NewChunker failed: %v", err)
+	}
+	for i := 0; ; i++ {
+		chunk, err := c.Next()
+		if errors.Is(err, io.EOF) {
+			if i != len(want) {
+				t.Errorf("Expected %d chunks, got %d", len(want), i)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if i >= len(want) || !tokenizer.EqualTokens(chunk, want[i]) {
+			t.Fatalf("Chunk %d differs from ChunkTokens", i)
+		}
+	}
+}
+
+// recordReader returns tokens in records of a fixed size.
+type recordReader struct {
+	tokens []int
+	size   int
+}
+
+func (r *recordReader) Read() ([]int, error) {
+	if len(r.tokens) == 0 {
+		return nil, io.EOF
+	}
+	n := min(r.size, len(r.tokens))
+	record := r.tokens[:n]
+	r.tokens = r.tokens[n:]
+	return record, nil
+}
+
+func TestNewChunkerOptions(t *testing.T) {
+	for _, opts := range []ChunkOptions{
+		{MinSize: 0, AvgSize: 10, MaxSize: 20},
+		{MinSize: 10, AvgSize: 10, MaxSize: 20},
+		{MinSize: 10, AvgSize: 20, MaxSize: 15},
+	} {
+		if _, err := NewChunker(&recordReader{}, opts); err == nil {
+			t.Errorf("Expected an error for %+v", opts)
+		}
+	}
+}
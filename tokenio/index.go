@@ -0,0 +1,298 @@
+package tokenio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// indexMagic starts the binary form of an Index.
+const indexMagic = "TKIX"
+
+// indexVersion is the version of the binary form of an Index.
+const indexVersion = 1
+
+// IndexEntry locates a token in a stream.
+type IndexEntry struct {
+	Token  int64 // Position of the token in the stream
+	Offset int64 // Byte offset of the record (or, in flat formats, the token) that starts there
+}
+
+// Index maps token positions of a stream to byte offsets, so that tokens at
+// any position can be read without scanning the stream from the start. It
+// is built by an indexed writer while the stream is written.
+//
+// Entries are at least Interval tokens apart, at record starts or, in flat
+// formats, at any token, so reading from a position skips at most Interval
+// tokens plus one record. A smaller interval makes seeking cheaper and the
+// index larger.
+type Index struct {
+	Format  Format
+	Tokens  int64 // Number of tokens in the stream
+	Entries []IndexEntry
+
+	// Interval is the minimum distance between entries in tokens, ChunkSize
+	// if zero. It only applies while the index is built and is not stored
+	// by MarshalBinary.
+	Interval int64
+}
+
+// NewIndexedWriter returns a writer of the given format that records an
+// index of the stream in idx. Any previous contents of idx other than
+// Interval are discarded. The index is complete once the writer is flushed.
+func NewIndexedWriter(w io.Writer, f Format, idx *Index) (Writer, error) {
+	if _, err := ParseFormat(string(f)); err != nil {
+		return nil, err
+	}
+	if idx.Interval < 0 {
+		return nil, fmt.Errorf("tokenio: negative index interval %d", idx.Interval)
+	}
+	*idx = Index{Format: f, Interval: idx.Interval}
+	return &writer{w: bufio.NewWriter(w), format: f, index: idx}, nil
+}
+
+// indexAt adds an index entry for token i of the record being written,
+// encoded at byte n of the record, if it is far enough from the last entry.
+func (w *writer) indexAt(i, n int) {
+	if w.index == nil {
+		return
+	}
+	interval := w.index.Interval
+	if interval == 0 {
+		interval = ChunkSize
+	}
+	pos := w.index.Tokens + int64(i)
+	entries := w.index.Entries
+	if len(entries) > 0 && pos-entries[len(entries)-1].Token < interval {
+		return
+	}
+	w.index.Entries = append(entries, IndexEntry{Token: pos, Offset: w.offset + int64(n)})
+}
+
+// MarshalBinary encodes the index for storage next to its stream.
+func (x *Index) MarshalBinary() ([]byte, error) {
+	b := append([]byte(indexMagic), indexVersion)
+	b = binary.AppendUvarint(b, uint64(len(x.Format)))
+	b = append(b, x.Format...)
+	b = binary.AppendUvarint(b, uint64(x.Tokens))       // #nosec G115 - counts are not negative
+	b = binary.AppendUvarint(b, uint64(len(x.Entries))) // #nosec G115 - counts are not negative
+
+	var prev IndexEntry
+	for _, e := range x.Entries {
+		if e.Token < prev.Token || e.Offset < prev.Offset {
+			return nil, errors.New("tokenio: index entries out of order")
+		}
+		b = binary.AppendUvarint(b, uint64(e.Token-prev.Token))   // #nosec G115 - checked above
+		b = binary.AppendUvarint(b, uint64(e.Offset-prev.Offset)) // #nosec G115 - checked above
+		prev = e
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes an index encoded by MarshalBinary.
+func (x *Index) UnmarshalBinary(data []byte) error {
+	if len(data) < len(indexMagic)+1 || string(data[:len(indexMagic)]) != indexMagic {
+		return errors.New("tokenio: not a token index")
+	}
+	if data[len(indexMagic)] != indexVersion {
+		return fmt.Errorf("tokenio: unsupported index version %d", data[len(indexMagic)])
+	}
+	data = data[len(indexMagic)+1:]
+
+	next := func() (uint64, error) {
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return 0, errors.New("tokenio: truncated index")
+		}
+		data = data[n:]
+		return v, nil
+	}
+
+	size, err := next()
+	if err != nil {
+		return err
+	}
+	if size > uint64(len(data)) {
+		return errors.New("tokenio: truncated index")
+	}
+	format, err := ParseFormat(string(data[:size]))
+	if err != nil {
+		return err
+	}
+	data = data[size:]
+
+	tokens, err := next()
+	if err != nil {
+		return err
+	}
+	count, err := next()
+	if err != nil {
+		return err
+	}
+	if tokens > math.MaxInt64 || count > uint64(len(data))/2 {
+		return errors.New("tokenio: corrupt index")
+	}
+
+	entries := make([]IndexEntry, count)
+	var token, offset uint64
+	for i := range entries {
+		dt, err := next()
+		if err != nil {
+			return err
+		}
+		do, err := next()
+		if err != nil {
+			return err
+		}
+		token += dt
+		offset += do
+		if token < dt || token > tokens || offset < do || offset > math.MaxInt64 {
+			return errors.New("tokenio: corrupt index")
+		}
+		entries[i] = IndexEntry{Token: int64(token), Offset: int64(offset)} // #nosec G115 - range checked above
+	}
+	if len(data) != 0 {
+		return errors.New("tokenio: trailing data after index")
+	}
+
+	*x = Index{Format: format, Tokens: int64(tokens), Entries: entries} // #nosec G115 - range checked above
+	return nil
+}
+
+// SeekReader reads an indexed stream from any token position. Records of
+// the stream are returned as they are stored, except that the first record
+// after a seek starts at the requested position.
+type SeekReader struct {
+	r   io.ReaderAt
+	idx *Index
+	rd  Reader
+	pos int64 // token position of the next token returned
+	// skip is the number of tokens between the index entry rd starts at and
+	// pos.
+	skip int64
+}
+
+// NewSeekReader returns a reader of the stream r described by idx,
+// positioned at its first token.
+func NewSeekReader(r io.ReaderAt, idx *Index) *SeekReader {
+	return &SeekReader{r: r, idx: idx}
+}
+
+// Seek sets the token position of the next Read to offset, interpreted as
+// by io.Seeker but in tokens: relative to the start of the stream, the
+// current position or the end. It returns the new position, which must be
+// between 0 and the number of tokens in the stream.
+func (s *SeekReader) Seek(offset int64, whence int) (int64, error) {
+	pos := offset
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		pos += s.pos
+	case io.SeekEnd:
+		pos += s.idx.Tokens
+	default:
+		return s.pos, fmt.Errorf("tokenio: invalid whence %d", whence)
+	}
+	if pos < 0 || pos > s.idx.Tokens {
+		return s.pos, fmt.Errorf("tokenio: seek to token %d out of range for %d tokens", pos, s.idx.Tokens)
+	}
+
+	var e IndexEntry
+	if i := sort.Search(len(s.idx.Entries), func(i int) bool { return s.idx.Entries[i].Token > pos }) - 1; i >= 0 {
+		e = s.idx.Entries[i]
+	}
+	rd, err := NewReader(io.NewSectionReader(s.r, e.Offset, math.MaxInt64-e.Offset), s.idx.Format)
+	if err != nil {
+		return s.pos, err
+	}
+	s.rd, s.pos, s.skip = rd, pos, pos-e.Token
+	return pos, nil
+}
+
+// Read returns the next record, or io.EOF at the end of the stream.
+func (s *SeekReader) Read() ([]int, error) {
+	if s.rd == nil {
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		record, err := s.rd.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) && s.skip > 0 {
+				return nil, fmt.Errorf("tokenio: stream is shorter than its index: %w", io.ErrUnexpectedEOF)
+			}
+			return nil, err
+		}
+		if s.skip >= int64(len(record)) {
+			s.skip -= int64(len(record))
+			continue
+		}
+		record = record[s.skip:]
+		s.skip = 0
+		s.pos += int64(len(record))
+		return record, nil
+	}
+}
+
+// Excerpt reads n tokens starting at token position start of the indexed
+// stream r. Records of the stream are treated as one flat stream, so an
+// excerpt may span records.
+func Excerpt(r io.ReaderAt, idx *Index, start int64, n int) ([]int, error) {
+	if start < 0 || n < 0 || start > idx.Tokens-int64(n) {
+		return nil, fmt.Errorf("tokenio: excerpt [%d, %d) out of range for %d tokens", start, start+int64(n), idx.Tokens)
+	}
+	sr := NewSeekReader(r, idx)
+	if _, err := sr.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	tokens := make([]int, 0, n)
+	for len(tokens) < n {
+		record, err := sr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("tokenio: stream is shorter than its index: %w", io.ErrUnexpectedEOF)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, record[:min(len(record), n-len(tokens))]...)
+	}
+	return tokens, nil
+}
+
+// SampleExcerpts reads count excerpts of exactly length tokens from the
+// indexed stream r. Start positions are drawn uniformly from all token
+// positions that leave room for a full excerpt, so every token is equally
+// likely to start an excerpt however the stream is split into records or
+// bytes. Excerpts are returned in stream order and may overlap.
+func SampleExcerpts(r io.ReaderAt, idx *Index, count, length int, rng *rand.Rand) ([][]int, error) {
+	if count < 0 || length < 1 {
+		return nil, fmt.Errorf("tokenio: invalid sample of %d excerpts of %d tokens", count, length)
+	}
+	if int64(length) > idx.Tokens {
+		return nil, fmt.Errorf("tokenio: excerpt length %d exceeds stream of %d tokens", length, idx.Tokens)
+	}
+
+	starts := make([]int64, count)
+	for i := range starts {
+		starts[i] = rng.Int63n(idx.Tokens - int64(length) + 1)
+	}
+	slices.Sort(starts)
+
+	excerpts := make([][]int, count)
+	for i, start := range starts {
+		excerpt, err := Excerpt(r, idx, start, length)
+		if err != nil {
+			return nil, err
+		}
+		excerpts[i] = excerpt
+	}
+	return excerpts, nil
+}
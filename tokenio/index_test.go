@@ -0,0 +1,219 @@
+package tokenio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/agentstation/tokenizer"
+)
+
+func TestIndexedExcerpts(t *testing.T) {
+	rng := rand.New(rand.NewSource(1)) // #nosec G404 - deterministic test data
+	var records [][]int
+	var flat []int
+	for i := 0; i < 60; i++ {
+		record := make([]int, rng.Intn(500))
+		if i == 30 {
+			record = make([]int, 3*ChunkSize+17)
+		}
+		for j := range record {
+			record[j] = rng.Intn(128256)
+		}
+		records = append(records, record)
+		flat = append(flat, record...)
+	}
+
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			var built Index
+			w, err := NewIndexedWriter(&buf, format, &built)
+			if err != nil {
+				t.Fatalf("NewIndexedWriter failed: %v", err)
+			}
+			for _, r := range records {
+				if err := w.Write(r); err != nil {
+					t.Fatalf("Write failed: %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush failed: %v", err)
+			}
+			if built.Tokens != int64(len(flat)) {
+				t.Fatalf("Expected %d tokens, got %d", len(flat), built.Tokens)
+			}
+
+			data, err := built.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary failed: %v", err)
+			}
+			var idx Index
+			if err := idx.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary failed: %v", err)
+			}
+			if idx.Format != format || idx.Tokens != built.Tokens || len(idx.Entries) != len(built.Entries) {
+				t.Fatalf("Expected %+v after round trip, got %+v", built, idx)
+			}
+
+			stream := bytes.NewReader(buf.Bytes())
+			for _, start := range []int{0, 1, ChunkSize - 1, ChunkSize, len(flat) / 2, len(flat) - 100} {
+				got, err := Excerpt(stream, &idx, int64(start), 100)
+				if err != nil {
+					t.Fatalf("Excerpt(%d) failed: %v", start, err)
+				}
+				if !tokenizer.EqualTokens(got, flat[start:start+100]) {
+					t.Errorf("Excerpt(%d) does not match the stream", start)
+				}
+			}
+			if _, err := Excerpt(stream, &idx, int64(len(flat)-99), 100); err == nil {
+				t.Error("Expected error for excerpt past the end")
+			}
+
+			excerpts, err := SampleExcerpts(stream, &idx, 20, 256, rand.New(rand.NewSource(2))) // #nosec G404 - deterministic test data
+			if err != nil {
+				t.Fatalf("SampleExcerpts failed: %v", err)
+			}
+			if len(excerpts) != 20 {
+				t.Fatalf("Expected 20 excerpts, got %d", len(excerpts))
+			}
+			for _, e := range excerpts {
+				if len(e) != 256 {
+					t.Fatalf("Expected 256 tokens, got %d", len(e))
+				}
+				if !containsRun(flat, e) {
+					t.Error("Expected excerpt to be a run of the stream")
+				}
+			}
+		})
+	}
+}
+
+// containsRun reports whether sub occurs in tokens.
+func containsRun(tokens, sub []int) bool {
+	for i := 0; i+len(sub) <= len(tokens); i++ {
+		if tokenizer.PrefixEqual(tokens[i:], sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIndexUnmarshalCorrupt(t *testing.T) {
+	var idx Index
+	w, err := NewIndexedWriter(&bytes.Buffer{}, U32, &idx)
+	if err != nil {
+		t.Fatalf("NewIndexedWriter failed: %v", err)
+	}
+	if err := w.Write(make([]int, 3*ChunkSize)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	data, err := idx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary failed: %v", err)
+	}
+
+	for i := range data {
+		var got Index
+		if err := got.UnmarshalBinary(data[:i]); err == nil {
+			t.Errorf("Expected error for index truncated to %d bytes", i)
+		}
+	}
+	if err := new(Index).UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("Expected error for trailing data")
+	}
+}
+
+func TestSeekReader(t *testing.T) {
+	records := [][]int{make([]int, 250), make([]int, 40), {}, make([]int, 1000)}
+	var flat []int
+	for _, r := range records {
+		for j := range r {
+			r[j] = len(flat) + j
+		}
+		flat = append(flat, r...)
+	}
+
+	for _, format := range Formats {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			idx := Index{Interval: 100}
+			w, err := NewIndexedWriter(&buf, format, &idx)
+			if err != nil {
+				t.Fatalf("NewIndexedWriter failed: %v", err)
+			}
+			if _, err := Copy(w, &sliceRecords{records: records}); err != nil {
+				t.Fatalf("Copy failed: %v", err)
+			}
+			// Record formats can only be indexed at record starts
+			wantEntries := 2
+			if !keepsRecords(format) {
+				wantEntries = (len(flat)-1)/100 + 1
+			}
+			if len(idx.Entries) != wantEntries {
+				t.Fatalf("Expected %d entries, got %d", wantEntries, len(idx.Entries))
+			}
+
+			sr := NewSeekReader(bytes.NewReader(buf.Bytes()), &idx)
+			tests := []struct {
+				offset int64
+				whence int
+				want   int64
+			}{
+				{0, io.SeekStart, 0},
+				{777, io.SeekStart, 777},
+				{-10, io.SeekEnd, int64(len(flat)) - 10},
+				{-500, io.SeekCurrent, int64(len(flat)) - 510},
+				{0, io.SeekEnd, int64(len(flat))},
+			}
+			for _, tt := range tests {
+				pos, err := sr.Seek(tt.offset, tt.whence)
+				if err != nil {
+					t.Fatalf("Seek(%d, %d) failed: %v", tt.offset, tt.whence, err)
+				}
+				if pos != tt.want {
+					t.Fatalf("Expected position %d, got %d", tt.want, pos)
+				}
+
+				var rest []int
+				for {
+					record, err := sr.Read()
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					if err != nil {
+						t.Fatalf("Read failed: %v", err)
+					}
+					rest = append(rest, record...)
+				}
+				if !tokenizer.EqualTokens(rest, flat[pos:]) {
+					t.Errorf("Expected the stream from %d, got %d tokens", pos, len(rest))
+				}
+				// Leave the position where the next seek expects it
+				if _, err := sr.Seek(pos, io.SeekStart); err != nil {
+					t.Fatalf("Seek failed: %v", err)
+				}
+			}
+
+			if _, err := sr.Seek(1, io.SeekEnd); err == nil {
+				t.Error("Expected error for seek past the end")
+			}
+		})
+	}
+}
+
+// sliceRecords reads records from a slice.
+type sliceRecords struct {
+	records [][]int
+}
+
+func (s *sliceRecords) Read() ([]int, error) {
+	if len(s.records) == 0 {
+		return nil, io.EOF
+	}
+	r := s.records[0]
+	s.records = s.records[1:]
+	return r, nil
+}
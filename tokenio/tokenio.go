@@ -0,0 +1,270 @@
+// Package tokenio reads and writes token ID streams in several storage
+// formats, so that stored corpora can be converted between formats without
+// decoding them to text.
+//
+// A stream is a sequence of records, each a slice of token IDs. NDJSON and
+// compressed streams keep record boundaries, typically one document per
+// record. The flat u32 and uvarint formats do not: writing joins records,
+// and reading returns chunks of up to ChunkSize tokens.
+package tokenio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/agentstation/tokenizer"
+)
+
+// Format names a token stream format.
+type Format string
+
+// Supported formats.
+const (
+	U32        Format = "u32"        // Little-endian uint32 per token
+	Uvarint    Format = "uvarint"    // Unsigned varint per token
+	NDJSON     Format = "ndjson"     // One JSON array of IDs per line and record
+	Compressed Format = "compressed" // Per record: uvarint length, then tokenizer.CompressTokens data
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{U32, Uvarint, NDJSON, Compressed}
+
+// ChunkSize is the largest record returned by readers of flat formats.
+const ChunkSize = 4096
+
+// ParseFormat returns the format with the given name.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("tokenio: unknown format %q: use u32, uvarint, ndjson or compressed", name)
+}
+
+// Reader reads records from a token stream.
+type Reader interface {
+	// Read returns the next record. It returns io.EOF after the last record
+	// and io.ErrUnexpectedEOF if the stream ends inside a record.
+	Read() ([]int, error)
+}
+
+// Writer writes records to a token stream. Output is buffered until Flush.
+type Writer interface {
+	Write(tokens []int) error
+	Flush() error
+}
+
+// NewReader returns a reader of the given format.
+func NewReader(r io.Reader, f Format) (Reader, error) {
+	br := bufio.NewReader(r)
+	switch f {
+	case U32:
+		return &u32Reader{r: br}, nil
+	case Uvarint:
+		return &uvarintReader{r: br}, nil
+	case NDJSON:
+		return &ndjsonReader{r: br}, nil
+	case Compressed:
+		return &compressedReader{r: br}, nil
+	}
+	_, err := ParseFormat(string(f))
+	return nil, err
+}
+
+// NewWriter returns a writer of the given format.
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	switch f {
+	case U32, Uvarint, NDJSON, Compressed:
+		return &writer{w: bufio.NewWriter(w), format: f}, nil
+	}
+	_, err := ParseFormat(string(f))
+	return nil, err
+}
+
+// Transcode copies a token stream from one format to another, one record at
+// a time, and returns the number of tokens copied.
+func Transcode(dst io.Writer, to Format, src io.Reader, from Format) (int64, error) {
+	r, err := NewReader(src, from)
+	if err != nil {
+		return 0, err
+	}
+	w, err := NewWriter(dst, to)
+	if err != nil {
+		return 0, err
+	}
+	return Copy(w, r)
+}
+
+// Copy copies records from r to w until io.EOF, flushes w, and returns the
+// number of tokens copied.
+func Copy(w Writer, r Reader) (int64, error) {
+	var n int64
+	for {
+		tokens, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := w.Write(tokens); err != nil {
+			return n, err
+		}
+		n += int64(len(tokens))
+	}
+	return n, w.Flush()
+}
+
+type u32Reader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func (u *u32Reader) Read() ([]int, error) {
+	if u.buf == nil {
+		u.buf = make([]byte, 4*ChunkSize)
+	}
+	n, err := io.ReadFull(u.r, u.buf)
+	if n == 0 {
+		return nil, err
+	}
+	if n%4 != 0 {
+		return nil, fmt.Errorf("tokenio: u32 stream ends inside a token: %w", io.ErrUnexpectedEOF)
+	}
+
+	tokens := make([]int, n/4)
+	for i := range tokens {
+		tokens[i] = int(binary.LittleEndian.Uint32(u.buf[4*i:]))
+	}
+	return tokens, nil
+}
+
+type uvarintReader struct {
+	r *bufio.Reader
+}
+
+func (u *uvarintReader) Read() ([]int, error) {
+	var tokens []int
+	for len(tokens) < ChunkSize {
+		v, err := binary.ReadUvarint(u.r)
+		if errors.Is(err, io.EOF) && len(tokens) > 0 {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxUint32 {
+			return nil, fmt.Errorf("tokenio: token ID %d out of range", v)
+		}
+		tokens = append(tokens, int(v))
+	}
+	return tokens, nil
+}
+
+type ndjsonReader struct {
+	r *bufio.Reader
+}
+
+func (n *ndjsonReader) Read() ([]int, error) {
+	for {
+		line, err := n.r.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		tokens := []int{}
+		if err := json.Unmarshal(line, &tokens); err != nil {
+			return nil, fmt.Errorf("tokenio: ndjson record: %w", err)
+		}
+		return tokens, nil
+	}
+}
+
+type compressedReader struct {
+	r *bufio.Reader
+}
+
+func (c *compressedReader) Read() ([]int, error) {
+	size, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read through a limit so a corrupt size cannot allocate more than the
+	// stream holds
+	data, err := io.ReadAll(io.LimitReader(c.r, int64(min(size, math.MaxInt64))))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) != size {
+		return nil, fmt.Errorf("tokenio: compressed record truncated: %w", io.ErrUnexpectedEOF)
+	}
+	return tokenizer.DecompressTokens(data)
+}
+
+type writer struct {
+	w      *bufio.Writer
+	format Format
+	buf    []byte
+
+	index  *Index // nil unless indexed
+	offset int64  // bytes written
+}
+
+func (w *writer) Write(tokens []int) error {
+	b := w.buf[:0]
+	w.indexAt(0, 0)
+	switch w.format {
+	case U32, Uvarint:
+		for i, id := range tokens {
+			if w.index != nil {
+				w.indexAt(i, len(b))
+			}
+			if id < 0 || id > math.MaxUint32 {
+				return fmt.Errorf("tokenio: token ID %d does not fit the %s format", id, w.format)
+			}
+			if w.format == U32 {
+				b = binary.LittleEndian.AppendUint32(b, uint32(id)) // #nosec G115 - range checked above
+			} else {
+				b = binary.AppendUvarint(b, uint64(id))
+			}
+		}
+	case NDJSON:
+		b = append(b, '[')
+		for i, id := range tokens {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendInt(b, int64(id), 10)
+		}
+		b = append(b, ']', '\n')
+	case Compressed:
+		data := tokenizer.CompressTokens(tokens)
+		b = binary.AppendUvarint(b, uint64(len(data)))
+		b = append(b, data...)
+	}
+	w.buf = b
+
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	if w.index != nil {
+		w.index.Tokens += int64(len(tokens))
+	}
+	return err
+}
+
+func (w *writer) Flush() error {
+	return w.w.Flush()
+}
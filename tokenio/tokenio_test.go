@@ -0,0 +1,140 @@
+package tokenio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer"
+)
+
+func TestTranscode(t *testing.T) {
+	long := make([]int, ChunkSize+10)
+	for i := range long {
+		long[i] = (i * 7919) % 128256
+	}
+	records := [][]int{{128000, 9906, 11, 1917, 0, 128001}, {}, {198, 198, 198, 198}, long}
+	var flat []int
+	for _, r := range records {
+		flat = append(flat, r...)
+	}
+
+	for _, from := range Formats {
+		for _, to := range Formats {
+			t.Run(string(from)+"-"+string(to), func(t *testing.T) {
+				var src bytes.Buffer
+				w, err := NewWriter(&src, from)
+				if err != nil {
+					t.Fatalf("NewWriter failed: %v", err)
+				}
+				for _, r := range records {
+					if err := w.Write(r); err != nil {
+						t.Fatalf("Write failed: %v", err)
+					}
+				}
+				if err := w.Flush(); err != nil {
+					t.Fatalf("Flush failed: %v", err)
+				}
+
+				var dst bytes.Buffer
+				n, err := Transcode(&dst, to, &src, from)
+				if err != nil {
+					t.Fatalf("Transcode failed: %v", err)
+				}
+				if n != int64(len(flat)) {
+					t.Errorf("Expected %d tokens, got %d", len(flat), n)
+				}
+
+				got := readAll(t, &dst, to)
+				var all []int
+				for _, r := range got {
+					all = append(all, r...)
+				}
+				if !tokenizer.EqualTokens(all, flat) {
+					t.Fatalf("Expected %d tokens to survive, got %d", len(flat), len(all))
+				}
+				if keepsRecords(from) && keepsRecords(to) && len(got) != len(records) {
+					t.Errorf("Expected %d records, got %d", len(records), len(got))
+				}
+			})
+		}
+	}
+}
+
+func keepsRecords(f Format) bool {
+	return f == NDJSON || f == Compressed
+}
+
+func readAll(t *testing.T, r io.Reader, f Format) [][]int {
+	t.Helper()
+	reader, err := NewReader(r, f)
+	if err != nil {
+		t.Fatalf("NewReader failed: %v", err)
+	}
+	var records [][]int
+	for {
+		tokens, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if len(tokens) > ChunkSize && !keepsRecords(f) {
+			t.Errorf("Expected flat records of at most %d tokens, got %d", ChunkSize, len(tokens))
+		}
+		records = append(records, tokens)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{U32, "\x01\x00\x00\x00\x02\x00"},
+		{Uvarint, "\x01\x80"},
+		{NDJSON, "[1,2]\n[3,\n"},
+		{NDJSON, `{"tokens":[1]}`},
+		{Compressed, "\x05\x01\x01\x02"},
+		{Compressed, "\x03\x01\x01\x7f"},
+		{Compressed, "\x0a\x01\x80\x80\x80\x20\x01\xfd\xff\xff\x1f"}, // 64M tokens in 10 bytes
+	}
+
+	for _, tt := range tests {
+		r, err := NewReader(strings.NewReader(tt.input), tt.format)
+		if err != nil {
+			t.Fatalf("NewReader failed: %v", err)
+		}
+		var readErr error
+		for readErr == nil {
+			_, readErr = r.Read()
+		}
+		if errors.Is(readErr, io.EOF) {
+			t.Errorf("%s %q: expected an error, got a clean end of stream", tt.format, tt.input)
+		}
+	}
+}
+
+func TestWriterRange(t *testing.T) {
+	for _, f := range []Format{U32, Uvarint} {
+		w, _ := NewWriter(io.Discard, f)
+		if err := w.Write([]int{1, -1}); err == nil {
+			t.Errorf("%s: expected an error for a negative token ID", f)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("ndjson"); err != nil || f != NDJSON {
+		t.Errorf("Expected ndjson, got %q (%v)", f, err)
+	}
+	if _, err := ParseFormat("csv"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if _, err := NewReader(strings.NewReader(""), "csv"); err == nil {
+		t.Error("Expected NewReader to reject an unknown format")
+	}
+}
@@ -0,0 +1,132 @@
+// Package tokenizerbench measures the allocations and speed of code that
+// calls a tokenizer, over a standard set of text corpora.
+//
+// It is meant for integrations that rely on the tokenizers' low-allocation
+// paths, such as AppendTokens with a reused destination slice. A test can
+// assert that the integration stays within an allocation budget, so a change
+// that falls off those paths fails:
+//
+//	func TestEncodeAllocs(t *testing.T) {
+//		dst := make([]int, 0, 4096)
+//		tokenizerbench.AssertAllocs(t, budget, func(text string) {
+//			dst = tok.AppendTokens(dst[:0], text, opts)
+//		})
+//	}
+package tokenizerbench
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// DefaultRuns is the number of measured runs per corpus used by Measure.
+const DefaultRuns = 100
+
+// Corpus is a named sample text.
+type Corpus struct {
+	Name string
+	Text string
+}
+
+// Corpora returns the standard corpora: English prose, source code,
+// multilingual text, whitespace-heavy text, numbers and punctuation, and a
+// chat transcript containing Llama 3 special tokens. Each text is a few
+// kilobytes, large enough to reach steady state without dominating run time.
+func Corpora() []Corpus {
+	return []Corpus{
+		{"prose", strings.Repeat("The quick brown fox jumps over the lazy dog. It's a sunny day, isn't it? ", 40)},
+		{"code", strings.Repeat("func (t *Tokenizer) Encode(text string) []int {\n\tif len(text) == 0 {\n\t\treturn nil\n\t}\n\treturn t.encode(text)\n}\n\n", 24)},
+		{"multilingual", strings.Repeat("Hello world. Bonjour le monde. Hallo Welt. Привет, мир. 你好，世界。こんにちは世界。안녕하세요 세계. مرحبا بالعالم. 🦙🌍 ", 16)},
+		{"whitespace", strings.Repeat("word   \t\t  word\n\n\n    indented\r\n\t\ttrailing   \n", 48)},
+		{"numbers", strings.Repeat("3.14159, 2.71828; 1,000,000 + 42 = 1000042!!! #1 (99%) $19.99 -- 2024-01-15T12:34:56Z ", 32)},
+		{"chat", strings.Repeat("<|start_header_id|>user<|end_header_id|>\n\nWhat's the capital of France?<|eot_id|><|start_header_id|>assistant<|end_header_id|>\n\nThe capital of France is Paris.<|eot_id|>", 16)},
+	}
+}
+
+// Result is the measurement of a function over one corpus.
+type Result struct {
+	Corpus      string
+	Bytes       int     // Size of the corpus text
+	AllocsPerOp float64 // Heap allocations per call
+	BytesPerOp  float64 // Heap bytes allocated per call
+	NsPerOp     float64 // Wall time per call in nanoseconds
+}
+
+// String formats the result like a benchmark line.
+func (r Result) String() string {
+	return fmt.Sprintf("%-14s %8d B %12.0f ns/op %10.1f B/op %8.1f allocs/op",
+		r.Corpus, r.Bytes, r.NsPerOp, r.BytesPerOp, r.AllocsPerOp)
+}
+
+// Measure calls fn with the text of each standard corpus and returns the
+// allocations and time per call, like testing.AllocsPerRun. Each corpus is
+// run once as warmup, so caches are populated and steady-state behaviour is
+// measured, followed by runs measured calls. If runs is 0 or less,
+// DefaultRuns is used.
+//
+// Measure sets GOMAXPROCS to 1 while it runs, so allocations by other
+// goroutines are not counted.
+func Measure(runs int, fn func(text string)) []Result {
+	corpora := Corpora()
+	results := make([]Result, len(corpora))
+	for i, c := range corpora {
+		results[i] = MeasureCorpus(runs, c, fn)
+	}
+	return results
+}
+
+// MeasureCorpus measures fn over a single corpus. See Measure.
+func MeasureCorpus(runs int, c Corpus, fn func(text string)) Result {
+	if runs <= 0 {
+		runs = DefaultRuns
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1))
+
+	// Warm up
+	fn(c.Text)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		fn(c.Text)
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return Result{
+		Corpus:      c.Name,
+		Bytes:       len(c.Text),
+		AllocsPerOp: float64(after.Mallocs-before.Mallocs) / float64(runs),
+		BytesPerOp:  float64(after.TotalAlloc-before.TotalAlloc) / float64(runs),
+		NsPerOp:     float64(elapsed.Nanoseconds()) / float64(runs),
+	}
+}
+
+// AssertAllocs measures fn over the standard corpora and reports an error on
+// tb for each corpus where fn allocates more than maxAllocs times per call.
+func AssertAllocs(tb testing.TB, maxAllocs float64, fn func(text string)) {
+	tb.Helper()
+	for _, r := range Measure(DefaultRuns, fn) {
+		if r.AllocsPerOp > maxAllocs {
+			tb.Errorf("%s: %.1f allocs/op, want at most %.1f", r.Corpus, r.AllocsPerOp, maxAllocs)
+		}
+	}
+}
+
+// Benchmark runs fn as a sub-benchmark of b for each standard corpus,
+// reporting allocations and throughput.
+func Benchmark(b *testing.B, fn func(text string)) {
+	for _, c := range Corpora() {
+		b.Run(c.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(c.Text)))
+			for i := 0; i < b.N; i++ {
+				fn(c.Text)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package tokenizerbench
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/agentstation/tokenizer/llama3"
+)
+
+func TestCorpora(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, c := range Corpora() {
+		if seen[c.Name] {
+			t.Errorf("Duplicate corpus %q", c.Name)
+		}
+		seen[c.Name] = true
+		if len(c.Text) < 1024 {
+			t.Errorf("Corpus %q is only %d bytes", c.Name, len(c.Text))
+		}
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	t.Run("no_allocs", func(t *testing.T) {
+		n := 0
+		for _, r := range Measure(10, func(text string) { n += len(text) }) {
+			if r.AllocsPerOp != 0 || r.BytesPerOp != 0 {
+				t.Errorf("%s: expected no allocations, got %.1f allocs/op, %.1f B/op", r.Corpus, r.AllocsPerOp, r.BytesPerOp)
+			}
+		}
+	})
+
+	t.Run("allocs", func(t *testing.T) {
+		var sink []byte
+		for _, r := range Measure(10, func(text string) { sink = []byte(text) }) {
+			if r.AllocsPerOp != 1 {
+				t.Errorf("%s: expected 1 alloc/op, got %.1f", r.Corpus, r.AllocsPerOp)
+			}
+			if r.BytesPerOp < float64(r.Bytes) {
+				t.Errorf("%s: expected at least %d B/op, got %.1f", r.Corpus, r.Bytes, r.BytesPerOp)
+			}
+		}
+		_ = sink
+	})
+}
+
+func TestAssertAllocs(t *testing.T) {
+	recorder := &errorRecorder{TB: t}
+	AssertAllocs(recorder, 0, func(text string) { _ = strings.Clone(text) })
+	if len(recorder.errors) != len(Corpora()) {
+		t.Errorf("Expected an error per corpus, got %q", recorder.errors)
+	}
+}
+
+// errorRecorder records Errorf calls instead of failing the test.
+type errorRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, format)
+}
+
+func BenchmarkLlama3Encode(b *testing.B) {
+	tokenizer, err := llama3.New()
+	if err != nil {
+		b.Fatalf("Failed to create tokenizer: %v", err)
+	}
+
+	Benchmark(b, func(text string) {
+		_ = tokenizer.Encode(text, nil)
+	})
+}
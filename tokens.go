@@ -0,0 +1,42 @@
+package tokenizer
+
+// EqualTokens reports whether a and b hold the same token IDs in the same
+// order. A nil slice equals an empty one.
+//
+// The comparison does not stop at the first difference: its running time
+// depends only on the lengths, and the loop compiles to branch-free blocks
+// that are fast on long sequences.
+func EqualTokens(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return diffTokens(a, b) == 0
+}
+
+// PrefixEqual reports whether tokens starts with prefix, for example whether
+// a cached prompt encoding can be reused. Like EqualTokens, it compares every
+// token of prefix.
+func PrefixEqual(tokens, prefix []int) bool {
+	if len(prefix) > len(tokens) {
+		return false
+	}
+	return diffTokens(tokens[:len(prefix)], prefix) == 0
+}
+
+// diffTokens returns zero if a and b, which have the same length, are equal.
+// It ORs the XOR of each pair in blocks of eight, which the compiler can keep
+// in registers without bounds checks.
+func diffTokens(a, b []int) int {
+	b = b[:len(a)]
+	var diff int
+	for len(a) >= 8 {
+		x, y := a[:8:8], b[:8:8]
+		diff |= (x[0] ^ y[0]) | (x[1] ^ y[1]) | (x[2] ^ y[2]) | (x[3] ^ y[3]) |
+			(x[4] ^ y[4]) | (x[5] ^ y[5]) | (x[6] ^ y[6]) | (x[7] ^ y[7])
+		a, b = a[8:], b[8:]
+	}
+	for i := range a {
+		diff |= a[i] ^ b[i]
+	}
+	return diff
+}
@@ -0,0 +1,71 @@
+package tokenizer
+
+import "testing"
+
+func TestEqualTokens(t *testing.T) {
+	long := make([]int, 37)
+	for i := range long {
+		long[i] = i * 1000
+	}
+	changed := append([]int(nil), long...)
+	changed[33] = -1
+
+	tests := []struct {
+		name string
+		a, b []int
+		want bool
+	}{
+		{"nil and empty", nil, []int{}, true},
+		{"equal", []int{1, 2, 3}, []int{1, 2, 3}, true},
+		{"different", []int{1, 2, 3}, []int{1, 2, 4}, false},
+		{"different length", []int{1, 2}, []int{1, 2, 3}, false},
+		{"long equal", long, append([]int(nil), long...), true},
+		{"long different in tail", long, changed, false},
+		{"long different in block", long, append([]int{7}, long[1:]...), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EqualTokens(tt.a, tt.b); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrefixEqual(t *testing.T) {
+	tokens := []int{128000, 9906, 11, 1917, 0, 128001, 5, 6, 7, 8}
+
+	tests := []struct {
+		prefix []int
+		want   bool
+	}{
+		{nil, true},
+		{tokens[:1], true},
+		{tokens, true},
+		{tokens[:9], true},
+		{[]int{128000, 9906, 12}, false},
+		{append(tokens[:len(tokens):len(tokens)], 9), false},
+	}
+
+	for _, tt := range tests {
+		if got := PrefixEqual(tokens, tt.prefix); got != tt.want {
+			t.Errorf("PrefixEqual(%v): expected %v, got %v", tt.prefix, tt.want, got)
+		}
+	}
+}
+
+func BenchmarkEqualTokens(b *testing.B) {
+	a := make([]int, 4096)
+	for i := range a {
+		a[i] = i
+	}
+	c := append([]int(nil), a...)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !EqualTokens(a, c) {
+			b.Fatal("Expected equal sequences")
+		}
+	}
+}
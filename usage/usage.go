@@ -0,0 +1,200 @@
+// Package usage aggregates token usage across models, so services that wrap a
+// tokenizer can report how many tokens they send and receive:
+//
+//	meter := usage.NewMeter()
+//	meter.Add("llama-3.1-8b", usage.Input, len(promptTokens))
+//	meter.Add("llama-3.1-8b", usage.Output, len(replyTokens))
+//
+//	snap := meter.Snapshot()
+//	_ = json.NewEncoder(w).Encode(snap)
+//
+// A Meter keeps running totals and the tokens of the last minute, from which
+// it reports per-minute rates. It is safe for concurrent use.
+package usage
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Direction is whether tokens were sent to a model or generated by it.
+type Direction string
+
+// Directions of token usage.
+const (
+	Input  Direction = "input"  // prompt tokens
+	Output Direction = "output" // generated tokens
+)
+
+// rateBuckets is the number of one-second buckets of the rate window.
+const rateBuckets = 60
+
+// Usage is the token usage of a model in one direction.
+type Usage struct {
+	Model     string    `json:"model"`
+	Direction Direction `json:"direction"`
+	Tokens    int64     `json:"tokens"`     // total since the meter started or was reset
+	Calls     int64     `json:"calls"`      // number of Add calls
+	PerMinute int64     `json:"per_minute"` // tokens in the last minute
+}
+
+// Snapshot is the usage of every model and direction at a point in time.
+type Snapshot struct {
+	Time  time.Time `json:"time"`
+	Since time.Time `json:"since"` // when the meter started or was last reset
+	Usage []Usage   `json:"usage"` // sorted by model, then direction
+}
+
+// Total returns the tokens of the snapshot in direction dir across models.
+func (s Snapshot) Total(dir Direction) int64 {
+	var total int64
+	for _, u := range s.Usage {
+		if u.Direction == dir {
+			total += u.Tokens
+		}
+	}
+	return total
+}
+
+// Meter aggregates token usage. The zero value is not usable; create meters
+// with NewMeter.
+type Meter struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	since  time.Time
+	series map[seriesKey]*series
+}
+
+type seriesKey struct {
+	model string
+	dir   Direction
+}
+
+// series is the usage of one model and direction.
+type series struct {
+	tokens  int64
+	calls   int64
+	buckets [rateBuckets]int64 // tokens per second, a ring indexed by Unix second
+	seconds [rateBuckets]int64 // Unix second of each bucket
+}
+
+// NewMeter returns an empty meter.
+func NewMeter() *Meter {
+	return newMeter(time.Now)
+}
+
+func newMeter(now func() time.Time) *Meter {
+	return &Meter{now: now, since: now(), series: make(map[seriesKey]*series)}
+}
+
+// Add records tokens used by model in direction dir. Negative counts are
+// recorded as a call without tokens.
+func (m *Meter) Add(model string, dir Direction, tokens int) {
+	tokens = max(tokens, 0)
+	sec := m.now().Unix()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := seriesKey{model, dir}
+	s := m.series[key]
+	if s == nil {
+		s = &series{}
+		m.series[key] = s
+	}
+	s.tokens += int64(tokens)
+	s.calls++
+
+	i := bucket(sec)
+	if s.seconds[i] != sec {
+		s.seconds[i] = sec
+		s.buckets[i] = 0
+	}
+	s.buckets[i] += int64(tokens)
+}
+
+// Total returns the tokens used by model in direction dir.
+func (m *Meter) Total(model string, dir Direction) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.series[seriesKey{model, dir}]; s != nil {
+		return s.tokens
+	}
+	return 0
+}
+
+// Rate returns the tokens used by model in direction dir in the last minute.
+func (m *Meter) Rate(model string, dir Direction) int64 {
+	sec := m.now().Unix()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s := m.series[seriesKey{model, dir}]; s != nil {
+		return s.lastMinute(sec)
+	}
+	return 0
+}
+
+// Snapshot returns the usage of every model and direction seen.
+func (m *Meter) Snapshot() Snapshot {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshotLocked(now)
+}
+
+// Reset clears the meter and returns its usage up to the reset, so periodic
+// exports count every token exactly once.
+func (m *Meter) Reset() Snapshot {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snap := m.snapshotLocked(now)
+	m.series = make(map[seriesKey]*series)
+	m.since = now
+	return snap
+}
+
+func (m *Meter) snapshotLocked(now time.Time) Snapshot {
+	snap := Snapshot{Time: now, Since: m.since, Usage: make([]Usage, 0, len(m.series))}
+	for key, s := range m.series {
+		snap.Usage = append(snap.Usage, Usage{
+			Model:     key.model,
+			Direction: key.dir,
+			Tokens:    s.tokens,
+			Calls:     s.calls,
+			PerMinute: s.lastMinute(now.Unix()),
+		})
+	}
+	sort.Slice(snap.Usage, func(i, j int) bool {
+		a, b := snap.Usage[i], snap.Usage[j]
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		return a.Direction < b.Direction
+	})
+	return snap
+}
+
+// lastMinute returns the tokens of the minute up to and including second sec.
+func (s *series) lastMinute(sec int64) int64 {
+	var total int64
+	for i, at := range s.seconds {
+		if at > sec-rateBuckets && at <= sec {
+			total += s.buckets[i]
+		}
+	}
+	return total
+}
+
+// bucket returns the index of the bucket of Unix second sec.
+func bucket(sec int64) int {
+	i := sec % rateBuckets
+	if i < 0 {
+		i += rateBuckets
+	}
+	return int(i)
+}
@@ -0,0 +1,145 @@
+package usage
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestMeter() (*Meter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 7, 23, 12, 0, 0, 0, time.UTC)}
+	return newMeter(clock.Now), clock
+}
+
+func TestMeterTotalsAndRates(t *testing.T) {
+	m, clock := newTestMeter()
+
+	m.Add("llama", Input, 100)
+	m.Add("llama", Output, 20)
+	clock.Advance(30 * time.Second)
+	m.Add("llama", Input, 50)
+	m.Add("llama", Input, -5)
+
+	if got := m.Total("llama", Input); got != 150 {
+		t.Errorf("Expected 150 input tokens, got %d", got)
+	}
+	if got := m.Rate("llama", Input); got != 150 {
+		t.Errorf("Expected 150 input tokens per minute, got %d", got)
+	}
+
+	// The first tokens leave the one-minute window
+	clock.Advance(30 * time.Second)
+	if got := m.Rate("llama", Input); got != 50 {
+		t.Errorf("Expected 50 input tokens per minute, got %d", got)
+	}
+	if got := m.Rate("llama", Output); got != 0 {
+		t.Errorf("Expected 0 output tokens per minute, got %d", got)
+	}
+	if got := m.Total("llama", Input); got != 150 {
+		t.Errorf("Expected the total to stay 150, got %d", got)
+	}
+
+	// The bucket of the first tokens is reused
+	m.Add("llama", Input, 7)
+	if got := m.Rate("llama", Input); got != 57 {
+		t.Errorf("Expected 57 input tokens per minute, got %d", got)
+	}
+
+	if got := m.Total("other", Input); got != 0 {
+		t.Errorf("Expected 0 tokens for an unknown model, got %d", got)
+	}
+}
+
+func TestMeterSnapshot(t *testing.T) {
+	m, clock := newTestMeter()
+	start := clock.Now()
+	m.Add("b", Output, 3)
+	m.Add("a", Output, 2)
+	m.Add("b", Input, 1)
+	m.Add("a", Input, 4)
+	clock.Advance(time.Second)
+
+	snap := m.Snapshot()
+	want := []Usage{
+		{"a", Input, 4, 1, 4},
+		{"a", Output, 2, 1, 2},
+		{"b", Input, 1, 1, 1},
+		{"b", Output, 3, 1, 3},
+	}
+	if len(snap.Usage) != len(want) {
+		t.Fatalf("Expected %d entries, got %+v", len(want), snap.Usage)
+	}
+	for i := range want {
+		if snap.Usage[i] != want[i] {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, want[i], snap.Usage[i])
+		}
+	}
+	if !snap.Since.Equal(start) || !snap.Time.Equal(start.Add(time.Second)) {
+		t.Errorf("Expected the snapshot to span %v to %v, got %v to %v", start, start.Add(time.Second), snap.Since, snap.Time)
+	}
+	if in, out := snap.Total(Input), snap.Total(Output); in != 5 || out != 5 {
+		t.Errorf("Expected 5 input and 5 output tokens, got %d and %d", in, out)
+	}
+
+	data, err := json.Marshal(snap.Usage[0])
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := `{"model":"a","direction":"input","tokens":4,"calls":1,"per_minute":4}`; string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+}
+
+func TestMeterReset(t *testing.T) {
+	m, clock := newTestMeter()
+	m.Add("llama", Input, 10)
+	clock.Advance(time.Minute)
+
+	snap := m.Reset()
+	if snap.Total(Input) != 10 {
+		t.Errorf("Expected the reset snapshot to hold 10 tokens, got %d", snap.Total(Input))
+	}
+	after := m.Snapshot()
+	if len(after.Usage) != 0 || !after.Since.Equal(clock.Now()) {
+		t.Errorf("Expected an empty meter since %v, got %+v", clock.Now(), after)
+	}
+}
+
+func TestMeterConcurrent(t *testing.T) {
+	m := NewMeter()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.Add("llama", Input, 1)
+				_ = m.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := m.Total("llama", Input); got != 8000 {
+		t.Errorf("Expected 8000 tokens, got %d", got)
+	}
+}